@@ -23,7 +23,7 @@ func Unpanic(f func() error) (err error) {
 		if r := recover(); r != nil {
 			r := fmt.Errorf("panic: %v", r)
 			r = xerrors.Extend(stacktrace.GetStack(panicStackDepth, true), r)
-			err = errclass.WrapAs(r, errclass.Panic)
+			err = notifyPanicHook(errclass.WrapAs(r, errclass.Panic))
 		}
 	}()
 
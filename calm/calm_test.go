@@ -85,3 +85,22 @@ func TestUnpanic(t *testing.T) {
 		}
 	}
 }
+
+func TestPanicHook(t *testing.T) { //nolint:paralleltest // sets the global panic hook
+	var hooked error
+	calm.SetPanicHook(func(err error) {
+		hooked = err
+		panic("panics in the hook are ignored")
+	})
+	t.Cleanup(func() { calm.SetPanicHook(nil) })
+
+	err := calm.Unpanic(a)
+	if err == nil || hooked == nil || hooked.Error() != err.Error() {
+		t.Errorf("expected hook to receive the recovered error: got %v want %v", hooked, err)
+	}
+
+	hooked = nil
+	if err := calm.Unpanic(func() error { return nil }); err != nil || hooked != nil {
+		t.Errorf("expected hook not to be called without a panic")
+	}
+}
@@ -0,0 +1,171 @@
+// Package crashreport writes structured crash reports for recovered panics to blob storage.
+package crashreport
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/zircuit-labs/zkr-go-common/calm"
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/log/identity"
+	"github.com/zircuit-labs/zkr-go-common/version"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const (
+	defaultPrefix  = "crash-reports/"
+	defaultTimeout = time.Second * 10
+)
+
+// Uploader stores crash reports. The s3.BlobStore satisfies this interface.
+type Uploader interface {
+	Upload(ctx context.Context, key string, data []byte) error
+}
+
+// Report is the content of a crash report.
+type Report struct {
+	Time     time.Time                   `json:"time"`
+	Service  string                      `json:"service"`
+	Instance string                      `json:"instance"`
+	Error    string                      `json:"error"`
+	Stack    stacktrace.StackTrace       `json:"stack,omitempty"`
+	Version  *version.VersionInformation `json:"version,omitempty"`
+	Config   map[string]any              `json:"config,omitempty"`
+	Logs     []json.RawMessage           `json:"logs,omitempty"`
+}
+
+type options struct {
+	logger    *slog.Logger
+	prefix    string
+	timeout   time.Duration
+	logBuffer *LogBuffer
+	cfg       *config.Configuration
+	redact    []config.DumpOption
+	version   *version.VersionInformation
+}
+
+// Option is an option func for New.
+type Option func(options *options)
+
+// WithLogger sets the logger to be used.
+func WithLogger(logger *slog.Logger) Option {
+	return func(options *options) {
+		options.logger = logger
+	}
+}
+
+// WithPrefix sets the key prefix under which reports are written. Defaults to "crash-reports/".
+func WithPrefix(prefix string) Option {
+	return func(options *options) {
+		options.prefix = prefix
+	}
+}
+
+// WithTimeout sets the maximum time allowed for writing a report.
+func WithTimeout(d time.Duration) Option {
+	return func(options *options) {
+		options.timeout = d
+	}
+}
+
+// WithLogBuffer includes the records retained by the LogBuffer in reports.
+func WithLogBuffer(buffer *LogBuffer) Option {
+	return func(options *options) {
+		options.logBuffer = buffer
+	}
+}
+
+// WithConfig includes a snapshot of the configuration in reports, redacted as by Configuration.Dump:
+// secret references and keys matching the default patterns are always redacted, and the options may
+// redact further keys (see config.RedactKeys and config.RedactStruct).
+func WithConfig(cfg *config.Configuration, redact ...config.DumpOption) Option {
+	return func(options *options) {
+		options.cfg = cfg
+		options.redact = redact
+	}
+}
+
+// WithVersion includes the version information in reports.
+func WithVersion(versionInfo *version.VersionInformation) Option {
+	return func(options *options) {
+		options.version = versionInfo
+	}
+}
+
+// Reporter writes crash reports to an Uploader.
+type Reporter struct {
+	store Uploader
+	opts  options
+}
+
+// New creates a Reporter writing to the store.
+func New(store Uploader, opts ...Option) *Reporter {
+	options := options{
+		logger:  log.NewNilLogger(),
+		prefix:  defaultPrefix,
+		timeout: defaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &Reporter{store: store, opts: options}
+}
+
+// Install sets the Reporter as the calm panic hook, such that
+// a report is written for every panic recovered by calm.Unpanic.
+func (r *Reporter) Install() {
+	calm.SetPanicHook(r.Report)
+}
+
+// Report writes a crash report for err, logging any failure to do so.
+func (r *Reporter) Report(err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.opts.timeout)
+	defer cancel()
+	key, writeErr := r.Write(ctx, err)
+	if writeErr != nil {
+		r.opts.logger.Error("failed to write crash report", log.ErrAttr(writeErr))
+		return
+	}
+	r.opts.logger.Info("crash report written", slog.String("key", key))
+}
+
+// Write writes a crash report for err and returns its key.
+func (r *Reporter) Write(ctx context.Context, err error) (string, error) {
+	report := r.build(err)
+	data, marshalErr := json.Marshal(report)
+	if marshalErr != nil {
+		return "", stacktrace.Wrap(marshalErr)
+	}
+
+	key := r.opts.prefix + report.Time.Format("20060102T150405Z") + "-" + report.Instance + "-" + xid.New().String() + ".json"
+	if uploadErr := r.store.Upload(ctx, key, data); uploadErr != nil {
+		return "", stacktrace.Wrap(uploadErr)
+	}
+	return key, nil
+}
+
+func (r *Reporter) build(err error) Report {
+	service, instance := identity.WhoAmI()
+	report := Report{
+		Time:     time.Now().UTC(),
+		Service:  service,
+		Instance: instance,
+		Stack:    stacktrace.Extract(err),
+		Version:  r.opts.version,
+	}
+	if err != nil {
+		report.Error = err.Error()
+	}
+	if r.opts.logBuffer != nil {
+		report.Logs = r.opts.logBuffer.Entries()
+	}
+	if r.opts.cfg != nil {
+		report.Config = r.opts.cfg.Redacted(r.opts.redact...)
+	}
+	return report
+}
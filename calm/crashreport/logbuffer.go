@@ -0,0 +1,90 @@
+package crashreport
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// LogBuffer retains the most recent log records so that they can be included in a crash report,
+// even when the log pipeline itself drops records during a crash.
+type LogBuffer struct {
+	mu      sync.Mutex
+	entries [][]byte
+	next    int
+	full    bool
+}
+
+// NewLogBuffer creates a LogBuffer retaining up to size records.
+func NewLogBuffer(size int) *LogBuffer {
+	return &LogBuffer{entries: make([][]byte, max(size, 1))}
+}
+
+// Write implements io.Writer, storing each write as a single entry.
+func (b *LogBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries[b.next] = slices.Clone(p)
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+	return len(p), nil
+}
+
+// Entries returns the retained records as JSON, oldest first.
+func (b *LogBuffer) Entries() []json.RawMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var ordered [][]byte
+	if b.full {
+		ordered = append(ordered, b.entries[b.next:]...)
+	}
+	ordered = append(ordered, b.entries[:b.next]...)
+
+	result := make([]json.RawMessage, 0, len(ordered))
+	for _, e := range ordered {
+		result = append(result, json.RawMessage(e))
+	}
+	return result
+}
+
+// Handler wraps next such that all records, including those below the level of next, are also retained.
+func (b *LogBuffer) Handler(next slog.Handler) slog.Handler {
+	return &teeHandler{
+		next:   next,
+		buffer: slog.NewJSONHandler(b, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	}
+}
+
+type teeHandler struct {
+	next   slog.Handler
+	buffer slog.Handler
+}
+
+// Enabled implements slog.Handler.
+func (h *teeHandler) Enabled(_ context.Context, _ slog.Level) bool {
+	return true
+}
+
+// Handle implements slog.Handler.
+func (h *teeHandler) Handle(ctx context.Context, record slog.Record) error {
+	// the buffer never fails, so ignore any error
+	_ = h.buffer.Handle(ctx, record)
+	if !h.next.Enabled(ctx, record.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, record)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &teeHandler{next: h.next.WithAttrs(attrs), buffer: h.buffer.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler.
+func (h *teeHandler) WithGroup(name string) slog.Handler {
+	return &teeHandler{next: h.next.WithGroup(name), buffer: h.buffer.WithGroup(name)}
+}
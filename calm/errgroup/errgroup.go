@@ -5,38 +5,92 @@ import (
 	"context"
 
 	"github.com/zircuit-labs/zkr-go-common/calm"
+	"github.com/zircuit-labs/zkr-go-common/log"
 	"golang.org/x/sync/errgroup"
 )
 
+type options struct {
+	limit int
+}
+
+// Option is an option func for New and WithContext.
+type Option func(options *options)
+
+// WithLimit limits the number of goroutines active in the group at once (see SetLimit),
+// such that the group acts as a bounded worker pool. A negative value means no limit (default).
+func WithLimit(n int) Option {
+	return func(options *options) {
+		options.limit = n
+	}
+}
+
+// Group is a collection of goroutines working on subtasks of the same overall task.
+// Panics within the goroutines are recovered and returned from Wait as errors classed as errclass.Panic
+// with the stack trace of the panic (see calm.Unpanic).
 type Group struct {
 	group *errgroup.Group
+	ctx   context.Context
 }
 
-func WithContext(ctx context.Context) (*Group, context.Context) {
+// WithContext returns a new Group and an associated context derived from ctx,
+// which is cancelled the first time a function passed to Go returns an error or Wait returns.
+func WithContext(ctx context.Context, opts ...Option) (*Group, context.Context) {
 	group, ctx := errgroup.WithContext(ctx)
-	return &Group{group: group}, ctx
+	g := &Group{group: group, ctx: ctx}
+	g.apply(opts)
+	return g, ctx
 }
 
-func New() *Group {
-	return &Group{group: new(errgroup.Group)}
+// New returns a new Group without an associated context.
+func New(opts ...Option) *Group {
+	g := &Group{group: new(errgroup.Group), ctx: context.Background()}
+	g.apply(opts)
+	return g
 }
 
+func (g *Group) apply(opts []Option) {
+	options := options{
+		limit: -1,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	g.group.SetLimit(options.limit)
+}
+
+// Go calls f in a new goroutine, recovering any panic as an error.
+// If the group has a limit, Go blocks until f can be started without exceeding it.
 func (g *Group) Go(f func() error) {
 	g.group.Go(func() error {
 		return calm.Unpanic(f)
 	})
 }
 
+// GoTask calls f as per Go, giving it the group context (if any) carrying the task name
+// and a new run ID, such that its logs can be told apart from those of other goroutines.
+// See log.ContextWithTask.
+func (g *Group) GoTask(name string, f func(ctx context.Context) error) {
+	ctx := log.ContextWithTask(g.ctx, name)
+	g.Go(func() error {
+		return f(ctx)
+	})
+}
+
+// SetLimit limits the number of goroutines active in the group at once to n.
+// A negative value means no limit. The limit must not be changed while any goroutines are active.
 func (g *Group) SetLimit(n int) {
 	g.group.SetLimit(n)
 }
 
+// TryGo calls f in a new goroutine as per Go, but only if doing so does not exceed the limit of the group.
+// It reports whether f was started.
 func (g *Group) TryGo(f func() error) bool {
 	return g.group.TryGo(func() error {
 		return calm.Unpanic(f)
 	})
 }
 
+// Wait blocks until all goroutines have returned, then returns the first error (if any) from them.
 func (g *Group) Wait() error {
 	return g.group.Wait()
 }
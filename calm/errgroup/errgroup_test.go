@@ -1,10 +1,13 @@
 package errgroup_test
 
 import (
+	"context"
 	"fmt"
+	"sync/atomic"
 	"testing"
 
 	"github.com/zircuit-labs/zkr-go-common/calm/errgroup"
+	"github.com/zircuit-labs/zkr-go-common/log"
 	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
 )
 
@@ -65,3 +68,67 @@ func TestErrGroup(t *testing.T) {
 		})
 	}
 }
+
+func TestGoTask(t *testing.T) {
+	t.Parallel()
+
+	g, _ := errgroup.WithContext(t.Context())
+	g.GoTask("worker", func(ctx context.Context) error {
+		info, ok := log.TaskFromContext(ctx)
+		if !ok || info.Name != "worker" {
+			return fmt.Errorf("unexpected task info: %+v", info)
+		}
+		return nil
+	})
+	g.GoTask("panics", func(ctx context.Context) error {
+		panic("this is a test panic")
+	})
+
+	if class := errclass.GetClass(g.Wait()); class != errclass.Panic {
+		t.Errorf("unexpected error class: want: %s got %s", errclass.Panic, class)
+	}
+}
+
+func TestWithLimit(t *testing.T) {
+	t.Parallel()
+
+	g := errgroup.New(errgroup.WithLimit(2))
+	release := make(chan struct{})
+	var active, maxActive atomic.Int32
+	block := func() error {
+		n := active.Add(1)
+		for {
+			m := maxActive.Load()
+			if n <= m || maxActive.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		<-release
+		active.Add(-1)
+		return nil
+	}
+
+	if !g.TryGo(block) || !g.TryGo(block) {
+		t.Fatal("expected TryGo to start goroutines within the limit")
+	}
+	if g.TryGo(block) {
+		t.Error("expected TryGo to fail when the limit is reached")
+	}
+
+	// Go blocks until there is room, so must be called while the others are released
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.Go(block)
+		g.Go(c)
+	}()
+	close(release)
+	<-done
+
+	if class := errclass.GetClass(g.Wait()); class != errclass.Panic {
+		t.Errorf("unexpected error class: want: %s got %s", errclass.Panic, class)
+	}
+	if m := maxActive.Load(); m > 2 {
+		t.Errorf("limit exceeded: %d goroutines active at once", m)
+	}
+}
@@ -0,0 +1,31 @@
+package calm
+
+import "sync/atomic"
+
+var panicHook atomic.Pointer[func(err error)]
+
+// SetPanicHook sets a function to be called with every panic recovered by Unpanic,
+// for example to write a crash report. Passing nil removes the hook.
+// The hook is called synchronously, and any panic within it is ignored.
+func SetPanicHook(hook func(err error)) {
+	if hook == nil {
+		panicHook.Store(nil)
+		return
+	}
+	panicHook.Store(&hook)
+}
+
+// notifyPanicHook calls the panic hook, if set, and returns err unchanged.
+func notifyPanicHook(err error) error {
+	if hook := panicHook.Load(); hook != nil {
+		callPanicHook(*hook, err)
+	}
+	return err
+}
+
+func callPanicHook(hook func(err error), err error) {
+	defer func() {
+		_ = recover()
+	}()
+	hook(err)
+}
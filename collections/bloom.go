@@ -0,0 +1,178 @@
+package collections
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+	"sync"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// bloomEncodingVersion is the first byte of an encoded BloomFilter.
+const bloomEncodingVersion = 1
+
+var (
+	ErrInvalidBloomParams = errors.New("expected items must be positive and the false positive rate within (0, 1)")
+	ErrIncompatible       = errors.New("cannot merge sketches with different parameters")
+	ErrInvalidEncoding    = errors.New("invalid encoding")
+)
+
+// BloomFilter is a set membership test which uses a fixed amount of memory, at the cost of
+// false positives: Contains never returns false for an item which was added, but may return
+// true for one which was not. Items cannot be removed. It is safe for concurrent use.
+//
+// Hashing is stable across processes, so a filter may be persisted (see MarshalBinary)
+// and later restored or merged by another instance. Create it with NewBloomFilter, or
+// restore the zero value with UnmarshalBinary.
+type BloomFilter struct {
+	mu     sync.RWMutex
+	hashes uint32
+	bits   []uint64
+}
+
+// NewBloomFilter creates an empty BloomFilter sized such that the rate of false positives is
+// at most falsePositiveRate once expectedItems have been added.
+func NewBloomFilter(expectedItems uint64, falsePositiveRate float64) (*BloomFilter, error) {
+	if expectedItems == 0 || !(falsePositiveRate > 0 && falsePositiveRate < 1) {
+		return nil, stacktrace.Wrap(ErrInvalidBloomParams)
+	}
+	n := float64(expectedItems)
+	m := math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	k := max(1, math.Round(m/n*math.Ln2))
+	return &BloomFilter{
+		hashes: uint32(k),
+		bits:   make([]uint64, (uint64(m)+63)/64),
+	}, nil
+}
+
+// Add adds the items to the filter.
+func (f *BloomFilter) Add(items ...[]byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, item := range items {
+		h1, h2 := bloomHashes(item)
+		for i := range f.hashes {
+			pos := f.position(h1, h2, i)
+			f.bits[pos/64] |= 1 << (pos % 64)
+		}
+	}
+}
+
+// AddString adds the items to the filter.
+func (f *BloomFilter) AddString(items ...string) {
+	for _, item := range items {
+		f.Add([]byte(item))
+	}
+}
+
+// Contains reports whether the item may have been added to the filter.
+func (f *BloomFilter) Contains(item []byte) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	h1, h2 := bloomHashes(item)
+	for i := range f.hashes {
+		pos := f.position(h1, h2, i)
+		if f.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ContainsString reports whether the item may have been added to the filter.
+func (f *BloomFilter) ContainsString(item string) bool {
+	return f.Contains([]byte(item))
+}
+
+// EstimatedCount estimates the number of distinct items added to the filter.
+func (f *BloomFilter) EstimatedCount() uint64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	set := 0
+	for _, w := range f.bits {
+		set += bits.OnesCount64(w)
+	}
+	m := float64(len(f.bits) * 64)
+	if set == len(f.bits)*64 {
+		return math.MaxUint64
+	}
+	return uint64(math.Round(-m / float64(f.hashes) * math.Log(1-float64(set)/m)))
+}
+
+// Merge adds all items of other to the filter, which must have been created with the same parameters.
+func (f *BloomFilter) Merge(other *BloomFilter) error {
+	if f == other {
+		return nil
+	}
+	// copy other first rather than holding both locks, which could deadlock with other.Merge(f)
+	other.mu.RLock()
+	hashes, words := other.hashes, slices.Clone(other.bits)
+	other.mu.RUnlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.hashes != hashes || len(f.bits) != len(words) {
+		return stacktrace.Wrap(ErrIncompatible)
+	}
+	for i, w := range words {
+		f.bits[i] |= w
+	}
+	return nil
+}
+
+// Clear removes all items from the filter.
+func (f *BloomFilter) Clear() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	clear(f.bits)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (f *BloomFilter) MarshalBinary() ([]byte, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	data := make([]byte, 0, 5+len(f.bits)*8)
+	data = append(data, bloomEncodingVersion)
+	data = binary.BigEndian.AppendUint32(data, f.hashes)
+	for _, w := range f.bits {
+		data = binary.BigEndian.AppendUint64(data, w)
+	}
+	return data, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, replacing the contents of the filter.
+func (f *BloomFilter) UnmarshalBinary(data []byte) error {
+	if len(data) <= 5 || data[0] != bloomEncodingVersion || (len(data)-5)%8 != 0 {
+		return stacktrace.Wrap(fmt.Errorf("bloom filter: %w", ErrInvalidEncoding))
+	}
+	hashes := binary.BigEndian.Uint32(data[1:5])
+	if hashes == 0 {
+		return stacktrace.Wrap(fmt.Errorf("bloom filter: %w", ErrInvalidEncoding))
+	}
+	words := make([]uint64, (len(data)-5)/8)
+	for i := range words {
+		words[i] = binary.BigEndian.Uint64(data[5+i*8:])
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hashes = hashes
+	f.bits = words
+	return nil
+}
+
+// position returns the ith bit of an item using double hashing.
+func (f *BloomFilter) position(h1, h2 uint64, i uint32) uint64 {
+	return (h1 + uint64(i)*h2) % uint64(len(f.bits)*64)
+}
+
+// bloomHashes returns two independent hashes of the item, the second being odd
+// so that it is never a multiple of the filter size.
+func bloomHashes(item []byte) (uint64, uint64) {
+	h := hash64(item)
+	return h, mix64(h^0x9e3779b97f4a7c15) | 1
+}
@@ -0,0 +1,93 @@
+package collections_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/collections"
+)
+
+func TestNewBloomFilterErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		items uint64
+		rate  float64
+	}{{0, 0.01}, {100, 0}, {100, 1}, {100, -0.5}} {
+		_, err := collections.NewBloomFilter(tc.items, tc.rate)
+		require.ErrorIs(t, err, collections.ErrInvalidBloomParams)
+	}
+}
+
+func TestBloomFilter(t *testing.T) {
+	t.Parallel()
+
+	const n = 10000
+	f, err := collections.NewBloomFilter(n, 0.01)
+	require.NoError(t, err)
+
+	for i := range n {
+		f.AddString(fmt.Sprintf("0x%064x", i))
+	}
+	for i := range n {
+		require.True(t, f.ContainsString(fmt.Sprintf("0x%064x", i)), "no false negatives")
+	}
+
+	falsePositives := 0
+	for i := n; i < 2*n; i++ {
+		if f.ContainsString(fmt.Sprintf("0x%064x", i)) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, float64(falsePositives)/n, 0.02)
+	assert.InEpsilon(t, n, f.EstimatedCount(), 0.05)
+
+	f.Clear()
+	assert.False(t, f.ContainsString("0x0"))
+	assert.Zero(t, f.EstimatedCount())
+}
+
+func TestBloomFilterMerge(t *testing.T) {
+	t.Parallel()
+
+	a, err := collections.NewBloomFilter(1000, 0.01)
+	require.NoError(t, err)
+	b, err := collections.NewBloomFilter(1000, 0.01)
+	require.NoError(t, err)
+	a.Add([]byte("alice"))
+	b.Add([]byte("bob"))
+
+	require.NoError(t, a.Merge(b))
+	assert.True(t, a.Contains([]byte("alice")))
+	assert.True(t, a.Contains([]byte("bob")))
+	assert.False(t, b.Contains([]byte("alice")))
+
+	c, err := collections.NewBloomFilter(1000, 0.001)
+	require.NoError(t, err)
+	require.ErrorIs(t, a.Merge(c), collections.ErrIncompatible)
+}
+
+func TestBloomFilterBinary(t *testing.T) {
+	t.Parallel()
+
+	f, err := collections.NewBloomFilter(1000, 0.01)
+	require.NoError(t, err)
+	f.AddString("alice", "bob")
+
+	data, err := f.MarshalBinary()
+	require.NoError(t, err)
+
+	var restored collections.BloomFilter
+	require.NoError(t, restored.UnmarshalBinary(data))
+	assert.True(t, restored.ContainsString("alice"))
+	assert.True(t, restored.ContainsString("bob"))
+	assert.False(t, restored.ContainsString("carol"))
+	require.NoError(t, restored.Merge(f), "restored filter has the same parameters")
+
+	require.ErrorIs(t, restored.UnmarshalBinary(nil), collections.ErrInvalidEncoding)
+	require.ErrorIs(t, restored.UnmarshalBinary(data[:len(data)-1]), collections.ErrInvalidEncoding)
+	assert.True(t, restored.ContainsString("alice"), "unchanged by failed unmarshal")
+}
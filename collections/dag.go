@@ -0,0 +1,178 @@
+package collections
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// ErrCycle is returned when adding an edge would create a cycle.
+var ErrCycle = errors.New("edge would create a cycle")
+
+// DAG is a directed acyclic graph, in which an edge from one node to another means that
+// the first must come before the second (eg it is a dependency of the second).
+// Orderings are deterministic, with otherwise unordered nodes kept in the order they were added.
+// It is not safe for concurrent use.
+type DAG[T comparable] struct {
+	index map[T]int
+	nodes []T
+	// out and in hold the indices of the successors and predecessors of each node
+	out [][]int
+	in  [][]int
+}
+
+// NewDAG creates an empty DAG.
+func NewDAG[T comparable]() *DAG[T] {
+	return &DAG[T]{index: make(map[T]int)}
+}
+
+// AddNode adds the nodes, ignoring any already present.
+func (g *DAG[T]) AddNode(nodes ...T) {
+	for _, n := range nodes {
+		g.add(n)
+	}
+}
+
+func (g *DAG[T]) add(n T) int {
+	if i, ok := g.index[n]; ok {
+		return i
+	}
+	i := len(g.nodes)
+	g.index[n] = i
+	g.nodes = append(g.nodes, n)
+	g.out = append(g.out, nil)
+	g.in = append(g.in, nil)
+	return i
+}
+
+// AddEdge adds an edge such that from comes before to, adding either node if not already present.
+// If the edge would create a cycle it is not added, and ErrCycle is returned with the cycle as error context.
+func (g *DAG[T]) AddEdge(from, to T) error {
+	f, t := g.add(from), g.add(to)
+	if slices.Contains(g.out[f], t) {
+		return nil
+	}
+	if path := g.path(t, f); path != nil {
+		cycle := make([]T, 0, len(path)+1)
+		cycle = append(cycle, from)
+		for _, i := range path {
+			cycle = append(cycle, g.nodes[i])
+		}
+		return errcontext.Add(stacktrace.Wrap(ErrCycle), slog.Any("cycle", cycle))
+	}
+	g.out[f] = append(g.out[f], t)
+	g.in[t] = append(g.in[t], f)
+	return nil
+}
+
+// path returns the indices of the nodes along a path from one node to another, or nil if there is none.
+func (g *DAG[T]) path(from, to int) []int {
+	parent := map[int]int{from: from}
+	queue := []int{from}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		if i == to {
+			var path []int
+			for ; i != from; i = parent[i] {
+				path = append(path, i)
+			}
+			path = append(path, from)
+			slices.Reverse(path)
+			return path
+		}
+		for _, j := range g.out[i] {
+			if _, seen := parent[j]; !seen {
+				parent[j] = i
+				queue = append(queue, j)
+			}
+		}
+	}
+	return nil
+}
+
+// Has returns true if the node is in the graph.
+func (g *DAG[T]) Has(n T) bool {
+	_, ok := g.index[n]
+	return ok
+}
+
+// Len returns the number of nodes.
+func (g *DAG[T]) Len() int {
+	return len(g.nodes)
+}
+
+// Nodes returns all nodes in the order they were added.
+func (g *DAG[T]) Nodes() []T {
+	return slices.Clone(g.nodes)
+}
+
+// Dependencies returns the nodes with an edge to n, ie those which must come directly before it.
+func (g *DAG[T]) Dependencies(n T) []T {
+	i, ok := g.index[n]
+	if !ok {
+		return nil
+	}
+	return g.lookup(g.in[i])
+}
+
+// Dependents returns the nodes with an edge from n, ie those which must come directly after it.
+func (g *DAG[T]) Dependents(n T) []T {
+	i, ok := g.index[n]
+	if !ok {
+		return nil
+	}
+	return g.lookup(g.out[i])
+}
+
+func (g *DAG[T]) lookup(indices []int) []T {
+	nodes := make([]T, len(indices))
+	for k, i := range indices {
+		nodes[k] = g.nodes[i]
+	}
+	return nodes
+}
+
+// TopologicalSort returns all nodes such that every node comes after all of its dependencies.
+func (g *DAG[T]) TopologicalSort() []T {
+	sorted := make([]T, 0, len(g.nodes))
+	for _, level := range g.Levels() {
+		sorted = append(sorted, level...)
+	}
+	return sorted
+}
+
+// Levels groups the nodes for parallel execution: every node is in the level after the last of its
+// dependencies, so the nodes within a level do not depend on each other, and each level can be
+// executed once the levels before it have completed. The first level holds the nodes without dependencies.
+func (g *DAG[T]) Levels() [][]T {
+	// Kahn's algorithm, processing each level as a whole
+	remaining := make([]int, len(g.nodes))
+	var current []int
+	for i := range g.nodes {
+		remaining[i] = len(g.in[i])
+		if remaining[i] == 0 {
+			current = append(current, i)
+		}
+	}
+
+	var levels [][]T
+	for len(current) > 0 {
+		levels = append(levels, g.lookup(current))
+		var next []int
+		for _, i := range current {
+			for _, j := range g.out[i] {
+				remaining[j]--
+				if remaining[j] == 0 {
+					next = append(next, j)
+				}
+			}
+		}
+		slices.Sort(next)
+		current = next
+	}
+	return levels
+}
@@ -0,0 +1,112 @@
+package collections_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/collections"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+)
+
+func TestDAGOrdering(t *testing.T) {
+	t.Parallel()
+
+	type edge struct{ from, to string }
+
+	testCases := []struct {
+		name   string
+		nodes  []string
+		edges  []edge
+		levels [][]string
+		sorted []string
+	}{
+		{
+			name:   "empty",
+			levels: nil,
+			sorted: []string{},
+		},
+		{
+			name:   "independent nodes keep insertion order",
+			nodes:  []string{"c", "a", "b"},
+			levels: [][]string{{"c", "a", "b"}},
+			sorted: []string{"c", "a", "b"},
+		},
+		{
+			name:   "chain",
+			edges:  []edge{{"b", "c"}, {"a", "b"}},
+			levels: [][]string{{"a"}, {"b"}, {"c"}},
+			sorted: []string{"a", "b", "c"},
+		},
+		{
+			name:   "diamond",
+			edges:  []edge{{"db", "api"}, {"cache", "api"}, {"config", "db"}, {"config", "cache"}},
+			levels: [][]string{{"config"}, {"db", "cache"}, {"api"}},
+			sorted: []string{"config", "db", "cache", "api"},
+		},
+		{
+			name:   "levels follow the longest path",
+			edges:  []edge{{"a", "b"}, {"b", "c"}, {"a", "c"}, {"d", "c"}},
+			levels: [][]string{{"a", "d"}, {"b"}, {"c"}},
+			sorted: []string{"a", "d", "b", "c"},
+		},
+		{
+			name:   "duplicate edges are ignored",
+			edges:  []edge{{"a", "b"}, {"a", "b"}},
+			levels: [][]string{{"a"}, {"b"}},
+			sorted: []string{"a", "b"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			g := collections.NewDAG[string]()
+			g.AddNode(tc.nodes...)
+			for _, e := range tc.edges {
+				require.NoError(t, g.AddEdge(e.from, e.to))
+			}
+			assert.Equal(t, tc.levels, g.Levels())
+			assert.Equal(t, tc.sorted, g.TopologicalSort())
+		})
+	}
+}
+
+func TestDAGCycle(t *testing.T) {
+	t.Parallel()
+
+	g := collections.NewDAG[string]()
+	require.NoError(t, g.AddEdge("a", "b"))
+	require.NoError(t, g.AddEdge("b", "c"))
+
+	err := g.AddEdge("c", "a")
+	require.ErrorIs(t, err, collections.ErrCycle)
+	ctx := errcontext.Get(err)
+	assert.Equal(t, []string{"c", "a", "b", "c"}, ctx["cycle"].Any())
+
+	require.ErrorIs(t, g.AddEdge("d", "d"), collections.ErrCycle)
+
+	// the rejected edges were not added, though their nodes were
+	assert.Empty(t, g.Dependencies("a"))
+	assert.Equal(t, []string{"a", "d", "b", "c"}, g.TopologicalSort())
+}
+
+func TestDAGNodes(t *testing.T) {
+	t.Parallel()
+
+	g := collections.NewDAG[int]()
+	g.AddNode(1, 2, 2)
+	require.NoError(t, g.AddEdge(1, 3))
+	require.NoError(t, g.AddEdge(2, 3))
+
+	assert.Equal(t, 3, g.Len())
+	assert.Equal(t, []int{1, 2, 3}, g.Nodes())
+	assert.True(t, g.Has(3))
+	assert.False(t, g.Has(4))
+	assert.Equal(t, []int{1, 2}, g.Dependencies(3))
+	assert.Equal(t, []int{3}, g.Dependents(1))
+	assert.Empty(t, g.Dependents(3))
+	assert.Nil(t, g.Dependencies(4))
+}
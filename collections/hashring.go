@@ -0,0 +1,157 @@
+package collections
+
+import (
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+const defaultVirtualNodes = 128
+
+// ConsistentHashRing maps keys to nodes such that adding or removing a node
+// only remaps the keys of that node. Each node is placed on the ring at several
+// virtual positions to spread keys evenly. It is safe for concurrent use.
+//
+// Hashing is stable across processes, so independent instances with the same
+// nodes agree on the mapping.
+type ConsistentHashRing[T comparable] struct {
+	mu           sync.RWMutex
+	virtualNodes int
+	nodeKey      func(T) string
+	hashes       []uint64
+	owners       map[uint64][]T // every node at each position, the first of which owns it
+	nodes        Set[T]
+}
+
+// NewConsistentHashRing creates an empty ring.
+// nodeKey returns a unique and stable name for each node (eg an endpoint URL),
+// and virtualNodes is the number of ring positions per node (a default is used if not positive).
+func NewConsistentHashRing[T comparable](virtualNodes int, nodeKey func(T) string) *ConsistentHashRing[T] {
+	if virtualNodes <= 0 {
+		virtualNodes = defaultVirtualNodes
+	}
+	return &ConsistentHashRing[T]{
+		virtualNodes: virtualNodes,
+		nodeKey:      nodeKey,
+		owners:       map[uint64][]T{},
+		nodes:        NewSet[T](),
+	}
+}
+
+// Add adds nodes to the ring. Nodes already present are ignored.
+func (r *ConsistentHashRing[T]) Add(nodes ...T) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, node := range nodes {
+		if r.nodes.Contains(node) {
+			continue
+		}
+		r.nodes.Add(node)
+		name := r.nodeKey(node)
+		for i := range r.virtualNodes {
+			h := ringHash(name + "#" + strconv.Itoa(i))
+			owners := r.owners[h]
+			if len(owners) == 0 {
+				r.hashes = append(r.hashes, h)
+			}
+			// In the unlikely event of a collision, the node with the lowest key owns the position
+			// regardless of the order nodes were added, and the others take over if it is removed
+			at := slices.IndexFunc(owners, func(owner T) bool { return r.nodeKey(owner) > name })
+			if at < 0 {
+				at = len(owners)
+			}
+			r.owners[h] = slices.Insert(owners, at, node)
+		}
+	}
+	slices.Sort(r.hashes)
+}
+
+// Remove removes nodes from the ring.
+func (r *ConsistentHashRing[T]) Remove(nodes ...T) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	remove := NewSet(nodes...)
+	r.hashes = slices.DeleteFunc(r.hashes, func(h uint64) bool {
+		owners := slices.DeleteFunc(r.owners[h], func(owner T) bool { return remove.Contains(owner) })
+		if len(owners) == 0 {
+			delete(r.owners, h)
+			return true
+		}
+		r.owners[h] = owners
+		return false
+	})
+	r.nodes.Remove(nodes...)
+}
+
+// Get returns the node responsible for the key, or false if the ring is empty.
+func (r *ConsistentHashRing[T]) Get(key string) (T, bool) {
+	nodes := r.GetN(key, 1)
+	if len(nodes) == 0 {
+		var zero T
+		return zero, false
+	}
+	return nodes[0], true
+}
+
+// GetN returns up to n distinct nodes for the key in ring order.
+// The first is the node returned by Get, and the rest are suitable fallbacks or replicas.
+func (r *ConsistentHashRing[T]) GetN(key string, n int) []T {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.hashes) == 0 || n <= 0 {
+		return nil
+	}
+	n = min(n, r.nodes.Size())
+
+	h := ringHash(key)
+	start, _ := slices.BinarySearch(r.hashes, h)
+	result := make([]T, 0, n)
+	seen := NewSet[T]()
+	for i := 0; i < len(r.hashes) && len(result) < n; i++ {
+		node := r.owners[r.hashes[(start+i)%len(r.hashes)]][0]
+		if seen.Contains(node) {
+			continue
+		}
+		seen.Add(node)
+		result = append(result, node)
+	}
+	return result
+}
+
+// Nodes returns the nodes in the ring.
+func (r *ConsistentHashRing[T]) Nodes() []T {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.nodes.Members()
+}
+
+// Len returns the number of nodes in the ring.
+func (r *ConsistentHashRing[T]) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.nodes.Size()
+}
+
+// ringHash hashes a key or virtual node name onto the ring.
+func ringHash(s string) uint64 {
+	return hash64([]byte(s))
+}
+
+// hash64 is FNV-1a followed by a mixing step, since FNV alone
+// distributes similar short inputs poorly. It is stable across processes.
+func hash64(b []byte) uint64 {
+	f := fnv.New64a()
+	_, _ = f.Write(b)
+	return mix64(f.Sum64())
+}
+
+// mix64 is the 64 bit finalizer of MurmurHash3.
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
@@ -0,0 +1,107 @@
+package collections_test
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/collections"
+)
+
+func identity(s string) string { return s }
+
+func TestConsistentHashRingEmpty(t *testing.T) {
+	t.Parallel()
+
+	r := collections.NewConsistentHashRing(0, identity)
+	_, ok := r.Get("key")
+	assert.False(t, ok)
+	assert.Empty(t, r.GetN("key", 3))
+	assert.Zero(t, r.Len())
+}
+
+func TestConsistentHashRing(t *testing.T) {
+	t.Parallel()
+
+	nodes := []string{"node-a", "node-b", "node-c", "node-d"}
+	r := collections.NewConsistentHashRing(0, identity)
+	r.Add(nodes...)
+	r.Add("node-a") // duplicates are ignored
+	assert.ElementsMatch(t, nodes, r.Nodes())
+	assert.Equal(t, 4, r.Len())
+
+	// keys are spread over all nodes roughly evenly
+	const keys = 10000
+	before := map[string]string{}
+	counts := map[string]int{}
+	for i := range keys {
+		key := "key-" + strconv.Itoa(i)
+		node, ok := r.Get(key)
+		require.True(t, ok)
+		before[key] = node
+		counts[node]++
+	}
+	for _, n := range nodes {
+		assert.InDelta(t, keys/len(nodes), counts[n], keys/10, n)
+	}
+
+	// the mapping is stable across instances
+	r2 := collections.NewConsistentHashRing(0, identity)
+	r2.Add("node-d", "node-c", "node-b", "node-a")
+	for key, node := range before {
+		got, _ := r2.Get(key)
+		assert.Equal(t, node, got)
+	}
+
+	// removing a node only remaps the keys it owned
+	r.Remove("node-b")
+	for key, node := range before {
+		got, _ := r.Get(key)
+		if node == "node-b" {
+			assert.NotEqual(t, "node-b", got)
+		} else {
+			assert.Equal(t, node, got, key)
+		}
+	}
+}
+
+func TestConsistentHashRingGetN(t *testing.T) {
+	t.Parallel()
+
+	r := collections.NewConsistentHashRing(16, func(i int) string { return fmt.Sprintf("node-%d", i) })
+	r.Add(1, 2, 3)
+
+	for i := range 100 {
+		key := strconv.Itoa(i)
+		first, _ := r.Get(key)
+		replicas := r.GetN(key, 5)
+		require.Len(t, replicas, 3)
+		assert.Equal(t, first, replicas[0])
+		assert.ElementsMatch(t, []int{1, 2, 3}, replicas)
+	}
+}
+
+func TestConsistentHashRingCollision(t *testing.T) {
+	t.Parallel()
+
+	// every position of both nodes collides, as they have the same key
+	r := collections.NewConsistentHashRing(8, func(int) string { return "shared" })
+	r.Add(1, 2)
+	node, ok := r.Get("key")
+	require.True(t, ok)
+	assert.Equal(t, 1, node)
+
+	// the positions are restored to the other node once the owner is removed
+	r.Remove(1)
+	node, ok = r.Get("key")
+	require.True(t, ok)
+	assert.Equal(t, 2, node)
+	assert.Equal(t, []int{2}, r.GetN("key", 2))
+
+	r.Remove(2)
+	_, ok = r.Get("key")
+	assert.False(t, ok)
+}
@@ -0,0 +1,155 @@
+package collections
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+	"sync"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const (
+	// hllEncodingVersion is the first byte of an encoded HyperLogLog.
+	hllEncodingVersion = 1
+
+	// MinHyperLogLogPrecision and MaxHyperLogLogPrecision bound the precision of a HyperLogLog.
+	MinHyperLogLogPrecision = 4
+	MaxHyperLogLogPrecision = 18
+)
+
+var ErrInvalidPrecision = errors.New("precision must be between 4 and 18")
+
+// HyperLogLog estimates the number of distinct items added to it using a fixed amount of memory:
+// 2^precision bytes, for a standard error of 1.04/sqrt(2^precision)
+// (eg 16KiB and 0.8% for a precision of 14). It is safe for concurrent use.
+//
+// Hashing is stable across processes, so a sketch may be persisted (see MarshalBinary) and
+// later restored or merged by another instance, eg to count across shards or time windows.
+// Create it with NewHyperLogLog, or restore the zero value with UnmarshalBinary.
+type HyperLogLog struct {
+	mu        sync.RWMutex
+	precision uint8
+	registers []uint8
+}
+
+// NewHyperLogLog creates an empty HyperLogLog with 2^precision registers.
+func NewHyperLogLog(precision uint8) (*HyperLogLog, error) {
+	if precision < MinHyperLogLogPrecision || precision > MaxHyperLogLogPrecision {
+		return nil, stacktrace.Wrap(ErrInvalidPrecision)
+	}
+	return &HyperLogLog{
+		precision: precision,
+		registers: make([]uint8, 1<<precision),
+	}, nil
+}
+
+// Add adds the items to the sketch.
+func (h *HyperLogLog) Add(items ...[]byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, item := range items {
+		x := hash64(item)
+		// the first bits select the register, and the rest give the rank
+		idx := x >> (64 - h.precision)
+		rank := uint8(bits.LeadingZeros64(x<<h.precision|1<<(h.precision-1))) + 1
+		h.registers[idx] = max(h.registers[idx], rank)
+	}
+}
+
+// AddString adds the items to the sketch.
+func (h *HyperLogLog) AddString(items ...string) {
+	for _, item := range items {
+		h.Add([]byte(item))
+	}
+}
+
+// Count estimates the number of distinct items added to the sketch.
+func (h *HyperLogLog) Count() uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	m := float64(len(h.registers))
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	var alpha float64
+	switch len(h.registers) {
+	case 16:
+		alpha = 0.673
+	case 32:
+		alpha = 0.697
+	case 64:
+		alpha = 0.709
+	default:
+		alpha = 0.7213 / (1 + 1.079/m)
+	}
+	estimate := alpha * m * m / sum
+	// use linear counting for small cardinalities, where the raw estimate is biased
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(math.Round(estimate))
+}
+
+// Merge adds all items of other to the sketch, which must have the same precision.
+func (h *HyperLogLog) Merge(other *HyperLogLog) error {
+	if h == other {
+		return nil
+	}
+	// copy other first rather than holding both locks, which could deadlock with other.Merge(h)
+	other.mu.RLock()
+	precision, registers := other.precision, slices.Clone(other.registers)
+	other.mu.RUnlock()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.precision != precision {
+		return stacktrace.Wrap(ErrIncompatible)
+	}
+	for i, r := range registers {
+		h.registers[i] = max(h.registers[i], r)
+	}
+	return nil
+}
+
+// Clear removes all items from the sketch.
+func (h *HyperLogLog) Clear() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	clear(h.registers)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (h *HyperLogLog) MarshalBinary() ([]byte, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	data := make([]byte, 0, 2+len(h.registers))
+	data = append(data, hllEncodingVersion, h.precision)
+	return append(data, h.registers...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, replacing the contents of the sketch.
+func (h *HyperLogLog) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 || data[0] != hllEncodingVersion {
+		return stacktrace.Wrap(fmt.Errorf("hyperloglog: %w", ErrInvalidEncoding))
+	}
+	precision := data[1]
+	if precision < MinHyperLogLogPrecision || precision > MaxHyperLogLogPrecision ||
+		len(data)-2 != 1<<precision || slices.ContainsFunc(data[2:], func(r uint8) bool { return r > 65-precision }) {
+		return stacktrace.Wrap(fmt.Errorf("hyperloglog: %w", ErrInvalidEncoding))
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.precision = precision
+	h.registers = slices.Clone(data[2:])
+	return nil
+}
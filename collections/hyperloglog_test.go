@@ -0,0 +1,88 @@
+package collections_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/collections"
+)
+
+func TestNewHyperLogLogErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := collections.NewHyperLogLog(collections.MinHyperLogLogPrecision - 1)
+	require.ErrorIs(t, err, collections.ErrInvalidPrecision)
+	_, err = collections.NewHyperLogLog(collections.MaxHyperLogLogPrecision + 1)
+	require.ErrorIs(t, err, collections.ErrInvalidPrecision)
+}
+
+func TestHyperLogLog(t *testing.T) {
+	t.Parallel()
+
+	for _, n := range []int{0, 10, 1000, 100000} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			t.Parallel()
+
+			h, err := collections.NewHyperLogLog(14)
+			require.NoError(t, err)
+			for i := range n {
+				address := fmt.Sprintf("0x%040x", i)
+				h.AddString(address, address) // duplicates are not counted
+			}
+			if n == 0 {
+				assert.Zero(t, h.Count())
+				return
+			}
+			// the standard error at precision 14 is 0.8%
+			assert.InEpsilon(t, n, h.Count(), 0.03)
+		})
+	}
+}
+
+func TestHyperLogLogMerge(t *testing.T) {
+	t.Parallel()
+
+	a, err := collections.NewHyperLogLog(12)
+	require.NoError(t, err)
+	b, err := collections.NewHyperLogLog(12)
+	require.NoError(t, err)
+	for i := range 20000 {
+		a.AddString(fmt.Sprint(i))
+		b.AddString(fmt.Sprint(i + 10000)) // overlapping half
+	}
+
+	require.NoError(t, a.Merge(b))
+	assert.InEpsilon(t, 30000, a.Count(), 0.05)
+
+	c, err := collections.NewHyperLogLog(10)
+	require.NoError(t, err)
+	require.ErrorIs(t, a.Merge(c), collections.ErrIncompatible)
+
+	a.Clear()
+	assert.Zero(t, a.Count())
+}
+
+func TestHyperLogLogBinary(t *testing.T) {
+	t.Parallel()
+
+	h, err := collections.NewHyperLogLog(10)
+	require.NoError(t, err)
+	for i := range 500 {
+		h.Add([]byte{byte(i), byte(i >> 8)})
+	}
+
+	data, err := h.MarshalBinary()
+	require.NoError(t, err)
+	assert.Len(t, data, 2+1<<10)
+
+	var restored collections.HyperLogLog
+	require.NoError(t, restored.UnmarshalBinary(data))
+	assert.Equal(t, h.Count(), restored.Count())
+
+	require.ErrorIs(t, restored.UnmarshalBinary(data[:100]), collections.ErrInvalidEncoding)
+	data[1] = 30
+	require.ErrorIs(t, restored.UnmarshalBinary(data), collections.ErrInvalidEncoding)
+}
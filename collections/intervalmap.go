@@ -0,0 +1,160 @@
+package collections
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+)
+
+// Span is the half-open range [Start, End).
+type Span[K cmp.Ordered] struct {
+	Start K
+	End   K
+}
+
+// Contains returns true if k is within the span.
+func (s Span[K]) Contains(k K) bool {
+	return s.Start <= k && k < s.End
+}
+
+// Overlaps returns true if the spans have any key in common.
+func (s Span[K]) Overlaps(other Span[K]) bool {
+	return s.Start < other.End && other.Start < s.End
+}
+
+// Empty returns true if the span contains no keys.
+func (s Span[K]) Empty() bool {
+	return s.Start >= s.End
+}
+
+// Interval is a span of keys mapped to a value.
+type Interval[K cmp.Ordered, V any] struct {
+	Span[K]
+	Value V
+}
+
+// IntervalMap maps non-overlapping half-open ranges of keys (eg block numbers or unix timestamps) to values.
+// Setting a range overwrites any overlapping parts of existing ranges, and adjacent ranges with equal
+// values are merged. Lookups are O(log n) using binary search over the sorted ranges.
+// It is not safe for concurrent use.
+type IntervalMap[K cmp.Ordered, V comparable] struct {
+	intervals []Interval[K, V]
+}
+
+// NewIntervalMap creates an empty IntervalMap.
+func NewIntervalMap[K cmp.Ordered, V comparable]() *IntervalMap[K, V] {
+	return &IntervalMap[K, V]{}
+}
+
+// search returns the index of the first interval ending after k,
+// which is the only interval that could contain k.
+func (m *IntervalMap[K, V]) search(k K) int {
+	i, _ := slices.BinarySearchFunc(m.intervals, k, func(iv Interval[K, V], k K) int {
+		if iv.End <= k {
+			return -1
+		}
+		return 1
+	})
+	return i
+}
+
+// Set maps the range [start, end) to value. It does nothing if the range is empty.
+func (m *IntervalMap[K, V]) Set(start, end K, value V) {
+	if start >= end {
+		return
+	}
+	m.Delete(start, end)
+
+	// after deletion, every interval before i ends at or before start, and every interval from i starts at or after end
+	i := m.search(start)
+	m.intervals = slices.Insert(m.intervals, i, Interval[K, V]{Span: Span[K]{Start: start, End: end}, Value: value})
+
+	// merge with adjacent intervals of equal value
+	if i+1 < len(m.intervals) && m.intervals[i+1].Start == end && m.intervals[i+1].Value == value {
+		m.intervals[i].End = m.intervals[i+1].End
+		m.intervals = slices.Delete(m.intervals, i+1, i+2)
+	}
+	if i > 0 && m.intervals[i-1].End == start && m.intervals[i-1].Value == value {
+		m.intervals[i-1].End = m.intervals[i].End
+		m.intervals = slices.Delete(m.intervals, i, i+1)
+	}
+}
+
+// Delete removes the range [start, end), trimming or splitting any intervals which overlap it.
+func (m *IntervalMap[K, V]) Delete(start, end K) {
+	if start >= end {
+		return
+	}
+
+	i := m.search(start)
+	j := i
+	for j < len(m.intervals) && m.intervals[j].Start < end {
+		j++
+	}
+	if i == j {
+		return
+	}
+
+	var remaining []Interval[K, V]
+	if first := m.intervals[i]; first.Start < start {
+		remaining = append(remaining, Interval[K, V]{Span: Span[K]{Start: first.Start, End: start}, Value: first.Value})
+	}
+	if last := m.intervals[j-1]; last.End > end {
+		remaining = append(remaining, Interval[K, V]{Span: Span[K]{Start: end, End: last.End}, Value: last.Value})
+	}
+	m.intervals = slices.Replace(m.intervals, i, j, remaining...)
+}
+
+// Get returns the value mapped to k, if any.
+func (m *IntervalMap[K, V]) Get(k K) (V, bool) {
+	if i := m.search(k); i < len(m.intervals) && m.intervals[i].Contains(k) {
+		return m.intervals[i].Value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Overlapping returns the intervals overlapping [start, end) in order, clipped to that range.
+func (m *IntervalMap[K, V]) Overlapping(start, end K) []Interval[K, V] {
+	var result []Interval[K, V]
+	for i := m.search(start); i < len(m.intervals) && m.intervals[i].Start < end; i++ {
+		iv := m.intervals[i]
+		iv.Start = max(iv.Start, start)
+		iv.End = min(iv.End, end)
+		if !iv.Empty() {
+			result = append(result, iv)
+		}
+	}
+	return result
+}
+
+// Gaps returns the parts of [start, end) which are not mapped to any value, in order.
+func (m *IntervalMap[K, V]) Gaps(start, end K) []Span[K] {
+	var gaps []Span[K]
+	cursor := start
+	for _, iv := range m.Overlapping(start, end) {
+		if iv.Start > cursor {
+			gaps = append(gaps, Span[K]{Start: cursor, End: iv.Start})
+		}
+		cursor = iv.End
+	}
+	if cursor < end {
+		gaps = append(gaps, Span[K]{Start: cursor, End: end})
+	}
+	return gaps
+}
+
+// Covers returns true if every key in [start, end) is mapped to a value.
+func (m *IntervalMap[K, V]) Covers(start, end K) bool {
+	return len(m.Gaps(start, end)) == 0
+}
+
+// All returns an iterator over all intervals in order.
+func (m *IntervalMap[K, V]) All() iter.Seq[Interval[K, V]] {
+	return slices.Values(m.intervals)
+}
+
+// Len returns the number of (merged) intervals.
+func (m *IntervalMap[K, V]) Len() int {
+	return len(m.intervals)
+}
@@ -0,0 +1,161 @@
+package collections_test
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zircuit-labs/zkr-go-common/collections"
+)
+
+func interval[V any](start, end int, value V) collections.Interval[int, V] {
+	return collections.Interval[int, V]{Span: collections.Span[int]{Start: start, End: end}, Value: value}
+}
+
+func TestIntervalMapSet(t *testing.T) {
+	t.Parallel()
+
+	type set struct {
+		start, end int
+		value      string
+	}
+
+	testCases := []struct {
+		name     string
+		sets     []set
+		expected []collections.Interval[int, string]
+	}{
+		{
+			name:     "empty range is ignored",
+			sets:     []set{{5, 5, "a"}, {6, 5, "a"}},
+			expected: nil,
+		},
+		{
+			name:     "disjoint",
+			sets:     []set{{10, 20, "b"}, {0, 5, "a"}},
+			expected: []collections.Interval[int, string]{interval(0, 5, "a"), interval(10, 20, "b")},
+		},
+		{
+			name:     "adjacent equal values merge",
+			sets:     []set{{0, 5, "a"}, {10, 15, "a"}, {5, 10, "a"}},
+			expected: []collections.Interval[int, string]{interval(0, 15, "a")},
+		},
+		{
+			name:     "adjacent different values do not merge",
+			sets:     []set{{0, 5, "a"}, {5, 10, "b"}},
+			expected: []collections.Interval[int, string]{interval(0, 5, "a"), interval(5, 10, "b")},
+		},
+		{
+			name:     "overwrite splits",
+			sets:     []set{{0, 20, "a"}, {5, 10, "b"}},
+			expected: []collections.Interval[int, string]{interval(0, 5, "a"), interval(5, 10, "b"), interval(10, 20, "a")},
+		},
+		{
+			name:     "overwrite spans several",
+			sets:     []set{{0, 5, "a"}, {6, 8, "b"}, {9, 12, "c"}, {3, 10, "d"}},
+			expected: []collections.Interval[int, string]{interval(0, 3, "a"), interval(3, 10, "d"), interval(10, 12, "c")},
+		},
+		{
+			name:     "overlapping equal values merge",
+			sets:     []set{{0, 5, "a"}, {3, 8, "a"}},
+			expected: []collections.Interval[int, string]{interval(0, 8, "a")},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			m := collections.NewIntervalMap[int, string]()
+			for _, s := range tc.sets {
+				m.Set(s.start, s.end, s.value)
+			}
+			assert.Equal(t, tc.expected, slices.Collect(m.All()))
+			assert.Equal(t, len(tc.expected), m.Len())
+		})
+	}
+}
+
+func TestIntervalMapQueries(t *testing.T) {
+	t.Parallel()
+
+	// proved block ranges
+	m := collections.NewIntervalMap[uint64, bool]()
+	m.Set(100, 200, true)
+	m.Set(300, 400, true)
+
+	v, ok := m.Get(100)
+	assert.True(t, ok)
+	assert.True(t, v)
+	_, ok = m.Get(200)
+	assert.False(t, ok)
+	_, ok = m.Get(99)
+	assert.False(t, ok)
+
+	overlapping := m.Overlapping(150, 350)
+	assert.Equal(t, []collections.Interval[uint64, bool]{
+		{Span: collections.Span[uint64]{Start: 150, End: 200}, Value: true},
+		{Span: collections.Span[uint64]{Start: 300, End: 350}, Value: true},
+	}, overlapping)
+	assert.Empty(t, m.Overlapping(200, 300))
+
+	assert.Equal(t, []collections.Span[uint64]{{Start: 50, End: 100}, {Start: 200, End: 300}, {Start: 400, End: 450}}, m.Gaps(50, 450))
+	assert.True(t, m.Covers(120, 180))
+	assert.False(t, m.Covers(120, 320))
+
+	m.Delete(150, 350)
+	assert.Equal(t, []collections.Interval[uint64, bool]{
+		{Span: collections.Span[uint64]{Start: 100, End: 150}, Value: true},
+		{Span: collections.Span[uint64]{Start: 350, End: 400}, Value: true},
+	}, slices.Collect(m.All()))
+
+	// filling the gap merges everything
+	m.Set(150, 350, true)
+	assert.Equal(t, 1, m.Len())
+	assert.True(t, m.Covers(100, 400))
+}
+
+func TestIntervalMapRandom(t *testing.T) {
+	t.Parallel()
+
+	const size = 100
+	r := rand.New(rand.NewPCG(1, 2)) //nolint:gosec // deterministic test data
+	m := collections.NewIntervalMap[int, int]()
+	var naive [size]int // 0 means unset
+
+	for range 1000 {
+		start, end := r.IntN(size), r.IntN(size)
+		if r.IntN(4) == 0 {
+			m.Delete(start, end)
+			for k := start; k < end; k++ {
+				naive[k] = 0
+			}
+			continue
+		}
+		value := 1 + r.IntN(3)
+		m.Set(start, end, value)
+		for k := start; k < end; k++ {
+			naive[k] = value
+		}
+	}
+
+	for k := range size {
+		v, ok := m.Get(k)
+		assert.Equal(t, naive[k] != 0, ok, k)
+		assert.Equal(t, naive[k], v, k)
+	}
+
+	// intervals are sorted, non-empty, non-overlapping, and merged
+	intervals := slices.Collect(m.All())
+	for i, iv := range intervals {
+		assert.False(t, iv.Empty())
+		if i > 0 {
+			prev := intervals[i-1]
+			assert.LessOrEqual(t, prev.End, iv.Start)
+			if prev.End == iv.Start {
+				assert.NotEqual(t, prev.Value, iv.Value)
+			}
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package collections
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+)
+
+// sortedMapDegree is the minimum degree of the B-tree: every node other than the root
+// holds between sortedMapDegree-1 and 2*sortedMapDegree-1 entries.
+const sortedMapDegree = 32
+
+const maxNodeEntries = 2*sortedMapDegree - 1
+
+type sortedMapEntry[K cmp.Ordered, V any] struct {
+	key   K
+	value V
+}
+
+// btreeNode is a node of the B-tree. It is a leaf if it has no children,
+// otherwise it has exactly one more child than entries.
+type btreeNode[K cmp.Ordered, V any] struct {
+	entries  []sortedMapEntry[K, V]
+	children []*btreeNode[K, V]
+}
+
+// SortedMap maps keys to values, keeping the keys in order (eg block numbers or timestamps) so that
+// iteration, floor and ceiling lookups, and range scans need no sorting. It is backed by a B-tree,
+// giving O(log n) Get, Set and Delete with good memory locality.
+// It is not safe for concurrent use, and must not be modified while iterating over it.
+type SortedMap[K cmp.Ordered, V any] struct {
+	root *btreeNode[K, V]
+	size int
+}
+
+// NewSortedMap creates an empty SortedMap.
+func NewSortedMap[K cmp.Ordered, V any]() *SortedMap[K, V] {
+	return &SortedMap[K, V]{}
+}
+
+// Len returns the number of keys in the map.
+func (m *SortedMap[K, V]) Len() int {
+	return m.size
+}
+
+// Get returns the value mapped to k, if any.
+func (m *SortedMap[K, V]) Get(k K) (V, bool) {
+	for n := m.root; n != nil; {
+		i, found := n.find(k)
+		if found {
+			return n.entries[i].value, true
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+	var zero V
+	return zero, false
+}
+
+// Set maps k to value, replacing any existing value.
+func (m *SortedMap[K, V]) Set(k K, value V) {
+	if m.root == nil {
+		m.root = &btreeNode[K, V]{}
+	}
+	// split a full root in advance, so that insertion never has to back up the tree
+	if len(m.root.entries) == maxNodeEntries {
+		m.root = &btreeNode[K, V]{children: []*btreeNode[K, V]{m.root}}
+		m.root.splitChild(0)
+	}
+	if m.root.insert(k, value) {
+		m.size++
+	}
+}
+
+// Delete removes k from the map, returning true if it was present.
+func (m *SortedMap[K, V]) Delete(k K) bool {
+	if m.root == nil {
+		return false
+	}
+	deleted := m.root.delete(k)
+	if len(m.root.entries) == 0 {
+		if m.root.leaf() {
+			m.root = nil
+		} else {
+			m.root = m.root.children[0]
+		}
+	}
+	if deleted {
+		m.size--
+	}
+	return deleted
+}
+
+// Min returns the smallest key and its value, if the map is not empty.
+func (m *SortedMap[K, V]) Min() (K, V, bool) {
+	if m.root == nil {
+		return none[K, V]()
+	}
+	n := m.root
+	for !n.leaf() {
+		n = n.children[0]
+	}
+	return n.entries[0].key, n.entries[0].value, true
+}
+
+// Max returns the largest key and its value, if the map is not empty.
+func (m *SortedMap[K, V]) Max() (K, V, bool) {
+	if m.root == nil {
+		return none[K, V]()
+	}
+	n := m.root
+	for !n.leaf() {
+		n = n.children[len(n.children)-1]
+	}
+	e := n.entries[len(n.entries)-1]
+	return e.key, e.value, true
+}
+
+// Floor returns the largest key less than or equal to k and its value, if there is one.
+func (m *SortedMap[K, V]) Floor(k K) (K, V, bool) {
+	var floor *sortedMapEntry[K, V]
+	for n := m.root; n != nil; {
+		i, found := n.find(k)
+		if found {
+			return n.entries[i].key, n.entries[i].value, true
+		}
+		if i > 0 {
+			floor = &n.entries[i-1]
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+	if floor == nil {
+		return none[K, V]()
+	}
+	return floor.key, floor.value, true
+}
+
+// Ceiling returns the smallest key greater than or equal to k and its value, if there is one.
+func (m *SortedMap[K, V]) Ceiling(k K) (K, V, bool) {
+	var ceiling *sortedMapEntry[K, V]
+	for n := m.root; n != nil; {
+		i, found := n.find(k)
+		if found {
+			return n.entries[i].key, n.entries[i].value, true
+		}
+		if i < len(n.entries) {
+			ceiling = &n.entries[i]
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+	if ceiling == nil {
+		return none[K, V]()
+	}
+	return ceiling.key, ceiling.value, true
+}
+
+// All returns an iterator over all keys and their values in ascending order of key.
+func (m *SortedMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if m.root != nil {
+			var zero K
+			m.root.ascend(zero, false, yield)
+		}
+	}
+}
+
+// Backward returns an iterator over all keys and their values in descending order of key.
+func (m *SortedMap[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if m.root != nil {
+			m.root.descend(yield)
+		}
+	}
+}
+
+// Keys returns an iterator over all keys in ascending order.
+func (m *SortedMap[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for k := range m.All() {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// Range returns an iterator over the keys in the half-open range [start, end) and their values, in ascending order.
+func (m *SortedMap[K, V]) Range(start, end K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if m.root == nil || start >= end {
+			return
+		}
+		m.root.ascend(start, true, func(k K, v V) bool {
+			return k < end && yield(k, v)
+		})
+	}
+}
+
+func none[K cmp.Ordered, V any]() (K, V, bool) {
+	var k K
+	var v V
+	return k, v, false
+}
+
+func (n *btreeNode[K, V]) leaf() bool {
+	return len(n.children) == 0
+}
+
+// find returns the index of the first entry with a key not less than k, and whether it is equal to k.
+func (n *btreeNode[K, V]) find(k K) (int, bool) {
+	return slices.BinarySearchFunc(n.entries, k, func(e sortedMapEntry[K, V], k K) int {
+		return cmp.Compare(e.key, k)
+	})
+}
+
+// splitChild splits the full child i around its middle entry, which moves up into n.
+func (n *btreeNode[K, V]) splitChild(i int) {
+	child := n.children[i]
+	middle := child.entries[sortedMapDegree-1]
+	right := &btreeNode[K, V]{entries: slices.Clone(child.entries[sortedMapDegree:])}
+	child.entries = slices.Delete(child.entries, sortedMapDegree-1, len(child.entries))
+	if !child.leaf() {
+		right.children = slices.Clone(child.children[sortedMapDegree:])
+		child.children = slices.Delete(child.children, sortedMapDegree, len(child.children))
+	}
+	n.entries = slices.Insert(n.entries, i, middle)
+	n.children = slices.Insert(n.children, i+1, right)
+}
+
+// insert sets k to value within the subtree of n, which must not be full, returning true if k is new.
+func (n *btreeNode[K, V]) insert(k K, value V) bool {
+	i, found := n.find(k)
+	if found {
+		n.entries[i].value = value
+		return false
+	}
+	if n.leaf() {
+		n.entries = slices.Insert(n.entries, i, sortedMapEntry[K, V]{key: k, value: value})
+		return true
+	}
+	if len(n.children[i].entries) == maxNodeEntries {
+		n.splitChild(i)
+		switch c := cmp.Compare(k, n.entries[i].key); {
+		case c == 0:
+			n.entries[i].value = value
+			return false
+		case c > 0:
+			i++
+		}
+	}
+	return n.children[i].insert(k, value)
+}
+
+// delete removes k from the subtree of n, which must have more than the minimum number of entries
+// unless it is the root, returning true if k was present.
+func (n *btreeNode[K, V]) delete(k K) bool {
+	i, found := n.find(k)
+	if n.leaf() {
+		if found {
+			n.entries = slices.Delete(n.entries, i, i+1)
+		}
+		return found
+	}
+
+	if found {
+		// replace the entry with its predecessor or successor from a child which can spare one,
+		// otherwise merge the children around it and delete from the result
+		switch {
+		case len(n.children[i].entries) >= sortedMapDegree:
+			n.entries[i] = n.children[i].last()
+			return n.children[i].delete(n.entries[i].key)
+		case len(n.children[i+1].entries) >= sortedMapDegree:
+			n.entries[i] = n.children[i+1].first()
+			return n.children[i+1].delete(n.entries[i].key)
+		default:
+			n.merge(i)
+			return n.children[i].delete(k)
+		}
+	}
+
+	// make sure the child descended into can spare an entry
+	if len(n.children[i].entries) < sortedMapDegree {
+		i = n.fill(i)
+	}
+	return n.children[i].delete(k)
+}
+
+// fill gives child i more than the minimum number of entries, by borrowing from a sibling or merging with one.
+// It returns the index of the child which now holds the keys of child i.
+func (n *btreeNode[K, V]) fill(i int) int {
+	switch {
+	case i > 0 && len(n.children[i-1].entries) >= sortedMapDegree:
+		child, left := n.children[i], n.children[i-1]
+		child.entries = slices.Insert(child.entries, 0, n.entries[i-1])
+		n.entries[i-1] = left.entries[len(left.entries)-1]
+		left.entries = slices.Delete(left.entries, len(left.entries)-1, len(left.entries))
+		if !left.leaf() {
+			child.children = slices.Insert(child.children, 0, left.children[len(left.children)-1])
+			left.children = slices.Delete(left.children, len(left.children)-1, len(left.children))
+		}
+		return i
+	case i < len(n.entries) && len(n.children[i+1].entries) >= sortedMapDegree:
+		child, right := n.children[i], n.children[i+1]
+		child.entries = append(child.entries, n.entries[i])
+		n.entries[i] = right.entries[0]
+		right.entries = slices.Delete(right.entries, 0, 1)
+		if !right.leaf() {
+			child.children = append(child.children, right.children[0])
+			right.children = slices.Delete(right.children, 0, 1)
+		}
+		return i
+	case i < len(n.entries):
+		n.merge(i)
+		return i
+	default:
+		n.merge(i - 1)
+		return i - 1
+	}
+}
+
+// merge combines child i, entry i and child i+1 into child i.
+func (n *btreeNode[K, V]) merge(i int) {
+	left, right := n.children[i], n.children[i+1]
+	left.entries = append(append(left.entries, n.entries[i]), right.entries...)
+	left.children = append(left.children, right.children...)
+	n.entries = slices.Delete(n.entries, i, i+1)
+	n.children = slices.Delete(n.children, i+1, i+2)
+}
+
+func (n *btreeNode[K, V]) first() sortedMapEntry[K, V] {
+	for !n.leaf() {
+		n = n.children[0]
+	}
+	return n.entries[0]
+}
+
+func (n *btreeNode[K, V]) last() sortedMapEntry[K, V] {
+	for !n.leaf() {
+		n = n.children[len(n.children)-1]
+	}
+	return n.entries[len(n.entries)-1]
+}
+
+// ascend yields the entries of the subtree in ascending order, starting from start if bounded,
+// returning false if yield stopped the iteration.
+func (n *btreeNode[K, V]) ascend(start K, bounded bool, yield func(K, V) bool) bool {
+	i := 0
+	if bounded {
+		i, _ = n.find(start)
+	}
+	for ; i < len(n.entries); i++ {
+		if !n.leaf() && !n.children[i].ascend(start, bounded, yield) {
+			return false
+		}
+		if !yield(n.entries[i].key, n.entries[i].value) {
+			return false
+		}
+	}
+	if !n.leaf() {
+		return n.children[len(n.entries)].ascend(start, bounded, yield)
+	}
+	return true
+}
+
+// descend yields the entries of the subtree in descending order, returning false if yield stopped the iteration.
+func (n *btreeNode[K, V]) descend(yield func(K, V) bool) bool {
+	for i := len(n.entries) - 1; i >= 0; i-- {
+		if !n.leaf() && !n.children[i+1].descend(yield) {
+			return false
+		}
+		if !yield(n.entries[i].key, n.entries[i].value) {
+			return false
+		}
+	}
+	if !n.leaf() {
+		return n.children[0].descend(yield)
+	}
+	return true
+}
@@ -0,0 +1,147 @@
+package collections_test
+
+import (
+	"iter"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/collections"
+)
+
+func keysOf[K, V any](seq iter.Seq2[K, V]) []K {
+	keys := []K{}
+	for k := range seq {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func TestSortedMapQueries(t *testing.T) {
+	t.Parallel()
+
+	m := collections.NewSortedMap[uint64, string]()
+	_, _, ok := m.Min()
+	assert.False(t, ok)
+	_, _, ok = m.Floor(10)
+	assert.False(t, ok)
+	assert.Empty(t, slices.Collect(m.Keys()))
+	assert.False(t, m.Delete(10))
+
+	for _, block := range []uint64{30, 10, 50, 20, 40} {
+		m.Set(block, "block")
+	}
+	m.Set(20, "replaced")
+	assert.Equal(t, 5, m.Len())
+
+	v, ok := m.Get(20)
+	assert.True(t, ok)
+	assert.Equal(t, "replaced", v)
+	_, ok = m.Get(25)
+	assert.False(t, ok)
+
+	k, _, ok := m.Min()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(10), k)
+	k, _, ok = m.Max()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(50), k)
+
+	testCases := []struct {
+		key     uint64
+		floor   uint64
+		ceiling uint64
+	}{
+		{key: 5, ceiling: 10},
+		{key: 10, floor: 10, ceiling: 10},
+		{key: 25, floor: 20, ceiling: 30},
+		{key: 55, floor: 50},
+	}
+	for _, tc := range testCases {
+		k, _, ok := m.Floor(tc.key)
+		assert.Equal(t, tc.floor != 0, ok, tc.key)
+		assert.Equal(t, tc.floor, k, tc.key)
+		k, _, ok = m.Ceiling(tc.key)
+		assert.Equal(t, tc.ceiling != 0, ok, tc.key)
+		assert.Equal(t, tc.ceiling, k, tc.key)
+	}
+
+	assert.Equal(t, []uint64{20, 30, 40}, keysOf(m.Range(15, 50)))
+	assert.Empty(t, keysOf(m.Range(30, 30)))
+	assert.Equal(t, []uint64{50, 40, 30, 20, 10}, keysOf(m.Backward()))
+
+	// iteration can stop early
+	for k := range m.All() {
+		if k == 20 {
+			break
+		}
+	}
+
+	assert.True(t, m.Delete(30))
+	assert.False(t, m.Delete(30))
+	assert.Equal(t, []uint64{10, 20, 40, 50}, slices.Collect(m.Keys()))
+}
+
+func TestSortedMapRandom(t *testing.T) {
+	t.Parallel()
+
+	const size = 5000
+	r := rand.New(rand.NewPCG(1, 2)) //nolint:gosec // deterministic test data
+	m := collections.NewSortedMap[int, int]()
+	naive := map[int]int{}
+
+	// enough operations to grow the tree several levels deep and shrink it again
+	for i := range 50000 {
+		k := r.IntN(size)
+		if i > 30000 || r.IntN(3) == 0 {
+			_, exists := naive[k]
+			assert.Equal(t, exists, m.Delete(k), k)
+			delete(naive, k)
+			continue
+		}
+		m.Set(k, i)
+		naive[k] = i
+	}
+	require.Equal(t, len(naive), m.Len())
+
+	keys := slices.Sorted(maps.Keys(naive))
+	assert.Equal(t, keys, slices.Collect(m.Keys()))
+	for k, v := range m.All() {
+		assert.Equal(t, naive[k], v, k)
+	}
+
+	for range 1000 {
+		k := r.IntN(size)
+		i, found := slices.BinarySearch(keys, k)
+		floor, _, ok := m.Floor(k)
+		switch {
+		case found:
+			assert.Equal(t, k, floor)
+		case i > 0:
+			assert.Equal(t, keys[i-1], floor)
+		default:
+			assert.False(t, ok)
+		}
+		ceiling, _, ok := m.Ceiling(k)
+		if i < len(keys) {
+			assert.Equal(t, keys[i], ceiling)
+		} else {
+			assert.False(t, ok)
+		}
+
+		end := k + r.IntN(200)
+		j, _ := slices.BinarySearch(keys, end)
+		assert.Equal(t, keys[i:j], keysOf(m.Range(k, end)))
+	}
+
+	// deleting everything leaves an empty map
+	for _, k := range keys {
+		assert.True(t, m.Delete(k))
+	}
+	assert.Equal(t, 0, m.Len())
+	assert.Empty(t, slices.Collect(m.Keys()))
+}
@@ -0,0 +1,104 @@
+package collections
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+var (
+	ErrNoChoices     = errors.New("at least one choice with a positive weight is required")
+	ErrInvalidWeight = errors.New("weights must be finite and non-negative")
+)
+
+// Weighted is an item with a relative weight.
+type Weighted[T any] struct {
+	Item   T
+	Weight float64
+}
+
+// WeightedChooser selects items at random in proportion to their weights.
+// Selection is O(1) using the alias method, and is safe for concurrent use.
+type WeightedChooser[T any] struct {
+	items []T
+	prob  []float64
+	alias []int
+}
+
+// NewWeightedChooser creates a WeightedChooser from the given choices.
+// Choices with zero weight are never selected.
+func NewWeightedChooser[T any](choices ...Weighted[T]) (*WeightedChooser[T], error) {
+	total := 0.0
+	for _, c := range choices {
+		if c.Weight < 0 || math.IsNaN(c.Weight) || math.IsInf(c.Weight, 0) {
+			return nil, stacktrace.Wrap(ErrInvalidWeight)
+		}
+		total += c.Weight
+	}
+	if total == 0 {
+		return nil, stacktrace.Wrap(ErrNoChoices)
+	}
+
+	// Vose's alias method: scale weights so that the average is 1,
+	// then pair each under-full bucket with an over-full one.
+	n := len(choices)
+	w := &WeightedChooser[T]{
+		items: make([]T, n),
+		prob:  make([]float64, n),
+		alias: make([]int, n),
+	}
+	scaled := make([]float64, n)
+	var small, large []int
+	for i, c := range choices {
+		w.items[i] = c.Item
+		scaled[i] = c.Weight * float64(n) / total
+		if scaled[i] < 1 {
+			small = append(small, i)
+		} else {
+			large = append(large, i)
+		}
+	}
+	for len(small) > 0 && len(large) > 0 {
+		s, l := small[len(small)-1], large[len(large)-1]
+		small = small[:len(small)-1]
+		w.prob[s] = scaled[s]
+		w.alias[s] = l
+		scaled[l] = scaled[l] + scaled[s] - 1
+		if scaled[l] < 1 {
+			large = large[:len(large)-1]
+			small = append(small, l)
+		}
+	}
+	// Any remaining buckets are full (up to floating point error)
+	for _, i := range large {
+		w.prob[i] = 1
+	}
+	for _, i := range small {
+		w.prob[i] = 1
+	}
+	return w, nil
+}
+
+// Choose selects an item at random.
+func (w *WeightedChooser[T]) Choose() T {
+	return w.choose(rand.IntN(len(w.items)), rand.Float64())
+}
+
+// ChooseWith selects an item using the given source of randomness, eg for reproducible results.
+func (w *WeightedChooser[T]) ChooseWith(r *rand.Rand) T {
+	return w.choose(r.IntN(len(w.items)), r.Float64())
+}
+
+// Len returns the number of choices.
+func (w *WeightedChooser[T]) Len() int {
+	return len(w.items)
+}
+
+func (w *WeightedChooser[T]) choose(i int, f float64) T {
+	if f < w.prob[i] {
+		return w.items[i]
+	}
+	return w.items[w.alias[i]]
+}
@@ -0,0 +1,76 @@
+package collections_test
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/collections"
+)
+
+func TestNewWeightedChooserErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		choices  []collections.Weighted[string]
+		expected error
+	}{
+		{
+			name:     "no choices",
+			expected: collections.ErrNoChoices,
+		},
+		{
+			name:     "all zero",
+			choices:  []collections.Weighted[string]{{Item: "a"}, {Item: "b"}},
+			expected: collections.ErrNoChoices,
+		},
+		{
+			name:     "negative weight",
+			choices:  []collections.Weighted[string]{{Item: "a", Weight: 1}, {Item: "b", Weight: -1}},
+			expected: collections.ErrInvalidWeight,
+		},
+		{
+			name:     "NaN weight",
+			choices:  []collections.Weighted[string]{{Item: "a", Weight: math.NaN()}},
+			expected: collections.ErrInvalidWeight,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := collections.NewWeightedChooser(tt.choices...)
+			assert.ErrorIs(t, err, tt.expected)
+		})
+	}
+}
+
+func TestWeightedChooserDistribution(t *testing.T) {
+	t.Parallel()
+
+	w, err := collections.NewWeightedChooser(
+		collections.Weighted[string]{Item: "a", Weight: 1},
+		collections.Weighted[string]{Item: "b", Weight: 2},
+		collections.Weighted[string]{Item: "never", Weight: 0},
+		collections.Weighted[string]{Item: "c", Weight: 7},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 4, w.Len())
+
+	const samples = 100000
+	r := rand.New(rand.NewPCG(1, 2)) //nolint:gosec // deterministic randomness for testing
+	counts := map[string]int{}
+	for range samples {
+		counts[w.ChooseWith(r)]++
+	}
+	assert.Zero(t, counts["never"])
+	assert.InDelta(t, 0.1, float64(counts["a"])/samples, 0.01)
+	assert.InDelta(t, 0.2, float64(counts["b"])/samples, 0.01)
+	assert.InDelta(t, 0.7, float64(counts["c"])/samples, 0.01)
+
+	// the default source is also usable
+	assert.NotEqual(t, "never", w.Choose())
+}
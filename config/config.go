@@ -4,8 +4,10 @@ package config
 import (
 	"fmt"
 	"io/fs"
+	"log/slog"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/knadh/koanf"
 	"github.com/knadh/koanf/parsers/toml"
@@ -32,6 +34,17 @@ type options struct {
 	filepath     string
 	separator    string
 	envSeparator string
+	deprecations []deprecation
+	logger       *slog.Logger
+	strictEnv    *strictEnv
+
+	remote       koanf.Provider
+	remoteParser koanf.Parser
+	interval     time.Duration
+
+	secretResolvers map[string]SecretResolver
+	secretTimeout   time.Duration
+	cachedSecrets   map[SecretRef]string // secrets resolved by a previous load, reused by a Watcher
 }
 
 // Option is an option func for NewConfiguration.
@@ -77,10 +90,24 @@ func WithEnvSeparator(separator string) Option {
 	}
 }
 
+// WithRemoteProvider additionally loads settings from a remote provider (eg a koanf provider
+// for a key value store), parsed with the given parser (nil if the provider returns a nested map).
+// Remote settings override those of the TOML file, while environment variables still take precedence.
+func WithRemoteProvider(provider koanf.Provider, parser koanf.Parser) Option {
+	return func(options *options) error {
+		options.remote = provider
+		options.remoteParser = parser
+		return nil
+	}
+}
+
 // Configuration is a wrapper for koanf to hide complexity.
 type Configuration struct {
-	k   *koanf.Koanf
-	env string
+	k       *koanf.Koanf
+	env     string
+	secrets []string // keys resolved from secret references, always redacted by Dump
+
+	resolved map[SecretRef]string // the secret of each reference, reused by a Watcher
 }
 
 // NewConfigurationFromMap allows for a direct flat map to be used to create configuration.
@@ -97,6 +124,17 @@ func NewConfigurationFromMap(cfg map[string]any) (*Configuration, error) {
 
 // NewConfiguration parses config from the given file system and environment variables.
 func NewConfiguration(f fs.FS, opts ...Option) (*Configuration, error) {
+	options, err := newOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkEnvVars(options); err != nil {
+		return nil, err
+	}
+	return load(f, options)
+}
+
+func newOptions(opts ...Option) (options, error) {
 	// Set up default parsing options
 	options := options{
 		defaultEnv:   defaultEnv,
@@ -104,16 +142,25 @@ func NewConfiguration(f fs.FS, opts ...Option) (*Configuration, error) {
 		separator:    defaultConfSeparator,
 		envSeparator: defaultEnvSeparator,
 		filepath:     defaultSettingsPath,
+		logger:       slog.Default(),
+		interval:     defaultReloadInterval,
+
+		secretResolvers: map[string]SecretResolver{"env": EnvSecretResolver()},
+		secretTimeout:   defaultSecretTimeout,
 	}
 
 	// Apply provided options
 	for _, opt := range opts {
 		err := opt(&options)
 		if err != nil {
-			return nil, err
+			return options, err
 		}
 	}
+	return options, nil
+}
 
+// load parses config from the given file system and environment variables using the parsed options.
+func load(f fs.FS, options options) (*Configuration, error) {
 	// If no file system is provided, only use environment variables
 	if f == nil {
 		return envOnlyConfig(options)
@@ -169,15 +216,12 @@ func NewConfiguration(f fs.FS, opts ...Option) (*Configuration, error) {
 		environment = options.defaultEnv
 	}
 
-	// Load and merge override settings from environment variables
-	if err := merged.Load(
-		env.Provider(options.envPrefix, options.separator, envToConfig(options)),
-		nil,
-	); err != nil {
-		return nil, errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+	secrets, resolved, err := loadOverrides(merged, options)
+	if err != nil {
+		return nil, err
 	}
 
-	return &Configuration{k: merged, env: environment}, nil
+	return &Configuration{k: merged, env: environment, secrets: secrets, resolved: resolved}, nil
 }
 
 func envOnlyConfig(options options) (*Configuration, error) {
@@ -188,15 +232,38 @@ func envOnlyConfig(options options) (*Configuration, error) {
 		environment = options.defaultEnv
 	}
 
-	// Load settings from environment variables
+	// Load settings from the remote provider (if any) and environment variables
 	k := koanf.New(defaultConfSeparator)
+	secrets, resolved, err := loadOverrides(k, options)
+	if err != nil {
+		return nil, err
+	}
+	return &Configuration{k: k, env: environment, secrets: secrets, resolved: resolved}, nil
+}
+
+// loadOverrides merges the remote settings (if any) and then environment variables over k,
+// then resolves any secret references, returning the keys of the resolved secrets and the
+// secret of each reference.
+func loadOverrides(k *koanf.Koanf, options options) ([]string, map[SecretRef]string, error) {
+	if options.remote != nil {
+		if err := k.Load(options.remote, options.remoteParser); err != nil {
+			return nil, nil, errclass.WrapAs(stacktrace.Wrap(err), errclass.Transient)
+		}
+	}
+
+	// Load and merge override settings from environment variables
 	if err := k.Load(
 		env.Provider(options.envPrefix, options.separator, envToConfig(options)),
 		nil,
 	); err != nil {
-		return nil, errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+		return nil, nil, errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
 	}
-	return &Configuration{k: k, env: environment}, nil
+
+	if err := applyDeprecations(k, options); err != nil {
+		return nil, nil, err
+	}
+
+	return resolveSecrets(k, options)
 }
 
 // Unmarshal sets values in struct `a` from the config rooted at `path`.
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/knadh/koanf"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// wildcard matches any single element of a key in a deprecation.
+const wildcard = "*"
+
+var ErrInvalidDeprecation = errors.New("deprecated and replacement keys must be non-empty, different, and have the same wildcards")
+
+type deprecation struct {
+	old []string
+	new []string
+}
+
+// WithDeprecatedKey declares that the key has been renamed to replacement. If the deprecated key is set
+// (in the TOML file or by environment variable), its value is moved to the replacement and a warning is logged
+// with both names. If both are set, the replacement wins and the deprecated value is ignored.
+// Keys are full paths, and may also be sections, in which case everything within them is moved.
+// An element "*" matches any single element, with the matched elements substituted in order into the
+// wildcards of the replacement, eg "*.durable" renames the "durable" key of every top level section.
+func WithDeprecatedKey(key, replacement string) Option {
+	return func(options *options) error {
+		d := deprecation{
+			old: strings.Split(key, defaultConfSeparator),
+			new: strings.Split(replacement, defaultConfSeparator),
+		}
+		if key == "" || replacement == "" || key == replacement ||
+			countWildcards(d.old) != countWildcards(d.new) {
+			return errclass.WrapAs(stacktrace.Wrap(ErrInvalidDeprecation), errclass.Persistent)
+		}
+		options.deprecations = append(options.deprecations, d)
+		return nil
+	}
+}
+
+// WithLogger sets the logger used to warn about deprecated keys. Defaults to slog.Default(),
+// since configuration is usually loaded before the service's logger is created.
+func WithLogger(logger *slog.Logger) Option {
+	return func(options *options) error {
+		options.logger = logger
+		return nil
+	}
+}
+
+func countWildcards(elements []string) int {
+	count := 0
+	for _, e := range elements {
+		if e == wildcard {
+			count++
+		}
+	}
+	return count
+}
+
+// match returns the elements of the deprecated key matched by the start of the key elements,
+// along with those of its replacement.
+func (d deprecation) match(elements []string) ([]string, []string, bool) {
+	if len(elements) < len(d.old) {
+		return nil, nil, false
+	}
+	var matched []string
+	for i, e := range d.old {
+		switch e {
+		case wildcard:
+			matched = append(matched, elements[i])
+		case elements[i]:
+		default:
+			return nil, nil, false
+		}
+	}
+	replacement := slices.Clone(d.new)
+	for i, e := range replacement {
+		if e == wildcard {
+			replacement[i], matched = matched[0], matched[1:]
+		}
+	}
+	return elements[:len(d.old)], replacement, true
+}
+
+// applyDeprecations moves the values of deprecated keys to their replacements.
+func applyDeprecations(k *koanf.Koanf, options options) error {
+	delim := k.Delim()
+	for _, d := range options.deprecations {
+		renamed := map[string]string{}
+		for _, key := range k.Keys() {
+			elements := strings.Split(key, delim)
+			old, replacement, ok := d.match(elements)
+			if !ok {
+				continue
+			}
+			renamed[strings.Join(old, delim)] = strings.Join(replacement, delim)
+
+			newKey := strings.Join(append(replacement, elements[len(d.old):]...), delim)
+			if k.Exists(newKey) {
+				continue
+			}
+			if err := k.Set(newKey, k.Get(key)); err != nil {
+				return errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+			}
+		}
+
+		for _, old := range slices.Sorted(maps.Keys(renamed)) {
+			options.logger.Warn("deprecated config key",
+				slog.String("deprecated_key", old),
+				slog.String("replacement_key", renamed[old]),
+			)
+			k.Delete(old)
+		}
+	}
+	return nil
+}
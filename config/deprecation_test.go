@@ -0,0 +1,89 @@
+package config_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+)
+
+type consumerConfig struct {
+	Stream       string
+	Durable      string
+	DurableQueue string
+}
+
+type busConfig struct {
+	URL     string
+	Timeout string
+}
+
+func TestDeprecatedKeys(t *testing.T) { //nolint:paralleltest // uses env vars
+	t.Setenv(testPrefix+"L3_DURABLE", "l3-consumer")
+
+	buf := &bytes.Buffer{}
+	cfg, err := config.NewConfiguration(
+		f,
+		config.WithFilePath("test/deprecated.toml"),
+		config.WithEnvPrefix(testPrefix),
+		config.WithLogger(slog.New(slog.NewTextHandler(buf, nil))),
+		config.WithDeprecatedKey("*.durable", "*.durablequeue"),
+		config.WithDeprecatedKey("nats", "messagebus"),
+	)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		path     string
+		expected consumerConfig
+	}{
+		{path: "l1", expected: consumerConfig{Stream: "L1", DurableQueue: "l1-consumer"}},
+		// the replacement wins when both are set
+		{path: "l2", expected: consumerConfig{Stream: "L2", DurableQueue: "l2-consumer"}},
+		// environment variables are migrated too
+		{path: "l3", expected: consumerConfig{DurableQueue: "l3-consumer"}},
+	}
+	for _, tc := range testCases {
+		var consumer consumerConfig
+		require.NoError(t, cfg.Unmarshal(tc.path, &consumer))
+		assert.Equal(t, tc.expected, consumer, tc.path)
+	}
+
+	// whole sections can be renamed
+	var bus busConfig
+	require.NoError(t, cfg.Unmarshal("messagebus", &bus))
+	assert.Equal(t, busConfig{URL: "nats://localhost:4222", Timeout: "5s"}, bus)
+	bus = busConfig{}
+	require.NoError(t, cfg.Unmarshal("nats", &bus))
+	assert.Empty(t, bus)
+
+	// each rename is logged once, with both names
+	logs := buf.String()
+	for _, expected := range []string{
+		"deprecated_key=l1.durable replacement_key=l1.durablequeue",
+		"deprecated_key=l2.durable replacement_key=l2.durablequeue",
+		"deprecated_key=l3.durable replacement_key=l3.durablequeue",
+		"deprecated_key=nats replacement_key=messagebus",
+	} {
+		assert.Contains(t, logs, expected)
+	}
+	assert.Equal(t, 4, bytes.Count(buf.Bytes(), []byte("\n")))
+}
+
+func TestInvalidDeprecatedKeys(t *testing.T) { //nolint:paralleltest // uses env vars
+	for _, keys := range [][2]string{
+		{"", "new"},
+		{"old", ""},
+		{"same", "same"},
+		{"*.old", "new"},
+	} {
+		_, err := config.NewConfiguration(f,
+			config.WithFilePath("test/deprecated.toml"),
+			config.WithDeprecatedKey(keys[0], keys[1]),
+		)
+		require.ErrorIs(t, err, config.ErrInvalidDeprecation, keys)
+	}
+}
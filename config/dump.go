@@ -0,0 +1,188 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	koanfmaps "github.com/knadh/koanf/maps"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const redacted = "[REDACTED]"
+
+// defaultRedactPatterns are the key elements which are always redacted by Dump.
+var defaultRedactPatterns = []string{
+	"password", "passwd", "secret", "token", "nkey", "apikey", "api_key", "privatekey", "private_key", "credential",
+}
+
+var secretType = reflect.TypeFor[Secret]()
+
+// Secret is a string config value which is redacted when formatted or logged, and by Dump
+// (for structs given with RedactStruct). Convert it to a string to use the value.
+type Secret string
+
+// String implements fmt.Stringer, redacting the value.
+func (s Secret) String() string {
+	return redacted
+}
+
+// LogValue implements slog.LogValuer, redacting the value.
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(redacted)
+}
+
+type dumpOptions struct {
+	patterns []string
+	keys     [][]string
+}
+
+// DumpOption configures Dump and Redacted.
+type DumpOption func(options *dumpOptions)
+
+// RedactKeys redacts the values of keys with any element containing (case-insensitive) one of the patterns,
+// in addition to the defaults (eg "password", "secret" and "token").
+func RedactKeys(patterns ...string) DumpOption {
+	return func(options *dumpOptions) {
+		for _, p := range patterns {
+			options.patterns = append(options.patterns, strings.ToLower(p))
+		}
+	}
+}
+
+// RedactStruct redacts the values of the fields of type Secret within the config struct a
+// (or a pointer to it) unmarshaled from path, using the same naming as Unmarshal.
+func RedactStruct(path string, a any) DumpOption {
+	return func(options *dumpOptions) {
+		var elements []string
+		if path != "" {
+			elements = strings.Split(path, defaultConfSeparator)
+		}
+		options.keys = append(options.keys, secretKeys(elements, reflect.TypeOf(a))...)
+	}
+}
+
+// Dump writes the effective configuration, after environment variables, renamed keys and secret
+// references have been applied, as TOML with one key per line in sorted order.
+// The values of secret references are always redacted, as are those of keys matching the default
+// patterns (see RedactKeys), so that the output can be safely logged at startup.
+func (c Configuration) Dump(w io.Writer, opts ...DumpOption) error {
+	options := newDumpOptions(opts)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# environment: %s\n", c.env)
+	all := c.k.All()
+	for _, key := range slices.Sorted(maps.Keys(all)) {
+		value := all[key]
+		if value == nil {
+			continue
+		}
+		elements := strings.Split(key, c.k.Delim())
+		formatted := formatString(redacted)
+		if !c.redact(key, elements, options) {
+			var ok bool
+			if formatted, ok = formatValue(reflect.ValueOf(value)); !ok {
+				formatted = formatString(fmt.Sprint(value))
+			}
+		}
+		quoted := make([]string, len(elements))
+		for i, e := range elements {
+			quoted[i] = formatKey(e)
+		}
+		fmt.Fprintf(&b, "%s = %s\n", strings.Join(quoted, "."), formatted)
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return errclass.WrapAs(stacktrace.Wrap(err), errclass.Transient)
+	}
+	return nil
+}
+
+// Redacted returns the effective configuration as nested maps, with values redacted as by Dump,
+// eg for inclusion in a crash report.
+func (c Configuration) Redacted(opts ...DumpOption) map[string]any {
+	options := newDumpOptions(opts)
+	all := c.k.All()
+	for key := range all {
+		if c.redact(key, strings.Split(key, c.k.Delim()), options) {
+			all[key] = redacted
+		}
+	}
+	return koanfmaps.Unflatten(all, c.k.Delim())
+}
+
+func newDumpOptions(opts []DumpOption) dumpOptions {
+	options := dumpOptions{patterns: defaultRedactPatterns}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// redact reports whether the value of the key should be redacted.
+func (c Configuration) redact(key string, elements []string, options dumpOptions) bool {
+	if slices.Contains(c.secrets, key) {
+		return true
+	}
+	for _, e := range elements {
+		e = strings.ToLower(e)
+		if slices.ContainsFunc(options.patterns, func(p string) bool { return strings.Contains(e, p) }) {
+			return true
+		}
+	}
+	return slices.ContainsFunc(options.keys, func(k []string) bool { return matchKey(k, elements) })
+}
+
+// matchKey reports whether the key elements start with pattern, where a wildcard matches any element.
+func matchKey(pattern, elements []string) bool {
+	if len(elements) < len(pattern) {
+		return false
+	}
+	for i, p := range pattern {
+		if p != wildcard && p != elements[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// secretKeys lists the keys of values of type Secret within type t at path, with a wildcard for map keys.
+func secretKeys(path []string, t reflect.Type) [][]string {
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		t = t.Elem()
+	}
+	switch {
+	case t == nil:
+		return nil
+	case t == secretType:
+		return [][]string{path}
+	case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String:
+		return secretKeys(append(slices.Clip(path), wildcard), t.Elem())
+	case t.Kind() != reflect.Struct:
+		return nil
+	}
+
+	var keys [][]string
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key, squash := fieldKey(field)
+		if key == "-" {
+			continue
+		}
+		fieldPath := path
+		if !squash {
+			fieldPath = append(slices.Clip(path), key)
+		}
+		keys = append(keys, secretKeys(fieldPath, field.Type)...)
+	}
+	return keys
+}
@@ -0,0 +1,367 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// commentTag is the struct tag used to document a field in the generated example.
+const commentTag = "comment"
+
+var (
+	bareKey           = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	durationType      = reflect.TypeFor[time.Duration]()
+	timeType          = reflect.TypeFor[time.Time]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// Section registers a config struct for inclusion in a generated example.
+type Section struct {
+	// Path is the config path passed to Unmarshal, eg "alice".
+	Path string
+	// Defaults is the config struct (or a pointer to it) populated with its default values.
+	Defaults any
+	// Comment optionally describes the section.
+	Comment string
+}
+
+// GenerateExample emits a commented example TOML file containing the defaults of each section
+// within the default environment. Each key is annotated with the environment variable that
+// overrides it, and with the `comment` struct tag of its field if present.
+// The options are the same as those given to NewConfiguration.
+//
+// Nil slices, maps and pointers are commented out so that loading the example
+// unmarshals to the same defaults.
+func GenerateExample(sections []Section, opts ...Option) ([]byte, error) {
+	options := options{
+		defaultEnv:   defaultEnv,
+		envPrefix:    defaultEnvPrefix,
+		separator:    defaultConfSeparator,
+		envSeparator: defaultEnvSeparator,
+	}
+	for _, opt := range opts {
+		if err := opt(&options); err != nil {
+			return nil, err
+		}
+	}
+
+	g := exampleGenerator{options: options}
+	g.printf("# default values are always used\n[%s]\n", formatKey(options.defaultEnv))
+	for _, s := range sections {
+		v := reflect.ValueOf(s.Defaults)
+		for v.Kind() == reflect.Pointer && !v.IsNil() {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return nil, errclass.WrapAs(stacktrace.Wrap(
+				fmt.Errorf("defaults for section '%s' must be a struct, not %s", s.Path, v.Kind()),
+			), errclass.Persistent)
+		}
+		var path []string
+		if s.Path != "" {
+			path = strings.Split(s.Path, defaultConfSeparator)
+		}
+		g.table(path, v, commentLines(s.Comment))
+	}
+	return g.buf.Bytes(), nil
+}
+
+type exampleGenerator struct {
+	options options
+	buf     bytes.Buffer
+}
+
+// entry is a single key within a table.
+type entry struct {
+	key      string
+	value    reflect.Value
+	comments []string
+}
+
+func (g *exampleGenerator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// table writes the header for the table at path followed by its contents.
+// Scalar keys are written before any sub-tables, as TOML requires.
+func (g *exampleGenerator) table(path []string, v reflect.Value, comments []string) {
+	if len(path) > 0 {
+		g.printf("\n")
+		g.comments(comments)
+		g.printf("[%s]\n", g.tablePath(path))
+	}
+
+	var tables []entry
+	for _, e := range entries(v) {
+		value, isNil := deref(e.value)
+		isNil = isNil || ((value.Kind() == reflect.Slice || value.Kind() == reflect.Map) && value.IsNil())
+		if isTable(value.Type()) {
+			if isNil {
+				g.printf("\n")
+				g.comments(e.comments)
+				g.printf("# [%s]\n", g.tablePath(append(slices.Clone(path), e.key)))
+				continue
+			}
+			tables = append(tables, entry{key: e.key, value: value, comments: e.comments})
+			continue
+		}
+		formatted, ok := formatValue(value)
+		if !ok {
+			continue
+		}
+		g.comments(e.comments)
+		g.envComment(append(slices.Clone(path), e.key))
+		if isNil {
+			g.printf("# ")
+		}
+		g.printf("%s = %s\n", formatKey(e.key), formatted)
+	}
+	for _, e := range tables {
+		g.table(append(slices.Clone(path), e.key), e.value, e.comments)
+	}
+}
+
+func (g *exampleGenerator) comments(lines []string) {
+	for _, line := range lines {
+		g.printf("# %s\n", line)
+	}
+}
+
+// envComment notes the environment variable overriding the key at path.
+// Keys which are not lower case or which contain the env separator cannot be overridden.
+func (g *exampleGenerator) envComment(path []string) {
+	for _, p := range path {
+		if p != strings.ToLower(p) || strings.Contains(p, g.options.envSeparator) {
+			g.printf("# env: (cannot be overridden by environment variable)\n")
+			return
+		}
+	}
+	g.printf("# env: %s%s\n", g.options.envPrefix, strings.ToUpper(strings.Join(path, g.options.envSeparator)))
+}
+
+func (g *exampleGenerator) tablePath(path []string) string {
+	keys := make([]string, 0, len(path)+1)
+	keys = append(keys, formatKey(g.options.defaultEnv))
+	for _, p := range path {
+		keys = append(keys, formatKey(p))
+	}
+	return strings.Join(keys, ".")
+}
+
+// entries lists the keys of a struct or string keyed map, using the same naming as Unmarshal.
+// Map keys are sorted for stable output.
+func entries(v reflect.Value) []entry {
+	if v.Kind() == reflect.Map {
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return strings.Compare(a.String(), b.String())
+		})
+		result := make([]entry, 0, len(keys))
+		for _, k := range keys {
+			result = append(result, entry{key: k.String(), value: v.MapIndex(k)})
+		}
+		return result
+	}
+
+	var result []entry
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("koanf"), ",")
+		if name == "-" {
+			continue
+		}
+		if opts == "squash" {
+			if value, _ := deref(v.Field(i)); value.Kind() == reflect.Struct {
+				result = append(result, entries(value)...)
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		result = append(result, entry{
+			key:      name,
+			value:    v.Field(i),
+			comments: commentLines(field.Tag.Get(commentTag)),
+		})
+	}
+	return result
+}
+
+// deref follows pointers and interfaces, returning the zero value of the
+// underlying type (and true) when nil.
+func deref(v reflect.Value) (reflect.Value, bool) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			if v.Kind() == reflect.Interface {
+				return v, true
+			}
+			return reflect.Zero(v.Type().Elem()), true
+		}
+		v = v.Elem()
+	}
+	return v, false
+}
+
+// isTable reports whether values of type t are written as TOML tables.
+func isTable(t reflect.Type) bool {
+	if isText(t) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		return true
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String
+	default:
+		return false
+	}
+}
+
+// isText reports whether values of type t are written as strings rather than by kind.
+func isText(t reflect.Type) bool {
+	return t == durationType || (t != timeType && t.Implements(textMarshalerType))
+}
+
+// formatValue formats v as an inline TOML value.
+// It returns false for values which cannot be represented.
+func formatValue(v reflect.Value) (string, bool) {
+	v, isNil := deref(v)
+	if isNil && v.Kind() == reflect.Interface {
+		return "", false
+	}
+
+	switch {
+	case v.Type() == durationType:
+		return formatString(time.Duration(v.Int()).String()), true
+	case v.Type() == timeType:
+		t, _ := reflect.TypeAssert[time.Time](v)
+		return t.Format(time.RFC3339Nano), true
+	case isText(v.Type()):
+		m, _ := reflect.TypeAssert[encoding.TextMarshaler](v)
+		b, err := m.MarshalText()
+		if err != nil {
+			return "", false
+		}
+		return formatString(string(b)), true
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return formatString(v.String()), true
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return formatFloat(v.Float(), v.Type().Bits()), true
+	case reflect.Slice, reflect.Array:
+		items := make([]string, 0, v.Len())
+		for i := range v.Len() {
+			item, ok := formatValue(v.Index(i))
+			if !ok {
+				return "", false
+			}
+			items = append(items, item)
+		}
+		return "[" + strings.Join(items, ", ") + "]", true
+	case reflect.Struct, reflect.Map:
+		if !isTable(v.Type()) {
+			return "", false
+		}
+		items := []string{}
+		for _, e := range entries(v) {
+			item, ok := formatValue(e.value)
+			if !ok {
+				continue
+			}
+			items = append(items, formatKey(e.key)+" = "+item)
+		}
+		if len(items) == 0 {
+			return "{}", true
+		}
+		return "{ " + strings.Join(items, ", ") + " }", true
+	default:
+		return "", false
+	}
+}
+
+// formatFloat formats f such that it is always parsed as a float.
+func formatFloat(f float64, bits int) string {
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, bits)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+// formatString formats s as a TOML basic string.
+func formatString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// formatKey quotes keys which are not valid bare keys.
+func formatKey(key string) string {
+	if bareKey.MatchString(key) {
+		return key
+	}
+	return formatString(key)
+}
+
+func commentLines(comment string) []string {
+	if comment == "" {
+		return nil
+	}
+	return strings.Split(comment, "\n")
+}
@@ -0,0 +1,134 @@
+package config_test
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zircuit-labs/zkr-go-common/config"
+)
+
+type aliceConfig struct {
+	Host        string `comment:"base URL of the upstream service"`
+	Endpoint    string
+	Credentials credentials
+	Period      time.Duration `koanf:"frequency" comment:"how often to poll"`
+	Retries     *int
+	Ignored     string `koanf:"-"`
+}
+
+type credentials struct {
+	UserName string `koanf:"user_name"`
+	Password string `comment:"set via environment variable only"`
+}
+
+type bobConfig struct {
+	Enabled bool
+	Ratio   float64
+	Ports   []int
+	Data    [][]string
+	Tags    []string
+	Quote   string
+	Targets targets `koanf:"temp_targets"`
+	Servers map[string]server
+	Labels  map[string]string
+}
+
+type server struct {
+	IP   string
+	Role string
+}
+
+func exampleSections() []config.Section {
+	return []config.Section{
+		{
+			Path:    "alice",
+			Comment: "alice polls an upstream service",
+			Defaults: aliceConfig{
+				Endpoint:    "/some/endpoint",
+				Credentials: credentials{UserName: "alice"},
+				Period:      time.Hour*2 + time.Minute*15,
+			},
+		},
+		{
+			Path: "bob",
+			Defaults: &bobConfig{
+				Enabled: true,
+				Ratio:   1,
+				Ports:   []int{8000, 8001},
+				Data:    [][]string{{"delta", "phi"}, {"kappa"}},
+				Quote:   "say \"hi\"\n\tand go",
+				Targets: targets{CPU: 79.5, Case: 72},
+				Servers: map[string]server{
+					"beta":  {IP: "10.0.0.2", Role: "backend"},
+					"alpha": {IP: "10.0.0.1", Role: "frontend"},
+				},
+			},
+		},
+	}
+}
+
+// TestGenerateExample ensures the checked in example matches the generated one,
+// as a service would to keep its example settings in sync with its config structs.
+func TestGenerateExample(t *testing.T) { //nolint:paralleltest // uses env vars
+	generated, err := config.GenerateExample(exampleSections(), config.WithEnvPrefix(testPrefix))
+	require.NoError(t, err)
+
+	expected, err := f.ReadFile("test/generated.toml")
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(generated))
+}
+
+// TestGenerateExampleRoundTrip ensures that loading the generated example
+// unmarshals to the same defaults.
+func TestGenerateExampleRoundTrip(t *testing.T) { //nolint:paralleltest // uses env vars
+	sections := exampleSections()
+	generated, err := config.GenerateExample(sections, config.WithEnvPrefix(testPrefix))
+	require.NoError(t, err)
+
+	cfg, err := config.NewConfiguration(
+		fstest.MapFS{"data/settings.toml": {Data: generated}},
+		config.WithEnvPrefix(testPrefix),
+	)
+	require.NoError(t, err)
+
+	var alice aliceConfig
+	require.NoError(t, cfg.Unmarshal("alice", &alice))
+	assert.Equal(t, sections[0].Defaults, alice)
+
+	var bob bobConfig
+	require.NoError(t, cfg.Unmarshal("bob", &bob))
+	assert.Equal(t, sections[1].Defaults, &bob)
+}
+
+// TestGenerateExampleEnvNames ensures the documented env vars override their keys.
+func TestGenerateExampleEnvNames(t *testing.T) {
+	t.Setenv(testPrefix+"ALICE_CREDENTIALS_PASSWORD", "secret")
+	t.Setenv(testPrefix+"BOB_SERVERS_ALPHA_IP", "127.0.0.1")
+
+	generated, err := config.GenerateExample(exampleSections(), config.WithEnvPrefix(testPrefix))
+	require.NoError(t, err)
+	assert.Contains(t, string(generated), "# env: ABCD_ALICE_CREDENTIALS_PASSWORD\n")
+	assert.Contains(t, string(generated), "# env: ABCD_BOB_SERVERS_ALPHA_IP\n")
+
+	cfg, err := config.NewConfiguration(
+		fstest.MapFS{"data/settings.toml": {Data: generated}},
+		config.WithEnvPrefix(testPrefix),
+	)
+	require.NoError(t, err)
+
+	var alice aliceConfig
+	require.NoError(t, cfg.Unmarshal("alice", &alice))
+	assert.Equal(t, "secret", alice.Credentials.Password)
+
+	var bob bobConfig
+	require.NoError(t, cfg.Unmarshal("bob", &bob))
+	assert.Equal(t, "127.0.0.1", bob.Servers["alpha"].IP)
+}
+
+func TestGenerateExampleNotStruct(t *testing.T) { //nolint:paralleltest // uses env vars
+	_, err := config.GenerateExample([]config.Section{{Path: "x", Defaults: 5}})
+	require.Error(t, err)
+}
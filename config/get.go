@@ -0,0 +1,58 @@
+package config
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+var ErrKeyNotFound = errors.New("config key not found")
+
+// Get returns the value at path converted to T, eg a time.Duration from "5s", a []string from
+// "a,b,c" or a TOML array, or a struct as Unmarshal would. It returns ErrKeyNotFound if nothing is set at path.
+func Get[T any](c *Configuration, path string) (T, error) {
+	var value T
+	if path != "" && !c.k.Exists(path) {
+		return value, errcontext.Add(
+			errclass.WrapAs(stacktrace.Wrap(ErrKeyNotFound), errclass.Persistent),
+			slog.String("key", path),
+		)
+	}
+	if err := c.k.Unmarshal(path, &value); err != nil {
+		return value, errcontext.Add(
+			errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent),
+			slog.String("key", path),
+		)
+	}
+	return value, nil
+}
+
+// GetOr returns the value at path converted to T as Get does, or fallback if nothing is set at path
+// or it cannot be converted.
+func GetOr[T any](c *Configuration, path string, fallback T) T {
+	value, err := Get[T](c, path)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// Sub returns the configuration rooted at path, so that a library can be given only its own section,
+// eg cfg.Sub("nats").Unmarshal("", &natsConfig). The result is empty if there is no section at path.
+func (c Configuration) Sub(path string) *Configuration {
+	if path == "" {
+		return &c
+	}
+	prefix := path + c.k.Delim()
+	var secrets []string
+	for _, key := range c.secrets {
+		if rest, ok := strings.CutPrefix(key, prefix); ok {
+			secrets = append(secrets, rest)
+		}
+	}
+	return &Configuration{k: c.k.Cut(path), env: c.env, secrets: secrets}
+}
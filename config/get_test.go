@@ -0,0 +1,94 @@
+package config_test
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+const getSettings = `
+[default]
+name = "svc"
+[default.nats]
+address = "nats://localhost:4222"
+timeout = "5s"
+subjects = ["blocks", "txs"]
+auth = "secretref:env://GET_AUTH"
+[default.nats.consumer]
+durable = "indexer"
+batch = 100
+`
+
+type getConsumer struct {
+	Durable string
+	Batch   int
+}
+
+func TestGet(t *testing.T) { //nolint:paralleltest // uses env vars
+	t.Setenv("GET_AUTH", "hunter2")
+	t.Setenv(testPrefix+"NATS_SERVERS", "a:4222,b:4222")
+
+	files := fstest.MapFS{"data/settings.toml": {Data: []byte(getSettings)}}
+	cfg, err := config.NewConfiguration(files, config.WithEnvPrefix(testPrefix))
+	require.NoError(t, err)
+
+	timeout, err := config.Get[time.Duration](cfg, "nats.timeout")
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, timeout)
+
+	subjects, err := config.Get[[]string](cfg, "nats.subjects")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"blocks", "txs"}, subjects)
+
+	servers, err := config.Get[[]string](cfg, "nats.servers")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a:4222", "b:4222"}, servers)
+
+	consumer, err := config.Get[getConsumer](cfg, "nats.consumer")
+	require.NoError(t, err)
+	assert.Equal(t, getConsumer{Durable: "indexer", Batch: 100}, consumer)
+
+	_, err = config.Get[string](cfg, "nats.missing")
+	require.ErrorIs(t, err, config.ErrKeyNotFound)
+	_, err = config.Get[int](cfg, "nats.address")
+	require.Error(t, err)
+	assert.Equal(t, errclass.Persistent, errclass.GetClass(err))
+
+	assert.Equal(t, 3, config.GetOr(cfg, "nats.retries", 3))
+	assert.Equal(t, 100, config.GetOr(cfg, "nats.consumer.batch", 3))
+}
+
+func TestSub(t *testing.T) { //nolint:paralleltest // uses env vars
+	t.Setenv("GET_AUTH", "hunter2")
+	t.Setenv(testEnv, "")
+
+	files := fstest.MapFS{"data/settings.toml": {Data: []byte(getSettings)}}
+	cfg, err := config.NewConfiguration(files, config.WithEnvPrefix(testPrefix))
+	require.NoError(t, err)
+
+	nats := cfg.Sub("nats")
+	assert.Equal(t, cfg.Environment(), nats.Environment())
+	address, err := config.Get[string](nats, "address")
+	require.NoError(t, err)
+	assert.Equal(t, "nats://localhost:4222", address)
+
+	var consumer getConsumer
+	require.NoError(t, nats.Sub("consumer").Unmarshal("", &consumer))
+	assert.Equal(t, getConsumer{Durable: "indexer", Batch: 100}, consumer)
+
+	// secrets remain redacted within the section
+	var dump strings.Builder
+	require.NoError(t, nats.Dump(&dump))
+	assert.Contains(t, dump.String(), `auth = "[REDACTED]"`)
+	assert.NotContains(t, dump.String(), "name")
+
+	_, err = config.Get[string](cfg.Sub("missing"), "address")
+	require.ErrorIs(t, err, config.ErrKeyNotFound)
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/knadh/koanf"
+	"github.com/knadh/koanf/providers/confmap"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const (
+	// SecretRefPrefix marks a config value as a reference to a secret, eg "secretref:vault://secret/data/nats#nkey".
+	SecretRefPrefix = "secretref:"
+
+	defaultSecretTimeout = 30 * time.Second
+)
+
+var (
+	ErrInvalidSecretRef    = errors.New("invalid secret reference")
+	ErrUnknownSecretScheme = errors.New("no secret resolver for scheme")
+	ErrSecretNotFound      = errors.New("secret not found")
+)
+
+// SecretRef is a parsed secret reference of the form "secretref:<scheme>://<path>#<key>".
+type SecretRef struct {
+	Scheme string // selects the resolver, eg "vault"
+	Path   string // locates the secret, eg "secret/data/nats"
+	Key    string // optionally selects a field of the secret, eg "nkey"
+}
+
+// String returns the reference as written in config.
+func (r SecretRef) String() string {
+	s := SecretRefPrefix + r.Scheme + "://" + r.Path
+	if r.Key != "" {
+		s += "#" + r.Key
+	}
+	return s
+}
+
+// SecretResolver resolves secret references of a scheme to their values.
+// Errors should be classed (see errclass), and wrap ErrSecretNotFound if the secret does not exist.
+type SecretResolver interface {
+	ResolveSecret(ctx context.Context, ref SecretRef) (string, error)
+}
+
+// SecretResolverFunc is a func implementing SecretResolver.
+type SecretResolverFunc func(ctx context.Context, ref SecretRef) (string, error)
+
+// ResolveSecret implements SecretResolver.
+func (f SecretResolverFunc) ResolveSecret(ctx context.Context, ref SecretRef) (string, error) {
+	return f(ctx, ref)
+}
+
+// WithSecretResolver resolves references of the scheme (eg "vault") using the resolver.
+// Values beginning with "secretref:" are replaced with the secret they refer to when the
+// configuration is loaded. The "env" scheme (see EnvSecretResolver) is always available.
+func WithSecretResolver(scheme string, resolver SecretResolver) Option {
+	return func(options *options) error {
+		if options.secretResolvers == nil {
+			options.secretResolvers = make(map[string]SecretResolver)
+		}
+		options.secretResolvers[scheme] = resolver
+		return nil
+	}
+}
+
+// WithSecretTimeout limits the time taken to resolve all secret references when loading. Defaults to 30s.
+func WithSecretTimeout(timeout time.Duration) Option {
+	return func(options *options) error {
+		options.secretTimeout = timeout
+		return nil
+	}
+}
+
+// EnvSecretResolver resolves "secretref:env://NAME" to the value of the environment variable NAME,
+// eg so that a secret injected under a fixed name can be referenced from TOML.
+func EnvSecretResolver() SecretResolver {
+	return SecretResolverFunc(func(_ context.Context, ref SecretRef) (string, error) {
+		value, ok := os.LookupEnv(ref.Path)
+		if !ok {
+			return "", errclass.WrapAs(stacktrace.Wrap(ErrSecretNotFound), errclass.Persistent)
+		}
+		return value, nil
+	})
+}
+
+// ParseSecretRef parses a value of the form "secretref:<scheme>://<path>#<key>" (the key being optional).
+// It returns false if the value is not a secret reference.
+func ParseSecretRef(value string) (SecretRef, bool, error) {
+	rest, ok := strings.CutPrefix(value, SecretRefPrefix)
+	if !ok {
+		return SecretRef{}, false, nil
+	}
+	scheme, rest, found := strings.Cut(rest, "://")
+	path, key, _ := strings.Cut(rest, "#")
+	if !found || scheme == "" || path == "" {
+		return SecretRef{}, true, errclass.WrapAs(
+			stacktrace.Wrap(fmt.Errorf("%w: %q", ErrInvalidSecretRef, value)),
+			errclass.Persistent,
+		)
+	}
+	return SecretRef{Scheme: scheme, Path: path, Key: key}, true, nil
+}
+
+// resolveSecrets replaces every string value of k which is a secret reference with the secret.
+// Each distinct reference is resolved once, unless it is one of the cached secrets given in the options.
+// It returns the keys of the resolved secrets, and the secret of each reference.
+func resolveSecrets(k *koanf.Koanf, options options) ([]string, map[SecretRef]string, error) {
+	refs := make(map[string]SecretRef)
+	for key, value := range k.All() {
+		s, ok := value.(string)
+		if !ok {
+			continue
+		}
+		ref, ok, err := ParseSecretRef(s)
+		if err != nil {
+			return nil, nil, errcontext.Add(err, slog.String("key", key))
+		}
+		if ok {
+			refs[key] = ref
+		}
+	}
+	if len(refs) == 0 {
+		return nil, nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), options.secretTimeout)
+	defer cancel()
+
+	resolved := make(map[string]any, len(refs))
+	secrets := make(map[SecretRef]string, len(refs))
+	// in key order, so that the same error is returned each time for a bad configuration
+	for _, key := range slices.Sorted(maps.Keys(refs)) {
+		ref := refs[key]
+		secret, ok := secrets[ref]
+		if !ok {
+			secret, ok = options.cachedSecrets[ref]
+		}
+		if !ok {
+			resolver, found := options.secretResolvers[ref.Scheme]
+			if !found {
+				return nil, nil, errcontext.Add(
+					errclass.WrapAs(stacktrace.Wrap(fmt.Errorf("%w %q", ErrUnknownSecretScheme, ref.Scheme)), errclass.Persistent),
+					slog.String("key", key),
+				)
+			}
+			var err error
+			secret, err = resolver.ResolveSecret(ctx, ref)
+			if err != nil {
+				// the reference is not itself secret, so is included to help find the problem
+				return nil, nil, errcontext.Add(err, slog.String("key", key), slog.String("secret_ref", ref.String()))
+			}
+		}
+		secrets[ref] = secret
+		resolved[key] = secret
+	}
+
+	if err := k.Load(confmap.Provider(resolved, defaultConfSeparator), nil); err != nil {
+		return nil, nil, errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+	}
+	return slices.Sorted(maps.Keys(resolved)), secrets, nil
+}
@@ -0,0 +1,154 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const awsService = "secretsmanager"
+
+var (
+	ErrNoRegion      = errors.New("no region supplied")
+	ErrNoCredentials = errors.New("no credentials supplied")
+)
+
+// AWSSecretsManager resolves references of the form "secretref:aws-sm://<name>#<key>" using AWS Secrets Manager,
+// where the name may also be an ARN. If a key is given, the secret must be a JSON object, and the value is its
+// field with the key. Otherwise the value is the whole secret.
+type AWSSecretsManager struct {
+	cfg      aws.Config
+	endpoint string
+	signer   *v4.Signer
+	options  options
+}
+
+var _ config.SecretResolver = (*AWSSecretsManager)(nil)
+
+// NewAWSSecretsManager creates an AWS Secrets Manager resolver using the region and credentials of the
+// AWS config (eg from awsconfig.LoadDefaultConfig).
+func NewAWSSecretsManager(cfg aws.Config, opts ...Option) (*AWSSecretsManager, error) {
+	if cfg.Region == "" {
+		return nil, errclass.WrapAs(stacktrace.Wrap(ErrNoRegion), errclass.Persistent)
+	}
+	if cfg.Credentials == nil {
+		return nil, errclass.WrapAs(stacktrace.Wrap(ErrNoCredentials), errclass.Persistent)
+	}
+	options := parseOptions(opts)
+	endpoint := options.endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com", awsService, cfg.Region)
+	}
+	return &AWSSecretsManager{
+		cfg:      cfg,
+		endpoint: endpoint,
+		signer:   v4.NewSigner(),
+		options:  options,
+	}, nil
+}
+
+// ResolveSecret implements config.SecretResolver.
+func (m *AWSSecretsManager) ResolveSecret(ctx context.Context, ref config.SecretRef) (string, error) {
+	body, err := json.Marshal(map[string]string{"SecretId": ref.Path})
+	if err != nil {
+		return "", errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+
+	creds, err := m.cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return "", errclass.WrapAs(stacktrace.Wrap(err), errclass.Transient)
+	}
+	sum := sha256.Sum256(body)
+	if err := m.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(sum[:]), awsService, m.cfg.Region, time.Now()); err != nil {
+		return "", errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+	}
+
+	resp, err := m.options.client.Do(req)
+	if err != nil {
+		return "", errclass.WrapAs(stacktrace.Wrap(err), errclass.Transient)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", errclass.WrapAs(stacktrace.Wrap(err), errclass.Transient)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", awsError(resp.StatusCode, respBody)
+	}
+
+	var output struct {
+		SecretString *string `json:"SecretString"`
+		SecretBinary []byte  `json:"SecretBinary"` // base64 encoded, as decoded by encoding/json
+	}
+	if err := json.Unmarshal(respBody, &output); err != nil {
+		return "", errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+	}
+	secret := string(output.SecretBinary)
+	if output.SecretString != nil {
+		secret = *output.SecretString
+	}
+	if ref.Key == "" {
+		return secret, nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", errclass.WrapAs(
+			stacktrace.Wrap(fmt.Errorf("selecting key of secret which is not a JSON object: %w", err)),
+			errclass.Persistent,
+		)
+	}
+	return selectField(fields, ref.Key)
+}
+
+// awsError returns an error for an unsuccessful response according to its error type.
+func awsError(code int, body []byte) error {
+	var apiErr struct {
+		Type    string `json:"__type"`
+		Message string `json:"message"`
+	}
+	_ = json.Unmarshal(body, &apiErr) // the status code suffices if the body cannot be parsed
+	// the type may be qualified by its namespace, eg "com.amazonaws...#ResourceNotFoundException"
+	if i := strings.LastIndex(apiErr.Type, "#"); i >= 0 {
+		apiErr.Type = apiErr.Type[i+1:]
+	}
+
+	switch apiErr.Type {
+	case "ResourceNotFoundException":
+		return errclass.WrapAs(
+			stacktrace.Wrap(fmt.Errorf("%w: %s", config.ErrSecretNotFound, apiErr.Message)),
+			errclass.Persistent,
+		)
+	case "ThrottlingException":
+		return errclass.WrapAs(stacktrace.Wrap(fmt.Errorf("%s: %s", apiErr.Type, apiErr.Message)), errclass.Transient)
+	case "":
+		return statusError(code, awsService)
+	default:
+		err := fmt.Errorf("%s: %s", apiErr.Type, apiErr.Message)
+		if code >= http.StatusInternalServerError {
+			return errclass.WrapAs(stacktrace.Wrap(err), errclass.Transient)
+		}
+		return errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+	}
+}
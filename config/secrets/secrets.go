@@ -0,0 +1,94 @@
+// Package secrets provides config.SecretResolver backends for secret managers.
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+var (
+	ErrKeyRequired    = errors.New("secret has several fields, so the reference must select one with #key")
+	ErrUnexpectedType = errors.New("secret field is not a string")
+)
+
+type options struct {
+	client    *http.Client
+	namespace string
+	endpoint  string
+}
+
+// Option is an option func for NewVault and NewAWSSecretsManager.
+type Option func(options *options)
+
+// WithHTTPClient sets the HTTP client used for requests. Defaults to http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(options *options) {
+		options.client = client
+	}
+}
+
+// WithNamespace sets the Vault Enterprise namespace of requests.
+func WithNamespace(namespace string) Option {
+	return func(options *options) {
+		options.namespace = namespace
+	}
+}
+
+// WithEndpoint overrides the endpoint of AWS Secrets Manager, eg for LocalStack.
+func WithEndpoint(endpoint string) Option {
+	return func(options *options) {
+		options.endpoint = endpoint
+	}
+}
+
+func parseOptions(opts []Option) options {
+	options := options{client: http.DefaultClient}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// selectField returns the field of the secret with the key, which may only be
+// omitted if the secret has a single field.
+func selectField(fields map[string]any, key string) (string, error) {
+	if key == "" {
+		if len(fields) != 1 {
+			return "", errclass.WrapAs(stacktrace.Wrap(ErrKeyRequired), errclass.Persistent)
+		}
+		for k := range fields {
+			key = k
+		}
+	}
+
+	value, ok := fields[key]
+	if !ok {
+		return "", errclass.WrapAs(
+			stacktrace.Wrap(fmt.Errorf("%w: no field %q", config.ErrSecretNotFound, key)),
+			errclass.Persistent,
+		)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", errclass.WrapAs(stacktrace.Wrap(fmt.Errorf("%w: %q", ErrUnexpectedType, key)), errclass.Persistent)
+	}
+	return s, nil
+}
+
+// statusError returns an error for an unsuccessful response, classed as Transient
+// for server errors and throttling, and wrapping config.ErrSecretNotFound if appropriate.
+func statusError(code int, service string) error {
+	err := fmt.Errorf("%s responded with status %d", service, code)
+	if code == http.StatusNotFound {
+		err = fmt.Errorf("%w: %w", config.ErrSecretNotFound, err)
+	}
+	if code >= http.StatusInternalServerError || code == http.StatusTooManyRequests {
+		return errclass.WrapAs(stacktrace.Wrap(err), errclass.Transient)
+	}
+	return errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+}
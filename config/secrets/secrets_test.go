@@ -0,0 +1,131 @@
+package secrets_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/config/secrets"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+func TestVault(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		assert.Equal(t, "team", r.Header.Get("X-Vault-Namespace"))
+		switch r.URL.Path {
+		case "/v1/secret/data/nats": // KV version 2
+			_, _ = w.Write([]byte(`{"data":{"data":{"nkey":"SUAKEY","user":"svc"},"metadata":{"version":3}}}`))
+		case "/v1/kv/s3": // KV version 1
+			_, _ = w.Write([]byte(`{"data":{"secret":"s3cr3t"}}`))
+		case "/v1/unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	_, err := secrets.NewVault("", "token")
+	require.ErrorIs(t, err, secrets.ErrNoVaultAddress)
+
+	vault, err := secrets.NewVault(server.URL+"/", "token", secrets.WithNamespace("team"))
+	require.NoError(t, err)
+
+	value, err := vault.ResolveSecret(ctx, config.SecretRef{Scheme: "vault", Path: "secret/data/nats", Key: "nkey"})
+	require.NoError(t, err)
+	assert.Equal(t, "SUAKEY", value)
+
+	// the key may be omitted for secrets with a single field
+	value, err = vault.ResolveSecret(ctx, config.SecretRef{Scheme: "vault", Path: "kv/s3"})
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t", value)
+	_, err = vault.ResolveSecret(ctx, config.SecretRef{Scheme: "vault", Path: "secret/data/nats"})
+	require.ErrorIs(t, err, secrets.ErrKeyRequired)
+
+	_, err = vault.ResolveSecret(ctx, config.SecretRef{Scheme: "vault", Path: "secret/data/nats", Key: "missing"})
+	require.ErrorIs(t, err, config.ErrSecretNotFound)
+	_, err = vault.ResolveSecret(ctx, config.SecretRef{Scheme: "vault", Path: "secret/data/missing", Key: "nkey"})
+	require.ErrorIs(t, err, config.ErrSecretNotFound)
+	assert.Equal(t, errclass.Persistent, errclass.GetClass(err))
+
+	_, err = vault.ResolveSecret(ctx, config.SecretRef{Scheme: "vault", Path: "unavailable"})
+	require.Error(t, err)
+	assert.Equal(t, errclass.Transient, errclass.GetClass(err))
+}
+
+func TestAWSSecretsManager(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secretsmanager.GetSecretValue", r.Header.Get("X-Amz-Target"))
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/secretsmanager/aws4_request")
+
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		var input struct {
+			SecretID string `json:"SecretId"`
+		}
+		assert.NoError(t, json.Unmarshal(body, &input))
+
+		switch input.SecretID {
+		case "prod/nats":
+			_, _ = w.Write([]byte(`{"Name":"prod/nats","SecretString":"{\"nkey\":\"SUAKEY\"}"}`))
+		case "prod/token":
+			_, _ = w.Write([]byte(`{"Name":"prod/token","SecretBinary":"dG9rZW4="}`))
+		case "throttled":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"ThrottlingException","message":"Rate exceeded"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"com.amazonaws.secretsmanager#ResourceNotFoundException","message":"Secrets Manager can't find the specified secret."}`))
+		}
+	}))
+	defer server.Close()
+
+	_, err := secrets.NewAWSSecretsManager(aws.Config{})
+	require.ErrorIs(t, err, secrets.ErrNoRegion)
+
+	sm, err := secrets.NewAWSSecretsManager(aws.Config{
+		Region:      "eu-west-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+	}, secrets.WithEndpoint(server.URL))
+	require.NoError(t, err)
+
+	value, err := sm.ResolveSecret(ctx, config.SecretRef{Scheme: "aws-sm", Path: "prod/nats", Key: "nkey"})
+	require.NoError(t, err)
+	assert.Equal(t, "SUAKEY", value)
+
+	value, err = sm.ResolveSecret(ctx, config.SecretRef{Scheme: "aws-sm", Path: "prod/nats"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"nkey":"SUAKEY"}`, value)
+
+	value, err = sm.ResolveSecret(ctx, config.SecretRef{Scheme: "aws-sm", Path: "prod/token"})
+	require.NoError(t, err)
+	assert.Equal(t, "token", value)
+
+	_, err = sm.ResolveSecret(ctx, config.SecretRef{Scheme: "aws-sm", Path: "missing"})
+	require.ErrorIs(t, err, config.ErrSecretNotFound)
+	assert.Equal(t, errclass.Persistent, errclass.GetClass(err))
+
+	_, err = sm.ResolveSecret(ctx, config.SecretRef{Scheme: "aws-sm", Path: "throttled"})
+	require.Error(t, err)
+	assert.Equal(t, errclass.Transient, errclass.GetClass(err))
+}
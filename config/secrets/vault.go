@@ -0,0 +1,91 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+var ErrNoVaultAddress = errors.New("no vault address supplied")
+
+// Vault resolves references of the form "secretref:vault://<path>#<key>" by reading the path
+// from the Vault HTTP API, eg "secretref:vault://secret/data/nats#nkey" for the "nkey" field of
+// the "nats" secret of a KV version 2 engine mounted at "secret".
+type Vault struct {
+	address string
+	token   string
+	options options
+}
+
+var _ config.SecretResolver = (*Vault)(nil)
+
+// NewVault creates a Vault resolver for the server at the address, authenticating with the token.
+func NewVault(address, token string, opts ...Option) (*Vault, error) {
+	if address == "" {
+		return nil, errclass.WrapAs(stacktrace.Wrap(ErrNoVaultAddress), errclass.Persistent)
+	}
+	return &Vault{
+		address: strings.TrimSuffix(address, "/"),
+		token:   token,
+		options: parseOptions(opts),
+	}, nil
+}
+
+// NewVaultFromEnv creates a Vault resolver using the standard VAULT_ADDR, VAULT_TOKEN and VAULT_NAMESPACE
+// environment variables.
+func NewVaultFromEnv(opts ...Option) (*Vault, error) {
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		opts = append([]Option{WithNamespace(namespace)}, opts...)
+	}
+	return NewVault(os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN"), opts...)
+}
+
+// ResolveSecret implements config.SecretResolver.
+func (v *Vault) ResolveSecret(ctx context.Context, ref config.SecretRef) (string, error) {
+	endpoint := v.address + "/v1/" + (&url.URL{Path: strings.TrimPrefix(ref.Path, "/")}).EscapedPath()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if v.options.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.options.namespace)
+	}
+
+	resp, err := v.options.client.Do(req)
+	if err != nil {
+		return "", errclass.WrapAs(stacktrace.Wrap(err), errclass.Transient)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", errclass.WrapAs(stacktrace.Wrap(err), errclass.Transient)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", statusError(resp.StatusCode, "vault")
+	}
+
+	var secret struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+	}
+	// KV version 2 engines nest the fields within data, alongside metadata
+	fields := secret.Data
+	if nested, ok := fields["data"].(map[string]any); ok {
+		if _, ok := fields["metadata"]; ok {
+			fields = nested
+		}
+	}
+	return selectField(fields, ref.Key)
+}
@@ -0,0 +1,97 @@
+package config_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+)
+
+const secretSettings = `
+[default]
+[default.nats]
+address = "nats://localhost:4222"
+nkey = "secretref:vault://secret/data/nats#nkey"
+[default.s3]
+secretaccesskey = "secretref:vault://secret/data/s3#secret"
+accesskeyid = "secretref:env://S3_ACCESS_KEY_ID"
+`
+
+func TestSecretRefs(t *testing.T) { //nolint:paralleltest // uses env vars
+	t.Setenv("S3_ACCESS_KEY_ID", "AKIA123")
+	// secret references may also be given by environment variable
+	t.Setenv(testPrefix+"NATS_USER", "secretref:vault://secret/data/nats#user")
+
+	var resolved []config.SecretRef
+	vault := config.SecretResolverFunc(func(_ context.Context, ref config.SecretRef) (string, error) {
+		resolved = append(resolved, ref)
+		return ref.Path + "/" + ref.Key, nil
+	})
+
+	files := fstest.MapFS{"data/settings.toml": {Data: []byte(secretSettings)}}
+	cfg, err := config.NewConfiguration(files,
+		config.WithEnvPrefix(testPrefix),
+		config.WithSecretResolver("vault", vault),
+	)
+	require.NoError(t, err)
+	assert.Len(t, resolved, 3)
+
+	var nats struct {
+		Address string
+		NKey    string
+		User    string
+	}
+	require.NoError(t, cfg.Unmarshal("nats", &nats))
+	assert.Equal(t, "nats://localhost:4222", nats.Address)
+	assert.Equal(t, "secret/data/nats/nkey", nats.NKey)
+	assert.Equal(t, "secret/data/nats/user", nats.User)
+
+	var s3 struct {
+		AccessKeyID     string
+		SecretAccessKey string
+	}
+	require.NoError(t, cfg.Unmarshal("s3", &s3))
+	assert.Equal(t, "AKIA123", s3.AccessKeyID)
+	assert.Equal(t, "secret/data/s3/secret", s3.SecretAccessKey)
+}
+
+func TestSecretRefErrors(t *testing.T) { //nolint:paralleltest // uses env vars
+	files := fstest.MapFS{"data/settings.toml": {Data: []byte(secretSettings)}}
+
+	// no resolver for vault
+	_, err := config.NewConfiguration(files, config.WithEnvPrefix(testPrefix))
+	require.ErrorIs(t, err, config.ErrUnknownSecretScheme)
+
+	// missing environment variable
+	vault := config.SecretResolverFunc(func(context.Context, config.SecretRef) (string, error) { return "x", nil })
+	_, err = config.NewConfiguration(files, config.WithEnvPrefix(testPrefix), config.WithSecretResolver("vault", vault))
+	require.ErrorIs(t, err, config.ErrSecretNotFound)
+
+	t.Setenv(testPrefix+"NATS_NKEY", "secretref:vault")
+	_, err = config.NewConfiguration(files, config.WithEnvPrefix(testPrefix), config.WithSecretResolver("vault", vault))
+	require.ErrorIs(t, err, config.ErrInvalidSecretRef)
+}
+
+func TestParseSecretRef(t *testing.T) { //nolint:paralleltest // uses env vars
+	ref, ok, err := config.ParseSecretRef("secretref:aws-sm://arn:aws:secretsmanager:us-east-1:123:secret:prod/nats#nkey")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, config.SecretRef{
+		Scheme: "aws-sm",
+		Path:   "arn:aws:secretsmanager:us-east-1:123:secret:prod/nats",
+		Key:    "nkey",
+	}, ref)
+	assert.Equal(t, "secretref:aws-sm://arn:aws:secretsmanager:us-east-1:123:secret:prod/nats#nkey", ref.String())
+
+	_, ok, err = config.ParseSecretRef("plain value")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, ok, err = config.ParseSecretRef("secretref:vault://")
+	require.ErrorIs(t, err, config.ErrInvalidSecretRef)
+	assert.True(t, ok)
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+var ErrUnknownEnvVar = errors.New("environment variable does not match any config key")
+
+// StrictEnvMode determines what happens when an environment variable with the prefix does not match any config key.
+type StrictEnvMode int
+
+const (
+	StrictEnvFail StrictEnvMode = iota // loading fails with ErrUnknownEnvVar
+	StrictEnvWarn                      // a warning is logged for each variable
+)
+
+// knownKey is the key of a config value (with a wildcard for each map key), and whether
+// it may also have arbitrary keys below it (eg for a map[string]any).
+type knownKey struct {
+	elements []string
+	open     bool
+}
+
+// WithStrictEnv checks, when the configuration is first loaded, that every environment variable
+// with the prefix overrides a key of one of the sections (or a deprecated key, see WithDeprecatedKey),
+// catching typos such as CFG_NATS_ADDRES which would otherwise silently do nothing.
+// The sections are the same as those given to GenerateExample, only their types being used.
+func WithStrictEnv(mode StrictEnvMode, sections ...Section) Option {
+	return func(options *options) error {
+		options.strictEnv = &strictEnv{mode: mode}
+		for _, s := range sections {
+			var path []string
+			if s.Path != "" {
+				path = strings.Split(s.Path, defaultConfSeparator)
+			}
+			walkKeys(path, reflect.TypeOf(s.Defaults), nil, func(path []string, t reflect.Type) {
+				options.strictEnv.keys = append(options.strictEnv.keys, knownKey{
+					elements: path,
+					open:     t.Kind() == reflect.Interface || isTable(t),
+				})
+			})
+		}
+		return nil
+	}
+}
+
+type strictEnv struct {
+	mode StrictEnvMode
+	keys []knownKey
+}
+
+// checkEnvVars fails or warns (according to the strict mode, if any) about environment variables
+// with the prefix which do not override any known key.
+func checkEnvVars(options options) error {
+	if options.strictEnv == nil {
+		return nil
+	}
+	toKey := envToConfig(options)
+	var unknown []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, options.envPrefix) || name == options.envPrefix+envVarName {
+			continue
+		}
+		key := toKey(name)
+		if options.strictEnv.known(strings.Split(key, options.separator), options.deprecations) {
+			continue
+		}
+		if options.strictEnv.mode == StrictEnvWarn {
+			options.logger.Warn("unknown config environment variable", slog.String("env_var", name), slog.String("key", key))
+			continue
+		}
+		unknown = append(unknown, name)
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	slices.Sort(unknown)
+	return errcontext.Add(
+		errclass.WrapAs(stacktrace.Wrap(fmt.Errorf("%w: %s", ErrUnknownEnvVar, strings.Join(unknown, ", "))), errclass.Persistent),
+		slog.Any("env_vars", unknown),
+	)
+}
+
+// known reports whether the key elements match a known key, or a deprecated key.
+func (s *strictEnv) known(elements []string, deprecations []deprecation) bool {
+	for _, k := range s.keys {
+		if len(elements) == len(k.elements) || (k.open && len(elements) > len(k.elements)) {
+			if matchKey(k.elements, elements) {
+				return true
+			}
+		}
+	}
+	for _, d := range deprecations {
+		if _, _, ok := d.match(elements); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// walkKeys calls leaf with the key of each value within type t at path, using the same naming as Unmarshal
+// and a wildcard for map keys. Structs and string keyed maps are walked into, except for those which are
+// unmarshaled from strings (eg time.Time), and types already being walked (ie recursive types), which are leaves.
+func walkKeys(path []string, t reflect.Type, walking []reflect.Type, leaf func(path []string, t reflect.Type)) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == nil:
+		return
+	case t == timeType || isText(t) || slices.Contains(walking, t):
+		leaf(path, t)
+		return
+	case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String:
+		walkKeys(append(slices.Clip(path), wildcard), t.Elem(), append(walking, t), leaf)
+		return
+	case t.Kind() != reflect.Struct:
+		leaf(path, t)
+		return
+	}
+
+	walking = append(walking, t)
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key, squash := fieldKey(field)
+		if key == "-" {
+			continue
+		}
+		fieldPath := path
+		if !squash {
+			fieldPath = append(slices.Clip(path), key)
+		}
+		walkKeys(fieldPath, field.Type, slices.Clip(walking), leaf)
+	}
+}
@@ -0,0 +1,112 @@
+package config_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+)
+
+const strictPrefix = "STRICT_"
+
+type strictNatsConfig struct {
+	Address string
+	Timeout time.Duration
+	Creds   *string `koanf:"credentials"`
+}
+
+type strictConfig struct {
+	Nats    strictNatsConfig
+	Common  strictCommon `koanf:",squash"`
+	Peers   map[string]strictNatsConfig
+	Labels  map[string]any
+	Ignored string `koanf:"-"`
+}
+
+type strictCommon struct {
+	Name string
+}
+
+func strictSections() []config.Section {
+	return []config.Section{{Path: "svc", Defaults: strictConfig{}}}
+}
+
+func TestStrictEnv(t *testing.T) { //nolint:paralleltest // uses env vars
+	testCases := []struct {
+		name    string
+		env     []string
+		unknown []string
+	}{
+		{name: "none"},
+		{
+			name: "known",
+			env: []string{
+				"SVC_NATS_ADDRESS", "SVC_NATS_TIMEOUT", "SVC_NATS_CREDENTIALS", "SVC_NAME",
+				"SVC_PEERS_ALICE_ADDRESS", "SVC_LABELS_TEAM", "SVC_LABELS_TEAM_LEAD", "ENV",
+			},
+		},
+		{name: "deprecated", env: []string{"SVC_NATS_ADDR"}},
+		{
+			name:    "typos",
+			env:     []string{"SVC_NATS_ADDRESS", "SVC_NATS_ADDRES", "SVC_NATS", "SVC_COMMON_NAME", "SVC_IGNORED"},
+			unknown: []string{"SVC_COMMON_NAME", "SVC_IGNORED", "SVC_NATS", "SVC_NATS_ADDRES"},
+		},
+		{name: "map keys", env: []string{"SVC_PEERS_ALICE_ADDR"}, unknown: []string{"SVC_PEERS_ALICE_ADDR"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, e := range tc.env {
+				value := "x"
+				if e == "ENV" {
+					value = "default"
+				}
+				t.Setenv(strictPrefix+e, value)
+			}
+			opts := []config.Option{
+				config.WithEnvPrefix(strictPrefix),
+				config.WithDeprecatedKey("svc.nats.addr", "svc.nats.address"),
+			}
+
+			_, err := config.NewConfiguration(nil, append(opts, config.WithStrictEnv(config.StrictEnvFail, strictSections()...))...)
+			if len(tc.unknown) == 0 {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, config.ErrUnknownEnvVar)
+				for _, name := range tc.unknown {
+					assert.ErrorContains(t, err, strictPrefix+name)
+				}
+			}
+
+			buf := &bytes.Buffer{}
+			_, err = config.NewConfiguration(nil, append(opts,
+				config.WithLogger(slog.New(slog.NewTextHandler(buf, nil))),
+				config.WithStrictEnv(config.StrictEnvWarn, strictSections()...),
+			)...)
+			require.NoError(t, err)
+			for _, name := range tc.unknown {
+				assert.Contains(t, buf.String(), "env_var="+strictPrefix+name)
+			}
+			assert.Equal(t, len(tc.unknown), bytes.Count(buf.Bytes(), []byte("unknown config environment variable")))
+		})
+	}
+}
+
+func TestStrictEnvWatcher(t *testing.T) { //nolint:paralleltest // uses env vars
+	t.Setenv(strictPrefix+"SVC_NATS_ADRESS", "nats://localhost:4222")
+
+	_, err := config.NewWatcher(nil,
+		config.WithEnvPrefix(strictPrefix),
+		config.WithStrictEnv(config.StrictEnvFail, strictSections()...),
+	)
+	require.ErrorIs(t, err, config.ErrUnknownEnvVar)
+
+	// without strict mode the typo silently does nothing
+	_, err = config.NewWatcher(nil, config.WithEnvPrefix(strictPrefix))
+	require.NoError(t, err)
+}
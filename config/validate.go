@@ -0,0 +1,286 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const validateTag = "validate"
+
+var (
+	ErrInvalidConfig = errors.New("invalid configuration")
+	ErrUnknownRule   = errors.New("unknown validation rule")
+)
+
+// Validatable is implemented by config structs with validation that cannot be expressed with tags
+// (eg fields which depend on each other). See UnmarshalValidated.
+type Validatable interface {
+	Validate() error
+}
+
+// FieldError describes a config field which failed validation.
+type FieldError struct {
+	Path string // full path of the config key, eg "alice.credentials.password"
+	Rule string // the rule which failed, eg "required", or "validate" for a Validatable
+	Err  error
+}
+
+// Error implements the error interface.
+func (e FieldError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError lists every config field which failed validation.
+// It matches ErrInvalidConfig with errors.Is.
+type ValidationError []FieldError
+
+// Error implements the error interface.
+func (e ValidationError) Error() string {
+	messages := make([]string, len(e))
+	for i, fe := range e {
+		messages[i] = fe.Error()
+	}
+	return ErrInvalidConfig.Error() + ": " + strings.Join(messages, "; ")
+}
+
+// Is reports whether the target is ErrInvalidConfig.
+func (e ValidationError) Is(target error) bool {
+	return target == ErrInvalidConfig
+}
+
+// LogValue implements slog.LogValuer.
+func (e ValidationError) LogValue() slog.Value {
+	attrs := make([]slog.Attr, len(e))
+	for i, fe := range e {
+		attrs[i] = slog.String(fe.Path, fe.Err.Error())
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// UnmarshalValidated sets values in struct `a` from the config rooted at `path` as Unmarshal,
+// then validates them. Fields are validated according to their `validate` tag, a comma separated
+// list of rules:
+//
+//   - required: must not be the zero value
+//   - omitempty: skip the remaining rules if the value is the zero value
+//   - min=n, max=n: bounds of numbers, durations (eg min=1s) or the length of strings, slices and maps
+//   - oneof=a b c: must be one of the space separated values
+//   - url: must be an absolute URL
+//
+// Nested structs (including through pointers, slices and maps) are validated in turn, and any struct
+// implementing Validatable has Validate called after its fields are validated.
+// Every failure is listed in the returned ValidationError, classed as Persistent.
+// Tags with an unknown rule or invalid parameter fail with ErrUnknownRule instead.
+func (c Configuration) UnmarshalValidated(path string, a any) error {
+	if err := c.Unmarshal(path, a); err != nil {
+		return errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+	}
+
+	var v validator
+	v.validate(path, reflect.ValueOf(a))
+	if v.ruleErr != nil {
+		return errclass.WrapAs(stacktrace.Wrap(v.ruleErr), errclass.Persistent)
+	}
+	if len(v.errs) > 0 {
+		return errclass.WrapAs(stacktrace.Wrap(v.errs), errclass.Persistent)
+	}
+	return nil
+}
+
+type validator struct {
+	errs    ValidationError
+	ruleErr error // the first misuse of a tag
+}
+
+func (v *validator) fail(path, rule string, err error) {
+	v.errs = append(v.errs, FieldError{Path: path, Rule: rule, Err: err})
+}
+
+// validate recursively validates the nested structs of the value.
+func (v *validator) validate(path string, value reflect.Value) {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		v.validateStruct(path, value)
+	case reflect.Slice, reflect.Array:
+		for i := range value.Len() {
+			v.validate(fmt.Sprintf("%s[%d]", path, i), value.Index(i))
+		}
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			v.validate(joinPath(path, fmt.Sprint(iter.Key().Interface())), iter.Value())
+		}
+	default:
+	}
+}
+
+func (v *validator) validateStruct(path string, value reflect.Value) {
+	t := value.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key, squash := fieldKey(field)
+		if key == "-" {
+			continue
+		}
+		fieldPath := joinPath(path, key)
+		if squash {
+			fieldPath = path
+		}
+		fieldValue := value.Field(i)
+		if tag := field.Tag.Get(validateTag); tag != "" {
+			v.validateField(fieldPath, fieldValue, tag)
+		}
+		v.validate(fieldPath, fieldValue)
+	}
+
+	if value.CanAddr() {
+		value = value.Addr()
+	}
+	if validatable, ok := value.Interface().(Validatable); ok {
+		if err := validatable.Validate(); err != nil {
+			v.fail(path, "validate", err)
+		}
+	}
+}
+
+// validateField applies the rules of the tag to the field.
+func (v *validator) validateField(path string, value reflect.Value, tag string) {
+	for rule := range strings.SplitSeq(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "required":
+			if value.IsZero() {
+				v.fail(path, name, errors.New("is required"))
+				return
+			}
+			continue
+		case "omitempty":
+			if value.IsZero() {
+				return
+			}
+			continue
+		}
+
+		// the remaining rules apply to the value pointed to, if any
+		elem := value
+		for elem.Kind() == reflect.Pointer {
+			if elem.IsNil() {
+				return
+			}
+			elem = elem.Elem()
+		}
+		err := checkRule(elem, name, param)
+		switch {
+		case errors.Is(err, ErrUnknownRule):
+			if v.ruleErr == nil {
+				v.ruleErr = FieldError{Path: path, Rule: name, Err: err}
+			}
+		case err != nil:
+			v.fail(path, name, err)
+		}
+	}
+}
+
+func checkRule(value reflect.Value, name, param string) error {
+	switch name {
+	case "min", "max":
+		return checkBound(value, name, param)
+	case "oneof":
+		s := fmt.Sprint(value.Interface())
+		if !slices.Contains(strings.Fields(param), s) {
+			return fmt.Errorf("must be one of [%s], not %q", param, s)
+		}
+	case "url":
+		u, err := url.Parse(value.String())
+		if value.Kind() != reflect.String || err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("must be an absolute URL, not %q", value.String())
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownRule, name)
+	}
+	return nil
+}
+
+// checkBound checks a min or max rule.
+func checkBound(value reflect.Value, name, param string) error {
+	var (
+		actual, bound float64
+		err           error
+		describe      = param
+	)
+	switch {
+	case value.Type() == durationType:
+		var d time.Duration
+		d, err = time.ParseDuration(param)
+		actual, bound = float64(value.Int()), float64(d)
+	case value.CanInt():
+		actual = float64(value.Int())
+		bound, err = strconv.ParseFloat(param, 64)
+	case value.CanUint():
+		actual = float64(value.Uint())
+		bound, err = strconv.ParseFloat(param, 64)
+	case value.CanFloat():
+		actual = value.Float()
+		bound, err = strconv.ParseFloat(param, 64)
+	case value.Kind() == reflect.String, value.Kind() == reflect.Slice, value.Kind() == reflect.Map, value.Kind() == reflect.Array:
+		actual = float64(value.Len())
+		bound, err = strconv.ParseFloat(param, 64)
+		describe = "length " + param
+	default:
+		return fmt.Errorf("%w: %s does not apply to %s", ErrUnknownRule, name, value.Type())
+	}
+	if err != nil {
+		return fmt.Errorf("%w: invalid %s parameter %q", ErrUnknownRule, name, param)
+	}
+
+	if name == "min" && actual < bound {
+		return fmt.Errorf("must be at least %s", describe)
+	}
+	if name == "max" && actual > bound {
+		return fmt.Errorf("must be at most %s", describe)
+	}
+	return nil
+}
+
+// fieldKey returns the config key of the field, being its koanf tag or its name in lower case,
+// and whether its fields are squashed into the parent.
+func fieldKey(field reflect.StructField) (string, bool) {
+	key, opts, _ := strings.Cut(field.Tag.Get("koanf"), ",")
+	squash := slices.Contains(strings.Split(opts, ","), "squash")
+	if key == "" {
+		key = strings.ToLower(field.Name)
+	}
+	return key, squash
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + defaultConfSeparator + key
+}
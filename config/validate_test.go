@@ -0,0 +1,99 @@
+package config_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+type upstreamConfig struct {
+	Host    string        `validate:"required,url"`
+	Timeout time.Duration `validate:"min=1s,max=1m"`
+	Mode    string        `validate:"omitempty,oneof=fast safe"`
+}
+
+type serviceConfig struct {
+	Name      string `validate:"required,min=3"`
+	Workers   int    `validate:"min=1,max=64"`
+	Upstream  upstreamConfig
+	Fallbacks []upstreamConfig
+	Limits    *rateConfig `koanf:"rate_limits"`
+}
+
+type rateConfig struct {
+	Rate  int
+	Burst int
+}
+
+func (c rateConfig) Validate() error {
+	if c.Burst < c.Rate {
+		return errors.New("burst must be at least rate")
+	}
+	return nil
+}
+
+func TestUnmarshalValidated(t *testing.T) { //nolint:paralleltest // uses env vars
+	cfg, err := config.NewConfigurationFromMap(map[string]any{
+		"svc.name":              "api",
+		"svc.workers":           4,
+		"svc.upstream.host":     "https://example.com",
+		"svc.upstream.timeout":  "5s",
+		"svc.rate_limits.rate":  10,
+		"svc.rate_limits.burst": 20,
+	})
+	require.NoError(t, err)
+
+	var svc serviceConfig
+	require.NoError(t, cfg.UnmarshalValidated("svc", &svc))
+	assert.Equal(t, "https://example.com", svc.Upstream.Host)
+
+	cfg, err = config.NewConfigurationFromMap(map[string]any{
+		"svc.name":              "a",
+		"svc.upstream.host":     "example.com",
+		"svc.upstream.timeout":  "5m",
+		"svc.upstream.mode":     "reckless",
+		"svc.fallbacks":         []map[string]any{{"host": "http://fallback", "timeout": "1s"}, {"timeout": "1s"}},
+		"svc.rate_limits.rate":  10,
+		"svc.rate_limits.burst": 5,
+	})
+	require.NoError(t, err)
+
+	err = cfg.UnmarshalValidated("svc", &serviceConfig{})
+	require.ErrorIs(t, err, config.ErrInvalidConfig)
+	assert.Equal(t, errclass.Persistent, errclass.GetClass(err))
+
+	var validationErr config.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	failures := map[string]string{}
+	for _, fe := range validationErr {
+		failures[fe.Path] = fe.Rule
+	}
+	assert.Equal(t, map[string]string{
+		"svc.name":              "min",
+		"svc.workers":           "min",
+		"svc.upstream.host":     "url",
+		"svc.upstream.timeout":  "max",
+		"svc.upstream.mode":     "oneof",
+		"svc.fallbacks[1].host": "required",
+		"svc.rate_limits":       "validate",
+	}, failures)
+	assert.Contains(t, err.Error(), "svc.name: must be at least length 3")
+	assert.Contains(t, err.Error(), "svc.rate_limits: burst must be at least rate")
+}
+
+func TestUnmarshalValidatedUnknownRule(t *testing.T) { //nolint:paralleltest // uses env vars
+	cfg, err := config.NewConfigurationFromMap(map[string]any{"svc.name": "api"})
+	require.NoError(t, err)
+
+	var svc struct {
+		Name string `validate:"required,email"`
+	}
+	err = cfg.UnmarshalValidated("svc", &svc)
+	require.ErrorIs(t, err, config.ErrUnknownRule)
+}
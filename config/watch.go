@@ -0,0 +1,175 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const defaultReloadInterval = 30 * time.Second
+
+var ErrInvalidReloadInterval = errors.New("reload interval must be positive")
+
+// WithReloadInterval sets how often a Watcher re-reads its configuration. Defaults to 30s.
+// It has no effect on NewConfiguration.
+func WithReloadInterval(interval time.Duration) Option {
+	return func(options *options) error {
+		if interval <= 0 {
+			return errclass.WrapAs(stacktrace.Wrap(ErrInvalidReloadInterval), errclass.Persistent)
+		}
+		options.interval = interval
+		return nil
+	}
+}
+
+// Watcher holds a Configuration which is re-read while it runs, so that long-running services
+// can adjust settings (eg log level or rate limits) without restarting.
+// It is a task: run it with a task.Manager to reload periodically, and use ReloadAction with
+// the sighup task to also reload on SIGHUP.
+type Watcher struct {
+	f       fs.FS
+	options options
+	current atomic.Pointer[Configuration]
+
+	reloadMu    sync.Mutex // serializes reloads, so that callbacks see changes in order
+	callbacksMu sync.Mutex
+	callbacks   []changeCallback
+}
+
+type changeCallback struct {
+	path     string
+	callback func(Configuration)
+}
+
+// NewWatcher loads the configuration as NewConfiguration does, and returns a Watcher that
+// re-reads it using the same file system and options.
+func NewWatcher(f fs.FS, opts ...Option) (*Watcher, error) {
+	options, err := newOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkEnvVars(options); err != nil {
+		return nil, err
+	}
+	cfg, err := load(f, options)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		f:       f,
+		options: options,
+	}
+	w.current.Store(cfg)
+	return w, nil
+}
+
+// Get returns a snapshot of the current configuration. It is never modified by a reload,
+// so values read from it are consistent with each other.
+func (w *Watcher) Get() *Configuration {
+	return w.current.Load()
+}
+
+// OnChange registers a callback to be called with the new configuration after each reload
+// in which anything rooted at path changed. An empty path matches any change.
+// Callbacks are called synchronously, in the order registered, so should not block.
+func (w *Watcher) OnChange(path string, callback func(Configuration)) {
+	w.callbacksMu.Lock()
+	defer w.callbacksMu.Unlock()
+	w.callbacks = append(w.callbacks, changeCallback{path: path, callback: callback})
+}
+
+// Reload re-reads the configuration, and if it changed, replaces the current snapshot and calls
+// the callbacks of the changed paths. If it cannot be read, the current snapshot is kept.
+// Secret references are resolved again, whereas the periodic reloads of Run only resolve
+// references which are new or changed, so that they do not depend on the secret manager.
+func (w *Watcher) Reload() error {
+	return w.reload(true)
+}
+
+func (w *Watcher) reload(refreshSecrets bool) error {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	previous := w.current.Load()
+	options := w.options
+	if !refreshSecrets {
+		options.cachedSecrets = previous.resolved
+	}
+	next, err := load(w.f, options)
+	if err != nil {
+		return err
+	}
+	if reflect.DeepEqual(previous.k.Raw(), next.k.Raw()) {
+		return nil
+	}
+	w.current.Store(next)
+	w.options.logger.Info("configuration changed")
+
+	w.callbacksMu.Lock()
+	callbacks := append([]changeCallback(nil), w.callbacks...)
+	w.callbacksMu.Unlock()
+
+	for _, c := range callbacks {
+		if !reflect.DeepEqual(previous.k.Get(c.path), next.k.Get(c.path)) {
+			c.callback(*next)
+		}
+	}
+	return nil
+}
+
+// Name returns the name of this task.
+func (w *Watcher) Name() string {
+	return "config watcher"
+}
+
+// Run reloads the configuration at the reload interval until the context is done, reusing the
+// secrets already resolved (see Reload). Failures to reload are logged, leaving the current
+// configuration in use.
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.options.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := w.reload(false); err != nil {
+				w.options.logger.Warn("failed to reload configuration", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// ReloadAction returns an action for the sighup task which reloads the configuration,
+// including resolving secret references again.
+func (w *Watcher) ReloadAction() ReloadAction {
+	return ReloadAction{w: w}
+}
+
+// ReloadAction reloads the configuration of a Watcher. See Watcher.ReloadAction.
+type ReloadAction struct {
+	w *Watcher
+}
+
+// Run reloads the configuration.
+func (a ReloadAction) Run(context.Context) error {
+	return a.w.Reload()
+}
+
+// Cleanup does nothing.
+func (a ReloadAction) Cleanup() {}
+
+// Name returns the name of this action.
+func (a ReloadAction) Name() string {
+	return "reload configuration"
+}
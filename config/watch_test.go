@@ -0,0 +1,238 @@
+package config_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"testing/synctest"
+	"time"
+
+	"github.com/knadh/koanf/providers/confmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+)
+
+var errUnavailable = errors.New("secret manager unavailable")
+
+type limitsConfig struct {
+	Rate  int
+	Burst int
+}
+
+const watchSettings = `
+[default]
+[default.log]
+level = "info"
+[default.limits]
+rate = 10
+burst = 20
+`
+
+func TestWatcherReload(t *testing.T) { //nolint:paralleltest // uses env vars
+	files := fstest.MapFS{"data/settings.toml": {Data: []byte(watchSettings)}}
+	w, err := config.NewWatcher(files, config.WithEnvPrefix(testPrefix), config.WithLogger(slog.New(slog.DiscardHandler)))
+	require.NoError(t, err)
+
+	var logChanges, limitChanges, anyChanges int
+	var limits limitsConfig
+	w.OnChange("log", func(config.Configuration) { logChanges++ })
+	w.OnChange("limits", func(cfg config.Configuration) {
+		limitChanges++
+		assert.NoError(t, cfg.Unmarshal("limits", &limits))
+	})
+	w.OnChange("", func(config.Configuration) { anyChanges++ })
+
+	// nothing changed
+	require.NoError(t, w.Reload())
+	assert.Zero(t, anyChanges)
+
+	before := w.Get()
+	files["data/settings.toml"] = &fstest.MapFile{Data: []byte(`
+[default]
+[default.log]
+level = "info"
+[default.limits]
+rate = 5
+burst = 20
+`)}
+	require.NoError(t, w.Reload())
+	assert.Equal(t, 0, logChanges)
+	assert.Equal(t, 1, limitChanges)
+	assert.Equal(t, 1, anyChanges)
+	assert.Equal(t, limitsConfig{Rate: 5, Burst: 20}, limits)
+
+	// the previous snapshot is unchanged
+	require.NoError(t, before.Unmarshal("limits", &limits))
+	assert.Equal(t, limitsConfig{Rate: 10, Burst: 20}, limits)
+	require.NoError(t, w.Get().Unmarshal("limits", &limits))
+	assert.Equal(t, limitsConfig{Rate: 5, Burst: 20}, limits)
+
+	// environment variables still take precedence
+	t.Setenv(testPrefix+"LOG_LEVEL", "debug")
+	require.NoError(t, w.Reload())
+	assert.Equal(t, 1, logChanges)
+	assert.Equal(t, 1, limitChanges)
+	assert.Equal(t, 2, anyChanges)
+}
+
+func TestWatcherReloadFailure(t *testing.T) { //nolint:paralleltest // uses env vars
+	files := fstest.MapFS{"data/settings.toml": {Data: []byte(watchSettings)}}
+	w, err := config.NewWatcher(files, config.WithEnvPrefix(testPrefix))
+	require.NoError(t, err)
+
+	called := false
+	w.OnChange("", func(config.Configuration) { called = true })
+	before := w.Get()
+
+	files["data/settings.toml"] = &fstest.MapFile{Data: []byte("not toml = = =")}
+	require.Error(t, w.Reload())
+	assert.False(t, called)
+	assert.Same(t, before, w.Get())
+
+	_, err = config.NewWatcher(files, config.WithEnvPrefix(testPrefix))
+	require.Error(t, err)
+
+	_, err = config.NewWatcher(files, config.WithReloadInterval(0))
+	require.ErrorIs(t, err, config.ErrInvalidReloadInterval)
+}
+
+func TestWatcherRemoteProvider(t *testing.T) { //nolint:paralleltest // uses env vars
+	files := fstest.MapFS{"data/settings.toml": {Data: []byte(watchSettings)}}
+	remote := confmap.Provider(map[string]any{"limits.rate": 100}, ".")
+	t.Setenv(testPrefix+"LIMITS_BURST", "200")
+
+	w, err := config.NewWatcher(files, config.WithEnvPrefix(testPrefix), config.WithRemoteProvider(remote, nil))
+	require.NoError(t, err)
+
+	var limits limitsConfig
+	require.NoError(t, w.Get().Unmarshal("limits", &limits))
+	assert.Equal(t, limitsConfig{Rate: 100, Burst: 200}, limits)
+}
+
+// lockedFS is a file system whose files may be replaced while a Watcher is reading it.
+type lockedFS struct {
+	mu    sync.Mutex
+	files fstest.MapFS
+}
+
+func (l *lockedFS) Open(name string) (fs.File, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.files.Open(name)
+}
+
+func (l *lockedFS) set(name, data string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.files[name] = &fstest.MapFile{Data: []byte(data)}
+}
+
+func TestWatcherRun(t *testing.T) { //nolint:paralleltest // uses env vars
+	synctest.Test(t, func(t *testing.T) {
+		files := &lockedFS{files: fstest.MapFS{"data/settings.toml": {Data: []byte(watchSettings)}}}
+		buf := &bytes.Buffer{}
+		w, err := config.NewWatcher(files,
+			config.WithEnvPrefix(testPrefix),
+			config.WithReloadInterval(time.Minute),
+			config.WithLogger(slog.New(slog.NewTextHandler(buf, nil))),
+		)
+		require.NoError(t, err)
+
+		changed := make(chan struct{}, 2)
+		w.OnChange("log.level", func(config.Configuration) { changed <- struct{}{} })
+
+		ctx, cancel := context.WithCancel(t.Context())
+		done := make(chan error)
+		go func() { done <- w.Run(ctx) }()
+
+		synctest.Wait()
+		files.set("data/settings.toml", `
+[default]
+[default.log]
+level = "debug"
+`)
+		time.Sleep(time.Minute)
+		synctest.Wait()
+		assert.Len(t, changed, 1)
+
+		// a failed reload is logged and the task keeps running
+		files.set("data/settings.toml", "not toml = = =")
+		time.Sleep(time.Minute)
+		synctest.Wait()
+		assert.Contains(t, buf.String(), "failed to reload configuration")
+
+		// reloading on demand (eg on SIGHUP)
+		files.set("data/settings.toml", watchSettings)
+		action := w.ReloadAction()
+		require.NoError(t, action.Run(t.Context()))
+		assert.Len(t, changed, 2)
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+}
+
+func TestWatcherSecretCache(t *testing.T) { //nolint:paralleltest // uses env vars
+	synctest.Test(t, func(t *testing.T) {
+		const settings = `
+[default]
+[default.db]
+password = "secretref:vault://db#%s"
+`
+		var calls atomic.Int32
+		var failing atomic.Bool
+		vault := config.SecretResolverFunc(func(_ context.Context, ref config.SecretRef) (string, error) {
+			calls.Add(1)
+			if failing.Load() {
+				return "", errUnavailable
+			}
+			return ref.Key + "-value", nil
+		})
+
+		files := &lockedFS{files: fstest.MapFS{"data/settings.toml": {Data: []byte(fmt.Sprintf(settings, "password"))}}}
+		w, err := config.NewWatcher(files,
+			config.WithEnvPrefix(testPrefix),
+			config.WithReloadInterval(time.Minute),
+			config.WithSecretResolver("vault", vault),
+			config.WithLogger(slog.New(slog.DiscardHandler)),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), calls.Load())
+
+		ctx, cancel := context.WithCancel(t.Context())
+		done := make(chan error)
+		go func() { done <- w.Run(ctx) }()
+
+		// periodic reloads reuse resolved secrets, so do not depend on the secret manager
+		failing.Store(true)
+		time.Sleep(2 * time.Minute)
+		synctest.Wait()
+		assert.Equal(t, int32(1), calls.Load())
+		failing.Store(false)
+
+		// but resolve references which changed
+		files.set("data/settings.toml", fmt.Sprintf(settings, "rotated"))
+		time.Sleep(time.Minute)
+		synctest.Wait()
+		assert.Equal(t, int32(2), calls.Load())
+		var db struct{ Password string }
+		require.NoError(t, w.Get().Unmarshal("db", &db))
+		assert.Equal(t, "rotated-value", db.Password)
+
+		// while an explicit reload (eg on SIGHUP) resolves them all again
+		require.NoError(t, w.ReloadAction().Run(t.Context()))
+		assert.Equal(t, int32(3), calls.Load())
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+}
@@ -0,0 +1,52 @@
+package flags
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// ConfigSource is a static Source backed by the service configuration.
+type ConfigSource struct {
+	values map[string]string
+}
+
+// NewConfigSource reads all flags rooted at cfgPath.
+// Nested tables are flattened using "." as the separator, so that
+// `[default.flags.production] foo = true` is available as "production.foo".
+func NewConfigSource(cfg *config.Configuration, cfgPath string) (*ConfigSource, error) {
+	raw := map[string]any{}
+	if err := cfg.Unmarshal(cfgPath, &raw); err != nil {
+		return nil, stacktrace.Wrap(err)
+	}
+
+	values := map[string]string{}
+	flatten("", raw, values)
+	return &ConfigSource{values: values}, nil
+}
+
+// Lookup implements Source.
+func (s *ConfigSource) Lookup(key string) (string, bool) {
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// Values returns a copy of all flag values.
+func (s *ConfigSource) Values() map[string]string {
+	return maps.Clone(s.values)
+}
+
+func flatten(prefix string, in map[string]any, out map[string]string) {
+	for k, v := range in {
+		if prefix != "" {
+			k = prefix + "." + k
+		}
+		if nested, ok := v.(map[string]any); ok {
+			flatten(k, nested, out)
+			continue
+		}
+		out[k] = fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,273 @@
+// Package flags provides typed feature flags backed by pluggable sources.
+package flags
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/metrics"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+var ErrInvalidPercentage = errors.New("percentage must be between 0 and 100")
+
+// Source provides the raw value of a flag by key.
+type Source interface {
+	Lookup(key string) (string, bool)
+}
+
+// StaticSource is a Source backed by a fixed map of values.
+type StaticSource map[string]string
+
+// Lookup implements Source.
+func (s StaticSource) Lookup(key string) (string, bool) {
+	v, ok := s[key]
+	return v, ok
+}
+
+// EvalContext describes who a flag is being evaluated for.
+type EvalContext struct {
+	// Instance identifies the running service instance.
+	Instance string
+	// Environment is the name of the running environment (eg "production").
+	// When set, a value stored under "<environment>.<key>" takes precedence over "<key>".
+	Environment string
+	// Key is hashed to determine percentage rollouts (eg a user or account ID).
+	// If empty, Instance is used instead.
+	Key string
+}
+
+// merge fills any empty fields of ec with those of base.
+func (ec EvalContext) merge(base EvalContext) EvalContext {
+	if ec.Instance == "" {
+		ec.Instance = base.Instance
+	}
+	if ec.Environment == "" {
+		ec.Environment = base.Environment
+	}
+	if ec.Key == "" {
+		ec.Key = base.Key
+	}
+	return ec
+}
+
+func (ec EvalContext) hashKey() string {
+	if ec.Key != "" {
+		return ec.Key
+	}
+	return ec.Instance
+}
+
+// Flag is a typed feature flag definition.
+type Flag[T any] struct {
+	key   string
+	def   T
+	parse func(raw string, ec EvalContext) (T, error)
+}
+
+// Key returns the key of the flag.
+func (f Flag[T]) Key() string {
+	return f.key
+}
+
+// Default returns the value used when the flag is not set or cannot be parsed.
+func (f Flag[T]) Default() T {
+	return f.def
+}
+
+// Get evaluates the flag using the given evaluator.
+// If the flag is not set, or the set value is invalid, the default value is returned.
+func (f Flag[T]) Get(e *Evaluator, ec EvalContext) T {
+	ec = ec.merge(e.opts.evalContext)
+
+	raw, ok := e.lookup(f.key, ec)
+	if !ok {
+		e.record(f.key, ec, f.def, "default")
+		return f.def
+	}
+
+	v, err := f.parse(raw, ec)
+	if err != nil {
+		err = errcontext.Add(errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent), slog.String("flag", f.key), slog.String("raw", raw))
+		e.opts.logger.Warn("invalid flag value - using default", log.ErrAttr(err))
+		e.record(f.key, ec, f.def, "invalid")
+		return f.def
+	}
+
+	e.record(f.key, ec, v, "source")
+	return v
+}
+
+// Bool defines a boolean flag.
+func Bool(key string, def bool) Flag[bool] {
+	return Flag[bool]{
+		key: key,
+		def: def,
+		parse: func(raw string, _ EvalContext) (bool, error) {
+			return strconv.ParseBool(strings.TrimSpace(raw))
+		},
+	}
+}
+
+// Int defines an integer flag.
+func Int(key string, def int) Flag[int] {
+	return Flag[int]{
+		key: key,
+		def: def,
+		parse: func(raw string, _ EvalContext) (int, error) {
+			return strconv.Atoi(strings.TrimSpace(raw))
+		},
+	}
+}
+
+// String defines a string flag.
+func String(key string, def string) Flag[string] {
+	return Flag[string]{
+		key: key,
+		def: def,
+		parse: func(raw string, _ EvalContext) (string, error) {
+			return raw, nil
+		},
+	}
+}
+
+// Percentage defines a rollout flag whose value is a percentage in [0, 100].
+// The flag evaluates to true for that percentage of evaluation context keys.
+// The same key always produces the same result for a given flag and percentage,
+// and increasing the percentage never disables a key that was previously enabled.
+func Percentage(key string, def bool) Flag[bool] {
+	return Flag[bool]{
+		key: key,
+		def: def,
+		parse: func(raw string, ec EvalContext) (bool, error) {
+			pct, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(raw), "%"), 64)
+			if err != nil {
+				return false, err
+			}
+			if pct < 0 || pct > 100 {
+				return false, ErrInvalidPercentage
+			}
+			return bucket(key, ec.hashKey()) < uint64(pct*100), nil
+		},
+	}
+}
+
+// bucket deterministically maps the flag and hash key to a value in [0, 10000).
+func bucket(flagKey, hashKey string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(flagKey))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(hashKey))
+	return h.Sum64() % 10000
+}
+
+type options struct {
+	logger      *slog.Logger
+	registerer  prometheus.Registerer
+	evalContext EvalContext
+}
+
+// Option is an option func for NewEvaluator.
+type Option func(options *options)
+
+// WithLogger sets the logger to be used.
+// Every evaluation is logged at debug level.
+func WithLogger(logger *slog.Logger) Option {
+	return func(options *options) {
+		options.logger = logger
+	}
+}
+
+// WithMetrics records a counter of flag evaluations with the given registerer.
+func WithMetrics(registerer prometheus.Registerer) Option {
+	return func(options *options) {
+		options.registerer = registerer
+	}
+}
+
+// WithEvalContext sets the default evaluation context.
+// Fields set in the context passed to Get take precedence.
+func WithEvalContext(ec EvalContext) Option {
+	return func(options *options) {
+		options.evalContext = ec
+	}
+}
+
+// Evaluator evaluates flags against one or more sources.
+type Evaluator struct {
+	sources     []Source
+	opts        options
+	evaluations *prometheus.CounterVec
+}
+
+// NewEvaluator creates an Evaluator. Sources are consulted in order, and the first
+// source to contain a value for the flag wins.
+func NewEvaluator(sources []Source, opts ...Option) (*Evaluator, error) {
+	options := options{
+		logger: log.NewNilLogger(),
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	e := &Evaluator{
+		sources: sources,
+		opts:    options,
+	}
+
+	if options.registerer != nil {
+		evaluations, err := metrics.Register(options.registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "feature_flag_evaluations_total",
+			Help: "Number of feature flag evaluations by flag, result (of boolean flags only), and origin of the value.",
+		}, []string{"flag", "value", "origin"}))
+		if err != nil {
+			return nil, err
+		}
+		e.evaluations = evaluations
+	}
+
+	return e, nil
+}
+
+func (e *Evaluator) lookup(key string, ec EvalContext) (string, bool) {
+	keys := []string{key}
+	if ec.Environment != "" {
+		keys = []string{ec.Environment + "." + key, key}
+	}
+	for _, k := range keys {
+		for _, s := range e.sources {
+			if v, ok := s.Lookup(k); ok {
+				return v, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (e *Evaluator) record(key string, ec EvalContext, value any, origin string) {
+	v := fmt.Sprint(value)
+	if e.evaluations != nil {
+		// only boolean values are bounded, so the value of other flags is not a label
+		var label string
+		if _, ok := value.(bool); ok {
+			label = v
+		}
+		e.evaluations.WithLabelValues(key, label, origin).Inc()
+	}
+	e.opts.logger.Debug("feature flag evaluated",
+		slog.String("flag", key),
+		slog.String("value", v),
+		slog.String("origin", origin),
+		slog.String("environment", ec.Environment),
+		slog.String("eval_key", ec.hashKey()),
+	)
+}
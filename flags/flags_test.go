@@ -0,0 +1,149 @@
+package flags_test
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/flags"
+)
+
+func TestTypedFlags(t *testing.T) {
+	t.Parallel()
+
+	source := flags.StaticSource{
+		"enabled":   "true",
+		"limit":     "42",
+		"name":      "zircuit",
+		"broken":    "not-a-number",
+		"full":      "100",
+		"none":      "0%",
+		"too-large": "101",
+	}
+	e, err := flags.NewEvaluator([]flags.Source{source})
+	require.NoError(t, err)
+
+	assert.True(t, flags.Bool("enabled", false).Get(e, flags.EvalContext{}))
+	assert.True(t, flags.Bool("missing", true).Get(e, flags.EvalContext{}))
+	assert.Equal(t, 42, flags.Int("limit", 1).Get(e, flags.EvalContext{}))
+	assert.Equal(t, 7, flags.Int("broken", 7).Get(e, flags.EvalContext{}))
+	assert.Equal(t, "zircuit", flags.String("name", "").Get(e, flags.EvalContext{}))
+	assert.True(t, flags.Percentage("full", false).Get(e, flags.EvalContext{Key: "abc"}))
+	assert.False(t, flags.Percentage("none", true).Get(e, flags.EvalContext{Key: "abc"}))
+	assert.True(t, flags.Percentage("too-large", true).Get(e, flags.EvalContext{Key: "abc"}))
+}
+
+func TestPercentageRollout(t *testing.T) {
+	t.Parallel()
+
+	e, err := flags.NewEvaluator([]flags.Source{flags.StaticSource{"rollout": "25"}})
+	require.NoError(t, err)
+	f := flags.Percentage("rollout", false)
+
+	enabled := 0
+	for i := range 10000 {
+		ec := flags.EvalContext{Key: fmt.Sprintf("user-%d", i)}
+		v := f.Get(e, ec)
+		// the same key always produces the same result
+		assert.Equal(t, v, f.Get(e, ec))
+		if v {
+			enabled++
+		}
+	}
+	assert.InDelta(t, 2500, enabled, 250)
+
+	// without a key, the instance is used for hashing
+	withInstance := flags.EvalContext{Instance: "instance-1"}
+	withKey := flags.EvalContext{Key: "instance-1"}
+	assert.Equal(t, f.Get(e, withKey), f.Get(e, withInstance))
+}
+
+func TestEnvironmentScoping(t *testing.T) {
+	t.Parallel()
+
+	source := flags.StaticSource{
+		"feature":            "false",
+		"production.feature": "true",
+	}
+	e, err := flags.NewEvaluator(
+		[]flags.Source{source},
+		flags.WithEvalContext(flags.EvalContext{Environment: "production"}),
+	)
+	require.NoError(t, err)
+
+	f := flags.Bool("feature", false)
+	assert.True(t, f.Get(e, flags.EvalContext{}))
+	assert.False(t, f.Get(e, flags.EvalContext{Environment: "staging"}))
+}
+
+func TestSourcePrecedence(t *testing.T) {
+	t.Parallel()
+
+	e, err := flags.NewEvaluator([]flags.Source{
+		flags.StaticSource{"a": "first"},
+		flags.StaticSource{"a": "second", "b": "second"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "first", flags.String("a", "").Get(e, flags.EvalContext{}))
+	assert.Equal(t, "second", flags.String("b", "").Get(e, flags.EvalContext{}))
+}
+
+func TestConfigSource(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := config.NewConfigurationFromMap(map[string]any{
+		"flags.enabled":            true,
+		"flags.limit":              10,
+		"flags.production.enabled": false,
+	})
+	require.NoError(t, err)
+
+	source, err := flags.NewConfigSource(cfg, "flags")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"enabled":            "true",
+		"limit":              "10",
+		"production.enabled": "false",
+	}, source.Values())
+
+	e, err := flags.NewEvaluator([]flags.Source{source})
+	require.NoError(t, err)
+	assert.True(t, flags.Bool("enabled", false).Get(e, flags.EvalContext{}))
+	assert.False(t, flags.Bool("enabled", true).Get(e, flags.EvalContext{Environment: "production"}))
+	assert.Equal(t, 10, flags.Int("limit", 0).Get(e, flags.EvalContext{}))
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	reg := prometheus.NewRegistry()
+	e, err := flags.NewEvaluator([]flags.Source{flags.StaticSource{"a": "true"}}, flags.WithMetrics(reg))
+	require.NoError(t, err)
+
+	// registering twice with the same registry reuses the existing collector
+	_, err = flags.NewEvaluator(nil, flags.WithMetrics(reg))
+	require.NoError(t, err)
+
+	flags.Bool("a", false).Get(e, flags.EvalContext{})
+	flags.Bool("a", false).Get(e, flags.EvalContext{})
+	flags.Bool("b", false).Get(e, flags.EvalContext{})
+
+	count, err := testutil.GatherAndCount(reg, "feature_flag_evaluations_total")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// the values of other flags are unbounded, so are not recorded
+	for i := range 3 {
+		flags.String("s", strconv.Itoa(i)).Get(e, flags.EvalContext{})
+	}
+	count, err = testutil.GatherAndCount(reg, "feature_flag_evaluations_total")
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
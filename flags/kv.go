@@ -0,0 +1,119 @@
+package flags
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// KVSource is a Source backed by a NATS KV bucket.
+// It implements the Task interface: while running, changes to the bucket
+// are applied immediately so that flags can be updated without a restart.
+type KVSource struct {
+	kv     jetstream.KeyValue
+	logger *slog.Logger
+
+	mu     sync.RWMutex
+	values map[string]string
+}
+
+// NewKVSource creates a KVSource using the named bucket, creating the bucket if it does not exist.
+// The current contents of the bucket are loaded before returning.
+// Only the WithLogger option is used.
+func NewKVSource(ctx context.Context, js jetstream.JetStream, bucket string, opts ...Option) (*KVSource, error) {
+	options := options{
+		logger: log.NewNilLogger(),
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	kv, err := js.KeyValue(ctx, bucket)
+	if errors.Is(err, jetstream.ErrBucketNotFound) {
+		kv, err = js.CreateKeyValue(ctx, jetstream.KeyValueConfig{Bucket: bucket})
+	}
+	if err != nil {
+		return nil, stacktrace.Wrap(err)
+	}
+
+	s := &KVSource{
+		kv:     kv,
+		logger: options.logger.With(slog.String("bucket", bucket)),
+		values: map[string]string{},
+	}
+
+	// Load the initial values. The watcher sends a nil entry once all current values are delivered.
+	watcher, err := kv.WatchAll(ctx)
+	if err != nil {
+		return nil, stacktrace.Wrap(err)
+	}
+	defer func() { _ = watcher.Stop() }()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, stacktrace.Wrap(ctx.Err())
+		case entry := <-watcher.Updates():
+			if entry == nil {
+				return s, nil
+			}
+			s.apply(entry)
+		}
+	}
+}
+
+// Name returns the name of this task.
+func (s *KVSource) Name() string {
+	return "feature flags (" + s.kv.Bucket() + ")"
+}
+
+// Run watches the bucket for changes until the context is done.
+func (s *KVSource) Run(ctx context.Context) error {
+	watcher, err := s.kv.WatchAll(ctx)
+	if err != nil {
+		return stacktrace.Wrap(err)
+	}
+	defer func() { _ = watcher.Stop() }()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case entry, ok := <-watcher.Updates():
+			if !ok {
+				return nil
+			}
+			if entry != nil {
+				s.apply(entry)
+			}
+		}
+	}
+}
+
+// Lookup implements Source.
+func (s *KVSource) Lookup(key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.values[key]
+	return v, ok
+}
+
+func (s *KVSource) apply(entry jetstream.KeyValueEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch entry.Operation() {
+	case jetstream.KeyValueDelete, jetstream.KeyValuePurge:
+		delete(s.values, entry.Key())
+		s.logger.Info("feature flag removed", slog.String("flag", entry.Key()))
+	default:
+		s.values[entry.Key()] = string(entry.Value())
+		s.logger.Info("feature flag updated", slog.String("flag", entry.Key()), slog.String("value", string(entry.Value())))
+	}
+}
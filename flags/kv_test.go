@@ -0,0 +1,63 @@
+package flags_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/calm/errgroup"
+	"github.com/zircuit-labs/zkr-go-common/flags"
+	zkrlog "github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/messagebus/testutils"
+)
+
+func TestKVSource(t *testing.T) { //nolint:paralleltest // parallel exposes a data race in the nats server code itself
+	natsServer := testutils.NewEmbeddedServer(t)
+	t.Cleanup(natsServer.Close)
+	nc, js := natsServer.Conn(t)
+	t.Cleanup(nc.Close)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	t.Cleanup(cancel)
+
+	// the embedded server storage may outlive a test run, so use a unique bucket
+	bucket := "flags_" + xid.New().String()
+	kv, err := js.CreateKeyValue(ctx, jetstream.KeyValueConfig{Bucket: bucket})
+	require.NoError(t, err)
+	_, err = kv.PutString(ctx, "enabled", "true")
+	require.NoError(t, err)
+
+	source, err := flags.NewKVSource(ctx, js, bucket, flags.WithLogger(zkrlog.NewTestLogger(t)))
+	require.NoError(t, err)
+
+	e, err := flags.NewEvaluator([]flags.Source{source})
+	require.NoError(t, err)
+	enabled := flags.Bool("enabled", false)
+	limit := flags.Int("limit", 1)
+
+	// initial values are loaded on creation
+	assert.True(t, enabled.Get(e, flags.EvalContext{}))
+	assert.Equal(t, 1, limit.Get(e, flags.EvalContext{}))
+
+	g := errgroup.New()
+	g.Go(func() error {
+		return source.Run(ctx)
+	})
+
+	// live updates are applied while running
+	_, err = kv.PutString(ctx, "limit", "5")
+	require.NoError(t, err)
+	require.NoError(t, kv.Delete(ctx, "enabled"))
+
+	assert.Eventually(t, func() bool {
+		return limit.Get(e, flags.EvalContext{}) == 5 && !enabled.Get(e, flags.EvalContext{})
+	}, time.Second*5, time.Millisecond*10)
+
+	cancel()
+	require.NoError(t, g.Wait())
+}
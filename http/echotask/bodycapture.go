@@ -0,0 +1,213 @@
+package echotask
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/zircuit-labs/zkr-go-common/calm"
+	"github.com/zircuit-labs/zkr-go-common/http/requestid"
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const (
+	defaultCaptureMaxSize = 64 * 1024
+	defaultCapturePrefix  = "captures/"
+)
+
+// BodyStore persists captured requests, eg a storage.BlobStore or *s3.BlobStore.
+type BodyStore interface {
+	Upload(ctx context.Context, key string, data []byte) error
+}
+
+// CapturedRequest is the JSON document persisted to the BodyStore for each captured request.
+type CapturedRequest struct {
+	Time        time.Time `json:"time"`
+	RequestID   string    `json:"request_id,omitempty"`
+	Method      string    `json:"method"`
+	URI         string    `json:"uri"`
+	ContentType string    `json:"content_type"`
+	Status      int       `json:"status"`
+	Body        []byte    `json:"body"`
+	Truncated   bool      `json:"truncated"`
+}
+
+type bodyCaptureOptions struct {
+	maxSize      int
+	contentTypes []string
+	store        BodyStore
+	prefix       string
+}
+
+// BodyCaptureOption is an option func for BodyCapture and WithBodyCapture.
+type BodyCaptureOption func(options *bodyCaptureOptions)
+
+// WithCaptureMaxSize sets the maximum number of bytes of a body captured (default 64KiB).
+// Longer bodies are truncated, while the handler still reads them in full.
+func WithCaptureMaxSize(size int) BodyCaptureOption {
+	return func(options *bodyCaptureOptions) {
+		options.maxSize = size
+	}
+}
+
+// WithCaptureContentTypes sets the media types of bodies which are captured, eg "application/json" or "text/*"
+// (default application/json, application/x-www-form-urlencoded and text/*). Other bodies, eg file uploads, are not.
+func WithCaptureContentTypes(contentTypes ...string) BodyCaptureOption {
+	return func(options *bodyCaptureOptions) {
+		options.contentTypes = contentTypes
+	}
+}
+
+// WithCaptureStore persists each captured request as a CapturedRequest below the prefix (eg "captures/"),
+// keyed by date and request ID, so that it can be replayed later.
+func WithCaptureStore(store BodyStore, prefix string) BodyCaptureOption {
+	return func(options *bodyCaptureOptions) {
+		options.store = store
+		options.prefix = prefix
+	}
+}
+
+// WithBodyCapture adds the BodyCapture middleware, using the server's logger.
+func WithBodyCapture(opts ...BodyCaptureOption) Option {
+	return func(options *options) {
+		options.captureBody = true
+		options.bodyCapture = append(options.bodyCapture, opts...)
+	}
+}
+
+// BodyCapture returns a middleware which captures request bodies so that requests ending in a 5xx response
+// can be diagnosed after the fact. The captured body is added to the context of the error returned by the handler
+// (see errcontext), and is logged directly if the handler wrote the response itself. Only bodies of the configured
+// content types are captured, up to the maximum size. Headers are never captured, since they may hold credentials.
+// NOTE: Bodies often contain personal or sensitive data, so only capture routes where logging them is acceptable.
+func BodyCapture(logger *slog.Logger, opts ...BodyCaptureOption) echo.MiddlewareFunc {
+	options := bodyCaptureOptions{
+		maxSize:      defaultCaptureMaxSize,
+		contentTypes: []string{echo.MIMEApplicationJSON, echo.MIMEApplicationForm, "text/*"},
+		prefix:       defaultCapturePrefix,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			contentType := req.Header.Get(echo.HeaderContentType)
+			if req.Body == nil || req.Body == http.NoBody || !options.captures(contentType) {
+				return next(c)
+			}
+
+			// read the start of the body, leaving the handler to read it in full
+			body, readErr := io.ReadAll(io.LimitReader(req.Body, int64(options.maxSize)+1))
+			req.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), req.Body), Closer: req.Body}
+			truncated := len(body) > options.maxSize
+			if truncated {
+				body = body[:options.maxSize]
+			}
+
+			// catch panics, so that the body is also included when Recover logs them
+			err := calm.Unpanic(func() error {
+				return next(c)
+			})
+			status := responseStatus(c, err)
+			if status < http.StatusInternalServerError || readErr != nil {
+				return err
+			}
+
+			ctx := req.Context()
+			captured := CapturedRequest{
+				Time:        time.Now().UTC(),
+				Method:      req.Method,
+				URI:         req.RequestURI,
+				ContentType: contentType,
+				Status:      status,
+				Body:        body,
+				Truncated:   truncated,
+			}
+			captured.RequestID, _ = requestid.FromContext(ctx)
+			attrs := []slog.Attr{
+				slog.String("request_body", string(body)),
+				slog.Bool("request_body_truncated", truncated),
+			}
+			if options.store != nil {
+				if key, storeErr := options.persist(ctx, captured); storeErr != nil {
+					logger.WarnContext(ctx, "failed to persist captured request", log.ErrAttr(storeErr))
+				} else {
+					attrs = append(attrs, slog.String("request_body_key", key))
+				}
+			}
+
+			if err != nil {
+				return errcontext.Add(err, attrs...)
+			}
+			logger.LogAttrs(ctx, slog.LevelError, "request failed", append(attrs, slog.Int("status", status))...)
+			return nil
+		}
+	}
+}
+
+// captures reports whether bodies of the content type are captured.
+func (o bodyCaptureOptions) captures(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range o.contentTypes {
+		if prefix, ok := strings.CutSuffix(t, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+		} else if mediaType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// persist uploads the captured request to the store, returning its key.
+// The key uses a generated ID, since the request ID may be chosen by the client.
+func (o bodyCaptureOptions) persist(ctx context.Context, captured CapturedRequest) (string, error) {
+	key := o.prefix + captured.Time.Format(time.DateOnly) + "/" + requestid.New() + ".json"
+	data, err := json.Marshal(captured)
+	if err != nil {
+		return "", stacktrace.Wrap(err)
+	}
+	// the request may have been cancelled, but the capture is still wanted
+	if err := o.store.Upload(context.WithoutCancel(ctx), key, data); err != nil {
+		return "", stacktrace.Wrap(err)
+	}
+	return key, nil
+}
+
+// responseStatus returns the status of the response, or else the status the error will be rendered with.
+func responseStatus(c echo.Context, err error) int {
+	if err == nil || c.Response().Committed {
+		return c.Response().Status
+	}
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		var internal *echo.HTTPError
+		if errors.As(he.Internal, &internal) {
+			he = internal
+		}
+		return he.Code
+	}
+	return http.StatusInternalServerError
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
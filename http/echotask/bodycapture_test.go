@@ -0,0 +1,146 @@
+package echotask_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/http/echotask"
+	"github.com/zircuit-labs/zkr-go-common/http/requestid"
+	"github.com/zircuit-labs/zkr-go-common/stores/storage"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+)
+
+func TestBodyCapture(t *testing.T) {
+	t.Parallel()
+
+	const payload = `{"to":"0xabc","amount":"12"}`
+	testCases := []struct {
+		name        string
+		contentType string
+		handler     echo.HandlerFunc
+		captured    string
+		truncated   bool
+	}{
+		{
+			name:        "server error",
+			contentType: echo.MIMEApplicationJSON,
+			handler:     func(echo.Context) error { return ErrTest },
+			captured:    payload,
+		},
+		{
+			name:        "truncated",
+			contentType: echo.MIMEApplicationJSONCharsetUTF8,
+			handler: func(echo.Context) error {
+				return echo.NewHTTPError(http.StatusBadGateway, "upstream failed")
+			},
+			captured:  payload[:16],
+			truncated: true,
+		},
+		{
+			name:        "panic",
+			contentType: "text/plain",
+			handler:     func(echo.Context) error { panic("malformed") },
+			captured:    payload,
+		},
+		{
+			name:        "client error",
+			contentType: echo.MIMEApplicationJSON,
+			handler:     func(echo.Context) error { return echo.ErrBadRequest },
+		},
+		{
+			name:        "other content type",
+			contentType: echo.MIMEOctetStream,
+			handler:     func(echo.Context) error { return ErrTest },
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			store := storage.NewMemoryBlobStore()
+			opts := []echotask.BodyCaptureOption{echotask.WithCaptureStore(store, "failed/")}
+			if tc.truncated {
+				opts = append(opts, echotask.WithCaptureMaxSize(16))
+			}
+			middleware := echotask.BodyCapture(slog.New(slog.DiscardHandler), opts...)
+
+			req := httptest.NewRequest(http.MethodPost, "/transfers?dry=true", strings.NewReader(payload))
+			req.Header.Set(echo.HeaderContentType, tc.contentType)
+			req = req.WithContext(requestid.NewContext(req.Context(), "../req-1"))
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			// the handler still reads the whole body
+			err := middleware(func(c echo.Context) error {
+				body, err := io.ReadAll(c.Request().Body)
+				require.NoError(t, err)
+				assert.Equal(t, payload, string(body))
+				return tc.handler(c)
+			})(c)
+			require.Error(t, err)
+			if tc.name == "panic" {
+				assert.Equal(t, errclass.Panic, errclass.GetClass(err))
+			}
+
+			keys, listErr := store.List(t.Context(), "")
+			require.NoError(t, listErr)
+			errCtx := errcontext.Get(err)
+			if tc.captured == "" {
+				assert.Empty(t, errCtx)
+				assert.Empty(t, keys)
+				return
+			}
+
+			attrs := map[string]slog.Value{}
+			for _, attr := range errCtx.Flatten() {
+				attrs[attr.Key] = attr.Value
+			}
+			assert.Equal(t, tc.captured, attrs["request_body"].String())
+			assert.Equal(t, tc.truncated, attrs["request_body_truncated"].Bool())
+
+			// the request is persisted for replay, under a generated ID rather than that chosen by the client
+			require.Len(t, keys, 1)
+			assert.Regexp(t, `^failed/\d{4}-\d{2}-\d{2}/[0-9a-v]{20}\.json$`, keys[0])
+			assert.Equal(t, keys[0], attrs["request_body_key"].String())
+			data, getErr := store.Get(t.Context(), keys[0])
+			require.NoError(t, getErr)
+			var captured echotask.CapturedRequest
+			require.NoError(t, json.Unmarshal(data, &captured))
+			assert.Equal(t, "../req-1", captured.RequestID)
+			assert.Equal(t, http.MethodPost, captured.Method)
+			assert.Equal(t, "/transfers?dry=true", captured.URI)
+			assert.Equal(t, tc.captured, string(captured.Body))
+			assert.Equal(t, tc.truncated, captured.Truncated)
+			assert.GreaterOrEqual(t, captured.Status, http.StatusInternalServerError)
+		})
+	}
+}
+
+func TestBodyCaptureWrittenResponse(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	middleware := echotask.BodyCapture(slog.New(slog.NewJSONHandler(buf, nil)))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"bad":true}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+
+	// without an error to add the body to, it is logged
+	err := middleware(func(c echo.Context) error {
+		return c.String(http.StatusServiceUnavailable, "unavailable")
+	})(c)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, buf.String(), `"msg":"request failed","request_body":"{\"bad\":true}","request_body_truncated":false,"status":503`)
+}
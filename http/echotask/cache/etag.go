@@ -0,0 +1,219 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	headerETag        = "ETag"
+	headerIfNoneMatch = "If-None-Match"
+)
+
+// ETag computes an entity tag for the given response body.
+// Weak tags (prefixed with W/) indicate semantic rather than byte-for-byte equivalence.
+func ETag(body []byte, weak bool) string {
+	sum := sha256.Sum256(body)
+	tag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	if weak {
+		return "W/" + tag
+	}
+	return tag
+}
+
+// NotModified reports whether the conditional headers of a GET or HEAD request show
+// that the client already has the current representation, identified by etag and/or lastModified.
+// Either may be empty/zero if unknown. As per RFC 9110, If-Modified-Since is ignored when
+// If-None-Match is present, and If-None-Match uses weak comparison.
+func NotModified(req *http.Request, etag string, lastModified time.Time) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := req.Header.Get(headerIfNoneMatch); inm != "" {
+		return etag != "" && matchesETag(inm, etag)
+	}
+
+	if ims := req.Header.Get(echo.HeaderIfModifiedSince); ims != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		// HTTP dates have a resolution of one second
+		return !lastModified.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
+
+// matchesETag reports whether the If-None-Match header value matches etag using weak comparison.
+func matchesETag(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for candidate := range strings.SplitSeq(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ConditionalJSON sends a JSON response with an ETag (and Last-Modified, if not zero) header,
+// or an empty 304 Not Modified response when the request shows the client already has it.
+func ConditionalJSON(c echo.Context, code int, i any, lastModified time.Time) error {
+	body, err := json.Marshal(i)
+	if err != nil {
+		return err
+	}
+
+	etag := ETag(body, false)
+	header := c.Response().Header()
+	header.Set(headerETag, etag)
+	if !lastModified.IsZero() {
+		header.Set(echo.HeaderLastModified, lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if code == http.StatusOK && NotModified(c.Request(), etag, lastModified) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.JSONBlob(code, body)
+}
+
+type etagOptions struct {
+	weak    bool
+	skipper func(c echo.Context) bool
+}
+
+// ETagOption is an option func for ETagMiddleware.
+type ETagOption func(options *etagOptions)
+
+// WithWeakETags generates weak rather than strong entity tags.
+func WithWeakETags() ETagOption {
+	return func(options *etagOptions) {
+		options.weak = true
+	}
+}
+
+// WithETagSkipper sets a func to identify requests which should bypass the middleware,
+// such as streaming responses which must not be buffered.
+func WithETagSkipper(skipper func(c echo.Context) bool) ETagOption {
+	return func(options *etagOptions) {
+		options.skipper = skipper
+	}
+}
+
+// ETagMiddleware buffers successful GET and HEAD responses in order to set an ETag header,
+// and replaces them with an empty 304 Not Modified response when the request's If-None-Match
+// (or If-Modified-Since, if the handler sets Last-Modified) shows the client already has them.
+// An ETag set by the handler is used as is.
+// When used with ResponseCacheMiddleware, it must be registered first (ie outside it)
+// so that cached responses are also tagged.
+func ETagMiddleware(opts ...ETagOption) echo.MiddlewareFunc {
+	options := etagOptions{
+		skipper: func(echo.Context) bool { return false },
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if (req.Method != http.MethodGet && req.Method != http.MethodHead) || options.skipper(c) {
+				return next(c)
+			}
+
+			res := c.Response()
+			original := res.Writer
+			buf := &etagBuffer{writer: original}
+			res.Writer = buf
+			defer func() {
+				res.Writer = original
+			}()
+
+			if err := next(c); err != nil {
+				// nothing was written, leaving the error handler to respond
+				if !buf.wroteHeader {
+					return err
+				}
+				buf.flush()
+				return err
+			}
+
+			if buf.status() != http.StatusOK {
+				buf.flush()
+				return nil
+			}
+
+			header := original.Header()
+			etag := header.Get(headerETag)
+			if etag == "" {
+				etag = ETag(buf.body.Bytes(), options.weak)
+				header.Set(headerETag, etag)
+			}
+
+			var lastModified time.Time
+			if lm := header.Get(echo.HeaderLastModified); lm != "" {
+				lastModified, _ = http.ParseTime(lm)
+			}
+
+			if NotModified(req, etag, lastModified) {
+				header.Del(echo.HeaderContentType)
+				header.Del(echo.HeaderContentLength)
+				res.Status = http.StatusNotModified
+				original.WriteHeader(http.StatusNotModified)
+				return nil
+			}
+
+			buf.flush()
+			return nil
+		}
+	}
+}
+
+// etagBuffer holds the response until it is complete, since the ETag header depends on the full body.
+type etagBuffer struct {
+	writer      http.ResponseWriter
+	body        bytes.Buffer
+	code        int
+	wroteHeader bool
+}
+
+func (b *etagBuffer) Header() http.Header {
+	return b.writer.Header()
+}
+
+func (b *etagBuffer) Write(data []byte) (int, error) {
+	if !b.wroteHeader {
+		b.WriteHeader(http.StatusOK)
+	}
+	return b.body.Write(data)
+}
+
+func (b *etagBuffer) WriteHeader(statusCode int) {
+	if b.wroteHeader {
+		return
+	}
+	b.code = statusCode
+	b.wroteHeader = true
+}
+
+func (b *etagBuffer) status() int {
+	if !b.wroteHeader {
+		return http.StatusOK
+	}
+	return b.code
+}
+
+// flush sends the buffered response to the underlying writer.
+func (b *etagBuffer) flush() {
+	b.writer.WriteHeader(b.status())
+	_, _ = b.writer.Write(b.body.Bytes())
+}
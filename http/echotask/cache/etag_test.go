@@ -0,0 +1,255 @@
+package cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestETag(t *testing.T) {
+	t.Parallel()
+
+	strong := ETag([]byte(`{"status":"ok"}`), false)
+	weak := ETag([]byte(`{"status":"ok"}`), true)
+
+	assert.Equal(t, strong, ETag([]byte(`{"status":"ok"}`), false))
+	assert.NotEqual(t, strong, ETag([]byte(`{"status":"failed"}`), false))
+	assert.Equal(t, "W/"+strong, weak)
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, strong)
+}
+
+func TestNotModified(t *testing.T) {
+	t.Parallel()
+
+	etag := ETag([]byte("body"), false)
+	lastModified := time.Date(2025, 1, 1, 12, 0, 0, 500, time.UTC)
+
+	tests := []struct {
+		name         string
+		method       string
+		headers      map[string]string
+		etag         string
+		lastModified time.Time
+		want         bool
+	}{
+		{
+			name: "no conditional headers",
+			etag: etag,
+		},
+		{
+			name:    "matching etag",
+			headers: map[string]string{headerIfNoneMatch: etag},
+			etag:    etag,
+			want:    true,
+		},
+		{
+			name:    "matching etag in list",
+			headers: map[string]string{headerIfNoneMatch: `"other", ` + etag},
+			etag:    etag,
+			want:    true,
+		},
+		{
+			name:    "weak comparison",
+			headers: map[string]string{headerIfNoneMatch: "W/" + etag},
+			etag:    etag,
+			want:    true,
+		},
+		{
+			name:    "wildcard",
+			headers: map[string]string{headerIfNoneMatch: "*"},
+			etag:    etag,
+			want:    true,
+		},
+		{
+			name:    "different etag",
+			headers: map[string]string{headerIfNoneMatch: `"other"`},
+			etag:    etag,
+		},
+		{
+			name:    "not GET or HEAD",
+			method:  http.MethodPost,
+			headers: map[string]string{headerIfNoneMatch: etag},
+			etag:    etag,
+		},
+		{
+			name:         "not modified since",
+			headers:      map[string]string{echo.HeaderIfModifiedSince: lastModified.Format(http.TimeFormat)},
+			lastModified: lastModified,
+			want:         true,
+		},
+		{
+			name:         "modified since",
+			headers:      map[string]string{echo.HeaderIfModifiedSince: lastModified.Add(-time.Minute).Format(http.TimeFormat)},
+			lastModified: lastModified,
+		},
+		{
+			name:         "invalid date",
+			headers:      map[string]string{echo.HeaderIfModifiedSince: "yesterday"},
+			lastModified: lastModified,
+		},
+		{
+			name: "if-none-match takes precedence",
+			headers: map[string]string{
+				headerIfNoneMatch:          `"other"`,
+				echo.HeaderIfModifiedSince: lastModified.Format(http.TimeFormat),
+			},
+			etag:         etag,
+			lastModified: lastModified,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "/", http.NoBody)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			assert.Equal(t, tt.want, NotModified(req, tt.etag, tt.lastModified))
+		})
+	}
+}
+
+func TestConditionalJSON(t *testing.T) {
+	t.Parallel()
+
+	e := echo.New()
+	lastModified := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	handler := func(c echo.Context) error {
+		return ConditionalJSON(c, http.StatusOK, map[string]string{"status": "ok"}, lastModified)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/status", http.NoBody)
+	rec := httptest.NewRecorder()
+	require.NoError(t, handler(e.NewContext(req, rec)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+	etag := rec.Header().Get(headerETag)
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, lastModified.Format(http.TimeFormat), rec.Header().Get(echo.HeaderLastModified))
+
+	req = httptest.NewRequest(http.MethodGet, "/status", http.NoBody)
+	req.Header.Set(headerIfNoneMatch, etag)
+	rec = httptest.NewRecorder()
+	require.NoError(t, handler(e.NewContext(req, rec)))
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+	assert.Equal(t, etag, rec.Header().Get(headerETag))
+}
+
+func TestETagMiddleware(t *testing.T) {
+	t.Parallel()
+
+	status := "ok"
+	e := echo.New()
+	e.Use(ETagMiddleware())
+	e.GET("/status", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]string{"status": status})
+	})
+	e.GET("/fail", func(c echo.Context) error {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"status": "failed"})
+	})
+	e.GET("/error", func(echo.Context) error {
+		return echo.NewHTTPError(http.StatusTeapot, "teapot")
+	})
+	e.GET("/modified", func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderLastModified, "Wed, 01 Jan 2025 12:00:00 GMT")
+		return c.String(http.StatusOK, "content")
+	})
+
+	serve := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, http.NoBody)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("/status", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+	etag := rec.Header().Get(headerETag)
+	require.NotEmpty(t, etag)
+
+	// unchanged content gives a 304 with no body
+	rec = serve("/status", map[string]string{headerIfNoneMatch: etag})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentType))
+
+	// changed content gives the full response
+	status = "draining"
+	rec = serve("/status", map[string]string{headerIfNoneMatch: etag})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"draining"}`, rec.Body.String())
+	assert.NotEqual(t, etag, rec.Header().Get(headerETag))
+
+	// unsuccessful responses are passed through untagged
+	rec = serve("/fail", map[string]string{headerIfNoneMatch: "*"})
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Empty(t, rec.Header().Get(headerETag))
+
+	// errors are left to the error handler
+	rec = serve("/error", map[string]string{headerIfNoneMatch: "*"})
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+	assert.Empty(t, rec.Header().Get(headerETag))
+
+	// Last-Modified set by the handler is honored
+	rec = serve("/modified", map[string]string{echo.HeaderIfModifiedSince: "Wed, 01 Jan 2025 12:00:00 GMT"})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	rec = serve("/modified", map[string]string{echo.HeaderIfModifiedSince: "Tue, 31 Dec 2024 12:00:00 GMT"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "content", rec.Body.String())
+}
+
+func TestETagMiddlewareWithResponseCache(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	e := echo.New()
+	e.Use(ETagMiddleware(WithWeakETags()), ResponseCacheMiddleware(NewMemory(100, time.Minute)))
+	e.GET("/status", func(c echo.Context) error {
+		calls++
+		return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", http.NoBody))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get(headerETag)
+	assert.Regexp(t, `^W/"`, etag)
+
+	// the cached response is given the same tag
+	req := httptest.NewRequest(http.MethodGet, "/status", http.NoBody)
+	req.Header.Set(headerIfNoneMatch, etag)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+	assert.Equal(t, 1, calls)
+}
+
+func TestETagMiddlewareSkipper(t *testing.T) {
+	t.Parallel()
+
+	e := echo.New()
+	e.Use(ETagMiddleware(WithETagSkipper(func(echo.Context) bool { return true })))
+	e.GET("/status", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", http.NoBody))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get(headerETag))
+}
@@ -0,0 +1,164 @@
+package echotask
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/zircuit-labs/zkr-go-common/http/requestid"
+	"github.com/zircuit-labs/zkr-go-common/metrics"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// Variants reported by CanaryVariant and used as the "variant" metric label.
+const (
+	VariantStable = "stable"
+	VariantCanary = "canary"
+)
+
+// canaryBuckets is the resolution of the canary percentage, allowing eg 0.25%.
+const canaryBuckets = 10000
+
+const canaryVariantKey = "echotask.canary.variant"
+
+var ErrInvalidCanaryPercent = errors.New("canary percent must be between 0 and 100")
+
+type canaryOptions struct {
+	key        func(c echo.Context) string
+	registerer prometheus.Registerer
+}
+
+// CanaryOption is an option func for Canary.
+type CanaryOption func(options *canaryOptions)
+
+// WithCanaryKey sets the func returning the key which determines the variant of a request.
+// Requests with the same key always get the same variant, and requests with an empty key get the stable one.
+// Defaults to the request ID.
+func WithCanaryKey(key func(c echo.Context) string) CanaryOption {
+	return func(options *canaryOptions) {
+		options.key = key
+	}
+}
+
+// WithCanaryKeyHeader determines the variant of a request from a request header, eg an API key,
+// so that each client consistently gets the same variant.
+func WithCanaryKeyHeader(header string) CanaryOption {
+	return WithCanaryKey(func(c echo.Context) string {
+		return c.Request().Header.Get(header)
+	})
+}
+
+// WithCanaryMetrics records the number and latency of requests by route, variant and status code
+// with the given registerer, so that the variants can be compared.
+func WithCanaryMetrics(registerer prometheus.Registerer) CanaryOption {
+	return func(options *canaryOptions) {
+		options.registerer = registerer
+	}
+}
+
+// Canary returns a route middleware which sends percent (0-100) of requests to the canary handler
+// instead of the route's own (stable) handler, eg to try out a new implementation of a query in-process:
+//
+//	r.GET("/orders", listOrders, canary)
+//
+// Requests are assigned a variant by hashing their key, so the same key always gets the same variant.
+func Canary(canary echo.HandlerFunc, percent float64, opts ...CanaryOption) (echo.MiddlewareFunc, error) {
+	if percent < 0 || percent > 100 {
+		return nil, errclass.WrapAs(stacktrace.Wrap(fmt.Errorf("%w: %v", ErrInvalidCanaryPercent, percent)), errclass.Persistent)
+	}
+
+	options := canaryOptions{
+		key: func(c echo.Context) string {
+			id, _ := requestid.FromContext(c.Request().Context())
+			return id
+		},
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var metrics *canaryMetrics
+	if options.registerer != nil {
+		var err error
+		if metrics, err = registerCanaryMetrics(options.registerer); err != nil {
+			return nil, err
+		}
+	}
+
+	threshold := uint64(percent * canaryBuckets / 100)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			variant, handler := VariantStable, next
+			if key := options.key(c); key != "" && canaryBucket(key) < threshold {
+				variant, handler = VariantCanary, canary
+			}
+			c.Set(canaryVariantKey, variant)
+
+			start := time.Now()
+			err := handler(c)
+			metrics.observe(c, variant, start, err)
+			return err
+		}
+	}, nil
+}
+
+// CanaryVariant returns the variant chosen by Canary for the request, or an empty string if none was.
+func CanaryVariant(c echo.Context) string {
+	variant, _ := c.Get(canaryVariantKey).(string)
+	return variant
+}
+
+func canaryBucket(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum64() % canaryBuckets
+}
+
+type canaryMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+func registerCanaryMetrics(registerer prometheus.Registerer) (*canaryMetrics, error) {
+	requests, err := metrics.Register(registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_canary_requests_total",
+		Help: "Number of requests handled by each variant of a canaried route.",
+	}, []string{"route", "variant", "code"}))
+	if err != nil {
+		return nil, err
+	}
+	duration, err := metrics.Register(registerer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_canary_request_duration_seconds",
+		Help:    "Latency of requests handled by each variant of a canaried route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "variant"}))
+	if err != nil {
+		return nil, err
+	}
+	return &canaryMetrics{requests: requests, duration: duration}, nil
+}
+
+// observe records a handled request. It is a no-op when metrics are not enabled.
+func (m *canaryMetrics) observe(c echo.Context, variant string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	// The error is not yet rendered, so take the status it will be rendered with
+	code := c.Response().Status
+	if err != nil {
+		code = http.StatusInternalServerError
+		var he *echo.HTTPError
+		if errors.As(err, &he) {
+			code = he.Code
+		}
+	}
+	m.requests.WithLabelValues(c.Path(), variant, strconv.Itoa(code)).Inc()
+	m.duration.WithLabelValues(c.Path(), variant).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,170 @@
+package echotask_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/http/echotask"
+	"github.com/zircuit-labs/zkr-go-common/http/requestid"
+)
+
+// canaryServer serves /query, answering with the variant which handled the request.
+func canaryServer(t *testing.T, percent float64, opts ...echotask.CanaryOption) *echo.Echo {
+	t.Helper()
+	canary, err := echotask.Canary(func(c echo.Context) error {
+		return c.String(http.StatusOK, echotask.VariantCanary)
+	}, percent, opts...)
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.Use(echotask.RequestID())
+	e.GET("/query", func(c echo.Context) error {
+		return c.String(http.StatusOK, echotask.VariantStable)
+	}, canary)
+	return e
+}
+
+func serveCanary(e *echo.Echo, header, value string) string {
+	req := httptest.NewRequest(http.MethodGet, "/query", http.NoBody)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec.Body.String()
+}
+
+func TestCanary(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		percent float64
+		min     int
+		max     int
+	}{
+		{name: "none", percent: 0, min: 0, max: 0},
+		{name: "all", percent: 100, min: 1000, max: 1000},
+		{name: "some", percent: 20, min: 150, max: 250},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := canaryServer(t, tt.percent)
+			canaried := 0
+			for i := range 1000 {
+				if serveCanary(e, requestid.Header, fmt.Sprintf("request-%d", i)) == echotask.VariantCanary {
+					canaried++
+				}
+			}
+			assert.GreaterOrEqual(t, canaried, tt.min)
+			assert.LessOrEqual(t, canaried, tt.max)
+		})
+	}
+}
+
+func TestCanaryConsistentByKey(t *testing.T) {
+	t.Parallel()
+
+	e := canaryServer(t, 50, echotask.WithCanaryKeyHeader("X-Api-Key"))
+	variants := map[string]bool{}
+	for i := range 20 {
+		key := fmt.Sprintf("key-%d", i)
+		variant := serveCanary(e, "X-Api-Key", key)
+		variants[variant] = true
+
+		// the same key always gets the same variant
+		for range 5 {
+			assert.Equal(t, variant, serveCanary(e, "X-Api-Key", key))
+		}
+	}
+	assert.Len(t, variants, 2)
+
+	// requests without a key get the stable variant
+	for range 20 {
+		assert.Equal(t, echotask.VariantStable, serveCanary(e, "", ""))
+	}
+}
+
+func TestCanaryVariant(t *testing.T) {
+	t.Parallel()
+
+	canary, err := echotask.Canary(func(c echo.Context) error {
+		return c.String(http.StatusOK, echotask.CanaryVariant(c))
+	}, 100)
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.Use(echotask.RequestID())
+	e.GET("/query", func(c echo.Context) error {
+		return c.String(http.StatusOK, echotask.CanaryVariant(c))
+	}, canary)
+	assert.Equal(t, echotask.VariantCanary, serveCanary(e, "", ""))
+
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", http.NoBody), httptest.NewRecorder())
+	assert.Empty(t, echotask.CanaryVariant(c))
+}
+
+func TestCanaryMetrics(t *testing.T) {
+	t.Parallel()
+
+	registry := prometheus.NewRegistry()
+	canary, err := echotask.Canary(func(_ echo.Context) error {
+		return echo.NewHTTPError(http.StatusTeapot)
+	}, 50, echotask.WithCanaryKeyHeader("X-Api-Key"), echotask.WithCanaryMetrics(registry))
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.GET("/query", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, canary)
+	for i := range 100 {
+		serveCanary(e, "X-Api-Key", fmt.Sprintf("key-%d", i))
+	}
+
+	// requests are counted by variant and status code
+	counts := map[string]float64{}
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "http_canary_requests_total" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			assert.Equal(t, "/query", labels["route"])
+			counts[labels["variant"]+"/"+labels["code"]] = m.GetCounter().GetValue()
+		}
+	}
+	assert.Len(t, counts, 2)
+	assert.Positive(t, counts["stable/200"])
+	assert.Positive(t, counts["canary/418"])
+	assert.InDelta(t, 100, counts["stable/200"]+counts["canary/418"], 0)
+	assert.Equal(t, 2, testutil.CollectAndCount(registry, "http_canary_request_duration_seconds"))
+
+	// metrics may be shared between routes
+	_, err = echotask.Canary(func(_ echo.Context) error { return nil }, 50, echotask.WithCanaryMetrics(registry))
+	require.NoError(t, err)
+}
+
+func TestCanaryInvalidPercent(t *testing.T) {
+	t.Parallel()
+
+	for _, percent := range []float64{-1, 101} {
+		_, err := echotask.Canary(func(_ echo.Context) error { return nil }, percent)
+		require.ErrorIs(t, err, echotask.ErrInvalidCanaryPercent)
+	}
+}
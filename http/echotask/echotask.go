@@ -20,8 +20,11 @@ import (
 	"github.com/zircuit-labs/zkr-go-common/http/echotask/cache"
 	"github.com/zircuit-labs/zkr-go-common/http/echotask/healthcheck"
 	"github.com/zircuit-labs/zkr-go-common/http/port"
+	"github.com/zircuit-labs/zkr-go-common/http/requestid"
 	"github.com/zircuit-labs/zkr-go-common/log"
 	"github.com/zircuit-labs/zkr-go-common/log/identity"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcode"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errseverity"
 	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
 )
 
@@ -40,6 +43,10 @@ type RouteRegistrant interface {
 
 const (
 	healthCheckRoute = "/healthcheck"
+	livenessRoute    = "/livez"
+	readinessRoute   = "/readyz"
+	startupRoute     = "/startupz"
+	tasksRoute       = "/tasksz"
 	metricsRoute     = "/metrics"
 )
 
@@ -52,6 +59,9 @@ type echoServerConfig struct {
 	Port               int
 	DisableCompression bool `koanf:"nogzip"`
 	Prometheus         string
+	// Debug includes full error details in error responses.
+	// It must never be enabled in production, where errors are sanitized instead.
+	Debug bool
 }
 
 type options struct {
@@ -60,7 +70,15 @@ type options struct {
 	middlewares []echo.MiddlewareFunc
 	cleanup     func()
 	healthcheck healthChecker
+	probes      *healthcheck.Probes
+	tasks       healthcheck.TaskStatuses
+	catalog     *errcode.Catalog
+	severities  *errseverity.Mapping
 	logger      *slog.Logger
+	captureBody bool
+	bodyCapture []BodyCaptureOption
+
+	streamGracePeriod time.Duration
 }
 
 type healthChecker interface {
@@ -98,6 +116,36 @@ func WithHealthCheck(checker healthChecker) Option {
 	}
 }
 
+// WithProbes adds liveness, readiness, and startup probe routes to be served.
+func WithProbes(probes *healthcheck.Probes) Option {
+	return func(options *options) {
+		options.probes = probes
+	}
+}
+
+// WithTaskStatus adds a route serving the status of each task (eg of the task.Manager running this server).
+func WithTaskStatus(tasks healthcheck.TaskStatuses) Option {
+	return func(options *options) {
+		options.tasks = tasks
+	}
+}
+
+// WithErrorCatalog renders the message of errors with a code from the catalog,
+// in the locale requested by the client (see LocalizedErrorHandler).
+func WithErrorCatalog(catalog *errcode.Catalog) Option {
+	return func(options *options) {
+		options.catalog = catalog
+	}
+}
+
+// WithSeverityMapping logs each error resulting in a server error at the level of its severity,
+// with a "severity" attribute for alert rules (see SeverityLoggingErrorHandler).
+func WithSeverityMapping(severities *errseverity.Mapping) Option {
+	return func(options *options) {
+		options.severities = severities
+	}
+}
+
 // WithCleanup sets a cleanup func to be called after server shutdown.
 func WithCleanup(f func()) Option {
 	return func(options *options) {
@@ -113,6 +161,14 @@ func WithMemoryCache(maxItems int, ttl time.Duration) Option {
 	}
 }
 
+// WithETags adds ETag and conditional request support to GET and HEAD responses.
+// It is applied ahead of all other middlewares (including WithMemoryCache) so that cached responses are tagged too.
+func WithETags(opts ...cache.ETagOption) Option {
+	return func(options *options) {
+		options.middlewares = append([]echo.MiddlewareFunc{cache.ETagMiddleware(opts...)}, options.middlewares...)
+	}
+}
+
 // WithMiddleware adds a middleware to be served.
 func WithMiddleware(middleware echo.MiddlewareFunc) Option {
 	return func(options *options) {
@@ -127,6 +183,9 @@ type Server struct {
 	port    int
 	cleanup func()
 	logger  *slog.Logger
+
+	streams           *streamTracker
+	streamGracePeriod time.Duration
 }
 
 // NewServer creates an HTTP(S) server using the echo framework that implements the Task interface.
@@ -139,8 +198,9 @@ func NewServer(cfg *config.Configuration, cfgPath string, opts ...Option) (*Serv
 
 	// Set up default options
 	options := options{
-		name:   "echo server",
-		logger: log.NewNilLogger(),
+		name:              "echo server",
+		logger:            log.NewNilLogger(),
+		streamGracePeriod: defaultStreamGracePeriod,
 	}
 
 	// Apply provided options
@@ -148,6 +208,9 @@ func NewServer(cfg *config.Configuration, cfgPath string, opts ...Option) (*Serv
 		opt(&options)
 	}
 
+	// Include the request ID in all logs made with the request context
+	options.logger = slog.New(requestid.NewHandler(options.logger.Handler()))
+
 	// Determine appropriate port
 	var p int
 	var err error
@@ -164,6 +227,11 @@ func NewServer(cfg *config.Configuration, cfgPath string, opts ...Option) (*Serv
 	e := echo.New()
 	e.HideBanner = true
 	e.HidePort = true
+	e.Debug = serverConfig.Debug
+	e.HTTPErrorHandler = LocalizedErrorHandler(serverConfig.Debug, options.catalog)
+	if options.severities != nil {
+		e.HTTPErrorHandler = SeverityLoggingErrorHandler(e.HTTPErrorHandler, options.severities, options.logger)
+	}
 	// include DataDog trace middleware if the env var is set
 	if _, ok := os.LookupEnv("DD_APM_ENABLED"); ok {
 		name, id := identity.WhoAmI()
@@ -172,8 +240,14 @@ func NewServer(cfg *config.Configuration, cfgPath string, opts ...Option) (*Serv
 			ddtrace.WithCustomTag("instance", id),
 		))
 	}
+	streams := newStreamTracker()
+	e.Use(RequestID())
+	e.Use(streams.middleware)
 	e.Use(middleware.CORS())
 	e.Use(Recover(options.logger))
+	if options.captureBody {
+		e.Use(BodyCapture(options.logger, options.bodyCapture...))
+	}
 	e.Pre(middleware.RemoveTrailingSlash())
 
 	// enable gzip compression
@@ -210,12 +284,25 @@ func NewServer(cfg *config.Configuration, cfgPath string, opts ...Option) (*Serv
 		e.GET(healthCheckRoute, healthcheck.New(options.healthcheck).Handle)
 	}
 
+	if options.probes != nil {
+		e.GET(livenessRoute, options.probes.LiveHandler)
+		e.GET(readinessRoute, options.probes.ReadyHandler)
+		e.GET(startupRoute, options.probes.StartupHandler)
+	}
+
+	if options.tasks != nil {
+		e.GET(tasksRoute, healthcheck.TasksHandler(options.tasks))
+	}
+
 	return &Server{
 		e:       e,
 		port:    p,
 		name:    options.name,
 		cleanup: options.cleanup,
 		logger:  options.logger,
+
+		streams:           streams,
+		streamGracePeriod: options.streamGracePeriod,
 	}, nil
 }
 
@@ -242,6 +329,8 @@ func (t *Server) Run(ctx context.Context) error {
 	// This is also blocking
 	g.Go(func() error {
 		<-ctx.Done()
+		// Streams would otherwise keep the shutdown waiting, or be dropped abruptly
+		t.closeStreams()
 		return t.e.Shutdown(context.Background())
 	})
 
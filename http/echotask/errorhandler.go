@@ -0,0 +1,145 @@
+package echotask
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/xerrors"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcode"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errseverity"
+)
+
+// ErrorHandler returns an echo.HTTPErrorHandler that renders errors as JSON.
+// When debug is false (ie in production), errors are sanitized with xerrors.Sanitize
+// before being rendered so that stack traces and internal error context are never
+// returned to clients, errors that are not an *echo.HTTPError are reported only as an
+// internal server error, and *echo.HTTPError messages which are errors are replaced by
+// the status text. The error class and code are included when known.
+func ErrorHandler(debug bool) echo.HTTPErrorHandler {
+	return LocalizedErrorHandler(debug, nil)
+}
+
+// LocalizedErrorHandler is the same as ErrorHandler, except that the message of errors with a code
+// (see errcode.WrapAs) is rendered from the catalog, in the locale best matching the Accept-Language
+// header of the request. If the catalog is nil or has no message for the code, the message is unchanged.
+func LocalizedErrorHandler(debug bool, catalog *errcode.Catalog) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		if c.Response().Committed {
+			return
+		}
+
+		// Find any HTTPError before sanitizing, since sanitizing removes the error chain.
+		var he *echo.HTTPError
+		isHTTPError := errors.As(err, &he)
+		if !debug {
+			err = xerrors.Sanitize(err)
+		}
+
+		code := http.StatusInternalServerError
+		body := echo.Map{"message": http.StatusText(http.StatusInternalServerError)}
+
+		if isHTTPError {
+			var internal *echo.HTTPError
+			if errors.As(he.Internal, &internal) {
+				he = internal
+			}
+			code = he.Code
+			switch m := he.Message.(type) {
+			case string:
+				body["message"] = m
+			case error:
+				// the message of an error may include internal details (eg hostnames)
+				if debug {
+					body["message"] = m.Error()
+				} else {
+					body["message"] = http.StatusText(code)
+				}
+			default:
+				body["message"] = m
+			}
+		}
+
+		if class := errclass.GetClass(err); class != errclass.Unknown && class != errclass.Nil {
+			body["class"] = class.String()
+		}
+		if detail, ok := errorCode(err, he); ok {
+			body["code"] = detail.Code
+			if catalog != nil {
+				locale := catalog.Match(c.Request().Header.Get("Accept-Language"))
+				if message, renderErr := catalog.Render(detail.Code, locale, detail.Params); renderErr == nil {
+					body["message"] = message
+					c.Response().Header().Set("Content-Language", locale)
+				} else {
+					c.Logger().Error(renderErr)
+				}
+			}
+		}
+		if debug {
+			body["error"] = err.Error()
+		}
+
+		if c.Request().Method == http.MethodHead {
+			err = c.NoContent(code)
+		} else {
+			err = c.JSON(code, body)
+		}
+		if err != nil {
+			c.Logger().Error(err)
+		}
+	}
+}
+
+// errorCode returns the code of the error, or else of the message of the HTTPError.
+func errorCode(err error, he *echo.HTTPError) (errcode.Detail, bool) {
+	if detail, ok := errcode.Get(err); ok {
+		return detail, true
+	}
+	if he != nil {
+		if m, ok := he.Message.(error); ok {
+			return errcode.Get(m)
+		}
+	}
+	return errcode.Detail{}, false
+}
+
+// SeverityLoggingErrorHandler wraps an error handler, logging each error which results in a server error (5xx)
+// at the level of its severity (see errseverity), along with a "severity" attribute for alert rules to match.
+// Errors of severity None are not logged, and nor are client errors (4xx), which warrant no alert.
+func SeverityLoggingErrorHandler(next echo.HTTPErrorHandler, severities *errseverity.Mapping, logger *slog.Logger) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		next(err, c)
+
+		status := c.Response().Status
+		if status < http.StatusInternalServerError {
+			return
+		}
+		severity := severityOf(severities, err)
+		if severity == errseverity.None {
+			return
+		}
+		logger.LogAttrs(c.Request().Context(), severity.Level(), "request failed",
+			slog.String("severity", severity.String()),
+			slog.Int("status", status),
+			log.ErrAttr(err),
+		)
+	}
+}
+
+// severityOf returns the severity of the error, or if it has neither class nor code, of the message of the HTTPError.
+func severityOf(severities *errseverity.Mapping, err error) errseverity.Severity {
+	if _, ok := errcode.Get(err); ok || errclass.GetClass(err) != errclass.Unknown {
+		return severities.Of(err)
+	}
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		if m, ok := he.Message.(error); ok {
+			return severities.Of(m)
+		}
+	}
+	return severities.Of(err)
+}
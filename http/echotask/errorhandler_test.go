@@ -0,0 +1,223 @@
+package echotask_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/http/echotask"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcode"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errseverity"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+func TestErrorHandler(t *testing.T) {
+	t.Parallel()
+
+	internalErr := errcontext.Add(
+		errclass.WrapAs(stacktrace.Wrap(errors.New("dial tcp db.internal:5432")), errclass.Transient),
+		slog.String("host", "db.internal"),
+	)
+
+	testCases := []struct {
+		name         string
+		debug        bool
+		err          error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "production internal error",
+			err:          internalErr,
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: `{"class":"transient","message":"Internal Server Error"}`,
+		},
+		{
+			name:         "production http error",
+			err:          echo.NewHTTPError(http.StatusBadRequest, "bad input"),
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"message":"bad input"}`,
+		},
+		{
+			name:         "production http error with error message",
+			err:          echo.NewHTTPError(http.StatusBadGateway, errors.New("dial tcp db.internal:5432")),
+			expectedCode: http.StatusBadGateway,
+			expectedBody: `{"message":"Bad Gateway"}`,
+		},
+		{
+			name:         "debug http error with error message",
+			debug:        true,
+			err:          echo.NewHTTPError(http.StatusBadGateway, errors.New("dial tcp db.internal:5432")),
+			expectedCode: http.StatusBadGateway,
+			expectedBody: `{"error":"code=502, message=dial tcp db.internal:5432","message":"dial tcp db.internal:5432"}`,
+		},
+		{
+			name:         "debug internal error",
+			debug:        true,
+			err:          internalErr,
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: `{"class":"transient","error":"dial tcp db.internal:5432","message":"Internal Server Error"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			echotask.ErrorHandler(tc.debug)(tc.err, c)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			assert.JSONEq(t, tc.expectedBody, rec.Body.String())
+			if !tc.debug {
+				assert.NotContains(t, rec.Body.String(), "db.internal")
+			}
+		})
+	}
+}
+
+func TestLocalizedErrorHandler(t *testing.T) {
+	t.Parallel()
+
+	catalog, err := errcode.NewCatalog(fstest.MapFS{
+		"en.json": {Data: []byte(`{"account.insufficient_funds": "Your balance of {{.balance}} is too low."}`)},
+		"fr.json": {Data: []byte(`{"account.insufficient_funds": "Votre solde de {{.balance}} est insuffisant."}`)},
+	})
+	require.NoError(t, err)
+
+	coded := errcode.WrapAs(
+		errclass.WrapAs(stacktrace.Wrap(errors.New("balance 5 below 10 for account 42")), errclass.Persistent),
+		"account.insufficient_funds", map[string]any{"balance": 5},
+	)
+
+	testCases := []struct {
+		name             string
+		catalog          *errcode.Catalog
+		acceptLanguage   string
+		err              error
+		expectedCode     int
+		expectedBody     string
+		expectedLanguage string
+	}{
+		{
+			name:             "coded internal error in requested locale",
+			catalog:          catalog,
+			acceptLanguage:   "fr-CH, en;q=0.5",
+			err:              coded,
+			expectedCode:     http.StatusInternalServerError,
+			expectedBody:     `{"class":"persistent","code":"account.insufficient_funds","message":"Votre solde de 5 est insuffisant."}`,
+			expectedLanguage: "fr",
+		},
+		{
+			name:             "coded http error in default locale",
+			catalog:          catalog,
+			acceptLanguage:   "de",
+			err:              echo.NewHTTPError(http.StatusUnprocessableEntity, coded),
+			expectedCode:     http.StatusUnprocessableEntity,
+			expectedBody:     `{"code":"account.insufficient_funds","message":"Your balance of 5 is too low."}`,
+			expectedLanguage: "en",
+		},
+		{
+			name:         "unknown code keeps the message",
+			catalog:      catalog,
+			err:          errcode.WrapAs(echo.NewHTTPError(http.StatusNotFound, "not found"), "account.unknown", nil),
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"code":"account.unknown","message":"not found"}`,
+		},
+		{
+			name:         "without a catalog the code is still included",
+			err:          coded,
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: `{"class":"persistent","code":"account.insufficient_funds","message":"Internal Server Error"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.Header.Set("Accept-Language", tc.acceptLanguage)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			echotask.LocalizedErrorHandler(false, tc.catalog)(tc.err, c)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			assert.JSONEq(t, tc.expectedBody, rec.Body.String())
+			assert.Equal(t, tc.expectedLanguage, rec.Header().Get("Content-Language"))
+			assert.NotContains(t, rec.Body.String(), "account 42")
+		})
+	}
+}
+
+func TestSeverityLoggingErrorHandler(t *testing.T) {
+	t.Parallel()
+
+	severities := errseverity.NewMapping(errseverity.WithCode("payments.provider_down", errseverity.Page))
+
+	testCases := []struct {
+		name          string
+		err           error
+		expectedLevel string
+		expectedAttrs string
+	}{
+		{
+			name:          "unclassified",
+			err:           errors.New("boom"),
+			expectedLevel: `"level":"ERROR"`,
+			expectedAttrs: `"severity":"ticket","status":500`,
+		},
+		{
+			name:          "transient",
+			err:           errclass.WrapAs(errors.New("timeout"), errclass.Transient),
+			expectedLevel: `"level":"WARN"`,
+			expectedAttrs: `"severity":"log","status":500`,
+		},
+		{
+			name:          "code",
+			err:           echo.NewHTTPError(http.StatusBadGateway, errcode.WrapAs(errors.New("unavailable"), "payments.provider_down", nil)),
+			expectedLevel: `"level":"ERROR"`,
+			expectedAttrs: `"severity":"page","status":502`,
+		},
+		{
+			name: "client error",
+			err:  echo.NewHTTPError(http.StatusNotFound, "not found"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := &bytes.Buffer{}
+			logger := slog.New(slog.NewJSONHandler(buf, nil))
+			handler := echotask.SeverityLoggingErrorHandler(echotask.ErrorHandler(false), severities, logger)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			rec := httptest.NewRecorder()
+			handler(tc.err, e.NewContext(req, rec))
+
+			if tc.expectedAttrs == "" {
+				assert.Empty(t, buf.String())
+				return
+			}
+			assert.Contains(t, buf.String(), tc.expectedLevel)
+			assert.Contains(t, buf.String(), tc.expectedAttrs)
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+const (
+	statusOK      = "ok"
+	statusFailed  = "failed"
+	statusPending = "pending"
+	statusDrain   = "draining"
+)
+
+// CheckerFunc allows a plain function to be used as a Checker.
+type CheckerFunc func(ctx context.Context) error
+
+// HealthCheck implements Checker.
+func (f CheckerFunc) HealthCheck(ctx context.Context) error {
+	return f(ctx)
+}
+
+// ProbeResponse is the body returned by each probe endpoint.
+type ProbeResponse struct {
+	Status  string                 `json:"status"`
+	Checks  map[string]string      `json:"checks,omitempty"`
+	Details map[string]CheckResult `json:"details,omitempty"`
+}
+
+// CheckResult details the result of a single checker.
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	// Class is the class of the error (see errclass) if the check failed. The error itself is not exposed.
+	Class string `json:"class,omitempty"`
+}
+
+type namedChecker struct {
+	name    string
+	checker Checker
+}
+
+// Probes separates health into three independent probes, each with its own set of checkers:
+//   - liveness: the process is healthy and does not need to be restarted.
+//   - readiness: dependencies are available and the service is not draining, so it may receive traffic.
+//   - startup: initialization is complete.
+//
+// Readiness also fails until startup completes.
+type Probes struct {
+	mu        sync.RWMutex
+	liveness  []namedChecker
+	readiness []namedChecker
+	startup   []namedChecker
+
+	started  atomic.Bool
+	draining atomic.Bool
+}
+
+// NewProbes creates Probes with no checkers.
+func NewProbes() *Probes {
+	return &Probes{}
+}
+
+// AddLivenessCheck adds a named checker to the liveness probe.
+func (p *Probes) AddLivenessCheck(name string, checker Checker) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.liveness = append(p.liveness, namedChecker{name: name, checker: checker})
+}
+
+// AddReadinessCheck adds a named checker to the readiness probe.
+func (p *Probes) AddReadinessCheck(name string, checker Checker) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.readiness = append(p.readiness, namedChecker{name: name, checker: checker})
+}
+
+// AddStartupCheck adds a named checker to the startup probe.
+func (p *Probes) AddStartupCheck(name string, checker Checker) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.startup = append(p.startup, namedChecker{name: name, checker: checker})
+}
+
+// MarkStarted records that initialization is complete.
+func (p *Probes) MarkStarted() {
+	p.started.Store(true)
+}
+
+// MarkDraining records that the service is shutting down and should no longer receive traffic.
+func (p *Probes) MarkDraining() {
+	p.draining.Store(true)
+}
+
+// DrainOn marks the probes as draining once ctx is done.
+// Typically this is given the context of the task manager.
+func (p *Probes) DrainOn(ctx context.Context) {
+	context.AfterFunc(ctx, p.MarkDraining)
+}
+
+// Started reports whether initialization is complete.
+func (p *Probes) Started() bool {
+	return p.started.Load()
+}
+
+// Draining reports whether the service is draining.
+func (p *Probes) Draining() bool {
+	return p.draining.Load()
+}
+
+// Live evaluates the liveness probe.
+func (p *Probes) Live(ctx context.Context) (ProbeResponse, bool) {
+	return p.evaluate(ctx, p.checkers(&p.liveness), "")
+}
+
+// Ready evaluates the readiness probe.
+func (p *Probes) Ready(ctx context.Context) (ProbeResponse, bool) {
+	switch {
+	case p.Draining():
+		return ProbeResponse{Status: statusDrain}, false
+	case !p.Started():
+		return ProbeResponse{Status: statusPending}, false
+	}
+	return p.evaluate(ctx, p.checkers(&p.readiness), "")
+}
+
+// Startup evaluates the startup probe.
+func (p *Probes) Startup(ctx context.Context) (ProbeResponse, bool) {
+	status := ""
+	if !p.Started() {
+		status = statusPending
+	}
+	return p.evaluate(ctx, p.checkers(&p.startup), status)
+}
+
+// LiveHandler serves the liveness probe.
+func (p *Probes) LiveHandler(c echo.Context) error {
+	return respond(c)(p.Live(c.Request().Context()))
+}
+
+// ReadyHandler serves the readiness probe.
+func (p *Probes) ReadyHandler(c echo.Context) error {
+	return respond(c)(p.Ready(c.Request().Context()))
+}
+
+// StartupHandler serves the startup probe.
+func (p *Probes) StartupHandler(c echo.Context) error {
+	return respond(c)(p.Startup(c.Request().Context()))
+}
+
+func (p *Probes) checkers(set *[]namedChecker) []namedChecker {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return *set
+}
+
+// evaluate runs all checkers. A non-empty status overrides a successful result.
+func (p *Probes) evaluate(ctx context.Context, checkers []namedChecker, status string) (ProbeResponse, bool) {
+	resp := ProbeResponse{Status: statusOK}
+	if len(checkers) > 0 {
+		resp.Checks = make(map[string]string, len(checkers))
+		resp.Details = make(map[string]CheckResult, len(checkers))
+	}
+	for _, nc := range checkers {
+		start := time.Now()
+		err := nc.checker.HealthCheck(ctx)
+		result := CheckResult{Status: statusOK, LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+		if err != nil {
+			result.Status = statusFailed
+			result.Class = errclass.GetClass(err).String()
+			resp.Status = statusFailed
+		}
+		resp.Checks[nc.name] = result.Status
+		resp.Details[nc.name] = result
+	}
+	if resp.Status == statusOK && status != "" {
+		resp.Status = status
+	}
+	return resp, resp.Status == statusOK
+}
+
+func respond(c echo.Context) func(ProbeResponse, bool) error {
+	return func(resp ProbeResponse, ok bool) error {
+		if !ok {
+			return c.JSON(http.StatusServiceUnavailable, resp)
+		}
+		return c.JSON(http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,103 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+func serve(t *testing.T, h echo.HandlerFunc) (int, ProbeResponse) {
+	t.Helper()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	rec := httptest.NewRecorder()
+	require.NoError(t, h(e.NewContext(req, rec)))
+
+	var resp ProbeResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return rec.Code, resp
+}
+
+func TestProbesLifecycle(t *testing.T) {
+	t.Parallel()
+
+	probes := NewProbes()
+	var dbErr error
+	probes.AddReadinessCheck("db", CheckerFunc(func(context.Context) error { return dbErr }))
+	probes.AddLivenessCheck("loop", CheckerFunc(func(context.Context) error { return nil }))
+
+	// before startup completes, only liveness succeeds
+	code, resp := serve(t, probes.LiveHandler)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, statusOK, resp.Status)
+	assert.Equal(t, map[string]string{"loop": statusOK}, resp.Checks)
+
+	code, resp = serve(t, probes.StartupHandler)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, statusPending, resp.Status)
+
+	code, resp = serve(t, probes.ReadyHandler)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, statusPending, resp.Status)
+
+	// once started, readiness depends on its checkers
+	probes.MarkStarted()
+	code, _ = serve(t, probes.StartupHandler)
+	assert.Equal(t, http.StatusOK, code)
+
+	code, resp = serve(t, probes.ReadyHandler)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]string{"db": statusOK}, resp.Checks)
+
+	dbErr = errclass.WrapAs(errors.New("connection refused"), errclass.Transient)
+	code, resp = serve(t, probes.ReadyHandler)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, statusFailed, resp.Status)
+	assert.Equal(t, map[string]string{"db": statusFailed}, resp.Checks)
+
+	// the class of the error is given, but not the error itself
+	assert.Equal(t, statusFailed, resp.Details["db"].Status)
+	assert.Equal(t, errclass.Transient.String(), resp.Details["db"].Class)
+	assert.GreaterOrEqual(t, resp.Details["db"].LatencyMS, 0.0)
+
+	// liveness is independent of readiness
+	code, _ = serve(t, probes.LiveHandler)
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestProbesDrainOn(t *testing.T) {
+	t.Parallel()
+
+	probes := NewProbes()
+	probes.MarkStarted()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	probes.DrainOn(ctx)
+
+	code, _ := serve(t, probes.ReadyHandler)
+	assert.Equal(t, http.StatusOK, code)
+
+	cancel()
+	assert.Eventually(t, probes.Draining, time.Second, time.Millisecond)
+
+	code, resp := serve(t, probes.ReadyHandler)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, statusDrain, resp.Status)
+
+	// draining does not affect liveness or startup
+	code, _ = serve(t, probes.LiveHandler)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = serve(t, probes.StartupHandler)
+	assert.Equal(t, http.StatusOK, code)
+}
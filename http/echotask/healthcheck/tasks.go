@@ -0,0 +1,46 @@
+package healthcheck
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/zircuit-labs/zkr-go-common/task"
+)
+
+const statusDegraded = "degraded"
+
+// TaskStatuses provides a snapshot of the status of each task, and is implemented by task.Manager.
+type TaskStatuses interface {
+	Statuses() []task.TaskState
+}
+
+// TasksResponse is the body returned by the task status endpoint.
+type TasksResponse struct {
+	// Status is failed if any task has failed, otherwise degraded if any task is degraded, otherwise ok.
+	Status string           `json:"status"`
+	Tasks  []task.TaskState `json:"tasks"`
+}
+
+// TasksHandler serves the status of each task, so that operators can see exactly which task is unhealthy.
+// It responds with 503 if any task has failed or is degraded.
+func TasksHandler(source TaskStatuses) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		resp := TasksResponse{Status: statusOK, Tasks: source.Statuses()}
+		for _, state := range resp.Tasks {
+			switch state.Status {
+			case task.StatusFailed:
+				resp.Status = statusFailed
+			case task.StatusDegraded:
+				if resp.Status == statusOK {
+					resp.Status = statusDegraded
+				}
+			default:
+			}
+		}
+		if resp.Status != statusOK {
+			return c.JSON(http.StatusServiceUnavailable, resp)
+		}
+		return c.JSON(http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,81 @@
+package healthcheck
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/task"
+)
+
+type staticStatuses []task.TaskState
+
+func (s staticStatuses) Statuses() []task.TaskState {
+	return s
+}
+
+func TestTasksHandler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		statuses       []task.TaskStatus
+		expectedCode   int
+		expectedStatus string
+	}{
+		{
+			name:           "healthy",
+			statuses:       []task.TaskStatus{task.StatusRunning, task.StatusStarting},
+			expectedCode:   http.StatusOK,
+			expectedStatus: "ok",
+		},
+		{
+			name:           "degraded",
+			statuses:       []task.TaskStatus{task.StatusRunning, task.StatusDegraded},
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: "degraded",
+		},
+		{
+			name:           "failed",
+			statuses:       []task.TaskStatus{task.StatusFailed, task.StatusDegraded},
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: "failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			states := make(staticStatuses, 0, len(tt.statuses))
+			for _, status := range tt.statuses {
+				states = append(states, task.TaskState{Name: status.String() + " task", Status: status})
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			rec := httptest.NewRecorder()
+			require.NoError(t, TasksHandler(states)(e.NewContext(req, rec)))
+			assert.Equal(t, tt.expectedCode, rec.Code)
+
+			var resp struct {
+				Status string `json:"status"`
+				Tasks  []struct {
+					Name   string `json:"name"`
+					Status string `json:"status"`
+				} `json:"tasks"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, tt.expectedStatus, resp.Status)
+			require.Len(t, resp.Tasks, len(tt.statuses))
+			for i, status := range tt.statuses {
+				assert.Equal(t, status.String(), resp.Tasks[i].Status)
+			}
+		})
+	}
+}
@@ -20,7 +20,7 @@ func Recover(logger *slog.Logger) echo.MiddlewareFunc {
 			case errclass.Nil:
 				return nil
 			case errclass.Panic:
-				logger.Error("middleware recovered from panic", log.ErrAttr(err))
+				logger.ErrorContext(c.Request().Context(), "middleware recovered from panic", log.ErrAttr(err))
 				c.Error(err)
 				return nil
 			default:
@@ -0,0 +1,44 @@
+package echotask
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"github.com/zircuit-labs/zkr-go-common/http/requestid"
+)
+
+const maxRequestIDLength = 64
+
+// RequestID returns a middleware which ensures every request has a request ID.
+// The ID is taken from the X-Request-ID request header, or generated if absent or invalid
+// (being longer than 64 characters, or containing characters other than letters, digits, '.', '_' and '-'),
+// and is set on the response header and carried by the request context,
+// from where it is added to logs and propagated to downstream calls.
+func RequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			id := req.Header.Get(requestid.Header)
+			if !validRequestID(id) {
+				id = requestid.New()
+			}
+			c.Response().Header().Set(requestid.Header, id)
+			c.SetRequest(req.WithContext(requestid.NewContext(req.Context(), id)))
+			return next(c)
+		}
+	}
+}
+
+// validRequestID reports whether a request ID chosen by the client is safe to log, propagate and echo back.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == '.', r == '_', r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package echotask_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/http/echotask"
+	"github.com/zircuit-labs/zkr-go-common/http/requestid"
+)
+
+func TestRequestID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		header string
+		valid  bool
+	}{
+		{name: "generated when absent"},
+		{name: "taken from request", header: "abc123", valid: true},
+		{name: "allowed punctuation", header: "Req_1.2-3", valid: true},
+		{name: "maximum length", header: strings.Repeat("a", 64), valid: true},
+		{name: "too long", header: strings.Repeat("a", 65)},
+		{name: "path", header: "../abc"},
+		{name: "whitespace", header: "abc 123"},
+		{name: "non-ascii", header: "abcé"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			if tt.header != "" {
+				req.Header.Set(requestid.Header, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			var fromContext string
+			h := echotask.RequestID()(func(c echo.Context) error {
+				var ok bool
+				fromContext, ok = requestid.FromContext(c.Request().Context())
+				require.True(t, ok)
+				return nil
+			})
+			require.NoError(t, h(c))
+
+			id := rec.Header().Get(requestid.Header)
+			assert.NotEmpty(t, id)
+			assert.Equal(t, id, fromContext)
+			if tt.valid {
+				assert.Equal(t, tt.header, id)
+			} else {
+				assert.NotEqual(t, tt.header, id)
+			}
+		})
+	}
+}
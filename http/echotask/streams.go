@@ -0,0 +1,147 @@
+package echotask
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// CloseGoingAway is the WebSocket close code (see RFC 6455) given to streams when the server shuts down.
+	CloseGoingAway = 1001
+	// CloseReasonShutdown is the reason given to streams when the server shuts down.
+	CloseReasonShutdown = "server shutting down"
+
+	defaultStreamGracePeriod = 10 * time.Second
+	streamsKey               = "echotask.streams"
+)
+
+// CloseFunc asks a stream to close gracefully, eg by sending a WebSocket close frame with the code and reason,
+// or a final server-sent event. It is called from another goroutine, so must be safe to call concurrently
+// with the handler, and must not block for long.
+type CloseFunc func(code int, reason string)
+
+// WithStreamGracePeriod sets how long streams (see OpenStream) are given to close after being asked to
+// when the server shuts down, before they are forced to. Defaults to 10 seconds.
+func WithStreamGracePeriod(d time.Duration) Option {
+	return func(options *options) {
+		options.streamGracePeriod = d
+	}
+}
+
+// OpenStream registers a long-lived connection (eg a WebSocket or server-sent events) being served by the handler,
+// so that the server closes it gracefully on shutdown rather than abruptly dropping it. When shutdown begins,
+// closeFunc is called with CloseGoingAway and CloseReasonShutdown, and the stream is given a grace period
+// (see WithStreamGracePeriod) to end before the returned context is cancelled.
+// The handler must stop streaming once the context is done, and call the returned func when the stream ends.
+// Outside of a Server (eg in tests), the stream is not registered, and the context is that of the request.
+func OpenStream(c echo.Context, closeFunc CloseFunc) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(c.Request().Context())
+	streams, ok := c.Get(streamsKey).(*streamTracker)
+	if !ok {
+		return ctx, cancel
+	}
+	s := &stream{close: closeFunc, cancel: cancel}
+	streams.add(s)
+	return ctx, func() {
+		cancel()
+		streams.remove(s)
+	}
+}
+
+type stream struct {
+	close  CloseFunc
+	cancel context.CancelFunc
+}
+
+// streamTracker tracks the open streams of a server.
+type streamTracker struct {
+	mu      sync.Mutex
+	streams map[*stream]struct{}
+	closing bool
+
+	done      chan struct{} // closed when closing and no streams remain
+	closeDone sync.Once
+}
+
+func newStreamTracker() *streamTracker {
+	return &streamTracker{
+		streams: map[*stream]struct{}{},
+		done:    make(chan struct{}),
+	}
+}
+
+// middleware makes the tracker available to OpenStream.
+func (t *streamTracker) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Set(streamsKey, t)
+		return next(c)
+	}
+}
+
+func (t *streamTracker) add(s *stream) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.streams[s] = struct{}{}
+	if t.closing {
+		// opened after shutdown began, so close it at once
+		go s.close(CloseGoingAway, CloseReasonShutdown)
+	}
+}
+
+func (t *streamTracker) remove(s *stream) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.streams[s]; !ok {
+		return
+	}
+	delete(t.streams, s)
+	if t.closing && len(t.streams) == 0 {
+		t.closeDone.Do(func() { close(t.done) })
+	}
+}
+
+// shutdown asks all streams to close, waiting up to the grace period for them to end
+// before cancelling the context of any which remain. It returns the number forced to close.
+func (t *streamTracker) shutdown(grace time.Duration) int {
+	t.mu.Lock()
+	t.closing = true
+	open := slices.Collect(maps.Keys(t.streams))
+	t.mu.Unlock()
+	if len(open) == 0 {
+		return 0
+	}
+
+	// closeFunc may end the stream itself, so must be called without the lock
+	for _, s := range open {
+		s.close(CloseGoingAway, CloseReasonShutdown)
+	}
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-t.done:
+		return 0
+	case <-timer.C:
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for s := range t.streams {
+		s.cancel()
+	}
+	return len(t.streams)
+}
+
+// closeStreams closes the open streams of the server, logging any which had to be forced.
+func (t *Server) closeStreams() {
+	if forced := t.streams.shutdown(t.streamGracePeriod); forced > 0 {
+		t.logger.Warn("forced streams to close after grace period",
+			slog.Int("streams", forced), slog.Duration("grace_period", t.streamGracePeriod))
+	}
+}
@@ -0,0 +1,123 @@
+package echotask_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/http/echotask"
+	"github.com/zircuit-labs/zkr-go-common/http/port"
+)
+
+// eventRoutes serves server-sent events until the stream is closed,
+// ending with a close event unless ignoring the request to close.
+type eventRoutes struct {
+	opened      chan struct{}
+	ignoreClose bool
+}
+
+func (r *eventRoutes) RegisterRoutes(rr echotask.RouteRegistrant) error {
+	rr.GET("/events", func(c echo.Context) error {
+		closing := make(chan string, 1)
+		ctx, done := echotask.OpenStream(c, func(code int, reason string) {
+			closing <- fmt.Sprintf("%d %s", code, reason)
+		})
+		defer done()
+
+		c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
+		c.Response().WriteHeader(http.StatusOK)
+		c.Response().Flush()
+		r.opened <- struct{}{}
+
+		select {
+		case reason := <-closing:
+			if !r.ignoreClose {
+				_, err := fmt.Fprintf(c.Response(), "event: close\ndata: %s\n\n", reason)
+				return err
+			}
+			<-ctx.Done()
+		case <-ctx.Done():
+		}
+		return nil
+	})
+	return nil
+}
+
+func TestStreamShutdown(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		ignoreClose bool
+		expected    string
+	}{
+		{name: "graceful", expected: "event: close\ndata: 1001 server shutting down\n\n"},
+		{name: "forced", ignoreClose: true, expected: ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			p, err := port.AvailablePort()
+			require.NoError(t, err)
+			cfg, err := config.NewConfigurationFromMap(map[string]any{"port": p, "nogzip": true})
+			require.NoError(t, err)
+
+			routes := &eventRoutes{opened: make(chan struct{}, 1), ignoreClose: tc.ignoreClose}
+			server, err := echotask.NewServer(cfg, "",
+				echotask.WithRoutes(routes),
+				echotask.WithStreamGracePeriod(100*time.Millisecond),
+			)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			stopped := make(chan error, 1)
+			go func() { stopped <- server.Run(ctx) }()
+
+			req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, fmt.Sprintf("http://localhost:%d/events", p), http.NoBody)
+			require.NoError(t, err)
+			var resp *http.Response
+			require.Eventually(t, func() bool {
+				resp, err = http.DefaultClient.Do(req)
+				return err == nil
+			}, 5*time.Second, 10*time.Millisecond)
+			defer resp.Body.Close()
+			<-routes.opened
+
+			cancel()
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, string(body))
+
+			select {
+			case err := <-stopped:
+				require.NoError(t, err)
+			case <-time.After(5 * time.Second):
+				require.FailNow(t, "server did not stop")
+			}
+		})
+	}
+}
+
+func TestOpenStreamOutsideServer(t *testing.T) {
+	t.Parallel()
+
+	e := echo.New()
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "/", http.NoBody)
+	require.NoError(t, err)
+	c := e.NewContext(req, nil)
+
+	ctx, done := echotask.OpenStream(c, func(int, string) {})
+	require.NoError(t, ctx.Err())
+	done()
+	require.Error(t, ctx.Err())
+}
@@ -0,0 +1,141 @@
+package httpcache
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// heuristicFraction of the time since the response was last modified is used as its
+	// freshness lifetime when none is given explicitly (see RFC 9111 section 4.2.2).
+	heuristicFraction = 10
+	maxHeuristic      = 24 * time.Hour
+)
+
+// cacheableStatus are the status codes which are cacheable by default (see RFC 9110 section 15.1).
+var cacheableStatus = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// cacheControl holds the directives of a Cache-Control header, keyed by lower case name.
+type cacheControl map[string]string
+
+func parseCacheControl(header http.Header) cacheControl {
+	cc := cacheControl{}
+	for _, value := range header.Values("Cache-Control") {
+		for directive := range strings.SplitSeq(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name == "" {
+				continue
+			}
+			cc[strings.ToLower(name)] = strings.Trim(arg, `"`)
+		}
+	}
+	return cc
+}
+
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
+
+// seconds returns the value of a directive given in seconds, if present and valid.
+func (cc cacheControl) seconds(directive string) (time.Duration, bool) {
+	arg, ok := cc[directive]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// headerTime returns the time in a header, if present and valid.
+func headerTime(header http.Header, key string) (time.Time, bool) {
+	t, err := http.ParseTime(header.Get(key))
+	return t, err == nil
+}
+
+// freshnessLifetime returns how long the response is fresh for after it was generated,
+// and whether it was given explicitly rather than being a heuristic.
+// See RFC 9111 section 4.2.1.
+func freshnessLifetime(header http.Header, responseTime time.Time) (time.Duration, bool) {
+	cc := parseCacheControl(header)
+	// the store may be shared, so s-maxage takes precedence (see RFC 9111 section 5.2.2.10)
+	if sMaxAge, ok := cc.seconds("s-maxage"); ok {
+		return sMaxAge, true
+	}
+	if maxAge, ok := cc.seconds("max-age"); ok {
+		return maxAge, true
+	}
+
+	date, ok := headerTime(header, "Date")
+	if !ok {
+		date = responseTime
+	}
+	if header.Get("Expires") != "" {
+		// Invalid dates (eg "0") represent a time in the past
+		expires, ok := headerTime(header, "Expires")
+		if !ok {
+			return 0, true
+		}
+		return max(expires.Sub(date), 0), true
+	}
+
+	if lastModified, ok := headerTime(header, "Last-Modified"); ok && date.After(lastModified) {
+		return min(date.Sub(lastModified)/heuristicFraction, maxHeuristic), false
+	}
+	return 0, false
+}
+
+// currentAge returns the age of a response received at responseTime.
+// See RFC 9111 section 4.2.3.
+func currentAge(header http.Header, responseTime, now time.Time) time.Duration {
+	var apparentAge time.Duration
+	if date, ok := headerTime(header, "Date"); ok {
+		apparentAge = max(responseTime.Sub(date), 0)
+	}
+	if age, err := strconv.ParseInt(header.Get("Age"), 10, 64); err == nil && age > 0 {
+		apparentAge = max(apparentAge, time.Duration(age)*time.Second)
+	}
+	return apparentAge + max(now.Sub(responseTime), 0)
+}
+
+// storable reports whether a response to a GET request may be stored, and is worth storing:
+// that is, it is fresh for some time or can be revalidated.
+// As the store may be shared between instances and callers, the rules of a shared cache apply:
+// private responses are not stored, nor are responses to requests with an Authorization header
+// unless the response explicitly allows it. See RFC 9111 sections 3, 3.5 and 5.2.2.7.
+func storable(req *http.Request, resp *http.Response, responseTime time.Time) bool {
+	if !cacheableStatus[resp.StatusCode] {
+		return false
+	}
+	cc := parseCacheControl(resp.Header)
+	if cc.has("no-store") || cc.has("private") {
+		return false
+	}
+	if req.Header.Get("Authorization") != "" && !cc.has("public") && !cc.has("s-maxage") && !cc.has("must-revalidate") {
+		return false
+	}
+	if resp.Header.Get("Vary") == "*" {
+		return false
+	}
+	if lifetime, _ := freshnessLifetime(resp.Header, responseTime); lifetime > 0 {
+		return true
+	}
+	return resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""
+}
@@ -0,0 +1,37 @@
+package httpcache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/zircuit-labs/zkr-go-common/metrics"
+)
+
+// Results of a request, used as the "result" metric label.
+const (
+	ResultHit         = "hit"
+	ResultMiss        = "miss"
+	ResultRevalidated = "revalidated"
+	ResultBypass      = "bypass"
+)
+
+type cacheMetrics struct {
+	requests *prometheus.CounterVec
+}
+
+func registerCacheMetrics(registerer prometheus.Registerer) (*cacheMetrics, error) {
+	requests, err := metrics.Register(registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_client_cache_requests_total",
+		Help: "Number of requests made through the HTTP client cache, by result.",
+	}, []string{"result"}))
+	if err != nil {
+		return nil, err
+	}
+	return &cacheMetrics{requests: requests}, nil
+}
+
+// record is a no-op when metrics are not enabled.
+func (m *cacheMetrics) record(result string) {
+	if m != nil {
+		m.requests.WithLabelValues(result).Inc()
+	}
+}
@@ -0,0 +1,52 @@
+package httpcache
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+)
+
+// Store stores cached responses. Implementations must be safe for concurrent use.
+// A shared store (eg backed by Redis) allows instances to share cached responses. Responses which are
+// private to the caller (see RFC 9111 section 3.5) are never stored, so a store may be shared safely.
+type Store interface {
+	// Get returns the value stored with the key, if any.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the value with the key, replacing any existing value.
+	Set(ctx context.Context, key string, value []byte) error
+	// Delete removes the value stored with the key, if any.
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryStore is a Store holding responses in an in-memory LRU cache, local to the instance.
+type MemoryStore struct {
+	cache *expirable.LRU[string, []byte]
+}
+
+// NewMemoryStore creates a MemoryStore holding at most maxEntries responses, each for at most ttl.
+// Responses are kept after they become stale so that they can be revalidated, so ttl should be longer than
+// their freshness lifetime. A ttl of zero means responses are only removed when the store is full.
+func NewMemoryStore(maxEntries int, ttl time.Duration) *MemoryStore {
+	return &MemoryStore{
+		cache: expirable.NewLRU[string, []byte](maxEntries, nil, ttl),
+	}
+}
+
+// Get implements Store.
+func (m *MemoryStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	value, ok := m.cache.Get(key)
+	return value, ok, nil
+}
+
+// Set implements Store.
+func (m *MemoryStore) Set(_ context.Context, key string, value []byte) error {
+	m.cache.Add(key, value)
+	return nil
+}
+
+// Delete implements Store.
+func (m *MemoryStore) Delete(_ context.Context, key string) error {
+	m.cache.Remove(key)
+	return nil
+}
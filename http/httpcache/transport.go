@@ -0,0 +1,327 @@
+// Package httpcache provides an http.RoundTripper caching responses to GET requests
+// according to their Cache-Control, Expires, ETag and Last-Modified headers.
+package httpcache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const (
+	defaultMaxEntries  = 1000
+	defaultStoreTTL    = 24 * time.Hour
+	defaultMaxBodySize = 1 << 20
+)
+
+type options struct {
+	store       Store
+	maxBodySize int64
+	registerer  prometheus.Registerer
+	clock       clockwork.Clock
+	logger      *slog.Logger
+}
+
+// Option is an option func for NewTransport.
+type Option func(options *options)
+
+// WithStore sets where responses are stored. Defaults to a MemoryStore of 1000 responses kept for up to 24 hours.
+func WithStore(store Store) Option {
+	return func(options *options) {
+		options.store = store
+	}
+}
+
+// WithMaxBodySize sets the size in bytes of the largest response body that is cached. Defaults to 1MiB.
+func WithMaxBodySize(size int64) Option {
+	return func(options *options) {
+		options.maxBodySize = size
+	}
+}
+
+// WithMetrics counts requests as "http_client_cache_requests_total" labeled by result
+// (hit, miss, revalidated or bypass).
+func WithMetrics(registerer prometheus.Registerer) Option {
+	return func(options *options) {
+		options.registerer = registerer
+	}
+}
+
+// WithClock allows users to mock the clock used to determine freshness for testing purposes.
+func WithClock(clock clockwork.Clock) Option {
+	return func(options *options) {
+		options.clock = clock
+	}
+}
+
+// WithLogger sets the logger to be used.
+func WithLogger(logger *slog.Logger) Option {
+	return func(options *options) {
+		options.logger = logger
+	}
+}
+
+// Transport is an http.RoundTripper which caches responses to GET requests as a shared cache
+// (see RFC 9111), since its Store may be shared between callers and instances. Fresh responses are served from the cache, and stale responses are revalidated
+// with the server using their ETag and/or Last-Modified headers where possible.
+// Responses to other requests which change the resource (eg POST) invalidate any cached response for its URL.
+type Transport struct {
+	base    http.RoundTripper
+	opts    options
+	metrics *cacheMetrics
+}
+
+// NewTransport creates a Transport caching the responses of base. If base is nil, http.DefaultTransport is used.
+func NewTransport(base http.RoundTripper, opts ...Option) (*Transport, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	// Set up default options
+	options := options{
+		maxBodySize: defaultMaxBodySize,
+		clock:       clockwork.NewRealClock(),
+		logger:      log.NewNilLogger(),
+	}
+
+	// Apply provided options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.store == nil {
+		options.store = NewMemoryStore(defaultMaxEntries, defaultStoreTTL)
+	}
+
+	t := &Transport{
+		base: base,
+		opts: options,
+	}
+	if options.registerer != nil {
+		metrics, err := registerCacheMetrics(options.registerer)
+		if err != nil {
+			return nil, err
+		}
+		t.metrics = metrics
+	}
+	return t, nil
+}
+
+// entry is a stored response.
+type entry struct {
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	// ResponseTime is when the response was received.
+	ResponseTime time.Time `json:"response_time"`
+	// Vary holds the values of the request headers named by the Vary header of the response.
+	Vary map[string]string `json:"vary,omitempty"`
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		resp, err := t.base.RoundTrip(req)
+		if err == nil && !isSafe(req.Method) && resp.StatusCode < http.StatusBadRequest {
+			t.delete(req.Context(), cacheKey(req))
+		}
+		return resp, err
+	}
+
+	reqCC := parseCacheControl(req.Header)
+	if reqCC.has("no-store") || req.Header.Get("Range") != "" ||
+		req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		// The caller is managing caching themselves
+		t.metrics.record(ResultBypass)
+		return t.base.RoundTrip(req)
+	}
+
+	key := cacheKey(req)
+	cached := t.load(req.Context(), key)
+	if cached != nil && !cached.matches(req) {
+		cached = nil
+	}
+
+	now := t.opts.clock.Now()
+	if cached != nil && t.fresh(cached, reqCC, now) {
+		t.metrics.record(ResultHit)
+		return cached.response(req, now), nil
+	}
+
+	// Revalidate a stale response if possible
+	outReq := req
+	if cached != nil {
+		etag, lastModified := cached.Header.Get("ETag"), cached.Header.Get("Last-Modified")
+		if etag != "" || lastModified != "" {
+			// RoundTrippers must not modify the original request
+			outReq = req.Clone(req.Context())
+			if etag != "" {
+				outReq.Header.Set("If-None-Match", etag)
+			}
+			if lastModified != "" {
+				outReq.Header.Set("If-Modified-Since", lastModified)
+			}
+		} else {
+			cached = nil
+		}
+	}
+
+	resp, err := t.base.RoundTrip(outReq)
+	if err != nil {
+		return nil, err
+	}
+	responseTime := t.opts.clock.Now()
+
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		// Update the stored response with the headers of the new one
+		for name, values := range resp.Header {
+			cached.Header[name] = values
+		}
+		cached.ResponseTime = responseTime
+		t.store(req.Context(), key, cached)
+		t.metrics.record(ResultRevalidated)
+		return cached.response(req, responseTime), nil
+	}
+
+	t.metrics.record(ResultMiss)
+	if !storable(req, resp, responseTime) {
+		return resp, nil
+	}
+	return t.storeResponse(req, key, resp, responseTime), nil
+}
+
+// fresh reports whether the stored response may be used without revalidation.
+func (t *Transport) fresh(e *entry, reqCC cacheControl, now time.Time) bool {
+	if reqCC.has("no-cache") || parseCacheControl(e.Header).has("no-cache") {
+		return false
+	}
+	lifetime, _ := freshnessLifetime(e.Header, e.ResponseTime)
+	if maxAge, ok := reqCC.seconds("max-age"); ok {
+		lifetime = min(lifetime, maxAge)
+	}
+	return currentAge(e.Header, e.ResponseTime, now) < lifetime
+}
+
+// storeResponse stores the response if its body is small enough, returning an equivalent response.
+func (t *Transport) storeResponse(req *http.Request, key string, resp *http.Response, responseTime time.Time) *http.Response {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, t.opts.maxBodySize+1))
+	if err != nil || int64(len(body)) > t.opts.maxBodySize {
+		// Give the caller everything that was read, followed by the remainder (or error)
+		resp.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), resp.Body), Closer: resp.Body}
+		return resp
+	}
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	e := &entry{
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header.Clone(),
+		Body:         body,
+		ResponseTime: responseTime,
+	}
+	for name := range strings.SplitSeq(resp.Header.Get("Vary"), ",") {
+		if name = http.CanonicalHeaderKey(strings.TrimSpace(name)); name != "" {
+			if e.Vary == nil {
+				e.Vary = map[string]string{}
+			}
+			e.Vary[name] = strings.Join(req.Header.Values(name), ", ")
+		}
+	}
+	t.store(req.Context(), key, e)
+	return resp
+}
+
+// load returns the stored response, if any. Failures of the store are logged and treated as a miss.
+func (t *Transport) load(ctx context.Context, key string) *entry {
+	value, ok, err := t.opts.store.Get(ctx, key)
+	if err != nil {
+		t.opts.logger.Warn("failed to load cached response", log.ErrAttr(err), slog.String("key", key))
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	var e entry
+	if err := json.Unmarshal(value, &e); err != nil {
+		t.opts.logger.Warn("failed to decode cached response", log.ErrAttr(stacktrace.Wrap(err)), slog.String("key", key))
+		return nil
+	}
+	return &e
+}
+
+// store stores the response. Failures of the store are logged.
+func (t *Transport) store(ctx context.Context, key string, e *entry) {
+	value, err := json.Marshal(e)
+	if err == nil {
+		err = t.opts.store.Set(ctx, key, value)
+	}
+	if err != nil {
+		t.opts.logger.Warn("failed to store cached response", log.ErrAttr(err), slog.String("key", key))
+	}
+}
+
+// delete removes any stored response. Failures of the store are logged.
+func (t *Transport) delete(ctx context.Context, key string) {
+	if err := t.opts.store.Delete(ctx, key); err != nil {
+		t.opts.logger.Warn("failed to invalidate cached response", log.ErrAttr(err), slog.String("key", key))
+	}
+}
+
+// matches reports whether the stored response was selected by the same header values as the request.
+func (e *entry) matches(req *http.Request) bool {
+	for name, value := range e.Vary {
+		if strings.Join(req.Header.Values(name), ", ") != value {
+			return false
+		}
+	}
+	return true
+}
+
+// response returns the stored response to the request, with its age at the given time.
+func (e *entry) response(req *http.Request, now time.Time) *http.Response {
+	header := e.Header.Clone()
+	header.Set("Age", strconv.FormatInt(int64(currentAge(e.Header, e.ResponseTime, now)/time.Second), 10))
+	return &http.Response{
+		Status:        strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// cacheKey identifies the resource of the request.
+func cacheKey(req *http.Request) string {
+	return req.URL.String()
+}
+
+// isSafe reports whether the method does not change the resource (see RFC 9110 section 9.2.1).
+func isSafe(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,304 @@
+package httpcache_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/http/httpcache"
+)
+
+// upstream is a test server counting the requests it receives, and the conditional ones it answers with 304.
+type upstream struct {
+	*httptest.Server
+	requests    atomic.Int32
+	notModified atomic.Int32
+}
+
+func newUpstream(t *testing.T, clock clockwork.Clock, handler func(w http.ResponseWriter, r *http.Request)) *upstream {
+	t.Helper()
+	u := &upstream{}
+	u.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u.requests.Add(1)
+		w.Header().Set("Date", clock.Now().UTC().Format(http.TimeFormat))
+		handler(w, r)
+	}))
+	t.Cleanup(u.Close)
+	return u
+}
+
+func newClient(t *testing.T, opts ...httpcache.Option) *http.Client {
+	t.Helper()
+	transport, err := httpcache.NewTransport(nil, opts...)
+	require.NoError(t, err)
+	return &http.Client{Transport: transport}
+}
+
+func get(t *testing.T, client *http.Client, url string, header ...string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, url, nil)
+	require.NoError(t, err)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(body)
+}
+
+func TestFreshAndRevalidated(t *testing.T) {
+	t.Parallel()
+
+	clock := clockwork.NewFakeClock()
+	var server *upstream
+	server = newUpstream(t, clock, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			server.notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = io.WriteString(w, "hello")
+	})
+
+	registry := prometheus.NewRegistry()
+	client := newClient(t, httpcache.WithClock(clock), httpcache.WithMetrics(registry))
+
+	resp, body := get(t, client, server.URL)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "hello", body)
+
+	// fresh: served from the cache
+	clock.Advance(30 * time.Second)
+	resp, body = get(t, client, server.URL)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "hello", body)
+	assert.Equal(t, "30", resp.Header.Get("Age"))
+	assert.Equal(t, int32(1), server.requests.Load())
+
+	// stale: revalidated, then fresh again
+	clock.Advance(time.Minute)
+	resp, body = get(t, client, server.URL)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "hello", body)
+	assert.Equal(t, int32(1), server.notModified.Load())
+
+	get(t, client, server.URL)
+	assert.Equal(t, int32(2), server.requests.Load())
+
+	// the caller can demand revalidation
+	get(t, client, server.URL, "Cache-Control", "no-cache")
+	assert.Equal(t, int32(2), server.notModified.Load())
+
+	assert.Equal(t, map[string]float64{"hit": 2, "miss": 1, "revalidated": 2}, results(t, registry))
+}
+
+// results returns the number of requests by result.
+func results(t *testing.T, registry *prometheus.Registry) map[string]float64 {
+	t.Helper()
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	counts := map[string]float64{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			counts[metric.GetLabel()[0].GetValue()] = metric.GetCounter().GetValue()
+		}
+	}
+	return counts
+}
+
+func TestNotCached(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		header  map[string]string
+		status  int
+		request []string
+	}{
+		{
+			name:   "no-store",
+			header: map[string]string{"Cache-Control": "no-store, max-age=60"},
+		},
+		{
+			name:   "no freshness or validator",
+			header: map[string]string{},
+		},
+		{
+			name:   "uncacheable status",
+			header: map[string]string{"Cache-Control": "max-age=60"},
+			status: http.StatusInternalServerError,
+		},
+		{
+			name:   "vary all",
+			header: map[string]string{"Cache-Control": "max-age=60", "Vary": "*"},
+		},
+		{
+			name:    "request no-store",
+			header:  map[string]string{"Cache-Control": "max-age=60"},
+			request: []string{"Cache-Control", "no-store"},
+		},
+		{
+			name:   "expired",
+			header: map[string]string{"Expires": "0"},
+		},
+		{
+			name:   "private",
+			header: map[string]string{"Cache-Control": "private, max-age=60"},
+		},
+		{
+			name:    "authorization",
+			header:  map[string]string{"Cache-Control": "max-age=60"},
+			request: []string{"Authorization", "Bearer alice"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			clock := clockwork.NewFakeClock()
+			server := newUpstream(t, clock, func(w http.ResponseWriter, _ *http.Request) {
+				for k, v := range tc.header {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(max(tc.status, http.StatusOK))
+				_, _ = io.WriteString(w, "hello")
+			})
+			client := newClient(t, httpcache.WithClock(clock))
+
+			for range 2 {
+				_, body := get(t, client, server.URL, tc.request...)
+				assert.Equal(t, "hello", body)
+			}
+			assert.Equal(t, int32(2), server.requests.Load())
+		})
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	t.Parallel()
+
+	for _, cacheControl := range []string{"public, max-age=60", "s-maxage=60", "max-age=60, must-revalidate"} {
+		t.Run(cacheControl, func(t *testing.T) {
+			t.Parallel()
+
+			clock := clockwork.NewFakeClock()
+			server := newUpstream(t, clock, func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Cache-Control", cacheControl)
+				_, _ = io.WriteString(w, "hello")
+			})
+			client := newClient(t, httpcache.WithClock(clock))
+
+			// responses to authorized requests are only stored if explicitly allowed
+			for range 2 {
+				_, body := get(t, client, server.URL, "Authorization", "Bearer alice")
+				assert.Equal(t, "hello", body)
+			}
+			assert.Equal(t, int32(1), server.requests.Load())
+		})
+	}
+}
+
+func TestHeuristicFreshness(t *testing.T) {
+	t.Parallel()
+
+	clock := clockwork.NewFakeClock()
+	lastModified := clock.Now().Add(-10 * time.Minute).UTC().Format(http.TimeFormat)
+	server := newUpstream(t, clock, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", lastModified)
+		if r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = io.WriteString(w, "hello")
+	})
+	client := newClient(t, httpcache.WithClock(clock))
+
+	// fresh for 10% of the time since last modified
+	get(t, client, server.URL)
+	clock.Advance(59 * time.Second)
+	get(t, client, server.URL)
+	assert.Equal(t, int32(1), server.requests.Load())
+
+	clock.Advance(time.Second)
+	_, body := get(t, client, server.URL)
+	assert.Equal(t, "hello", body)
+	assert.Equal(t, int32(2), server.requests.Load())
+}
+
+func TestVary(t *testing.T) {
+	t.Parallel()
+
+	clock := clockwork.NewFakeClock()
+	server := newUpstream(t, clock, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		_, _ = io.WriteString(w, r.Header.Get("Accept-Language"))
+	})
+	client := newClient(t, httpcache.WithClock(clock))
+
+	_, body := get(t, client, server.URL, "Accept-Language", "en")
+	assert.Equal(t, "en", body)
+	_, body = get(t, client, server.URL, "Accept-Language", "en")
+	assert.Equal(t, "en", body)
+	assert.Equal(t, int32(1), server.requests.Load())
+
+	_, body = get(t, client, server.URL, "Accept-Language", "fr")
+	assert.Equal(t, "fr", body)
+	assert.Equal(t, int32(2), server.requests.Load())
+}
+
+func TestInvalidation(t *testing.T) {
+	t.Parallel()
+
+	clock := clockwork.NewFakeClock()
+	server := newUpstream(t, clock, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = io.WriteString(w, "hello")
+	})
+	client := newClient(t, httpcache.WithClock(clock))
+
+	get(t, client, server.URL)
+	get(t, client, server.URL)
+	assert.Equal(t, int32(1), server.requests.Load())
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, server.URL, strings.NewReader("update"))
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	get(t, client, server.URL)
+	assert.Equal(t, int32(3), server.requests.Load())
+}
+
+func TestMaxBodySize(t *testing.T) {
+	t.Parallel()
+
+	clock := clockwork.NewFakeClock()
+	large := strings.Repeat("x", 100)
+	server := newUpstream(t, clock, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = io.WriteString(w, large)
+	})
+	client := newClient(t, httpcache.WithClock(clock), httpcache.WithMaxBodySize(10))
+
+	for range 2 {
+		_, body := get(t, client, server.URL)
+		assert.Equal(t, large, body)
+	}
+	assert.Equal(t, int32(2), server.requests.Load())
+}
@@ -0,0 +1,92 @@
+// Package requestid generates request IDs and propagates them via context,
+// HTTP headers, message headers, and logs.
+package requestid
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/rs/xid"
+)
+
+const (
+	// Header is the header used to carry the request ID over HTTP and NATS.
+	Header = "X-Request-ID"
+	// LogKey is the attribute key used when adding the request ID to logs.
+	LogKey = "request_id"
+)
+
+type contextKey struct{}
+
+// New generates a new request ID.
+func New() string {
+	return xid.New().String()
+}
+
+// NewContext returns a context carrying the request ID.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID carried by ctx, if any.
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextKey{}).(string)
+	return id, ok && id != ""
+}
+
+// Transport is an http.RoundTripper which sets the request ID header on outgoing
+// requests whose context carries a request ID, unless the header is already set.
+type Transport struct {
+	// Base is the underlying RoundTripper. If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if id, ok := FromContext(req.Context()); ok && req.Header.Get(Header) == "" {
+		// RoundTrippers must not modify the original request
+		req = req.Clone(req.Context())
+		req.Header.Set(Header, id)
+	}
+	return base.RoundTrip(req)
+}
+
+// handler adds the request ID from the context to each log record.
+type handler struct {
+	next slog.Handler
+}
+
+// NewHandler wraps a slog.Handler such that records logged with a context
+// carrying a request ID (eg logger.InfoContext) include it as an attribute.
+func NewHandler(next slog.Handler) slog.Handler {
+	return &handler{next: next}
+}
+
+// Enabled implements slog.Handler.
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *handler) Handle(ctx context.Context, record slog.Record) error {
+	if id, ok := FromContext(ctx); ok {
+		record = record.Clone()
+		record.AddAttrs(slog.String(LogKey, id))
+	}
+	return h.next.Handle(ctx, record)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &handler{next: h.next.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler.
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{next: h.next.WithGroup(name)}
+}
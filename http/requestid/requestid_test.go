@@ -0,0 +1,84 @@
+package requestid_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/http/requestid"
+)
+
+func TestContext(t *testing.T) {
+	t.Parallel()
+
+	_, ok := requestid.FromContext(t.Context())
+	assert.False(t, ok)
+
+	id := requestid.New()
+	assert.NotEmpty(t, id)
+	assert.NotEqual(t, id, requestid.New())
+
+	got, ok := requestid.FromContext(requestid.NewContext(t.Context(), id))
+	assert.True(t, ok)
+	assert.Equal(t, id, got)
+}
+
+func TestTransport(t *testing.T) {
+	t.Parallel()
+
+	received := make(chan string, 3)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(requestid.Header)
+	}))
+	t.Cleanup(server.Close)
+
+	client := &http.Client{Transport: &requestid.Transport{}}
+	do := func(ctx context.Context, header string) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, http.NoBody)
+		require.NoError(t, err)
+		if header != "" {
+			req.Header.Set(requestid.Header, header)
+		}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	// propagated from the context
+	do(requestid.NewContext(t.Context(), "abc"), "")
+	assert.Equal(t, "abc", <-received)
+
+	// an explicit header is not overwritten
+	do(requestid.NewContext(t.Context(), "abc"), "xyz")
+	assert.Equal(t, "xyz", <-received)
+
+	// nothing to propagate
+	do(t.Context(), "")
+	assert.Empty(t, <-received)
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger := slog.New(requestid.NewHandler(slog.NewJSONHandler(&buf, nil))).With("key", "value")
+
+	logger.InfoContext(requestid.NewContext(t.Context(), "abc"), "with id")
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "abc", entry[requestid.LogKey])
+	assert.Equal(t, "value", entry["key"])
+
+	buf.Reset()
+	logger.InfoContext(t.Context(), "without id")
+	entry = nil
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.NotContains(t, entry, requestid.LogKey)
+}
@@ -0,0 +1,77 @@
+package iter
+
+import (
+	"iter"
+	"slices"
+	"time"
+)
+
+// SlidingWindow returns a sequence of windows of size consecutive elements of s, with each window
+// starting step elements after the previous one. Windows overlap when step is less than size,
+// and elements are skipped when it is greater. Only full windows are yielded, so a trailing
+// partial window is dropped. Only the current window is held in memory, and each window is
+// a new slice which may be retained. If size or step is less than 1, the sequence is empty.
+func SlidingWindow[V any](size, step int, s iter.Seq[V]) iter.Seq[[]V] {
+	return func(yield func([]V) bool) {
+		if size < 1 || step < 1 {
+			return
+		}
+		buf := make([]V, 0, size)
+		skip := 0
+		for v := range s {
+			if skip > 0 {
+				skip--
+				continue
+			}
+			buf = append(buf, v)
+			if len(buf) < size {
+				continue
+			}
+			if !yield(slices.Clone(buf)) {
+				return
+			}
+			if step >= size {
+				skip = step - size
+				buf = buf[:0]
+			} else {
+				buf = append(buf[:0], buf[step:]...)
+			}
+		}
+	}
+}
+
+// TumblingWindowByTime returns a sequence of consecutive, non-overlapping windows of duration d,
+// grouping the elements of s by the time returned by ts. Each window is yielded along with its
+// start time once an element beyond it is reached, or s ends. Windows start at multiples of d
+// since the zero time (see time.Time.Truncate), so eg windows of a minute start on the minute.
+// Windows without any elements are not yielded.
+//
+// The elements of s must be ordered by time: an element earlier than the current window is
+// included in it. If d is not positive, the sequence is empty.
+func TumblingWindowByTime[V any](d time.Duration, ts func(V) time.Time, s iter.Seq[V]) iter.Seq2[time.Time, []V] {
+	return func(yield func(time.Time, []V) bool) {
+		if d <= 0 {
+			return
+		}
+		var (
+			window []V
+			start  time.Time
+		)
+		for v := range s {
+			t := ts(v)
+			if len(window) > 0 && !t.Before(start.Add(d)) {
+				if !yield(start, window) {
+					return
+				}
+				window = nil
+			}
+			if len(window) == 0 {
+				start = t.Truncate(d)
+			}
+			window = append(window, v)
+		}
+		if len(window) > 0 {
+			yield(start, window)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package iter_test
+
+import (
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	zkriter "github.com/zircuit-labs/zkr-go-common/iter"
+)
+
+func TestSlidingWindow(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		input    []int
+		size     int
+		step     int
+		expected [][]int
+	}{
+		{
+			name:     "overlapping",
+			input:    []int{1, 2, 3, 4, 5},
+			size:     3,
+			step:     1,
+			expected: [][]int{{1, 2, 3}, {2, 3, 4}, {3, 4, 5}},
+		},
+		{
+			name:     "step greater than one",
+			input:    []int{1, 2, 3, 4, 5, 6, 7},
+			size:     3,
+			step:     2,
+			expected: [][]int{{1, 2, 3}, {3, 4, 5}, {5, 6, 7}},
+		},
+		{
+			name:     "adjacent",
+			input:    []int{1, 2, 3, 4, 5},
+			size:     2,
+			step:     2,
+			expected: [][]int{{1, 2}, {3, 4}},
+		},
+		{
+			name:     "skipping",
+			input:    []int{1, 2, 3, 4, 5, 6, 7, 8},
+			size:     2,
+			step:     3,
+			expected: [][]int{{1, 2}, {4, 5}, {7, 8}},
+		},
+		{
+			name:     "shorter than size",
+			input:    []int{1, 2},
+			size:     3,
+			step:     1,
+			expected: nil,
+		},
+		{
+			name:     "empty input",
+			input:    []int{},
+			size:     1,
+			step:     1,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			result := slices.Collect(zkriter.SlidingWindow(tt.size, tt.step, slices.Values(tt.input)))
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestSlidingWindow_Retained(t *testing.T) {
+	t.Parallel()
+
+	// windows may be retained without being overwritten by later windows
+	var windows [][]int
+	for window := range zkriter.SlidingWindow(2, 1, slices.Values([]int{1, 2, 3, 4})) {
+		windows = append(windows, window)
+	}
+	assert.Equal(t, [][]int{{1, 2}, {2, 3}, {3, 4}}, windows)
+}
+
+func TestSlidingWindow_EarlyTermination(t *testing.T) {
+	t.Parallel()
+
+	var consumed []int
+	input := func(yield func(int) bool) {
+		for i := 1; i <= 10; i++ {
+			consumed = append(consumed, i)
+			if !yield(i) {
+				return
+			}
+		}
+	}
+	for window := range zkriter.SlidingWindow(3, 1, input) {
+		if window[0] == 2 {
+			break
+		}
+	}
+	assert.Equal(t, []int{1, 2, 3, 4}, consumed)
+}
+
+func TestSlidingWindow_MovingAverage(t *testing.T) {
+	t.Parallel()
+
+	average := func(window []float64) float64 {
+		sum := 0.0
+		for _, v := range window {
+			sum += v
+		}
+		return sum / float64(len(window))
+	}
+	gasUsed := []float64{10, 20, 30, 40, 50}
+	averages := slices.Collect(zkriter.Transform(average, zkriter.SlidingWindow(2, 1, slices.Values(gasUsed))))
+	assert.Equal(t, []float64{15, 25, 35, 45}, averages)
+}
+
+func TestSlidingWindow_Invalid(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, slices.Collect(zkriter.SlidingWindow(0, 1, slices.Values([]int{1, 2}))))
+	assert.Empty(t, slices.Collect(zkriter.SlidingWindow(1, 0, slices.Values([]int{1, 2}))))
+	assert.Empty(t, slices.Collect(zkriter.SlidingWindow(-1, -1, slices.Values([]int{1, 2}))))
+}
+
+type block struct {
+	number int
+	time   time.Time
+}
+
+func TestTumblingWindowByTime(t *testing.T) {
+	t.Parallel()
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(number int, offset time.Duration) block {
+		return block{number: number, time: base.Add(offset)}
+	}
+	blockTime := func(b block) time.Time { return b.time }
+	numbers := func(blocks []block) []int {
+		result := make([]int, 0, len(blocks))
+		for _, b := range blocks {
+			result = append(result, b.number)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		input    []block
+		expected map[time.Time][]int
+	}{
+		{
+			name: "consecutive windows",
+			input: []block{
+				at(1, 0), at(2, 20*time.Second), at(3, 59*time.Second),
+				at(4, time.Minute), at(5, 90*time.Second),
+				at(6, 2*time.Minute),
+			},
+			expected: map[time.Time][]int{
+				base:                      {1, 2, 3},
+				base.Add(time.Minute):     {4, 5},
+				base.Add(2 * time.Minute): {6},
+			},
+		},
+		{
+			name:  "windows are aligned",
+			input: []block{at(1, 30*time.Second), at(2, 70*time.Second)},
+			expected: map[time.Time][]int{
+				base:                  {1},
+				base.Add(time.Minute): {2},
+			},
+		},
+		{
+			name:  "empty windows are skipped",
+			input: []block{at(1, 0), at(2, 5*time.Minute)},
+			expected: map[time.Time][]int{
+				base:                      {1},
+				base.Add(5 * time.Minute): {2},
+			},
+		},
+		{
+			name:     "empty input",
+			input:    []block{},
+			expected: map[time.Time][]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			result := map[time.Time][]int{}
+			var starts []time.Time
+			for start, window := range zkriter.TumblingWindowByTime(time.Minute, blockTime, slices.Values(tt.input)) {
+				result[start] = numbers(window)
+				starts = append(starts, start)
+			}
+			assert.Equal(t, tt.expected, result)
+			// windows are yielded in order
+			assert.True(t, slices.IsSortedFunc(starts, time.Time.Compare))
+			assert.Len(t, starts, len(slices.Collect(maps.Keys(tt.expected))))
+		})
+	}
+}
+
+func TestTumblingWindowByTime_EarlyTermination(t *testing.T) {
+	t.Parallel()
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	input := []time.Time{base, base.Add(time.Minute), base.Add(2 * time.Minute)}
+	count := 0
+	for range zkriter.TumblingWindowByTime(time.Minute, func(t time.Time) time.Time { return t }, slices.Values(input)) {
+		count++
+		break
+	}
+	assert.Equal(t, 1, count)
+}
+
+func TestTumblingWindowByTime_Invalid(t *testing.T) {
+	t.Parallel()
+
+	times := slices.Values([]time.Time{time.Now()})
+	identity := func(t time.Time) time.Time { return t }
+	for _, d := range []time.Duration{0, -time.Second} {
+		for range zkriter.TumblingWindowByTime(d, identity, times) {
+			t.Fatalf("unexpected window for duration %v", d)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+)
+
+// allowListHandler drops all attributes other than the allowed ones from records below a level.
+// It maintains two derived handlers, so that attributes added with WithAttrs are kept in full
+// for records at or above the level, while being filtered once for those below it.
+type allowListHandler struct {
+	full     slog.Handler
+	filtered slog.Handler
+	level    slog.Leveler
+	allowed  map[string]struct{}
+}
+
+// NewAllowListHandler wraps a slog.Handler such that records below level only include
+// attributes with the given keys, to keep high volume debug logging affordable.
+// Records at or above level include all attributes. Groups are kept or dropped as a whole
+// according to their own key, and attributes within a group (see slog.Logger.WithGroup)
+// are matched by their own key.
+func NewAllowListHandler(next slog.Handler, level slog.Leveler, keys ...string) slog.Handler {
+	allowed := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		allowed[key] = struct{}{}
+	}
+	return &allowListHandler{full: next, filtered: next, level: level, allowed: allowed}
+}
+
+// Enabled implements slog.Handler.
+func (h *allowListHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.full.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *allowListHandler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level >= h.level.Level() {
+		return h.full.Handle(ctx, record)
+	}
+
+	filtered := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(a slog.Attr) bool {
+		if h.allow(a) {
+			filtered.AddAttrs(a)
+		}
+		return true
+	})
+	return h.filtered.Handle(ctx, filtered)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *allowListHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	allowed := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		if h.allow(a) {
+			allowed = append(allowed, a)
+		}
+	}
+	return &allowListHandler{
+		full:     h.full.WithAttrs(attrs),
+		filtered: h.filtered.WithAttrs(allowed),
+		level:    h.level,
+		allowed:  h.allowed,
+	}
+}
+
+// WithGroup implements slog.Handler.
+func (h *allowListHandler) WithGroup(name string) slog.Handler {
+	return &allowListHandler{
+		full:     h.full.WithGroup(name),
+		filtered: h.filtered.WithGroup(name),
+		level:    h.level,
+		allowed:  h.allowed,
+	}
+}
+
+func (h *allowListHandler) allow(a slog.Attr) bool {
+	_, ok := h.allowed[a.Key]
+	return ok
+}
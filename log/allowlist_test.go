@@ -0,0 +1,88 @@
+package log_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/log"
+)
+
+func decodeRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for line := range strings.SplitSeq(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestAllowListHandler(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	handler := log.NewAllowListHandler(
+		slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		slog.LevelInfo,
+		"block", "request",
+	)
+	logger := slog.New(handler).With(slog.Int("block", 7), slog.String("payload", "large"))
+
+	logger.Debug("debug", slog.String("request", "abc"), slog.String("detail", "verbose"),
+		slog.Group("request", slog.String("id", "abc")))
+	logger.Info("info", slog.String("request", "abc"), slog.String("detail", "verbose"))
+	logger.WithGroup("tx").Debug("grouped", slog.String("hash", "0x1"), slog.String("request", "abc"))
+
+	records := decodeRecords(t, &buf)
+	require.Len(t, records, 3)
+
+	// below the level only allowed keys remain, including groups with an allowed key
+	assert.InDelta(t, 7, records[0]["block"], 0)
+	assert.Equal(t, map[string]any{"id": "abc"}, records[0]["request"])
+	assert.NotContains(t, records[0], "detail")
+	assert.NotContains(t, records[0], "payload")
+
+	// at or above the level everything remains
+	assert.Equal(t, "abc", records[1]["request"])
+	assert.Equal(t, "verbose", records[1]["detail"])
+	assert.Equal(t, "large", records[1]["payload"])
+
+	// attributes within a group are matched by their own key
+	assert.Equal(t, map[string]any{"request": "abc"}, records[2]["tx"])
+}
+
+func TestWithAllowList(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger, err := log.NewLogger(
+		log.WithWriter(&buf),
+		log.WithServiceName("test-service"),
+		log.WithAllowList(slog.LevelWarn, "block"),
+	)
+	require.NoError(t, err)
+
+	ctx := log.ContextWithTask(t.Context(), "indexer")
+	logger.InfoContext(ctx, "info", slog.Int("block", 7), slog.String("detail", "verbose"))
+	logger.ErrorContext(ctx, "error", slog.Int("block", 7), log.ErrAttr(errors.New("boom")))
+
+	records := decodeRecords(t, &buf)
+	require.Len(t, records, 2)
+
+	// identity and task attributes are always kept
+	for _, record := range records {
+		assert.Equal(t, "test-service", record["service"])
+		assert.Equal(t, "indexer", record[log.TaskKey])
+		assert.InDelta(t, 7, record["block"], 0)
+	}
+	assert.NotContains(t, records[0], "detail")
+	assert.Contains(t, records[1], log.ErrorKey)
+}
@@ -0,0 +1,56 @@
+package log
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/log/identity"
+	"github.com/zircuit-labs/zkr-go-common/version"
+)
+
+// Sampling used by NewLoggerForEnv outside of development environments:
+// per level and message, the first 100 records each second, then every 100th.
+const (
+	defaultSampleFirst      = 100
+	defaultSampleThereafter = 100
+	defaultSampleTick       = time.Second
+)
+
+// developmentEnvironments are the environments in which NewLoggerForEnv logs for humans.
+var developmentEnvironments = []string{"dev", "development", "local", "test"}
+
+// NewLoggerForEnv creates a logger with defaults suited to the environment of the configuration,
+// including the service identity (see identity.WhoAmI) and version.
+//   - In development environments ("dev", "development", "local" or "test"), text is logged at debug level.
+//   - In all others (eg "default", "staging" or "production"), JSON is logged at info level,
+//     with repetitive records below warn level sampled (see WithSampling).
+//
+// The given options are applied after these defaults, so can override them.
+// NOTE: The log level is global (see SetLogLevel), so is set by this function.
+func NewLoggerForEnv(cfg *config.Configuration, opts ...Option) (*slog.Logger, error) {
+	name, id := identity.WhoAmI()
+	defaults := []Option{
+		WithServiceName(name),
+		WithInstanceID(id),
+		WithVersion(&version.Info),
+	}
+	if IsDevelopment(cfg.Environment()) {
+		logLevel.Set(slog.LevelDebug)
+		defaults = append(defaults, WithLogStyle(LogStyleText))
+	} else {
+		logLevel.Set(slog.LevelInfo)
+		defaults = append(defaults,
+			WithLogStyle(LogStyleJSON),
+			WithSampling(defaultSampleFirst, defaultSampleThereafter, defaultSampleTick),
+		)
+	}
+	return NewLogger(append(defaults, opts...)...)
+}
+
+// IsDevelopment reports whether the environment is one in which NewLoggerForEnv logs for humans.
+func IsDevelopment(environment string) bool {
+	return slices.Contains(developmentEnvironments, strings.ToLower(environment))
+}
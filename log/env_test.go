@@ -0,0 +1,66 @@
+package log_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/log"
+)
+
+func TestIsDevelopment(t *testing.T) {
+	t.Parallel()
+
+	for _, env := range []string{"dev", "development", "local", "test", "Local"} {
+		assert.True(t, log.IsDevelopment(env), env)
+	}
+	for _, env := range []string{"default", "staging", "production", ""} {
+		assert.False(t, log.IsDevelopment(env), env)
+	}
+}
+
+func TestNewLoggerForEnv(t *testing.T) { //nolint:paralleltest // This test cannot be parallel since it changes the global log level
+	originalLevel := log.GetLogLevel()
+	t.Cleanup(func() {
+		_ = log.SetLogLevel(originalLevel)
+	})
+
+	newLogger := func(env string) (*bytes.Buffer, func(), error) {
+		cfg, err := config.NewConfiguration(nil, config.WithEnvPrefix("LOGENVTEST_"), config.WithDefaultEnv(env))
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		logger, err := log.NewLoggerForEnv(cfg, log.WithWriter(&buf))
+		return &buf, func() {
+			logger.Debug("debug")
+			for range 200 {
+				logger.Info("hot")
+			}
+		}, err
+	}
+
+	t.Run("development", func(t *testing.T) {
+		buf, logSome, err := newLogger("dev")
+		require.NoError(t, err)
+		assert.Equal(t, "debug", log.GetLogLevel())
+
+		logSome()
+		assert.Contains(t, buf.String(), "msg=debug")
+		assert.Equal(t, 201, bytes.Count(buf.Bytes(), []byte("\n")))
+	})
+
+	t.Run("production", func(t *testing.T) {
+		buf, logSome, err := newLogger("production")
+		require.NoError(t, err)
+		assert.Equal(t, "info", log.GetLogLevel())
+
+		logSome()
+		records := decodeRecords(t, buf)
+		// debug is disabled and the hot loop is sampled: the first 100, then the 200th
+		require.Len(t, records, 101)
+		assert.Equal(t, "hot", records[0]["msg"])
+		assert.Contains(t, records[0], "service")
+	})
+}
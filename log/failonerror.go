@@ -0,0 +1,149 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestingT is the part of testing.TB used to report unexpected error records.
+type TestingT interface {
+	Errorf(format string, args ...any)
+}
+
+type failOnErrorOptions struct {
+	messages []string
+	errs     []error
+}
+
+// FailOnErrorOption configures FailOnError and NewFailOnErrorHandler.
+type FailOnErrorOption func(options *failOnErrorOptions)
+
+// AllowMessage allows error records whose message contains substr.
+func AllowMessage(substr string) FailOnErrorOption {
+	return func(options *failOnErrorOptions) {
+		options.messages = append(options.messages, substr)
+	}
+}
+
+// AllowError allows error records with an error attribute (see ErrAttr) matching target according to errors.Is.
+func AllowError(target error) FailOnErrorOption {
+	return func(options *failOnErrorOptions) {
+		options.errs = append(options.errs, target)
+	}
+}
+
+// FailOnError creates a test logger (see NewTestLogger) which fails the test if any record at
+// slog.LevelError or above is logged, other than those allowed by the options. This catches
+// errors which are logged rather than returned (eg by consumers and tasks) being silently swallowed.
+func FailOnError(t *testing.T, opts ...FailOnErrorOption) *slog.Logger {
+	t.Helper()
+	return slog.New(NewFailOnErrorHandler(NewTestLogger(t).Handler(), t, opts...))
+}
+
+// failOnErrorHandler reports records at slog.LevelError or above to t, unless allowed.
+type failOnErrorHandler struct {
+	next    slog.Handler
+	t       TestingT
+	attrs   []slog.Attr // added with WithAttrs, to find errors added to the logger rather than the record
+	options failOnErrorOptions
+}
+
+// NewFailOnErrorHandler wraps a slog.Handler such that each record at slog.LevelError or above is
+// reported to t with Errorf, unless allowed by the options. All records are passed on to next.
+func NewFailOnErrorHandler(next slog.Handler, t TestingT, opts ...FailOnErrorOption) slog.Handler {
+	var options failOnErrorOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &failOnErrorHandler{next: next, t: t, options: options}
+}
+
+// Enabled implements slog.Handler.
+// Error records are always enabled, so that raising the log level cannot hide them.
+func (h *failOnErrorHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelError || h.next.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *failOnErrorHandler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level >= slog.LevelError && !h.allowed(record) {
+		h.t.Errorf("unexpected %s log: %s", record.Level, formatRecord(record, h.attrs))
+	}
+	if !h.next.Enabled(ctx, record.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, record)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *failOnErrorHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &failOnErrorHandler{
+		next:    h.next.WithAttrs(attrs),
+		t:       h.t,
+		attrs:   append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...),
+		options: h.options,
+	}
+}
+
+// WithGroup implements slog.Handler.
+func (h *failOnErrorHandler) WithGroup(name string) slog.Handler {
+	return &failOnErrorHandler{
+		next:    h.next.WithGroup(name),
+		t:       h.t,
+		attrs:   h.attrs,
+		options: h.options,
+	}
+}
+
+// allowed reports whether the record matches any of the allowed messages or errors.
+func (h *failOnErrorHandler) allowed(record slog.Record) bool {
+	for _, substr := range h.options.messages {
+		if strings.Contains(record.Message, substr) {
+			return true
+		}
+	}
+	if len(h.options.errs) == 0 {
+		return false
+	}
+
+	found := false
+	check := func(a slog.Attr) bool {
+		err, ok := a.Value.Any().(error)
+		if a.Key != ErrorKey || !ok {
+			return true
+		}
+		for _, target := range h.options.errs {
+			if errors.Is(err, target) {
+				found = true
+				return false
+			}
+		}
+		return true
+	}
+	for _, a := range h.attrs {
+		if !check(a) {
+			return true
+		}
+	}
+	record.Attrs(check)
+	return found
+}
+
+// formatRecord renders the message and attributes of a record for a test failure.
+func formatRecord(record slog.Record, attrs []slog.Attr) string {
+	var b strings.Builder
+	b.WriteString(record.Message)
+	write := func(a slog.Attr) bool {
+		fmt.Fprintf(&b, " %s=%v", a.Key, a.Value)
+		return true
+	}
+	for _, a := range attrs {
+		write(a)
+	}
+	record.Attrs(write)
+	return b.String()
+}
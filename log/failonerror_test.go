@@ -0,0 +1,62 @@
+package log_test
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// recordingT records failures rather than failing the test.
+type recordingT struct {
+	failures []string
+}
+
+func (r *recordingT) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestFailOnErrorHandler(t *testing.T) {
+	t.Parallel()
+
+	errAllowed := errors.New("allowed error")
+	rt := &recordingT{}
+	logger, buf := newTestLogger(t)
+	logger = slog.New(log.NewFailOnErrorHandler(logger.Handler(), rt,
+		log.AllowMessage("expected failure"),
+		log.AllowError(errAllowed),
+	))
+
+	logger.Info("info")
+	logger.Warn("warning", log.ErrAttr(errTest))
+	logger.Error("expected failure during test")
+	logger.Error("wrapped", log.ErrAttr(stacktrace.Wrap(errAllowed)))
+	logger.With(log.ErrAttr(errAllowed)).Error("added to logger")
+	require.Empty(t, rt.failures)
+
+	logger.WithGroup("consumer").Error("swallowed", log.ErrAttr(errTest), slog.Int("attempt", 3))
+	require.Len(t, rt.failures, 1)
+	assert.Contains(t, rt.failures[0], "unexpected ERROR log: swallowed")
+	assert.Contains(t, rt.failures[0], "attempt=3")
+
+	logger.Log(t.Context(), slog.LevelError+2, "worse")
+	require.Len(t, rt.failures, 2)
+
+	// all records are still logged
+	assert.Contains(t, buf.String(), `"msg":"swallowed"`)
+	assert.Contains(t, buf.String(), `"msg":"info"`)
+}
+
+func TestFailOnError(t *testing.T) {
+	t.Parallel()
+
+	logger := log.FailOnError(t, log.AllowMessage("ignored"))
+	logger.Info("info")
+	logger.Error("ignored error", log.ErrAttr(errTest))
+}
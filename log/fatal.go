@@ -0,0 +1,131 @@
+package log
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// LevelFatal is the level of records after which the process exits. See Fatal.
+	LevelFatal = slog.LevelError + 4
+
+	defaultFatalExitCode = 1
+	// fatalHookTimeout bounds the time spent in shutdown hooks, which may be waiting
+	// on the very goroutine which logged the fatal record.
+	fatalHookTimeout = 10 * time.Second
+)
+
+// Flusher is implemented by writers (and handlers) which buffer records, so that they can be
+// written out before the process exits.
+type Flusher interface {
+	Flush() error
+}
+
+// fatalHooks holds the shutdown hooks registered with OnFatal.
+var fatalHooks = struct {
+	mu    sync.Mutex
+	next  int
+	hooks map[int]func()
+}{hooks: map[int]func(){}}
+
+// OnFatal registers a shutdown hook which runs after a fatal record is logged, before the process exits
+// (eg to stop running tasks). Hooks run in the reverse order of registration, and are given at most
+// 10 seconds in total. The returned func unregisters the hook.
+func OnFatal(f func()) func() {
+	fatalHooks.mu.Lock()
+	defer fatalHooks.mu.Unlock()
+	id := fatalHooks.next
+	fatalHooks.next++
+	fatalHooks.hooks[id] = f
+	return func() {
+		fatalHooks.mu.Lock()
+		defer fatalHooks.mu.Unlock()
+		delete(fatalHooks.hooks, id)
+	}
+}
+
+// Fatal logs the message at LevelFatal, then flushes the logger, runs the hooks registered with
+// OnFatal, and exits the process. It never returns.
+// Loggers from NewLogger do the same for any record at LevelFatal (eg logger.Log(ctx, log.LevelFatal, msg)),
+// exiting with the code set by WithFatalExitCode; otherwise the exit code is 1.
+func Fatal(ctx context.Context, logger *slog.Logger, msg string, args ...any) {
+	logger.Log(ctx, LevelFatal, msg, args...)
+	// only reached if the logger did not exit itself
+	if f, ok := logger.Handler().(Flusher); ok {
+		_ = f.Flush()
+	}
+	shutdown(defaultFatalExitCode)
+}
+
+// shutdown runs the fatal hooks, then exits with the code.
+func shutdown(code int) {
+	fatalHooks.mu.Lock()
+	ids := make([]int, 0, len(fatalHooks.hooks))
+	for id := range fatalHooks.hooks {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	hooks := make([]func(), 0, len(ids))
+	for _, id := range slices.Backward(ids) {
+		hooks = append(hooks, fatalHooks.hooks[id])
+	}
+	fatalHooks.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, hook := range hooks {
+			hook()
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(fatalHookTimeout):
+	}
+
+	os.Exit(code) //revive:disable:deep-exit // intentional
+}
+
+// fatalHandler exits the process after handling a record at LevelFatal.
+type fatalHandler struct {
+	next   slog.Handler
+	writer io.Writer
+	code   int
+}
+
+// Enabled implements slog.Handler. Fatal records are always enabled.
+func (h *fatalHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= LevelFatal || h.next.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *fatalHandler) Handle(ctx context.Context, record slog.Record) error {
+	err := h.next.Handle(ctx, record)
+	if record.Level < LevelFatal {
+		return err
+	}
+
+	switch w := h.writer.(type) {
+	case Flusher:
+		_ = w.Flush()
+	case interface{ Sync() error }:
+		_ = w.Sync()
+	}
+	shutdown(h.code)
+	return err
+}
+
+// WithAttrs implements slog.Handler.
+func (h *fatalHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &fatalHandler{next: h.next.WithAttrs(attrs), writer: h.writer, code: h.code}
+}
+
+// WithGroup implements slog.Handler.
+func (h *fatalHandler) WithGroup(name string) slog.Handler {
+	return &fatalHandler{next: h.next.WithGroup(name), writer: h.writer, code: h.code}
+}
@@ -0,0 +1,116 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/log"
+)
+
+// fatalModeEnv selects how TestFatalSubprocess exits when run as a subprocess.
+const fatalModeEnv = "LOG_FATAL_TEST_MODE"
+
+// bufferedWriter holds everything written until flushed to stdout.
+type bufferedWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *bufferedWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *bufferedWriter) Flush() error {
+	_, err := w.buf.WriteTo(os.Stdout)
+	return err
+}
+
+// TestFatalSubprocess only does something when run by TestFatal, since the process exits.
+func TestFatalSubprocess(t *testing.T) {
+	t.Parallel()
+	mode := os.Getenv(fatalModeEnv)
+	if mode == "" {
+		t.Skip("only run as a subprocess of TestFatal")
+	}
+
+	// hooks run in reverse order of registration, unless unregistered
+	log.OnFatal(func() { fmt.Println("hook 1") })
+	unregister := log.OnFatal(func() { fmt.Println("unregistered hook") })
+	log.OnFatal(func() { fmt.Println("hook 2") })
+	unregister()
+
+	ctx := context.Background()
+	switch mode {
+	case "level":
+		logger, err := log.NewLogger(log.WithWriter(&bufferedWriter{}), log.WithFatalExitCode(3))
+		require.NoError(t, err)
+		_ = log.SetLogLevel("error")
+		logger.Info("not logged")
+		logger.Log(ctx, log.LevelFatal, "cannot continue", log.ErrAttr(errors.New("disk full")))
+	case "func":
+		logger, err := log.NewLogger(log.WithWriter(os.Stdout))
+		require.NoError(t, err)
+		log.Fatal(ctx, logger.With("component", "db"), "cannot continue")
+	case "other":
+		log.Fatal(ctx, log.NewNilLogger(), "cannot continue")
+	}
+	fmt.Println("not reached")
+}
+
+func TestFatal(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		mode     string
+		exitCode int
+		contains []string
+	}{
+		{
+			mode:     "level",
+			exitCode: 3,
+			contains: []string{`"level":"fatal","msg":"cannot continue","error":"disk full"`},
+		},
+		{
+			mode:     "func",
+			exitCode: 1,
+			contains: []string{`"level":"fatal","msg":"cannot continue","component":"db"`},
+		},
+		{
+			mode:     "other",
+			exitCode: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.mode, func(t *testing.T) {
+			t.Parallel()
+
+			cmd := exec.CommandContext(t.Context(), os.Args[0], "-test.run=^TestFatalSubprocess$") //nolint:gosec // re-running this test binary
+			cmd.Env = append(os.Environ(), fatalModeEnv+"="+tc.mode)
+			out, err := cmd.Output()
+			var exitErr *exec.ExitError
+			require.ErrorAs(t, err, &exitErr)
+			assert.Equal(t, tc.exitCode, exitErr.ExitCode())
+
+			output := string(out)
+			for _, s := range tc.contains {
+				assert.Contains(t, output, s)
+			}
+			// buffered records are flushed before the hooks run
+			assert.NotContains(t, output, "not logged")
+			assert.NotContains(t, output, "unregistered hook")
+			assert.NotContains(t, output, "not reached")
+			assert.Regexp(t, "(?s)hook 2.*hook 1", output)
+			if len(tc.contains) > 0 {
+				assert.Less(t, strings.Index(output, "fatal"), strings.Index(output, "hook 2"))
+			}
+		})
+	}
+}
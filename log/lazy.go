@@ -0,0 +1,24 @@
+package log
+
+import "log/slog"
+
+// LazyValue is a slog.LogValuer which computes its value only when resolved.
+type LazyValue func() slog.Value
+
+// LogValue implements slog.LogValuer.
+func (f LazyValue) LogValue() slog.Value {
+	return f()
+}
+
+// Deferred implements replaceattrmore.Deferred so that our handlers leave
+// resolution to the final handler, after any filtering or sampling.
+func (f LazyValue) Deferred() {}
+
+// Lazy returns an attribute whose value is only computed if the record is emitted,
+// ie the level is enabled and the record is not dropped by any handler.
+// Use this for expensive values such as serialized structs or digests.
+// NOTE: Pass lazy attributes to the log call itself. Attributes given to
+// logger.With are resolved immediately by the standard library handlers.
+func Lazy(key string, f func() slog.Value) slog.Attr {
+	return slog.Any(key, LazyValue(f))
+}
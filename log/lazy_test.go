@@ -0,0 +1,63 @@
+package log_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/log"
+)
+
+// dropHandler drops all records, as a sampling handler might.
+type dropHandler struct {
+	slog.Handler
+}
+
+func (h dropHandler) Handle(_ context.Context, _ slog.Record) error {
+	return nil
+}
+
+func TestLazy(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	expensive := func() slog.Value {
+		calls++
+		return slog.StringValue("computed")
+	}
+
+	logger, buf := newTestLogger(t)
+
+	// not computed when the level is disabled
+	logger.Debug("debug", log.Lazy("value", expensive))
+	assert.Zero(t, calls)
+	assert.Empty(t, buf.String())
+
+	// computed once when emitted
+	logger.WithGroup("group").Info("info", log.Lazy("value", expensive))
+	assert.Equal(t, 1, calls)
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	group, ok := entry["group"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "computed", group["value"])
+}
+
+func TestLazyDropped(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	expensive := func() slog.Value {
+		calls++
+		return slog.StringValue("computed")
+	}
+
+	// not computed when a later handler drops the record
+	logger := slog.New(log.NewLoggableErrorHandler(dropHandler{slog.DiscardHandler}))
+	logger.Info("info", log.Lazy("value", expensive))
+	assert.Zero(t, calls)
+}
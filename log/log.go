@@ -7,12 +7,15 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/zircuit-labs/zkr-go-common/metrics"
 	"github.com/zircuit-labs/zkr-go-common/version"
 )
 
 const (
 	ErrorKey = "error"
+	OpsKey   = "ops" // Key of the chain of operations (see xerrors.Op) logged alongside an error
 )
 
 type LogStyle int
@@ -69,6 +72,21 @@ type options struct {
 	serviceName string
 	versionInfo *version.VersionInformation
 	logStyle    LogStyle
+
+	target        Target
+	targetNetwork string
+	targetAddress string
+
+	allowListLevel slog.Leveler
+	allowList      []string
+
+	fatalExitCode int
+
+	sampleFirst      int
+	sampleThereafter int
+	sampleTick       time.Duration
+
+	metrics *metrics.Registry
 }
 
 // Option configures logger creation
@@ -114,27 +132,88 @@ func WithLogStyle(logStyle LogStyle) Option {
 	}
 }
 
+// WithTarget configures the logger to send logs to the given target, rather than the writer.
+// Logs are still formatted according to the log style.
+func WithTarget(target Target) Option {
+	return func(opts *options) {
+		opts.target = target
+	}
+}
+
+// WithTargetAddress overrides the address of the syslog or journal target.
+// The network is as per net.Dial, eg "unixgram" (the default), "udp", or "tcp".
+func WithTargetAddress(network, address string) Option {
+	return func(opts *options) {
+		opts.targetNetwork = network
+		opts.targetAddress = address
+	}
+}
+
+// WithAllowList configures the logger such that records below the given level (eg debug records when
+// the level is info) only include attributes with the given keys, while all others are dropped.
+// Records at or above the level include all attributes. The service identity, version, and task
+// attributes are always included.
+func WithAllowList(level slog.Leveler, keys ...string) Option {
+	return func(opts *options) {
+		opts.allowListLevel = level
+		opts.allowList = keys
+	}
+}
+
+// WithSampling configures the logger such that, within each tick, only the first records with the same level
+// and message are logged, then every thereafter-th. Records at warn level and above are never dropped.
+// See NewSamplingHandler.
+func WithSampling(first, thereafter int, tick time.Duration) Option {
+	return func(opts *options) {
+		opts.sampleFirst = first
+		opts.sampleThereafter = thereafter
+		opts.sampleTick = tick
+	}
+}
+
+// WithoutSampling configures the logger to log every record (default).
+func WithoutSampling() Option {
+	return func(opts *options) {
+		opts.sampleTick = 0
+	}
+}
+
+// WithFatalExitCode sets the code the process exits with after a record at LevelFatal is logged (default 1).
+func WithFatalExitCode(code int) Option {
+	return func(opts *options) {
+		opts.fatalExitCode = code
+	}
+}
+
 // NewLogger creates a new logger using replaceattrmore.Handler chained with slog.JSONHandler.
 // This approach leverages all of slog's built-in functionality while providing custom
 // LoggableError flattening. Use ErrAttr() when logging errors with this logger.
 func NewLogger(opts ...Option) (*slog.Logger, error) {
 	// Parse cfg
 	cfg := options{
-		writer:   os.Stdout,
-		logStyle: LogStyleJSON,
+		writer:        os.Stdout,
+		logStyle:      LogStyleJSON,
+		fatalExitCode: defaultFatalExitCode,
 	}
 	for _, opt := range opts {
 		opt(&cfg)
 	}
 
 	// Create base log handler with lowercase level formatting and key sanitization as required
-	logHandler, err := formatHandler(cfg.logStyle, cfg.writer)
+	var logHandler slog.Handler
+	var err error
+	if cfg.target == TargetWriter {
+		logHandler, err = formatHandler(cfg.logStyle, cfg.writer)
+	} else {
+		logHandler, err = newTargetHandler(cfg)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	// Chain with loggable error handler for error flattening
-	handler := NewLoggableErrorHandler(logHandler)
+	// Chain with loggable error handler for error flattening,
+	// and add any task from the context
+	handler := NewTaskHandler(NewLoggableErrorHandler(logHandler))
 
 	// Add Optional Attributes
 	attrs := []slog.Attr{}
@@ -156,7 +235,29 @@ func NewLogger(opts ...Option) (*slog.Logger, error) {
 		}
 	}
 
-	return slog.New(handler.WithAttrs(attrs)), nil
+	handler = handler.WithAttrs(attrs)
+	if cfg.allowListLevel != nil {
+		// Wrapped outermost so that the attributes above, and the task, are always kept
+		handler = NewAllowListHandler(handler, cfg.allowListLevel, cfg.allowList...)
+	}
+
+	if cfg.sampleTick > 0 {
+		// Sampled after the allow-list, so that dropped records are never filtered
+		handler = NewSamplingHandler(handler, cfg.sampleFirst, cfg.sampleThereafter, cfg.sampleTick)
+	}
+
+	if cfg.metrics != nil {
+		// Counted ahead of sampling, so that dropped records are counted too
+		handler, err = NewMetricsHandler(handler, cfg.metrics)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Records at LevelFatal are flushed before shutting down
+	handler = &fatalHandler{next: handler, writer: cfg.writer, code: cfg.fatalExitCode}
+
+	return slog.New(handler), nil
 }
 
 func formatHandler(logStyle LogStyle, writer io.Writer) (slog.Handler, error) {
@@ -166,7 +267,7 @@ func formatHandler(logStyle LogStyle, writer io.Writer) (slog.Handler, error) {
 			// Convert level to lowercase to match our expected format
 			if a.Key == slog.LevelKey {
 				if lvl, ok := a.Value.Any().(slog.Level); ok {
-					a.Value = slog.StringValue(strings.ToLower(lvl.String()))
+					a.Value = slog.StringValue(levelName(lvl))
 				} else {
 					// Fallback if another handler set a string or other kind
 					a.Value = slog.StringValue(strings.ToLower(a.Value.String()))
@@ -125,6 +125,53 @@ func TestErrorLog(t *testing.T) {
 	assert.JSONEq(t, expectedLog, cleanedActual)
 }
 
+// TestLogErrorLabeledStackTraces validates that labeled stack traces are logged as separate entries.
+func TestLogErrorLabeledStackTraces(t *testing.T) {
+	t.Parallel()
+
+	// Create a test logger
+	logger, buf := newTestLogger(t)
+
+	err := stacktrace.WrapLabeled(errTest, "submitted_at")
+	err = stacktrace.WrapLabeled(err, "retried_at attempt 1")
+	logger.Error("example labeled error log", log.ErrAttr(err))
+
+	expectedLog := `
+	{
+		"time":"2021-01-01T00:00:00Z",
+		"level": "error",
+		"error": "test error",
+		"error_detail": {
+			"github_com/zircuit-labs/zkr-go-common/xerrors_ExtendedError[github_com/zircuit-labs/zkr-go-common/xerrors/stacktrace_LabeledStackTrace]": [
+				{
+					"label": "retried_at attempt 1",
+					"stacktrace": [
+						{
+							"func": "github.com/zircuit-labs/zkr-go-common/log_test.TestLogErrorLabeledStackTraces",
+							"line": 0
+						}
+					]
+				},
+				{
+					"label": "submitted_at",
+					"stacktrace": [
+						{
+							"func": "github.com/zircuit-labs/zkr-go-common/log_test.TestLogErrorLabeledStackTraces",
+							"line": 0
+						}
+					]
+				}
+			]
+		},
+		"msg": "example labeled error log",
+		"service": "test-service"
+	}
+	`
+	actualLogJSON := buf.String()
+	cleanedActual := comparableLog(actualLogJSON)
+	assert.JSONEq(t, expectedLog, cleanedActual)
+}
+
 // TestLogErrorSimple validates that a simple error is logged correctly.
 func TestLogErrorSimple(t *testing.T) {
 	t.Parallel()
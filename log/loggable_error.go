@@ -13,13 +13,28 @@ import (
 )
 
 // collectLogValuerAttrs walks an error chain and collects slog.LogValuer data as sanitized attributes.
+// Data of a type found more than once in the chain (eg labeled stack traces) is collected into an array, outermost first.
 func collectLogValuerAttrs(err error) []slog.Attr {
 	var attrs []slog.Attr
+	index := map[string]int{}      // of the attribute of each type path
+	repeated := map[string][]any{} // entries of each type path found more than once
 	for e := err; e != nil; e = errors.Unwrap(e) {
 		if lv, ok := e.(slog.LogValuer); ok {
 			typePath := getTypePath(e)
 			logValue := lv.LogValue()
 
+			if i, ok := index[typePath]; ok {
+				// Render repeated types as separate entries rather than duplicate keys
+				entries, ok := repeated[typePath]
+				if !ok {
+					entries = []any{slogValueToAny(attrs[i].Value)}
+				}
+				repeated[typePath] = append(entries, slogValueToAny(logValue))
+				attrs[i] = slog.Any(typePath, repeated[typePath])
+				continue
+			}
+			index[typePath] = len(attrs)
+
 			if logValue.Kind() == slog.KindGroup {
 				// If it's already a group, create a nested group with the type path
 				// Convert group values to proper format using slogValueToAny
@@ -108,6 +123,9 @@ func flattenLoggableError(loggableErr LoggableError) []slog.Attr {
 	attrs := []slog.Attr{
 		slog.String(ErrorKey, loggableErr.Error()),
 	}
+	if ops := xerrors.Ops(loggableErr.err); len(ops) > 0 {
+		attrs = append(attrs, slog.Any(OpsKey, ops))
+	}
 
 	// Collect error_detail as attributes from the error chain
 	if errorDetailAttrs := collectLogValuerAttrs(loggableErr.err); len(errorDetailAttrs) > 0 {
@@ -139,6 +157,9 @@ func flattenJoinedErrors(errs []error) []slog.Attr {
 		// Collect attributes for this specific error
 		var thisErrorAttrs []slog.Attr
 		thisErrorAttrs = append(thisErrorAttrs, slog.String("error", err.Error()))
+		if ops := xerrors.Ops(err); len(ops) > 0 {
+			thisErrorAttrs = append(thisErrorAttrs, slog.Any(OpsKey, ops))
+		}
 
 		// Add any extended error details
 		if details := collectLogValuerAttrs(err); len(details) > 0 {
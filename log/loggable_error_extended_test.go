@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/xerrors"
 	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
 	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
 	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
@@ -134,6 +135,58 @@ func TestLoggableError_ComplexChains(t *testing.T) {
 	})
 }
 
+func TestLoggableError_Ops(t *testing.T) {
+	t.Parallel()
+
+	t.Run("SingleError", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newTestLogger(t)
+
+		err := xerrors.Op("pg.Exec", errors.New("connection reset"))
+		err = errcontext.Add(err, slog.String("table", "users"))
+		err = xerrors.Op("ledger.Debit", err)
+
+		logger.Error("debit failed", log.ErrAttr(err))
+
+		expectedLog := `{
+			"time": "2021-01-01T00:00:00Z",
+			"level": "error",
+			"error": "connection reset",
+			"ops": ["ledger.Debit", "pg.Exec"],
+			"error_detail": {
+				"github_com/zircuit-labs/zkr-go-common/xerrors_ExtendedError[github_com/zircuit-labs/zkr-go-common/xerrors/errcontext_Context]": {
+					"table": "users"
+				}
+			},
+			"msg": "debit failed",
+			"service": "test-service"
+		}`
+		assert.JSONEq(t, expectedLog, comparableLog(buf.String()))
+	})
+
+	t.Run("JoinedErrors", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newTestLogger(t)
+
+		err := errors.Join(xerrors.Op("s3.Get", errors.New("first")), errors.New("second"))
+		logger.Error("sync failed", log.ErrAttr(err))
+
+		expectedLog := `{
+			"time": "2021-01-01T00:00:00Z",
+			"level": "error",
+			"error": "first; second",
+			"errors": ["first", "second"],
+			"error_detail": {
+				"error_0": {"error": "first", "ops": ["s3.Get"]},
+				"error_1": {"error": "second"}
+			},
+			"msg": "sync failed",
+			"service": "test-service"
+		}`
+		assert.JSONEq(t, expectedLog, comparableLog(buf.String()))
+	})
+}
+
 func TestJoinedErrors_EdgeCases(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,113 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/zircuit-labs/zkr-go-common/metrics"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+// WithMetrics configures the logger to count records by level, and errors (see ErrAttr) by level and class,
+// with metrics created by the registry. See NewMetricsHandler.
+func WithMetrics(registry *metrics.Registry) Option {
+	return func(opts *options) {
+		opts.metrics = registry
+	}
+}
+
+type logMetrics struct {
+	records *metrics.Counter
+	errors  *metrics.Counter
+}
+
+func newLogMetrics(registry *metrics.Registry) (*logMetrics, error) {
+	records, err := registry.Counter("log_records_total", "Number of records logged by level, including any dropped by sampling.", "level")
+	if err != nil {
+		return nil, err
+	}
+	errs, err := registry.Counter("log_errors_total", "Number of errors logged by level and error class.", "level", "class")
+	if err != nil {
+		return nil, err
+	}
+	return &logMetrics{records: records, errors: errs}, nil
+}
+
+// metricsHandler counts records and the classes of their errors.
+type metricsHandler struct {
+	next    slog.Handler
+	metrics *logMetrics
+	err     error // added with WithAttrs, to count errors added to the logger rather than the record
+}
+
+// NewMetricsHandler wraps a slog.Handler such that each record is counted by level (log_records_total),
+// and each with an error attribute (see ErrAttr) by level and errclass (log_errors_total),
+// so that the rate of eg Persistent errors can be alerted on without parsing logs.
+// Records are counted before being passed to next, so those dropped by sampling are still counted.
+// The counters carry the standard labels of the registry, and may be shared between handlers,
+// as the same counters are used if already created.
+func NewMetricsHandler(next slog.Handler, registry *metrics.Registry) (slog.Handler, error) {
+	m, err := newLogMetrics(registry)
+	if err != nil {
+		return nil, err
+	}
+	return &metricsHandler{next: next, metrics: m}, nil
+}
+
+// Enabled implements slog.Handler.
+func (h *metricsHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *metricsHandler) Handle(ctx context.Context, record slog.Record) error {
+	level := levelName(record.Level)
+	h.metrics.records.Inc(level)
+
+	err := h.err
+	record.Attrs(func(a slog.Attr) bool {
+		if e, ok := errorAttr(a); ok {
+			err = e
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		h.metrics.errors.Inc(level, errclass.GetClass(err).String())
+	}
+	return h.next.Handle(ctx, record)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *metricsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	err := h.err
+	for _, a := range attrs {
+		if e, ok := errorAttr(a); ok {
+			err = e
+		}
+	}
+	return &metricsHandler{next: h.next.WithAttrs(attrs), metrics: h.metrics, err: err}
+}
+
+// WithGroup implements slog.Handler.
+func (h *metricsHandler) WithGroup(name string) slog.Handler {
+	return &metricsHandler{next: h.next.WithGroup(name), metrics: h.metrics, err: h.err}
+}
+
+// errorAttr returns the error of an error attribute (see ErrAttr).
+func errorAttr(a slog.Attr) (error, bool) {
+	if a.Key != ErrorKey {
+		return nil, false
+	}
+	err, ok := a.Value.Any().(error)
+	return err, ok && err != nil
+}
+
+// levelName returns the lower case name of the level, as logged.
+func levelName(level slog.Level) string {
+	if level == LevelFatal {
+		return "fatal"
+	}
+	return strings.ToLower(level.String())
+}
@@ -0,0 +1,72 @@
+package log_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/log/identity"
+	"github.com/zircuit-labs/zkr-go-common/metrics"
+	"github.com/zircuit-labs/zkr-go-common/version"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+	registry := prometheus.NewRegistry()
+	r := metrics.NewRegistry(metrics.WithRegisterer(registry))
+	logger, err := log.NewLogger(
+		log.WithWriter(io.Discard),
+		log.WithMetrics(r),
+		log.WithSampling(1, 0, time.Hour),
+	)
+	require.NoError(t, err)
+
+	// records dropped by sampling are still counted
+	for range 3 {
+		logger.Info("repeated")
+	}
+	logger.Error("transient", log.ErrAttr(errclass.WrapAs(errTest, errclass.Transient)))
+	logger.Error("persistent", log.ErrAttr(errclass.WrapAs(errTest, errclass.Persistent)))
+	// errors added to the logger are counted too
+	logger.With(log.ErrAttr(errclass.WrapAs(errTest, errclass.Persistent))).Warn("persistent")
+	logger.Error("unclassified", log.ErrAttr(errors.New("plain")))
+	logger.Error("nil error", log.ErrAttr(nil))
+
+	// the counters carry the standard labels
+	service, instance := identity.WhoAmI()
+	v := version.Info.Version
+	if v == "" {
+		v = "unknown"
+	}
+	expected := strings.NewReplacer(
+		"INSTANCE", fmt.Sprintf("instance=%q", instance),
+		"SERVICE", fmt.Sprintf("service=%q,version=%q", service, v),
+	).Replace(`
+# HELP log_errors_total Number of errors logged by level and error class.
+# TYPE log_errors_total counter
+log_errors_total{class="persistent",INSTANCE,level="error",SERVICE} 1
+log_errors_total{class="persistent",INSTANCE,level="warn",SERVICE} 1
+log_errors_total{class="transient",INSTANCE,level="error",SERVICE} 1
+log_errors_total{class="unknown",INSTANCE,level="error",SERVICE} 1
+# HELP log_records_total Number of records logged by level, including any dropped by sampling.
+# TYPE log_records_total counter
+log_records_total{INSTANCE,level="error",SERVICE} 4
+log_records_total{INSTANCE,level="info",SERVICE} 3
+log_records_total{INSTANCE,level="warn",SERVICE} 1
+`)
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected)))
+
+	// metrics may be shared between loggers
+	_, err = log.NewLogger(log.WithWriter(io.Discard), log.WithMetrics(r))
+	assert.NoError(t, err)
+}
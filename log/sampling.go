@@ -0,0 +1,85 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// sampler counts records by level and message within each tick.
+// It is shared by all handlers derived from the same sampling handler.
+type sampler struct {
+	first      int
+	thereafter int
+	tick       time.Duration
+
+	mu     sync.Mutex
+	start  time.Time
+	counts map[sampleKey]int
+}
+
+type sampleKey struct {
+	level   slog.Level
+	message string
+}
+
+// sample reports whether the record should be kept.
+func (s *sampler) sample(record slog.Record) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if record.Time.Sub(s.start) >= s.tick || record.Time.Before(s.start) {
+		s.start = record.Time
+		clear(s.counts)
+	}
+	key := sampleKey{level: record.Level, message: record.Message}
+	s.counts[key]++
+	n := s.counts[key]
+	return n <= s.first || (s.thereafter > 0 && (n-s.first)%s.thereafter == 0)
+}
+
+// samplingHandler drops repetitive records below warn level.
+type samplingHandler struct {
+	next    slog.Handler
+	sampler *sampler
+}
+
+// NewSamplingHandler wraps a slog.Handler such that, within each tick, only the first records with the same
+// level and message are kept, and every thereafter-th record after that (none if thereafter is not positive).
+// This keeps the cost of logging in hot loops bounded while still showing that they happen.
+// Records at warn level and above are never dropped.
+func NewSamplingHandler(next slog.Handler, first, thereafter int, tick time.Duration) slog.Handler {
+	return &samplingHandler{
+		next: next,
+		sampler: &sampler{
+			first:      first,
+			thereafter: thereafter,
+			tick:       tick,
+			counts:     make(map[sampleKey]int),
+		},
+	}
+}
+
+// Enabled implements slog.Handler.
+func (h *samplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *samplingHandler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level < slog.LevelWarn && !h.sampler.sample(record) {
+		return nil
+	}
+	return h.next.Handle(ctx, record)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{next: h.next.WithAttrs(attrs), sampler: h.sampler}
+}
+
+// WithGroup implements slog.Handler.
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{next: h.next.WithGroup(name), sampler: h.sampler}
+}
@@ -0,0 +1,84 @@
+package log_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/log"
+)
+
+func TestSamplingHandler(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	handler := log.NewSamplingHandler(
+		slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		2, 3, time.Second,
+	)
+	logger := slog.New(handler)
+	start := time.Now()
+
+	emit := func(level slog.Level, msg string, offset time.Duration, i int) {
+		record := slog.NewRecord(start.Add(offset), level, msg, 0)
+		record.AddAttrs(slog.Int("i", i))
+		require.NoError(t, logger.Handler().Handle(t.Context(), record))
+	}
+
+	for i := range 10 {
+		emit(slog.LevelInfo, "hot", 0, i)
+		emit(slog.LevelWarn, "warning", 0, i)
+	}
+	// derived handlers share the counts
+	require.NoError(t, logger.With(slog.String("k", "v")).Handler().Handle(t.Context(),
+		slog.NewRecord(start, slog.LevelInfo, "hot", 0)))
+	// counts are reset after each tick
+	emit(slog.LevelInfo, "hot", time.Second, 10)
+
+	var hot []float64
+	warnings := 0
+	for _, record := range decodeRecords(t, &buf) {
+		switch record["msg"] {
+		case "hot":
+			i, ok := record["i"].(float64)
+			if !ok {
+				i = -1
+			}
+			hot = append(hot, i)
+		case "warning":
+			warnings++
+		}
+	}
+
+	// first 2, then every 3rd: 5th, 8th and 11th (the derived handler), then reset
+	assert.Equal(t, []float64{0, 1, 4, 7, -1, 10}, hot)
+	assert.Equal(t, 10, warnings)
+}
+
+func TestWithSampling(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger, err := log.NewLogger(
+		log.WithWriter(&buf),
+		log.WithLogStyle(log.LogStyleJSON),
+		log.WithSampling(1, 0, time.Hour),
+	)
+	require.NoError(t, err)
+
+	for range 5 {
+		logger.Info("hot")
+		logger.Error("failed")
+	}
+
+	records := decodeRecords(t, &buf)
+	require.Len(t, records, 6)
+	assert.Equal(t, "hot", records[0]["msg"])
+	for _, record := range records[1:] {
+		assert.Equal(t, "failed", record["msg"])
+	}
+}
@@ -0,0 +1,236 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Target is the destination of log output.
+type Target int
+
+const (
+	// TargetWriter writes logs to the configured io.Writer (stdout by default).
+	TargetWriter Target = iota
+	// TargetSyslog sends logs to a syslog daemon as RFC5424 messages.
+	TargetSyslog
+	// TargetJournal sends logs to the systemd journal using its native protocol.
+	TargetJournal
+)
+
+const (
+	// DefaultSyslogAddress is the local syslog socket used by TargetSyslog.
+	DefaultSyslogAddress = "/dev/log"
+	// DefaultJournalAddress is the local journald socket used by TargetJournal.
+	DefaultJournalAddress = "/run/systemd/journal/socket"
+
+	syslogFacilityUser = 1
+	syslogMaxAppName   = 48
+)
+
+// ParseTarget converts a configuration value (writer, syslog, or journal) into a Target.
+func ParseTarget(s string) (Target, error) {
+	switch strings.ToLower(s) {
+	case "", "writer", "stdout":
+		return TargetWriter, nil
+	case "syslog":
+		return TargetSyslog, nil
+	case "journal", "journald":
+		return TargetJournal, nil
+	default:
+		return TargetWriter, fmt.Errorf("unsupported log target: %q", s)
+	}
+}
+
+// Priority maps a slog level onto a syslog/journald priority:
+// debug (7), info (6), warning (4), error (3), and critical (2) for levels above error.
+func Priority(level slog.Level) int {
+	switch {
+	case level < slog.LevelInfo:
+		return 7
+	case level < slog.LevelWarn:
+		return 6
+	case level < slog.LevelError:
+		return 4
+	case level < slog.LevelError+4:
+		return 3
+	default:
+		return 2
+	}
+}
+
+// targetHandler formats each record with the configured style, then frames it for the target.
+type targetHandler struct {
+	inner slog.Handler
+	out   *targetWriter
+}
+
+func newTargetHandler(cfg options) (slog.Handler, error) {
+	network, address := cfg.targetNetwork, cfg.targetAddress
+	if network == "" {
+		network = "unixgram"
+	}
+
+	out := &targetWriter{
+		network: network,
+		address: address,
+		appName: cfg.serviceName,
+	}
+	if out.appName == "" {
+		out.appName = filepath.Base(os.Args[0])
+	}
+
+	switch cfg.target {
+	case TargetSyslog:
+		if out.address == "" {
+			out.address = DefaultSyslogAddress
+		}
+		out.hostname, _ = os.Hostname()
+		out.frame = out.syslogFrame
+	case TargetJournal:
+		if out.address == "" {
+			out.address = DefaultJournalAddress
+		}
+		out.frame = out.journalFrame
+	default:
+		return nil, fmt.Errorf("unsupported log target option: %v", cfg.target)
+	}
+
+	if err := out.dial(); err != nil {
+		return nil, err
+	}
+
+	inner, err := formatHandler(cfg.logStyle, out)
+	if err != nil {
+		return nil, err
+	}
+	return &targetHandler{inner: inner, out: out}, nil
+}
+
+// Enabled implements slog.Handler.
+func (h *targetHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *targetHandler) Handle(ctx context.Context, record slog.Record) error {
+	h.out.mu.Lock()
+	defer h.out.mu.Unlock()
+
+	h.out.buf.Reset()
+	if err := h.inner.Handle(ctx, record); err != nil {
+		return err
+	}
+	return h.out.send(record.Level, record.Time)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *targetHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &targetHandler{inner: h.inner.WithAttrs(attrs), out: h.out}
+}
+
+// WithGroup implements slog.Handler.
+func (h *targetHandler) WithGroup(name string) slog.Handler {
+	return &targetHandler{inner: h.inner.WithGroup(name), out: h.out}
+}
+
+// targetWriter collects a single formatted record, and sends it as one message.
+// It is shared by all handlers derived from the same logger.
+type targetWriter struct {
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	conn     net.Conn
+	network  string
+	address  string
+	appName  string
+	hostname string
+	frame    func(level slog.Level, t time.Time, msg []byte) []byte
+}
+
+// Write implements io.Writer, and is only called by the inner handler while mu is held.
+func (w *targetWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *targetWriter) dial() error {
+	conn, err := net.Dial(w.network, w.address)
+	if err != nil {
+		return fmt.Errorf("failed to connect to log target %s %s: %w", w.network, w.address, err)
+	}
+	w.conn = conn
+	return nil
+}
+
+// send writes the framed record, reconnecting once if the daemon has restarted.
+func (w *targetWriter) send(level slog.Level, t time.Time) error {
+	msg := w.frame(level, t, bytes.TrimRight(w.buf.Bytes(), "\n"))
+	if w.conn != nil {
+		if _, err := w.conn.Write(msg); err == nil {
+			return nil
+		}
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	if err := w.dial(); err != nil {
+		return err
+	}
+	_, err := w.conn.Write(msg)
+	return err
+}
+
+// syslogFrame formats the message as per RFC5424, using octet counting for stream connections (RFC6587).
+func (w *targetWriter) syslogFrame(level slog.Level, t time.Time, msg []byte) []byte {
+	hostname := w.hostname
+	if hostname == "" {
+		hostname = "-"
+	}
+	appName := w.appName
+	if len(appName) > syslogMaxAppName {
+		appName = appName[:syslogMaxAppName]
+	}
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %d - - ",
+		syslogFacilityUser*8+Priority(level),
+		t.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		hostname,
+		appName,
+		os.Getpid(),
+	)
+	b.Write(msg)
+
+	if strings.HasPrefix(w.network, "tcp") || w.network == "unix" {
+		return append([]byte(strconv.Itoa(b.Len())+" "), b.Bytes()...)
+	}
+	return b.Bytes()
+}
+
+// journalFrame formats the message using the journald native protocol.
+func (w *targetWriter) journalFrame(level slog.Level, _ time.Time, msg []byte) []byte {
+	var b bytes.Buffer
+	writeJournalField(&b, "PRIORITY", []byte(strconv.Itoa(Priority(level))))
+	writeJournalField(&b, "SYSLOG_IDENTIFIER", []byte(w.appName))
+	writeJournalField(&b, "MESSAGE", msg)
+	return b.Bytes()
+}
+
+// writeJournalField writes a single field.
+// Formatted records never contain a newline (the JSON and text handlers escape them),
+// so the simple KEY=value encoding is always sufficient.
+func writeJournalField(b *bytes.Buffer, key string, value []byte) {
+	b.WriteString(key)
+	b.WriteByte('=')
+	b.Write(value)
+	b.WriteByte('\n')
+}
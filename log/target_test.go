@@ -0,0 +1,137 @@
+package log_test
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/log"
+)
+
+// listen creates a unix datagram socket standing in for the syslog or journald daemon.
+func listen(t *testing.T) (string, func() string) {
+	t.Helper()
+	// unix socket paths are limited in length, so avoid the (long) test temp dir
+	dir, err := os.MkdirTemp("", "log")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	addr := filepath.Join(dir, "sock")
+	conn, err := net.ListenPacket("unixgram", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return addr, func() string {
+		buf := make([]byte, 64*1024)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second*5)))
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		return string(buf[:n])
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		value  string
+		target log.Target
+		err    bool
+	}{
+		{value: "", target: log.TargetWriter},
+		{value: "stdout", target: log.TargetWriter},
+		{value: "syslog", target: log.TargetSyslog},
+		{value: "Journal", target: log.TargetJournal},
+		{value: "eventlog", err: true},
+	}
+	for _, tc := range testCases {
+		target, err := log.ParseTarget(tc.value)
+		if tc.err {
+			assert.Error(t, err, tc.value)
+			continue
+		}
+		assert.NoError(t, err, tc.value)
+		assert.Equal(t, tc.target, target, tc.value)
+	}
+}
+
+func TestPriority(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 7, log.Priority(slog.LevelDebug))
+	assert.Equal(t, 6, log.Priority(slog.LevelInfo))
+	assert.Equal(t, 6, log.Priority(slog.LevelInfo+2))
+	assert.Equal(t, 4, log.Priority(slog.LevelWarn))
+	assert.Equal(t, 3, log.Priority(slog.LevelError))
+	assert.Equal(t, 2, log.Priority(slog.LevelError+4))
+}
+
+func TestSyslogTarget(t *testing.T) {
+	t.Parallel()
+
+	addr, read := listen(t)
+	logger, err := log.NewLogger(
+		log.WithTarget(log.TargetSyslog),
+		log.WithTargetAddress("unixgram", addr),
+		log.WithServiceName("my-service"),
+	)
+	require.NoError(t, err)
+
+	logger.With(slog.String("component", "db")).Warn("disk almost full")
+	msg := read()
+
+	// user facility (1) * 8 + warning (4)
+	assert.True(t, strings.HasPrefix(msg, "<12>1 "), msg)
+	assert.Contains(t, msg, " my-service ")
+	assert.Contains(t, msg, `"msg":"disk almost full"`)
+	assert.Contains(t, msg, `"component":"db"`)
+	assert.False(t, strings.HasSuffix(msg, "\n"))
+
+	logger.Error("failed", log.ErrAttr(errors.New("boom")))
+	msg = read()
+	assert.True(t, strings.HasPrefix(msg, "<11>1 "), msg)
+}
+
+func TestJournalTarget(t *testing.T) {
+	t.Parallel()
+
+	addr, read := listen(t)
+	logger, err := log.NewLogger(
+		log.WithTarget(log.TargetJournal),
+		log.WithTargetAddress("unixgram", addr),
+		log.WithServiceName("my-service"),
+		log.WithLogStyle(log.LogStyleText),
+	)
+	require.NoError(t, err)
+
+	logger.Info("started")
+	msg := read()
+	assert.Contains(t, msg, "PRIORITY=6\n")
+	assert.Contains(t, msg, "SYSLOG_IDENTIFIER=my-service\n")
+	assert.Contains(t, msg, "MESSAGE=")
+	assert.Contains(t, msg, "msg=started")
+
+	// newlines are escaped, so the message remains a single field
+	logger.Error("multi\nline")
+	msg = read()
+	assert.Contains(t, msg, "PRIORITY=3\n")
+	assert.Equal(t, 3, strings.Count(msg, "\n"))
+}
+
+func TestTargetUnavailable(t *testing.T) {
+	t.Parallel()
+
+	_, err := log.NewLogger(
+		log.WithTarget(log.TargetSyslog),
+		log.WithTargetAddress("unixgram", filepath.Join(t.TempDir(), "missing")),
+	)
+	assert.Error(t, err)
+}
@@ -0,0 +1,93 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+const (
+	// TaskKey is the attribute key used when adding the task name to logs.
+	TaskKey = "task"
+	// TaskRunKey is the attribute key used when adding the task run ID to logs.
+	TaskRunKey = "task_run"
+)
+
+// taskRuns provides process-unique run IDs.
+var taskRuns atomic.Uint64
+
+// TaskInfo identifies a single run of a task.
+type TaskInfo struct {
+	Name string
+	// Run is incremented each time any task is started, so that repeated
+	// or concurrent runs of the same task can be told apart.
+	Run uint64
+}
+
+// Attrs returns the log attributes for the task.
+func (t TaskInfo) Attrs() []slog.Attr {
+	return []slog.Attr{
+		slog.String(TaskKey, t.Name),
+		slog.Uint64(TaskRunKey, t.Run),
+	}
+}
+
+type taskContextKey struct{}
+
+// ContextWithTask returns a context carrying the task name and a new run ID.
+// Records logged with this context (eg logger.InfoContext) by loggers from NewLogger include them.
+func ContextWithTask(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, taskContextKey{}, TaskInfo{Name: name, Run: taskRuns.Add(1)})
+}
+
+// TaskFromContext returns the task carried by ctx, if any.
+func TaskFromContext(ctx context.Context) (TaskInfo, bool) {
+	t, ok := ctx.Value(taskContextKey{}).(TaskInfo)
+	return t, ok
+}
+
+// TaskLogger returns the logger with the attributes of the task carried by ctx, if any.
+// Use this for code which logs without passing the context.
+func TaskLogger(ctx context.Context, logger *slog.Logger) *slog.Logger {
+	t, ok := TaskFromContext(ctx)
+	if !ok {
+		return logger
+	}
+	return slog.New(logger.Handler().WithAttrs(t.Attrs()))
+}
+
+// taskHandler adds the task from the context to each log record.
+type taskHandler struct {
+	next slog.Handler
+}
+
+// NewTaskHandler wraps a slog.Handler such that records logged with a context
+// carrying a task (see ContextWithTask) include its name and run ID as attributes.
+// Loggers from NewLogger already include this handler.
+func NewTaskHandler(next slog.Handler) slog.Handler {
+	return &taskHandler{next: next}
+}
+
+// Enabled implements slog.Handler.
+func (h *taskHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *taskHandler) Handle(ctx context.Context, record slog.Record) error {
+	if t, ok := TaskFromContext(ctx); ok {
+		record = record.Clone()
+		record.AddAttrs(t.Attrs()...)
+	}
+	return h.next.Handle(ctx, record)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *taskHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &taskHandler{next: h.next.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler.
+func (h *taskHandler) WithGroup(name string) slog.Handler {
+	return &taskHandler{next: h.next.WithGroup(name)}
+}
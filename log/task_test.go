@@ -0,0 +1,56 @@
+package log_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/log"
+)
+
+func TestContextWithTask(t *testing.T) {
+	t.Parallel()
+
+	_, ok := log.TaskFromContext(t.Context())
+	assert.False(t, ok)
+
+	first, ok := log.TaskFromContext(log.ContextWithTask(t.Context(), "consumer"))
+	require.True(t, ok)
+	assert.Equal(t, "consumer", first.Name)
+
+	// each run gets a new ID, even for the same task
+	second, ok := log.TaskFromContext(log.ContextWithTask(t.Context(), "consumer"))
+	require.True(t, ok)
+	assert.Greater(t, second.Run, first.Run)
+}
+
+func TestTaskHandler(t *testing.T) {
+	t.Parallel()
+
+	logger, buf := newTestLogger(t)
+	ctx := log.ContextWithTask(context.Background(), "consumer")
+	info, _ := log.TaskFromContext(ctx)
+
+	logger.InfoContext(ctx, "with context")
+	logger.Info("without context")
+	log.TaskLogger(ctx, logger).Info("task logger")
+	log.TaskLogger(context.Background(), logger).Info("task logger without task")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+	expected := []bool{true, false, true, false}
+	for i, line := range lines {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		if !expected[i] {
+			assert.NotContains(t, record, log.TaskKey, line)
+			continue
+		}
+		assert.Equal(t, "consumer", record[log.TaskKey], line)
+		assert.InDelta(t, float64(info.Run), record[log.TaskRunKey], 0, line)
+	}
+}
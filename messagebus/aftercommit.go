@@ -0,0 +1,156 @@
+package messagebus
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/zircuit-labs/zkr-go-common/http/requestid"
+	"github.com/zircuit-labs/zkr-go-common/stores/txhook"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+var (
+	ErrNoOutbox     = errors.New("strict atomicity requires an outbox")
+	ErrAlreadyEnded = errors.New("deferred publishes have already been committed or rolled back")
+)
+
+// Outbox stores messages as part of the current database transaction.
+// A separate relay is then responsible for publishing them, which guarantees
+// that a message is published if and only if the transaction commits.
+// The relay must publish the header along with the payload, since it carries eg the content type,
+// compression, message ID, request ID and trace context.
+type Outbox interface {
+	Store(ctx context.Context, subject string, header nats.Header, payload []byte) error
+}
+
+// DeferredPublishes collects Produce calls made during a database transaction
+// so that they are only executed once the transaction has successfully committed.
+// Transaction managers should call Commit after a successful commit and Rollback otherwise,
+// which those registering txhook.Hooks, such as pg.TxManager, do themselves (see ContextWithDeferredPublishes).
+type DeferredPublishes struct {
+	mu      sync.Mutex
+	pending []func(ctx context.Context) error
+	outbox  Outbox
+	ended   bool
+}
+
+// NewDeferredPublishes creates a DeferredPublishes.
+// The outbox is optional, and is only needed for ProduceAtomic.
+func NewDeferredPublishes(outbox Outbox) *DeferredPublishes {
+	return &DeferredPublishes{outbox: outbox}
+}
+
+type deferredPublishesKey struct{}
+
+// ContextWithDeferredPublishes returns a context carrying d, such that
+// ProduceAfterCommit and ProduceAtomic calls using this context are deferred.
+// If ctx carries a transaction with txhook.Hooks, such as one begun by pg.TxManager.RunInTx,
+// d is committed once it commits, and rolled back along with it otherwise.
+func ContextWithDeferredPublishes(ctx context.Context, d *DeferredPublishes) context.Context {
+	if txhook.AfterCommit(ctx, d.Commit) {
+		txhook.AfterRollback(ctx, d.Rollback)
+	}
+	return context.WithValue(ctx, deferredPublishesKey{}, d)
+}
+
+// DeferredPublishesFromContext returns the DeferredPublishes carried by ctx, if any.
+func DeferredPublishesFromContext(ctx context.Context) (*DeferredPublishes, bool) {
+	d, ok := ctx.Value(deferredPublishesKey{}).(*DeferredPublishes)
+	return d, ok
+}
+
+// ProduceAfterCommit produces the data once the transaction associated with ctx commits,
+// being that of any DeferredPublishes carried by ctx, or otherwise any transaction with txhook.Hooks
+// such as one begun by pg.TxManager.RunInTx (in which case a failure to produce is logged by the TxManager).
+// If there is no such transaction, the data is produced immediately.
+// NOTE: If the process stops between commit and publish the message is lost.
+// Use ProduceAtomic when that is not acceptable.
+func ProduceAfterCommit[T any](ctx context.Context, p Producer[T], data T) error {
+	id, hasID := requestid.FromContext(ctx)
+	replay, isReplay := ReplayFromContext(ctx)
+	produce := func(ctx context.Context) error {
+		// Commit is typically called with a different context, so retain the request ID and any replay
+		if hasID {
+			ctx = requestid.NewContext(ctx, id)
+		}
+		if isReplay {
+			ctx = NewReplayContext(ctx, replay)
+		}
+		return p.Produce(ctx, data)
+	}
+
+	if d, ok := DeferredPublishesFromContext(ctx); ok {
+		return d.add(produce)
+	}
+	if txhook.AfterCommit(ctx, produce) {
+		return nil
+	}
+	return p.Produce(ctx, data)
+}
+
+// ProduceAtomic stores the message for the data, with the same headers as Produce would send,
+// in the outbox as part of the transaction associated with ctx, leaving the outbox relay to
+// publish it after commit.
+// It is an error to call this without a transaction that has an outbox.
+func ProduceAtomic[T any](ctx context.Context, p *NatsStreamProducer[T], data T) error {
+	d, ok := DeferredPublishesFromContext(ctx)
+	if !ok || d.outbox == nil {
+		return errclass.WrapAs(stacktrace.Wrap(ErrNoOutbox), errclass.Persistent)
+	}
+
+	msg, err := p.newMsg(ctx, data)
+	if err != nil {
+		return err
+	}
+	if err := d.outbox.Store(ctx, msg.Subject, msg.Header, msg.Data); err != nil {
+		return stacktrace.Wrap(err)
+	}
+	return nil
+}
+
+func (d *DeferredPublishes) add(f func(ctx context.Context) error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.ended {
+		return errclass.WrapAs(stacktrace.Wrap(ErrAlreadyEnded), errclass.Persistent)
+	}
+	d.pending = append(d.pending, f)
+	return nil
+}
+
+// Len returns the number of publishes waiting for commit.
+func (d *DeferredPublishes) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.pending)
+}
+
+// Commit executes all deferred publishes in the order they were registered.
+// All publishes are attempted, and any errors are joined.
+func (d *DeferredPublishes) Commit(ctx context.Context) error {
+	d.mu.Lock()
+	pending := d.pending
+	d.pending = nil
+	d.ended = true
+	d.mu.Unlock()
+
+	var errs []error
+	for _, f := range pending {
+		if err := f(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Rollback discards all deferred publishes.
+func (d *DeferredPublishes) Rollback() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pending = nil
+	d.ended = true
+}
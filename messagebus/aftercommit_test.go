@@ -0,0 +1,191 @@
+package messagebus_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/http/requestid"
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+	"github.com/zircuit-labs/zkr-go-common/stores/pg"
+)
+
+var errRolledBack = errors.New("rolled back")
+
+type outboxEntry struct {
+	subject string
+	header  nats.Header
+	payload []byte
+}
+
+type testOutbox struct {
+	entries []outboxEntry
+}
+
+func (o *testOutbox) Store(_ context.Context, subject string, header nats.Header, payload []byte) error {
+	o.entries = append(o.entries, outboxEntry{subject: subject, header: header, payload: payload})
+	return nil
+}
+
+func TestProduceAfterCommit(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+
+	cfg, err := config.NewConfigurationFromMap(
+		map[string]any{
+			"subject": "grault",
+			"stream":  "GRAULT",
+		},
+	)
+	require.NoError(t, err)
+
+	producer, err := messagebus.NewNatsStreamProducer[sampleMessage](cfg, "", messagebus.WithNATSConnection(nc))
+	require.NoError(t, err)
+	t.Cleanup(producer.Close)
+
+	// publishes during a rolled back transaction are discarded
+	rolledBack := messagebus.NewDeferredPublishes(nil)
+	ctx := messagebus.ContextWithDeferredPublishes(t.Context(), rolledBack)
+	require.NoError(t, messagebus.ProduceAfterCommit(ctx, producer, sampleMessages[0]))
+	assert.Equal(t, 1, rolledBack.Len())
+	rolledBack.Rollback()
+	assert.Equal(t, 0, rolledBack.Len())
+	assert.ErrorIs(t, messagebus.ProduceAfterCommit(ctx, producer, sampleMessages[0]), messagebus.ErrAlreadyEnded)
+
+	_, _, err = messagebus.GetLastMessage[sampleMessage](cfg, "", messagebus.WithNATSConnection(nc))
+	assert.ErrorIs(t, err, messagebus.ErrNoMessages)
+
+	// publishes are only executed on commit
+	committed := messagebus.NewDeferredPublishes(nil)
+	ctx = messagebus.ContextWithDeferredPublishes(t.Context(), committed)
+	for _, m := range sampleMessages {
+		require.NoError(t, messagebus.ProduceAfterCommit(ctx, producer, m))
+	}
+
+	_, _, err = messagebus.GetLastMessage[sampleMessage](cfg, "", messagebus.WithNATSConnection(nc))
+	assert.ErrorIs(t, err, messagebus.ErrNoMessages)
+
+	require.NoError(t, committed.Commit(t.Context()))
+	lastMessage, _, err := messagebus.GetLastMessage[sampleMessage](cfg, "", messagebus.WithNATSConnection(nc))
+	require.NoError(t, err)
+	assert.Equal(t, sampleMessages[1], lastMessage)
+
+	// without a transaction, the data is produced immediately
+	require.NoError(t, messagebus.ProduceAfterCommit(t.Context(), producer, sampleMessages[0]))
+	lastMessage, _, err = messagebus.GetLastMessage[sampleMessage](cfg, "", messagebus.WithNATSConnection(nc))
+	require.NoError(t, err)
+	assert.Equal(t, sampleMessages[0], lastMessage)
+}
+
+// recordingProducer records the data it produces, and the request ID it was produced with.
+type recordingProducer struct {
+	produced   []string
+	requestIDs []string
+}
+
+func (p *recordingProducer) Produce(ctx context.Context, data string) error {
+	id, _ := requestid.FromContext(ctx)
+	p.produced = append(p.produced, data)
+	p.requestIDs = append(p.requestIDs, id)
+	return nil
+}
+
+func TestProduceAfterCommitTxManager(t *testing.T) {
+	t.Parallel()
+
+	sqldb, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqldb.Close() })
+	tm, err := pg.NewTxManager(bun.NewDB(sqldb, pgdialect.New()))
+	require.NoError(t, err)
+
+	// publishes within a rolled back transaction are discarded
+	producer := &recordingProducer{}
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	err = tm.RunInTx(t.Context(), func(ctx context.Context) error {
+		require.NoError(t, messagebus.ProduceAfterCommit(ctx, producer, "rolled back"))
+		return errRolledBack
+	})
+	require.ErrorIs(t, err, errRolledBack)
+	assert.Empty(t, producer.produced)
+
+	// as are those of a DeferredPublishes, which then refuses further publishes
+	var deferredCtx context.Context
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	err = tm.RunInTx(t.Context(), func(ctx context.Context) error {
+		deferredCtx = messagebus.ContextWithDeferredPublishes(ctx, messagebus.NewDeferredPublishes(nil))
+		require.NoError(t, messagebus.ProduceAfterCommit(deferredCtx, producer, "rolled back"))
+		return errRolledBack
+	})
+	require.ErrorIs(t, err, errRolledBack)
+	assert.ErrorIs(t, messagebus.ProduceAfterCommit(deferredCtx, producer, "too late"), messagebus.ErrAlreadyEnded)
+	assert.Empty(t, producer.produced)
+
+	// and otherwise executed once the transaction commits, including those of a DeferredPublishes
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	err = tm.RunInTx(t.Context(), func(ctx context.Context) error {
+		ctx = requestid.NewContext(ctx, "request-1")
+		require.NoError(t, messagebus.ProduceAfterCommit(ctx, producer, "first"))
+		deferred := messagebus.NewDeferredPublishes(nil)
+		require.NoError(t, messagebus.ProduceAfterCommit(messagebus.ContextWithDeferredPublishes(ctx, deferred), producer, "second"))
+		assert.Empty(t, producer.produced)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, producer.produced)
+	assert.Equal(t, []string{"request-1", "request-1"}, producer.requestIDs)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProduceAtomic(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+
+	cfg, err := config.NewConfigurationFromMap(
+		map[string]any{
+			"subject": "grault",
+		},
+	)
+	require.NoError(t, err)
+
+	producer, err := messagebus.NewNatsStreamProducer[sampleMessage](cfg, "", messagebus.WithNATSConnection(nc))
+	require.NoError(t, err)
+	t.Cleanup(producer.Close)
+
+	// an outbox is required
+	err = messagebus.ProduceAtomic(t.Context(), producer, sampleMessages[0])
+	assert.ErrorIs(t, err, messagebus.ErrNoOutbox)
+	ctx := messagebus.ContextWithDeferredPublishes(t.Context(), messagebus.NewDeferredPublishes(nil))
+	err = messagebus.ProduceAtomic(ctx, producer, sampleMessages[0])
+	assert.ErrorIs(t, err, messagebus.ErrNoOutbox)
+
+	// messages are stored in the outbox rather than published, along with their headers
+	producer.SetMessageID(func(m sampleMessage) string { return m.Message })
+	outbox := &testOutbox{}
+	ctx = messagebus.ContextWithDeferredPublishes(t.Context(), messagebus.NewDeferredPublishes(outbox))
+	ctx = requestid.NewContext(ctx, "request-1")
+	require.NoError(t, messagebus.ProduceAtomic(ctx, producer, sampleMessages[0]))
+	require.Len(t, outbox.entries, 1)
+	assert.Equal(t, "grault", outbox.entries[0].subject)
+	assert.Equal(t, messagebus.ContentTypeJSON, outbox.entries[0].header.Get(messagebus.ContentTypeHeader))
+	assert.Equal(t, sampleMessages[0].Message, outbox.entries[0].header.Get(jetstream.MsgIDHeader))
+	assert.Equal(t, "request-1", outbox.entries[0].header.Get(requestid.Header))
+
+	var stored sampleMessage
+	require.NoError(t, json.Unmarshal(outbox.entries[0].payload, &stored))
+	assert.Equal(t, sampleMessages[0], stored)
+}
@@ -0,0 +1,149 @@
+package messagebus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/zircuit-labs/zkr-go-common/calm/errgroup"
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+var ErrNoSources = errors.New("no sources supplied")
+
+// Adapter normalizes a message of type T from a source into an event of type E.
+type Adapter[T, E any] func(ctx context.Context, data T, subject string) (E, error)
+
+// Source is a stream consumed by an AggregatingConsumer, whose messages are adapted to events of type E.
+// Create it with NewSource.
+type Source[E any] struct {
+	cfgPath     string
+	opts        []Option
+	newConsumer func(cfg *config.Configuration, handler ConsumerHandler[E], opts []Option) (aggregatedConsumer, error)
+}
+
+// aggregatedConsumer is a consumer of a single source, regardless of its message type.
+type aggregatedConsumer interface {
+	Run(ctx context.Context) error
+	Name() string
+}
+
+// NewSource creates a Source for the stream consumer configured at cfgPath (as for NewNatsStreamConsumer),
+// whose messages of type T are converted to events by adapt. The options apply to this source only,
+// after any given to NewAggregatingConsumer (eg its codec, or WithConsumerSubjectTransform).
+func NewSource[T, E any](cfgPath string, adapt Adapter[T, E], opts ...Option) Source[E] {
+	return Source[E]{
+		cfgPath: cfgPath,
+		opts:    opts,
+		newConsumer: func(cfg *config.Configuration, handler ConsumerHandler[E], opts []Option) (aggregatedConsumer, error) {
+			adapted := ConsumerHandlerFunc[T](func(ctx context.Context, data T, subject string, metadata jetstream.MsgMetadata) error {
+				event, err := adapt(ctx, data, subject)
+				if err != nil {
+					return err
+				}
+				return handler.HandleMessage(ctx, event, subject, metadata)
+			})
+			return NewNatsStreamConsumer[T](cfg, cfgPath, adapted, opts...)
+		},
+	}
+}
+
+// AggregatingConsumer is a Task which merges several streams, with different message types,
+// into events of a single type handled by one ConsumerHandler.
+// Each source is consumed by its own NatsStreamConsumer, so messages from the same source are handled
+// in order (unless WithMaxConcurrency is used for that source), while messages from different sources
+// are handled concurrently. The handler must therefore be safe for concurrent use; metadata.Stream identifies the source.
+type AggregatingConsumer[E any] struct {
+	nc            *nats.Conn
+	shouldCloseNC bool
+	consumers     []aggregatedConsumer
+}
+
+// NewAggregatingConsumer creates an AggregatingConsumer of the sources, which share a single NATS connection.
+// The options apply to every source.
+func NewAggregatingConsumer[E any](cfg *config.Configuration, handler ConsumerHandler[E], sources []Source[E], opts ...Option) (*AggregatingConsumer[E], error) {
+	if len(sources) == 0 {
+		return nil, errclass.WrapAs(stacktrace.Wrap(ErrNoSources), errclass.Persistent)
+	}
+
+	options := parseOptions(opts)
+	a := &AggregatingConsumer[E]{}
+	if options.nc != nil && options.js != nil {
+		// Use provided NATS connection
+		a.nc = options.nc
+	} else {
+		// Set up NATS connection from config
+		nc, js, err := NewJetStreamConnection(cfg, opts...)
+		if err != nil {
+			return nil, stacktrace.Wrap(err)
+		}
+		a.shouldCloseNC = true
+		a.nc = nc
+		opts = append(opts, withJetStream(nc, js))
+	}
+
+	for _, source := range sources {
+		sourceOpts := append(append([]Option{}, opts...), source.opts...)
+		consumer, err := source.newConsumer(cfg, handler, sourceOpts)
+		if err != nil {
+			if a.shouldCloseNC {
+				a.nc.Close()
+			}
+			return nil, errcontext.Add(err, slog.String("source", source.cfgPath))
+		}
+		a.consumers = append(a.consumers, consumer)
+	}
+
+	return a, nil
+}
+
+// withJetStream sets the connection, without creating another JetStream context as WithNATSConnection does.
+func withJetStream(nc *nats.Conn, js jetstream.JetStream) Option {
+	return func(options *options) {
+		options.nc = nc
+		options.js = js
+	}
+}
+
+// HealthCheck returns an error if the NATS connection is not "connected".
+func (a *AggregatingConsumer[E]) HealthCheck(ctx context.Context) error {
+	if a.nc.Status() != nats.CONNECTED {
+		return stacktrace.Wrap(ErrNATSNotConnected)
+	}
+
+	return nil
+}
+
+// Name returns the name of this task
+func (a *AggregatingConsumer[E]) Name() string {
+	names := make([]string, 0, len(a.consumers))
+	for _, consumer := range a.consumers {
+		names = append(names, consumer.Name())
+	}
+	return fmt.Sprintf("aggregating-consumer (%s)", strings.Join(names, ", "))
+}
+
+// Run consumes every source until the context is done, or any of them fails, which stops the others.
+func (a *AggregatingConsumer[E]) Run(ctx context.Context) error {
+	// Only close the nats connection if it was one we made.
+	// Otherwise the responsibility for this lies with its creator.
+	if a.shouldCloseNC {
+		defer a.nc.Close()
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	for _, consumer := range a.consumers {
+		g.Go(func() error {
+			return consumer.Run(ctx)
+		})
+	}
+	return g.Wait()
+}
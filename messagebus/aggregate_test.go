@@ -0,0 +1,139 @@
+package messagebus_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+)
+
+type l1Block struct {
+	Number uint64
+}
+
+type l2Batch struct {
+	Index  int
+	Blocks []uint64
+}
+
+type chainEvent struct {
+	Layer  string
+	Number uint64
+}
+
+func TestAggregatingConsumer(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+	js := getJetStream(t, nc)
+
+	id := xid.New().String()
+	cfg, err := config.NewConfigurationFromMap(map[string]any{
+		"l1": map[string]any{"stream": "L1_" + id, "subject": "l1." + id, "durablequeue": "l1-agg"},
+		"l2": map[string]any{"stream": "L2_" + id, "subject": "l2." + id, "durablequeue": "l2-agg"},
+	})
+	require.NoError(t, err)
+	for _, layer := range []string{"L1", "L2"} {
+		_, err := messagebus.CreateOrUpdateStream(t.Context(), js, cfg, jetstream.StreamConfig{
+			Name:     layer + "_" + id,
+			Subjects: []string{strings.ToLower(layer) + "." + id},
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_ = js.DeleteStream(context.Background(), layer+"_"+id)
+		})
+	}
+
+	// publish heterogeneous messages to each stream
+	const count = 20
+	l1, err := messagebus.NewNatsStreamProducer[l1Block](cfg, "l1", messagebus.WithNATSConnection(nc))
+	require.NoError(t, err)
+	t.Cleanup(l1.Close)
+	l2, err := messagebus.NewNatsStreamProducer[l2Batch](cfg, "l2", messagebus.WithNATSConnection(nc))
+	require.NoError(t, err)
+	t.Cleanup(l2.Close)
+	for i := range count {
+		require.NoError(t, l1.Produce(t.Context(), l1Block{Number: uint64(i)}))
+		require.NoError(t, l2.Produce(t.Context(), l2Batch{Index: i, Blocks: []uint64{uint64(i * 10)}}))
+	}
+
+	// each source is normalized into the same event type
+	sources := []messagebus.Source[chainEvent]{
+		messagebus.NewSource("l1", func(_ context.Context, data l1Block, _ string) (chainEvent, error) {
+			return chainEvent{Layer: "l1", Number: data.Number}, nil
+		}),
+		messagebus.NewSource("l2", func(_ context.Context, data l2Batch, _ string) (chainEvent, error) {
+			if len(data.Blocks) == 0 {
+				return chainEvent{}, fmt.Errorf("batch %d has no blocks", data.Index)
+			}
+			return chainEvent{Layer: "l2", Number: data.Blocks[0]}, nil
+		}),
+	}
+
+	mu := sync.Mutex{}
+	received := map[string][]uint64{}
+	handler := messagebus.ConsumerHandlerFunc[chainEvent](func(_ context.Context, event chainEvent, _ string, _ jetstream.MsgMetadata) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received[event.Layer] = append(received[event.Layer], event.Number)
+		return nil
+	})
+	consumer, err := messagebus.NewAggregatingConsumer(cfg, handler, sources, messagebus.WithNATSConnection(nc))
+	require.NoError(t, err)
+	assert.Contains(t, consumer.Name(), "l1-agg")
+	assert.Contains(t, consumer.Name(), "l2-agg")
+	require.NoError(t, consumer.HealthCheck(t.Context()))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+	go func() {
+		done <- consumer.Run(ctx)
+	}()
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received["l1"]) == count && len(received["l2"]) == count
+	}, time.Second*10, time.Millisecond*10)
+	cancel()
+	require.NoError(t, <-done)
+
+	// the order of each source is preserved
+	for i := range count {
+		assert.Equal(t, uint64(i), received["l1"][i])
+		assert.Equal(t, uint64(i*10), received["l2"][i])
+	}
+}
+
+func TestAggregatingConsumerErrors(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+
+	cfg, err := config.NewConfigurationFromMap(map[string]any{
+		"missing": map[string]any{"stream": "MISSING_" + xid.New().String(), "subject": "missing"},
+	})
+	require.NoError(t, err)
+	handler := messagebus.ConsumerHandlerFunc[chainEvent](func(context.Context, chainEvent, string, jetstream.MsgMetadata) error {
+		return nil
+	})
+
+	_, err = messagebus.NewAggregatingConsumer(cfg, handler, nil, messagebus.WithNATSConnection(nc))
+	require.ErrorIs(t, err, messagebus.ErrNoSources)
+
+	// the stream of the source does not exist
+	sources := []messagebus.Source[chainEvent]{
+		messagebus.NewSource("missing", func(_ context.Context, data l1Block, _ string) (chainEvent, error) {
+			return chainEvent{Number: data.Number}, nil
+		}),
+	}
+	_, err = messagebus.NewAggregatingConsumer(cfg, handler, sources, messagebus.WithNATSConnection(nc))
+	require.ErrorIs(t, err, jetstream.ErrStreamNotFound)
+}
@@ -0,0 +1,251 @@
+package messagebus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/zircuit-labs/zkr-go-common/calm"
+	"github.com/zircuit-labs/zkr-go-common/calm/errgroup"
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/log"
+)
+
+const (
+	defaultBatchSize    = 100
+	defaultBatchTimeout = time.Second
+)
+
+// Envelope is a single message within a batch.
+type Envelope[T any] struct {
+	Data     T
+	Subject  string
+	Metadata jetstream.MsgMetadata
+	// Replay is the provenance of the message if it was replayed, or nil for fresh events.
+	Replay *Replay
+}
+
+// BatchConsumerHandler handles incoming messages in batches.
+//
+// Returning nil acks every message in the batch. Returning a *BatchError (possibly wrapped)
+// handles each failed message according to the class of its own error, and acks the others.
+// Any other error applies to every message in the batch.
+type BatchConsumerHandler[T any] interface {
+	HandleBatch(ctx context.Context, batch []Envelope[T]) error
+}
+
+// BatchConsumerHandlerFunc allows a plain function to be used as a BatchConsumerHandler.
+type BatchConsumerHandlerFunc[T any] func(ctx context.Context, batch []Envelope[T]) error
+
+// HandleBatch implements BatchConsumerHandler.
+func (f BatchConsumerHandlerFunc[T]) HandleBatch(ctx context.Context, batch []Envelope[T]) error {
+	return f(ctx, batch)
+}
+
+// BatchError reports the failure of individual messages within a batch.
+type BatchError struct {
+	// Failed maps the index of each failed message within the batch to its error.
+	Failed map[int]error
+}
+
+// Error implements the error interface.
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d messages of batch failed: %v", len(e.Failed), errors.Join(e.errors()...))
+}
+
+// Unwrap returns the errors of the failed messages.
+func (e *BatchError) Unwrap() []error {
+	return e.errors()
+}
+
+func (e *BatchError) errors() []error {
+	errs := make([]error, 0, len(e.Failed))
+	for _, err := range e.Failed {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// WithBatchSize sets the maximum number of messages passed to a BatchConsumerHandler at once. Defaults to 100.
+// NOTE: The consumer's MaxAckPending must be at least the batch size for full batches to be collected.
+func WithBatchSize(n int) Option {
+	return func(options *options) {
+		options.batchSize = n
+	}
+}
+
+// WithBatchTimeout sets the maximum time to wait for a batch to fill, from when its first message is received.
+// Defaults to 1 second.
+func WithBatchTimeout(d time.Duration) Option {
+	return func(options *options) {
+		options.batchTimeout = d
+	}
+}
+
+// NewNatsStreamBatchConsumer creates a new NatsStreamConsumer which passes messages to the handler in batches
+// of up to WithBatchSize messages, waiting at most WithBatchTimeout for each batch to fill.
+// Batches are handled one at a time, so WithMaxConcurrency and WithFairScheduling do not apply,
+// and neither do consumer middleware nor deduplication.
+func NewNatsStreamBatchConsumer[T any](cfg *config.Configuration, cfgPath string, handler BatchConsumerHandler[T], opts ...Option) (*NatsStreamConsumer[T], error) {
+	// The single message handler is never called once a batch handler is set
+	consumer, err := NewNatsStreamConsumer[T](cfg, cfgPath, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	consumer.batchHandler = handler
+	return consumer, nil
+}
+
+type batchItem struct {
+	msg          jetstream.Msg
+	stopProgress context.CancelFunc
+}
+
+// startBatching starts collecting messages into batches which are passed to the batch handler.
+// It returns a func to enqueue messages, and a func to stop which naks any messages
+// not yet handled so that they are redelivered promptly.
+func (n *NatsStreamConsumer[T]) startBatching(ctx context.Context) (func(jetstream.Msg), func()) {
+	size := max(n.opts.batchSize, 1)
+	items := make(chan batchItem, size)
+	done := make(chan struct{})
+
+	g := errgroup.New()
+	g.Go(func() error {
+		for {
+			batch, ok := n.collectBatch(items, done, size)
+			if !ok {
+				nakAll(batch)
+				return nil
+			}
+			n.handleBatch(ctx, batch)
+		}
+	})
+
+	enqueue := func(msg jetstream.Msg) {
+		// Messages waiting for their batch must also be kept in progress, or NATS will redeliver them
+		progressCtx, cancel := context.WithCancel(ctx)
+		progressAcker := newInProgressAcker(msg, n.opts.inProgressInterval, false)
+		go func() {
+			_ = progressAcker.Run(progressCtx)
+		}()
+		select {
+		case items <- batchItem{msg: msg, stopProgress: cancel}:
+		case <-done:
+			nakAll([]batchItem{{msg: msg, stopProgress: cancel}})
+		}
+	}
+
+	stop := func() {
+		close(done)
+		_ = g.Wait()
+		for {
+			select {
+			case item := <-items:
+				nakAll([]batchItem{item})
+			default:
+				return
+			}
+		}
+	}
+
+	return enqueue, stop
+}
+
+// collectBatch waits for a first message, and then for the batch to fill or time out.
+// It returns false if stopped meanwhile, along with any messages already collected.
+func (n *NatsStreamConsumer[T]) collectBatch(items <-chan batchItem, done <-chan struct{}, size int) ([]batchItem, bool) {
+	var batch []batchItem
+	select {
+	case item := <-items:
+		batch = append(batch, item)
+	case <-done:
+		return nil, false
+	}
+
+	timer := time.NewTimer(n.opts.batchTimeout)
+	defer timer.Stop()
+	for len(batch) < size {
+		select {
+		case item := <-items:
+			batch = append(batch, item)
+		case <-timer.C:
+			return batch, true
+		case <-done:
+			return batch, false
+		}
+	}
+	return batch, true
+}
+
+func nakAll(items []batchItem) {
+	for _, item := range items {
+		item.stopProgress()
+		_ = item.msg.Nak()
+	}
+}
+
+// handleBatch passes the batch to the batch handler, then acks or naks each message
+// according to the class of its error.
+func (n *NatsStreamConsumer[T]) handleBatch(ctx context.Context, items []batchItem) {
+	batch := make([]Envelope[T], 0, len(items))
+	handled := make([]batchItem, 0, len(items))
+	loggers := make([]*slog.Logger, 0, len(items))
+	for _, item := range items {
+		meta, err := item.msg.Metadata()
+		if err != nil || meta == nil {
+			// This should never happen, but if it does we should log an error and retry the message later
+			n.opts.logger.Error("failed to fetch message metadata", log.ErrAttr(err), slog.String("task", n.Name()), slog.String("subject", item.msg.Subject()))
+			item.stopProgress()
+			_ = item.msg.NakWithDelay(baseNakDelay)
+			continue
+		}
+		logger := n.opts.logger.With(
+			slog.String("task", n.Name()),
+			slog.String("subject", item.msg.Subject()),
+			slog.Uint64("sequence_number", meta.Sequence.Stream),
+			slog.Uint64("delivery_attempt", meta.NumDelivered),
+		)
+		n.metrics.received(meta.NumDelivered)
+
+		var data T
+		if err := n.opts.unmarshal(item.msg.Headers(), item.msg.Data(), &data); err != nil {
+			// If we can't unmarshal the data, it's useless to us.
+			// Log a warning, and consider it otherwise handled.
+			n.metrics.unmarshalFailed()
+			logger.Error("failed to unmarshal data - skipping", log.ErrAttr(err),
+				slog.String("comment", "This should never happen, and a human needs to investigate how and why it did."))
+			item.stopProgress()
+			continue
+		}
+
+		env := Envelope[T]{Data: data, Subject: n.ns.trim(item.msg.Subject()), Metadata: *meta}
+		if r, ok := ReplayFromHeader(item.msg.Headers()); ok {
+			env.Replay = &r
+		}
+		batch = append(batch, env)
+		handled = append(handled, item)
+		loggers = append(loggers, logger)
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	err := calm.Unpanic(func() error {
+		return n.batchHandler.HandleBatch(ctx, batch)
+	})
+
+	var batchErr *BatchError
+	perMessage := errors.As(err, &batchErr)
+	for i, item := range handled {
+		item.stopProgress()
+		msgErr := err
+		if perMessage {
+			msgErr = batchErr.Failed[i]
+		}
+		n.settle(ctx, item.msg, &batch[i].Metadata, msgErr, loggers[i])
+	}
+}
@@ -0,0 +1,127 @@
+package messagebus_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+	"github.com/zircuit-labs/zkr-go-common/retry"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+)
+
+func TestProduceBatchAndAsync(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+	js := getJetStream(t, nc)
+
+	cfg, err := config.NewConfigurationFromMap(
+		map[string]any{
+			"subject": "wobble",
+			"stream":  "WOBBLE",
+		},
+	)
+	require.NoError(t, err)
+
+	producer, err := messagebus.NewNatsStreamProducer[sampleMessage](cfg, "", messagebus.WithNATSConnection(nc))
+	require.NoError(t, err)
+	t.Cleanup(producer.Close)
+
+	streamMsgs := func() uint64 {
+		stream, err := js.Stream(t.Context(), "WOBBLE")
+		require.NoError(t, err)
+		info, err := stream.Info(t.Context())
+		require.NoError(t, err)
+		return info.State.Msgs
+	}
+
+	// batches are acked before returning
+	require.NoError(t, producer.ProduceBatch(t.Context(), sampleMessages))
+	assert.Equal(t, uint64(len(sampleMessages)), streamMsgs())
+
+	// async publishes are tracked until flushed
+	for _, m := range sampleMessages {
+		require.NoError(t, producer.ProduceAsync(t.Context(), m))
+	}
+	assert.Equal(t, len(sampleMessages), producer.PendingAsync())
+	require.NoError(t, producer.Flush(t.Context()))
+	assert.Equal(t, 0, producer.PendingAsync())
+	assert.Equal(t, uint64(2*len(sampleMessages)), streamMsgs())
+
+	// nothing to flush
+	require.NoError(t, producer.Flush(t.Context()))
+}
+
+func TestProduceBatchFailure(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+
+	cfg, err := config.NewConfigurationFromMap(
+		map[string]any{
+			"subject": "no.such.stream",
+			"stream":  "WOBBLE",
+		},
+	)
+	require.NoError(t, err)
+
+	retrier, err := retry.NewRetrier(retry.WithMaxAttempts(1))
+	require.NoError(t, err)
+	producer, err := messagebus.NewNatsStreamProducer[sampleMessage](cfg, "",
+		messagebus.WithNATSConnection(nc),
+		messagebus.WithRetrier(retrier),
+	)
+	require.NoError(t, err)
+	t.Cleanup(producer.Close)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	t.Cleanup(cancel)
+
+	// acks for subjects without a stream fail
+	require.NoError(t, producer.ProduceAsync(ctx, sampleMessages[0]))
+	require.Error(t, producer.Flush(ctx))
+
+	require.Error(t, producer.ProduceBatch(ctx, sampleMessages[:1]))
+
+	// failures include the subject
+	ack, err := producer.ProduceWithAck(ctx, sampleMessages[0])
+	require.Error(t, err)
+	assert.Nil(t, ack)
+	assert.Equal(t, "no.such.stream", errcontext.Get(err)["subject"].String())
+}
+
+func TestProduceWithAck(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+
+	cfg, err := config.NewConfigurationFromMap(
+		map[string]any{
+			"subject": "plonk",
+		},
+	)
+	require.NoError(t, err)
+
+	producer, err := messagebus.NewNatsStreamProducer[sampleMessage](cfg, "", messagebus.WithNATSConnection(nc))
+	require.NoError(t, err)
+	t.Cleanup(producer.Close)
+	producer.SetMessageID(func(data sampleMessage) string {
+		return data.Message
+	})
+
+	first, err := producer.ProduceWithAck(t.Context(), sampleMessages[0])
+	require.NoError(t, err)
+	assert.Equal(t, "PLONK", first.Stream)
+	assert.False(t, first.Duplicate)
+
+	second, err := producer.ProduceWithAck(t.Context(), sampleMessages[1])
+	require.NoError(t, err)
+	assert.Equal(t, first.Sequence+1, second.Sequence)
+
+	// the same message ID is deduplicated by the stream
+	duplicate, err := producer.ProduceWithAck(t.Context(), sampleMessages[0])
+	require.NoError(t, err)
+	assert.True(t, duplicate.Duplicate)
+	assert.Equal(t, first.Sequence, duplicate.Sequence)
+}
@@ -0,0 +1,170 @@
+package messagebus_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/calm/errgroup"
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+// batchHandler records the integers of each batch, failing messages as determined by fail.
+type batchHandler struct {
+	mu      sync.Mutex
+	batches [][]int
+	fail    func(msg sampleMessage, attempt uint64) error
+}
+
+func (h *batchHandler) HandleBatch(_ context.Context, batch []messagebus.Envelope[sampleMessage]) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ints := make([]int, 0, len(batch))
+	failed := map[int]error{}
+	for i, env := range batch {
+		ints = append(ints, env.Data.Integer)
+		if h.fail != nil {
+			if err := h.fail(env.Data, env.Metadata.NumDelivered); err != nil {
+				failed[i] = err
+			}
+		}
+	}
+	h.batches = append(h.batches, ints)
+	if len(failed) > 0 {
+		return &messagebus.BatchError{Failed: failed}
+	}
+	return nil
+}
+
+func (h *batchHandler) handled() []int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var all []int
+	for _, batch := range h.batches {
+		all = append(all, batch...)
+	}
+	return all
+}
+
+func publishInts(t *testing.T, subject string, ints ...int) {
+	t.Helper()
+	js := getJetStream(t, getNatsConnection(t))
+	for _, i := range ints {
+		b, err := json.Marshal(sampleMessage{Integer: i})
+		require.NoError(t, err)
+		_, err = js.Publish(t.Context(), subject, b)
+		require.NoError(t, err)
+	}
+}
+
+func runBatchConsumer(t *testing.T, subject string, handler messagebus.BatchConsumerHandler[sampleMessage], opts ...messagebus.Option) (context.CancelFunc, func() error) {
+	t.Helper()
+	cfg, err := config.NewConfigurationFromMap(map[string]any{
+		"subject":      subject,
+		"stream":       "GRUNT",
+		"durablequeue": strings.ReplaceAll(subject, ".", "-"),
+	})
+	require.NoError(t, err)
+
+	consumer, err := messagebus.NewNatsStreamBatchConsumer(cfg, "", handler,
+		append([]messagebus.Option{messagebus.WithNATSConnection(getNatsConnection(t))}, opts...)...,
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second*10)
+	t.Cleanup(cancel)
+	group, _ := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		return consumer.Run(ctx)
+	})
+	return cancel, group.Wait
+}
+
+func TestBatchConsumer(t *testing.T) {
+	t.Parallel()
+	publishInts(t, "grunt.batch", 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+
+	handler := &batchHandler{}
+	cancel, wait := runBatchConsumer(t, "grunt.batch", handler,
+		messagebus.WithBatchSize(4),
+		messagebus.WithBatchTimeout(time.Millisecond*200),
+	)
+
+	require.Eventually(t, func() bool { return len(handler.handled()) == 10 }, time.Second*5, time.Millisecond*10)
+	cancel()
+	require.NoError(t, wait())
+
+	// full batches are handled as soon as they fill, and the remainder once the timeout expires
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	assert.Equal(t, [][]int{{1, 2, 3, 4}, {5, 6, 7, 8}, {9, 10}}, handler.batches)
+}
+
+func TestBatchConsumerPartialFailure(t *testing.T) {
+	t.Parallel()
+	publishInts(t, "grunt.partial", 1, 2, 3)
+
+	handler := &batchHandler{
+		fail: func(msg sampleMessage, attempt uint64) error {
+			switch {
+			case msg.Integer == 2 && attempt == 1:
+				return errclass.WrapAs(assert.AnError, errclass.Transient)
+			case msg.Integer == 3:
+				return errclass.WrapAs(assert.AnError, errclass.Persistent)
+			}
+			return nil
+		},
+	}
+	cancel, wait := runBatchConsumer(t, "grunt.partial", handler,
+		messagebus.WithBatchSize(3),
+		messagebus.WithBatchTimeout(time.Millisecond*100),
+	)
+
+	// only the transient failure is redelivered
+	require.Eventually(t, func() bool { return len(handler.handled()) == 4 }, time.Second*5, time.Millisecond*10)
+	time.Sleep(time.Millisecond * 500)
+	cancel()
+	require.NoError(t, wait())
+
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	assert.Equal(t, [][]int{{1, 2, 3}, {2}}, handler.batches)
+}
+
+func TestBatchConsumerBatchFailure(t *testing.T) {
+	t.Parallel()
+	publishInts(t, "grunt.failure", 1, 2)
+
+	var mu sync.Mutex
+	attempts := 0
+	handler := messagebus.BatchConsumerHandlerFunc[sampleMessage](func(_ context.Context, batch []messagebus.Envelope[sampleMessage]) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts += len(batch)
+		if attempts <= 2 {
+			// an error which is not a BatchError applies to the whole batch
+			return errclass.WrapAs(assert.AnError, errclass.Transient)
+		}
+		return nil
+	})
+	cancel, wait := runBatchConsumer(t, "grunt.failure", handler,
+		messagebus.WithBatchSize(2),
+		messagebus.WithBatchTimeout(time.Millisecond*100),
+	)
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return attempts == 4
+	}, time.Second*5, time.Millisecond*10)
+	cancel()
+	require.NoError(t, wait())
+}
@@ -0,0 +1,216 @@
+package messagebus
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const (
+	// ContentTypeHeader identifies how the message payload is serialized.
+	// Messages without it are deserialized using the configured codec.
+	ContentTypeHeader = "Content-Type"
+
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/protobuf"
+	ContentTypeMsgpack  = "application/msgpack"
+)
+
+var (
+	ErrUnsupportedContentType = errors.New("unsupported content type")
+	ErrNotProtoMessage        = errors.New("data is not a protobuf message")
+)
+
+// Codec serializes message data.
+type Codec interface {
+	// ContentType is set as the Content-Type header of produced messages, if not empty.
+	ContentType() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	// JSONCodec serializes data using encoding/json. It is the default.
+	JSONCodec Codec = jsonCodec{}
+	// ProtobufCodec serializes data which is a protobuf message.
+	// Use a pointer to the generated type as the type parameter, eg NatsStreamProducer[*pb.Order].
+	ProtobufCodec Codec = protobufCodec{}
+	// MsgpackCodec serializes data using MessagePack.
+	MsgpackCodec Codec = msgpackCodec{}
+)
+
+// builtinCodecs are always recognized when consuming, including common aliases.
+var builtinCodecs = map[string]Codec{
+	ContentTypeJSON:           JSONCodec,
+	ContentTypeProtobuf:       ProtobufCodec,
+	"application/x-protobuf":  ProtobufCodec,
+	ContentTypeMsgpack:        MsgpackCodec,
+	"application/x-msgpack":   MsgpackCodec,
+	"application/vnd.msgpack": MsgpackCodec,
+}
+
+// WithCodec sets the codec used to serialize data, and for messages without a Content-Type header.
+// Messages with a Content-Type header are deserialized using the matching codec, so streams
+// containing a mix of formats can be consumed safely. The built-in codecs are always recognized,
+// and custom codecs are recognized once given to WithCodec.
+func WithCodec(codec Codec) Option {
+	return func(options *options) {
+		options.codec = codec
+		options.codecs = append(options.codecs, codec)
+	}
+}
+
+// WithDataSerialization sets an alternative method to serialize data.
+// Produced messages have no Content-Type header; use WithCodec to set one.
+func WithDataSerialization(marshaler MarshalFn, unmarshaler UnmarshalFn) Option {
+	return func(options *options) {
+		options.codec = funcCodec{marshal: marshaler, unmarshal: unmarshaler}
+	}
+}
+
+// marshal serializes v using the configured codec, setting the content type header if known.
+// The header may be nil when it is not needed.
+func (o options) marshal(header nats.Header, v any) ([]byte, error) {
+	b, err := o.codec.Marshal(v)
+	if err != nil {
+		return nil, stacktrace.Wrap(err)
+	}
+	if contentType := o.codec.ContentType(); contentType != "" && header != nil {
+		header.Set(ContentTypeHeader, contentType)
+	}
+	return b, nil
+}
+
+// unmarshal decodes the (possibly compressed) payload into v using the codec given by the
+// content type header, or the configured codec if there is none.
+func (o options) unmarshal(header nats.Header, data []byte, v any) error {
+	payload, err := decodePayload(header, data)
+	if err != nil {
+		return err
+	}
+	codec, err := o.codecFor(header.Get(ContentTypeHeader))
+	if err != nil {
+		return err
+	}
+	if err := codec.Unmarshal(payload, v); err != nil {
+		return stacktrace.Wrap(err)
+	}
+	return nil
+}
+
+func (o options) codecFor(contentType string) (Codec, error) {
+	// ignore any parameters, eg "application/json; charset=utf-8"
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if mediaType == "" || mediaType == o.codec.ContentType() {
+		return o.codec, nil
+	}
+	for _, codec := range o.codecs {
+		if mediaType == codec.ContentType() {
+			return codec, nil
+		}
+	}
+	if codec, ok := builtinCodecs[mediaType]; ok {
+		return codec, nil
+	}
+	return nil, errclass.WrapAs(stacktrace.Wrap(ErrUnsupportedContentType), errclass.Persistent)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string {
+	return ContentTypeJSON
+}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+type protobufCodec struct{}
+
+func (protobufCodec) ContentType() string {
+	return ContentTypeProtobuf
+}
+
+func (protobufCodec) Marshal(v any) ([]byte, error) {
+	m, ok := protoMessage(v, false)
+	if !ok {
+		return nil, errclass.WrapAs(stacktrace.Wrap(ErrNotProtoMessage), errclass.Persistent)
+	}
+	return proto.Marshal(m)
+}
+
+func (protobufCodec) Unmarshal(data []byte, v any) error {
+	m, ok := protoMessage(v, true)
+	if !ok {
+		return errclass.WrapAs(stacktrace.Wrap(ErrNotProtoMessage), errclass.Persistent)
+	}
+	return proto.Unmarshal(data, m)
+}
+
+// protoMessage finds the message in v, following pointers as data may be given as a
+// pointer to the type parameter which is itself a pointer to a message.
+// If alloc is set, nil messages are allocated so they can be unmarshaled into.
+func protoMessage(v any, alloc bool) (proto.Message, bool) {
+	if m, ok := v.(proto.Message); ok {
+		return m, true
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() == reflect.Pointer {
+		rv = rv.Elem()
+		if rv.IsNil() {
+			if !alloc {
+				return nil, false
+			}
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		if m, ok := rv.Interface().(proto.Message); ok {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) ContentType() string {
+	return ContentTypeMsgpack
+}
+
+func (msgpackCodec) Marshal(v any) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v any) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+// funcCodec adapts the functions given to WithDataSerialization.
+type funcCodec struct {
+	marshal   MarshalFn
+	unmarshal UnmarshalFn
+}
+
+func (funcCodec) ContentType() string {
+	return ""
+}
+
+func (f funcCodec) Marshal(v any) ([]byte, error) {
+	return f.marshal(v)
+}
+
+func (f funcCodec) Unmarshal(data []byte, v any) error {
+	return f.unmarshal(data, v)
+}
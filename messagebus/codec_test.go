@@ -0,0 +1,94 @@
+package messagebus_test
+
+import (
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+func TestCodecs(t *testing.T) {
+	t.Parallel()
+
+	for _, codec := range []messagebus.Codec{messagebus.JSONCodec, messagebus.MsgpackCodec} {
+		t.Run(codec.ContentType(), func(t *testing.T) {
+			t.Parallel()
+			b, err := codec.Marshal(sampleMessages[0])
+			require.NoError(t, err)
+			var got sampleMessage
+			require.NoError(t, codec.Unmarshal(b, &got))
+			assert.Equal(t, sampleMessages[0], got)
+		})
+	}
+
+	t.Run(messagebus.ContentTypeProtobuf, func(t *testing.T) {
+		t.Parallel()
+		codec := messagebus.ProtobufCodec
+
+		// messages may be given directly, or as a pointer to them as done by generic producers
+		want := wrapperspb.String("hello")
+		b, err := codec.Marshal(want)
+		require.NoError(t, err)
+		b2, err := codec.Marshal(&want)
+		require.NoError(t, err)
+		assert.Equal(t, b, b2)
+
+		// nil messages are allocated when unmarshaling
+		var got *wrapperspb.StringValue
+		require.NoError(t, codec.Unmarshal(b, &got))
+		assert.Equal(t, "hello", got.GetValue())
+
+		_, err = codec.Marshal(sampleMessages[0])
+		require.ErrorIs(t, err, messagebus.ErrNotProtoMessage)
+		assert.Equal(t, errclass.Persistent, errclass.GetClass(err))
+	})
+}
+
+func TestMixedCodecs(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+	js := getJetStream(t, nc)
+
+	cfg, err := config.NewConfigurationFromMap(
+		map[string]any{
+			"subject":      "blorp",
+			"stream":       "BLORP",
+			"durablequeue": "blorp",
+		},
+	)
+	require.NoError(t, err)
+
+	jsonProducer, err := messagebus.NewNatsStreamProducer[sampleMessage](cfg, "", messagebus.WithNATSConnection(nc))
+	require.NoError(t, err)
+	t.Cleanup(jsonProducer.Close)
+	msgpackProducer, err := messagebus.NewNatsStreamProducer[sampleMessage](cfg, "",
+		messagebus.WithNATSConnection(nc),
+		messagebus.WithCodec(messagebus.MsgpackCodec),
+	)
+	require.NoError(t, err)
+	t.Cleanup(msgpackProducer.Close)
+
+	require.NoError(t, jsonProducer.Produce(t.Context(), sampleMessages[0]))
+	// messages of an unknown type are skipped rather than decoded incorrectly
+	_, err = js.PublishMsg(t.Context(), &nats.Msg{
+		Subject: "blorp",
+		Header:  nats.Header{messagebus.ContentTypeHeader: []string{"application/xml"}},
+		Data:    []byte("<sample/>"),
+	})
+	require.NoError(t, err)
+	require.NoError(t, msgpackProducer.Produce(t.Context(), sampleMessages[1]))
+
+	// the consumer uses the codec given by the content type of each message
+	handler := &streamConsumerHandler[sampleMessage]{ExpectedMessages: 2, Done: make(chan struct{})}
+	consumer, err := messagebus.NewNatsStreamConsumer(cfg, "", handler, messagebus.WithNATSConnection(nc))
+	require.NoError(t, err)
+	consumeN(t, consumer, handler)
+
+	assert.Equal(t, sampleMessages[:2], handler.Messages)
+}
@@ -0,0 +1,93 @@
+package messagebus
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/nats-io/nats.go"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const (
+	// ContentEncodingHeader identifies how the message payload is encoded.
+	// Messages without it are uncompressed.
+	ContentEncodingHeader = "Content-Encoding"
+	// EncodingZstd identifies payloads compressed with zstd.
+	EncodingZstd = "zstd"
+
+	// maxDecodedSize protects consumers from decompression bombs.
+	maxDecodedSize = 64 << 20
+)
+
+var ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+
+var (
+	// zstd encoders and decoders are safe for concurrent use with EncodeAll and DecodeAll
+	zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+		return zstd.NewWriter(nil)
+	})
+	zstdDecoder = sync.OnceValues(func() (*zstd.Decoder, error) {
+		return zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxDecodedSize), zstd.WithDecoderConcurrency(0))
+	})
+)
+
+// WithCompression makes producers compress payloads of at least threshold bytes using zstd,
+// setting the Content-Encoding header accordingly. Consumers always decompress such payloads
+// automatically, so they must be upgraded before their producers enable compression.
+func WithCompression(threshold int) Option {
+	return func(options *options) {
+		options.compressionThreshold = threshold
+	}
+}
+
+// compressPayload compresses the payload if it meets the threshold, returning the encoding used (if any).
+func compressPayload(payload []byte, threshold int) ([]byte, string, error) {
+	if threshold <= 0 || len(payload) < threshold {
+		return payload, "", nil
+	}
+	encoder, err := zstdEncoder()
+	if err != nil {
+		return nil, "", stacktrace.Wrap(err)
+	}
+	compressed := encoder.EncodeAll(payload, make([]byte, 0, len(payload)/2))
+	// not worth it if it doesn't get any smaller
+	if len(compressed) >= len(payload) {
+		return payload, "", nil
+	}
+	return compressed, EncodingZstd, nil
+}
+
+// decodePayload returns the original payload according to the Content-Encoding header.
+func decodePayload(header nats.Header, data []byte) ([]byte, error) {
+	switch encoding := header.Get(ContentEncodingHeader); encoding {
+	case "", "identity":
+		return data, nil
+	case EncodingZstd:
+		decoder, err := zstdDecoder()
+		if err != nil {
+			return nil, stacktrace.Wrap(err)
+		}
+		decoded, err := decoder.DecodeAll(data, nil)
+		if err != nil {
+			return nil, errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+		}
+		return decoded, nil
+	default:
+		return nil, errclass.WrapAs(stacktrace.Wrap(ErrUnsupportedEncoding), errclass.Persistent)
+	}
+}
+
+// compressInto compresses the payload as configured, setting the content encoding header if needed.
+func compressInto(header nats.Header, b []byte, threshold int) ([]byte, error) {
+	b, encoding, err := compressPayload(b, threshold)
+	if err != nil {
+		return nil, err
+	}
+	if encoding != "" {
+		header.Set(ContentEncodingHeader, encoding)
+	}
+	return b, nil
+}
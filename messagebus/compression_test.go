@@ -0,0 +1,82 @@
+package messagebus_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+)
+
+func TestCompression(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+	js := getJetStream(t, nc)
+
+	newConfig := func(subject string) *config.Configuration {
+		cfg, err := config.NewConfigurationFromMap(
+			map[string]any{
+				"subject": subject,
+				"stream":  "FRED",
+			},
+		)
+		require.NoError(t, err)
+		return cfg
+	}
+
+	large := sampleMessages[0]
+	large.Message = strings.Repeat("compressible ", 1000)
+	small := sampleMessages[1]
+
+	bigProducer, err := messagebus.NewNatsStreamProducer[sampleMessage](newConfig("fred.big"), "", messagebus.WithNATSConnection(nc), messagebus.WithCompression(1024))
+	require.NoError(t, err)
+	t.Cleanup(bigProducer.Close)
+	require.NoError(t, bigProducer.Produce(t.Context(), large))
+
+	smallProducer, err := messagebus.NewNatsStreamProducer[sampleMessage](newConfig("fred.small"), "", messagebus.WithNATSConnection(nc), messagebus.WithCompression(1024))
+	require.NoError(t, err)
+	t.Cleanup(smallProducer.Close)
+	require.NoError(t, smallProducer.Produce(t.Context(), small))
+
+	stream, err := js.Stream(t.Context(), "FRED")
+	require.NoError(t, err)
+
+	// payloads above the threshold are compressed and marked as such
+	raw, err := stream.GetLastMsgForSubject(t.Context(), "fred.big")
+	require.NoError(t, err)
+	assert.Equal(t, messagebus.EncodingZstd, raw.Header.Get(messagebus.ContentEncodingHeader))
+	assert.Less(t, len(raw.Data), len(large.Message))
+
+	// payloads below it are not
+	raw, err = stream.GetLastMsgForSubject(t.Context(), "fred.small")
+	require.NoError(t, err)
+	assert.Empty(t, raw.Header.Get(messagebus.ContentEncodingHeader))
+
+	// consumers decompress transparently, and still accept uncompressed payloads
+	msg, _, err := messagebus.GetLastMessage[sampleMessage](newConfig("fred.big"), "", messagebus.WithNATSConnection(nc))
+	require.NoError(t, err)
+	assert.Equal(t, large, msg)
+
+	handler := &streamConsumerHandler[sampleMessage]{
+		ExpectedMessages: 2,
+		Done:             make(chan struct{}),
+	}
+	consumer, err := messagebus.NewNatsStreamConsumer(newConfig("fred.>"), "", handler, messagebus.WithNATSConnection(nc))
+	require.NoError(t, err)
+	consumeN(t, consumer, handler)
+	assert.Equal(t, []sampleMessage{large, small}, handler.Messages)
+
+	// unknown encodings are rejected
+	_, err = js.PublishMsg(t.Context(), &nats.Msg{
+		Subject: "fred.unknown",
+		Data:    []byte("{}"),
+		Header:  nats.Header{messagebus.ContentEncodingHeader: []string{"br"}},
+	})
+	require.NoError(t, err)
+	_, _, err = messagebus.GetLastMessage[sampleMessage](newConfig("fred.unknown"), "", messagebus.WithNATSConnection(nc))
+	require.ErrorIs(t, err, messagebus.ErrUnsupportedEncoding)
+}
@@ -0,0 +1,76 @@
+package messagebus
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// ErrConcurrentAckAll is returned when concurrent handling is combined with AckAllPolicy,
+// where acking a message would also ack earlier messages still being handled.
+var ErrConcurrentAckAll = errors.New("concurrent message handling requires explicit acks")
+
+// concurrencyLimit is the number of messages handled concurrently, which is
+// never more than the consumer allows to be pending an ack.
+func (n *NatsStreamConsumer[T]) concurrencyLimit(consumerConfig jetstream.ConsumerConfig) int {
+	limit := n.opts.maxConcurrency
+	if consumerConfig.MaxAckPending > 0 {
+		limit = min(limit, consumerConfig.MaxAckPending)
+	}
+	return limit
+}
+
+// startConcurrentDispatch handles each message in its own goroutine, up to limit at a time.
+// It returns a func to dispatch messages, which blocks while the limit is reached,
+// and a func to stop dispatching which waits for messages already being handled.
+// Each message is acked individually by handleMessage once handled, so acks may be out of order.
+func (n *NatsStreamConsumer[T]) startConcurrentDispatch(ctx context.Context, limit int) (func(jetstream.Msg), func()) {
+	slots := make(chan struct{}, limit)
+	stopped := make(chan struct{})
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	dispatch := func(msg jetstream.Msg) {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			_ = msg.Nak()
+			return
+		case <-stopped:
+			_ = msg.Nak()
+			return
+		}
+
+		// stop may have been called while waiting for a slot
+		mu.Lock()
+		select {
+		case <-stopped:
+			mu.Unlock()
+			<-slots
+			_ = msg.Nak()
+			return
+		default:
+		}
+		wg.Add(1)
+		mu.Unlock()
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			n.handleMessage(ctx, msg)
+		}()
+	}
+
+	stop := func() {
+		mu.Lock()
+		close(stopped)
+		mu.Unlock()
+		wg.Wait()
+	}
+
+	return dispatch, stop
+}
@@ -0,0 +1,181 @@
+package messagebus_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/calm/errgroup"
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+)
+
+// concurrencyHandler blocks each message until release is closed, tracking how many are in flight.
+type concurrencyHandler struct {
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	handled     int
+	expected    int
+	release     chan struct{}
+	done        chan struct{}
+}
+
+func (h *concurrencyHandler) HandleMessage(ctx context.Context, _ sampleMessage, _ string, _ jetstream.MsgMetadata) error {
+	h.mu.Lock()
+	h.inFlight++
+	h.maxInFlight = max(h.maxInFlight, h.inFlight)
+	h.mu.Unlock()
+
+	select {
+	case <-h.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.inFlight--
+	h.handled++
+	if h.handled == h.expected {
+		close(h.done)
+	}
+	return nil
+}
+
+func (h *concurrencyHandler) peak() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.maxInFlight
+}
+
+func TestMaxConcurrency(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+	js := getJetStream(t, nc)
+
+	b, err := json.Marshal(sampleMessages[0])
+	require.NoError(t, err)
+	const total = 6
+	for range total {
+		_, err := js.Publish(t.Context(), "thud.max", b)
+		require.NoError(t, err)
+	}
+
+	cfg, err := config.NewConfigurationFromMap(map[string]any{
+		"subject": "thud.max",
+		"stream":  "THUD",
+	})
+	require.NoError(t, err)
+
+	handler := &concurrencyHandler{expected: total, release: make(chan struct{}), done: make(chan struct{})}
+	consumer, err := messagebus.NewNatsStreamConsumer(cfg, "", handler,
+		messagebus.WithNATSConnection(nc),
+		messagebus.WithMaxConcurrency(3),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second*10)
+	t.Cleanup(cancel)
+	group, _ := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		err := consumer.Run(ctx)
+		cancel()
+		return err
+	})
+
+	// messages are handled concurrently, but no more than the limit at a time
+	require.Eventually(t, func() bool { return handler.peak() == 3 }, time.Second*5, time.Millisecond*10)
+	time.Sleep(time.Millisecond * 50)
+	assert.Equal(t, 3, handler.peak())
+	close(handler.release)
+
+	select {
+	case <-handler.done:
+		cancel()
+	case <-ctx.Done():
+	}
+	require.NoError(t, group.Wait())
+
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	assert.Equal(t, total, handler.handled)
+	assert.Equal(t, 3, handler.maxInFlight)
+}
+
+func TestMaxConcurrencyCappedByMaxAckPending(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+	js := getJetStream(t, nc)
+
+	b, err := json.Marshal(sampleMessages[0])
+	require.NoError(t, err)
+	const total = 4
+	for range total {
+		_, err := js.Publish(t.Context(), "thud.capped", b)
+		require.NoError(t, err)
+	}
+
+	cfg, err := config.NewConfigurationFromMap(map[string]any{"stream": "THUD"})
+	require.NoError(t, err)
+
+	handler := &concurrencyHandler{expected: total, release: make(chan struct{}), done: make(chan struct{})}
+	consumer, err := messagebus.NewNatsStreamConsumer(cfg, "", handler,
+		messagebus.WithNATSConnection(nc),
+		messagebus.WithConsumerConfig(&jetstream.ConsumerConfig{
+			Durable:       "thud-capped",
+			FilterSubject: "thud.capped",
+			AckPolicy:     jetstream.AckExplicitPolicy,
+			MaxAckPending: 2,
+		}),
+		messagebus.WithMaxConcurrency(10),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second*10)
+	t.Cleanup(cancel)
+	group, _ := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		err := consumer.Run(ctx)
+		cancel()
+		return err
+	})
+
+	require.Eventually(t, func() bool { return handler.peak() == 2 }, time.Second*5, time.Millisecond*10)
+	time.Sleep(time.Millisecond * 50)
+	assert.Equal(t, 2, handler.peak())
+	close(handler.release)
+
+	select {
+	case <-handler.done:
+		cancel()
+	case <-ctx.Done():
+	}
+	require.NoError(t, group.Wait())
+}
+
+func TestMaxConcurrencyAckAll(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+
+	cfg, err := config.NewConfigurationFromMap(map[string]any{"stream": "THUD"})
+	require.NoError(t, err)
+
+	handler := &concurrencyHandler{}
+	_, err = messagebus.NewNatsStreamConsumer(cfg, "", handler,
+		messagebus.WithNATSConnection(nc),
+		messagebus.WithConsumerConfig(&jetstream.ConsumerConfig{
+			Durable:       "thud-ackall",
+			FilterSubject: "thud.ackall",
+			AckPolicy:     jetstream.AckAllPolicy,
+		}),
+		messagebus.WithMaxConcurrency(2),
+	)
+	require.ErrorIs(t, err, messagebus.ErrConcurrentAckAll)
+}
@@ -0,0 +1,179 @@
+package messagebus
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// DedupStore records the IDs of handled messages.
+type DedupStore interface {
+	// Seen reports whether the ID was marked within the window.
+	Seen(ctx context.Context, id string, window time.Duration) (bool, error)
+	// Mark records that the message with the ID has been handled.
+	Mark(ctx context.Context, id string, window time.Duration) error
+}
+
+// deduplication holds the options given to WithDeduplication.
+type deduplication struct {
+	window time.Duration
+	store  DedupStore
+	id     any // func(data T) string
+}
+
+// WithDeduplication makes a NatsStreamConsumer skip (and ack) messages which it has already handled
+// successfully within the window, as happens when an ack is lost and the message is redelivered.
+// Messages are identified by their Nats-Msg-Id header (see NatsStreamProducer.SetMessageID),
+// or by the func given to WithDeduplicationID, and messages without an ID are always handled.
+// If store is nil, a MemoryDedupStore is used, which only deduplicates within this instance.
+func WithDeduplication(window time.Duration, store DedupStore) Option {
+	return func(options *options) {
+		if store == nil {
+			store = NewMemoryDedupStore()
+		}
+		if options.dedup == nil {
+			options.dedup = &deduplication{}
+		}
+		options.dedup.window = window
+		options.dedup.store = store
+	}
+}
+
+// WithDeduplicationID identifies messages for WithDeduplication using their data rather than
+// the Nats-Msg-Id header. The type parameter must match that of the consumer.
+func WithDeduplicationID[T any](f func(data T) string) Option {
+	return func(options *options) {
+		if options.dedup == nil {
+			options.dedup = &deduplication{}
+		}
+		options.dedup.id = f
+	}
+}
+
+// dedupIDFunc returns the func identifying messages for deduplication, or nil if disabled.
+func dedupIDFunc[T any](dedup *deduplication) (func(msg jetstream.Msg, data T) string, error) {
+	if dedup == nil || dedup.store == nil {
+		return nil, nil
+	}
+	if dedup.id == nil {
+		return func(msg jetstream.Msg, _ T) string {
+			return msg.Headers().Get(jetstream.MsgIDHeader)
+		}, nil
+	}
+	f, err := middlewareFor[func(data T) string]([]any{dedup.id})
+	if err != nil {
+		return nil, err
+	}
+	return func(_ jetstream.Msg, data T) string {
+		return f[0](data)
+	}, nil
+}
+
+// isDuplicate reports whether the message was already handled.
+// Store failures are logged and the message is handled again, preserving at-least-once delivery.
+func (d *deduplication) isDuplicate(ctx context.Context, id string, logger *slog.Logger) bool {
+	seen, err := d.store.Seen(ctx, id, d.window)
+	if err != nil {
+		logger.Warn("failed to check for duplicate message", log.ErrAttr(err))
+		return false
+	}
+	return seen
+}
+
+// markHandled records that the message was handled, logging any failure.
+func (d *deduplication) markHandled(ctx context.Context, id string, logger *slog.Logger) {
+	if err := d.store.Mark(ctx, id, d.window); err != nil {
+		logger.Warn("failed to record handled message for deduplication", log.ErrAttr(err))
+	}
+}
+
+// MemoryDedupStore is a DedupStore local to the process.
+type MemoryDedupStore struct {
+	mu        sync.Mutex
+	expiresAt map[string]time.Time
+	lastPrune time.Time
+}
+
+// NewMemoryDedupStore creates a new MemoryDedupStore.
+func NewMemoryDedupStore() *MemoryDedupStore {
+	return &MemoryDedupStore{
+		expiresAt: make(map[string]time.Time),
+	}
+}
+
+// Seen implements DedupStore.
+func (s *MemoryDedupStore) Seen(_ context.Context, id string, _ time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiresAt, ok := s.expiresAt[id]
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+// Mark implements DedupStore. Expired IDs are pruned at most once per window.
+func (s *MemoryDedupStore) Mark(_ context.Context, id string, window time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.lastPrune) >= window {
+		for k, expiresAt := range s.expiresAt {
+			if !now.Before(expiresAt) {
+				delete(s.expiresAt, k)
+			}
+		}
+		s.lastPrune = now
+	}
+	s.expiresAt[id] = now.Add(window)
+	return nil
+}
+
+// KVDedupStore is a DedupStore backed by a NATS KV bucket, shared by all instances using the bucket.
+type KVDedupStore struct {
+	kv jetstream.KeyValue
+}
+
+// NewKVDedupStore creates (or updates) the bucket such that entries expire after the window.
+func NewKVDedupStore(ctx context.Context, js jetstream.JetStream, bucket string, window time.Duration) (*KVDedupStore, error) {
+	kv, err := js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket:      bucket,
+		Description: "handled message IDs for deduplication",
+		TTL:         window,
+	})
+	if err != nil {
+		return nil, stacktrace.Wrap(err)
+	}
+	return &KVDedupStore{kv: kv}, nil
+}
+
+// Seen implements DedupStore.
+func (s *KVDedupStore) Seen(ctx context.Context, id string, window time.Duration) (bool, error) {
+	entry, err := s.kv.Get(ctx, dedupKey(id))
+	switch {
+	case errors.Is(err, jetstream.ErrKeyNotFound):
+		return false, nil
+	case err != nil:
+		return false, stacktrace.Wrap(err)
+	}
+	// the bucket TTL may be longer than the window
+	return time.Since(entry.Created()) < window, nil
+}
+
+// Mark implements DedupStore.
+func (s *KVDedupStore) Mark(ctx context.Context, id string, _ time.Duration) error {
+	if _, err := s.kv.Put(ctx, dedupKey(id), nil); err != nil {
+		return stacktrace.Wrap(err)
+	}
+	return nil
+}
+
+// dedupKey encodes the ID as a valid KV key.
+func dedupKey(id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(id))
+}
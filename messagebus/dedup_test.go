@@ -0,0 +1,141 @@
+package messagebus_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+)
+
+func TestDeduplication(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+
+	tests := []struct {
+		name string
+		opts []messagebus.Option
+	}{
+		{
+			name: "header",
+			opts: nil,
+		},
+		{
+			name: "extractor",
+			opts: []messagebus.Option{
+				messagebus.WithDeduplicationID(func(data sampleMessage) string { return data.Message }),
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := config.NewConfigurationFromMap(
+				map[string]any{
+					"subject":      "flob." + tc.name,
+					"stream":       "FLOB",
+					"durablequeue": "flob-" + tc.name,
+				},
+			)
+			require.NoError(t, err)
+
+			producer, err := messagebus.NewNatsStreamProducer[sampleMessage](cfg, "", messagebus.WithNATSConnection(nc))
+			require.NoError(t, err)
+			t.Cleanup(producer.Close)
+			if tc.opts == nil {
+				producer.SetMessageID(func(data sampleMessage) string { return tc.name + data.Message })
+			}
+
+			// "a" has already been handled, eg before a lost ack
+			store := messagebus.NewMemoryDedupStore()
+			id := func(message string) string {
+				if tc.opts == nil {
+					return tc.name + message
+				}
+				return message
+			}
+			require.NoError(t, store.Mark(t.Context(), id("a"), time.Minute))
+
+			for _, m := range []string{"a", "b", "c"} {
+				require.NoError(t, producer.Produce(t.Context(), sampleMessage{Message: m}))
+			}
+
+			handler := &streamConsumerHandler[sampleMessage]{ExpectedMessages: 2, Done: make(chan struct{})}
+			opts := append([]messagebus.Option{
+				messagebus.WithNATSConnection(nc),
+				messagebus.WithDeduplication(time.Minute, store),
+			}, tc.opts...)
+			consumer, err := messagebus.NewNatsStreamConsumer(cfg, "", handler, opts...)
+			require.NoError(t, err)
+			consumeN(t, consumer, handler)
+
+			assert.Equal(t, []sampleMessage{{Message: "b"}, {Message: "c"}}, handler.Messages)
+			// handled messages are marked once the handler returns
+			for _, m := range []string{"b", "c"} {
+				assert.Eventually(t, func() bool {
+					seen, err := store.Seen(t.Context(), id(m), time.Minute)
+					return err == nil && seen
+				}, time.Second, 10*time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestDeduplicationIDTypeMismatch(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+
+	cfg, err := config.NewConfigurationFromMap(map[string]any{"subject": "flob.mismatch", "stream": "FLOB"})
+	require.NoError(t, err)
+
+	handler := &streamConsumerHandler[sampleMessage]{}
+	_, err = messagebus.NewNatsStreamConsumer(cfg, "", handler,
+		messagebus.WithNATSConnection(nc),
+		messagebus.WithDeduplication(time.Minute, nil),
+		messagebus.WithDeduplicationID(func(data int) string { return "" }),
+	)
+	require.ErrorIs(t, err, messagebus.ErrMiddlewareType)
+}
+
+func TestDedupStores(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+	js := getJetStream(t, nc)
+
+	kvStore, err := messagebus.NewKVDedupStore(t.Context(), js, "dedup_test", time.Minute)
+	require.NoError(t, err)
+
+	stores := map[string]messagebus.DedupStore{
+		"memory": messagebus.NewMemoryDedupStore(),
+		"kv":     kvStore,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			ctx := t.Context()
+			window := 200 * time.Millisecond
+
+			// IDs may contain characters which are not valid in KV keys
+			id := "order 42/" + name
+
+			seen, err := store.Seen(ctx, id, window)
+			require.NoError(t, err)
+			assert.False(t, seen)
+
+			require.NoError(t, store.Mark(ctx, id, window))
+			seen, err = store.Seen(ctx, id, window)
+			require.NoError(t, err)
+			assert.True(t, seen)
+
+			// IDs are forgotten after the window
+			assert.Eventually(t, func() bool {
+				seen, err := store.Seen(ctx, id, window)
+				return err == nil && !seen
+			}, 2*time.Second, 50*time.Millisecond)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package messagebus_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/calm/errgroup"
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+)
+
+// consumeN runs the consumer until the handler has received n messages (or timeout).
+func consumeN(t *testing.T, consumer *messagebus.NatsStreamConsumer[sampleMessage], handler *streamConsumerHandler[sampleMessage]) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second*10)
+	t.Cleanup(cancel)
+	group, _ := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		err := consumer.Run(ctx)
+		cancel()
+		return err
+	})
+
+	select {
+	case <-handler.Done:
+		cancel()
+	case <-ctx.Done():
+	}
+	require.NoError(t, group.Wait())
+}
+
+func TestDeliverPolicy(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+	js := getJetStream(t, nc)
+
+	publish := func(subject string, m sampleMessage) {
+		b, err := json.Marshal(m)
+		require.NoError(t, err)
+		_, err = js.Publish(t.Context(), subject, b)
+		require.NoError(t, err)
+	}
+
+	// sequence 1, 2, 3
+	publish("plugh.a", sampleMessages[0])
+	time.Sleep(time.Millisecond * 50)
+	startTime := time.Now()
+	publish("plugh.b", sampleMessages[1])
+	publish("plugh.a", sampleMessages[1])
+
+	cfg, err := config.NewConfigurationFromMap(
+		map[string]any{
+			"subject": "plugh.>",
+			"stream":  "PLUGH",
+		},
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		option   messagebus.Option
+		subjects []string
+	}{
+		{
+			name:     "start sequence",
+			option:   messagebus.WithDeliverByStartSequence(2),
+			subjects: []string{"plugh.b", "plugh.a"},
+		},
+		{
+			name:     "start time",
+			option:   messagebus.WithDeliverByStartTime(startTime),
+			subjects: []string{"plugh.b", "plugh.a"},
+		},
+		{
+			name:     "last per subject",
+			option:   messagebus.WithDeliverLastPerSubject(),
+			subjects: []string{"plugh.b", "plugh.a"},
+		},
+		{
+			name:     "new only",
+			option:   messagebus.WithDeliverNew(),
+			subjects: []string{"plugh.c"},
+		},
+	}
+
+	// run sequentially since the final case publishes to the shared stream
+	for _, tt := range tests {
+		handler := &streamConsumerHandler[sampleMessage]{
+			ExpectedMessages: len(tt.subjects),
+			Done:             make(chan struct{}),
+		}
+		consumer, err := messagebus.NewNatsStreamConsumer(cfg, "", handler, messagebus.WithNATSConnection(nc), tt.option)
+		require.NoError(t, err, tt.name)
+
+		if tt.name == "new only" {
+			publish("plugh.c", sampleMessages[0])
+		}
+
+		consumeN(t, consumer, handler)
+		assert.Equal(t, tt.subjects, handler.Subjects, tt.name)
+	}
+}
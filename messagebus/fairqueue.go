@@ -0,0 +1,209 @@
+package messagebus
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/zircuit-labs/zkr-go-common/calm/errgroup"
+)
+
+// FairScheduling configures weighted-fair handling of messages across tenants,
+// such that a backlog of messages for one tenant cannot starve the others.
+type FairScheduling struct {
+	// TenantKey extracts the tenant from the subject and/or message data (after any decompression).
+	TenantKey func(subject string, data []byte) string
+	// MaxInFlight is the maximum number of messages handled concurrently. Defaults to 1.
+	MaxInFlight int
+	// MaxInFlightPerTenant is the maximum number of messages for a single tenant
+	// handled concurrently. Zero means no limit beyond MaxInFlight.
+	MaxInFlightPerTenant int
+	// Weights is the number of messages dispatched for each tenant per round. Defaults to 1.
+	Weights map[string]int
+}
+
+func (f FairScheduling) weight(tenant string) int {
+	if w, ok := f.Weights[tenant]; ok && w > 0 {
+		return w
+	}
+	return 1
+}
+
+// fairQueue holds a sub-queue per tenant and dispatches items using weighted round-robin.
+type fairQueue[T any] struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	cfg      FairScheduling
+	queues   map[string][]T
+	inFlight map[string]int
+	// tenants in round-robin order, along with the current position and its remaining credits
+	order   []string
+	pos     int
+	credits int
+	closed  bool
+}
+
+func newFairQueue[T any](cfg FairScheduling) *fairQueue[T] {
+	q := &fairQueue[T]{
+		cfg:      cfg,
+		queues:   map[string][]T{},
+		inFlight: map[string]int{},
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push adds an item to the tenant's queue.
+func (q *fairQueue[T]) push(tenant string, item T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.queues[tenant]; !ok {
+		if len(q.order) == 0 {
+			q.pos = 0
+			q.credits = q.cfg.weight(tenant)
+		}
+		q.order = append(q.order, tenant)
+	}
+	q.queues[tenant] = append(q.queues[tenant], item)
+	q.cond.Signal()
+}
+
+// pop blocks until an item can be dispatched, or the queue is closed.
+// Callers must call done with the tenant once the item has been handled.
+func (q *fairQueue[T]) pop() (string, T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for {
+		if q.closed {
+			var zero T
+			return "", zero, false
+		}
+		if tenant, item, ok := q.next(); ok {
+			return tenant, item, true
+		}
+		q.cond.Wait()
+	}
+}
+
+// next finds the next eligible item. Must be called with the lock held.
+func (q *fairQueue[T]) next() (string, T, bool) {
+	for range len(q.order) {
+		tenant := q.order[q.pos]
+		items := q.queues[tenant]
+		limited := q.cfg.MaxInFlightPerTenant > 0 && q.inFlight[tenant] >= q.cfg.MaxInFlightPerTenant
+		if len(items) == 0 || limited || q.credits <= 0 {
+			q.advance()
+			continue
+		}
+
+		item := items[0]
+		var zero T
+		items[0] = zero
+		q.queues[tenant] = items[1:]
+		q.inFlight[tenant]++
+		q.credits--
+		if q.credits <= 0 {
+			q.advance()
+		}
+		return tenant, item, true
+	}
+	var zero T
+	return "", zero, false
+}
+
+// advance moves to the next tenant, removing idle tenants. Must be called with the lock held.
+func (q *fairQueue[T]) advance() {
+	tenant := q.order[q.pos]
+	if len(q.queues[tenant]) == 0 && q.inFlight[tenant] == 0 {
+		delete(q.queues, tenant)
+		delete(q.inFlight, tenant)
+		q.order = append(q.order[:q.pos], q.order[q.pos+1:]...)
+	} else {
+		q.pos++
+	}
+	if q.pos >= len(q.order) {
+		q.pos = 0
+	}
+	if len(q.order) > 0 {
+		q.credits = q.cfg.weight(q.order[q.pos])
+	}
+}
+
+// done records that an item for the tenant has been handled.
+func (q *fairQueue[T]) done(tenant string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.inFlight[tenant]--
+	q.cond.Broadcast()
+}
+
+// close wakes all waiting callers and returns any items which were not dispatched.
+func (q *fairQueue[T]) close() []T {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	var remaining []T
+	for _, items := range q.queues {
+		remaining = append(remaining, items...)
+	}
+	q.queues = map[string][]T{}
+	q.cond.Broadcast()
+	return remaining
+}
+
+type queuedMsg struct {
+	msg          jetstream.Msg
+	stopProgress context.CancelFunc
+}
+
+// startFairScheduling starts workers handling messages from a fair queue.
+// It returns a func to enqueue messages, and a func to stop the workers which
+// naks any messages not yet handled so that they are redelivered promptly.
+func (n *NatsStreamConsumer[T]) startFairScheduling(ctx context.Context) (func(jetstream.Msg), func()) {
+	cfg := *n.opts.fairScheduling
+	q := newFairQueue[queuedMsg](cfg)
+
+	g := errgroup.New()
+	for range max(cfg.MaxInFlight, 1) {
+		g.Go(func() error {
+			for {
+				tenant, item, ok := q.pop()
+				if !ok {
+					return nil
+				}
+				item.stopProgress()
+				n.handleMessage(ctx, item.msg)
+				q.done(tenant)
+			}
+		})
+	}
+
+	enqueue := func(msg jetstream.Msg) {
+		tenant := ""
+		if cfg.TenantKey != nil {
+			// tenant keys are derived from the original payload, and an undecodable one
+			// falls back to the default tenant leaving handleMessage to report it
+			if payload, err := decodePayload(msg.Headers(), msg.Data()); err == nil {
+				tenant = cfg.TenantKey(n.ns.trim(msg.Subject()), payload)
+			}
+		}
+		// Queued messages must also be kept in progress, or NATS will redeliver them
+		progressCtx, cancel := context.WithCancel(ctx)
+		progressAcker := newInProgressAcker(msg, n.opts.inProgressInterval, false)
+		go func() {
+			_ = progressAcker.Run(progressCtx)
+		}()
+		q.push(tenant, queuedMsg{msg: msg, stopProgress: cancel})
+	}
+
+	stop := func() {
+		for _, item := range q.close() {
+			item.stopProgress()
+			_ = item.msg.Nak()
+		}
+		_ = g.Wait()
+	}
+
+	return enqueue, stop
+}
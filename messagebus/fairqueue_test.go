@@ -0,0 +1,99 @@
+package messagebus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func popN(q *fairQueue[int], n int) ([]string, []int) {
+	var tenants []string
+	var items []int
+	for range n {
+		tenant, item, _ := q.pop()
+		tenants = append(tenants, tenant)
+		items = append(items, item)
+		q.done(tenant)
+	}
+	return tenants, items
+}
+
+func TestFairQueueRoundRobin(t *testing.T) {
+	t.Parallel()
+
+	q := newFairQueue[int](FairScheduling{})
+	for i := range 4 {
+		q.push("a", i)
+	}
+	q.push("b", 10)
+	q.push("c", 20)
+	q.push("b", 11)
+
+	tenants, items := popN(q, 7)
+	assert.Equal(t, []string{"a", "b", "c", "a", "b", "a", "a"}, tenants)
+	assert.Equal(t, []int{0, 10, 20, 1, 11, 2, 3}, items)
+}
+
+func TestFairQueueWeights(t *testing.T) {
+	t.Parallel()
+
+	q := newFairQueue[int](FairScheduling{Weights: map[string]int{"a": 2}})
+	for i := range 4 {
+		q.push("a", i)
+		q.push("b", i)
+	}
+
+	tenants, _ := popN(q, 8)
+	assert.Equal(t, []string{"a", "a", "b", "a", "a", "b", "b", "b"}, tenants)
+}
+
+func TestFairQueueMaxInFlightPerTenant(t *testing.T) {
+	t.Parallel()
+
+	q := newFairQueue[int](FairScheduling{MaxInFlightPerTenant: 1, Weights: map[string]int{"a": 5}})
+	q.push("a", 1)
+	q.push("a", 2)
+	q.push("b", 3)
+
+	// "a" is limited to one in flight, so "b" is dispatched next despite the weight of "a"
+	tenant, _, _ := q.pop()
+	assert.Equal(t, "a", tenant)
+	tenant, _, _ = q.pop()
+	assert.Equal(t, "b", tenant)
+
+	q.done("a")
+	tenant, item, _ := q.pop()
+	assert.Equal(t, "a", tenant)
+	assert.Equal(t, 2, item)
+}
+
+func TestFairQueueClose(t *testing.T) {
+	t.Parallel()
+
+	q := newFairQueue[int](FairScheduling{})
+	q.push("a", 1)
+	popN(q, 1)
+
+	// pop blocks until an item is available or the queue is closed
+	result := make(chan bool)
+	go func() {
+		_, _, ok := q.pop()
+		result <- ok
+	}()
+
+	q.push("b", 2)
+	assert.True(t, <-result)
+
+	go func() {
+		_, _, ok := q.pop()
+		result <- ok
+	}()
+	assert.Empty(t, q.close())
+	assert.False(t, <-result)
+
+	// items not yet dispatched are returned on close
+	q = newFairQueue[int](FairScheduling{})
+	q.push("a", 1)
+	q.push("b", 2)
+	assert.ElementsMatch(t, []int{1, 2}, q.close())
+}
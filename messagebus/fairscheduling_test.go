@@ -0,0 +1,106 @@
+package messagebus_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/calm/errgroup"
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+)
+
+type tenantHandler struct {
+	mu       sync.Mutex
+	tenants  []string
+	expected int
+	done     chan struct{}
+}
+
+func (h *tenantHandler) HandleMessage(_ context.Context, _ sampleMessage, subject string, _ jetstream.MsgMetadata) error {
+	// simulate work so that the backlog builds up
+	time.Sleep(time.Millisecond * 5)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tenants = append(h.tenants, strings.TrimPrefix(subject, "xyzzy."))
+	if len(h.tenants) == h.expected {
+		close(h.done)
+	}
+	return nil
+}
+
+func TestFairScheduling(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+	js := getJetStream(t, nc)
+
+	b, err := json.Marshal(sampleMessages[0])
+	require.NoError(t, err)
+
+	// tenant "a" has a large backlog before tenant "b" produces anything
+	const backlog = 20
+	for range backlog {
+		_, err := js.Publish(t.Context(), "xyzzy.a", b)
+		require.NoError(t, err)
+	}
+	for range 2 {
+		_, err := js.Publish(t.Context(), "xyzzy.b", b)
+		require.NoError(t, err)
+	}
+
+	cfg, err := config.NewConfigurationFromMap(
+		map[string]any{
+			"subject": "xyzzy.>",
+			"stream":  "XYZZY",
+		},
+	)
+	require.NoError(t, err)
+
+	handler := &tenantHandler{expected: backlog + 2, done: make(chan struct{})}
+	consumer, err := messagebus.NewNatsStreamConsumer(cfg, "", handler,
+		messagebus.WithNATSConnection(nc),
+		messagebus.WithFairScheduling(messagebus.FairScheduling{
+			TenantKey: func(subject string, _ []byte) string {
+				return strings.TrimPrefix(subject, "xyzzy.")
+			},
+		}),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second*10)
+	t.Cleanup(cancel)
+	group, _ := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		err := consumer.Run(ctx)
+		cancel()
+		return err
+	})
+
+	select {
+	case <-handler.done:
+		cancel()
+	case <-ctx.Done():
+	}
+	require.NoError(t, group.Wait())
+
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	require.Len(t, handler.tenants, backlog+2)
+
+	// tenant "b" is not starved by the backlog of tenant "a"
+	var positions []int
+	for i, tenant := range handler.tenants {
+		if tenant == "b" {
+			positions = append(positions, i)
+		}
+	}
+	require.Len(t, positions, 2)
+	assert.Less(t, positions[1], 6, handler.tenants)
+}
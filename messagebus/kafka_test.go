@@ -0,0 +1,271 @@
+package messagebus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/http/requestid"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+var errKafkaTest = errors.New("kafka test error")
+
+type kafkaSample struct {
+	ID int `json:"id"`
+}
+
+// fakeKafkaReader serves the given messages, then blocks until the context is done.
+type fakeKafkaReader struct {
+	mu        sync.Mutex
+	messages  []kafka.Message
+	committed []int64
+	closed    bool
+}
+
+func (r *fakeKafkaReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	r.mu.Lock()
+	if len(r.messages) > 0 {
+		msg := r.messages[0]
+		r.messages = r.messages[1:]
+		r.mu.Unlock()
+		return msg, nil
+	}
+	r.mu.Unlock()
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (r *fakeKafkaReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, msg := range msgs {
+		r.committed = append(r.committed, msg.Offset)
+	}
+	return nil
+}
+
+func (r *fakeKafkaReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return nil
+}
+
+func (r *fakeKafkaReader) commits() []int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.committed
+}
+
+type fakeKafkaWriter struct {
+	messages []kafka.Message
+}
+
+func (w *fakeKafkaWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func (w *fakeKafkaWriter) Close() error {
+	return nil
+}
+
+// kafkaHandlerFunc adapts a function to the ConsumerHandler interface.
+type kafkaHandlerFunc func(ctx context.Context, data kafkaSample, meta jetstream.MsgMetadata) error
+
+func (f kafkaHandlerFunc) HandleMessage(ctx context.Context, data kafkaSample, _ string, meta jetstream.MsgMetadata) error {
+	return f(ctx, data, meta)
+}
+
+func kafkaMessage(t *testing.T, offset int64, data any) kafka.Message {
+	t.Helper()
+	b, err := json.Marshal(data)
+	require.NoError(t, err)
+	return kafka.Message{Topic: "topic", Offset: offset, Value: b}
+}
+
+func runKafkaConsumer(t *testing.T, reader *fakeKafkaReader, handler kafkaHandlerFunc) *KafkaStreamConsumer[kafkaSample] {
+	t.Helper()
+	consumer := newKafkaStreamConsumer[kafkaSample](
+		kafkaStreamConsumerConfig{Topic: "topic", Group: "group"},
+		reader, handler, parseOptions(nil),
+	)
+	consumer.sleep = func(context.Context, time.Duration) {}
+	return consumer
+}
+
+func TestKafkaStreamConsumer(t *testing.T) {
+	t.Parallel()
+
+	reader := &fakeKafkaReader{messages: []kafka.Message{
+		kafkaMessage(t, 0, kafkaSample{ID: 0}),
+		kafkaMessage(t, 1, "not a sample"),
+		kafkaMessage(t, 2, kafkaSample{ID: 2}),
+		kafkaMessage(t, 3, kafkaSample{ID: 3}),
+	}}
+	reader.messages[0].Headers = []kafka.Header{{Key: requestid.Header, Value: []byte("abc")}}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	var handled []int
+	var attempts []uint64
+	var requestID string
+	consumer := runKafkaConsumer(t, reader, func(ctx context.Context, data kafkaSample, meta jetstream.MsgMetadata) error {
+		handled = append(handled, data.ID)
+		assert.Equal(t, "topic", meta.Stream)
+		assert.Equal(t, "group", meta.Consumer)
+		switch data.ID {
+		case 0:
+			requestID, _ = requestid.FromContext(ctx)
+		case 2:
+			// transient errors are retried in place
+			attempts = append(attempts, meta.NumDelivered)
+			if meta.NumDelivered < 3 {
+				return errclass.WrapAs(errKafkaTest, errclass.Transient)
+			}
+		case 3:
+			// persistent errors are skipped
+			cancel()
+			return errclass.WrapAs(errKafkaTest, errclass.Persistent)
+		}
+		return nil
+	})
+
+	require.NoError(t, consumer.Run(ctx))
+	assert.Equal(t, []int{0, 2, 2, 2, 3}, handled)
+	assert.Equal(t, []uint64{1, 2, 3}, attempts)
+	assert.Equal(t, "abc", requestID)
+	// the message which failed to unmarshal is skipped, and the last commit survives cancellation
+	assert.Equal(t, []int64{0, 1, 2, 3}, reader.commits())
+	assert.True(t, reader.closed)
+}
+
+func TestKafkaStreamConsumerStopWhileRetrying(t *testing.T) {
+	t.Parallel()
+
+	reader := &fakeKafkaReader{messages: []kafka.Message{kafkaMessage(t, 7, kafkaSample{ID: 7})}}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	consumer := runKafkaConsumer(t, reader, func(context.Context, kafkaSample, jetstream.MsgMetadata) error {
+		cancel()
+		return errKafkaTest
+	})
+
+	// the message is left uncommitted to be redelivered
+	require.NoError(t, consumer.Run(ctx))
+	assert.Empty(t, reader.commits())
+}
+
+func TestKafkaStreamProducer(t *testing.T) {
+	t.Parallel()
+
+	writer := &fakeKafkaWriter{}
+	tag := func(next ProduceFunc[kafkaSample]) ProduceFunc[kafkaSample] {
+		return func(ctx context.Context, data kafkaSample, msg *nats.Msg) error {
+			msg.Header.Set("X-Tag", "tagged")
+			return next(ctx, data, msg)
+		}
+	}
+	producer, err := newKafkaStreamProducer[kafkaSample](
+		kafkaStreamProducerConfig{Topic: "topic"},
+		writer,
+		parseOptions([]Option{WithProducerInterceptor(tag)}),
+	)
+	require.NoError(t, err)
+	producer.SetKey(func(kafkaSample) string { return "key" })
+
+	ctx := requestid.NewContext(t.Context(), "abc")
+	require.NoError(t, producer.Produce(ctx, kafkaSample{ID: 42}))
+
+	require.Len(t, writer.messages, 1)
+	msg := writer.messages[0]
+	assert.Equal(t, []byte("key"), msg.Key)
+	assert.JSONEq(t, `{"id":42}`, string(msg.Value))
+	header := natsHeader(msg.Headers)
+	assert.Equal(t, "abc", header.Get(requestid.Header))
+	assert.Equal(t, "tagged", header.Get("X-Tag"))
+}
+
+func TestKafkaConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		kafka   map[string]any
+		wantErr error
+	}{
+		{
+			name:  "plain",
+			kafka: map[string]any{"brokers": []string{"localhost:9092"}, "mechanism": "plain"},
+		},
+		{
+			name:  "scram",
+			kafka: map[string]any{"brokers": []string{"localhost:9092"}, "mechanism": "SCRAM-SHA-512", "username": "u", "password": "p"},
+		},
+		{
+			name:    "no brokers",
+			kafka:   map[string]any{},
+			wantErr: ErrNoBrokers,
+		},
+		{
+			name:    "unsupported mechanism",
+			kafka:   map[string]any{"brokers": []string{"localhost:9092"}, "mechanism": "gssapi"},
+			wantErr: ErrUnsupportedMechanism,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := config.NewConfigurationFromMap(map[string]any{
+				"kafka":    tc.kafka,
+				"consumer": map[string]any{"topic": "topic", "group": "group"},
+			})
+			require.NoError(t, err)
+
+			consumer, err := NewKafkaStreamConsumer[kafkaSample](cfg, "consumer", kafkaHandlerFunc(nil))
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				assert.Equal(t, errclass.Persistent, errclass.GetClass(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "kafka-stream-consumer (topic/group)", consumer.Name())
+			require.NoError(t, consumer.reader.Close())
+		})
+	}
+}
+
+func TestKafkaConsumerGroup(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := config.NewConfigurationFromMap(map[string]any{
+		"kafka":    map[string]any{"brokers": []string{"localhost:9092"}},
+		"consumer": map[string]any{"topic": "topic"},
+	})
+	require.NoError(t, err)
+
+	// a group is required, either in config or as the durable queue
+	_, err = NewKafkaStreamConsumer[kafkaSample](cfg, "consumer", kafkaHandlerFunc(nil))
+	require.ErrorIs(t, err, ErrNoGroup)
+	assert.Equal(t, errclass.Persistent, errclass.GetClass(err))
+
+	consumer, err := NewKafkaStreamConsumer[kafkaSample](cfg, "consumer", kafkaHandlerFunc(nil), WithDurableQueue("queue"))
+	require.NoError(t, err)
+	assert.Equal(t, "kafka-stream-consumer (topic/queue)", consumer.Name())
+	require.NoError(t, consumer.reader.Close())
+}
@@ -0,0 +1,124 @@
+package messagebus
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const (
+	kafkaConfigPath = "kafka"
+)
+
+var (
+	ErrNoTopic              = errors.New("must provide a topic")
+	ErrNoGroup              = errors.New("kafka: must provide a consumer group")
+	ErrNoBrokers            = errors.New("kafka: must provide at least one broker")
+	ErrUnsupportedMechanism = errors.New("kafka: unsupported SASL mechanism")
+)
+
+type kafkaCommonConfig struct {
+	Brokers  []string
+	ClientID string `koanf:"clientid"`
+	TLS      bool
+	// Mechanism optionally enables SASL authentication: one of "plain", "scram-sha-256" or "scram-sha-512".
+	Mechanism string
+	Username  string
+	Password  string
+}
+
+// kafkaConnection holds what is needed to connect to the brokers.
+type kafkaConnection struct {
+	brokers   []string
+	clientID  string
+	tls       *tls.Config
+	mechanism sasl.Mechanism
+}
+
+// newKafkaConnection parses the kafka connection config at the configured path.
+func newKafkaConnection(cfg *config.Configuration, options options) (*kafkaConnection, error) {
+	kafkaConfig := kafkaCommonConfig{}
+	if cfg != nil {
+		if err := cfg.Unmarshal(options.kafkaConnectionConfigPath, &kafkaConfig); err != nil {
+			return nil, stacktrace.Wrap(err)
+		}
+	}
+	if len(kafkaConfig.Brokers) == 0 {
+		return nil, errclass.WrapAs(stacktrace.Wrap(ErrNoBrokers), errclass.Persistent)
+	}
+
+	conn := &kafkaConnection{
+		brokers:  kafkaConfig.Brokers,
+		clientID: kafkaConfig.ClientID,
+	}
+	if kafkaConfig.TLS {
+		conn.tls = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	var err error
+	switch strings.ToLower(kafkaConfig.Mechanism) {
+	case "":
+	case "plain":
+		conn.mechanism = plain.Mechanism{Username: kafkaConfig.Username, Password: kafkaConfig.Password}
+	case "scram-sha-256":
+		conn.mechanism, err = scram.Mechanism(scram.SHA256, kafkaConfig.Username, kafkaConfig.Password)
+	case "scram-sha-512":
+		conn.mechanism, err = scram.Mechanism(scram.SHA512, kafkaConfig.Username, kafkaConfig.Password)
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnsupportedMechanism, kafkaConfig.Mechanism)
+	}
+	if err != nil {
+		return nil, errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+	}
+	return conn, nil
+}
+
+func (c *kafkaConnection) dialer() *kafka.Dialer {
+	return &kafka.Dialer{
+		ClientID:      c.clientID,
+		DualStack:     true,
+		TLS:           c.tls,
+		SASLMechanism: c.mechanism,
+		Timeout:       kafka.DefaultDialer.Timeout,
+	}
+}
+
+func (c *kafkaConnection) transport() *kafka.Transport {
+	return &kafka.Transport{
+		ClientID: c.clientID,
+		TLS:      c.tls,
+		SASL:     c.mechanism,
+	}
+}
+
+// natsHeader converts kafka headers to nats headers, such that the same header handling
+// (eg content encoding and request IDs) applies to both.
+func natsHeader(headers []kafka.Header) nats.Header {
+	header := make(nats.Header, len(headers))
+	for _, h := range headers {
+		header.Add(h.Key, string(h.Value))
+	}
+	return header
+}
+
+// kafkaHeaders is the inverse of natsHeader.
+func kafkaHeaders(header nats.Header) []kafka.Header {
+	headers := make([]kafka.Header, 0, len(header))
+	for key, values := range header {
+		for _, value := range values {
+			headers = append(headers, kafka.Header{Key: key, Value: []byte(value)})
+		}
+	}
+	return headers
+}
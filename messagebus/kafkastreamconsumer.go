@@ -0,0 +1,228 @@
+package messagebus
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/zircuit-labs/zkr-go-common/calm"
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/http/requestid"
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// kafkaCommitTimeout limits committing an offset, which is not cancelled on shutdown
+// to avoid redelivering messages which were already handled.
+const kafkaCommitTimeout = 10 * time.Second
+
+// required config for a kafka consumer
+type kafkaStreamConsumerConfig struct {
+	Topic string
+	// Group is the consumer group, used to load-balance partitions between instances
+	// that share the same name, and to track committed offsets.
+	Group string
+}
+
+// kafkaReader is the subset of kafka.Reader used by the consumer.
+type kafkaReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// KafkaStreamConsumer is a Task which consumes messages from a Kafka topic,
+// passing them to the same ConsumerHandler as used by NatsStreamConsumer.
+//
+// The metadata given to the handler is populated from the Kafka message: Stream is the topic,
+// Consumer is the group, Sequence.Stream is the offset within the partition, Timestamp is
+// the message time, and NumDelivered is the attempt number.
+type KafkaStreamConsumer[T any] struct {
+	config  kafkaStreamConsumerConfig
+	reader  kafkaReader
+	handler ConsumerHandler[T]
+	opts    options
+	sleep   func(ctx context.Context, d time.Duration)
+}
+
+// NewKafkaStreamConsumer creates a new KafkaStreamConsumer
+func NewKafkaStreamConsumer[T any](cfg *config.Configuration, cfgPath string, handler ConsumerHandler[T], opts ...Option) (*KafkaStreamConsumer[T], error) {
+	options := parseOptions(opts)
+
+	consumerConfig := kafkaStreamConsumerConfig{}
+	if err := cfg.Unmarshal(cfgPath, &consumerConfig); err != nil {
+		return nil, stacktrace.Wrap(err)
+	}
+	if consumerConfig.Topic == "" {
+		return nil, stacktrace.Wrap(ErrNoTopic)
+	}
+	if options.durableQueue != "" {
+		consumerConfig.Group = options.durableQueue
+	}
+	if consumerConfig.Group == "" {
+		// without a group there are no committed offsets, and so no at-least-once delivery
+		return nil, errclass.WrapAs(stacktrace.Wrap(ErrNoGroup), errclass.Persistent)
+	}
+
+	middleware, err := middlewareFor[ConsumerMiddleware[T]](options.consumerMiddleware)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := newKafkaConnection(cfg, options)
+	if err != nil {
+		return nil, err
+	}
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: conn.brokers,
+		GroupID: consumerConfig.Group,
+		Topic:   consumerConfig.Topic,
+		Dialer:  conn.dialer(),
+	})
+
+	return newKafkaStreamConsumer(consumerConfig, reader, wrapHandler(handler, middleware), options), nil
+}
+
+func newKafkaStreamConsumer[T any](cfg kafkaStreamConsumerConfig, reader kafkaReader, handler ConsumerHandler[T], options options) *KafkaStreamConsumer[T] {
+	return &KafkaStreamConsumer[T]{
+		config:  cfg,
+		reader:  reader,
+		handler: handler,
+		opts:    options,
+		sleep:   sleepContext,
+	}
+}
+
+// Name returns the name of this task
+func (k *KafkaStreamConsumer[T]) Name() string {
+	return fmt.Sprintf("kafka-stream-consumer (%s/%s)", k.config.Topic, k.config.Group)
+}
+
+// Run consumes messages from Kafka and passes them to the handler
+// Messages are handled one at a time, and the offset is committed once handling is complete.
+func (k *KafkaStreamConsumer[T]) Run(ctx context.Context) error {
+	defer k.reader.Close()
+
+	for {
+		msg, err := k.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			// The reader reconnects by itself, so a failure here is unexpected.
+			return stacktrace.Wrap(err)
+		}
+
+		if !k.handleMessage(ctx, msg) {
+			// Stopped before the message was handled; it is redelivered once consumption resumes.
+			return nil
+		}
+
+		if err := k.commit(ctx, msg); err != nil {
+			return err
+		}
+	}
+}
+
+// commit commits the offset of the handled message, even if the context is done meanwhile.
+func (k *KafkaStreamConsumer[T]) commit(ctx context.Context, msg kafka.Message) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), kafkaCommitTimeout)
+	defer cancel()
+	if err := k.reader.CommitMessages(ctx, msg); err != nil {
+		return stacktrace.Wrap(err)
+	}
+	return nil
+}
+
+// handleMessage calls the handler until the message is handled or skipped, returning true,
+// or the context is done, returning false.
+// As Kafka has no equivalent of NAK, Transient (or unclassified) errors are retried in place
+// with the same backoff as NatsStreamConsumer, which blocks the partition meanwhile.
+func (k *KafkaStreamConsumer[T]) handleMessage(ctx context.Context, msg kafka.Message) bool {
+	logger := k.opts.logger.With(
+		slog.String("task", k.Name()),
+		slog.Int("partition", msg.Partition),
+		slog.Int64("offset", msg.Offset),
+	)
+
+	header := natsHeader(msg.Headers)
+	if id := header.Get(requestid.Header); id != "" {
+		ctx = requestid.NewContext(ctx, id)
+		logger = logger.With(slog.String(requestid.LogKey, id))
+	}
+	// Surface any replay provenance to the handler
+	if r, ok := ReplayFromHeader(header); ok {
+		ctx = NewReplayContext(ctx, r)
+		logger = logger.With(slog.Bool("replay", true))
+	}
+
+	var data T
+	if err := k.opts.unmarshal(header, msg.Value, &data); err != nil {
+		logger.Error("failed to unmarshal data - skipping", log.ErrAttr(err),
+			slog.String("comment", "This should never happen, and a human needs to investigate how and why it did."))
+		return true
+	}
+
+	meta := jetstream.MsgMetadata{
+		Sequence:  jetstream.SequencePair{Stream: uint64(max(msg.Offset, 0))},
+		Stream:    msg.Topic,
+		Consumer:  k.config.Group,
+		Timestamp: msg.Time,
+	}
+	for attempt := uint64(1); ; attempt++ {
+		meta.NumDelivered = attempt
+		spanCtx, span := k.opts.startConsumeSpan(ctx, header, "kafka", msg.Topic,
+			attribute.String("messaging.consumer.group.name", k.config.Group),
+			attribute.String("messaging.destination.partition.id", strconv.Itoa(msg.Partition)),
+			attribute.Int64("messaging.kafka.offset", msg.Offset),
+			attribute.Int64("messaging.delivery_attempt", int64(attempt)),
+		)
+		err := calm.Unpanic(func() error {
+			return k.handler.HandleMessage(spanCtx, data, msg.Topic, meta)
+		})
+		endConsumeSpan(span, err)
+
+		switch errclass.GetClass(err) {
+		case errclass.Nil:
+			return true
+		case errclass.Persistent, errclass.Panic:
+			if ctx.Err() == nil {
+				logger.Error("failed to handle message - skipping", log.ErrAttr(err),
+					slog.String("comment", "This indicates that a message is lost, and a human needs to investigate."))
+			}
+			return true
+		}
+
+		if ctx.Err() != nil {
+			return false
+		}
+		delay := CalculateNakDelay(&meta)
+		if attempt < 10 {
+			logger.Warn("failed to handle message - will retry", log.ErrAttr(err), slog.Duration("delay", delay))
+		} else {
+			logger.Error("failed to handle message - will retry", log.ErrAttr(err), slog.Duration("delay", delay),
+				slog.String("comment", "This message has been retried at least 10 times. A human needs to investigate"))
+		}
+		k.sleep(ctx, delay)
+		if ctx.Err() != nil {
+			return false
+		}
+	}
+}
+
+// sleepContext sleeps for d or until the context is done.
+func sleepContext(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
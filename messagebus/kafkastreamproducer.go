@@ -0,0 +1,124 @@
+package messagebus
+
+import (
+	"context"
+	"slices"
+
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/http/requestid"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// required config for a kafka producer
+type kafkaStreamProducerConfig struct {
+	// Topic identifies where to produce messages to
+	Topic string
+}
+
+// kafkaWriter is the subset of kafka.Writer used by the producer.
+type kafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// KafkaStreamProducer produces messages to a Kafka topic.
+// Producer interceptors are given the message as a *nats.Msg, whose subject is the topic.
+type KafkaStreamProducer[T any] struct {
+	config       kafkaStreamProducerConfig
+	writer       kafkaWriter
+	opts         options
+	key          func(data T) string
+	interceptors []ProducerInterceptor[T]
+}
+
+// NewKafkaStreamProducer creates a new KafkaStreamProducer
+func NewKafkaStreamProducer[T any](cfg *config.Configuration, cfgPath string, opts ...Option) (*KafkaStreamProducer[T], error) {
+	options := parseOptions(opts)
+
+	producerConfig := kafkaStreamProducerConfig{}
+	if err := cfg.Unmarshal(cfgPath, &producerConfig); err != nil {
+		return nil, stacktrace.Wrap(err)
+	}
+	if producerConfig.Topic == "" {
+		return nil, stacktrace.Wrap(ErrNoTopic)
+	}
+
+	conn, err := newKafkaConnection(cfg, options)
+	if err != nil {
+		return nil, err
+	}
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(conn.brokers...),
+		Topic:        producerConfig.Topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		Transport:    conn.transport(),
+	}
+
+	return newKafkaStreamProducer[T](producerConfig, writer, options)
+}
+
+func newKafkaStreamProducer[T any](cfg kafkaStreamProducerConfig, writer kafkaWriter, options options) (*KafkaStreamProducer[T], error) {
+	interceptors, err := middlewareFor[ProducerInterceptor[T]](options.producerInterceptors)
+	if err != nil {
+		return nil, err
+	}
+	return &KafkaStreamProducer[T]{
+		config:       cfg,
+		writer:       writer,
+		opts:         options,
+		interceptors: interceptors,
+	}, nil
+}
+
+// SetKey allows for users to set the message key based on the input data.
+// Messages with the same key are produced to the same partition, and so are consumed in order.
+func (k *KafkaStreamProducer[T]) SetKey(f func(data T) string) {
+	k.key = f
+}
+
+// Produce sends the data to the topic
+func (k *KafkaStreamProducer[T]) Produce(ctx context.Context, data T) error {
+	msg := &nats.Msg{Subject: k.config.Topic, Header: nats.Header{}}
+	b, err := k.opts.marshal(msg.Header, data)
+	if err != nil {
+		return errclass.WrapAs(err, errclass.Persistent)
+	}
+	msg.Data, err = compressInto(msg.Header, b, k.opts.compressionThreshold)
+	if err != nil {
+		return err
+	}
+	// Propagate any request ID to consumers
+	if id, ok := requestid.FromContext(ctx); ok {
+		msg.Header.Set(requestid.Header, id)
+	}
+	// Propagate any replay provenance to consumers
+	if r, ok := ReplayFromContext(ctx); ok {
+		SetReplayHeaders(msg.Header, r)
+	}
+	// Propagate any trace context to consumers
+	k.opts.injectTrace(ctx, msg.Header)
+
+	send := func(ctx context.Context, data T, msg *nats.Msg) error {
+		kafkaMsg := kafka.Message{Value: msg.Data, Headers: kafkaHeaders(msg.Header)}
+		if k.key != nil {
+			kafkaMsg.Key = []byte(k.key(data))
+		}
+		return k.opts.retrier.Try(ctx, func() error {
+			return stacktrace.Wrap(k.writer.WriteMessages(ctx, kafkaMsg))
+		})
+	}
+	for _, interceptor := range slices.Backward(k.interceptors) {
+		send = interceptor(send)
+	}
+	return send(ctx, data, msg)
+}
+
+// Close flushes any pending messages and terminates the connections
+func (k *KafkaStreamProducer[T]) Close() {
+	_ = k.writer.Close()
+}
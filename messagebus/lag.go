@@ -0,0 +1,104 @@
+package messagebus
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+var ErrConsumerLagging = errors.New("consumer pending messages exceed threshold")
+
+// LagThreshold identifies a JetStream consumer, and the number of pending
+// (ie not yet delivered) messages above which it is considered too far behind.
+type LagThreshold struct {
+	Stream     string
+	Consumer   string
+	MaxPending uint64
+}
+
+// LagChecker is a readiness checker (see healthcheck.Probes) which fails when the NATS
+// connection is down, or when any of the configured consumers has too many pending messages.
+type LagChecker struct {
+	nc         *nats.Conn
+	js         jetstream.JetStream
+	thresholds []LagThreshold
+}
+
+// NewLagChecker creates a LagChecker for the given consumers.
+// The thresholds can be loaded from config, eg `cfg.Unmarshal("readiness.consumers", &thresholds)`.
+func NewLagChecker(nc *nats.Conn, thresholds ...LagThreshold) (*LagChecker, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, stacktrace.Wrap(err)
+	}
+	return &LagChecker{
+		nc:         nc,
+		js:         js,
+		thresholds: thresholds,
+	}, nil
+}
+
+// LagChecker returns a LagChecker for this consumer with the given threshold.
+func (n *NatsStreamConsumer[T]) LagChecker(maxPending uint64) *LagChecker {
+	info := n.consumer.CachedInfo()
+	return &LagChecker{
+		nc: n.nc,
+		js: n.js,
+		thresholds: []LagThreshold{{
+			Stream:     info.Stream,
+			Consumer:   info.Name,
+			MaxPending: maxPending,
+		}},
+	}
+}
+
+// HealthCheck implements healthcheck.Checker.
+func (c *LagChecker) HealthCheck(ctx context.Context) error {
+	if c.nc.Status() != nats.CONNECTED {
+		return errclass.WrapAs(stacktrace.Wrap(ErrNATSNotConnected), errclass.Transient)
+	}
+
+	var errs []error
+	for _, threshold := range c.thresholds {
+		if err := c.check(ctx, threshold); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *LagChecker) check(ctx context.Context, threshold LagThreshold) (err error) {
+	defer func() {
+		if err != nil {
+			err = errcontext.Add(err,
+				slog.String("stream", threshold.Stream),
+				slog.String("consumer", threshold.Consumer),
+			)
+		}
+	}()
+
+	consumer, err := c.js.Consumer(ctx, threshold.Stream, threshold.Consumer)
+	if err != nil {
+		return stacktrace.Wrap(err)
+	}
+	info, err := consumer.Info(ctx)
+	if err != nil {
+		return stacktrace.Wrap(err)
+	}
+
+	if info.NumPending > threshold.MaxPending {
+		return errcontext.Add(
+			errclass.WrapAs(stacktrace.Wrap(ErrConsumerLagging), errclass.Transient),
+			slog.Uint64("pending", info.NumPending),
+			slog.Uint64("max_pending", threshold.MaxPending),
+		)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package messagebus_test
+
+import (
+	"testing"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/http/echotask/healthcheck"
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+)
+
+func TestLagChecker(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+
+	cfg, err := config.NewConfigurationFromMap(
+		map[string]any{
+			"subject":      "wibble",
+			"stream":       "WIBBLE",
+			"durablequeue": "wibble",
+		},
+	)
+	require.NoError(t, err)
+
+	producer, err := messagebus.NewNatsStreamProducer[sampleMessage](cfg, "", messagebus.WithNATSConnection(nc))
+	require.NoError(t, err)
+	t.Cleanup(producer.Close)
+
+	handler := &streamConsumerHandler[sampleMessage]{Done: make(chan struct{})}
+	consumer, err := messagebus.NewNatsStreamConsumer(cfg, "", handler, messagebus.WithNATSConnection(nc))
+	require.NoError(t, err)
+
+	for _, m := range sampleMessages {
+		require.NoError(t, producer.Produce(t.Context(), m))
+	}
+	pending := uint64(len(sampleMessages))
+
+	// within the threshold
+	require.NoError(t, consumer.LagChecker(pending).HealthCheck(t.Context()))
+
+	// exceeding the threshold
+	err = consumer.LagChecker(pending - 1).HealthCheck(t.Context())
+	require.ErrorIs(t, err, messagebus.ErrConsumerLagging)
+
+	// by name, as readiness probes
+	lagging, err := messagebus.NewLagChecker(nc,
+		messagebus.LagThreshold{Stream: "WIBBLE", Consumer: "wibble", MaxPending: pending},
+		messagebus.LagThreshold{Stream: "WIBBLE", Consumer: "wibble", MaxPending: 0},
+	)
+	require.NoError(t, err)
+	probes := healthcheck.NewProbes()
+	probes.MarkStarted()
+	probes.AddReadinessCheck("nats", lagging)
+	resp, ready := probes.Ready(t.Context())
+	assert.False(t, ready)
+	assert.Equal(t, "failed", resp.Checks["nats"])
+
+	// unknown consumers are not ready
+	missing, err := messagebus.NewLagChecker(nc, messagebus.LagThreshold{Stream: "WIBBLE", Consumer: "missing"})
+	require.NoError(t, err)
+	require.ErrorIs(t, missing.HealthCheck(t.Context()), jetstream.ErrConsumerNotFound)
+
+	// nor is a closed connection
+	closed := getNatsConnection(t)
+	closedChecker, err := messagebus.NewLagChecker(closed, messagebus.LagThreshold{Stream: "WIBBLE", Consumer: "wibble", MaxPending: pending})
+	require.NoError(t, err)
+	closed.Close()
+	require.ErrorIs(t, closedChecker.HealthCheck(t.Context()), messagebus.ErrNATSNotConnected)
+}
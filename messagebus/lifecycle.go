@@ -0,0 +1,111 @@
+package messagebus
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/zircuit-labs/zkr-go-common/log"
+)
+
+// ConsumerState is a lifecycle transition of a stream consumer.
+type ConsumerState string
+
+const (
+	ConsumerCreated      ConsumerState = "created"      // the JetStream consumer was created or updated
+	ConsumerConsuming    ConsumerState = "consuming"    // messages are being consumed for the first time
+	ConsumerReconnecting ConsumerState = "reconnecting" // consuming failed with a recoverable error, and will be retried
+	ConsumerRecovered    ConsumerState = "recovered"    // messages are being consumed again after reconnecting
+	ConsumerStopped      ConsumerState = "stopped"      // the consumer has stopped, see StopReason
+)
+
+// StopReason explains why a consumer stopped.
+type StopReason string
+
+const (
+	StopShutdown         StopReason = "shutdown"          // the context was cancelled
+	StopError            StopReason = "error"             // consuming failed with an unrecoverable error
+	StopRetriesExhausted StopReason = "retries_exhausted" // consuming kept failing with recoverable errors
+)
+
+// ConsumerEvent describes a lifecycle transition of a stream consumer.
+type ConsumerEvent struct {
+	State         ConsumerState
+	Time          time.Time
+	Stream        string
+	Durable       string
+	FilterSubject string // or filter subjects separated by commas
+	// Attempt is the number of the reconnection, for ConsumerReconnecting and ConsumerRecovered,
+	// or of reconnections made when stopped with StopRetriesExhausted.
+	Attempt int
+	// Reason is set for ConsumerStopped.
+	Reason StopReason
+	// Err is the cause of ConsumerReconnecting, or of ConsumerStopped unless shut down.
+	Err error
+}
+
+// LogValue implements slog.LogValuer for ConsumerEvent.
+func (e ConsumerEvent) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("state", string(e.State)),
+		slog.String("stream", e.Stream),
+		slog.String("durable", e.Durable),
+		slog.String("filter_subject", e.FilterSubject),
+	}
+	if e.Attempt > 0 {
+		attrs = append(attrs, slog.Int("attempt", e.Attempt))
+	}
+	if e.Reason != "" {
+		attrs = append(attrs, slog.String("reason", string(e.Reason)))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// WithConsumerEvents calls f with each lifecycle event of a stream consumer, in addition to logging it,
+// eg to count reconnections. f is called synchronously, so must not block.
+func WithConsumerEvents(f func(ConsumerEvent)) Option {
+	return func(options *options) {
+		options.consumerEvents = f
+	}
+}
+
+// emit logs the lifecycle event and passes it to the callback, if any.
+func (n *NatsStreamConsumer[T]) emit(ctx context.Context, state ConsumerState, attempt int, reason StopReason, err error) {
+	event := ConsumerEvent{
+		State:   state,
+		Time:    time.Now(),
+		Attempt: attempt,
+		Reason:  reason,
+		Err:     err,
+	}
+	if info := n.consumer.CachedInfo(); info != nil {
+		event.Stream = info.Stream
+		event.Durable = info.Config.Durable
+		event.FilterSubject = consumerFilter(info.Config)
+	}
+
+	level := slog.LevelInfo
+	if state == ConsumerReconnecting || (state == ConsumerStopped && reason != StopShutdown) {
+		level = slog.LevelWarn
+	}
+	attrs := []slog.Attr{slog.Any("consumer", event), slog.String("task", n.Name())}
+	if err != nil {
+		attrs = append(attrs, log.ErrAttr(err))
+	}
+	n.opts.logger.LogAttrs(ctx, level, "consumer "+string(state), attrs...)
+
+	if n.opts.consumerEvents != nil {
+		n.opts.consumerEvents(event)
+	}
+}
+
+// consumerFilter returns the filter subject of the consumer, or its filter subjects separated by commas.
+func consumerFilter(cfg jetstream.ConsumerConfig) string {
+	if cfg.FilterSubject == "" {
+		return strings.Join(cfg.FilterSubjects, ",")
+	}
+	return cfg.FilterSubject
+}
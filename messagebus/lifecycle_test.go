@@ -0,0 +1,123 @@
+package messagebus_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+)
+
+// eventRecorder collects consumer lifecycle events.
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []messagebus.ConsumerEvent
+}
+
+func (r *eventRecorder) record(e messagebus.ConsumerEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *eventRecorder) states() []messagebus.ConsumerState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	states := make([]messagebus.ConsumerState, 0, len(r.events))
+	for _, e := range r.events {
+		states = append(states, e.State)
+	}
+	return states
+}
+
+func (r *eventRecorder) last() messagebus.ConsumerEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.events[len(r.events)-1]
+}
+
+func TestConsumerLifecycleEvents(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+	js := getJetStream(t, nc)
+
+	tests := []struct {
+		name    string
+		durable string
+		stop    func(cancel context.CancelFunc) error
+		reason  messagebus.StopReason
+	}{
+		{
+			name:    "shutdown",
+			durable: "cycle-shutdown",
+			stop: func(cancel context.CancelFunc) error {
+				cancel()
+				return nil
+			},
+			reason: messagebus.StopShutdown,
+		},
+		{
+			name:    "consumer deleted",
+			durable: "cycle-deleted",
+			stop: func(context.CancelFunc) error {
+				return js.DeleteConsumer(t.Context(), "CYCLE", "cycle-deleted")
+			},
+			reason: messagebus.StopError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			cfg, err := config.NewConfigurationFromMap(map[string]any{
+				"subject":      "cycle.events",
+				"stream":       "CYCLE",
+				"durablequeue": tc.durable,
+			})
+			require.NoError(t, err)
+
+			recorder := &eventRecorder{}
+			handler := &streamConsumerHandler[sampleMessage]{}
+			consumer, err := messagebus.NewNatsStreamConsumer(cfg, "", handler,
+				messagebus.WithNATSConnection(nc),
+				messagebus.WithConsumerEvents(recorder.record),
+			)
+			require.NoError(t, err)
+			assert.Equal(t, []messagebus.ConsumerState{messagebus.ConsumerCreated}, recorder.states())
+			created := recorder.last()
+			assert.Equal(t, "CYCLE", created.Stream)
+			assert.Equal(t, tc.durable, created.Durable)
+			assert.Equal(t, "cycle.events", created.FilterSubject)
+
+			ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+			defer cancel()
+			done := make(chan error, 1)
+			go func() { done <- consumer.Run(ctx) }()
+
+			require.Eventually(t, func() bool { return len(recorder.states()) == 2 }, 5*time.Second, 10*time.Millisecond)
+			assert.Equal(t, messagebus.ConsumerConsuming, recorder.last().State)
+
+			require.NoError(t, tc.stop(cancel))
+			select {
+			case err := <-done:
+				if tc.reason == messagebus.StopShutdown {
+					require.NoError(t, err)
+				} else {
+					require.Error(t, err)
+				}
+			case <-time.After(5 * time.Second):
+				require.FailNow(t, "consumer did not stop")
+			}
+
+			stopped := recorder.last()
+			assert.Equal(t, messagebus.ConsumerStopped, stopped.State)
+			assert.Equal(t, tc.reason, stopped.Reason)
+			assert.Equal(t, tc.durable, stopped.Durable)
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package messagebus
+
+import (
+	"time"
+
+	"github.com/zircuit-labs/zkr-go-common/metrics"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+// Settlement actions used as the "action" label.
+const (
+	actionAck = "ack"
+	actionNak = "nak"
+)
+
+// WithMetrics records messages consumed and produced, handler latency, acks and naks,
+// redeliveries and unmarshal failures with metrics created by the registry.
+// Metrics are labeled by stream and consumer (or subject for producers), so several
+// consumers and producers may share the same registry.
+func WithMetrics(registry *metrics.Registry) Option {
+	return func(options *options) {
+		options.metrics = registry
+	}
+}
+
+type messageMetrics struct {
+	consumed    *metrics.Counter
+	redelivered *metrics.Counter
+	unmarshal   *metrics.Counter
+	duration    *metrics.Timer
+	settled     *metrics.Counter
+	produced    *metrics.Counter
+}
+
+func newMessageMetrics(registry *metrics.Registry) (*messageMetrics, error) {
+	consumerLabels := []string{"system", "stream", "consumer"}
+	consumed, err := registry.Counter("messagebus_messages_consumed_total",
+		"Number of messages received by consumers, including redeliveries.", consumerLabels...)
+	if err != nil {
+		return nil, err
+	}
+	redelivered, err := registry.Counter("messagebus_messages_redelivered_total",
+		"Number of messages received by consumers which had been delivered before.", consumerLabels...)
+	if err != nil {
+		return nil, err
+	}
+	unmarshal, err := registry.Counter("messagebus_unmarshal_failures_total",
+		"Number of messages skipped by consumers because they could not be unmarshaled.", consumerLabels...)
+	if err != nil {
+		return nil, err
+	}
+	duration, err := registry.Timer("messagebus_handle_duration_seconds",
+		"Latency of handling each message by error class, where nil means it was handled successfully.", append(consumerLabels, "class")...)
+	if err != nil {
+		return nil, err
+	}
+	settled, err := registry.Counter("messagebus_messages_settled_total",
+		"Number of handled messages acked or naked (to be redelivered) by consumers.", append(consumerLabels, "action")...)
+	if err != nil {
+		return nil, err
+	}
+	produced, err := registry.Counter("messagebus_messages_produced_total",
+		"Number of messages produced by error class, where nil means it was acknowledged.", "system", "subject", "class")
+	if err != nil {
+		return nil, err
+	}
+	return &messageMetrics{
+		consumed:    consumed,
+		redelivered: redelivered,
+		unmarshal:   unmarshal,
+		duration:    duration,
+		settled:     settled,
+		produced:    produced,
+	}, nil
+}
+
+// consumerMetrics records the metrics of one consumer.
+type consumerMetrics struct {
+	metrics *messageMetrics
+	labels  []string
+}
+
+// newConsumerMetrics creates the metrics if enabled, returning nil otherwise.
+// The labels are set by label once the consumer has been created.
+func newConsumerMetrics(registry *metrics.Registry) (*consumerMetrics, error) {
+	if registry == nil {
+		return nil, nil
+	}
+	m, err := newMessageMetrics(registry)
+	if err != nil {
+		return nil, err
+	}
+	return &consumerMetrics{metrics: m}, nil
+}
+
+// producerMetrics records the metrics of one producer.
+type producerMetrics struct {
+	metrics *messageMetrics
+	system  string
+	subject string
+}
+
+// newProducerMetrics registers the metrics if enabled, returning nil otherwise.
+func newProducerMetrics(registry *metrics.Registry, system, subject string) (*producerMetrics, error) {
+	if registry == nil {
+		return nil, nil
+	}
+	m, err := newMessageMetrics(registry)
+	if err != nil {
+		return nil, err
+	}
+	return &producerMetrics{metrics: m, system: system, subject: subject}, nil
+}
+
+// The following are no-ops when metrics are not enabled.
+
+func (m *consumerMetrics) label(system, stream, consumer string) {
+	if m != nil {
+		m.labels = []string{system, stream, consumer}
+	}
+}
+
+func (m *consumerMetrics) received(numDelivered uint64) {
+	if m == nil {
+		return
+	}
+	m.metrics.consumed.Inc(m.labels...)
+	if numDelivered > 1 {
+		m.metrics.redelivered.Inc(m.labels...)
+	}
+}
+
+func (m *consumerMetrics) unmarshalFailed() {
+	if m != nil {
+		m.metrics.unmarshal.Inc(m.labels...)
+	}
+}
+
+// handled records the latency of handling a message which started at start.
+func (m *consumerMetrics) handled(start time.Time, err error) {
+	if m != nil {
+		labels := append(m.labels[:len(m.labels):len(m.labels)], errclass.GetClass(err).String())
+		m.metrics.duration.Observe(time.Since(start), labels...)
+	}
+}
+
+func (m *consumerMetrics) settled(action string) {
+	if m != nil {
+		labels := append(m.labels[:len(m.labels):len(m.labels)], action)
+		m.metrics.settled.Inc(labels...)
+	}
+}
+
+func (m *producerMetrics) produced(err error) {
+	if m != nil {
+		m.metrics.produced.Inc(m.system, m.subject, errclass.GetClass(err).String())
+	}
+}
@@ -0,0 +1,145 @@
+package messagebus_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+	"github.com/zircuit-labs/zkr-go-common/metrics"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// retryOnceHandler fails the first delivery of each message with a transient error.
+type retryOnceHandler struct {
+	handled chan struct{}
+}
+
+func (h *retryOnceHandler) HandleMessage(_ context.Context, _ sampleMessage, _ string, meta jetstream.MsgMetadata) error {
+	if meta.NumDelivered == 1 {
+		return errclass.WrapAs(stacktrace.Wrap(errTraced), errclass.Transient)
+	}
+	h.handled <- struct{}{}
+	return nil
+}
+
+// metricValue returns the value of the counter, or the sample count of the histogram, with the labels.
+func metricValue(t *testing.T, registry *prometheus.Registry, name string, labels map[string]string) float64 {
+	t.Helper()
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			if matchLabels(m.GetLabel(), labels) {
+				if m.GetHistogram() != nil {
+					return float64(m.GetHistogram().GetSampleCount())
+				}
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+// matchLabels reports whether the pairs include all of the labels.
+func matchLabels(pairs []*dto.LabelPair, labels map[string]string) bool {
+	matched := 0
+	for _, pair := range pairs {
+		if value, ok := labels[pair.GetName()]; ok {
+			if value != pair.GetValue() {
+				return false
+			}
+			matched++
+		}
+	}
+	return matched == len(labels)
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+	js := getJetStream(t, nc)
+
+	registry := prometheus.NewRegistry()
+	opts := []messagebus.Option{
+		messagebus.WithNATSConnection(nc),
+		messagebus.WithMetrics(metrics.NewRegistry(metrics.WithRegisterer(registry), metrics.WithConstLabels(map[string]string{"team": "core"}))),
+	}
+	cfg, err := config.NewConfigurationFromMap(map[string]any{
+		"subject":      "cycle.metrics",
+		"stream":       "CYCLE",
+		"durablequeue": "cycle-metrics",
+	})
+	require.NoError(t, err)
+
+	producer, err := messagebus.NewNatsStreamProducer[sampleMessage](cfg, "", opts...)
+	require.NoError(t, err)
+	t.Cleanup(producer.Close)
+	require.NoError(t, producer.Produce(t.Context(), sampleMessages[0]))
+	_, err = js.Publish(t.Context(), "cycle.metrics", []byte("not json"))
+	require.NoError(t, err)
+
+	handler := &retryOnceHandler{handled: make(chan struct{}, 1)}
+	consumer, err := messagebus.NewNatsStreamConsumer(cfg, "", handler, opts...)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- consumer.Run(ctx) }()
+
+	select {
+	case <-handler.handled:
+	case <-ctx.Done():
+		require.FailNow(t, "message not handled")
+	}
+	labels := map[string]string{"system": "nats", "stream": "CYCLE", "consumer": "cycle-metrics", "team": "core"}
+	require.Eventually(t, func() bool {
+		return metricValue(t, registry, "messagebus_messages_settled_total", map[string]string{"consumer": "cycle-metrics", "action": "ack"}) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	assert.InDelta(t, 1, metricValue(t, registry, "messagebus_messages_produced_total",
+		map[string]string{"system": "nats", "subject": "cycle.metrics", "class": "nil"}), 0)
+
+	// the message was delivered twice, and the malformed one once
+	assert.InDelta(t, 3, metricValue(t, registry, "messagebus_messages_consumed_total", labels), 0)
+	assert.InDelta(t, 1, metricValue(t, registry, "messagebus_messages_redelivered_total", labels), 0)
+	assert.InDelta(t, 1, metricValue(t, registry, "messagebus_unmarshal_failures_total", labels), 0)
+
+	for class, count := range map[errclass.Class]float64{errclass.Transient: 1, errclass.Nil: 1} {
+		assert.InDelta(t, count, metricValue(t, registry, "messagebus_handle_duration_seconds",
+			map[string]string{"consumer": "cycle-metrics", "class": class.String()}), 0, class.String())
+	}
+	assert.InDelta(t, 1, metricValue(t, registry, "messagebus_messages_settled_total",
+		map[string]string{"consumer": "cycle-metrics", "action": "nak"}), 0)
+
+	// the metrics carry the standard labels of the registry
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			names := map[string]bool{}
+			for _, pair := range m.GetLabel() {
+				names[pair.GetName()] = true
+			}
+			assert.True(t, names[metrics.LabelService] && names[metrics.LabelInstance] && names[metrics.LabelVersion], family.GetName())
+		}
+	}
+
+	// metrics may be shared between consumers and producers
+	_, err = messagebus.NewNatsStreamProducer[sampleMessage](cfg, "", opts...)
+	require.NoError(t, err)
+}
@@ -0,0 +1,76 @@
+package messagebus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+var ErrMiddlewareType = errors.New("middleware does not match the message type")
+
+// ConsumerHandlerFunc allows a plain function to be used as a ConsumerHandler.
+type ConsumerHandlerFunc[T any] func(ctx context.Context, data T, subject string, metadata jetstream.MsgMetadata) error
+
+// HandleMessage implements ConsumerHandler.
+func (f ConsumerHandlerFunc[T]) HandleMessage(ctx context.Context, data T, subject string, metadata jetstream.MsgMetadata) error {
+	return f(ctx, data, subject, metadata)
+}
+
+// ConsumerMiddleware wraps a ConsumerHandler with cross-cutting behavior (eg metrics or validation).
+type ConsumerMiddleware[T any] func(next ConsumerHandler[T]) ConsumerHandler[T]
+
+// ProduceFunc sends a single encoded message. The data it was encoded from is given for reference.
+type ProduceFunc[T any] func(ctx context.Context, data T, msg *nats.Msg) error
+
+// ProducerInterceptor wraps the sending of each message by a producer.
+// Interceptors may inspect the data, modify the message (eg add headers), or reject it by returning an error.
+type ProducerInterceptor[T any] func(next ProduceFunc[T]) ProduceFunc[T]
+
+// WithConsumerMiddleware wraps the handler of a NatsStreamConsumer, such that the first middleware is outermost.
+// The type parameter must match that of the consumer.
+func WithConsumerMiddleware[T any](middleware ...ConsumerMiddleware[T]) Option {
+	return func(options *options) {
+		for _, m := range middleware {
+			options.consumerMiddleware = append(options.consumerMiddleware, m)
+		}
+	}
+}
+
+// WithProducerInterceptor wraps the sending of each message by a NatsStreamProducer,
+// such that the first interceptor is outermost. The type parameter must match that of the producer.
+// NOTE: For ProduceAsync and ProduceBatch, next returns once the message is sent rather than acknowledged.
+func WithProducerInterceptor[T any](interceptors ...ProducerInterceptor[T]) Option {
+	return func(options *options) {
+		for _, i := range interceptors {
+			options.producerInterceptors = append(options.producerInterceptors, i)
+		}
+	}
+}
+
+// middlewareFor asserts that the middleware set in options matches the type M.
+func middlewareFor[M any](middleware []any) ([]M, error) {
+	result := make([]M, 0, len(middleware))
+	for _, m := range middleware {
+		typed, ok := m.(M)
+		if !ok {
+			return nil, errclass.WrapAs(stacktrace.Wrap(fmt.Errorf("%w: got %T, want %T", ErrMiddlewareType, m, typed)), errclass.Persistent)
+		}
+		result = append(result, typed)
+	}
+	return result, nil
+}
+
+// wrapHandler applies the middleware to the handler, such that the first is outermost.
+func wrapHandler[T any](handler ConsumerHandler[T], middleware []ConsumerMiddleware[T]) ConsumerHandler[T] {
+	for _, m := range slices.Backward(middleware) {
+		handler = m(handler)
+	}
+	return handler
+}
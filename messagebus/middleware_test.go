@@ -0,0 +1,113 @@
+package messagebus_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+)
+
+var errRejected = errors.New("rejected")
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+
+	cfg, err := config.NewConfigurationFromMap(
+		map[string]any{
+			"subject":      "zot",
+			"stream":       "ZOT",
+			"durablequeue": "zot",
+		},
+	)
+	require.NoError(t, err)
+
+	// interceptors run in order, and can add headers or reject messages
+	var order []string
+	tag := func(name string) messagebus.ProducerInterceptor[sampleMessage] {
+		return func(next messagebus.ProduceFunc[sampleMessage]) messagebus.ProduceFunc[sampleMessage] {
+			return func(ctx context.Context, data sampleMessage, msg *nats.Msg) error {
+				order = append(order, name)
+				msg.Header.Set("X-Tag", name)
+				return next(ctx, data, msg)
+			}
+		}
+	}
+	reject := func(next messagebus.ProduceFunc[sampleMessage]) messagebus.ProduceFunc[sampleMessage] {
+		return func(ctx context.Context, data sampleMessage, msg *nats.Msg) error {
+			if data.Integer < 0 {
+				return errRejected
+			}
+			return next(ctx, data, msg)
+		}
+	}
+	producer, err := messagebus.NewNatsStreamProducer[sampleMessage](cfg, "",
+		messagebus.WithNATSConnection(nc),
+		messagebus.WithProducerInterceptor(tag("first"), tag("second"), reject),
+	)
+	require.NoError(t, err)
+	t.Cleanup(producer.Close)
+
+	require.NoError(t, producer.Produce(t.Context(), sampleMessages[0]))
+	require.ErrorIs(t, producer.Produce(t.Context(), sampleMessage{Integer: -1}), errRejected)
+	require.NoError(t, producer.ProduceAsync(t.Context(), sampleMessages[1]))
+	require.NoError(t, producer.Flush(t.Context()))
+	assert.Equal(t, []string{"first", "second", "first", "second", "first", "second"}, order)
+
+	// middleware wraps the handler
+	var subjects []string
+	record := func(next messagebus.ConsumerHandler[sampleMessage]) messagebus.ConsumerHandler[sampleMessage] {
+		return messagebus.ConsumerHandlerFunc[sampleMessage](func(ctx context.Context, data sampleMessage, subject string, metadata jetstream.MsgMetadata) error {
+			subjects = append(subjects, subject)
+			return next.HandleMessage(ctx, data, subject, metadata)
+		})
+	}
+	handler := &streamConsumerHandler[sampleMessage]{ExpectedMessages: 2, Done: make(chan struct{})}
+	consumer, err := messagebus.NewNatsStreamConsumer(cfg, "", handler,
+		messagebus.WithNATSConnection(nc),
+		messagebus.WithConsumerMiddleware(record),
+	)
+	require.NoError(t, err)
+	consumeN(t, consumer, handler)
+
+	assert.Equal(t, []string{"zot", "zot"}, subjects)
+	assert.Equal(t, sampleMessages[:2], handler.Messages)
+}
+
+func TestMiddlewareTypeMismatch(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+
+	cfg, err := config.NewConfigurationFromMap(
+		map[string]any{
+			"subject":      "zot",
+			"stream":       "ZOT",
+			"durablequeue": "zot-mismatch",
+		},
+	)
+	require.NoError(t, err)
+
+	_, err = messagebus.NewNatsStreamProducer[sampleMessage](cfg, "",
+		messagebus.WithNATSConnection(nc),
+		messagebus.WithProducerInterceptor(func(next messagebus.ProduceFunc[string]) messagebus.ProduceFunc[string] {
+			return next
+		}),
+	)
+	require.ErrorIs(t, err, messagebus.ErrMiddlewareType)
+
+	handler := &streamConsumerHandler[sampleMessage]{}
+	_, err = messagebus.NewNatsStreamConsumer(cfg, "", handler,
+		messagebus.WithNATSConnection(nc),
+		messagebus.WithConsumerMiddleware(func(next messagebus.ConsumerHandler[string]) messagebus.ConsumerHandler[string] {
+			return next
+		}),
+	)
+	require.ErrorIs(t, err, messagebus.ErrMiddlewareType)
+}
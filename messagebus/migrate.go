@@ -0,0 +1,208 @@
+package messagebus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const (
+	defaultCheckpointEvery = 100
+	// MigrationReason is the reason given in the replay headers of migrated messages (see Replay).
+	MigrationReason = "schema migration"
+)
+
+// ErrSkipMessage may be returned by the transform of a Migration to leave the message out of the target subject.
+var ErrSkipMessage = errors.New("message skipped")
+
+// Checkpointer records the progress of a Migration, such that it can be resumed (eg natskv.Checkpoint).
+type Checkpointer interface {
+	// LoadCheckpoint returns the stream sequence of the last message migrated, or 0 if there is none.
+	LoadCheckpoint(ctx context.Context) (uint64, error)
+	// SaveCheckpoint records the stream sequence of the last message migrated.
+	SaveCheckpoint(ctx context.Context, sequence uint64) error
+}
+
+// Migration describes the republishing of every message on a subject of a stream,
+// transformed from one type to another (eg after an incompatible change of message schema).
+type Migration[From, To any] struct {
+	// Stream holding the messages to migrate.
+	Stream string
+	// Subject of the messages to migrate, which may include wildcards.
+	Subject string
+	// Target is the subject to which migrated messages are published, which must be captured by a stream
+	// (but not by Subject).
+	Target string
+	// Transform converts each message. It may return ErrSkipMessage to leave the message out.
+	Transform func(ctx context.Context, from From) (To, error)
+	// Checkpoint, if set, records progress every CheckpointEvery messages (default 100) and once done,
+	// such that the migration resumes after the last checkpoint if run again.
+	Checkpoint      Checkpointer
+	CheckpointEvery int
+	// DryRun decodes, transforms and encodes every message without publishing anything or saving checkpoints,
+	// to check that the whole history can be migrated.
+	DryRun bool
+}
+
+// MigrationStats summarizes a migration.
+type MigrationStats struct {
+	Migrated uint64
+	Skipped  uint64
+	// LastSequence is the stream sequence of the last message migrated or skipped.
+	LastSequence uint64
+}
+
+// LogValue implements slog.LogValuer for MigrationStats.
+func (s MigrationStats) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Uint64("migrated", s.Migrated),
+		slog.Uint64("skipped", s.Skipped),
+		slog.Uint64("last_sequence", s.LastSequence),
+	)
+}
+
+// Migrate republishes every message of the migration, in order, returning once it has reached the end of the subject.
+// Messages are decoded with the codec given by their Content-Type header (or WithCodec), and encoded with WithCodec.
+// Migrated messages are stamped with the replay headers (see Replay), and with a message ID derived from the stream
+// and sequence of the original, so that any published again after resuming from a checkpoint are discarded
+// as duplicates by the target stream (within its duplicate window). Progress is logged at each checkpoint.
+// The stream and subjects are namespaced as per WithSubjectPrefix or WithEnvironmentPrefix.
+// Any failure stops the migration, returning the stats so far along with the error.
+func Migrate[From, To any](ctx context.Context, js jetstream.JetStream, cfg *config.Configuration, m Migration[From, To], opts ...Option) (MigrationStats, error) {
+	options := parseOptions(opts)
+	ns, err := newNamespace(cfg, options)
+	if err != nil {
+		return MigrationStats{}, err
+	}
+	stream, subject, target := ns.stream(m.Stream), ns.subject(m.Subject), ns.subject(m.Target)
+	every := m.CheckpointEvery
+	if every <= 0 {
+		every = defaultCheckpointEvery
+	}
+	logger := options.logger.With(
+		slog.String("stream", stream),
+		slog.String("subject", subject),
+		slog.String("target", target),
+		slog.Bool("dry_run", m.DryRun),
+	)
+
+	var stats MigrationStats
+	if m.Checkpoint != nil {
+		if stats.LastSequence, err = m.Checkpoint.LoadCheckpoint(ctx); err != nil {
+			return stats, stacktrace.Wrap(err)
+		}
+	}
+	checkpoint := func() error {
+		logger.Info("migration progress", slog.Any("stats", stats))
+		if m.Checkpoint == nil || m.DryRun {
+			return nil
+		}
+		return stacktrace.Wrap(m.Checkpoint.SaveCheckpoint(ctx, stats.LastSequence))
+	}
+
+	consumerConfig := jetstream.OrderedConsumerConfig{FilterSubjects: []string{subject}}
+	if stats.LastSequence > 0 {
+		consumerConfig.DeliverPolicy = jetstream.DeliverByStartSequencePolicy
+		consumerConfig.OptStartSeq = stats.LastSequence + 1
+	}
+	consumer, err := js.OrderedConsumer(ctx, stream, consumerConfig)
+	if err != nil {
+		return stats, errcontext.Add(stacktrace.Wrap(err), slog.String("stream", stream))
+	}
+	info, err := consumer.Info(ctx)
+	if err != nil {
+		return stats, stacktrace.Wrap(err)
+	}
+	logger.Info("migration starting", slog.Uint64("pending", info.NumPending), slog.Uint64("from_sequence", stats.LastSequence+1))
+
+	for pending := info.NumPending; pending > 0; {
+		msg, err := consumer.Next(jetstream.FetchContext(ctx))
+		if err != nil {
+			return stats, stacktrace.Wrap(err)
+		}
+		meta, err := msg.Metadata()
+		if err != nil {
+			return stats, stacktrace.Wrap(err)
+		}
+		pending = meta.NumPending
+
+		if err := migrateMessage(ctx, js, options, m, target, msg, meta); err != nil {
+			if !errors.Is(err, ErrSkipMessage) {
+				return stats, errcontext.Add(err, slog.Uint64("sequence", meta.Sequence.Stream))
+			}
+			stats.Skipped++
+		} else {
+			stats.Migrated++
+		}
+		stats.LastSequence = meta.Sequence.Stream
+
+		if (stats.Migrated+stats.Skipped)%uint64(every) == 0 {
+			if err := checkpoint(); err != nil {
+				return stats, err
+			}
+		}
+	}
+
+	if err := checkpoint(); err != nil {
+		return stats, err
+	}
+	logger.Info("migration complete", slog.Any("stats", stats))
+	return stats, nil
+}
+
+// migrateMessage decodes, transforms, encodes and (unless a dry run) publishes a single message.
+func migrateMessage[From, To any](
+	ctx context.Context,
+	js jetstream.JetStream,
+	options options,
+	m Migration[From, To],
+	target string,
+	msg jetstream.Msg,
+	meta *jetstream.MsgMetadata,
+) error {
+	var from From
+	if err := options.unmarshal(msg.Headers(), msg.Data(), &from); err != nil {
+		return errclass.WrapAs(err, errclass.Persistent)
+	}
+	to, err := m.Transform(ctx, from)
+	if err != nil {
+		return err
+	}
+
+	header := nats.Header{}
+	data, err := options.marshal(header, to)
+	if err != nil {
+		return errclass.WrapAs(err, errclass.Persistent)
+	}
+	if m.DryRun {
+		return nil
+	}
+
+	// Retain the provenance of messages which were themselves replayed (see Republish)
+	replay := Replay{
+		OriginalStream:    meta.Stream,
+		OriginalSequence:  meta.Sequence.Stream,
+		OriginalTimestamp: meta.Timestamp,
+		Reason:            MigrationReason,
+	}
+	if previous, ok := ReplayFromHeader(msg.Headers()); ok {
+		replay.OriginalStream = previous.OriginalStream
+		replay.OriginalSequence = previous.OriginalSequence
+		replay.OriginalTimestamp = previous.OriginalTimestamp
+	}
+	header.Set(jetstream.MsgIDHeader, fmt.Sprintf("%s:%d", meta.Stream, meta.Sequence.Stream))
+	SetReplayHeaders(header, replay)
+	if _, err := js.PublishMsg(ctx, &nats.Msg{Subject: target, Header: header, Data: data}); err != nil {
+		return errclass.WrapAs(stacktrace.Wrap(err), errclass.Transient)
+	}
+	return nil
+}
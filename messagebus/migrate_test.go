@@ -0,0 +1,163 @@
+package messagebus_test
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+)
+
+type orderV1 struct {
+	ID     string
+	Amount string // eg "12.50"
+}
+
+type orderV2 struct {
+	ID    string
+	Cents int
+}
+
+// memoryCheckpoint is a Checkpointer held in memory.
+type memoryCheckpoint struct {
+	mu       sync.Mutex
+	sequence uint64
+	saves    int
+}
+
+func (c *memoryCheckpoint) LoadCheckpoint(context.Context) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sequence, nil
+}
+
+func (c *memoryCheckpoint) SaveCheckpoint(_ context.Context, sequence uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sequence = sequence
+	c.saves++
+	return nil
+}
+
+func toV2(_ context.Context, from orderV1) (orderV2, error) {
+	if from.Amount == "" {
+		return orderV2{}, messagebus.ErrSkipMessage
+	}
+	whole, fraction, _ := strings.Cut(from.Amount, ".")
+	units, err := strconv.Atoi(whole + fraction)
+	if err != nil {
+		return orderV2{}, err
+	}
+	return orderV2{ID: from.ID, Cents: units}, nil
+}
+
+func TestMigrate(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+	js := getJetStream(t, nc)
+	ctx := t.Context()
+
+	publish := func(orders ...orderV1) {
+		for _, order := range orders {
+			b, err := json.Marshal(order)
+			require.NoError(t, err)
+			_, err = js.Publish(ctx, "migrate.v1", b)
+			require.NoError(t, err)
+		}
+	}
+	publish(orderV1{ID: "a", Amount: "1.25"}, orderV1{ID: "b"}, orderV1{ID: "c", Amount: "30.00"})
+
+	checkpoint := &memoryCheckpoint{}
+	migration := messagebus.Migration[orderV1, orderV2]{
+		Stream:          "MIGRATE",
+		Subject:         "migrate.v1",
+		Target:          "migrate.v2",
+		Transform:       toV2,
+		Checkpoint:      checkpoint,
+		CheckpointEvery: 2,
+	}
+
+	// a dry run publishes nothing and saves no checkpoint
+	dryRun := migration
+	dryRun.DryRun = true
+	stats, err := messagebus.Migrate(ctx, js, nil, dryRun)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), stats.Migrated)
+	assert.Equal(t, uint64(1), stats.Skipped)
+	assert.Zero(t, checkpoint.saves)
+
+	stats, err = messagebus.Migrate(ctx, js, nil, migration)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), stats.Migrated)
+	assert.Equal(t, uint64(1), stats.Skipped)
+	assert.Equal(t, stats.LastSequence, checkpoint.sequence)
+	assert.Equal(t, 2, checkpoint.saves)
+
+	// running again resumes after the checkpoint
+	publish(orderV1{ID: "d", Amount: "0.99"})
+	stats, err = messagebus.Migrate(ctx, js, nil, migration)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), stats.Migrated)
+	assert.Zero(t, stats.Skipped)
+
+	// the target holds the transformed messages, stamped as replays
+	reader, err := js.OrderedConsumer(ctx, "MIGRATE", jetstream.OrderedConsumerConfig{FilterSubjects: []string{"migrate.v2"}})
+	require.NoError(t, err)
+	var migrated []orderV2
+	for range 3 {
+		msg, err := reader.Next(jetstream.FetchMaxWait(5 * time.Second))
+		require.NoError(t, err)
+		var order orderV2
+		require.NoError(t, json.Unmarshal(msg.Data(), &order))
+		migrated = append(migrated, order)
+
+		replay, ok := messagebus.ReplayFromHeader(msg.Headers())
+		require.True(t, ok)
+		assert.Equal(t, messagebus.MigrationReason, replay.Reason)
+		assert.Equal(t, "MIGRATE", replay.OriginalStream)
+		assert.NotZero(t, replay.OriginalSequence)
+	}
+	assert.Equal(t, []orderV2{{ID: "a", Cents: 125}, {ID: "c", Cents: 3000}, {ID: "d", Cents: 99}}, migrated)
+
+	// migrating again from the start publishes only duplicates
+	stats, err = messagebus.Migrate(ctx, js, nil, messagebus.Migration[orderV1, orderV2]{
+		Stream:    "MIGRATE",
+		Subject:   "migrate.v1",
+		Target:    "migrate.v2",
+		Transform: toV2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), stats.Migrated)
+	info, err := reader.Info(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, info.NumPending)
+}
+
+func TestMigrateFailure(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+	js := getJetStream(t, nc)
+	ctx := t.Context()
+
+	_, err := js.Publish(ctx, "migrate.bad", []byte(`{"ID":"x","Amount":"lots"}`))
+	require.NoError(t, err)
+
+	checkpoint := &memoryCheckpoint{}
+	_, err = messagebus.Migrate(ctx, js, nil, messagebus.Migration[orderV1, orderV2]{
+		Stream:     "MIGRATE",
+		Subject:    "migrate.bad",
+		Target:     "migrate.bad.v2",
+		Transform:  toV2,
+		Checkpoint: checkpoint,
+	})
+	require.ErrorIs(t, err, strconv.ErrSyntax)
+	assert.Zero(t, checkpoint.saves)
+}
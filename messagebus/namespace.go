@@ -0,0 +1,112 @@
+package messagebus
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// ErrInvalidNamespace is returned when the subject prefix cannot be used within subjects and stream names.
+var ErrInvalidNamespace = errors.New("invalid subject prefix")
+
+// WithSubjectPrefix namespaces all subjects with the prefix (eg "staging" gives "staging.orders.created"),
+// and all stream names likewise (eg "staging_ORDERS"), so that environments sharing a NATS cluster
+// cannot consume each other's messages. It applies to producers, consumers, GetLastMessage
+// and CreateOrUpdateStream, which must all be given the same prefix.
+func WithSubjectPrefix(prefix string) Option {
+	return func(options *options) {
+		options.subjectPrefix = prefix
+		options.environmentPrefix = false
+	}
+}
+
+// WithEnvironmentPrefix is the same as WithSubjectPrefix, using the environment of the configuration.
+func WithEnvironmentPrefix() Option {
+	return func(options *options) {
+		options.subjectPrefix = ""
+		options.environmentPrefix = true
+	}
+}
+
+// namespace prefixes subjects and stream names. The zero value leaves them unchanged.
+type namespace string
+
+// newNamespace determines the namespace from the options.
+func newNamespace(cfg *config.Configuration, options options) (namespace, error) {
+	prefix := options.subjectPrefix
+	if options.environmentPrefix && cfg != nil {
+		prefix = cfg.Environment()
+	}
+	if prefix == "" {
+		return "", nil
+	}
+	// The prefix must be a single subject token which is also valid within a stream name
+	if strings.ContainsAny(prefix, ".*> \t\r\n/\\") {
+		return "", errcontext.Add(
+			errclass.WrapAs(stacktrace.Wrap(ErrInvalidNamespace), errclass.Persistent),
+			slog.String("prefix", prefix),
+		)
+	}
+	return namespace(prefix), nil
+}
+
+// subject returns the subject within the namespace.
+func (ns namespace) subject(subject string) string {
+	if ns == "" || subject == "" {
+		return subject
+	}
+	return string(ns) + "." + subject
+}
+
+// subjects returns the subjects within the namespace.
+func (ns namespace) subjects(subjects []string) []string {
+	if ns == "" || subjects == nil {
+		return subjects
+	}
+	prefixed := make([]string, len(subjects))
+	for i, subject := range subjects {
+		prefixed[i] = ns.subject(subject)
+	}
+	return prefixed
+}
+
+// trim returns the subject with the namespace removed, as it was given by the producer.
+func (ns namespace) trim(subject string) string {
+	if ns == "" {
+		return subject
+	}
+	return strings.TrimPrefix(subject, string(ns)+".")
+}
+
+// stream returns the stream name within the namespace.
+func (ns namespace) stream(name string) string {
+	if ns == "" || name == "" {
+		return name
+	}
+	return string(ns) + "_" + name
+}
+
+// CreateOrUpdateStream creates the stream, or updates it to match the config, with its name and subjects
+// namespaced as per WithSubjectPrefix or WithEnvironmentPrefix.
+func CreateOrUpdateStream(ctx context.Context, js jetstream.JetStream, cfg *config.Configuration, streamConfig jetstream.StreamConfig, opts ...Option) (jetstream.Stream, error) {
+	ns, err := newNamespace(cfg, parseOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	streamConfig.Name = ns.stream(streamConfig.Name)
+	streamConfig.Subjects = ns.subjects(streamConfig.Subjects)
+
+	stream, err := js.CreateOrUpdateStream(ctx, streamConfig)
+	if err != nil {
+		return nil, errcontext.Add(stacktrace.Wrap(err), slog.String("stream", streamConfig.Name))
+	}
+	return stream, nil
+}
@@ -0,0 +1,98 @@
+package messagebus_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+)
+
+func TestSubjectPrefix(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+	js := getJetStream(t, nc)
+
+	// the configuration from a map uses the default environment
+	streamName := "PLINTH_" + xid.New().String()
+	cfg, err := config.NewConfigurationFromMap(map[string]any{
+		"stream":       streamName,
+		"subject":      "plinth.orders",
+		"durablequeue": "plinth-orders",
+	})
+	require.NoError(t, err)
+	environments := map[string]messagebus.Option{
+		"default": messagebus.WithEnvironmentPrefix(),
+		"staging": messagebus.WithSubjectPrefix("staging"),
+	}
+
+	// each environment has its own stream
+	for env, prefix := range environments {
+		stream, err := messagebus.CreateOrUpdateStream(t.Context(), js, cfg, jetstream.StreamConfig{
+			Name:     streamName,
+			Subjects: []string{"plinth.>"},
+		}, prefix)
+		require.NoError(t, err)
+		info := stream.CachedInfo()
+		assert.Equal(t, env+"_"+streamName, info.Config.Name)
+		assert.Equal(t, []string{env + ".plinth.>"}, info.Config.Subjects)
+		t.Cleanup(func() {
+			_ = js.DeleteStream(context.Background(), info.Config.Name)
+		})
+	}
+
+	for env, prefix := range environments {
+		producer, err := messagebus.NewNatsStreamProducer[sampleMessage](cfg, "", messagebus.WithNATSConnection(nc), prefix)
+		require.NoError(t, err)
+		t.Cleanup(producer.Close)
+		ack, err := producer.ProduceWithAck(t.Context(), sampleMessage{Message: env})
+		require.NoError(t, err)
+		assert.Equal(t, env+"_"+streamName, ack.Stream)
+	}
+
+	// each environment only sees its own messages, with subjects as given to the producer
+	for env, prefix := range environments {
+		last, _, err := messagebus.GetLastMessage[sampleMessage](cfg, "", messagebus.WithNATSConnection(nc), prefix)
+		require.NoError(t, err)
+		assert.Equal(t, env, last.Message)
+
+		received := make(chan string, 2)
+		handler := messagebus.ConsumerHandlerFunc[sampleMessage](func(_ context.Context, data sampleMessage, subject string, _ jetstream.MsgMetadata) error {
+			assert.Equal(t, "plinth.orders", subject)
+			received <- data.Message
+			return nil
+		})
+		consumer, err := messagebus.NewNatsStreamConsumer(cfg, "", handler, messagebus.WithNATSConnection(nc), prefix)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(t.Context(), time.Second*10)
+		go func() {
+			_ = consumer.Run(ctx)
+		}()
+		select {
+		case name := <-received:
+			assert.Equal(t, env, name)
+		case <-ctx.Done():
+			require.FailNow(t, "message not handled")
+		}
+		cancel()
+	}
+}
+
+func TestSubjectPrefixInvalid(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+
+	cfg, err := config.NewConfigurationFromMap(map[string]any{"subject": "plinth.orders"})
+	require.NoError(t, err)
+	for _, prefix := range []string{"dev.eu", "dev*", "my env", ">"} {
+		_, err := messagebus.NewNatsStreamProducer[sampleMessage](cfg, "", messagebus.WithNATSConnection(nc), messagebus.WithSubjectPrefix(prefix))
+		require.ErrorIs(t, err, messagebus.ErrInvalidNamespace, prefix)
+	}
+}
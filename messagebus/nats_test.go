@@ -19,11 +19,28 @@ var (
 
 	// list of streams/subjects to create for tests
 	streams = map[string][]string{
-		"FOO":   {"foo"},
-		"BAZ":   {"baz"},
-		"QUX":   {"qux"},
-		"WALDO": {"waldo", "waldo.>"},
-		"CORGE": {"corge.>"},
+		"FOO":     {"foo"},
+		"BAZ":     {"baz"},
+		"QUX":     {"qux"},
+		"WALDO":   {"waldo", "waldo.>"},
+		"CORGE":   {"corge.>"},
+		"GRAULT":  {"grault"},
+		"GARPLY":  {"garply"},
+		"PLUGH":   {"plugh.>"},
+		"XYZZY":   {"xyzzy.>"},
+		"FRED":    {"fred.>"},
+		"WIBBLE":  {"wibble"},
+		"WOBBLE":  {"wobble"},
+		"PLONK":   {"plonk"},
+		"ZOT":     {"zot"},
+		"BLORP":   {"blorp"},
+		"FLOB":    {"flob.>"},
+		"THUD":    {"thud.>"},
+		"GRUNT":   {"grunt.>"},
+		"SPLAT":   {"splat.>"},
+		"POISON":  {"poison.>"},
+		"MIGRATE": {"migrate.>"},
+		"CYCLE":   {"cycle.>"},
 	}
 )
 
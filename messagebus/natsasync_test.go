@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/nats-io/nats.go/jetstream"
+	"github.com/rs/xid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/zircuit-labs/zkr-go-common/config"
 	"github.com/zircuit-labs/zkr-go-common/log"
 	"github.com/zircuit-labs/zkr-go-common/messagebus"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
 )
 
 var sampleMessages = []sampleMessage{
@@ -351,6 +353,101 @@ func TestConsumerSubjectTransform(t *testing.T) {
 	assert.Equal(t, []message{messages[2], messages[4], messages[5], messages[6]}, handlerv2.Messages)
 }
 
+func TestConsumerSubjectTransformValidation(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+	js := getJetStream(t, nc)
+
+	streamName := "TRANSFORM_" + xid.New().String()
+	_, err := js.CreateStream(t.Context(), jetstream.StreamConfig{
+		Name:     streamName,
+		Subjects: []string{"transform.v1.>", "transform.v2.*"},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = js.DeleteStream(context.Background(), streamName) })
+
+	cfg, err := config.NewConfigurationFromMap(map[string]any{
+		"subject":      "transform.<version>.*",
+		"stream":       streamName,
+		"durablequeue": "transform",
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name      string
+		transform map[string]string
+		filter    string
+		err       error
+	}{
+		{
+			name:      "replacement",
+			transform: map[string]string{"<version>": "v1"},
+			filter:    "transform.v1.*",
+		},
+		{
+			name:      "positional",
+			transform: map[string]string{"$2": "v2", "$3": "created"},
+			filter:    "transform.v2.created",
+		},
+		{
+			name:      "wildcard",
+			transform: map[string]string{"$2": "*"},
+			filter:    "transform.*.*",
+		},
+		{
+			name:      "not in stream",
+			transform: map[string]string{"<version>": "v3"},
+			err:       messagebus.ErrSubjectNotInStream,
+		},
+		{
+			name:      "empty token",
+			transform: map[string]string{"<version>": ""},
+			err:       messagebus.ErrInvalidSubjectTransform,
+		},
+		{
+			name:      "full wildcard not last",
+			transform: map[string]string{"$2": ">"},
+			err:       messagebus.ErrInvalidSubjectTransform,
+		},
+		{
+			name:      "partial wildcard",
+			transform: map[string]string{"<version>": "v*"},
+			err:       messagebus.ErrInvalidSubjectTransform,
+		},
+		{
+			name:      "position out of range",
+			transform: map[string]string{"$4": "v1"},
+			err:       messagebus.ErrInvalidSubjectTransform,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := messagebus.NewNatsStreamConsumer[sampleMessage](
+				cfg,
+				"",
+				&streamConsumerHandler[sampleMessage]{},
+				messagebus.WithNATSConnection(nc),
+				messagebus.WithConsumerSubjectTransform(tc.transform),
+			)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				assert.Equal(t, errclass.Persistent, errclass.GetClass(err))
+				return
+			}
+			require.NoError(t, err)
+
+			stream, err := js.Stream(t.Context(), streamName)
+			require.NoError(t, err)
+			var filters []string
+			for info := range stream.ListConsumers(t.Context()).Info() {
+				filters = append(filters, info.Config.FilterSubject)
+			}
+			assert.Contains(t, filters, tc.filter)
+		})
+	}
+}
+
 func TestNatsGetLastMessage(t *testing.T) {
 	t.Parallel()
 	nc := getNatsConnection(t)
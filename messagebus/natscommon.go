@@ -2,16 +2,18 @@ package messagebus
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"time"
 
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/zircuit-labs/zkr-go-common/config"
 	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/metrics"
 	"github.com/zircuit-labs/zkr-go-common/retry"
 	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
 )
@@ -94,33 +96,71 @@ type Retrier interface {
 	Try(ctx context.Context, f func() error) error
 }
 
+// Producer produces messages, regardless of the backend.
+// It is implemented by both NatsStreamProducer and KafkaStreamProducer.
+type Producer[T any] interface {
+	Produce(ctx context.Context, data T) error
+}
+
 type options struct {
-	logger                   *slog.Logger
-	marshaler                MarshalFn
-	unmarshaler              UnmarshalFn
-	retrier                  Retrier
-	inProgressInterval       time.Duration
-	consumerConfig           *jetstream.ConsumerConfig
-	nc                       *nats.Conn
-	js                       jetstream.JetStream
-	natsConnectionConfigPath string
-	consumerSubjectTransform map[string]string
-	durableQueue             string
+	logger                    *slog.Logger
+	codec                     Codec
+	codecs                    []Codec
+	retrier                   Retrier
+	inProgressInterval        time.Duration
+	consumerConfig            *jetstream.ConsumerConfig
+	nc                        *nats.Conn
+	js                        jetstream.JetStream
+	natsConnectionConfigPath  string
+	kafkaConnectionConfigPath string
+	consumerSubjectTransform  map[string]string
+	durableQueue              string
+	deliverPolicy             *deliverPolicy
+	fairScheduling            *FairScheduling
+	maxConcurrency            int
+	batchSize                 int
+	batchTimeout              time.Duration
+	compressionThreshold      int
+	consumerMiddleware        []any
+	producerInterceptors      []any
+	dedup                     *deduplication
+	quarantine                *quarantine
+	subjectPrefix             string
+	environmentPrefix         bool
+	consumerEvents            func(ConsumerEvent)
+	tracerProvider            trace.TracerProvider
+	propagator                propagation.TextMapPropagator
+	metrics                   *metrics.Registry
+}
+
+// deliverPolicy determines where a new consumer starts in the stream.
+type deliverPolicy struct {
+	policy       jetstream.DeliverPolicy
+	optStartSeq  uint64
+	optStartTime *time.Time
+}
+
+func (d *deliverPolicy) apply(consumerConfig *jetstream.ConsumerConfig) {
+	consumerConfig.DeliverPolicy = d.policy
+	consumerConfig.OptStartSeq = d.optStartSeq
+	consumerConfig.OptStartTime = d.optStartTime
 }
 
 func parseOptions(opts []Option) options {
 	// Set up default options
 	defaultRetrier, _ := retry.NewRetrier(retry.WithMaxAttempts(10))
 	options := options{
-		logger:                   log.NewNilLogger(),
-		marshaler:                json.Marshal,
-		unmarshaler:              json.Unmarshal,
-		retrier:                  defaultRetrier,
-		inProgressInterval:       defaultInProgressInterval,
-		consumerConfig:           nil,
-		nc:                       nil,
-		js:                       nil,
-		natsConnectionConfigPath: natsConfigPath,
+		logger:                    log.NewNilLogger(),
+		codec:                     JSONCodec,
+		retrier:                   defaultRetrier,
+		inProgressInterval:        defaultInProgressInterval,
+		consumerConfig:            nil,
+		nc:                        nil,
+		js:                        nil,
+		natsConnectionConfigPath:  natsConfigPath,
+		kafkaConnectionConfigPath: kafkaConfigPath,
+		batchSize:                 defaultBatchSize,
+		batchTimeout:              defaultBatchTimeout,
 	}
 
 	// Apply provided options
@@ -141,14 +181,6 @@ func WithLogger(logger *slog.Logger) Option {
 	}
 }
 
-// WithDataSerialization sets an alternative method to serialize data.
-func WithDataSerialization(marshaler MarshalFn, unmarshaler UnmarshalFn) Option {
-	return func(options *options) {
-		options.marshaler = marshaler
-		options.unmarshaler = unmarshaler
-	}
-}
-
 // WithRetrier allows users to specify a retry mechanism to use.
 func WithRetrier(retrier Retrier) Option {
 	return func(options *options) {
@@ -188,7 +220,18 @@ func WithNATSConnectionConfigPath(configPath string) Option {
 	}
 }
 
+// WithKafkaConnectionConfigPath allows to set the cfgPath to the kafka connection config.
+func WithKafkaConnectionConfigPath(configPath string) Option {
+	return func(options *options) {
+		options.kafkaConnectionConfigPath = configPath
+	}
+}
+
 // WithConsumerSubjectTransform allows for transforming the subject before creating a consumer.
+// Keys of the form "$N" replace the Nth token of the subject (counting from 1), eg {"$2": "v1"} turns
+// "orders.<version>.*" into "orders.v1.*", while other keys are replaced wherever they appear.
+// The transformed subject must be valid and matched by the stream's subjects, otherwise
+// ErrInvalidSubjectTransform or ErrSubjectNotInStream is returned (as Persistent errors).
 func WithConsumerSubjectTransform(transform map[string]string) Option {
 	return func(options *options) {
 		options.consumerSubjectTransform = transform
@@ -201,3 +244,56 @@ func WithDurableQueue(queue string) Option {
 		options.durableQueue = queue
 	}
 }
+
+// WithDeliverByStartSequence starts a new consumer at the given stream sequence number.
+// NOTE: The deliver policy of an existing durable consumer cannot be changed,
+// so use a new durable queue name when changing the start position.
+func WithDeliverByStartSequence(seq uint64) Option {
+	return func(options *options) {
+		options.deliverPolicy = &deliverPolicy{policy: jetstream.DeliverByStartSequencePolicy, optStartSeq: seq}
+	}
+}
+
+// WithDeliverByStartTime starts a new consumer at the first message received at or after the given time.
+// NOTE: The deliver policy of an existing durable consumer cannot be changed,
+// so use a new durable queue name when changing the start position.
+func WithDeliverByStartTime(t time.Time) Option {
+	return func(options *options) {
+		options.deliverPolicy = &deliverPolicy{policy: jetstream.DeliverByStartTimePolicy, optStartTime: &t}
+	}
+}
+
+// WithDeliverNew starts a new consumer with messages produced after it is created.
+func WithDeliverNew() Option {
+	return func(options *options) {
+		options.deliverPolicy = &deliverPolicy{policy: jetstream.DeliverNewPolicy}
+	}
+}
+
+// WithDeliverLastPerSubject starts a new consumer with the last message for each subject in the stream.
+func WithDeliverLastPerSubject() Option {
+	return func(options *options) {
+		options.deliverPolicy = &deliverPolicy{policy: jetstream.DeliverLastPerSubjectPolicy}
+	}
+}
+
+// WithFairScheduling makes consumers dispatch messages using weighted-fair scheduling across tenants,
+// so that a backlog for one tenant cannot starve the others. Messages are queued per tenant and
+// handled round-robin by up to MaxInFlight concurrent calls to the handler.
+// NOTE: The handler must be safe for concurrent use when MaxInFlight is greater than 1.
+func WithFairScheduling(cfg FairScheduling) Option {
+	return func(options *options) {
+		options.fairScheduling = &cfg
+	}
+}
+
+// WithMaxConcurrency makes consumers handle up to n messages concurrently, each with its own
+// InProgress updates and ack, so that a slow message does not hold up those after it.
+// The limit is capped by the consumer's MaxAckPending. Ignored when combined with WithFairScheduling,
+// whose MaxInFlight applies instead.
+// NOTE: The handler must be safe for concurrent use, and messages may complete out of stream order.
+func WithMaxConcurrency(n int) Option {
+	return func(options *options) {
+		options.maxConcurrency = n
+	}
+}
@@ -23,10 +23,14 @@ func GetLastMessage[T any](cfg *config.Configuration, cfgPath string, opts ...Op
 	if err := cfg.Unmarshal(cfgPath, &streamConfig); err != nil {
 		return data, nil, stacktrace.Wrap(err)
 	}
+	ns, err := newNamespace(cfg, options)
+	if err != nil {
+		return data, nil, err
+	}
 
 	consumerConfig := jetstream.ConsumerConfig{
 		Description:   streamConfig.Description,
-		FilterSubject: streamConfig.Subject,
+		FilterSubject: ns.subject(streamConfig.Subject),
 		AckPolicy:     jetstream.AckNonePolicy,     // Don't require an ACK
 		DeliverPolicy: jetstream.DeliverLastPolicy, // Deliver the last message first
 	}
@@ -55,7 +59,7 @@ func GetLastMessage[T any](cfg *config.Configuration, cfgPath string, opts ...Op
 	}
 
 	// Create the consumer
-	consumer, err := js.CreateOrUpdateConsumer(context.Background(), streamConfig.Stream, consumerConfig)
+	consumer, err := js.CreateOrUpdateConsumer(context.Background(), ns.stream(streamConfig.Stream), consumerConfig)
 	if err != nil {
 		return data, nil, stacktrace.Wrap(err)
 	}
@@ -83,9 +87,9 @@ func GetLastMessage[T any](cfg *config.Configuration, cfgPath string, opts ...Op
 		return data, nil, stacktrace.Wrap(err)
 	}
 
-	// unmarshal the message data
-	if err := options.unmarshaler(msg.Data(), &data); err != nil {
-		return data, nil, stacktrace.Wrap(err)
+	// unmarshal the (possibly compressed) message data
+	if err := options.unmarshal(msg.Headers(), msg.Data(), &data); err != nil {
+		return data, nil, err
 	}
 
 	return data, metadata, nil
@@ -12,9 +12,11 @@ import (
 
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/zircuit-labs/zkr-go-common/calm/errgroup"
 	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/http/requestid"
 	"github.com/zircuit-labs/zkr-go-common/log"
 	"github.com/zircuit-labs/zkr-go-common/retry"
 	"github.com/zircuit-labs/zkr-go-common/retry/strategy"
@@ -61,6 +63,10 @@ type NatsStreamConsumer[T any] struct {
 	consumer      jetstream.Consumer
 	handler       ConsumerHandler[T]
 	opts          options
+	ns            namespace
+	dedupID       func(msg jetstream.Msg, data T) string
+	batchHandler  BatchConsumerHandler[T]
+	metrics       *consumerMetrics
 }
 
 // NewNatsStreamConsumer creates a new NatsStreamConsumer
@@ -71,6 +77,10 @@ func NewNatsStreamConsumer[T any](cfg *config.Configuration, cfgPath string, han
 	if err := cfg.Unmarshal(cfgPath, &streamConfig); err != nil {
 		return nil, stacktrace.Wrap(err)
 	}
+	ns, err := newNamespace(cfg, options)
+	if err != nil {
+		return nil, err
+	}
 
 	// Set up consumer config
 	var consumerConfig jetstream.ConsumerConfig
@@ -90,11 +100,19 @@ func NewNatsStreamConsumer[T any](cfg *config.Configuration, cfgPath string, han
 			consumerConfig.Durable = options.durableQueue
 		}
 
+		// Set the start position if provided
+		if options.deliverPolicy != nil {
+			options.deliverPolicy.apply(&consumerConfig)
+		}
+
 		// If a subject can change (ie there is a transform), then the consumer durable name should be unique to the subject.
 		// Otherwise a previous durable consumer could have skipped a message that the new consumer wants, but will never get.
 		// For this reason, also set the inactive threshold to 15 minutes so that old consumers are cleaned up.
 		if len(options.consumerSubjectTransform) > 0 {
-			consumerConfig.FilterSubject = transformSubject(consumerConfig.FilterSubject, options.consumerSubjectTransform)
+			consumerConfig.FilterSubject, err = transformSubject(consumerConfig.FilterSubject, options.consumerSubjectTransform)
+			if err != nil {
+				return nil, err
+			}
 			consumerConfig.InactiveThreshold = time.Minute * 15
 			if consumerConfig.Durable != "" {
 				// Names must not contain certain characters, therefore we cannot directly reference the subject.
@@ -104,9 +122,37 @@ func NewNatsStreamConsumer[T any](cfg *config.Configuration, cfgPath string, han
 		}
 	}
 
+	// Keep to the subjects of this namespace (regardless of where the consumer config came from)
+	consumerConfig.FilterSubject = ns.subject(consumerConfig.FilterSubject)
+	consumerConfig.FilterSubjects = ns.subjects(consumerConfig.FilterSubjects)
+
+	// Acking a message would also ack earlier messages which may still be in progress
+	if options.maxConcurrency > 1 && options.fairScheduling == nil && consumerConfig.AckPolicy == jetstream.AckAllPolicy {
+		return nil, errclass.WrapAs(stacktrace.Wrap(ErrConcurrentAckAll), errclass.Persistent)
+	}
+
+	middleware, err := middlewareFor[ConsumerMiddleware[T]](options.consumerMiddleware)
+	if err != nil {
+		return nil, err
+	}
+
+	dedupID, err := dedupIDFunc[T](options.dedup)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the metrics before connecting, so that a failure does not leave a connection open
+	metrics, err := newConsumerMetrics(options.metrics)
+	if err != nil {
+		return nil, err
+	}
+
 	natsStreamConsumer := &NatsStreamConsumer[T]{
-		handler: handler,
+		handler: wrapHandler(handler, middleware),
 		opts:    options,
+		ns:      ns,
+		dedupID: dedupID,
+		metrics: metrics,
 	}
 
 	if options.nc != nil && options.js != nil {
@@ -124,16 +170,34 @@ func NewNatsStreamConsumer[T any](cfg *config.Configuration, cfgPath string, han
 		natsStreamConsumer.js = js
 	}
 
+	// A transformed subject outside of the stream would silently never receive a message
+	if options.consumerConfig == nil && len(options.consumerSubjectTransform) > 0 {
+		if err := checkStreamSubjects(context.Background(), natsStreamConsumer.js, ns.stream(streamConfig.Stream), consumerConfig.FilterSubject); err != nil {
+			natsStreamConsumer.closeOwnConnection()
+			return nil, err
+		}
+	}
+
 	// Create the consumer
-	consumer, err := natsStreamConsumer.js.CreateOrUpdateConsumer(context.Background(), streamConfig.Stream, consumerConfig)
+	consumer, err := natsStreamConsumer.js.CreateOrUpdateConsumer(context.Background(), ns.stream(streamConfig.Stream), consumerConfig)
 	if err != nil {
+		natsStreamConsumer.closeOwnConnection()
 		return nil, stacktrace.Wrap(err)
 	}
 	natsStreamConsumer.consumer = consumer
+	natsStreamConsumer.metrics.label("nats", consumer.CachedInfo().Stream, consumer.CachedInfo().Config.Durable)
+	natsStreamConsumer.emit(context.Background(), ConsumerCreated, 0, "", nil)
 
 	return natsStreamConsumer, nil
 }
 
+// closeOwnConnection closes the NATS connection if it was one we made.
+func (n *NatsStreamConsumer[T]) closeOwnConnection() {
+	if n.shouldCloseNC {
+		n.nc.Close()
+	}
+}
+
 // HealthCheck returns an error if the NATS connection is not "connected".
 func (n *NatsStreamConsumer[T]) HealthCheck(ctx context.Context) error {
 	if n.nc.Status() != nats.CONNECTED {
@@ -168,23 +232,34 @@ func (n *NatsStreamConsumer[T]) Run(ctx context.Context) error {
 		return stacktrace.Wrap(err)
 	}
 
-	return retrier.Try(ctx, func() error {
-		err := n.consumeLoop(ctx)
+	reconnects := 0
+	err = retrier.Try(ctx, func() error {
+		err := n.consumeLoop(ctx, reconnects)
 		if err != nil {
 			if isRecoverableStreamError(err) {
-				n.opts.logger.Warn("Recoverable error occurred, will retry...",
-					log.ErrAttr(err),
-					slog.String("task", n.Name()),
-				)
+				reconnects++
+				n.emit(ctx, ConsumerReconnecting, reconnects, "", err)
 				return stacktrace.Wrap(err)
 			}
 			return errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
 		}
 		return nil
 	})
+
+	switch {
+	case err == nil || ctx.Err() != nil:
+		n.emit(ctx, ConsumerStopped, 0, StopShutdown, err)
+	case isRecoverableStreamError(err):
+		n.emit(ctx, ConsumerStopped, reconnects, StopRetriesExhausted, err)
+	default:
+		n.emit(ctx, ConsumerStopped, 0, StopError, err)
+	}
+	return err
 }
 
-func (n *NatsStreamConsumer[T]) consumeLoop(ctx context.Context) error {
+// consumeLoop consumes messages until the context is done or consuming fails.
+// reconnects is the number of times consuming has failed with a recoverable error so far.
+func (n *NatsStreamConsumer[T]) consumeLoop(ctx context.Context, reconnects int) error {
 	// Recreate consumer to ensure it's using current connection (important after reconnection)
 	consumerInfo := n.consumer.CachedInfo()
 	if consumerInfo == nil {
@@ -203,12 +278,27 @@ func (n *NatsStreamConsumer[T]) consumeLoop(ctx context.Context) error {
 
 	consumerErrChan := make(chan error, 1)
 
+	dispatch := func(msg jetstream.Msg) {
+		n.handleMessage(ctx, msg)
+	}
+	if n.batchHandler != nil {
+		enqueue, stop := n.startBatching(ctx)
+		defer stop()
+		dispatch = enqueue
+	} else if n.opts.fairScheduling != nil {
+		enqueue, stop := n.startFairScheduling(ctx)
+		defer stop()
+		dispatch = enqueue
+	} else if n.opts.maxConcurrency > 1 {
+		concurrent, stop := n.startConcurrentDispatch(ctx, n.concurrencyLimit(newConsumer.CachedInfo().Config))
+		defer stop()
+		dispatch = concurrent
+	}
+
 	// Handle messages
 	cc, err := n.consumer.Consume(
 		// handle consumer messages
-		func(msg jetstream.Msg) {
-			n.handleMessage(ctx, msg)
-		},
+		dispatch,
 		// handle consumer errors
 		jetstream.ConsumeErrHandler(func(cc jetstream.ConsumeContext, err error) {
 			// stop immediately to avoid causing further errors
@@ -236,6 +326,12 @@ func (n *NatsStreamConsumer[T]) consumeLoop(ctx context.Context) error {
 	}
 	defer cc.Stop()
 
+	if reconnects == 0 {
+		n.emit(ctx, ConsumerConsuming, 0, "", nil)
+	} else {
+		n.emit(ctx, ConsumerRecovered, reconnects, "", nil)
+	}
+
 	// Run until stopped or consumer error
 	select {
 	case <-ctx.Done():
@@ -259,25 +355,57 @@ func (n *NatsStreamConsumer[T]) handleMessage(ctx context.Context, msg jetstream
 		slog.Uint64("sequence_number", meta.Sequence.Stream),
 		slog.Uint64("delivery_attempt", meta.NumDelivered),
 	)
+	n.metrics.received(meta.NumDelivered)
+
+	// Continue any request ID propagated by the producer
+	if id := msg.Headers().Get(requestid.Header); id != "" {
+		ctx = requestid.NewContext(ctx, id)
+		logger = logger.With(slog.String(requestid.LogKey, id))
+	}
+	// Surface any replay provenance to the handler
+	if r, ok := ReplayFromHeader(msg.Headers()); ok {
+		ctx = NewReplayContext(ctx, r)
+		logger = logger.With(slog.Bool("replay", true))
+	}
 
 	var data T
-	err = n.opts.unmarshaler(msg.Data(), &data)
-	if err != nil {
+	if err := n.opts.unmarshal(msg.Headers(), msg.Data(), &data); err != nil {
 		// If we can't unmarshal the data, it's useless to us.
 		// Log a warning, and consider it otherwise handled.
+		n.metrics.unmarshalFailed()
 		logger.Error("failed to unmarshal data - skipping", log.ErrAttr(err),
 			slog.String("comment", "This should never happen, and a human needs to investigate how and why it did."))
 		return
 	}
 
+	// Skip messages which were already handled, but whose ack was lost
+	var dedupID string
+	if n.dedupID != nil {
+		dedupID = n.dedupID(msg, data)
+	}
+	if dedupID != "" && n.opts.dedup.isDuplicate(ctx, dedupID, logger) {
+		logger.Debug("skipping duplicate message", slog.String("dedup_id", dedupID))
+		if err := n.ack(msg); err != nil {
+			logger.Warn("failed to ack message", log.ErrAttr(err))
+		}
+		return
+	}
+
 	// The default `AckWait` for NATS consumers is 30 seconds.
 	// If the message is not acked within that time frame, it will be resent.
 	// Since we expect messages may take much longer to process than that,
 	// this block will send an InProgress message, which resets the AckWait countdown,
 	// at regular intervals while the message is being worked on.
-	progressAcker := newInProgressAcker(msg, n.opts.inProgressInterval)
+	progressAcker := newInProgressAcker(msg, n.opts.inProgressInterval, true)
+	ctx, span := n.opts.startConsumeSpan(ctx, msg.Headers(), "nats", n.ns.trim(msg.Subject()),
+		attribute.String("messaging.consumer.group.name", meta.Consumer),
+		attribute.String("messaging.nats.stream", meta.Stream),
+		attribute.Int64("messaging.nats.stream_sequence", int64(meta.Sequence.Stream)),
+		attribute.Int64("messaging.delivery_attempt", int64(meta.NumDelivered)),
+	)
 	innerCtx, cancel := context.WithCancel(ctx)
 	g := errgroup.New()
+	start := time.Now()
 
 	// Call the handler to deal with the message.
 	// Cancel the innerCtx when done in order to stop the progressAcker
@@ -289,7 +417,7 @@ func (n *NatsStreamConsumer[T]) handleMessage(ctx context.Context, msg jetstream
 		} else if metadata == nil {
 			return stacktrace.Wrap(errors.New("metadata is nil"))
 		}
-		return n.handler.HandleMessage(innerCtx, data, msg.Subject(), *metadata)
+		return n.handler.HandleMessage(innerCtx, data, n.ns.trim(msg.Subject()), *metadata)
 	})
 	// Meanwhile, run the progressAcker (always returns nil)
 	g.Go(func() error {
@@ -297,11 +425,28 @@ func (n *NatsStreamConsumer[T]) handleMessage(ctx context.Context, msg jetstream
 	})
 
 	err = g.Wait()
+	n.metrics.handled(start, err)
+	endConsumeSpan(span, err)
+	if errclass.GetClass(err) == errclass.Nil && dedupID != "" {
+		n.opts.dedup.markHandled(ctx, dedupID, logger)
+	}
+	n.settle(ctx, msg, meta, err, logger)
+}
+
+// settle acks or naks a handled message according to the class of its error.
+func (n *NatsStreamConsumer[T]) settle(ctx context.Context, msg jetstream.Msg, meta *jetstream.MsgMetadata, err error, logger *slog.Logger) {
 	var ackErr error
 	switch errclass.GetClass(err) {
 	case errclass.Nil:
-		ackErr = msg.Ack()
-	case errclass.Persistent, errclass.Panic:
+		ackErr = n.ack(msg)
+	case errclass.Panic:
+		// Retry or quarantine repeated panics, if enabled
+		if settled, settleErr := n.settlePanic(ctx, msg, meta, err, logger); settled {
+			ackErr = settleErr
+			break
+		}
+		fallthrough
+	case errclass.Persistent:
 		// Only log if the context is still active to avoid logging after test completion
 		select {
 		case <-ctx.Done():
@@ -310,10 +455,10 @@ func (n *NatsStreamConsumer[T]) handleMessage(ctx context.Context, msg jetstream
 			logger.Error("failed to handle message - skipping", log.ErrAttr(err),
 				slog.String("comment", "This indicates that a message is lost, and a human needs to investigate."))
 		}
-		ackErr = msg.Ack()
+		ackErr = n.ack(msg)
 	default: // errclass.Transient or error class was not explicitly set
 		delay := CalculateNakDelay(meta)
-		ackErr = msg.NakWithDelay(delay)
+		ackErr = n.nak(msg, delay)
 		// Only log if the context is still active
 		select {
 		case <-ctx.Done():
@@ -339,14 +484,28 @@ func (n *NatsStreamConsumer[T]) handleMessage(ctx context.Context, msg jetstream
 	}
 }
 
-func newInProgressAcker(msg jetstream.Msg, d time.Duration) *polling.Task {
+// ack acks a handled message, recording it in the metrics.
+func (n *NatsStreamConsumer[T]) ack(msg jetstream.Msg) error {
+	n.metrics.settled(actionAck)
+	return msg.Ack()
+}
+
+// nak naks a handled message to be redelivered after the delay, recording it in the metrics.
+func (n *NatsStreamConsumer[T]) nak(msg jetstream.Msg, delay time.Duration) error {
+	n.metrics.settled(actionNak)
+	return msg.NakWithDelay(delay)
+}
+
+func newInProgressAcker(msg jetstream.Msg, d time.Duration, runAtStart bool) *polling.Task {
 	action := inProgressAction{Msg: msg}
 	// NOTE: never include WithTerminateOnError option since we don't want
 	// a failure to send the InProgress message to result in a message handling error.
 	options := []polling.Option{
-		polling.WithRunAtStart(),
 		polling.WithInterval(d),
 	}
+	if runAtStart {
+		options = append(options, polling.WithRunAtStart())
+	}
 	return polling.NewTask("msg in progress acker", &action, options...)
 }
 
@@ -377,13 +536,6 @@ func CalculateNakDelay(meta *jetstream.MsgMetadata) time.Duration {
 	return maxNakDelay
 }
 
-func transformSubject(subject string, transform map[string]string) string {
-	for k, v := range transform {
-		subject = strings.ReplaceAll(subject, k, v)
-	}
-	return subject
-}
-
 func subjectHash(subject string) string {
 	hash := fnv.New64a()
 	hash.Write([]byte(subject))
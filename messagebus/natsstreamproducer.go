@@ -2,10 +2,16 @@ package messagebus
 
 import (
 	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
 
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
 	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/http/requestid"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
 	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
 )
 
@@ -22,7 +28,14 @@ type NatsStreamProducer[T any] struct {
 	shouldCloseNC    bool
 	js               jetstream.JetStream
 	opts             options
+	ns               namespace
 	subjectTransform func(data T, defaultSubject string) string
+	messageID        func(data T) string
+	interceptors     []ProducerInterceptor[T]
+	metrics          *producerMetrics
+
+	asyncMu      sync.Mutex
+	asyncPending []jetstream.PubAckFuture
 }
 
 func nilTransform[T any](_ T, defaultSubject string) string {
@@ -41,12 +54,26 @@ func NewNatsStreamProducer[T any](cfg *config.Configuration, cfgPath string, opt
 	if streamConfig.Subject == "" {
 		return nil, stacktrace.Wrap(ErrNoSubject)
 	}
+	ns, err := newNamespace(cfg, options)
+	if err != nil {
+		return nil, err
+	}
 
 	producer := NatsStreamProducer[T]{
 		config:           streamConfig,
 		opts:             options,
+		ns:               ns,
 		subjectTransform: nilTransform[T],
 	}
+	interceptors, err := middlewareFor[ProducerInterceptor[T]](options.producerInterceptors)
+	if err != nil {
+		return nil, err
+	}
+	producer.interceptors = interceptors
+	producer.metrics, err = newProducerMetrics(options.metrics, "nats", ns.subject(streamConfig.Subject))
+	if err != nil {
+		return nil, err
+	}
 
 	if options.nc != nil {
 		if options.js == nil {
@@ -70,27 +97,226 @@ func NewNatsStreamProducer[T any](cfg *config.Configuration, cfgPath string, opt
 }
 
 // SetSubjectTransform allows for users to set dynamic subjects on which to produce based on the input data.
+// Any subject prefix is applied to the result.
 func (n *NatsStreamProducer[T]) SetSubjectTransform(f func(data T, defaultSubject string) string) {
 	n.subjectTransform = f
 }
 
+// SetMessageID allows for users to set a message ID based on the input data.
+// The stream uses it to discard duplicate publishes within its duplicate window,
+// which are reported by the Duplicate field of the ack from ProduceWithAck.
+func (n *NatsStreamProducer[T]) SetMessageID(f func(data T) string) {
+	n.messageID = f
+}
+
 // Produce sends the data to the stream
 func (n *NatsStreamProducer[T]) Produce(ctx context.Context, data T) error {
-	b, err := n.opts.marshaler(&data)
+	_, err := n.ProduceWithAck(ctx, data)
+	return err
+}
+
+// ProduceWithAck sends the data to the stream as per Produce, returning the ack from the stream.
+// The ack identifies the stream and sequence number of the message, and whether it was a duplicate.
+func (n *NatsStreamProducer[T]) ProduceWithAck(ctx context.Context, data T) (*jetstream.PubAck, error) {
+	msg, err := n.newMsg(ctx, data)
 	if err != nil {
-		return stacktrace.Wrap(err)
+		return nil, err
 	}
+	var ack *jetstream.PubAck
+	err = n.intercept(func(ctx context.Context, _ T, msg *nats.Msg) (err error) {
+		ack, err = n.publish(ctx, msg)
+		return err
+	})(ctx, data, msg)
+	n.metrics.produced(err)
+	if err != nil {
+		return nil, err
+	}
+	return ack, nil
+}
 
-	err = n.opts.retrier.Try(ctx, func() error {
-		sub := n.subjectTransform(data, n.config.Subject)
-		_, err = n.js.Publish(ctx, sub, b)
+// ProduceBatch sends all of the data to the stream, publishing asynchronously
+// and then waiting for every ack, rather than paying a round trip per message.
+// Any message which fails is retried individually as per Produce.
+// NOTE: The batch is not atomic; on error some messages may have been published.
+func (n *NatsStreamProducer[T]) ProduceBatch(ctx context.Context, data []T) error {
+	msgs := make([]*nats.Msg, len(data))
+	for i, d := range data {
+		msg, err := n.newMsg(ctx, d)
+		if err != nil {
+			return errcontext.Add(err, slog.Int("index", i))
+		}
+		msgs[i] = msg
+	}
+
+	futures := make([]jetstream.PubAckFuture, len(msgs))
+	for i, msg := range msgs {
+		// a failure here (eg too many pending acks) is retried below
+		_ = n.intercept(func(_ context.Context, _ T, msg *nats.Msg) (err error) {
+			futures[i], err = n.js.PublishMsgAsync(msg)
+			return stacktrace.Wrap(err)
+		})(ctx, data[i], msg)
+	}
+
+	var errs []error
+	for i, future := range futures {
+		if future != nil {
+			if err := awaitAck(ctx, future); err == nil {
+				n.metrics.produced(nil)
+				continue
+			}
+		}
+		if ctx.Err() != nil {
+			return stacktrace.Wrap(ctx.Err())
+		}
+		_, err := n.publish(ctx, msgs[i])
+		n.metrics.produced(err)
+		if err != nil {
+			errs = append(errs, errcontext.Add(err, slog.Int("index", i)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ProduceAsync sends the data to the stream without waiting for the ack.
+// Use Flush to wait for all outstanding acks and learn of any failures.
+func (n *NatsStreamProducer[T]) ProduceAsync(ctx context.Context, data T) error {
+	msg, err := n.newMsg(ctx, data)
+	if err != nil {
+		return err
+	}
+	err = n.intercept(func(_ context.Context, _ T, msg *nats.Msg) error {
+		future, err := n.js.PublishMsgAsync(msg)
 		if err != nil {
 			return stacktrace.Wrap(err)
 		}
+
+		n.asyncMu.Lock()
+		defer n.asyncMu.Unlock()
+		n.asyncPending = append(n.asyncPending, future)
 		return nil
+	})(ctx, data, msg)
+	if err != nil {
+		// otherwise the outcome is recorded by Flush
+		n.metrics.produced(err)
+	}
+	return err
+}
+
+// Flush waits for the acks of all messages sent with ProduceAsync,
+// returning the (joined) errors of any which failed. Failed messages are not retried.
+// If the context is done first, the outstanding acks remain pending for a later Flush.
+func (n *NatsStreamProducer[T]) Flush(ctx context.Context) error {
+	n.asyncMu.Lock()
+	pending := n.asyncPending
+	n.asyncPending = nil
+	n.asyncMu.Unlock()
+
+	var errs []error
+	for i, future := range pending {
+		err := awaitAck(ctx, future)
+		if err != nil && ctx.Err() != nil {
+			n.asyncMu.Lock()
+			n.asyncPending = append(pending[i:], n.asyncPending...)
+			n.asyncMu.Unlock()
+			return stacktrace.Wrap(ctx.Err())
+		}
+		n.metrics.produced(err)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PendingAsync returns the number of messages sent with ProduceAsync which have not yet been flushed.
+func (n *NatsStreamProducer[T]) PendingAsync() int {
+	n.asyncMu.Lock()
+	defer n.asyncMu.Unlock()
+	return len(n.asyncPending)
+}
+
+// awaitAck waits for the publish to be acknowledged.
+func awaitAck(ctx context.Context, future jetstream.PubAckFuture) error {
+	select {
+	case <-future.Ok():
+		return nil
+	case err := <-future.Err():
+		return errcontext.Add(stacktrace.Wrap(err), slog.String("subject", future.Msg().Subject))
+	case <-ctx.Done():
+		return stacktrace.Wrap(ctx.Err())
+	}
+}
+
+// newMsg encodes the data as a message, including any headers.
+func (n *NatsStreamProducer[T]) newMsg(ctx context.Context, data T) (*nats.Msg, error) {
+	header := nats.Header{}
+	sub, b, err := n.encode(header, data)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &nats.Msg{Subject: sub, Header: header}
+	msg.Data, err = compressInto(msg.Header, b, n.opts.compressionThreshold)
+	if err != nil {
+		return nil, err
+	}
+	if n.messageID != nil {
+		if id := n.messageID(data); id != "" {
+			msg.Header.Set(jetstream.MsgIDHeader, id)
+		}
+	}
+	// Propagate any request ID to consumers
+	if id, ok := requestid.FromContext(ctx); ok {
+		msg.Header.Set(requestid.Header, id)
+	}
+	// Propagate any replay provenance to consumers
+	if r, ok := ReplayFromContext(ctx); ok {
+		SetReplayHeaders(msg.Header, r)
+	}
+	// Propagate any trace context to consumers
+	n.opts.injectTrace(ctx, msg.Header)
+	return msg, nil
+}
+
+// intercept applies the producer interceptors to send, such that the first is outermost.
+func (n *NatsStreamProducer[T]) intercept(send ProduceFunc[T]) ProduceFunc[T] {
+	for _, interceptor := range slices.Backward(n.interceptors) {
+		send = interceptor(send)
+	}
+	return send
+}
+
+// publish sends the message synchronously, retrying as configured.
+func (n *NatsStreamProducer[T]) publish(ctx context.Context, msg *nats.Msg) (ack *jetstream.PubAck, err error) {
+	defer func() {
+		if err != nil {
+			attrs := []slog.Attr{slog.String("subject", msg.Subject)}
+			if id := msg.Header.Get(jetstream.MsgIDHeader); id != "" {
+				attrs = append(attrs, slog.String("msg_id", id))
+			}
+			err = errcontext.Add(err, attrs...)
+		}
+	}()
+
+	err = n.opts.retrier.Try(ctx, func() error {
+		var publishErr error
+		ack, publishErr = n.js.PublishMsg(ctx, msg)
+		return stacktrace.Wrap(publishErr)
 	})
+	if err != nil {
+		return nil, err
+	}
+	return ack, nil
+}
 
-	return err
+// encode determines the subject and serialized payload for the data,
+// setting the content type in the header if given.
+func (n *NatsStreamProducer[T]) encode(header nats.Header, data T) (string, []byte, error) {
+	b, err := n.opts.marshal(header, &data)
+	if err != nil {
+		return "", nil, err
+	}
+	return n.ns.subject(n.subjectTransform(data, n.config.Subject)), b, nil
 }
 
 // Close terminates the connections
@@ -0,0 +1,99 @@
+package messagebus
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// Headers recording why and from where a message was quarantined.
+const (
+	QuarantineOriginalSubjectHeader  = "Quarantine-Original-Subject"
+	QuarantineOriginalStreamHeader   = "Quarantine-Original-Stream"
+	QuarantineOriginalSequenceHeader = "Quarantine-Original-Sequence"
+	QuarantineDeliveriesHeader       = "Quarantine-Deliveries"
+	QuarantineErrorHeader            = "Quarantine-Error"
+	// QuarantineStackHeader holds the stack trace of the panic as JSON (see stacktrace.StackTrace).
+	QuarantineStackHeader = "Quarantine-Stack"
+)
+
+// quarantine holds the options given to WithPanicQuarantine.
+type quarantine struct {
+	subject   string
+	threshold uint64
+}
+
+// WithPanicQuarantine diverts poison messages, which repeatedly cause the handler of a NatsStreamConsumer to panic,
+// to the given subject, so that they can be investigated without losing them.
+// A message whose handling panics is retried until it has been delivered threshold times (minimum 1),
+// after which it is published to the quarantine subject along with the panic and its stack trace
+// (see the Quarantine headers), and the original is acked. If publishing fails, the message is retried instead.
+// The quarantine subject must be captured by a stream, and is subject to any namespace (see WithSubjectPrefix).
+// Without this option, a message whose handling panics is logged and acked immediately.
+func WithPanicQuarantine(subject string, threshold uint64) Option {
+	return func(options *options) {
+		options.quarantine = &quarantine{
+			subject:   subject,
+			threshold: max(threshold, 1),
+		}
+	}
+}
+
+// quarantineMessage publishes a copy of the message to the quarantine subject, stamped with the cause.
+func quarantineMessage(ctx context.Context, js jetstream.JetStream, subject string, msg jetstream.Msg, meta *jetstream.MsgMetadata, cause error) error {
+	header := nats.Header{}
+	for key, values := range msg.Headers() {
+		header[key] = values
+	}
+	// A copy must not be deduplicated against the original
+	header.Del(jetstream.MsgIDHeader)
+	header.Set(QuarantineOriginalSubjectHeader, msg.Subject())
+	header.Set(QuarantineOriginalStreamHeader, meta.Stream)
+	header.Set(QuarantineOriginalSequenceHeader, strconv.FormatUint(meta.Sequence.Stream, 10))
+	header.Set(QuarantineDeliveriesHeader, strconv.FormatUint(meta.NumDelivered, 10))
+	header.Set(QuarantineErrorHeader, cause.Error())
+	if st := stacktrace.Extract(cause); len(st) > 0 {
+		if b, err := json.Marshal(st); err == nil {
+			header.Set(QuarantineStackHeader, string(b))
+		}
+	}
+
+	if _, err := js.PublishMsg(ctx, &nats.Msg{Subject: subject, Header: header, Data: msg.Data()}); err != nil {
+		return stacktrace.Wrap(err)
+	}
+	return nil
+}
+
+// settlePanic quarantines the message once it has caused enough panics, or otherwise naks it to be retried.
+// It reports whether the message was settled, which it is not if quarantine is disabled.
+func (n *NatsStreamConsumer[T]) settlePanic(ctx context.Context, msg jetstream.Msg, meta *jetstream.MsgMetadata, err error, logger *slog.Logger) (bool, error) {
+	q := n.opts.quarantine
+	if q == nil {
+		return false, nil
+	}
+
+	if meta.NumDelivered < q.threshold {
+		delay := CalculateNakDelay(meta)
+		logger.Warn("handler panicked - will retry before quarantine", log.ErrAttr(err), slog.Duration("delay", delay))
+		return true, n.nak(msg, delay)
+	}
+
+	subject := n.ns.subject(q.subject)
+	if qErr := quarantineMessage(ctx, n.js, subject, msg, meta, err); qErr != nil {
+		delay := CalculateNakDelay(meta)
+		logger.Error("failed to quarantine poison message - will retry", log.ErrAttr(qErr),
+			slog.String("panic", err.Error()), slog.Duration("delay", delay))
+		return true, n.nak(msg, delay)
+	}
+
+	logger.Error("handler panicked repeatedly - quarantined poison message", log.ErrAttr(err),
+		slog.String("quarantine_subject", subject))
+	return true, n.ack(msg)
+}
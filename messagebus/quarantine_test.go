@@ -0,0 +1,108 @@
+package messagebus_test
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/calm/errgroup"
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// poisonHandler panics on "poison", and otherwise records the message.
+type poisonHandler struct {
+	panics  atomic.Int32
+	handled chan sampleMessage
+}
+
+func (h *poisonHandler) HandleMessage(_ context.Context, message sampleMessage, _ string, _ jetstream.MsgMetadata) error {
+	if message.Message == "poison" {
+		h.panics.Add(1)
+		panic("cannot digest")
+	}
+	h.handled <- message
+	return nil
+}
+
+func TestPanicQuarantine(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+	js := getJetStream(t, nc)
+
+	cfg, err := config.NewConfigurationFromMap(map[string]any{
+		"subject":      "poison.in",
+		"stream":       "POISON",
+		"durablequeue": "poison",
+	})
+	require.NoError(t, err)
+
+	producer, err := messagebus.NewNatsStreamProducer[sampleMessage](cfg, "", messagebus.WithNATSConnection(nc))
+	require.NoError(t, err)
+	t.Cleanup(producer.Close)
+	for _, m := range []string{"poison", "healthy"} {
+		require.NoError(t, producer.Produce(t.Context(), sampleMessage{Message: m}))
+	}
+
+	quarantined, err := js.CreateOrUpdateConsumer(t.Context(), "POISON", jetstream.ConsumerConfig{
+		Durable:       "poison-quarantine",
+		FilterSubject: "poison.quarantine",
+	})
+	require.NoError(t, err)
+
+	handler := &poisonHandler{handled: make(chan sampleMessage, 1)}
+	consumer, err := messagebus.NewNatsStreamConsumer(cfg, "", handler,
+		messagebus.WithNATSConnection(nc),
+		messagebus.WithPanicQuarantine("poison.quarantine", 3),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+	group, _ := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		return consumer.Run(ctx)
+	})
+
+	// the poison message does not block others
+	select {
+	case m := <-handler.handled:
+		assert.Equal(t, "healthy", m.Message)
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the healthy message")
+	}
+
+	// the poison message is retried until the threshold, then quarantined
+	msg, err := quarantined.Next(jetstream.FetchMaxWait(5 * time.Second))
+	require.NoError(t, err)
+	require.NoError(t, msg.Ack())
+	cancel()
+	require.NoError(t, group.Wait())
+
+	assert.Equal(t, int32(3), handler.panics.Load())
+
+	var data sampleMessage
+	require.NoError(t, json.Unmarshal(msg.Data(), &data))
+	assert.Equal(t, "poison", data.Message)
+
+	header := msg.Headers()
+	assert.Equal(t, "poison.in", header.Get(messagebus.QuarantineOriginalSubjectHeader))
+	assert.Equal(t, "POISON", header.Get(messagebus.QuarantineOriginalStreamHeader))
+	seq, err := strconv.ParseUint(header.Get(messagebus.QuarantineOriginalSequenceHeader), 10, 64)
+	require.NoError(t, err)
+	assert.Positive(t, seq)
+	assert.Equal(t, "3", header.Get(messagebus.QuarantineDeliveriesHeader))
+	assert.Contains(t, header.Get(messagebus.QuarantineErrorHeader), "cannot digest")
+
+	var st stacktrace.StackTrace
+	require.NoError(t, json.Unmarshal([]byte(header.Get(messagebus.QuarantineStackHeader)), &st))
+	assert.NotEmpty(t, st)
+}
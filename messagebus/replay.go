@@ -0,0 +1,136 @@
+package messagebus
+
+import (
+	"cmp"
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/zircuit-labs/zkr-go-common/log/identity"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// Headers recording the provenance of a replayed message.
+const (
+	ReplayOriginalStreamHeader    = "Replay-Original-Stream"
+	ReplayOriginalSequenceHeader  = "Replay-Original-Sequence"
+	ReplayOriginalTimestampHeader = "Replay-Original-Timestamp"
+	ReplayOperatorHeader          = "Replay-Operator"
+	ReplayInstanceHeader          = "Replay-Instance"
+	ReplayReasonHeader            = "Replay-Reason"
+)
+
+// Replay records the provenance of a message which was republished (eg by a replay or reprocessing tool)
+// rather than produced as a fresh event, so that consumers can skip non-idempotent side effects.
+type Replay struct {
+	// OriginalStream, OriginalSequence and OriginalTimestamp identify the message as first published.
+	OriginalStream    string
+	OriginalSequence  uint64
+	OriginalTimestamp time.Time
+	// Operator is who requested the replay.
+	Operator string
+	// Instance is what performed the replay. Defaults to the service name and instance ID of this process.
+	Instance string
+	// Reason is why the message was replayed.
+	Reason string
+}
+
+type replayKey struct{}
+
+// NewReplayContext returns a context carrying the replay, such that messages produced
+// with it are stamped with the replay headers.
+func NewReplayContext(ctx context.Context, r Replay) context.Context {
+	return context.WithValue(ctx, replayKey{}, r)
+}
+
+// ReplayFromContext returns the replay carried by ctx, if any.
+// Consumers add the replay of a replayed message to the context passed to the handler.
+func ReplayFromContext(ctx context.Context) (Replay, bool) {
+	r, ok := ctx.Value(replayKey{}).(Replay)
+	return r, ok
+}
+
+// SetReplayHeaders stamps the header with the replay.
+func SetReplayHeaders(header nats.Header, r Replay) {
+	if r.Instance == "" {
+		name, id := identity.WhoAmI()
+		r.Instance = name + "/" + id
+	}
+	setOrDelete(header, ReplayOriginalStreamHeader, r.OriginalStream)
+	if r.OriginalSequence != 0 {
+		header.Set(ReplayOriginalSequenceHeader, strconv.FormatUint(r.OriginalSequence, 10))
+	} else {
+		header.Del(ReplayOriginalSequenceHeader)
+	}
+	if !r.OriginalTimestamp.IsZero() {
+		header.Set(ReplayOriginalTimestampHeader, r.OriginalTimestamp.UTC().Format(time.RFC3339Nano))
+	} else {
+		header.Del(ReplayOriginalTimestampHeader)
+	}
+	setOrDelete(header, ReplayOperatorHeader, r.Operator)
+	header.Set(ReplayInstanceHeader, r.Instance)
+	setOrDelete(header, ReplayReasonHeader, r.Reason)
+}
+
+// ReplayFromHeader returns the replay stamped on the header, if any.
+// Malformed sequence numbers and timestamps are ignored.
+func ReplayFromHeader(header nats.Header) (Replay, bool) {
+	if header.Get(ReplayInstanceHeader) == "" {
+		return Replay{}, false
+	}
+	r := Replay{
+		OriginalStream: header.Get(ReplayOriginalStreamHeader),
+		Operator:       header.Get(ReplayOperatorHeader),
+		Instance:       header.Get(ReplayInstanceHeader),
+		Reason:         header.Get(ReplayReasonHeader),
+	}
+	r.OriginalSequence, _ = strconv.ParseUint(header.Get(ReplayOriginalSequenceHeader), 10, 64)
+	r.OriginalTimestamp, _ = time.Parse(time.RFC3339Nano, header.Get(ReplayOriginalTimestampHeader))
+	return r, true
+}
+
+// Republish publishes a copy of a message previously read from a stream to the subject,
+// stamped with the replay. The original stream, sequence and timestamp are taken from the
+// message unless set, and are retained from the message if it was itself a replay.
+// The message ID header is removed, so that the stream does not discard the copy as a duplicate.
+func Republish(ctx context.Context, js jetstream.JetStream, msg jetstream.Msg, subject string, r Replay) (*jetstream.PubAck, error) {
+	if previous, ok := ReplayFromHeader(msg.Headers()); ok {
+		r.OriginalStream = previous.OriginalStream
+		r.OriginalSequence = previous.OriginalSequence
+		r.OriginalTimestamp = previous.OriginalTimestamp
+	} else if meta, err := msg.Metadata(); err == nil && meta != nil {
+		r.OriginalStream = cmp.Or(r.OriginalStream, meta.Stream)
+		if r.OriginalSequence == 0 {
+			r.OriginalSequence = meta.Sequence.Stream
+		}
+		if r.OriginalTimestamp.IsZero() {
+			r.OriginalTimestamp = meta.Timestamp
+		}
+	}
+
+	header := nats.Header{}
+	for key, values := range msg.Headers() {
+		header[key] = append([]string(nil), values...)
+	}
+	header.Del(jetstream.MsgIDHeader)
+	SetReplayHeaders(header, r)
+
+	ack, err := js.PublishMsg(ctx, &nats.Msg{Subject: subject, Header: header, Data: msg.Data()})
+	if err != nil {
+		return nil, errclass.WrapAs(stacktrace.Wrap(err), errclass.Transient)
+	}
+	return ack, nil
+}
+
+func setOrDelete(header nats.Header, key, value string) {
+	if value != "" {
+		header.Set(key, value)
+	} else {
+		header.Del(key)
+	}
+}
+
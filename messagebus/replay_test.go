@@ -0,0 +1,138 @@
+package messagebus_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+)
+
+func TestReplayHeaders(t *testing.T) {
+	t.Parallel()
+
+	header := nats.Header{}
+	_, ok := messagebus.ReplayFromHeader(header)
+	assert.False(t, ok)
+
+	replay := messagebus.Replay{
+		OriginalStream:    "SPLAT",
+		OriginalSequence:  42,
+		OriginalTimestamp: time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+		Operator:          "alice",
+		Instance:          "reprocessor/1",
+		Reason:            "backfill",
+	}
+	messagebus.SetReplayHeaders(header, replay)
+	got, ok := messagebus.ReplayFromHeader(header)
+	require.True(t, ok)
+	assert.Equal(t, replay, got)
+
+	// the instance defaults to the identity of this process
+	header = nats.Header{}
+	messagebus.SetReplayHeaders(header, messagebus.Replay{Reason: "backfill"})
+	got, ok = messagebus.ReplayFromHeader(header)
+	require.True(t, ok)
+	assert.NotEmpty(t, got.Instance)
+	assert.Equal(t, "backfill", got.Reason)
+	assert.Zero(t, got.OriginalSequence)
+}
+
+func TestReplayPropagation(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+	js := getJetStream(t, nc)
+
+	// publish a fresh event
+	b, err := json.Marshal(sampleMessages[0])
+	require.NoError(t, err)
+	original, err := js.PublishMsg(t.Context(), &nats.Msg{
+		Subject: "splat.events",
+		Data:    b,
+		Header:  nats.Header{jetstream.MsgIDHeader: []string{"event-1"}},
+	})
+	require.NoError(t, err)
+
+	// a replay tool reads it back and republishes it
+	stream, err := js.Stream(t.Context(), "SPLAT")
+	require.NoError(t, err)
+	raw, err := stream.GetMsg(t.Context(), original.Sequence)
+	require.NoError(t, err)
+	reader, err := js.OrderedConsumer(t.Context(), "SPLAT", jetstream.OrderedConsumerConfig{
+		FilterSubjects: []string{"splat.events"},
+	})
+	require.NoError(t, err)
+	msg, err := reader.Next(jetstream.FetchMaxWait(time.Second * 5))
+	require.NoError(t, err)
+
+	ack, err := messagebus.Republish(t.Context(), js, msg, "splat.replayed", messagebus.Replay{
+		Operator: "alice",
+		Reason:   "backfill",
+	})
+	require.NoError(t, err)
+	assert.False(t, ack.Duplicate)
+
+	// the handler can tell the replay apart, and messages it produces carry the replay downstream
+	cfg, err := config.NewConfigurationFromMap(map[string]any{
+		"stream":       "SPLAT",
+		"subject":      "splat.replayed",
+		"durablequeue": "splat-replayed",
+	})
+	require.NoError(t, err)
+	downstreamCfg, err := config.NewConfigurationFromMap(map[string]any{"subject": "splat.downstream"})
+	require.NoError(t, err)
+	producer, err := messagebus.NewNatsStreamProducer[sampleMessage](downstreamCfg, "", messagebus.WithNATSConnection(nc))
+	require.NoError(t, err)
+	t.Cleanup(producer.Close)
+
+	replays := make(chan messagebus.Replay, 1)
+	handler := messagebus.ConsumerHandlerFunc[sampleMessage](func(ctx context.Context, data sampleMessage, _ string, _ jetstream.MsgMetadata) error {
+		replay, ok := messagebus.ReplayFromContext(ctx)
+		assert.True(t, ok)
+		replays <- replay
+		return producer.Produce(ctx, data)
+	})
+	consumer, err := messagebus.NewNatsStreamConsumer(cfg, "", handler, messagebus.WithNATSConnection(nc))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second*10)
+	t.Cleanup(cancel)
+	go func() {
+		_ = consumer.Run(ctx)
+	}()
+
+	var replay messagebus.Replay
+	select {
+	case replay = <-replays:
+	case <-ctx.Done():
+		require.FailNow(t, "message not handled")
+	}
+	assert.Equal(t, "SPLAT", replay.OriginalStream)
+	assert.Equal(t, original.Sequence, replay.OriginalSequence)
+	assert.True(t, raw.Time.Equal(replay.OriginalTimestamp))
+	assert.Equal(t, "alice", replay.Operator)
+	assert.Equal(t, "backfill", replay.Reason)
+	assert.NotEmpty(t, replay.Instance)
+
+	require.Eventually(t, func() bool {
+		downstream, err := stream.GetLastMsgForSubject(t.Context(), "splat.downstream")
+		if err != nil {
+			return false
+		}
+		got, ok := messagebus.ReplayFromHeader(downstream.Header)
+		return ok && got == replay
+	}, time.Second*5, time.Millisecond*10)
+	cancel()
+
+	// the message ID is removed so that the copy is not discarded as a duplicate
+	replayed, err := stream.GetLastMsgForSubject(t.Context(), "splat.replayed")
+	require.NoError(t, err)
+	assert.Empty(t, replayed.Header.Get(jetstream.MsgIDHeader))
+}
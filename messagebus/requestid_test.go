@@ -0,0 +1,81 @@
+package messagebus_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/calm/errgroup"
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/http/requestid"
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+)
+
+type requestIDHandler struct {
+	mu   sync.Mutex
+	ids  []string
+	done chan struct{}
+}
+
+func (h *requestIDHandler) HandleMessage(ctx context.Context, _ sampleMessage, _ string, _ jetstream.MsgMetadata) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	id, _ := requestid.FromContext(ctx)
+	h.ids = append(h.ids, id)
+	if len(h.ids) == 2 {
+		close(h.done)
+	}
+	return nil
+}
+
+func TestRequestIDPropagation(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+
+	cfg, err := config.NewConfigurationFromMap(
+		map[string]any{
+			"subject": "garply",
+			"stream":  "GARPLY",
+			"durable": "garply",
+		},
+	)
+	require.NoError(t, err)
+
+	producer, err := messagebus.NewNatsStreamProducer[sampleMessage](cfg, "", messagebus.WithNATSConnection(nc))
+	require.NoError(t, err)
+	t.Cleanup(producer.Close)
+
+	// the request ID is sent as a message header when present
+	require.NoError(t, producer.Produce(requestid.NewContext(t.Context(), "abc123"), sampleMessages[0]))
+	require.NoError(t, producer.Produce(t.Context(), sampleMessages[1]))
+
+	handler := &requestIDHandler{done: make(chan struct{})}
+	consumer, err := messagebus.NewNatsStreamConsumer(cfg, "", handler, messagebus.WithNATSConnection(nc))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second*10)
+	t.Cleanup(cancel)
+	group, _ := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		err := consumer.Run(ctx)
+		cancel()
+		return err
+	})
+
+	select {
+	case <-handler.done:
+		cancel()
+	case <-ctx.Done():
+	}
+	require.NoError(t, group.Wait())
+
+	// the consumer continues the request ID in the handler context
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	assert.Equal(t, []string{"abc123", ""}, handler.ids)
+}
@@ -0,0 +1,369 @@
+package messagebus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/zircuit-labs/zkr-go-common/calm"
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/http/requestid"
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/xerrors"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcode"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const (
+	// ErrorHeader carries a generic error message in replies to failed requests.
+	ErrorHeader = "Nats-Rpc-Error"
+	// ErrorClassHeader carries the error class in replies to failed requests.
+	ErrorClassHeader = "Nats-Rpc-Error-Class"
+	// ErrorCodeHeader carries the error code (see errcode.WrapAs), if any, in replies to failed requests.
+	ErrorCodeHeader = "Nats-Rpc-Error-Code"
+
+	// remoteErrorMessage is sent in place of the handler error, which may contain internal details.
+	remoteErrorMessage = "request failed"
+
+	defaultRequestTimeout = 5 * time.Second
+	defaultDrainTimeout   = 30 * time.Second
+	drainPollInterval     = 10 * time.Millisecond
+)
+
+var (
+	ErrNoReplySubject = errors.New("request has no reply subject")
+	ErrDrainTimeout   = errors.New("timed out handling requests received before shutdown")
+)
+
+// RemoteError is an error returned by a NatsResponder handler, as received by the requester.
+// Only what is safe to expose is sent (see xerrors.Sanitize): the error class of the handler error
+// is applied to the RemoteError, as is any error code, while the message is generic.
+type RemoteError struct {
+	Subject string
+	Message string
+	Code    errcode.Code
+}
+
+// Error implements the error interface.
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("remote error from %s: %s", e.Subject, e.Message)
+}
+
+// required config for a responder
+type natsResponderConfig struct {
+	// Subject identifies which requests to respond to
+	Subject string
+	// Queue optionally sets the queue group, which load-balances requests between instances
+	Queue string
+	// DrainTimeout limits how long requests received before shutdown are handled for. Defaults to 30s.
+	DrainTimeout time.Duration
+}
+
+// required config for a requester
+type natsRequesterConfig struct {
+	// Subject identifies where to send requests
+	Subject string
+	// Timeout limits each attempt at a request
+	Timeout time.Duration
+}
+
+// RequestHandler handles incoming requests.
+// Errors are returned to the requester along with their error class.
+type RequestHandler[Req, Resp any] interface {
+	HandleRequest(ctx context.Context, req Req, subject string) (Resp, error)
+}
+
+// NatsResponder is a Task which replies to requests made using a NatsRequester.
+type NatsResponder[Req, Resp any] struct {
+	config        natsResponderConfig
+	nc            *nats.Conn
+	shouldCloseNC bool
+	handler       RequestHandler[Req, Resp]
+	opts          options
+}
+
+// NewNatsResponder creates a new NatsResponder
+func NewNatsResponder[Req, Resp any](cfg *config.Configuration, cfgPath string, handler RequestHandler[Req, Resp], opts ...Option) (*NatsResponder[Req, Resp], error) {
+	options := parseOptions(opts)
+
+	responderConfig := natsResponderConfig{DrainTimeout: defaultDrainTimeout}
+	if err := cfg.Unmarshal(cfgPath, &responderConfig); err != nil {
+		return nil, stacktrace.Wrap(err)
+	}
+	if responderConfig.Subject == "" {
+		return nil, stacktrace.Wrap(ErrNoSubject)
+	}
+
+	responder := &NatsResponder[Req, Resp]{
+		config:  responderConfig,
+		handler: handler,
+		opts:    options,
+	}
+
+	if options.nc != nil {
+		// Use provided NATS connection
+		responder.nc = options.nc
+	} else {
+		// Set up NATS connection from config
+		nc, err := NewNatsConnection(cfg, opts...)
+		if err != nil {
+			return nil, err
+		}
+		responder.shouldCloseNC = true
+		responder.nc = nc
+	}
+
+	return responder, nil
+}
+
+// HealthCheck returns an error if the NATS connection is not "connected".
+func (n *NatsResponder[Req, Resp]) HealthCheck(ctx context.Context) error {
+	if n.nc.Status() != nats.CONNECTED {
+		return stacktrace.Wrap(ErrNATSNotConnected)
+	}
+	return nil
+}
+
+// Name returns the name of this task
+func (n *NatsResponder[Req, Resp]) Name() string {
+	return fmt.Sprintf("nats-responder (%s)", n.config.Subject)
+}
+
+// Run replies to requests until the context is done.
+// Requests are handled one at a time; run several responders in the same queue group for concurrency.
+// On shutdown, requests already received are still handled and replied to (bounded by the drain timeout),
+// so handlers are given a context which is not cancelled with that of Run.
+func (n *NatsResponder[Req, Resp]) Run(ctx context.Context) error {
+	// Only close the nats connection if it was one we made.
+	// Otherwise the responsibility for this lies with its creator.
+	if n.shouldCloseNC {
+		defer n.nc.Close()
+	}
+
+	handlerCtx := context.WithoutCancel(ctx)
+	sub, err := n.nc.QueueSubscribe(n.config.Subject, n.config.Queue, func(msg *nats.Msg) {
+		n.handleRequest(handlerCtx, msg)
+	})
+	if err != nil {
+		return errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+	}
+
+	<-ctx.Done()
+
+	// Stop receiving requests, and wait to finish handling any already received.
+	// Drain only starts draining, and the subscription becomes invalid once it is done.
+	if err := sub.Drain(); err != nil {
+		return stacktrace.Wrap(err)
+	}
+	timeout := time.NewTimer(n.config.DrainTimeout)
+	defer timeout.Stop()
+	poll := time.NewTicker(drainPollInterval)
+	defer poll.Stop()
+	for sub.IsValid() {
+		select {
+		case <-timeout.C:
+			return errclass.WrapAs(stacktrace.Wrap(ErrDrainTimeout), errclass.Transient)
+		case <-poll.C:
+		}
+	}
+	return nil
+}
+
+func (n *NatsResponder[Req, Resp]) handleRequest(ctx context.Context, msg *nats.Msg) {
+	logger := n.opts.logger.With(
+		slog.String("task", n.Name()),
+		slog.String("subject", msg.Subject),
+	)
+	if msg.Reply == "" {
+		logger.Warn("request has no reply subject - skipping", log.ErrAttr(stacktrace.Wrap(ErrNoReplySubject)))
+		return
+	}
+
+	// Continue any request ID propagated by the requester
+	if id := msg.Header.Get(requestid.Header); id != "" {
+		ctx = requestid.NewContext(ctx, id)
+		logger = logger.With(slog.String(requestid.LogKey, id))
+	}
+
+	reply := &nats.Msg{Subject: msg.Reply, Header: nats.Header{}}
+	resp, err := n.handle(ctx, msg)
+	if err == nil {
+		reply.Data, err = n.encode(reply.Header, resp)
+	}
+	if err != nil {
+		// The full error is only logged, as the reply may cross a trust boundary
+		logger.Warn("failed to handle request", log.ErrAttr(err))
+		reply.Data = nil
+		reply.Header = errorHeader(err)
+	}
+
+	if err := n.nc.PublishMsg(reply); err != nil {
+		logger.Warn("failed to send reply", log.ErrAttr(err))
+	}
+}
+
+// errorHeader returns the reply header for a failed request, carrying only the class and
+// code of the sanitized error along with a generic message.
+func errorHeader(err error) nats.Header {
+	err = xerrors.Sanitize(err)
+	header := nats.Header{}
+	header.Set(ErrorHeader, remoteErrorMessage)
+	header.Set(ErrorClassHeader, errclass.GetClass(err).String())
+	if detail, ok := errcode.Get(err); ok {
+		header.Set(ErrorCodeHeader, detail.Code.String())
+	}
+	return header
+}
+
+func (n *NatsResponder[Req, Resp]) handle(ctx context.Context, msg *nats.Msg) (resp Resp, err error) {
+	var req Req
+	if err := n.opts.unmarshal(msg.Header, msg.Data, &req); err != nil {
+		// A request that cannot be unmarshaled will never succeed
+		return resp, errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+	}
+
+	err = calm.Unpanic(func() error {
+		resp, err = n.handler.HandleRequest(ctx, req, msg.Subject)
+		return err
+	})
+	return resp, err
+}
+
+func (n *NatsResponder[Req, Resp]) encode(header nats.Header, resp Resp) ([]byte, error) {
+	b, err := n.opts.marshal(header, resp)
+	if err != nil {
+		return nil, errclass.WrapAs(err, errclass.Persistent)
+	}
+	return compressInto(header, b, n.opts.compressionThreshold)
+}
+
+// NatsRequester makes requests to a NatsResponder.
+type NatsRequester[Req, Resp any] struct {
+	config        natsRequesterConfig
+	nc            *nats.Conn
+	shouldCloseNC bool
+	opts          options
+}
+
+// NewNatsRequester creates a new NatsRequester
+func NewNatsRequester[Req, Resp any](cfg *config.Configuration, cfgPath string, opts ...Option) (*NatsRequester[Req, Resp], error) {
+	options := parseOptions(opts)
+
+	requesterConfig := natsRequesterConfig{
+		Timeout: defaultRequestTimeout,
+	}
+	if err := cfg.Unmarshal(cfgPath, &requesterConfig); err != nil {
+		return nil, stacktrace.Wrap(err)
+	}
+	if requesterConfig.Subject == "" {
+		return nil, stacktrace.Wrap(ErrNoSubject)
+	}
+
+	requester := &NatsRequester[Req, Resp]{
+		config: requesterConfig,
+		opts:   options,
+	}
+
+	if options.nc != nil {
+		// Use provided NATS connection
+		requester.nc = options.nc
+	} else {
+		// Set up NATS connection from config
+		nc, err := NewNatsConnection(cfg, opts...)
+		if err != nil {
+			return nil, err
+		}
+		requester.shouldCloseNC = true
+		requester.nc = nc
+	}
+
+	return requester, nil
+}
+
+// HealthCheck returns an error if the NATS connection is not "connected".
+func (n *NatsRequester[Req, Resp]) HealthCheck(ctx context.Context) error {
+	if n.nc.Status() != nats.CONNECTED {
+		return stacktrace.Wrap(ErrNATSNotConnected)
+	}
+	return nil
+}
+
+// Request sends the request and waits for the reply.
+// Each attempt is limited by the configured timeout, and failed attempts are retried
+// according to their error class, including errors returned by the responder.
+func (n *NatsRequester[Req, Resp]) Request(ctx context.Context, req Req) (resp Resp, err error) {
+	defer func() {
+		err = errcontext.Add(err, slog.String("subject", n.config.Subject))
+	}()
+
+	msg := &nats.Msg{Subject: n.config.Subject, Header: nats.Header{}}
+	b, err := n.opts.marshal(msg.Header, req)
+	if err != nil {
+		return resp, errclass.WrapAs(err, errclass.Persistent)
+	}
+	msg.Data, err = compressInto(msg.Header, b, n.opts.compressionThreshold)
+	if err != nil {
+		return resp, err
+	}
+	// Propagate any request ID to the responder
+	if id, ok := requestid.FromContext(ctx); ok {
+		msg.Header.Set(requestid.Header, id)
+	}
+
+	err = n.opts.retrier.Try(ctx, func() error {
+		var attemptErr error
+		resp, attemptErr = n.request(ctx, msg)
+		return attemptErr
+	})
+	return resp, err
+}
+
+func (n *NatsRequester[Req, Resp]) request(ctx context.Context, msg *nats.Msg) (resp Resp, err error) {
+	ctx, cancel := context.WithTimeout(ctx, n.config.Timeout)
+	defer cancel()
+
+	reply, err := n.nc.RequestMsgWithContext(ctx, msg)
+	if err != nil {
+		// No responders, timeouts, and connection issues may all resolve themselves
+		return resp, errclass.WrapAs(stacktrace.Wrap(err), errclass.Transient)
+	}
+
+	if message := reply.Header.Get(ErrorHeader); message != "" {
+		remoteErr := &RemoteError{Subject: msg.Subject, Message: message, Code: errcode.Code(reply.Header.Get(ErrorCodeHeader))}
+		err := errclass.WrapAs(stacktrace.Wrap(remoteErr), parseClass(reply.Header.Get(ErrorClassHeader)))
+		if remoteErr.Code != "" {
+			err = errcode.WrapAs(err, remoteErr.Code, nil)
+		}
+		return resp, err
+	}
+
+	if err := n.opts.unmarshal(reply.Header, reply.Data, &resp); err != nil {
+		return resp, errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+	}
+	return resp, nil
+}
+
+// Close terminates the connections
+func (n *NatsRequester[Req, Resp]) Close() {
+	// Only close the nats connection if it was one we made.
+	// Otherwise the responsibility for this lies with its creator.
+	if n.shouldCloseNC {
+		n.nc.Close()
+	}
+}
+
+// parseClass is the inverse of errclass.Class.String for classes sent in reply headers.
+func parseClass(s string) errclass.Class {
+	for _, class := range []errclass.Class{errclass.Transient, errclass.Persistent, errclass.Panic} {
+		if s == class.String() {
+			return class
+		}
+	}
+	return errclass.Unknown
+}
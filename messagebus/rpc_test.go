@@ -0,0 +1,233 @@
+package messagebus_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/calm/errgroup"
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/http/requestid"
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+	"github.com/zircuit-labs/zkr-go-common/retry"
+	"github.com/zircuit-labs/zkr-go-common/retry/strategy"
+	"github.com/zircuit-labs/zkr-go-common/xerrors"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcode"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+var errBadRequest = errors.New("bad request")
+
+type echoRequest struct {
+	Message string
+	Fail    bool
+	Panic   bool
+}
+
+type echoResponse struct {
+	Message   string
+	RequestID string
+}
+
+type echoHandler struct{}
+
+func (h echoHandler) HandleRequest(ctx context.Context, req echoRequest, subject string) (echoResponse, error) {
+	switch {
+	case req.Fail:
+		err := errclass.WrapAs(stacktrace.Wrap(errBadRequest), errclass.Persistent)
+		return echoResponse{}, errcode.WrapAs(err, "echo.bad_request", nil)
+	case req.Panic:
+		panic("echo panic")
+	}
+	id, _ := requestid.FromContext(ctx)
+	return echoResponse{Message: req.Message, RequestID: id}, nil
+}
+
+// blockingHandler waits to be released before replying, reporting whether its context was cancelled.
+type blockingHandler struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (h blockingHandler) HandleRequest(ctx context.Context, req echoRequest, subject string) (echoResponse, error) {
+	h.started <- struct{}{}
+	<-h.release
+	if ctx.Err() != nil {
+		return echoResponse{Message: ctx.Err().Error()}, nil
+	}
+	return echoResponse{Message: req.Message}, nil
+}
+
+func constantStrategy(t *testing.T) strategy.Factory {
+	t.Helper()
+	s, err := strategy.NewConstant(time.Millisecond * 10)
+	require.NoError(t, err)
+	return s
+}
+
+func TestRequestReply(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+
+	cfg, err := config.NewConfigurationFromMap(
+		map[string]any{
+			"subject": "rpc.echo",
+			"queue":   "echo",
+			"timeout": "1s",
+		},
+	)
+	require.NoError(t, err)
+
+	responder, err := messagebus.NewNatsResponder[echoRequest, echoResponse](cfg, "", echoHandler{}, messagebus.WithNATSConnection(nc))
+	require.NoError(t, err)
+	require.NoError(t, responder.HealthCheck(t.Context()))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	g := errgroup.New()
+	g.Go(func() error {
+		return responder.Run(ctx)
+	})
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, g.Wait())
+	})
+
+	retrier, err := retry.NewRetrier(retry.WithMaxAttempts(3), retry.WithStrategy(constantStrategy(t)))
+	require.NoError(t, err)
+	requester, err := messagebus.NewNatsRequester[echoRequest, echoResponse](cfg, "",
+		messagebus.WithNATSConnection(nc),
+		messagebus.WithRetrier(retrier),
+	)
+	require.NoError(t, err)
+	t.Cleanup(requester.Close)
+
+	// the subscription may not be active immediately, which is retried
+	reqCtx := requestid.NewContext(t.Context(), "abc")
+	resp, err := requester.Request(reqCtx, echoRequest{Message: "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, echoResponse{Message: "hello", RequestID: "abc"}, resp)
+
+	// handler errors are returned with their class, and are not retried when persistent
+	_, err = requester.Request(t.Context(), echoRequest{Fail: true})
+	var remoteErr *messagebus.RemoteError
+	require.ErrorAs(t, err, &remoteErr)
+	assert.Equal(t, "rpc.echo", remoteErr.Subject)
+	assert.Equal(t, errclass.Persistent, errclass.GetClass(err))
+	// along with their code, but not their message, which may contain internal details
+	assert.Equal(t, "request failed", remoteErr.Message)
+	assert.NotContains(t, err.Error(), errBadRequest.Error())
+	assert.Equal(t, errcode.Code("echo.bad_request"), remoteErr.Code)
+	detail, ok := errcode.Get(err)
+	require.True(t, ok)
+	assert.Equal(t, errcode.Code("echo.bad_request"), detail.Code)
+
+	// as are panics
+	_, err = requester.Request(t.Context(), echoRequest{Panic: true})
+	require.ErrorAs(t, err, &remoteErr)
+	assert.Equal(t, errclass.Panic, errclass.GetClass(err))
+}
+
+func TestResponderDrainsOnShutdown(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+
+	cfg, err := config.NewConfigurationFromMap(
+		map[string]any{
+			"subject": "rpc.drain",
+			"timeout": "5s",
+		},
+	)
+	require.NoError(t, err)
+
+	handler := blockingHandler{started: make(chan struct{}, 1), release: make(chan struct{})}
+	responder, err := messagebus.NewNatsResponder[echoRequest, echoResponse](cfg, "", handler, messagebus.WithNATSConnection(nc))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- responder.Run(ctx)
+	}()
+
+	retrier, err := retry.NewRetrier(retry.WithMaxAttempts(3), retry.WithStrategy(constantStrategy(t)))
+	require.NoError(t, err)
+	requester, err := messagebus.NewNatsRequester[echoRequest, echoResponse](cfg, "",
+		messagebus.WithNATSConnection(nc),
+		messagebus.WithRetrier(retrier),
+	)
+	require.NoError(t, err)
+	t.Cleanup(requester.Close)
+
+	type result struct {
+		resp echoResponse
+		err  error
+	}
+	results := make(chan result, 1)
+	go func() {
+		resp, err := requester.Request(t.Context(), echoRequest{Message: "hello"})
+		results <- result{resp: resp, err: err}
+	}()
+
+	// shut down while the request is being handled
+	<-handler.started
+	cancel()
+	select {
+	case err := <-runErr:
+		t.Fatalf("responder stopped before handling its request: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(handler.release)
+
+	// the request is still replied to, and the handler's context was not cancelled
+	res := <-results
+	require.NoError(t, res.err)
+	assert.Equal(t, "hello", res.resp.Message)
+	require.NoError(t, <-runErr)
+}
+
+func TestRequestNoResponders(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+
+	cfg, err := config.NewConfigurationFromMap(
+		map[string]any{
+			"subject": "rpc.nobody",
+			"timeout": "100ms",
+		},
+	)
+	require.NoError(t, err)
+
+	retrier, err := retry.NewRetrier(retry.WithMaxAttempts(2), retry.WithStrategy(constantStrategy(t)))
+	require.NoError(t, err)
+	requester, err := messagebus.NewNatsRequester[echoRequest, echoResponse](cfg, "",
+		messagebus.WithNATSConnection(nc),
+		messagebus.WithRetrier(retrier),
+	)
+	require.NoError(t, err)
+	t.Cleanup(requester.Close)
+
+	_, err = requester.Request(t.Context(), echoRequest{Message: "hello"})
+	require.ErrorIs(t, err, nats.ErrNoResponders)
+	stats, ok := xerrors.Extract[retry.Stats](err)
+	require.True(t, ok)
+	assert.Equal(t, retry.MaxAttemptsReached, stats.Cause)
+}
+
+func TestRPCConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := config.NewConfigurationFromMap(map[string]any{})
+	require.NoError(t, err)
+
+	_, err = messagebus.NewNatsRequester[echoRequest, echoResponse](cfg, "")
+	require.ErrorIs(t, err, messagebus.ErrNoSubject)
+	_, err = messagebus.NewNatsResponder[echoRequest, echoResponse](cfg, "", echoHandler{})
+	require.ErrorIs(t, err, messagebus.ErrNoSubject)
+}
@@ -0,0 +1,133 @@
+package messagebus
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+var (
+	// ErrInvalidSubjectTransform is returned when a consumer subject transform gives an invalid subject.
+	ErrInvalidSubjectTransform = errors.New("subject transform gives an invalid subject")
+	// ErrSubjectNotInStream is returned when a transformed consumer subject matches none of the stream's subjects,
+	// so the consumer would never receive a message.
+	ErrSubjectNotInStream = errors.New("subject is not matched by the stream's subjects")
+)
+
+const (
+	subjectSeparator = "."
+	tokenWildcard    = "*"
+	fullWildcard     = ">"
+	tokenPosition    = "$"
+)
+
+// transformSubject applies the transform to the subject. Keys of the form "$N" replace the Nth token of the subject
+// (counting from 1), then any other keys are replaced wherever they appear, in order of key.
+// The resulting subject is checked to be syntactically valid.
+func transformSubject(subject string, transform map[string]string) (string, error) {
+	tokens := strings.Split(subject, subjectSeparator)
+	var replacements []string
+	for _, k := range slices.Sorted(maps.Keys(transform)) {
+		position, ok := strings.CutPrefix(k, tokenPosition)
+		if !ok {
+			replacements = append(replacements, k)
+			continue
+		}
+		i, err := strconv.Atoi(position)
+		if err != nil {
+			replacements = append(replacements, k)
+			continue
+		}
+		if i < 1 || i > len(tokens) {
+			return "", errcontext.Add(
+				errclass.WrapAs(stacktrace.Wrap(ErrInvalidSubjectTransform), errclass.Persistent),
+				slog.String("subject", subject),
+				slog.String("token", k),
+			)
+		}
+		tokens[i-1] = transform[k]
+	}
+
+	transformed := strings.Join(tokens, subjectSeparator)
+	for _, k := range replacements {
+		transformed = strings.ReplaceAll(transformed, k, transform[k])
+	}
+
+	if !validSubject(transformed) {
+		return "", errcontext.Add(
+			errclass.WrapAs(stacktrace.Wrap(ErrInvalidSubjectTransform), errclass.Persistent),
+			slog.String("subject", subject),
+			slog.String("transformed_subject", transformed),
+		)
+	}
+	return transformed, nil
+}
+
+// validSubject reports whether the subject is valid for a consumer filter: non-empty tokens without whitespace,
+// with wildcards only as whole tokens, and ">" only as the last token.
+func validSubject(subject string) bool {
+	tokens := strings.Split(subject, subjectSeparator)
+	for i, token := range tokens {
+		switch {
+		case token == "":
+			return false
+		case strings.ContainsAny(token, " \t\r\n"):
+			return false
+		case token == fullWildcard:
+			if i != len(tokens)-1 {
+				return false
+			}
+		case token == tokenWildcard:
+		case strings.ContainsAny(token, tokenWildcard+fullWildcard):
+			return false
+		}
+	}
+	return true
+}
+
+// checkStreamSubjects returns an error if the subject cannot match any of the subjects of the stream.
+// Streams without subjects of their own (eg mirrors) are not checked.
+func checkStreamSubjects(ctx context.Context, js jetstream.JetStream, stream, subject string) error {
+	s, err := js.Stream(ctx, stream)
+	if err != nil {
+		return errcontext.Add(stacktrace.Wrap(err), slog.String("stream", stream))
+	}
+	subjects := s.CachedInfo().Config.Subjects
+	if len(subjects) == 0 || slices.ContainsFunc(subjects, func(s string) bool { return subjectsCollide(s, subject) }) {
+		return nil
+	}
+	return errcontext.Add(
+		errclass.WrapAs(stacktrace.Wrap(ErrSubjectNotInStream), errclass.Persistent),
+		slog.String("stream", stream),
+		slog.String("subject", subject),
+		slog.Any("stream_subjects", subjects),
+	)
+}
+
+// subjectsCollide reports whether any subject could be matched by both subjects (which may contain wildcards).
+func subjectsCollide(a, b string) bool {
+	at := strings.Split(a, subjectSeparator)
+	bt := strings.Split(b, subjectSeparator)
+	for i := range max(len(at), len(bt)) {
+		if i >= len(at) || i >= len(bt) {
+			return false
+		}
+		if at[i] == fullWildcard || bt[i] == fullWildcard {
+			return true
+		}
+		if at[i] != bt[i] && at[i] != tokenWildcard && bt[i] != tokenWildcard {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,117 @@
+package messagebus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransformSubject(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		subject   string
+		transform map[string]string
+		expected  string
+		err       error
+	}{
+		{name: "no transform", subject: "orders.created", expected: "orders.created"},
+		{name: "token position", subject: "orders.*.created", transform: map[string]string{"$2": "eu"}, expected: "orders.eu.created"},
+		{name: "last token", subject: "orders.created", transform: map[string]string{"$2": ">"}, expected: "orders.>"},
+		{name: "replacement", subject: "orders.v1.created", transform: map[string]string{"v1": "v2"}, expected: "orders.v2.created"},
+		{name: "replacements in order of key", subject: "a.b", transform: map[string]string{"a": "b", "b": "c"}, expected: "c.c"},
+		{
+			name:      "positions before replacements",
+			subject:   "a.b",
+			transform: map[string]string{"$1": "x", "x": "y"},
+			expected:  "y.b",
+		},
+		{name: "non-numeric position is a replacement", subject: "a.$x", transform: map[string]string{"$x": "b"}, expected: "a.b"},
+		{name: "position zero", subject: "a.b", transform: map[string]string{"$0": "c"}, err: ErrInvalidSubjectTransform},
+		{name: "position beyond subject", subject: "a.b", transform: map[string]string{"$3": "c"}, err: ErrInvalidSubjectTransform},
+		{name: "empty token", subject: "a.b", transform: map[string]string{"$1": ""}, err: ErrInvalidSubjectTransform},
+		{name: "full wildcard not last", subject: "a.b", transform: map[string]string{"$1": ">"}, err: ErrInvalidSubjectTransform},
+		{name: "whitespace", subject: "a.b", transform: map[string]string{"b": "b c"}, err: ErrInvalidSubjectTransform},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := transformSubject(tc.subject, tc.transform)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestValidSubject(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		subject string
+		valid   bool
+	}{
+		{subject: "a", valid: true},
+		{subject: "a.b.c", valid: true},
+		{subject: "a.*.c", valid: true},
+		{subject: "*", valid: true},
+		{subject: "a.>", valid: true},
+		{subject: ">", valid: true},
+		{subject: ""},
+		{subject: "a..b"},
+		{subject: "a."},
+		{subject: ".a"},
+		{subject: "a.>.b"},
+		{subject: "a.b*"},
+		{subject: "a.b>"},
+		{subject: "a.b c"},
+		{subject: "a.b\t"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.subject, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.valid, validSubject(tc.subject))
+		})
+	}
+}
+
+func TestSubjectsCollide(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		a, b    string
+		collide bool
+	}{
+		{a: "a.b", b: "a.b", collide: true},
+		{a: "a.b", b: "a.c"},
+		{a: "a.*", b: "a.b", collide: true},
+		{a: "a.*", b: "a.>", collide: true},
+		{a: "a.>", b: "a.b.c", collide: true},
+		{a: ">", b: "a", collide: true},
+		{a: "*.b", b: "a.*", collide: true},
+		{a: "*.b", b: "a.c"},
+		// a full wildcard must match at least one token
+		{a: "a.>", b: "a"},
+		// subjects of different lengths can only collide through a full wildcard
+		{a: "a", b: "a.b"},
+		{a: "a.*", b: "a.b.c"},
+		{a: "a.*", b: "a"},
+		{a: "*.*", b: "a.b.>"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.a+" "+tc.b, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.collide, subjectsCollide(tc.a, tc.b))
+			assert.Equal(t, tc.collide, subjectsCollide(tc.b, tc.a), "symmetric")
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package messagebus
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+const tracerName = "github.com/zircuit-labs/zkr-go-common/messagebus"
+
+// WithTracerProvider sets the OpenTelemetry TracerProvider used for consumer spans.
+// Defaults to the global provider (see otel.SetTracerProvider), which does nothing unless set.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(options *options) {
+		options.tracerProvider = tp
+	}
+}
+
+// WithPropagator sets the OpenTelemetry propagator used to carry trace context in message headers.
+// Defaults to the global propagator (see otel.SetTextMapPropagator), which does nothing unless set.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(options *options) {
+		options.propagator = p
+	}
+}
+
+// headerCarrier adapts message headers to propagation.TextMapCarrier.
+// Unlike propagation.HeaderCarrier, keys are not canonicalized, since NATS headers are case-sensitive.
+type headerCarrier nats.Header
+
+func (h headerCarrier) Get(key string) string {
+	return nats.Header(h).Get(key)
+}
+
+func (h headerCarrier) Set(key, value string) {
+	nats.Header(h).Set(key, value)
+}
+
+func (h headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (o options) textMapPropagator() propagation.TextMapPropagator {
+	if o.propagator != nil {
+		return o.propagator
+	}
+	return otel.GetTextMapPropagator()
+}
+
+func (o options) tracer() trace.Tracer {
+	tp := o.tracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer(tracerName)
+}
+
+// injectTrace adds the trace context of ctx to the header, to be continued by consumers.
+func (o options) injectTrace(ctx context.Context, header nats.Header) {
+	o.textMapPropagator().Inject(ctx, headerCarrier(header))
+}
+
+// startConsumeSpan continues any trace context from the header with a span for handling a message.
+func (o options) startConsumeSpan(ctx context.Context, header nats.Header, system, subject string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if header != nil {
+		ctx = o.textMapPropagator().Extract(ctx, headerCarrier(header))
+	}
+	attrs = append(attrs,
+		attribute.String("messaging.system", system),
+		attribute.String("messaging.operation.type", "process"),
+		attribute.String("messaging.destination.name", subject),
+	)
+	return o.tracer().Start(ctx, "process "+subject,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// endConsumeSpan ends the span, recording the error (if any) from handling the message.
+func endConsumeSpan(span trace.Span, err error) {
+	if class := errclass.GetClass(err); class != errclass.Nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.String("error.class", class.String()))
+	}
+	span.End()
+}
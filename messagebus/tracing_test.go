@@ -0,0 +1,105 @@
+package messagebus_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+var errTraced = errors.New("traced failure")
+
+// tracingHandler records the span context given to the handler, failing the first message.
+type tracingHandler struct {
+	spans chan trace.SpanContext
+}
+
+func (h *tracingHandler) HandleMessage(ctx context.Context, _ sampleMessage, _ string, meta jetstream.MsgMetadata) error {
+	h.spans <- trace.SpanContextFromContext(ctx)
+	if meta.NumDelivered == 1 {
+		return errclass.WrapAs(stacktrace.Wrap(errTraced), errclass.Transient)
+	}
+	return nil
+}
+
+func TestTracePropagation(t *testing.T) {
+	t.Parallel()
+	nc := getNatsConnection(t)
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	propagator := propagation.TraceContext{}
+	opts := []messagebus.Option{
+		messagebus.WithNATSConnection(nc),
+		messagebus.WithTracerProvider(tp),
+		messagebus.WithPropagator(propagator),
+	}
+
+	cfg, err := config.NewConfigurationFromMap(map[string]any{
+		"subject":      "cycle.traced",
+		"stream":       "CYCLE",
+		"durablequeue": "cycle-traced",
+	})
+	require.NoError(t, err)
+
+	producer, err := messagebus.NewNatsStreamProducer[sampleMessage](cfg, "", opts...)
+	require.NoError(t, err)
+	t.Cleanup(producer.Close)
+
+	ctx, parent := tp.Tracer("test").Start(t.Context(), "request")
+	require.NoError(t, producer.Produce(ctx, sampleMessages[0]))
+	parent.End()
+
+	handler := &tracingHandler{spans: make(chan trace.SpanContext, 2)}
+	consumer, err := messagebus.NewNatsStreamConsumer(cfg, "", handler, opts...)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- consumer.Run(ctx) }()
+
+	// each delivery is handled within its own span, continuing the producer's trace
+	for range 2 {
+		select {
+		case sc := <-handler.spans:
+			assert.Equal(t, parent.SpanContext().TraceID(), sc.TraceID())
+		case <-ctx.Done():
+			require.FailNow(t, "message not handled")
+		}
+	}
+	cancel()
+	require.NoError(t, <-done)
+
+	var spans tracetest.SpanStubs
+	require.Eventually(t, func() bool {
+		spans = exporter.GetSpans()
+		return len(spans) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	for i, span := range spans[1:] {
+		assert.Equal(t, "process cycle.traced", span.Name)
+		assert.Equal(t, trace.SpanKindConsumer, span.SpanKind)
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+		assert.Contains(t, span.Attributes, attribute.String("messaging.system", "nats"))
+		assert.Contains(t, span.Attributes, attribute.String("messaging.nats.stream", "CYCLE"))
+		assert.Contains(t, span.Attributes, attribute.Int64("messaging.delivery_attempt", int64(i+1)))
+	}
+	assert.Equal(t, codes.Error, spans[1].Status.Code)
+	assert.Contains(t, spans[1].Attributes, attribute.String("error.class", "transient"))
+	assert.Equal(t, codes.Unset, spans[2].Status.Code)
+}
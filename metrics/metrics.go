@@ -0,0 +1,164 @@
+// Package metrics wraps prometheus with a simple API for counters, gauges, histograms and timers,
+// labeled by convention with the identity and version of the service.
+package metrics
+
+import (
+	"errors"
+	"maps"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/zircuit-labs/zkr-go-common/log/identity"
+	"github.com/zircuit-labs/zkr-go-common/version"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// Standard labels of every metric.
+const (
+	LabelService  = "service"
+	LabelInstance = "instance"
+	LabelVersion  = "version"
+)
+
+const unknownVersion = "unknown"
+
+type options struct {
+	registerer  prometheus.Registerer
+	namespace   string
+	constLabels prometheus.Labels
+}
+
+// Option is an option func for NewRegistry.
+type Option func(options *options)
+
+// WithRegisterer sets the registerer of the metrics. Defaults to prometheus.DefaultRegisterer,
+// which is served by echotask (and most other exporters).
+func WithRegisterer(registerer prometheus.Registerer) Option {
+	return func(options *options) {
+		options.registerer = registerer
+	}
+}
+
+// WithNamespace prefixes the name of every metric with the namespace and an underscore.
+func WithNamespace(namespace string) Option {
+	return func(options *options) {
+		options.namespace = namespace
+	}
+}
+
+// WithConstLabels adds labels with fixed values to every metric, in addition to the standard labels.
+func WithConstLabels(labels map[string]string) Option {
+	return func(options *options) {
+		maps.Copy(options.constLabels, labels)
+	}
+}
+
+// Registry creates metrics with the standard labels.
+type Registry struct {
+	registerer  prometheus.Registerer
+	namespace   string
+	constLabels prometheus.Labels
+}
+
+// NewRegistry creates a Registry whose metrics are labeled with the service name and instance ID
+// (see identity.WhoAmI) and version (see version.Info) at the time it is created.
+// Set the service name before creating the registry.
+func NewRegistry(opts ...Option) *Registry {
+	service, instance := identity.WhoAmI()
+	v := version.Info.Version
+	if v == "" {
+		v = unknownVersion
+	}
+	options := options{
+		registerer: prometheus.DefaultRegisterer,
+		constLabels: prometheus.Labels{
+			LabelService:  service,
+			LabelInstance: instance,
+			LabelVersion:  v,
+		},
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &Registry{
+		registerer:  options.registerer,
+		namespace:   options.namespace,
+		constLabels: options.constLabels,
+	}
+}
+
+// Counter creates a counter with the given variable labels, or returns the existing one if already created.
+func (r *Registry) Counter(name, help string, labels ...string) (*Counter, error) {
+	vec, err := Register(r.registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   r.namespace,
+		Name:        name,
+		Help:        help,
+		ConstLabels: r.constLabels,
+	}, labels))
+	if err != nil {
+		return nil, err
+	}
+	return &Counter{vec: vec}, nil
+}
+
+// Gauge creates a gauge with the given variable labels, or returns the existing one if already created.
+func (r *Registry) Gauge(name, help string, labels ...string) (*Gauge, error) {
+	vec, err := Register(r.registerer, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   r.namespace,
+		Name:        name,
+		Help:        help,
+		ConstLabels: r.constLabels,
+	}, labels))
+	if err != nil {
+		return nil, err
+	}
+	return &Gauge{vec: vec}, nil
+}
+
+// Histogram creates a histogram with the given buckets (prometheus.DefBuckets if nil) and variable labels,
+// or returns the existing one if already created.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) (*Histogram, error) {
+	if buckets == nil {
+		buckets = prometheus.DefBuckets
+	}
+	vec, err := Register(r.registerer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   r.namespace,
+		Name:        name,
+		Help:        help,
+		ConstLabels: r.constLabels,
+		Buckets:     buckets,
+	}, labels))
+	if err != nil {
+		return nil, err
+	}
+	return &Histogram{vec: vec}, nil
+}
+
+// Timer creates a histogram of durations in seconds with the default buckets and given variable labels,
+// or returns the existing one if already created. By convention, its name should end in "_seconds".
+func (r *Registry) Timer(name, help string, labels ...string) (*Timer, error) {
+	h, err := r.Histogram(name, help, nil, labels...)
+	if err != nil {
+		return nil, err
+	}
+	return &Timer{histogram: h}, nil
+}
+
+// Register registers the collector, or returns the existing one if an equal collector is already
+// registered, such that components sharing a registerer may each register the same metrics.
+// Errors (eg a conflicting collector of the same name) are classed as Persistent.
+func Register[C prometheus.Collector](registerer prometheus.Registerer, c C) (C, error) {
+	if err := registerer.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if !errors.As(err, &are) {
+			return c, errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+		}
+		existing, ok := are.ExistingCollector.(C)
+		if !ok {
+			return c, errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+		}
+		return existing, nil
+	}
+	return c, nil
+}
@@ -0,0 +1,134 @@
+package metrics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/log/identity"
+	"github.com/zircuit-labs/zkr-go-common/metrics"
+	"github.com/zircuit-labs/zkr-go-common/version"
+)
+
+// gather returns the metrics of the family with the name.
+func gather(t *testing.T, registry *prometheus.Registry, name string) []*dto.Metric {
+	t.Helper()
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() == name {
+			return family.GetMetric()
+		}
+	}
+	require.FailNow(t, "metric not found", name)
+	return nil
+}
+
+func labels(m *dto.Metric) map[string]string {
+	labels := map[string]string{}
+	for _, pair := range m.GetLabel() {
+		labels[pair.GetName()] = pair.GetValue()
+	}
+	return labels
+}
+
+func TestStandardLabels(t *testing.T) {
+	t.Parallel()
+	registry := prometheus.NewRegistry()
+	r := metrics.NewRegistry(
+		metrics.WithRegisterer(registry),
+		metrics.WithNamespace("svc"),
+		metrics.WithConstLabels(map[string]string{"region": "eu"}),
+	)
+
+	counter, err := r.Counter("requests_total", "Requests served.", "route")
+	require.NoError(t, err)
+	counter.Inc("/a")
+
+	service, instance := identity.WhoAmI()
+	v := version.Info.Version
+	if v == "" {
+		v = "unknown"
+	}
+	got := gather(t, registry, "svc_requests_total")
+	require.Len(t, got, 1)
+	assert.Equal(t, map[string]string{
+		metrics.LabelService:  service,
+		metrics.LabelInstance: instance,
+		metrics.LabelVersion:  v,
+		"region":              "eu",
+		"route":               "/a",
+	}, labels(got[0]))
+
+	// variable labels must not clash with the standard labels
+	_, err = r.Counter("clash_total", "Clashing labels.", metrics.LabelService)
+	require.Error(t, err)
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+	registry := prometheus.NewRegistry()
+	r := metrics.NewRegistry(metrics.WithRegisterer(registry))
+
+	counter, err := r.Counter("events_total", "Events.", "kind")
+	require.NoError(t, err)
+	counter.Inc("a")
+	counter.Add(2, "a")
+	counter.Inc("b")
+
+	gauge, err := r.Gauge("queue_length", "Queue length.")
+	require.NoError(t, err)
+	gauge.Set(5)
+	gauge.Inc()
+	gauge.Dec()
+	gauge.Add(-2)
+
+	histogram, err := r.Histogram("response_bytes", "Response sizes.", []float64{10, 100})
+	require.NoError(t, err)
+	histogram.Observe(5)
+	histogram.Observe(50)
+
+	timer, err := r.Timer("query_duration_seconds", "Query latency.", "query")
+	require.NoError(t, err)
+	timer.Observe(time.Second, "select")
+	timer.Start("select")()
+
+	assert.Equal(t, 2, testutil.CollectAndCount(registry, "events_total"))
+	for _, m := range gather(t, registry, "events_total") {
+		expected := map[string]float64{"a": 3, "b": 1}[labels(m)["kind"]]
+		assert.InDelta(t, expected, m.GetCounter().GetValue(), 0)
+	}
+	assert.InDelta(t, 3, gather(t, registry, "queue_length")[0].GetGauge().GetValue(), 0)
+
+	h := gather(t, registry, "response_bytes")[0].GetHistogram()
+	assert.Equal(t, uint64(2), h.GetSampleCount())
+	assert.Equal(t, uint64(1), h.GetBucket()[0].GetCumulativeCount())
+
+	q := gather(t, registry, "query_duration_seconds")[0].GetHistogram()
+	assert.Equal(t, uint64(2), q.GetSampleCount())
+	assert.GreaterOrEqual(t, q.GetSampleSum(), 1.0)
+}
+
+func TestExisting(t *testing.T) {
+	t.Parallel()
+	registry := prometheus.NewRegistry()
+	r := metrics.NewRegistry(metrics.WithRegisterer(registry))
+
+	// the same metric may be created more than once, eg by several instances of a component
+	first, err := r.Counter("shared_total", "Shared.")
+	require.NoError(t, err)
+	second, err := r.Counter("shared_total", "Shared.")
+	require.NoError(t, err)
+	first.Inc()
+	second.Inc()
+	assert.InDelta(t, 2, gather(t, registry, "shared_total")[0].GetCounter().GetValue(), 0)
+
+	// but not as a different type
+	_, err = r.Gauge("shared_total", "Shared.")
+	require.Error(t, err)
+}
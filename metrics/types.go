@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Counter is a value which only increases, eg the number of requests served.
+// Methods take the values of its variable labels, in order, and panic if the number of values is wrong.
+type Counter struct {
+	vec *prometheus.CounterVec
+}
+
+// Inc increments the counter by one.
+func (c *Counter) Inc(labelValues ...string) {
+	c.vec.WithLabelValues(labelValues...).Inc()
+}
+
+// Add increases the counter by v, which must not be negative.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	c.vec.WithLabelValues(labelValues...).Add(v)
+}
+
+// Gauge is a value which may go up and down, eg the number of items in a queue.
+// Methods take the values of its variable labels, in order, and panic if the number of values is wrong.
+type Gauge struct {
+	vec *prometheus.GaugeVec
+}
+
+// Set sets the gauge to v.
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.vec.WithLabelValues(labelValues...).Set(v)
+}
+
+// Inc increments the gauge by one.
+func (g *Gauge) Inc(labelValues ...string) {
+	g.vec.WithLabelValues(labelValues...).Inc()
+}
+
+// Dec decrements the gauge by one.
+func (g *Gauge) Dec(labelValues ...string) {
+	g.vec.WithLabelValues(labelValues...).Dec()
+}
+
+// Add adds v (which may be negative) to the gauge.
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.vec.WithLabelValues(labelValues...).Add(v)
+}
+
+// Histogram counts observations in buckets, eg the size of responses.
+// Methods take the values of its variable labels, in order, and panic if the number of values is wrong.
+type Histogram struct {
+	vec *prometheus.HistogramVec
+}
+
+// Observe adds the observation v.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.vec.WithLabelValues(labelValues...).Observe(v)
+}
+
+// Timer is a histogram of durations in seconds, eg the latency of requests.
+// Methods take the values of its variable labels, in order, and panic if the number of values is wrong.
+type Timer struct {
+	histogram *Histogram
+}
+
+// Observe adds the duration d.
+func (t *Timer) Observe(d time.Duration, labelValues ...string) {
+	t.histogram.Observe(d.Seconds(), labelValues...)
+}
+
+// Start starts timing, returning a func which observes the duration since when called, eg
+//
+//	defer timer.Start("select_user")()
+func (t *Timer) Start(labelValues ...string) func() {
+	start := time.Now()
+	return func() {
+		t.Observe(time.Since(start), labelValues...)
+	}
+}
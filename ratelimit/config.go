@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const (
+	AlgorithmTokenBucket   = "tokenbucket"
+	AlgorithmSlidingWindow = "slidingwindow"
+)
+
+// Config configures a limiter.
+type Config struct {
+	// Algorithm is either "tokenbucket" (default) or "slidingwindow".
+	Algorithm string
+	// Limit is the number of events allowed per Period.
+	Limit int
+	// Period defaults to one second.
+	Period time.Duration
+	// Burst is the number of events allowed at once by a token bucket, defaulting to Limit.
+	Burst int
+}
+
+// NewFromConfig creates a Limiter from the config at cfgPath.
+func NewFromConfig(cfg *config.Configuration, cfgPath string, opts ...Option) (Limiter, error) {
+	c, err := parseConfig(cfg, cfgPath)
+	if err != nil {
+		return nil, err
+	}
+	switch c.Algorithm {
+	case AlgorithmSlidingWindow:
+		return NewSlidingWindow(c.Limit, c.Period, opts...)
+	default:
+		return NewTokenBucket(c.Limit, c.Period, c.Burst, opts...)
+	}
+}
+
+// NewKeyedFromConfig creates a Keyed limiter from the config at cfgPath, with the configured limit per key.
+func NewKeyedFromConfig[K comparable](cfg *config.Configuration, cfgPath string, opts ...Option) (*Keyed[K], error) {
+	c, err := parseConfig(cfg, cfgPath)
+	if err != nil {
+		return nil, err
+	}
+	switch c.Algorithm {
+	case AlgorithmSlidingWindow:
+		return NewKeyedSlidingWindow[K](c.Limit, c.Period, opts...)
+	default:
+		return NewKeyedTokenBucket[K](c.Limit, c.Period, c.Burst, opts...)
+	}
+}
+
+func parseConfig(cfg *config.Configuration, cfgPath string) (Config, error) {
+	c := Config{
+		Algorithm: AlgorithmTokenBucket,
+		Period:    time.Second,
+	}
+	if err := cfg.Unmarshal(cfgPath, &c); err != nil {
+		return Config{}, stacktrace.Wrap(err)
+	}
+	if c.Burst == 0 {
+		c.Burst = c.Limit
+	}
+	if c.Algorithm != AlgorithmTokenBucket && c.Algorithm != AlgorithmSlidingWindow {
+		return Config{}, errcontext.Add(
+			errclass.WrapAs(stacktrace.Wrap(ErrUnknownAlgorithm), errclass.Persistent),
+			slog.String("algorithm", c.Algorithm),
+		)
+	}
+	return c, nil
+}
@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const defaultIdleTimeout = time.Minute * 10
+
+// WithIdleTimeout sets how long the limiter of a key is kept after it was last used (default 10 minutes).
+// Keys which are used again after being forgotten start with a new limiter, so it should be longer than the period.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(options *options) {
+		options.idleTimeout = d
+	}
+}
+
+type keyedLimiter struct {
+	*limiter
+	lastUsed time.Time
+}
+
+// Keyed limits the rate of events separately per key (eg per subject or tenant), each key having its own limit.
+// Limiters of keys which have not been used for the idle timeout are forgotten, so that the number of keys
+// does not grow without bound. It is safe for concurrent use.
+type Keyed[K comparable] struct {
+	mu        sync.Mutex
+	opts      options
+	newPolicy func() policy
+	limiters  map[K]*keyedLimiter
+	lastSweep time.Time
+}
+
+// NewKeyedTokenBucket creates a Keyed limiter using a token bucket per key (see NewTokenBucket).
+func NewKeyedTokenBucket[K comparable](limit int, period time.Duration, burst int, opts ...Option) (*Keyed[K], error) {
+	if limit <= 0 || period <= 0 || burst <= 0 {
+		return nil, invalidLimit()
+	}
+	return newKeyed[K](func() policy { return newTokenBucket(limit, period, burst) }, parseOptions(opts)), nil
+}
+
+// NewKeyedSlidingWindow creates a Keyed limiter using a sliding window per key (see NewSlidingWindow).
+func NewKeyedSlidingWindow[K comparable](limit int, window time.Duration, opts ...Option) (*Keyed[K], error) {
+	if limit <= 0 || window <= 0 {
+		return nil, invalidLimit()
+	}
+	return newKeyed[K](func() policy { return newSlidingWindow(limit, window) }, parseOptions(opts)), nil
+}
+
+func newKeyed[K comparable](newPolicy func() policy, options options) *Keyed[K] {
+	return &Keyed[K]{
+		opts:      options,
+		newPolicy: newPolicy,
+		limiters:  make(map[K]*keyedLimiter),
+		lastSweep: options.clock.Now(),
+	}
+}
+
+// Get returns the Limiter of the key.
+func (k *Keyed[K]) Get(key K) Limiter {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.opts.clock.Now()
+	k.sweep(now)
+	l, ok := k.limiters[key]
+	if !ok {
+		l = &keyedLimiter{limiter: newLimiter(k.newPolicy(), k.opts)}
+		k.limiters[key] = l
+	}
+	l.lastUsed = now
+	return l.limiter
+}
+
+// sweep forgets the limiters of idle keys, at most once per idle timeout.
+func (k *Keyed[K]) sweep(now time.Time) {
+	if now.Sub(k.lastSweep) < k.opts.idleTimeout {
+		return
+	}
+	k.lastSweep = now
+	for key, l := range k.limiters {
+		if now.Sub(l.lastUsed) >= k.opts.idleTimeout {
+			delete(k.limiters, key)
+		}
+	}
+}
+
+// Len returns the number of keys currently tracked.
+func (k *Keyed[K]) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.limiters)
+}
+
+// Allow reports whether an event for the key may happen now, and counts it if so.
+func (k *Keyed[K]) Allow(key K) bool {
+	return k.Get(key).Allow()
+}
+
+// Reserve counts an event for the key, returning a Reservation of when it may happen.
+func (k *Keyed[K]) Reserve(key K) *Reservation {
+	return k.Get(key).Reserve()
+}
+
+// Wait blocks until an event for the key may happen, and counts it (see Limiter).
+func (k *Keyed[K]) Wait(ctx context.Context, key K) error {
+	return k.Get(key).Wait(ctx)
+}
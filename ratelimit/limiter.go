@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// limiter implements Limiter using a policy.
+type limiter struct {
+	mu     sync.Mutex
+	clock  clockwork.Clock
+	policy policy
+}
+
+func newLimiter(p policy, options options) *limiter {
+	return &limiter{clock: options.clock, policy: p}
+}
+
+func (l *limiter) reserve(maxDelay time.Duration) (*Reservation, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	delay, ok := l.policy.reserve(now, maxDelay)
+	if !ok {
+		return nil, false
+	}
+	return &Reservation{delay: delay, at: now.Add(delay), clock: l.clock, cancel: l.cancel}, true
+}
+
+func (l *limiter) cancel(at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.policy.cancel(at)
+}
+
+// Allow implements Limiter.
+func (l *limiter) Allow() bool {
+	_, ok := l.reserve(0)
+	return ok
+}
+
+// Reserve implements Limiter.
+func (l *limiter) Reserve() *Reservation {
+	r, _ := l.reserve(math.MaxInt64)
+	return r
+}
+
+// Wait implements Limiter.
+func (l *limiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return stacktrace.Wrap(err)
+	}
+	maxDelay := time.Duration(math.MaxInt64)
+	if deadline, ok := ctx.Deadline(); ok {
+		maxDelay = l.clock.Until(deadline)
+	}
+	r, ok := l.reserve(maxDelay)
+	if !ok {
+		return errclass.WrapAs(stacktrace.Wrap(ErrDeadlineTooSoon), errclass.Transient)
+	}
+	if r.delay <= 0 {
+		return nil
+	}
+
+	timer := l.clock.NewTimer(r.delay)
+	defer timer.Stop()
+	select {
+	case <-timer.Chan():
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return stacktrace.Wrap(ctx.Err())
+	}
+}
@@ -0,0 +1,94 @@
+// Package ratelimit limits the rate of events (eg requests, messages or calls to a dependency),
+// either in total or separately per key (eg per subject or tenant).
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+var (
+	ErrInvalidLimit     = errors.New("limit, period and burst must be positive")
+	ErrUnknownAlgorithm = errors.New("unknown rate limit algorithm")
+	// ErrDeadlineTooSoon is returned by Wait when the context deadline is before the event would be allowed.
+	ErrDeadlineTooSoon = errors.New("rate limit would not allow the event before the context deadline")
+)
+
+// Limiter limits the rate of events.
+type Limiter interface {
+	// Allow reports whether an event may happen now, and counts it if so.
+	Allow() bool
+	// Reserve counts an event, returning a Reservation of when it may happen.
+	Reserve() *Reservation
+	// Wait blocks until an event may happen, and counts it. If the context is done first, or its deadline is
+	// before the event would be allowed (in which case ErrDeadlineTooSoon is returned without waiting),
+	// the event is not counted.
+	Wait(ctx context.Context) error
+}
+
+// Reservation is an event counted by a Limiter, which may happen after its delay.
+type Reservation struct {
+	delay  time.Duration
+	at     time.Time
+	clock  clockwork.Clock
+	cancel func(at time.Time)
+}
+
+// Delay returns how long to wait from the time of reservation before the event may happen.
+func (r *Reservation) Delay() time.Duration {
+	return r.delay
+}
+
+// Cancel stops counting the event, if it has not yet been allowed to happen,
+// so that the capacity reserved for it is available to other events.
+func (r *Reservation) Cancel() {
+	if r.cancel != nil && r.clock.Now().Before(r.at) {
+		r.cancel(r.at)
+		r.cancel = nil
+	}
+}
+
+type options struct {
+	clock       clockwork.Clock
+	idleTimeout time.Duration
+}
+
+// Option is an option func for the limiter constructors.
+type Option func(options *options)
+
+// WithClock allows users to mock the clock for testing purposes.
+func WithClock(clock clockwork.Clock) Option {
+	return func(options *options) {
+		options.clock = clock
+	}
+}
+
+func parseOptions(opts []Option) options {
+	options := options{
+		clock:       clockwork.NewRealClock(),
+		idleTimeout: defaultIdleTimeout,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// policy is the algorithm of a limiter. It is not safe for concurrent use.
+type policy interface {
+	// reserve counts an event happening at now or as soon after as allowed, and returns the delay,
+	// unless the delay would exceed maxDelay in which case nothing is counted.
+	reserve(now time.Time, maxDelay time.Duration) (time.Duration, bool)
+	// cancel stops counting the event reserved to happen at the given time.
+	cancel(at time.Time)
+}
+
+func invalidLimit() error {
+	return errclass.WrapAs(stacktrace.Wrap(ErrInvalidLimit), errclass.Persistent)
+}
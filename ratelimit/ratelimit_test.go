@@ -0,0 +1,180 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/ratelimit"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+func TestInvalidLimits(t *testing.T) {
+	t.Parallel()
+
+	_, err := ratelimit.NewTokenBucket(0, time.Second, 1)
+	require.ErrorIs(t, err, ratelimit.ErrInvalidLimit)
+	_, err = ratelimit.NewTokenBucket(1, time.Second, 0)
+	require.ErrorIs(t, err, ratelimit.ErrInvalidLimit)
+	_, err = ratelimit.NewSlidingWindow(1, 0)
+	require.ErrorIs(t, err, ratelimit.ErrInvalidLimit)
+	_, err = ratelimit.NewKeyedTokenBucket[string](1, -time.Second, 1)
+	require.ErrorIs(t, err, ratelimit.ErrInvalidLimit)
+	_, err = ratelimit.NewKeyedSlidingWindow[string](-1, time.Second)
+	require.ErrorIs(t, err, ratelimit.ErrInvalidLimit)
+	assert.Equal(t, errclass.Persistent, errclass.GetClass(err))
+}
+
+func TestTokenBucket(t *testing.T) {
+	t.Parallel()
+
+	clock := clockwork.NewFakeClock()
+	limiter, err := ratelimit.NewTokenBucket(10, time.Second, 3, ratelimit.WithClock(clock))
+	require.NoError(t, err)
+
+	// a burst is allowed, then events wait for the next token
+	for range 3 {
+		assert.True(t, limiter.Allow())
+	}
+	assert.False(t, limiter.Allow())
+	assert.Equal(t, time.Millisecond*100, limiter.Reserve().Delay())
+	assert.Equal(t, time.Millisecond*200, limiter.Reserve().Delay())
+
+	// cancelling a reservation returns its token
+	r := limiter.Reserve()
+	assert.Equal(t, time.Millisecond*300, r.Delay())
+	r.Cancel()
+	assert.Equal(t, time.Millisecond*300, limiter.Reserve().Delay())
+
+	// tokens refill over time, up to the burst
+	clock.Advance(time.Minute)
+	for range 3 {
+		assert.True(t, limiter.Allow())
+	}
+	assert.False(t, limiter.Allow())
+}
+
+func TestSlidingWindow(t *testing.T) {
+	t.Parallel()
+
+	clock := clockwork.NewFakeClock()
+	limiter, err := ratelimit.NewSlidingWindow(3, time.Second, ratelimit.WithClock(clock))
+	require.NoError(t, err)
+
+	assert.True(t, limiter.Allow())
+	clock.Advance(time.Millisecond * 400)
+	assert.True(t, limiter.Allow())
+	assert.True(t, limiter.Allow())
+	assert.False(t, limiter.Allow())
+
+	// the next event waits for the first to leave the window, then the next for the second
+	assert.Equal(t, time.Millisecond*600, limiter.Reserve().Delay())
+	r := limiter.Reserve()
+	assert.Equal(t, time.Second, r.Delay())
+	r.Cancel()
+
+	clock.Advance(time.Millisecond * 600)
+	assert.False(t, limiter.Allow()) // taken by the reservation
+	clock.Advance(time.Millisecond * 400)
+	assert.True(t, limiter.Allow())
+	assert.True(t, limiter.Allow())
+	assert.False(t, limiter.Allow())
+}
+
+func TestWait(t *testing.T) {
+	t.Parallel()
+
+	clock := clockwork.NewFakeClock()
+	limiter, err := ratelimit.NewTokenBucket(1, time.Second, 1, ratelimit.WithClock(clock))
+	require.NoError(t, err)
+	require.NoError(t, limiter.Wait(t.Context()))
+
+	// the deadline is too soon to wait for the next token, so it fails without waiting or taking it
+	ctx, cancel := context.WithDeadline(t.Context(), clock.Now().Add(time.Millisecond*500))
+	defer cancel()
+	err = limiter.Wait(ctx)
+	require.ErrorIs(t, err, ratelimit.ErrDeadlineTooSoon)
+	assert.Equal(t, errclass.Transient, errclass.GetClass(err))
+
+	// waiting is interrupted when the context is cancelled, returning the token
+	ctx, cancel = context.WithCancel(t.Context())
+	done := make(chan error)
+	go func() { done <- limiter.Wait(ctx) }()
+	require.NoError(t, clock.BlockUntilContext(t.Context(), 1))
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	go func() { done <- limiter.Wait(t.Context()) }()
+	require.NoError(t, clock.BlockUntilContext(t.Context(), 1))
+	clock.Advance(time.Second)
+	require.NoError(t, <-done)
+}
+
+func TestKeyed(t *testing.T) {
+	t.Parallel()
+
+	clock := clockwork.NewFakeClock()
+	keyed, err := ratelimit.NewKeyedTokenBucket[string](1, time.Second, 2,
+		ratelimit.WithClock(clock),
+		ratelimit.WithIdleTimeout(time.Minute),
+	)
+	require.NoError(t, err)
+
+	// each key has its own limit
+	assert.True(t, keyed.Allow("tenant-a"))
+	assert.True(t, keyed.Allow("tenant-a"))
+	assert.False(t, keyed.Allow("tenant-a"))
+	assert.True(t, keyed.Allow("tenant-b"))
+	assert.Equal(t, time.Duration(0), keyed.Reserve("tenant-b").Delay())
+	require.NoError(t, keyed.Wait(t.Context(), "tenant-c"))
+	assert.Equal(t, 3, keyed.Len())
+
+	// idle keys are forgotten
+	clock.Advance(time.Second * 30)
+	assert.True(t, keyed.Allow("tenant-b"))
+	clock.Advance(time.Second * 40)
+	assert.True(t, keyed.Allow("tenant-a"))
+	assert.Equal(t, 2, keyed.Len())
+}
+
+func TestNewFromConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := config.NewConfigurationFromMap(map[string]any{
+		"api": map[string]any{
+			"limit": 2,
+		},
+		"tenants": map[string]any{
+			"algorithm": "slidingwindow",
+			"limit":     1,
+			"period":    "1m",
+		},
+		"broken": map[string]any{
+			"algorithm": "leakybucket",
+			"limit":     1,
+		},
+	})
+	require.NoError(t, err)
+
+	clock := clockwork.NewFakeClock()
+	limiter, err := ratelimit.NewFromConfig(cfg, "api", ratelimit.WithClock(clock))
+	require.NoError(t, err)
+	assert.True(t, limiter.Allow())
+	assert.True(t, limiter.Allow()) // burst defaults to the limit
+	assert.Equal(t, time.Millisecond*500, limiter.Reserve().Delay())
+
+	keyed, err := ratelimit.NewKeyedFromConfig[string](cfg, "tenants", ratelimit.WithClock(clock))
+	require.NoError(t, err)
+	assert.True(t, keyed.Allow("tenant-a"))
+	assert.Equal(t, time.Minute, keyed.Reserve("tenant-a").Delay())
+
+	_, err = ratelimit.NewFromConfig(cfg, "broken")
+	require.ErrorIs(t, err, ratelimit.ErrUnknownAlgorithm)
+	_, err = ratelimit.NewFromConfig(cfg, "missing")
+	require.ErrorIs(t, err, ratelimit.ErrInvalidLimit)
+}
@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"slices"
+	"time"
+)
+
+// slidingWindow records the time of each event within the last window, allowing at most limit of them.
+// Unlike a token bucket, it never allows more than limit events in any window, at the cost of memory per event.
+type slidingWindow struct {
+	limit  int
+	window time.Duration
+	events []time.Time // in order, including reserved events in the future
+}
+
+// NewSlidingWindow creates a Limiter allowing at most limit events within any window of the given duration.
+// It keeps the time of each event in the window, so is best suited to limits of up to thousands of events.
+func NewSlidingWindow(limit int, window time.Duration, opts ...Option) (Limiter, error) {
+	if limit <= 0 || window <= 0 {
+		return nil, invalidLimit()
+	}
+	return newLimiter(newSlidingWindow(limit, window), parseOptions(opts)), nil
+}
+
+func newSlidingWindow(limit int, window time.Duration) *slidingWindow {
+	return &slidingWindow{limit: limit, window: window}
+}
+
+func (w *slidingWindow) reserve(now time.Time, maxDelay time.Duration) (time.Duration, bool) {
+	// forget events which have left the window
+	expired := 0
+	for expired < len(w.events) && !w.events[expired].Add(w.window).After(now) {
+		expired++
+	}
+	w.events = slices.Delete(w.events, 0, expired)
+
+	// otherwise the event must wait for the limit-th most recent event to leave the window
+	at := now
+	if len(w.events) >= w.limit {
+		at = w.events[len(w.events)-w.limit].Add(w.window)
+	}
+	delay := at.Sub(now)
+	if delay > maxDelay {
+		return 0, false
+	}
+	w.events = append(w.events, at)
+	return delay, true
+}
+
+func (w *slidingWindow) cancel(at time.Time) {
+	if i := slices.IndexFunc(w.events, at.Equal); i >= 0 {
+		w.events = slices.Delete(w.events, i, i+1)
+	}
+}
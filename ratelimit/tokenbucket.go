@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"time"
+)
+
+// tokenBucket holds up to burst tokens, refilled at a constant rate. Each event takes a token,
+// and events without a token wait for the next one. It allows bursts of up to burst events,
+// with limit events per period on average thereafter.
+type tokenBucket struct {
+	burst    float64
+	interval float64 // nanoseconds per token
+	tokens   float64 // negative while events are waiting for tokens
+	last     time.Time
+}
+
+// NewTokenBucket creates a Limiter allowing limit events per period on average, in bursts of up to burst events.
+// A burst of one spaces events evenly.
+func NewTokenBucket(limit int, period time.Duration, burst int, opts ...Option) (Limiter, error) {
+	if limit <= 0 || period <= 0 || burst <= 0 {
+		return nil, invalidLimit()
+	}
+	return newLimiter(newTokenBucket(limit, period, burst), parseOptions(opts)), nil
+}
+
+func newTokenBucket(limit int, period time.Duration, burst int) *tokenBucket {
+	return &tokenBucket{
+		burst:    float64(burst),
+		interval: float64(period) / float64(limit),
+		tokens:   float64(burst),
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if !b.last.IsZero() && now.After(b.last) {
+		b.tokens = min(b.burst, b.tokens+float64(now.Sub(b.last))/b.interval)
+	}
+	if b.last.IsZero() || now.After(b.last) {
+		b.last = now
+	}
+}
+
+func (b *tokenBucket) reserve(now time.Time, maxDelay time.Duration) (time.Duration, bool) {
+	b.refill(now)
+	var delay time.Duration
+	if b.tokens < 1 {
+		delay = time.Duration((1 - b.tokens) * b.interval)
+	}
+	if delay > maxDelay {
+		return 0, false
+	}
+	b.tokens--
+	return delay, true
+}
+
+func (b *tokenBucket) cancel(time.Time) {
+	b.tokens = min(b.burst, b.tokens+1)
+}
@@ -9,6 +9,14 @@ import (
 // from a single input attribute, enabling 1-to-many transformations.
 type ReplaceAttrMoreFunc func(groups []string, a slog.Attr) []slog.Attr
 
+// Deferred may be implemented by slog.LogValuer values whose resolution should be left
+// to the next handler, eg because it is expensive and the record may yet be dropped.
+// Attributes with such values are passed through unchanged, without calling the ReplaceAttrMoreFunc.
+type Deferred interface {
+	slog.LogValuer
+	Deferred()
+}
+
 // Handler wraps any slog.Handler and applies ReplaceAttrMoreFunc
 // transformations before passing the record to the wrapped handler.
 type Handler struct {
@@ -50,7 +58,7 @@ func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
 	// Transform attributes using ReplaceAttrMoreFunc
 	var transformedAttrs []slog.Attr
 	for _, attr := range allAttrs {
-		if h.replace != nil {
+		if h.replace != nil && !isDeferred(attr) {
 			resolved := slog.Attr{Key: attr.Key, Value: attr.Value.Resolve()}
 			expanded := h.replace(h.groups, resolved)
 			transformedAttrs = append(transformedAttrs, expanded...)
@@ -73,7 +81,7 @@ func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	// Transform the new attrs through ReplaceAttrMoreFunc
 	var transformedAttrs []slog.Attr
 	for _, attr := range attrs {
-		if h.replace != nil {
+		if h.replace != nil && !isDeferred(attr) {
 			resolved := slog.Attr{Key: attr.Key, Value: attr.Value.Resolve()}
 			expanded := h.replace(h.groups, resolved)
 			transformedAttrs = append(transformedAttrs, expanded...)
@@ -98,3 +106,11 @@ func (h *Handler) WithGroup(name string) slog.Handler {
 		groups:  append(h.groups, name),
 	}
 }
+
+func isDeferred(attr slog.Attr) bool {
+	if attr.Value.Kind() != slog.KindLogValuer {
+		return false
+	}
+	_, ok := attr.Value.LogValuer().(Deferred)
+	return ok
+}
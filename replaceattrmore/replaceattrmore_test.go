@@ -305,3 +305,33 @@ func TestHandler_ComplexTransformation(t *testing.T) {
 	actualLogJSON := normalizeTime(buf.String())
 	assert.JSONEq(t, expectedJSON, actualLogJSON)
 }
+
+type deferredValue struct {
+	calls *int
+}
+
+func (d deferredValue) LogValue() slog.Value {
+	*d.calls++
+	return slog.StringValue("resolved")
+}
+
+func (d deferredValue) Deferred() {}
+
+func TestHandler_Deferred(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	replaced := 0
+	calls := 0
+	handler := replaceattrmore.New(slog.NewJSONHandler(&buf, nil), func(groups []string, a slog.Attr) []slog.Attr {
+		replaced++
+		return []slog.Attr{a}
+	})
+	logger := slog.New(handler)
+
+	// deferred values are passed through to the next handler without being resolved or replaced
+	logger.Info("test", slog.Any("deferred", deferredValue{calls: &calls}), slog.String("other", "value"))
+	assert.Equal(t, 1, replaced)
+	assert.Equal(t, 1, calls)
+	assert.Contains(t, buf.String(), `"deferred":"resolved"`)
+}
@@ -0,0 +1,61 @@
+package retry
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+var ErrInvalidBudget = errors.New("budget retries and window must be positive")
+
+// Budget limits the rate of retries shared by any number of Retriers (eg all callers of a downstream service),
+// so that a thundering herd of retries cannot overwhelm it. It is a token bucket holding up to `retries` tokens,
+// refilled at a rate of `retries` per window. Each retry takes a token; first attempts are free.
+// It is safe for concurrent use.
+type Budget struct {
+	mu       sync.Mutex
+	capacity float64
+	rate     float64 // tokens per nanosecond
+	tokens   float64
+	last     time.Time
+}
+
+// NewBudget creates a full Budget allowing bursts of up to `retries` retries, and `retries` per window thereafter.
+func NewBudget(retries int, window time.Duration) (*Budget, error) {
+	if retries <= 0 || window <= 0 {
+		return nil, stacktrace.Wrap(ErrInvalidBudget)
+	}
+	return &Budget{
+		capacity: float64(retries),
+		rate:     float64(retries) / float64(window),
+		tokens:   float64(retries),
+	}, nil
+}
+
+// take removes a token from the budget, returning false if none are available.
+func (b *Budget) take(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.last.IsZero() && now.After(b.last) {
+		b.tokens = min(b.capacity, b.tokens+float64(now.Sub(b.last))*b.rate)
+	}
+	if b.last.IsZero() || now.After(b.last) {
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// WithBudget makes retries draw from the shared budget. When it is exhausted, Try fails fast with the
+// last error, and the cause BudgetExhausted in its Stats.
+func WithBudget(budget *Budget) Option {
+	return func(options *options) {
+		options.budget = budget
+	}
+}
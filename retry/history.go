@@ -0,0 +1,76 @@
+package retry
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+const defaultHistorySize = 5
+
+// AttemptSummary summarizes consecutive attempts which failed with the same error.
+type AttemptSummary struct {
+	// Error is the error message, used as the fingerprint to group attempts.
+	Error string
+	// FirstAttempt is the number of the first attempt in this group.
+	FirstAttempt int
+	// Count is the number of consecutive attempts which failed this way.
+	Count int
+	// Delay is the total time waited after these attempts.
+	Delay time.Duration
+}
+
+// History is attached to the error returned by Try when max attempts are reached,
+// and shows whether the failures were identical or evolving.
+// Use `xerrors.Extract[retry.History](err)` to access it.
+type History struct {
+	// Attempts holds the most recent groups of failed attempts, oldest first.
+	Attempts []AttemptSummary
+	// Omitted is the number of earlier attempts not included in Attempts.
+	Omitted int
+}
+
+// LogValue implements slog.LogValuer for History.
+func (h History) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, len(h.Attempts)+1)
+	if h.Omitted > 0 {
+		attrs = append(attrs, slog.Int("omitted", h.Omitted))
+	}
+	for _, a := range h.Attempts {
+		attrs = append(attrs, slog.Group("attempt_"+strconv.Itoa(a.FirstAttempt),
+			slog.String("error", a.Error),
+			slog.Int("count", a.Count),
+			slog.Duration("delay", a.Delay),
+		))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// historyRecorder builds a History bounded to size groups.
+type historyRecorder struct {
+	size    int
+	history History
+}
+
+func (h *historyRecorder) recordFailure(attempt int, err error) {
+	msg := err.Error()
+	if n := len(h.history.Attempts); n > 0 && h.history.Attempts[n-1].Error == msg {
+		h.history.Attempts[n-1].Count++
+		return
+	}
+	h.history.Attempts = append(h.history.Attempts, AttemptSummary{
+		Error:        msg,
+		FirstAttempt: attempt,
+		Count:        1,
+	})
+	if len(h.history.Attempts) > h.size {
+		h.history.Omitted += h.history.Attempts[0].Count
+		h.history.Attempts = h.history.Attempts[1:]
+	}
+}
+
+func (h *historyRecorder) recordDelay(d time.Duration) {
+	if n := len(h.history.Attempts); n > 0 {
+		h.history.Attempts[n-1].Delay += d
+	}
+}
@@ -0,0 +1,39 @@
+package retry
+
+import (
+	"time"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+// OnRetryFunc is called after each failed attempt which will be retried, before waiting for nextDelay.
+// The error is wrapped as the class the Retrier classified it as (see WithUnknownErrorsAs, WithRetryOn),
+// and the Stats are a snapshot of the attempt which failed, with Cause not yet set.
+type OnRetryFunc func(err error, stats Stats, nextDelay time.Duration)
+
+// OnGiveUpFunc is called when Try stops without success, with the error and Stats it returns.
+type OnGiveUpFunc func(err error, stats Stats)
+
+// WithOnRetry calls the hook after each failed attempt which will be retried, eg to count retries or log them
+// without wrapping the whole of Try. It is called synchronously, so should not block.
+func WithOnRetry(hook OnRetryFunc) Option {
+	return func(options *options) {
+		options.onRetry = hook
+	}
+}
+
+// WithOnGiveUp calls the hook when Try stops without success, for whatever cause (see Stats).
+// It is called synchronously before Try returns.
+func WithOnGiveUp(hook OnGiveUpFunc) Option {
+	return func(options *options) {
+		options.onGiveUp = hook
+	}
+}
+
+// classified returns the error wrapped as the class, unless it already has that class.
+func classified(err error, class errclass.Class) error {
+	if errclass.GetClass(err) == class {
+		return err
+	}
+	return errclass.WrapAs(err, class)
+}
@@ -24,12 +24,18 @@ func None() Transformation {
 //
 // Inspired by https://www.awsarchitectureblog.com/2015/03/backoff.html
 func Full() Transformation {
+	return FullFrom(nil)
+}
+
+// FullFrom is Full drawing from the random source r, or the global source if nil.
+// As r is not safe for concurrent use, neither is the Transformation when r is given.
+func FullFrom(r *rand.Rand) Transformation {
 	return func(duration time.Duration) time.Duration {
 		// Panic prevention
 		if duration <= 0 {
 			return 0
 		}
-		return rand.N(duration)
+		return randN(r, duration)
 	}
 }
 
@@ -38,11 +44,25 @@ func Full() Transformation {
 //
 // Inspired by https://www.awsarchitectureblog.com/2015/03/backoff.html
 func Equal() Transformation {
+	return EqualFrom(nil)
+}
+
+// EqualFrom is Equal drawing from the random source r, or the global source if nil.
+// As r is not safe for concurrent use, neither is the Transformation when r is given.
+func EqualFrom(r *rand.Rand) Transformation {
 	return func(duration time.Duration) time.Duration {
 		// Panic prevention
 		if duration <= 0 {
 			return 0
 		}
-		return (duration / 2) + (rand.N(duration) / 2)
+		return (duration / 2) + (randN(r, duration) / 2)
+	}
+}
+
+// randN returns a random duration in [0, n) drawn from r, or the global source if nil.
+func randN(r *rand.Rand, n time.Duration) time.Duration {
+	if r != nil {
+		return time.Duration(r.Int64N(int64(n)))
 	}
+	return rand.N(n)
 }
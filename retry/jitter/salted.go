@@ -0,0 +1,30 @@
+package jitter
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"time"
+)
+
+// saltedSpread is the fraction of the range [n/2, n) randomly added to the salted position.
+const saltedSpread = 1.0 / 16
+
+// Salted creates a Transformation like Equal, with a result duration in [n/2, n),
+// but where in that range is fixed by the salt (eg an instance ID) with only a little
+// randomness added. Instances using different salts are spread evenly across the range,
+// so that they do not retry a recovering dependency at the same instant.
+func Salted(salt string) Transformation {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(salt))
+	position := float64(h.Sum64()) / (1 << 64)
+
+	return func(duration time.Duration) time.Duration {
+		// Panic prevention
+		if duration <= 0 {
+			return 0
+		}
+		offset := position + rand.Float64()*saltedSpread
+		offset -= float64(int(offset))
+		return (duration / 2) + time.Duration(offset*float64(duration/2))
+	}
+}
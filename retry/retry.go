@@ -3,6 +3,7 @@ package retry
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/jonboulle/clockwork"
@@ -23,6 +24,7 @@ const (
 	MaxDurationReached
 	PersistentErrorEncountered
 	ContextDone
+	BudgetExhausted
 )
 
 type options struct {
@@ -30,6 +32,13 @@ type options struct {
 	maxAttempts    int
 	treatUnknownAs errclass.Class
 	clock          clockwork.Clock
+	retryOn        []error
+	abortOn        []error
+	historySize    int
+	coordinator    Coordinator
+	budget         *Budget
+	onRetry        OnRetryFunc
+	onGiveUp       OnGiveUpFunc
 }
 
 type Option func(options *options)
@@ -64,6 +73,48 @@ func WithUnknownErrorsAs(class errclass.Class) Option {
 	}
 }
 
+// WithRetryOn allows users to always retry errors matching (via errors.Is) any of the given errors,
+// regardless of their class. This is useful for errors from libraries that cannot be re-wrapped.
+func WithRetryOn(errs ...error) Option {
+	return func(options *options) {
+		options.retryOn = append(options.retryOn, errs...)
+	}
+}
+
+// WithAbortOn allows users to never retry errors matching (via errors.Is) any of the given errors,
+// regardless of their class. This takes precedence over WithRetryOn.
+func WithAbortOn(errs ...error) Option {
+	return func(options *options) {
+		options.abortOn = append(options.abortOn, errs...)
+	}
+}
+
+// WithHistorySize sets the maximum number of groups of identical consecutive failures
+// kept in the History attached to the error when max attempts are reached (default 5).
+func WithHistorySize(size int) Option {
+	return func(options *options) {
+		options.historySize = size
+	}
+}
+
+// Coordinator shares failures of a dependency between instances, so that they do not all retry it at the same instant.
+// Coordination is best effort: errors are ignored, and retrying continues as if uncoordinated.
+type Coordinator interface {
+	// MarkFailure records that an attempt on the dependency failed at the given time.
+	MarkFailure(ctx context.Context, at time.Time) error
+	// LastFailure returns when an attempt on the dependency by any instance last failed, or the zero time if unknown.
+	LastFailure(ctx context.Context) (time.Time, error)
+}
+
+// WithCoordinator shares failures with other instances via the coordinator (eg natskv.FailureMarker).
+// If another instance fails while waiting to retry, the dependency has just been tried and is still failing,
+// so the next attempt is deferred until the delay has also passed since that failure.
+func WithCoordinator(coordinator Coordinator) Option {
+	return func(options *options) {
+		options.coordinator = coordinator
+	}
+}
+
 // Retrier wraps many settings in order to provide a highly customized retry function.
 type Retrier struct {
 	opts options
@@ -80,6 +131,7 @@ func NewRetrier(opts ...Option) (*Retrier, error) {
 		getStrategy:    defaultStrategy,
 		clock:          clockwork.NewRealClock(),
 		treatUnknownAs: errclass.Transient,
+		historySize:    defaultHistorySize,
 	}
 
 	// Apply provided options
@@ -108,6 +160,12 @@ func (r *Retrier) Try(ctx context.Context, f func() error) error {
 
 	// use a new copy of the desired Strategy on every use of `Try`
 	backoff := r.opts.getStrategy()
+	if budgeted, ok := backoff.(strategy.DeadlineAware); ok {
+		if deadline, ok := ctx.Deadline(); ok {
+			budgeted.Budget(r.opts.clock, deadline, r.opts.maxAttempts)
+		}
+	}
+	history := historyRecorder{size: max(r.opts.historySize, 1)}
 
 retryLoop:
 	for ; ; currentAttempt++ {
@@ -131,10 +189,7 @@ retryLoop:
 		err = calm.Unpanic(f)
 
 		// stop if successful or error is persistent
-		errorClass := errclass.GetClass(err)
-		if errorClass == errclass.Unknown {
-			errorClass = r.opts.treatUnknownAs
-		}
+		errorClass := r.classify(err)
 
 		switch errorClass {
 		case errclass.Nil:
@@ -145,16 +200,99 @@ retryLoop:
 			break retryLoop
 		}
 
-		// otherwise wait for the next calculated delay
-		r.wait(ctx, backoff.NextDelay())
+		// otherwise wait for the next calculated delay, if another attempt will follow and the budget allows it
+		history.recordFailure(currentAttempt, err)
+		if r.opts.maxAttempts > 0 && currentAttempt >= r.opts.maxAttempts {
+			// stop without waiting, reported as max attempts reached at the top of the loop
+			continue retryLoop
+		}
+		if r.opts.budget != nil && !r.opts.budget.take(r.opts.clock.Now()) {
+			cause = BudgetExhausted
+			break retryLoop
+		}
+		delay := backoff.NextDelay()
+		if r.opts.onRetry != nil {
+			r.opts.onRetry(classified(err, errorClass), Stats{
+				AttemptNumber: currentAttempt,
+				Duration:      r.opts.clock.Since(now),
+			}, delay)
+		}
+		failedAt := r.markFailure(ctx)
+		r.wait(ctx, delay)
+		history.recordDelay(delay)
+
+		// if another instance failed in the meantime, the dependency has just been tried, so wait once more
+		if extra := r.deferral(ctx, failedAt, delay); extra > 0 {
+			r.wait(ctx, extra)
+			history.recordDelay(extra)
+		}
+	}
+
+	// include the attempt history when giving up on a retryable error
+	if cause == MaxAttemptsReached || cause == BudgetExhausted {
+		err = xerrors.Extend(history.history, err)
 	}
 
 	// include RetryStats in the returned (non-nil) error
-	return xerrors.Extend(Stats{
+	stats := Stats{
 		AttemptNumber: currentAttempt,
 		Duration:      r.opts.clock.Since(now),
 		Cause:         cause,
-	}, err)
+	}
+	err = xerrors.Extend(stats, err)
+	if cause != Success && r.opts.onGiveUp != nil {
+		r.opts.onGiveUp(err, stats)
+	}
+	return err
+}
+
+// classify determines the class of the error, applying any overrides.
+func (r *Retrier) classify(err error) errclass.Class {
+	if err != nil {
+		if matchesAny(err, r.opts.abortOn) {
+			return errclass.Persistent
+		}
+		if matchesAny(err, r.opts.retryOn) {
+			return errclass.Transient
+		}
+	}
+
+	errorClass := errclass.GetClass(err)
+	if errorClass == errclass.Unknown {
+		errorClass = r.opts.treatUnknownAs
+	}
+	return errorClass
+}
+
+func matchesAny(err error, targets []error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// markFailure records the failure with the coordinator, if any, and returns when it occurred.
+func (r *Retrier) markFailure(ctx context.Context) time.Time {
+	failedAt := r.opts.clock.Now()
+	if r.opts.coordinator != nil {
+		_ = r.opts.coordinator.MarkFailure(ctx, failedAt)
+	}
+	return failedAt
+}
+
+// deferral returns how much longer to wait so that the next attempt is at least delay
+// after the most recent failure by any instance, if that is more recent than failedAt.
+func (r *Retrier) deferral(ctx context.Context, failedAt time.Time, delay time.Duration) time.Duration {
+	if r.opts.coordinator == nil || ctx.Err() != nil {
+		return 0
+	}
+	lastFailure, err := r.opts.coordinator.LastFailure(ctx)
+	if err != nil || !lastFailure.After(failedAt) {
+		return 0
+	}
+	return lastFailure.Add(delay).Sub(r.opts.clock.Now())
 }
 
 // wait blocks for duration d or until the context is done.
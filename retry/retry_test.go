@@ -1,15 +1,20 @@
 package retry_test
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"log/slog"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/zircuit-labs/zkr-go-common/retry"
 	"github.com/zircuit-labs/zkr-go-common/retry/strategy"
+	"github.com/zircuit-labs/zkr-go-common/retry/testutils"
 	"github.com/zircuit-labs/zkr-go-common/xerrors"
 	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
 )
@@ -213,3 +218,467 @@ func TestRetrySemantics(t *testing.T) {
 		})
 	}
 }
+
+func TestRetryOnAbortOn(t *testing.T) {
+	t.Parallel()
+
+	noWait, err := strategy.NewConstant(0)
+	require.NoError(t, err)
+
+	errSentinel := fmt.Errorf("sentinel error")
+	errOther := fmt.Errorf("other error")
+	wrappedSentinel := fmt.Errorf("wrapped: %w", errSentinel)
+
+	testCases := []struct {
+		testName          string
+		opts              []retry.Option
+		errs              []error
+		expectedCause     retry.FailureCause
+		expectedAttemptNo int
+	}{
+		{
+			testName:          "retry on overrides persistent class",
+			opts:              []retry.Option{retry.WithRetryOn(errSentinel)},
+			errs:              []error{errclass.WrapAs(wrappedSentinel, errclass.Persistent)},
+			expectedCause:     retry.Success,
+			expectedAttemptNo: 0,
+		},
+		{
+			testName:          "retry on overrides unknown as persistent",
+			opts:              []retry.Option{retry.WithRetryOn(errSentinel), retry.WithUnknownErrorsAs(errclass.Persistent)},
+			errs:              []error{wrappedSentinel, wrappedSentinel, wrappedSentinel, wrappedSentinel},
+			expectedCause:     retry.MaxAttemptsReached,
+			expectedAttemptNo: 4,
+		},
+		{
+			testName:          "abort on overrides transient class",
+			opts:              []retry.Option{retry.WithAbortOn(errSentinel)},
+			errs:              []error{errclass.WrapAs(wrappedSentinel, errclass.Transient)},
+			expectedCause:     retry.PersistentErrorEncountered,
+			expectedAttemptNo: 1,
+		},
+		{
+			testName:          "abort on takes precedence over retry on",
+			opts:              []retry.Option{retry.WithRetryOn(errSentinel), retry.WithAbortOn(errOther, errSentinel)},
+			errs:              []error{wrappedSentinel},
+			expectedCause:     retry.PersistentErrorEncountered,
+			expectedAttemptNo: 1,
+		},
+		{
+			testName:          "non-matching errors use class",
+			opts:              []retry.Option{retry.WithAbortOn(errOther)},
+			errs:              []error{errTransient, errPersistent},
+			expectedCause:     retry.PersistentErrorEncountered,
+			expectedAttemptNo: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+
+			opts := append([]retry.Option{retry.WithStrategy(noWait), retry.WithMaxAttempts(3)}, tc.opts...)
+			retrier, err := retry.NewRetrier(opts...)
+			require.NoError(t, err)
+
+			f := &foo{errs: tc.errs}
+			err = retrier.Try(t.Context(), f.bar)
+			if tc.expectedCause == retry.Success {
+				assert.NoError(t, err)
+				return
+			}
+
+			stats, ok := xerrors.Extract[retry.Stats](err)
+			require.True(t, ok)
+			assert.Equal(t, tc.expectedCause, stats.Cause)
+			assert.Equal(t, tc.expectedAttemptNo, stats.AttemptNumber)
+		})
+	}
+}
+
+func TestHistory(t *testing.T) {
+	t.Parallel()
+
+	constant, err := strategy.NewConstant(time.Millisecond)
+	require.NoError(t, err)
+
+	errA := fmt.Errorf("connection refused")
+	errB := fmt.Errorf("timeout")
+	errs := []error{errA, errA, errB, errA, errA}
+
+	testCases := []struct {
+		testName string
+		opts     []retry.Option
+		expected retry.History
+	}{
+		{
+			testName: "all attempts",
+			expected: retry.History{
+				Attempts: []retry.AttemptSummary{
+					{Error: errA.Error(), FirstAttempt: 1, Count: 2, Delay: time.Millisecond * 2},
+					{Error: errB.Error(), FirstAttempt: 3, Count: 1, Delay: time.Millisecond},
+					{Error: errA.Error(), FirstAttempt: 4, Count: 2, Delay: time.Millisecond},
+				},
+			},
+		},
+		{
+			testName: "bounded",
+			opts:     []retry.Option{retry.WithHistorySize(2)},
+			expected: retry.History{
+				Attempts: []retry.AttemptSummary{
+					{Error: errB.Error(), FirstAttempt: 3, Count: 1, Delay: time.Millisecond},
+					{Error: errA.Error(), FirstAttempt: 4, Count: 2, Delay: time.Millisecond},
+				},
+				Omitted: 2,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+
+			opts := append([]retry.Option{retry.WithStrategy(constant), retry.WithMaxAttempts(len(errs))}, tc.opts...)
+			retrier, err := retry.NewRetrier(opts...)
+			require.NoError(t, err)
+
+			f := &foo{errs: errs}
+			err = retrier.Try(t.Context(), f.bar)
+			require.ErrorIs(t, err, errA)
+
+			history, ok := xerrors.Extract[retry.History](err)
+			require.True(t, ok)
+			assert.Equal(t, tc.expected, history)
+		})
+	}
+}
+
+func TestHistoryOnlyWhenExhausted(t *testing.T) {
+	t.Parallel()
+
+	noWait, err := strategy.NewConstant(0)
+	require.NoError(t, err)
+	retrier, err := retry.NewRetrier(retry.WithStrategy(noWait), retry.WithMaxAttempts(3))
+	require.NoError(t, err)
+
+	f := &foo{errs: []error{errTransient, errPersistent}}
+	err = retrier.Try(t.Context(), f.bar)
+	require.Error(t, err)
+
+	_, ok := xerrors.Extract[retry.History](err)
+	assert.False(t, ok)
+}
+
+func TestHistoryLogValue(t *testing.T) {
+	t.Parallel()
+
+	history := retry.History{
+		Attempts: []retry.AttemptSummary{
+			{Error: "timeout", FirstAttempt: 3, Count: 2, Delay: time.Second},
+		},
+		Omitted: 2,
+	}
+
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+	logger.Info("test", slog.Any("history", history))
+	assert.Contains(t, buf.String(), `"history":{"omitted":2,"attempt_3":{"error":"timeout","count":2,"delay":1000000000}}`)
+}
+
+func TestSpreadUsesDeadline(t *testing.T) {
+	t.Parallel()
+
+	// the fallback would exceed the deadline after the first attempt
+	spread, err := strategy.NewSpread(time.Hour)
+	require.NoError(t, err)
+	retrier, err := retry.NewRetrier(retry.WithStrategy(spread), retry.WithMaxAttempts(5))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Millisecond*500)
+	defer cancel()
+
+	start := time.Now()
+	f := &foo{errs: []error{errTransient, errTransient, errTransient, errTransient, errTransient}}
+	err = retrier.Try(ctx, f.bar)
+	require.ErrorIs(t, err, errTest)
+
+	// all attempts are made before the deadline, using a good part of the budget
+	stats, ok := xerrors.Extract[retry.Stats](err)
+	require.True(t, ok)
+	assert.Equal(t, retry.MaxAttemptsReached, stats.Cause)
+	assert.Equal(t, 5, f.count)
+	assert.Greater(t, time.Since(start), time.Millisecond*150)
+}
+
+// fakeCoordinator reports a failure by another instance some time after each marked failure.
+type fakeCoordinator struct {
+	mu      sync.Mutex
+	marks   []time.Time
+	another time.Duration
+	err     error
+}
+
+func (c *fakeCoordinator) MarkFailure(_ context.Context, at time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.marks = append(c.marks, at)
+	return c.err
+}
+
+func (c *fakeCoordinator) LastFailure(_ context.Context) (time.Time, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil || len(c.marks) == 0 {
+		return time.Time{}, c.err
+	}
+	return c.marks[len(c.marks)-1].Add(c.another), nil
+}
+
+func TestCoordinator(t *testing.T) {
+	t.Parallel()
+
+	constant, err := strategy.NewConstant(time.Millisecond * 10)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		testName    string
+		coordinator *fakeCoordinator
+		minDelay    time.Duration
+		maxDelay    time.Duration
+	}{
+		{
+			testName:    "no failures by other instances",
+			coordinator: &fakeCoordinator{},
+			minDelay:    time.Millisecond * 10,
+			maxDelay:    time.Millisecond * 40,
+		},
+		{
+			testName:    "another instance failed while waiting",
+			coordinator: &fakeCoordinator{another: time.Millisecond * 50},
+			minDelay:    time.Millisecond * 60,
+			maxDelay:    time.Millisecond * 200,
+		},
+		{
+			testName:    "coordinator errors are ignored",
+			coordinator: &fakeCoordinator{another: time.Millisecond * 50, err: errTest},
+			minDelay:    time.Millisecond * 10,
+			maxDelay:    time.Millisecond * 40,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+
+			retrier, err := retry.NewRetrier(retry.WithStrategy(constant), retry.WithMaxAttempts(2), retry.WithCoordinator(tc.coordinator))
+			require.NoError(t, err)
+
+			f := &foo{errs: []error{errTransient, errTransient}}
+			err = retrier.Try(t.Context(), f.bar)
+			require.ErrorIs(t, err, errTest)
+			assert.Len(t, tc.coordinator.marks, 1)
+
+			stats, ok := xerrors.Extract[retry.Stats](err)
+			require.True(t, ok)
+			assert.GreaterOrEqual(t, stats.Duration, tc.minDelay)
+			assert.Less(t, stats.Duration, tc.maxDelay)
+		})
+	}
+}
+
+func TestNoWaitAfterLastAttempt(t *testing.T) {
+	t.Parallel()
+
+	constant, err := strategy.NewConstant(time.Second)
+	require.NoError(t, err)
+	clock := testutils.NewSimulatedClock(time.Now())
+	coordinator := &fakeCoordinator{another: time.Millisecond * 500}
+	var retries int
+	retrier, err := retry.NewRetrier(
+		retry.WithStrategy(constant),
+		retry.WithClock(clock),
+		retry.WithMaxAttempts(3),
+		retry.WithCoordinator(coordinator),
+		retry.WithOnRetry(func(error, retry.Stats, time.Duration) { retries++ }),
+	)
+	require.NoError(t, err)
+
+	// the last failure is neither waited after, deferred by the coordinator, nor announced as retried
+	f := &foo{errs: []error{errTransient, errTransient, errTransient}}
+	err = retrier.Try(t.Context(), f.bar)
+	require.ErrorIs(t, err, errTest)
+	assert.Equal(t, []time.Duration{time.Second, time.Millisecond * 500, time.Second, time.Millisecond * 500}, clock.Waits())
+	assert.Len(t, coordinator.marks, 2)
+	assert.Equal(t, 2, retries)
+
+	stats, ok := xerrors.Extract[retry.Stats](err)
+	require.True(t, ok)
+	assert.Equal(t, retry.MaxAttemptsReached, stats.Cause)
+	assert.Equal(t, time.Second*3, stats.Duration)
+}
+
+func TestBudget(t *testing.T) {
+	t.Parallel()
+
+	_, err := retry.NewBudget(0, time.Second)
+	require.ErrorIs(t, err, retry.ErrInvalidBudget)
+	_, err = retry.NewBudget(1, 0)
+	require.ErrorIs(t, err, retry.ErrInvalidBudget)
+
+	constant, err := strategy.NewConstant(time.Millisecond)
+	require.NoError(t, err)
+	budget, err := retry.NewBudget(2, time.Millisecond*100)
+	require.NoError(t, err)
+
+	// the budget is shared between retriers
+	first, err := retry.NewRetrier(retry.WithStrategy(constant), retry.WithMaxAttempts(10), retry.WithBudget(budget))
+	require.NoError(t, err)
+	second, err := retry.NewRetrier(retry.WithStrategy(constant), retry.WithMaxAttempts(10), retry.WithBudget(budget))
+	require.NoError(t, err)
+
+	f := &foo{errs: []error{errTransient, errTransient, errTransient, errTransient}}
+	err = first.Try(t.Context(), f.bar)
+	require.ErrorIs(t, err, errTest)
+	stats, ok := xerrors.Extract[retry.Stats](err)
+	require.True(t, ok)
+	assert.Equal(t, retry.BudgetExhausted, stats.Cause)
+	assert.Equal(t, 3, stats.AttemptNumber)
+	_, ok = xerrors.Extract[retry.History](err)
+	assert.True(t, ok)
+
+	// once exhausted, other retriers fail fast after their first attempt
+	f = &foo{errs: []error{errTransient}}
+	err = second.Try(t.Context(), f.bar)
+	stats, ok = xerrors.Extract[retry.Stats](err)
+	require.True(t, ok)
+	assert.Equal(t, retry.BudgetExhausted, stats.Cause)
+	assert.Equal(t, 1, f.count)
+
+	// first attempts are free, and the budget refills over time
+	f = &foo{}
+	require.NoError(t, second.Try(t.Context(), f.bar))
+	time.Sleep(time.Millisecond * 60)
+	f = &foo{errs: []error{errTransient}}
+	require.NoError(t, second.Try(t.Context(), f.bar))
+	assert.Equal(t, 2, f.count)
+}
+
+func TestBudgetLastAttempt(t *testing.T) {
+	t.Parallel()
+
+	constant, err := strategy.NewConstant(time.Millisecond)
+	require.NoError(t, err)
+	budget, err := retry.NewBudget(1, time.Hour)
+	require.NoError(t, err)
+	retrier, err := retry.NewRetrier(retry.WithStrategy(constant), retry.WithMaxAttempts(1), retry.WithBudget(budget))
+	require.NoError(t, err)
+
+	// no retry follows the last attempt, so the budget is not spent on it
+	for range 2 {
+		f := &foo{errs: []error{errTransient}}
+		err = retrier.Try(t.Context(), f.bar)
+		stats, ok := xerrors.Extract[retry.Stats](err)
+		require.True(t, ok)
+		assert.Equal(t, retry.MaxAttemptsReached, stats.Cause)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	t.Parallel()
+
+	constant, err := strategy.NewConstant(time.Millisecond)
+	require.NoError(t, err)
+
+	type retried struct {
+		class errclass.Class
+		stats retry.Stats
+		delay time.Duration
+	}
+	var retries []retried
+	var gaveUp []retry.Stats
+	retrier, err := retry.NewRetrier(
+		retry.WithStrategy(constant),
+		retry.WithMaxAttempts(3),
+		retry.WithOnRetry(func(err error, stats retry.Stats, nextDelay time.Duration) {
+			assert.ErrorIs(t, err, errTest)
+			retries = append(retries, retried{class: errclass.GetClass(err), stats: stats, delay: nextDelay})
+		}),
+		retry.WithOnGiveUp(func(err error, stats retry.Stats) {
+			assert.ErrorIs(t, err, errTest)
+			gaveUp = append(gaveUp, stats)
+		}),
+	)
+	require.NoError(t, err)
+
+	// unknown errors are passed to the hook classified as retried
+	f := &foo{errs: []error{errTest, errTransient, errTransient}}
+	err = retrier.Try(t.Context(), f.bar)
+	require.ErrorIs(t, err, errTest)
+	require.Len(t, retries, 2)
+	for i, r := range retries {
+		assert.Equal(t, errclass.Transient, r.class)
+		assert.Equal(t, i+1, r.stats.AttemptNumber)
+		assert.Equal(t, time.Millisecond, r.delay)
+	}
+	require.Len(t, gaveUp, 1)
+	assert.Equal(t, retry.MaxAttemptsReached, gaveUp[0].Cause)
+	assert.Equal(t, 4, gaveUp[0].AttemptNumber)
+
+	// giving up on a persistent error does not retry
+	retries, gaveUp = nil, nil
+	f = &foo{errs: []error{errPersistent}}
+	require.ErrorIs(t, retrier.Try(t.Context(), f.bar), errTest)
+	assert.Empty(t, retries)
+	require.Len(t, gaveUp, 1)
+	assert.Equal(t, retry.PersistentErrorEncountered, gaveUp[0].Cause)
+
+	// success calls neither hook
+	retries, gaveUp = nil, nil
+	f = &foo{errs: []error{errTransient}}
+	require.NoError(t, retrier.Try(t.Context(), f.bar))
+	assert.Len(t, retries, 1)
+	assert.Empty(t, gaveUp)
+}
+
+func TestSimulatedSchedule(t *testing.T) {
+	t.Parallel()
+
+	t.Run("exponential", func(t *testing.T) {
+		t.Parallel()
+
+		exponential, err := strategy.NewExponential(time.Second, time.Second*10, strategy.WithoutJitter())
+		require.NoError(t, err)
+		clock := testutils.NewSimulatedClock(time.Now())
+		retrier, err := retry.NewRetrier(retry.WithStrategy(exponential), retry.WithClock(clock))
+		require.NoError(t, err)
+
+		f := &foo{errs: []error{errTransient, errTransient, errTransient, errTransient, errTransient}}
+		require.NoError(t, retrier.Try(t.Context(), f.bar))
+		assert.Equal(t, []time.Duration{time.Second, time.Second * 2, time.Second * 4, time.Second * 8, time.Second * 10}, clock.Waits())
+	})
+
+	t.Run("spread", func(t *testing.T) {
+		t.Parallel()
+
+		spread, err := strategy.NewSpread(time.Hour, strategy.WithoutJitter())
+		require.NoError(t, err)
+		clock := testutils.NewSimulatedClock(time.Now())
+		retrier, err := retry.NewRetrier(retry.WithStrategy(spread), retry.WithClock(clock), retry.WithMaxAttempts(4))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithDeadline(t.Context(), clock.Now().Add(time.Second*50))
+		defer cancel()
+
+		// attempts are spread across the 50s, with no wait after the last
+		f := &foo{errs: []error{errTransient, errTransient, errTransient, errTransient}}
+		err = retrier.Try(ctx, f.bar)
+		require.ErrorIs(t, err, errTest)
+		step := time.Millisecond * 12500
+		assert.Equal(t, []time.Duration{step, step, step}, clock.Waits())
+
+		stats, ok := xerrors.Extract[retry.Stats](err)
+		require.True(t, ok)
+		assert.Equal(t, retry.MaxAttemptsReached, stats.Cause)
+		assert.Equal(t, step*3, stats.Duration)
+	})
+}
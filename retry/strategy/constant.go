@@ -22,7 +22,7 @@ func NewConstant(delay time.Duration, opts ...Option) (Factory, error) {
 
 	// Set up default options
 	options := options{
-		jitterFunc: jitter.None(), // no jitter by default
+		jitter: noJitter, // no jitter by default
 	}
 
 	// Apply provided options
@@ -33,7 +33,7 @@ func NewConstant(delay time.Duration, opts ...Option) (Factory, error) {
 	return func() Strategy {
 		return &Constant{
 			delay:      delay,
-			jitterFunc: options.jitterFunc,
+			jitterFunc: options.newJitter(),
 		}
 	}, nil
 }
@@ -0,0 +1,65 @@
+package strategy
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// Decorrelated strategy picks each delay randomly between the initial delay and three times the previous delay,
+// so that delays grow roughly exponentially while instances which failed together quickly drift apart.
+// The randomness is inherent, so jitter options do not apply.
+//
+// Inspired by https://www.awsarchitectureblog.com/2015/03/backoff.html
+type Decorrelated struct {
+	initialDelay  time.Duration
+	maxDelay      time.Duration
+	previousDelay time.Duration
+	rand          *rand.Rand
+}
+
+// NewDecorrelated creates a new decorrelated jitter delay strategy factory.
+// Use WithSeed for deterministic delays in tests.
+func NewDecorrelated(initialDelay, maxDelay time.Duration, opts ...Option) (Factory, error) {
+	if initialDelay <= 0 {
+		return nil, stacktrace.Wrap(ErrInvalidInitialDelay)
+	}
+
+	options := options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return func() Strategy {
+		return &Decorrelated{
+			initialDelay: initialDelay,
+			maxDelay:     maxDelay,
+			rand:         options.rand(),
+		}
+	}, nil
+}
+
+// NextDelay returns the next delay time.
+func (s *Decorrelated) NextDelay() time.Duration {
+	upper := max(s.previousDelay, s.initialDelay)
+	if upper < math.MaxInt64/3 {
+		upper *= 3
+	}
+
+	delay := s.initialDelay + s.randN(upper-s.initialDelay)
+	s.previousDelay = min(delay, s.maxDelay)
+	return s.previousDelay
+}
+
+// randN returns a random duration in [0, n).
+func (s *Decorrelated) randN(n time.Duration) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	if s.rand != nil {
+		return time.Duration(s.rand.Int64N(int64(n)))
+	}
+	return rand.N(n)
+}
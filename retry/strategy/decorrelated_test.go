@@ -0,0 +1,65 @@
+package strategy_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zircuit-labs/zkr-go-common/retry/strategy"
+)
+
+func TestDecorrelated(t *testing.T) {
+	t.Parallel()
+
+	initial := time.Second
+	mx := time.Minute
+
+	t.Run("bounds", func(t *testing.T) {
+		t.Parallel()
+
+		factory, err := strategy.NewDecorrelated(initial, mx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		s := factory()
+		previous := initial
+		for range 100 {
+			delay := s.NextDelay()
+			if delay < initial || delay > mx {
+				t.Fatalf("delay out of bounds: %v", delay)
+			}
+			if delay > 3*previous {
+				t.Fatalf("delay %v more than three times the previous %v", delay, previous)
+			}
+			previous = delay
+		}
+	})
+
+	t.Run("seeded", func(t *testing.T) {
+		t.Parallel()
+
+		factory, err := strategy.NewDecorrelated(initial, mx, strategy.WithSeed(42))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// Ensure the factory produces the same delays each time
+		a, b := factory(), factory()
+		for range 20 {
+			expected, actual := a.NextDelay(), b.NextDelay()
+			if actual != expected {
+				t.Errorf("unexpected output: want: %v got %v", expected, actual)
+			}
+		}
+	})
+
+	t.Run("invalid initial delay", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := strategy.NewDecorrelated(0, mx)
+		if err == nil || !errors.Is(err, strategy.ErrInvalidInitialDelay) {
+			t.Fatalf("expected error of type: %v, got %v", strategy.ErrInvalidInitialDelay, err)
+		}
+	})
+}
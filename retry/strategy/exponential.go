@@ -25,8 +25,8 @@ func NewExponential(initialDelay, maxDelay time.Duration, opts ...Option) (Facto
 
 	// Set up default options
 	options := options{
-		jitterFunc: jitter.Full(), // full jitter by default
-		base:       2,             // default base is 2 for exponential backoff
+		jitter: jitter.FullFrom, // full jitter by default
+		base:   2,               // default base is 2 for exponential backoff
 	}
 
 	// Apply provided options
@@ -38,7 +38,7 @@ func NewExponential(initialDelay, maxDelay time.Duration, opts ...Option) (Facto
 		return &Exponential{
 			initialDelay: initialDelay,
 			maxDelay:     maxDelay,
-			jitterFunc:   options.jitterFunc,
+			jitterFunc:   options.newJitter(),
 			base:         options.base,
 		}
 	}, nil
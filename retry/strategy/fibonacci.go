@@ -0,0 +1,55 @@
+package strategy
+
+import (
+	"time"
+
+	"github.com/zircuit-labs/zkr-go-common/retry/jitter"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// Fibonacci strategy increases the delay following the Fibonacci sequence (with optional jitter),
+// ie 1, 1, 2, 3, 5, 8... times the initial delay. It grows more gently than an exponential strategy with base 2.
+type Fibonacci struct {
+	initialDelay  time.Duration
+	maxDelay      time.Duration
+	previousDelay time.Duration
+	currentDelay  time.Duration
+	jitterFunc    jitter.Transformation
+}
+
+// NewFibonacci creates a new Fibonacci delay strategy factory.
+func NewFibonacci(initialDelay, maxDelay time.Duration, opts ...Option) (Factory, error) {
+	if initialDelay <= 0 {
+		return nil, stacktrace.Wrap(ErrInvalidInitialDelay)
+	}
+
+	// Set up default options
+	options := options{
+		jitter: jitter.FullFrom, // full jitter by default
+	}
+
+	// Apply provided options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return func() Strategy {
+		return &Fibonacci{
+			initialDelay: initialDelay,
+			maxDelay:     maxDelay,
+			jitterFunc:   options.newJitter(),
+		}
+	}, nil
+}
+
+// NextDelay returns the next delay time.
+func (s *Fibonacci) NextDelay() time.Duration {
+	if s.currentDelay == 0 {
+		s.currentDelay = s.initialDelay
+	} else {
+		s.previousDelay, s.currentDelay = s.currentDelay, min(s.previousDelay+s.currentDelay, s.maxDelay)
+	}
+
+	actualDelay := s.jitterFunc(s.currentDelay)
+	return min(actualDelay, s.maxDelay)
+}
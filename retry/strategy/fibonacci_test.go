@@ -0,0 +1,75 @@
+package strategy_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zircuit-labs/zkr-go-common/retry/strategy"
+)
+
+func TestFibonacci(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		testName             string
+		initialDelay         int
+		maxDelay             int
+		expectedOutputDelays []int
+		expectedError        error
+	}{
+		{
+			testName:             "one second initial, max ten",
+			initialDelay:         1,
+			maxDelay:             10,
+			expectedOutputDelays: []int{1, 1, 2, 3, 5, 8, 10, 10, 10},
+		},
+		{
+			testName:             "two seconds initial, max 30",
+			initialDelay:         2,
+			maxDelay:             30,
+			expectedOutputDelays: []int{2, 2, 4, 6, 10, 16, 26, 30, 30},
+		},
+		{
+			testName:      "zero seconds",
+			initialDelay:  0,
+			expectedError: strategy.ErrInvalidInitialDelay,
+		},
+		{
+			testName:      "negative seconds",
+			initialDelay:  -100,
+			expectedError: strategy.ErrInvalidInitialDelay,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+
+			initial := time.Duration(tc.initialDelay) * time.Second
+			mx := time.Duration(tc.maxDelay) * time.Second
+			factory, err := strategy.NewFibonacci(initial, mx, strategy.WithoutJitter())
+			if tc.expectedError != nil {
+				if err == nil || !errors.Is(err, tc.expectedError) {
+					t.Fatalf("expected error of type: %v, got %v", tc.expectedError, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// Ensure the factory produces the same strategy by doing the test multiple times
+			for range 3 {
+				s := factory()
+
+				// Verify the pattern of the delay values
+				for _, expected := range tc.expectedOutputDelays {
+					actual := int(s.NextDelay().Seconds())
+					if actual != expected {
+						t.Errorf("unexpected output: want: %v got %v", expected, actual)
+					}
+				}
+			}
+		})
+	}
+}
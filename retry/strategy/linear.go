@@ -23,7 +23,7 @@ func NewLinear(initialDelay, maxDelay time.Duration, opts ...Option) (Factory, e
 
 	// Set up default options
 	options := options{
-		jitterFunc: jitter.Full(), // full jitter by default
+		jitter: jitter.FullFrom, // full jitter by default
 	}
 
 	// Apply provided options
@@ -35,7 +35,7 @@ func NewLinear(initialDelay, maxDelay time.Duration, opts ...Option) (Factory, e
 		return &Linear{
 			initialDelay: initialDelay,
 			maxDelay:     maxDelay,
-			jitterFunc:   options.jitterFunc,
+			jitterFunc:   options.newJitter(),
 		}
 	}, nil
 }
@@ -1,25 +1,33 @@
 package strategy
 
-import "github.com/zircuit-labs/zkr-go-common/retry/jitter"
+import (
+	"math/rand/v2"
+
+	"github.com/zircuit-labs/zkr-go-common/retry/jitter"
+)
 
 type options struct {
-	jitterFunc jitter.Transformation
-	base       int // field to define the power base
+	jitter  func(r *rand.Rand) jitter.Transformation // creates the jitter of each Strategy, given its random source
+	base    int                                      // field to define the power base
+	newRand func() *rand.Rand                        // random source of each Strategy, nil for the global source
 }
 
 type Option func(options *options)
 
 // WithJitter allows users to specify usage jitter function of their choice.
+// The function is used as is, so is not affected by WithSeed.
 func WithJitter(jitterFunc jitter.Transformation) Option {
 	return func(options *options) {
-		options.jitterFunc = jitterFunc
+		options.jitter = func(*rand.Rand) jitter.Transformation {
+			return jitterFunc
+		}
 	}
 }
 
 // WithoutJitter allows users to forego jitter function usage.
 func WithoutJitter() Option {
 	return func(options *options) {
-		options.jitterFunc = jitter.None()
+		options.jitter = noJitter
 	}
 }
 
@@ -29,3 +37,31 @@ func WithBase(base int) Option {
 		options.base = base
 	}
 }
+
+// WithSeed makes the random delays of strategies deterministic, for testing purposes, whether drawn by their
+// default jitter or by the strategy itself (ie Decorrelated). Each Strategy created by the Factory gets its own
+// source seeded with the seed, so produces the same delays. Jitter given with WithJitter is not affected.
+func WithSeed(seed uint64) Option {
+	return func(options *options) {
+		options.newRand = func() *rand.Rand {
+			return rand.New(rand.NewPCG(seed, seed)) //nolint:gosec // not used for security
+		}
+	}
+}
+
+// rand returns the random source for a new Strategy, or nil for the global source.
+func (o options) rand() *rand.Rand {
+	if o.newRand == nil {
+		return nil
+	}
+	return o.newRand()
+}
+
+// newJitter returns the jitter for a new Strategy.
+func (o options) newJitter() jitter.Transformation {
+	return o.jitter(o.rand())
+}
+
+func noJitter(*rand.Rand) jitter.Transformation {
+	return jitter.None()
+}
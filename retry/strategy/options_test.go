@@ -0,0 +1,80 @@
+package strategy_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zircuit-labs/zkr-go-common/retry/strategy"
+)
+
+func TestWithSeed(t *testing.T) {
+	t.Parallel()
+
+	initial, mx := time.Second, time.Minute
+	testCases := []struct {
+		testName string
+		factory  func(opts ...strategy.Option) (strategy.Factory, error)
+	}{
+		{
+			testName: "exponential",
+			factory: func(opts ...strategy.Option) (strategy.Factory, error) {
+				return strategy.NewExponential(initial, mx, opts...)
+			},
+		},
+		{
+			testName: "fibonacci",
+			factory: func(opts ...strategy.Option) (strategy.Factory, error) {
+				return strategy.NewFibonacci(initial, mx, opts...)
+			},
+		},
+		{
+			testName: "linear",
+			factory: func(opts ...strategy.Option) (strategy.Factory, error) {
+				return strategy.NewLinear(initial, mx, opts...)
+			},
+		},
+		{
+			testName: "spread",
+			factory: func(opts ...strategy.Option) (strategy.Factory, error) {
+				return strategy.NewSpread(initial, opts...)
+			},
+		},
+		{
+			testName: "decorrelated",
+			factory: func(opts ...strategy.Option) (strategy.Factory, error) {
+				return strategy.NewDecorrelated(initial, mx, opts...)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+
+			seeded, err := tc.factory(strategy.WithSeed(42))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			unjittered, err := tc.factory(strategy.WithoutJitter())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// Ensure the default jitter is applied, and draws the same delays for each strategy of the factory
+			a, b, c := seeded(), seeded(), unjittered()
+			jittered := false
+			for range 20 {
+				expected, actual := a.NextDelay(), b.NextDelay()
+				if actual != expected {
+					t.Errorf("unexpected output: want: %v got %v", expected, actual)
+				}
+				if actual != c.NextDelay() {
+					jittered = true
+				}
+			}
+			if !jittered {
+				t.Errorf("expected jittered delays")
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package strategy
+
+import (
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/zircuit-labs/zkr-go-common/retry/jitter"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// DeadlineAware is implemented by strategies which adapt to the deadline of the context given to Retrier.Try.
+type DeadlineAware interface {
+	Strategy
+	// Budget is called before the first attempt when the context has a deadline, with the clock
+	// used by the retrier and its maximum number of attempts (less than 1 if unlimited).
+	Budget(clock clockwork.Clock, deadline time.Time, maxAttempts int)
+}
+
+// Spread strategy spreads the remaining attempts evenly (with optional jitter) across the time
+// remaining until the deadline, so that operations which must complete before a deadline use
+// their full time budget. Each delay leaves the last attempt as much time to run as the others.
+// Without a deadline or a maximum number of attempts, the fallback delay is used instead.
+type Spread struct {
+	fallback    time.Duration
+	jitterFunc  jitter.Transformation
+	clock       clockwork.Clock
+	deadline    time.Time
+	maxAttempts int
+	attempts    int
+}
+
+// NewSpread creates a new deadline spreading strategy factory.
+// Unlike other strategies, the default jitter is Equal so that attempts remain evenly spread.
+func NewSpread(fallback time.Duration, opts ...Option) (Factory, error) {
+	if fallback < 0 {
+		return nil, stacktrace.Wrap(ErrInvalidInitialDelay)
+	}
+
+	// Set up default options
+	options := options{
+		jitter: jitter.EqualFrom,
+	}
+
+	// Apply provided options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return func() Strategy {
+		return &Spread{
+			fallback:   fallback,
+			jitterFunc: options.newJitter(),
+		}
+	}, nil
+}
+
+// Budget implements DeadlineAware.
+func (s *Spread) Budget(clock clockwork.Clock, deadline time.Time, maxAttempts int) {
+	s.clock = clock
+	s.deadline = deadline
+	s.maxAttempts = maxAttempts
+}
+
+// NextDelay returns the next delay time.
+func (s *Spread) NextDelay() time.Duration {
+	// NextDelay is called after each failed attempt
+	s.attempts++
+	if s.clock == nil || s.maxAttempts < 1 {
+		return s.jitterFunc(s.fallback)
+	}
+
+	// no need to wait after the last attempt, or once the deadline has passed
+	remainingAttempts := s.maxAttempts - s.attempts
+	remaining := s.deadline.Sub(s.clock.Now())
+	if remainingAttempts < 1 || remaining <= 0 {
+		return 0
+	}
+	return s.jitterFunc(remaining / time.Duration(remainingAttempts+1))
+}
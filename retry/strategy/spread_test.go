@@ -0,0 +1,91 @@
+package strategy_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/retry/strategy"
+)
+
+func TestSpread(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		testName    string
+		budget      time.Duration
+		maxAttempts int
+		// attemptDurations is how long each attempt takes before the next delay is requested
+		attemptDurations []time.Duration
+		expectedDelays   []time.Duration
+	}{
+		{
+			testName:         "no deadline uses fallback",
+			maxAttempts:      5,
+			attemptDurations: []time.Duration{0, 0, 0},
+			expectedDelays:   []time.Duration{time.Minute, time.Minute, time.Minute},
+		},
+		{
+			testName:         "unlimited attempts uses fallback",
+			budget:           time.Second * 10,
+			attemptDurations: []time.Duration{0, 0},
+			expectedDelays:   []time.Duration{time.Minute, time.Minute},
+		},
+		{
+			testName:         "instant attempts are spread evenly",
+			budget:           time.Second * 10,
+			maxAttempts:      5,
+			attemptDurations: []time.Duration{0, 0, 0, 0, 0},
+			expectedDelays:   []time.Duration{time.Second * 2, time.Second * 2, time.Second * 2, time.Second * 2, 0},
+		},
+		{
+			testName:         "slow attempts reduce later delays",
+			budget:           time.Second * 10,
+			maxAttempts:      3,
+			attemptDurations: []time.Duration{time.Second * 4, time.Second, 0},
+			expectedDelays:   []time.Duration{time.Second * 2, time.Millisecond * 1500, 0},
+		},
+		{
+			testName:         "deadline passed",
+			budget:           time.Second,
+			maxAttempts:      3,
+			attemptDurations: []time.Duration{time.Second * 2},
+			expectedDelays:   []time.Duration{0},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+
+			factory, err := strategy.NewSpread(time.Minute, strategy.WithoutJitter())
+			require.NoError(t, err)
+
+			clock := clockwork.NewFakeClock()
+			s := factory()
+			if tc.budget > 0 {
+				spread, ok := s.(strategy.DeadlineAware)
+				require.True(t, ok)
+				spread.Budget(clock, clock.Now().Add(tc.budget), tc.maxAttempts)
+			}
+
+			delays := make([]time.Duration, 0, len(tc.attemptDurations))
+			for _, d := range tc.attemptDurations {
+				clock.Advance(d)
+				delay := s.NextDelay()
+				delays = append(delays, delay)
+				clock.Advance(delay)
+			}
+			assert.Equal(t, tc.expectedDelays, delays)
+		})
+	}
+}
+
+func TestSpreadInvalid(t *testing.T) {
+	t.Parallel()
+	_, err := strategy.NewSpread(-time.Second)
+	require.ErrorIs(t, err, strategy.ErrInvalidInitialDelay)
+}
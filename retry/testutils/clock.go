@@ -0,0 +1,59 @@
+package testutils
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+)
+
+// SimulatedClock is a clockwork.Clock for simulating retries (see retry.WithClock) without real sleeps.
+// Rather than waiting, every timer fires immediately, advancing the clock's time by its duration.
+// The durations waited are recorded, so tests can verify backoff schedules deterministically.
+// It is safe for concurrent use, but only simulates a single goroutine waiting at a time.
+type SimulatedClock struct {
+	*clockwork.FakeClock
+
+	mu    sync.Mutex
+	waits []time.Duration
+}
+
+// NewSimulatedClock creates a SimulatedClock starting at the given time.
+func NewSimulatedClock(start time.Time) *SimulatedClock {
+	return &SimulatedClock{FakeClock: clockwork.NewFakeClockAt(start)}
+}
+
+// NewTimer returns a timer which has already fired, having advanced the clock by d.
+func (c *SimulatedClock) NewTimer(d time.Duration) clockwork.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.waits = append(c.waits, d)
+	t := c.FakeClock.NewTimer(d)
+	c.FakeClock.Advance(d)
+	return t
+}
+
+// After returns a channel which has already received the time, having advanced the clock by d.
+func (c *SimulatedClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).Chan()
+}
+
+// Sleep advances the clock by d without blocking.
+func (c *SimulatedClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// Waits returns the durations waited so far, in order.
+func (c *SimulatedClock) Waits() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]time.Duration(nil), c.waits...)
+}
+
+// Reset forgets the durations waited so far, without changing the time.
+func (c *SimulatedClock) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waits = nil
+}
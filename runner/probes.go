@@ -0,0 +1,32 @@
+package runner
+
+import (
+	"github.com/zircuit-labs/zkr-go-common/http/echotask/healthcheck"
+	"github.com/zircuit-labs/zkr-go-common/task"
+)
+
+// probingRunner adds a readiness check for each task given to Run which implements healthcheck.Checker.
+// Tasks given to RunTerminable are expected to stop, after which their health should not affect readiness.
+type probingRunner struct {
+	Runner
+	probes *healthcheck.Probes
+	name   func(t task.Task) string // the name of the task as run by the underlying Runner
+}
+
+func newProbingRunner(tm Runner, probes *healthcheck.Probes, name func(t task.Task) string) Runner {
+	if probes == nil {
+		return tm
+	}
+	return &probingRunner{Runner: tm, probes: probes, name: name}
+}
+
+// Run implements Runner.
+func (p *probingRunner) Run(tasks ...task.Task) {
+	for _, t := range tasks {
+		t, _ = task.Dependencies(t)
+		if checker, ok := t.(healthcheck.Checker); ok {
+			p.probes.AddReadinessCheck(p.name(t), checker)
+		}
+	}
+	p.Runner.Run(tasks...)
+}
@@ -0,0 +1,59 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/http/echotask/healthcheck"
+	"github.com/zircuit-labs/zkr-go-common/task"
+)
+
+// checkedTask is a task implementing healthcheck.Checker.
+type checkedTask struct {
+	starterTask
+	err error
+}
+
+func (t *checkedTask) HealthCheck(context.Context) error {
+	return t.err
+}
+
+func (t *checkedTask) Name() string {
+	return "checked"
+}
+
+func TestProbingRunner(t *testing.T) {
+	t.Parallel()
+
+	tm := task.NewManager()
+	probes := healthcheck.NewProbes()
+	probes.MarkStarted()
+
+	// without probes, the runner is unchanged
+	assert.Equal(t, Runner(tm), newProbingRunner(tm, nil, task.Task.Name))
+
+	r := newProbingRunner(tm, probes, task.Task.Name)
+	w := newProbingRunner(newWorkerRunner(tm, 1, &sync.Mutex{}), probes, func(t task.Task) string {
+		return workerTaskName(t, 1)
+	})
+
+	healthy := &checkedTask{}
+	unhealthy := &checkedTask{err: errors.New("disconnected")}
+	r.Run(task.DependsOn(healthy, &starterTask{}))
+	w.Run(unhealthy)
+	r.RunTerminable(&checkedTask{err: errors.New("finished")})
+
+	resp, ok := probes.Ready(t.Context())
+	assert.False(t, ok)
+	assert.Equal(t, map[string]string{
+		"checked":            "ok",
+		"checked (worker 1)": "failed",
+	}, resp.Checks)
+
+	require.NoError(t, tm.Stop())
+}
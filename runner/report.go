@@ -0,0 +1,142 @@
+package runner
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/log/identity"
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+	"github.com/zircuit-labs/zkr-go-common/task"
+	"github.com/zircuit-labs/zkr-go-common/version"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const reportFlushTimeout = 5 * time.Second
+
+// Exit reasons of an ExitReport.
+const (
+	ExitNormal = "normal"
+	ExitError  = "error"
+	ExitPanic  = "panic"
+)
+
+// ExitReport summarizes the run of a service when it exits. It is logged with the final log record,
+// and optionally published to NATS (see WithExitReport).
+type ExitReport struct {
+	Service    string    `json:"service"`
+	InstanceID string    `json:"instance_id"`
+	Version    string    `json:"version"`
+	Started    time.Time `json:"started"`
+	// Uptime is in seconds.
+	Uptime   float64 `json:"uptime"`
+	ExitCode int     `json:"exit_code"`
+	// Reason is one of ExitNormal, ExitError or ExitPanic.
+	Reason     string `json:"reason"`
+	Error      string `json:"error,omitempty"`
+	ErrorClass string `json:"error_class,omitempty"`
+	// Tasks is the final status of each task run, including the error and its class for those which failed.
+	Tasks []task.TaskState `json:"tasks"`
+}
+
+// LogValue implements slog.LogValuer.
+func (r ExitReport) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("reason", r.Reason),
+		slog.Int("exit_code", r.ExitCode),
+		slog.Time("started", r.Started),
+		slog.Float64("uptime", r.Uptime),
+		slog.Int("tasks_run", len(r.Tasks)),
+	}
+	if r.ErrorClass != "" {
+		attrs = append(attrs, slog.String("error_class", r.ErrorClass))
+	}
+
+	// only tasks which failed are logged, since the rest are unremarkable
+	var failed []slog.Attr
+	for _, state := range r.Tasks {
+		if state.Status == task.StatusFailed {
+			failed = append(failed, slog.Group(state.Name,
+				slog.String("error", state.Reason),
+				slog.String("class", state.Class),
+			))
+		}
+	}
+	if len(failed) > 0 {
+		attrs = append(attrs, slog.GroupAttrs("failed_tasks", failed...))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// runState is the state of the service collected by protectedRun for the exit report.
+type runState struct {
+	started time.Time
+	cfg     *config.Configuration
+	tm      *task.Manager
+}
+
+// newExitReport reports the exit of the service with the error (if any) returned by protectedRun.
+func newExitReport(state *runState, err error) ExitReport {
+	name, id := identity.WhoAmI()
+	report := ExitReport{
+		Service:    name,
+		InstanceID: id,
+		Version:    version.Info.Version,
+		Started:    state.started,
+		Uptime:     time.Since(state.started).Seconds(),
+		Reason:     ExitNormal,
+	}
+	if state.tm != nil {
+		report.Tasks = state.tm.Statuses()
+	}
+
+	class := errclass.GetClass(err)
+	switch class {
+	case errclass.Nil:
+		return report
+	case errclass.Panic:
+		report.Reason = ExitPanic
+		report.ExitCode = exitPanic
+	default:
+		report.Reason = ExitError
+		report.ExitCode = exitError
+	}
+	report.Error = err.Error()
+	report.ErrorClass = class.String()
+	return report
+}
+
+// publisher is the subset of *nats.Conn used to publish the exit report.
+type publisher interface {
+	Publish(subject string, data []byte) error
+	FlushTimeout(timeout time.Duration) error
+}
+
+// publishExitReport publishes the report to the subject using a new NATS connection.
+// Failures are only logged, since the service is exiting regardless.
+func publishExitReport(cfg *config.Configuration, subject string, report ExitReport, logger *slog.Logger) {
+	nc, err := messagebus.NewNatsConnection(cfg)
+	if err != nil {
+		logger.Error("failed to publish exit report", log.ErrAttr(err))
+		return
+	}
+	defer nc.Close()
+
+	if err := publishReport(nc, subject, report); err != nil {
+		logger.Error("failed to publish exit report", log.ErrAttr(err))
+	}
+}
+
+func publishReport(p publisher, subject string, report ExitReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return stacktrace.Wrap(err)
+	}
+	if err := p.Publish(subject, data); err != nil {
+		return stacktrace.Wrap(err)
+	}
+	return stacktrace.Wrap(p.FlushTimeout(reportFlushTimeout))
+}
@@ -0,0 +1,107 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/calm"
+	"github.com/zircuit-labs/zkr-go-common/task"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+type failingTask struct {
+	err error
+}
+
+func (t failingTask) Run(context.Context) error {
+	return t.err
+}
+
+func (failingTask) Name() string {
+	return "failing"
+}
+
+type fakePublisher struct {
+	subject string
+	data    []byte
+}
+
+func (p *fakePublisher) Publish(subject string, data []byte) error {
+	p.subject = subject
+	p.data = data
+	return nil
+}
+
+func (p *fakePublisher) FlushTimeout(time.Duration) error {
+	return nil
+}
+
+func TestExitReport(t *testing.T) {
+	t.Parallel()
+
+	tm := task.NewManager()
+	tm.Run(failingTask{err: errclass.WrapAs(errors.New("disk full"), errclass.Persistent)}, &starterTask{})
+	err := tm.Wait()
+	require.Error(t, err)
+
+	state := &runState{started: time.Now().Add(-time.Minute), tm: tm}
+	report := newExitReport(state, err)
+	assert.Equal(t, ExitError, report.Reason)
+	assert.Equal(t, exitError, report.ExitCode)
+	assert.Equal(t, "persistent", report.ErrorClass)
+	assert.GreaterOrEqual(t, report.Uptime, time.Minute.Seconds())
+	require.Len(t, report.Tasks, 2)
+	assert.Equal(t, task.StatusFailed, report.Tasks[0].Status)
+	assert.Equal(t, "persistent", report.Tasks[0].Class)
+	assert.Equal(t, task.StatusStopped, report.Tasks[1].Status)
+
+	// only failed tasks are logged
+	buf := &bytes.Buffer{}
+	slog.New(slog.NewJSONHandler(buf, nil)).Info("exit", slog.Any("report", report))
+	var logged struct {
+		Report struct {
+			Reason      string                       `json:"reason"`
+			TasksRun    int                          `json:"tasks_run"`
+			FailedTasks map[string]map[string]string `json:"failed_tasks"`
+		} `json:"report"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &logged))
+	assert.Equal(t, ExitError, logged.Report.Reason)
+	assert.Equal(t, 2, logged.Report.TasksRun)
+	assert.Equal(t, map[string]map[string]string{
+		"failing": {"error": report.Tasks[0].Reason, "class": "persistent"},
+	}, logged.Report.FailedTasks)
+
+	// published as JSON
+	p := &fakePublisher{}
+	require.NoError(t, publishReport(p, "service.exits", report))
+	assert.Equal(t, "service.exits", p.subject)
+	var published ExitReport
+	require.NoError(t, json.Unmarshal(p.data, &published))
+	assert.Equal(t, report.Tasks[0].Reason, published.Tasks[0].Reason)
+	assert.Equal(t, report.Error, published.Error)
+}
+
+func TestExitReportReasons(t *testing.T) {
+	t.Parallel()
+
+	// the service may fail before the task manager is created
+	report := newExitReport(&runState{started: time.Now()}, nil)
+	assert.Equal(t, ExitNormal, report.Reason)
+	assert.Zero(t, report.ExitCode)
+	assert.Empty(t, report.Tasks)
+
+	err := calm.Unpanic(func() error { panic("boom") })
+	report = newExitReport(&runState{started: time.Now()}, err)
+	assert.Equal(t, ExitPanic, report.Reason)
+	assert.Equal(t, exitPanic, report.ExitCode)
+	assert.Equal(t, "panic", report.ErrorClass)
+}
@@ -7,23 +7,31 @@ import (
 	"io/fs"
 	"log/slog"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/DataDog/dd-trace-go/v2/ddtrace/tracer"
 	"github.com/DataDog/dd-trace-go/v2/profiler"
 
 	"github.com/zircuit-labs/zkr-go-common/calm"
+	"github.com/zircuit-labs/zkr-go-common/calm/errgroup"
 	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/http/echotask"
+	"github.com/zircuit-labs/zkr-go-common/http/echotask/healthcheck"
 	"github.com/zircuit-labs/zkr-go-common/log"
 	"github.com/zircuit-labs/zkr-go-common/log/identity"
 	"github.com/zircuit-labs/zkr-go-common/messagebus"
 	"github.com/zircuit-labs/zkr-go-common/singleton"
 	"github.com/zircuit-labs/zkr-go-common/task"
 	"github.com/zircuit-labs/zkr-go-common/task/ossignal"
+	"github.com/zircuit-labs/zkr-go-common/task/watchdog"
 	"github.com/zircuit-labs/zkr-go-common/version"
-	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
 	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
 )
 
+// errStopped indicates that the service was stopped while waiting for the singleton lock.
+var errStopped = errors.New("stopped before acquiring lock")
+
 const (
 	exitError = 1
 	exitPanic = 2 // go standard exit code on panic
@@ -37,6 +45,12 @@ type runnerConfig struct {
 type options struct {
 	singleton       bool
 	useProvidedName bool
+	probes          *healthcheck.Probes
+	probeServer     string
+	workers         int
+	watchdog        []watchdog.Option
+	shutdownTimeout time.Duration
+	reportSubject   string
 }
 
 type Option func(options *options)
@@ -53,12 +67,69 @@ func UseProvidedName() Option {
 	}
 }
 
+// WithProbes ties the given probes to the service lifecycle: startup completes once
+// the Runnable returns successfully, and readiness fails as soon as shutdown begins.
+// Tasks given to Runner.Run which implement healthcheck.Checker are added as readiness checks,
+// named after the task.
+func WithProbes(probes *healthcheck.Probes) Option {
+	return func(options *options) {
+		options.probes = probes
+	}
+}
+
+// WithProbeServer serves the probes (see WithProbes, otherwise new probes are used) and the status
+// of each task on their own echotask server, configured at the given path (eg "probes.port").
+func WithProbeServer(cfgPath string) Option {
+	return func(options *options) {
+		options.probeServer = cfgPath
+	}
+}
+
+// WithWorkers runs the Runnable as n logical workers within the process, which is useful
+// for horizontally partitioned workloads. Each worker receives its index through the
+// Runner context (see WorkerIndex) and a logger with a "worker" attribute.
+// Combined with AsSingleton, each worker acquires its own lock using the key
+// "<service>-<index>" (see WorkerKey), so that across all instances each index runs exactly once.
+func WithWorkers(n int) Option {
+	return func(options *options) {
+		options.workers = n
+	}
+}
+
+// WithWatchdog runs a watchdog task alongside the service, which samples its resource usage
+// (see the watchdog package for the available options). When a hard limit is exceeded, all tasks
+// are stopped and the service exits with an error, so that it can be restarted.
+func WithWatchdog(opts ...watchdog.Option) Option {
+	return func(options *options) {
+		options.watchdog = append([]watchdog.Option{}, opts...)
+	}
+}
+
+// WithShutdownTimeout limits the time each task may take to return once it is stopped, unless set otherwise
+// with task.StopWithin. Tasks exceeding it are abandoned, and the service exits with an error.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(options *options) {
+		options.shutdownTimeout = timeout
+	}
+}
+
+// WithExitReport additionally publishes the ExitReport as JSON to the given NATS subject when the
+// service exits, so that the reason for a restart can be found without searching the logs.
+// The report is published using a new connection configured as for messagebus.NewNatsConnection.
+func WithExitReport(subject string) Option {
+	return func(options *options) {
+		options.reportSubject = subject
+	}
+}
+
 // Runner limits task manager interface.
 type Runner interface {
 	Run(tasks ...task.Task)
 	RunTerminable(tasks ...task.Task)
 	Cleanup(f func())
 	Context() context.Context
+	// Statuses returns the status of each task (see task.Manager), eg to serve with echotask.WithTaskStatus.
+	Statuses() []task.TaskState
 }
 
 // Runnable is a func that takes arguments provided by Run.
@@ -93,23 +164,30 @@ func Run(serviceName string, f fs.FS, run Runnable, opts ...Option) {
 
 	// execute the core run logic protected from direct panics.
 	// NOTE: goroutines spawned by `run` must be themselves protected.
+	state := &runState{started: time.Now()}
 	err = calm.Unpanic(func() error {
-		return protectedRun(f, run, logger, options)
+		return protectedRun(f, run, logger, options, state)
 	})
 
-	switch errclass.GetClass(err) {
-	case errclass.Nil:
-		logger.Info("service exited normally")
-	case errclass.Panic:
-		logger.Error("service failed with panic", log.ErrAttr(err))
-		os.Exit(exitPanic) //revive:disable:deep-exit // intentional
+	report := newExitReport(state, err)
+	if options.reportSubject != "" && state.cfg != nil {
+		publishExitReport(state.cfg, options.reportSubject, report, logger)
+	}
+
+	switch report.Reason {
+	case ExitNormal:
+		logger.Info("service exited normally", slog.Any("report", report))
+	case ExitPanic:
+		logger.Error("service failed with panic", log.ErrAttr(err), slog.Any("report", report))
 	default:
-		logger.Error("service failed with error", log.ErrAttr(err))
-		os.Exit(exitError) //revive:disable:deep-exit // intentional
+		logger.Error("service failed with error", log.ErrAttr(err), slog.Any("report", report))
+	}
+	if report.ExitCode != 0 {
+		os.Exit(report.ExitCode) //revive:disable:deep-exit // intentional
 	}
 }
 
-func protectedRun(f fs.FS, run Runnable, logger *slog.Logger, opts options) error {
+func protectedRun(f fs.FS, run Runnable, logger *slog.Logger, opts options, state *runState) error {
 	name, id := identity.WhoAmI()
 	// start the DataDog profiler and tracer if the env var is set
 	if _, ok := os.LookupEnv("DD_APM_ENABLED"); ok {
@@ -151,6 +229,7 @@ func protectedRun(f fs.FS, run Runnable, logger *slog.Logger, opts options) erro
 	if err != nil {
 		return stacktrace.Wrap(err)
 	}
+	state.cfg = cfg
 
 	serverConfig := runnerConfig{}
 	if err := cfg.Unmarshal(cfgPath, &serverConfig); err != nil {
@@ -162,12 +241,48 @@ func protectedRun(f fs.FS, run Runnable, logger *slog.Logger, opts options) erro
 	}
 
 	// create task manager
-	tm := task.NewManager(task.WithLogger(logger))
+	tm := task.NewManager(task.WithLogger(logger), task.WithShutdownTimeout(opts.shutdownTimeout))
+	state.tm = tm
+
+	// stop running tasks (and run their cleanup) if a fatal record is logged
+	defer log.OnFatal(func() { _ = tm.Stop() })()
 
 	// start os signal task
 	tm.Run(ossignal.NewTask(ossignal.WithLogger(logger)))
 
+	// start the resource watchdog task, if requested
+	if opts.watchdog != nil {
+		wd, err := watchdog.NewTask(append([]watchdog.Option{watchdog.WithLogger(logger)}, opts.watchdog...)...)
+		if err != nil {
+			return stacktrace.Wrap(err)
+		}
+		tm.Run(wd)
+	}
+
+	// serve the probes, if requested
+	if opts.probeServer != "" {
+		if opts.probes == nil {
+			opts.probes = healthcheck.NewProbes()
+		}
+		server, err := echotask.NewServer(cfg, opts.probeServer,
+			echotask.WithName("probe server"),
+			echotask.WithProbes(opts.probes),
+			echotask.WithTaskStatus(tm),
+			echotask.WithLogger(logger),
+		)
+		if err != nil {
+			return stacktrace.Wrap(err)
+		}
+		tm.Run(server)
+	}
+
+	// stop advertising readiness once the tasks begin to stop
+	if opts.probes != nil {
+		opts.probes.DrainOn(tm.Context())
+	}
+
 	// set up singleton state
+	var lockFactory *singleton.LockFactory[any]
 	if opts.singleton {
 		nc, err := messagebus.NewNatsConnection(cfg)
 		if err != nil {
@@ -175,7 +290,7 @@ func protectedRun(f fs.FS, run Runnable, logger *slog.Logger, opts options) erro
 		}
 		defer nc.Close()
 
-		lockFactory, err := singleton.NewLockFactory[any](
+		lockFactory, err = singleton.NewLockFactory[any](
 			nc,
 			id,
 			singleton.WithLogger(logger),
@@ -183,14 +298,83 @@ func protectedRun(f fs.FS, run Runnable, logger *slog.Logger, opts options) erro
 		if err != nil {
 			return stacktrace.Wrap(err)
 		}
+	}
+
+	// execute the Runnable, once per logical worker if requested
+	if opts.workers > 0 {
+		err = runWorkers(cfg, tm, opts.probes, logger, lockFactory, name, run, opts.workers)
+	} else {
+		err = runWorker(tm.Context(), cfg, newProbingRunner(tm, opts.probes, task.Task.Name), logger, lockFactory, name, run)
+	}
+	if errors.Is(err, errStopped) {
+		// shutdown began while waiting for a lock, but other workers may have started tasks
+		return tm.Wait()
+	}
+	// if the Runnable fails, stop any running tasks and terminate now
+	if err != nil {
+		_ = tm.Stop() // ignore any error from Stop()
+		return err
+	}
 
+	if opts.probes != nil {
+		opts.probes.MarkStarted()
+	}
+
+	// otherwise wait for running tasks to complete
+	return tm.Wait()
+}
+
+// runWorkers executes the Runnable concurrently for each logical worker,
+// each with its own singleton lock key (if any) and worker index.
+func runWorkers(
+	cfg *config.Configuration,
+	tm *task.Manager,
+	probes *healthcheck.Probes,
+	logger *slog.Logger,
+	lockFactory *singleton.LockFactory[any],
+	name string,
+	run Runnable,
+	workers int,
+) error {
+	// a failing worker stops the others from waiting on their locks
+	g, ctx := errgroup.WithContext(tm.Context())
+	mu := &sync.Mutex{}
+	for i := range workers {
+		g.Go(func() error {
+			return runWorker(
+				ctx,
+				cfg,
+				newProbingRunner(newWorkerRunner(tm, i, mu), probes, func(t task.Task) string {
+					return workerTaskName(t, i)
+				}),
+				logger.With(slog.Int("worker", i)),
+				lockFactory,
+				WorkerKey(name, i),
+				run,
+			)
+		})
+	}
+	return g.Wait()
+}
+
+// runWorker acquires the singleton lock if required, then executes the Runnable.
+func runWorker(
+	ctx context.Context,
+	cfg *config.Configuration,
+	tm Runner,
+	logger *slog.Logger,
+	lockFactory *singleton.LockFactory[any],
+	key string,
+	run Runnable,
+) error {
+	if lockFactory != nil {
 		// Acquire lock before proceeding.
 		// Use the same context as the task manager so that
 		// the ossignal task can gracefully cancel this.
-		lock, err := lockFactory.CreateLock(tm.Context(), name, nil)
+		lock, err := lockFactory.CreateLock(ctx, key, nil)
 		if err != nil {
-			if errors.Is(err, tm.Context().Err()) {
-				return nil
+			if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+				return errStopped
 			}
 			return stacktrace.Wrap(err)
 		}
@@ -198,14 +382,5 @@ func protectedRun(f fs.FS, run Runnable, logger *slog.Logger, opts options) erro
 		tm.Run(lock)
 	}
 
-	// execute the Runnable
-	err = run(cfg, tm, logger)
-	// if the Runnable fails, stop any running tasks and terminate now
-	if err != nil {
-		_ = tm.Stop() // ignore any error from Stop()
-		return err
-	}
-
-	// otherwise wait for running tasks to complete
-	return tm.Wait()
+	return run(cfg, tm, logger)
 }
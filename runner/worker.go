@@ -0,0 +1,137 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/zircuit-labs/zkr-go-common/task"
+)
+
+type workerIndexKey struct{}
+
+// ContextWithWorkerIndex returns a context carrying the worker index.
+func ContextWithWorkerIndex(ctx context.Context, index int) context.Context {
+	return context.WithValue(ctx, workerIndexKey{}, index)
+}
+
+// WorkerIndex returns the index of the logical worker when running with WithWorkers.
+// It is available from the Runner context and from the context given to tasks run by that worker.
+func WorkerIndex(ctx context.Context) (int, bool) {
+	index, ok := ctx.Value(workerIndexKey{}).(int)
+	return index, ok
+}
+
+// WorkerKey returns the singleton lock key used by the worker with the given index.
+func WorkerKey(name string, index int) string {
+	return fmt.Sprintf("%s-%d", name, index)
+}
+
+// workerRunner is the Runner given to each logical worker.
+// It shares the underlying Runner, but carries the worker index in all contexts.
+type workerRunner struct {
+	Runner
+	index int
+	ctx   context.Context
+	mu    *sync.Mutex // serializes Cleanup, which the underlying Runner does not expect to be called concurrently
+
+	wrappedMu sync.Mutex
+	wrapped   map[task.Task]task.Task // so that each task keeps its identity as a dependency (see task.DependsOn)
+}
+
+func newWorkerRunner(tm Runner, index int, mu *sync.Mutex) *workerRunner {
+	return &workerRunner{
+		Runner: tm,
+		index:  index,
+		ctx:    ContextWithWorkerIndex(tm.Context(), index),
+		mu:     mu,
+
+		wrapped: make(map[task.Task]task.Task),
+	}
+}
+
+// Run implements Runner.
+func (w *workerRunner) Run(tasks ...task.Task) {
+	w.Runner.Run(w.wrap(tasks)...)
+}
+
+// RunTerminable implements Runner.
+func (w *workerRunner) RunTerminable(tasks ...task.Task) {
+	w.Runner.RunTerminable(w.wrap(tasks)...)
+}
+
+// Cleanup implements Runner.
+func (w *workerRunner) Cleanup(f func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.Runner.Cleanup(f)
+}
+
+// Context implements Runner.
+func (w *workerRunner) Context() context.Context {
+	return w.ctx
+}
+
+func (w *workerRunner) wrap(tasks []task.Task) []task.Task {
+	w.wrappedMu.Lock()
+	defer w.wrappedMu.Unlock()
+
+	wrapped := make([]task.Task, len(tasks))
+	for i, t := range tasks {
+		wrapped[i] = task.Rewrap(t, w.wrapTask)
+	}
+	return wrapped
+}
+
+// wrapTask returns the task wrapped for this worker, reusing the previous wrapping if possible.
+// It must be called with wrappedMu held.
+func (w *workerRunner) wrapTask(t task.Task) task.Task {
+	keyable := reflect.TypeOf(t).Comparable()
+	if keyable {
+		if wrapped, ok := w.wrapped[t]; ok {
+			return wrapped
+		}
+	}
+
+	var wrapped task.Task
+	wt := &workerTask{Task: t, index: w.index}
+	if _, ok := t.(task.Starter); ok {
+		wrapped = &workerStarter{workerTask: wt}
+	} else {
+		wrapped = wt
+	}
+	if keyable {
+		w.wrapped[t] = wrapped
+	}
+	return wrapped
+}
+
+// workerTask adds the worker index to the context of a task.
+type workerTask struct {
+	task.Task
+	index int
+}
+
+// Run implements task.Task.
+func (t *workerTask) Run(ctx context.Context) error {
+	return t.Task.Run(ContextWithWorkerIndex(ctx, t.index))
+}
+
+// Name implements task.Task.
+func (t *workerTask) Name() string {
+	return workerTaskName(t.Task, t.index)
+}
+
+// workerTaskName returns the name of the task when run by the worker with the given index.
+func workerTaskName(t task.Task, index int) string {
+	return fmt.Sprintf("%s (worker %d)", t.Name(), index)
+}
+
+// workerStarter is a workerTask which retains the task.Starter behavior of its task.
+type workerStarter struct {
+	*workerTask
+}
+
+// ReportsRunning implements task.Starter.
+func (t *workerStarter) ReportsRunning() {}
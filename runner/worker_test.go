@@ -0,0 +1,136 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/task"
+)
+
+type indexTask struct {
+	index chan int
+}
+
+func (t *indexTask) Run(ctx context.Context) error {
+	index, ok := WorkerIndex(ctx)
+	if !ok {
+		index = -1
+	}
+	t.index <- index
+	return nil
+}
+
+func (t *indexTask) Name() string {
+	return "index"
+}
+
+func TestWorkerIndex(t *testing.T) {
+	t.Parallel()
+
+	_, ok := WorkerIndex(context.Background())
+	assert.False(t, ok)
+
+	ctx := ContextWithWorkerIndex(context.Background(), 3)
+	index, ok := WorkerIndex(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, 3, index)
+
+	assert.Equal(t, "service-0", WorkerKey("service", 0))
+	assert.Equal(t, "service-3", WorkerKey("service", 3))
+}
+
+func TestWorkerRunner(t *testing.T) {
+	t.Parallel()
+
+	tm := task.NewManager()
+	mu := &sync.Mutex{}
+	w0 := newWorkerRunner(tm, 0, mu)
+	w1 := newWorkerRunner(tm, 1, mu)
+
+	// the worker index is available from the runner context
+	index, ok := WorkerIndex(w1.Context())
+	require.True(t, ok)
+	assert.Equal(t, 1, index)
+
+	// and from the context of tasks run by the worker
+	t0 := &indexTask{index: make(chan int, 1)}
+	t1 := &indexTask{index: make(chan int, 1)}
+	w0.RunTerminable(t0)
+	w1.RunTerminable(t1)
+	assert.Equal(t, 0, <-t0.index)
+	assert.Equal(t, 1, <-t1.index)
+
+	// cleanup may be registered concurrently
+	var wg sync.WaitGroup
+	var cleaned sync.WaitGroup
+	for _, w := range []*workerRunner{w0, w1} {
+		wg.Add(1)
+		cleaned.Add(1)
+		go func() {
+			defer wg.Done()
+			w.Cleanup(cleaned.Done)
+		}()
+	}
+	wg.Wait()
+
+	// tasks retain their status reporting behavior
+	w0.RunTerminable(&starterTask{})
+	require.Eventually(t, func() bool {
+		for _, state := range tm.Statuses() {
+			if state.Name == "starter (worker 0)" {
+				return state.Status == task.StatusStarting
+			}
+		}
+		return false
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, tm.Stop())
+	cleaned.Wait()
+}
+
+type starterTask struct{}
+
+func (t *starterTask) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+func (t *starterTask) Name() string {
+	return "starter"
+}
+
+func (t *starterTask) ReportsRunning() {}
+
+func TestWorkerRunnerDependencies(t *testing.T) {
+	t.Parallel()
+
+	tm := task.NewManager()
+	w := newWorkerRunner(tm, 0, &sync.Mutex{})
+
+	// dependencies are declared on the wrapped tasks, so are awaited as usual
+	dependency := &starterTask{}
+	dependent := &indexTask{index: make(chan int, 1)}
+	w.RunTerminable(task.DependsOn(dependent, dependency))
+	w.Run(dependency)
+
+	state := func(name string) task.TaskStatus {
+		for _, state := range tm.Statuses() {
+			if state.Name == name {
+				return state.Status
+			}
+		}
+		return task.StatusFailed
+	}
+	require.Eventually(t, func() bool {
+		return state("starter (worker 0)") == task.StatusStarting
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, task.StatusPending, state("index (worker 0)"))
+
+	require.NoError(t, tm.Stop())
+	assert.Empty(t, dependent.index)
+}
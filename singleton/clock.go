@@ -0,0 +1,41 @@
+package singleton
+
+import (
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+)
+
+// serverClock estimates the time of the NATS server, which timestamps KV entries,
+// from the local clock and the offset observed when writing lock values.
+type serverClock struct {
+	clock     clockwork.Clock
+	offset    atomic.Int64 // server time minus local time
+	threshold time.Duration
+	logger    *slog.Logger
+}
+
+// local returns the (unadjusted) local time.
+func (c *serverClock) local() time.Time {
+	return c.clock.Now()
+}
+
+// now returns the estimated server time.
+func (c *serverClock) now() time.Time {
+	return c.clock.Now().Add(time.Duration(c.offset.Load()))
+}
+
+// observe updates the offset given the server time at which an entry was written
+// by a request made between the local times before and after.
+func (c *serverClock) observe(serverTime, before, after time.Time) {
+	offset := serverTime.Sub(before.Add(after.Sub(before) / 2))
+	c.offset.Store(int64(offset))
+	if offset.Abs() > c.threshold {
+		c.logger.Warn("local clock differs from NATS server time",
+			slog.Duration("offset", offset),
+			slog.Duration("threshold", c.threshold),
+		)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/jonboulle/clockwork"
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
 
@@ -23,6 +24,8 @@ const (
 	UnlockTimeout               = time.Millisecond * 100
 	defaultLockValidityInterval = (time.Minute * 5) + (time.Second * 10)
 	defaultLockRefreshInterval  = time.Minute // refresh must be less than validity
+	defaultClockSkewTolerance   = time.Second * 5
+	defaultClockSkewWarning     = time.Second
 )
 
 var (
@@ -34,11 +37,15 @@ type LockFactory[T any] struct {
 	kv         jetstream.KeyValue
 	instanceID string
 	opts       options
+	clock      *serverClock
 }
 
 type options struct {
 	lockValidityInterval time.Duration
 	lockRefreshInterval  time.Duration
+	clockSkewTolerance   time.Duration
+	clockSkewWarning     time.Duration
+	clock                clockwork.Clock
 	logger               *slog.Logger
 }
 
@@ -56,6 +63,29 @@ func WithLockRefreshInterval(interval time.Duration) Option {
 	}
 }
 
+// WithClockSkewTolerance sets how long after its expiry a lock held by another instance is still
+// considered valid, to allow for the difference between clocks. Defaults to 5 seconds.
+func WithClockSkewTolerance(tolerance time.Duration) Option {
+	return func(options *options) {
+		options.clockSkewTolerance = tolerance
+	}
+}
+
+// WithClockSkewWarning sets the threshold above which a warning is logged when the local clock,
+// or that of another lock holder, differs from NATS server time. Defaults to 1 second.
+func WithClockSkewWarning(threshold time.Duration) Option {
+	return func(options *options) {
+		options.clockSkewWarning = threshold
+	}
+}
+
+// WithClock allows users to mock the local clock for testing purposes.
+func WithClock(clock clockwork.Clock) Option {
+	return func(options *options) {
+		options.clock = clock
+	}
+}
+
 func WithLogger(logger *slog.Logger) Option {
 	return func(options *options) {
 		options.logger = logger
@@ -67,6 +97,9 @@ func NewLockFactory[T any](nc *nats.Conn, instanceID string, opts ...Option) (*L
 	options := options{
 		lockValidityInterval: defaultLockValidityInterval,
 		lockRefreshInterval:  defaultLockRefreshInterval,
+		clockSkewTolerance:   defaultClockSkewTolerance,
+		clockSkewWarning:     defaultClockSkewWarning,
+		clock:                clockwork.NewRealClock(),
 		logger:               log.NewNilLogger(),
 	}
 	for _, opt := range opts {
@@ -78,6 +111,9 @@ func NewLockFactory[T any](nc *nats.Conn, instanceID string, opts ...Option) (*L
 	if BucketTTL < options.lockValidityInterval {
 		return nil, stacktrace.Wrap(ErrInvalidOption)
 	}
+	if options.clockSkewTolerance < 0 || options.clockSkewWarning <= 0 {
+		return nil, stacktrace.Wrap(ErrInvalidOption)
+	}
 
 	options.logger = options.logger.With(
 		slog.String("instance", instanceID),
@@ -102,21 +138,33 @@ func NewLockFactory[T any](nc *nats.Conn, instanceID string, opts ...Option) (*L
 		kv:         kv,
 		instanceID: instanceID,
 		opts:       options,
+		clock: &serverClock{
+			clock:     options.clock,
+			threshold: options.clockSkewWarning,
+			logger:    options.logger,
+		},
 	}, nil
 }
 
-// TryCreateLock attempts to create a new lock, but does not block if the lock is already held.
-// If the lock is already held, the current lock content is returned instead.
-func (f *LockFactory[T]) TryCreateLock(ctx context.Context, key string, content T) (*Lock[T], *T, error) {
+// newLock returns an unlocked lock for the key.
+func (f *LockFactory[T]) newLock(key string, content T) *Lock[T] {
 	lock := &Lock[T]{
 		kv:         f.kv,
 		key:        key,
 		content:    content,
 		instanceID: f.instanceID,
 		opts:       f.opts,
+		clock:      f.clock,
 	}
 	lock.LockCtx, lock.cancel = context.WithCancelCause(context.Background())
 	lock.opts.logger = lock.opts.logger.With(slog.String("key", key))
+	return lock
+}
+
+// TryCreateLock attempts to create a new lock, but does not block if the lock is already held.
+// If the lock is already held, the current lock content is returned instead.
+func (f *LockFactory[T]) TryCreateLock(ctx context.Context, key string, content T) (*Lock[T], *T, error) {
+	lock := f.newLock(key, content)
 
 	// Try to acquire the lock, or return the current lock content.
 	for {
@@ -128,7 +176,9 @@ func (f *LockFactory[T]) TryCreateLock(ctx context.Context, key string, content
 		}
 
 		// Attempt to acquire the lock.
+		before := f.clock.local()
 		rev, err := f.kv.Create(ctx, key, v)
+		after := f.clock.local()
 		switch {
 		case errors.Is(err, jetstream.ErrKeyExists):
 			// The lock is held by someone else.
@@ -137,10 +187,7 @@ func (f *LockFactory[T]) TryCreateLock(ctx context.Context, key string, content
 			return nil, nil, stacktrace.Wrap(err)
 		default:
 			// Lock acquired.
-			lock.opts.logger.Info("lock acquired", slog.Uint64("rev", rev))
-			lock.rev = rev
-			lock.locked = true
-			lock.wg.Go(lock.continuallyRefresh)
+			lock.acquired(ctx, rev, before, after)
 			return lock, nil, nil
 		}
 
@@ -169,15 +216,7 @@ func (f *LockFactory[T]) TryCreateLock(ctx context.Context, key string, content
 
 // CreateLock creates a new lock and blocks until the lock has been acquired.
 func (f *LockFactory[T]) CreateLock(ctx context.Context, key string, content T) (*Lock[T], error) {
-	lock := &Lock[T]{
-		kv:         f.kv,
-		key:        key,
-		content:    content,
-		instanceID: f.instanceID,
-		opts:       f.opts,
-	}
-	lock.LockCtx, lock.cancel = context.WithCancelCause(context.Background())
-	lock.opts.logger = lock.opts.logger.With(slog.String("key", key))
+	lock := f.newLock(key, content)
 
 	for {
 		// Marshal the lock content every time we try to acquire
@@ -188,7 +227,9 @@ func (f *LockFactory[T]) CreateLock(ctx context.Context, key string, content T)
 		}
 
 		// Attempt to acquire the lock.
+		before := f.clock.local()
 		rev, err := f.kv.Create(ctx, key, v)
+		after := f.clock.local()
 		switch {
 		case errors.Is(err, jetstream.ErrKeyExists):
 			// The lock is held by someone else.
@@ -197,10 +238,7 @@ func (f *LockFactory[T]) CreateLock(ctx context.Context, key string, content T)
 			return nil, stacktrace.Wrap(err)
 		default:
 			// Lock acquired.
-			lock.opts.logger.Info("lock acquired", slog.Uint64("rev", rev))
-			lock.rev = rev
-			lock.locked = true
-			lock.wg.Go(lock.continuallyRefresh)
+			lock.acquired(ctx, rev, before, after)
 			return lock, nil
 		}
 
@@ -225,14 +263,16 @@ func (f *LockFactory[T]) CreateLock(ctx context.Context, key string, content T)
 		}
 
 		// If lock has expired: delete it, ignoring any errors, and try again.
-		if value.ExpiresAt.Compare(time.Now()) == -1 {
+		expiresAt := f.expiry(kve, value).Add(f.opts.clockSkewTolerance)
+		now := f.clock.now()
+		if expiresAt.Before(now) {
 			f.opts.logger.Info("detected expired lock - deleting key", slog.Uint64("rev", kve.Revision()))
 			_ = f.kv.Delete(ctx, key, jetstream.LastRevision(kve.Revision()))
 			continue
 		}
 
 		// The current lock is valid, and won't expire until this time.
-		waitTime := time.Until(value.ExpiresAt)
+		waitTime := expiresAt.Sub(now)
 
 		// Alternatively, the lock holder might release before then.
 		watcher, err := f.kv.Watch(ctx, key, jetstream.MetaOnly(), jetstream.UpdatesOnly())
@@ -251,6 +291,25 @@ func (f *LockFactory[T]) CreateLock(ctx context.Context, key string, content T)
 	}
 }
 
+// expiry returns the server time at which the lock value expires.
+// Values which record their validity expire relative to the time the NATS server stored them,
+// so that the clock of the lock holder is irrelevant. Otherwise, the expiry time set by the
+// lock holder is used as is.
+func (f *LockFactory[T]) expiry(kve jetstream.KeyValueEntry, value lockValue[T]) time.Time {
+	if value.Validity <= 0 {
+		return value.ExpiresAt
+	}
+	expiresAt := kve.Created().Add(value.Validity)
+	if skew := value.ExpiresAt.Sub(expiresAt); skew.Abs() > f.opts.clockSkewWarning {
+		f.opts.logger.Warn("lock holder clock differs from NATS server time",
+			slog.String("holder", value.InstanceID),
+			slog.Duration("offset", skew),
+			slog.Uint64("rev", kve.Revision()),
+		)
+	}
+	return expiresAt
+}
+
 // Wait until either the context is done, the timer fires, or a change of the key-value is detected.
 func wait(ctx context.Context, d time.Duration, changes <-chan jetstream.KeyValueEntry) error {
 	timer := time.NewTimer(d)
@@ -268,7 +327,9 @@ func wait(ctx context.Context, d time.Duration, changes <-chan jetstream.KeyValu
 type lockValue[T any] struct {
 	InstanceID string    `json:"instance_id"`
 	ExpiresAt  time.Time `json:"expires_at"`
-	Content    T         `json:"content,omitempty"`
+	// Validity is the duration for which the value is valid after being stored.
+	Validity time.Duration `json:"validity,omitempty"`
+	Content  T             `json:"content,omitempty"`
 }
 
 // Lock is a distributed one-time use lock.
@@ -285,6 +346,7 @@ type Lock[T any] struct {
 	content    T
 	instanceID string
 	opts       options
+	clock      *serverClock
 	rev        uint64
 	locked     bool
 	wg         sync.WaitGroup
@@ -292,6 +354,15 @@ type Lock[T any] struct {
 	cancel     context.CancelCauseFunc
 }
 
+// acquired marks the lock as held at the given revision, and starts refreshing it.
+func (l *Lock[T]) acquired(ctx context.Context, rev uint64, before, after time.Time) {
+	l.opts.logger.Info("lock acquired", slog.Uint64("rev", rev))
+	l.rev = rev
+	l.locked = true
+	l.syncClock(ctx, before, after)
+	l.wg.Go(l.continuallyRefresh)
+}
+
 // Refresh the lock expiry on a regular interval.
 func (l *Lock[T]) continuallyRefresh() {
 	ticker := time.NewTicker(l.opts.lockRefreshInterval)
@@ -325,11 +396,14 @@ func (l *Lock[T]) refresh() error {
 	if err != nil {
 		return stacktrace.Wrap(err)
 	}
+	before := l.clock.local()
 	rev, err := l.kv.Update(l.LockCtx, l.key, v, l.rev)
+	after := l.clock.local()
 	switch {
 	case err == nil:
 		l.opts.logger.Debug("lock refreshed", slog.Uint64("rev", rev))
 		l.rev = rev
+		l.syncClock(l.LockCtx, before, after)
 		return nil
 	case errors.Is(err, l.LockCtx.Err()):
 		// Context was cancelled during operation.
@@ -345,6 +419,16 @@ func (l *Lock[T]) refresh() error {
 	}
 }
 
+// syncClock updates the estimated server time offset using the timestamp of the value just written.
+// This is best effort, and failures are ignored.
+func (l *Lock[T]) syncClock(ctx context.Context, before, after time.Time) {
+	kve, err := l.kv.GetRevision(ctx, l.key, l.rev)
+	if err != nil {
+		return
+	}
+	l.clock.observe(kve.Created(), before, after)
+}
+
 // Locked returns true if the lock is currently held.
 func (l *Lock[T]) Locked() bool {
 	l.mu.Lock()
@@ -388,7 +472,8 @@ func (l *Lock[T]) Unlock() error {
 func (l *Lock[T]) Marshal(content T) ([]byte, error) {
 	value := lockValue[T]{
 		InstanceID: l.instanceID,
-		ExpiresAt:  time.Now().Add(l.opts.lockValidityInterval).UTC(),
+		ExpiresAt:  l.clock.now().Add(l.opts.lockValidityInterval).UTC(),
+		Validity:   l.opts.lockValidityInterval,
 		Content:    content,
 	}
 	return json.Marshal(value)
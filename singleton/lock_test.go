@@ -2,10 +2,13 @@ package singleton_test
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log/slog"
 	"testing"
 	"time"
 
+	"github.com/jonboulle/clockwork"
 	"github.com/nats-io/nats.go"
 	"github.com/rs/xid"
 	"github.com/stretchr/testify/assert"
@@ -302,3 +305,86 @@ func TestLock(t *testing.T) { //nolint:paralleltest // parallel exposes a data r
 	res := <-out
 	assert.True(t, valuesIdentical(res))
 }
+
+func TestClockSkew(t *testing.T) { //nolint:paralleltest // parallel exposes a data race in the nats server code itself, but does not affect the validity of this test/code.
+	natsServer := testutils.NewEmbeddedServer(t)
+	t.Cleanup(natsServer.Close)
+	nc, js := natsServer.Conn(t)
+	t.Cleanup(nc.Close)
+
+	logger := zkrlog.NewTestLogger(t)
+	newFactory := func(opts ...singleton.Option) *singleton.LockFactory[string] {
+		opts = append([]singleton.Option{
+			singleton.WithLogger(logger),
+			singleton.WithLockRefreshInterval(lockRefreshInterval),
+			singleton.WithLockValidityInterval(lockValidityInterval),
+			singleton.WithClockSkewTolerance(0),
+		}, opts...)
+		lockFactory, err := singleton.NewLockFactory[string](nc, xid.New().String(), opts...)
+		require.NoError(t, err)
+		return lockFactory
+	}
+	lockFactory := newFactory()
+
+	ctx := t.Context()
+	kv, err := js.KeyValue(ctx, singleton.BucketName)
+	require.NoError(t, err)
+	skewedKey := "skewed-" + xid.New().String()
+	legacyKey := "legacy-" + xid.New().String()
+
+	// A holder whose clock is far behind writes an expiry in the past, but the lock
+	// remains valid as its validity is relative to when the NATS server stored it.
+	value := fmt.Sprintf(`{"instance_id":"skewed","expires_at":%q,"validity":%d}`,
+		time.Now().Add(-time.Hour).UTC().Format(time.RFC3339Nano), time.Hour)
+	_, err = kv.Create(ctx, skewedKey, []byte(value))
+	require.NoError(t, err)
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, lockValidityInterval*2)
+	defer cancel()
+	// (the error depends on where the deadline interrupts waiting)
+	_, err = lockFactory.CreateLock(timeoutCtx, skewedKey, "test")
+	require.Error(t, err)
+
+	// Values without a validity expire according to the clock of the holder.
+	value = fmt.Sprintf(`{"instance_id":"legacy","expires_at":%q}`,
+		time.Now().Add(-time.Hour).UTC().Format(time.RFC3339Nano))
+	_, err = kv.Create(ctx, legacyKey, []byte(value))
+	require.NoError(t, err)
+
+	lock, err := lockFactory.CreateLock(ctx, legacyKey, "test")
+	require.NoError(t, err)
+	require.NoError(t, lock.Unlock())
+
+	// A factory with a skewed clock corrects the expiry it writes using server time.
+	skewedFactory := newFactory(singleton.WithClock(clockwork.NewFakeClockAt(time.Now().Add(-time.Hour))))
+	lock, err = skewedFactory.CreateLock(ctx, t.Name(), "test")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = lock.Unlock() })
+
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		kve, err := kv.Get(ctx, t.Name())
+		require.NoError(c, err)
+		var got struct {
+			ExpiresAt time.Time `json:"expires_at"`
+		}
+		require.NoError(c, json.Unmarshal(kve.Value(), &got))
+		assert.WithinDuration(c, kve.Created().Add(lockValidityInterval), got.ExpiresAt, time.Second)
+	}, time.Second, lockRefreshInterval)
+
+	// and others cannot take the lock while it is refreshed
+	timeoutCtx, cancel = context.WithTimeout(ctx, lockValidityInterval*2)
+	defer cancel()
+	_, err = lockFactory.CreateLock(timeoutCtx, t.Name(), "test")
+	require.Error(t, err)
+	assert.True(t, lock.Locked())
+}
+
+func TestInvalidClockSkewOptions(t *testing.T) {
+	t.Parallel()
+
+	_, err := singleton.NewLockFactory[string](nil, "instance", singleton.WithClockSkewTolerance(-time.Second))
+	require.ErrorIs(t, err, singleton.ErrInvalidOption)
+
+	_, err = singleton.NewLockFactory[string](nil, "instance", singleton.WithClockSkewWarning(0))
+	require.ErrorIs(t, err, singleton.ErrInvalidOption)
+}
@@ -0,0 +1,217 @@
+package singleton
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+var ErrSlotHeld = errors.New("semaphore slot is already held")
+
+// Semaphore is a distributed counting semaphore, allowing up to size holders for a key at once
+// (eg to cap the number of concurrent provers across the fleet).
+// Each of the size slots is held as a Lock, so slots have the same fencing, validity and
+// refresh behavior, and can likewise be lost (see Run).
+// A Semaphore holds at most one slot at a time, so use one per concurrent holder.
+type Semaphore[T any] struct {
+	f    *LockFactory[T]
+	key  string
+	size int
+
+	mu   sync.Mutex
+	lock *Lock[T]
+}
+
+// NewSemaphore returns a semaphore allowing up to size holders for the key.
+// All holders must use the same size.
+func (f *LockFactory[T]) NewSemaphore(key string, size int) (*Semaphore[T], error) {
+	if size < 1 {
+		return nil, stacktrace.Wrap(ErrInvalidOption)
+	}
+	return &Semaphore[T]{f: f, key: key, size: size}, nil
+}
+
+// TryAcquire attempts to acquire a slot, but does not block if all slots are already held.
+// If all slots are held, the content of each current holder is returned instead.
+func (s *Semaphore[T]) TryAcquire(ctx context.Context, content T) (bool, []T, error) {
+	if s.Held() {
+		return false, nil, stacktrace.Wrap(ErrSlotHeld)
+	}
+
+	lock, holders, _, err := s.tryAcquire(ctx, content)
+	if err != nil {
+		return false, nil, err
+	}
+	if lock == nil {
+		return false, holders, nil
+	}
+	if err := s.hold(lock); err != nil {
+		return false, nil, err
+	}
+	return true, nil, nil
+}
+
+// Acquire blocks until a slot has been acquired.
+func (s *Semaphore[T]) Acquire(ctx context.Context, content T) error {
+	if s.Held() {
+		return stacktrace.Wrap(ErrSlotHeld)
+	}
+
+	for {
+		// Watch before trying, so that any release meanwhile is noticed.
+		watcher, err := s.f.kv.Watch(ctx, s.key+".slot.*", jetstream.MetaOnly(), jetstream.UpdatesOnly())
+		if err != nil {
+			return stacktrace.Wrap(err)
+		}
+
+		lock, _, waitTime, err := s.tryAcquire(ctx, content)
+		if err != nil || lock != nil {
+			_ = watcher.Stop()
+			if err != nil {
+				return err
+			}
+			return s.hold(lock)
+		}
+
+		// Wait until a slot may be available again.
+		err = wait(ctx, waitTime, watcher.Updates())
+		_ = watcher.Stop()
+		if err != nil {
+			return stacktrace.Wrap(err)
+		}
+	}
+}
+
+// Held returns true if a slot is currently held.
+func (s *Semaphore[T]) Held() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.held()
+}
+
+// Release releases the slot, if held.
+func (s *Semaphore[T]) Release() error {
+	s.mu.Lock()
+	lock := s.lock
+	s.lock = nil
+	s.mu.Unlock()
+
+	if lock == nil {
+		return nil
+	}
+	return lock.Unlock()
+}
+
+// Run blocks until the slot is lost, released, or the context is done.
+// Run will then release the slot if possible. If no slot is held, Run returns immediately.
+func (s *Semaphore[T]) Run(ctx context.Context) error {
+	s.mu.Lock()
+	lock := s.lock
+	s.mu.Unlock()
+
+	if lock == nil {
+		return nil
+	}
+	return lock.Run(ctx)
+}
+
+// Name returns the name of this semaphore.
+func (s *Semaphore[T]) Name() string {
+	return fmt.Sprintf("singleton-semaphore-%s", s.key)
+}
+
+func (s *Semaphore[T]) held() bool {
+	return s.lock != nil && s.lock.Locked()
+}
+
+// hold records the newly acquired slot, unless another was acquired concurrently.
+func (s *Semaphore[T]) hold(lock *Lock[T]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.held() {
+		_ = lock.Unlock()
+		return stacktrace.Wrap(ErrSlotHeld)
+	}
+	s.lock = lock
+	return nil
+}
+
+// tryAcquire attempts to acquire each slot in turn. If all are held, it returns the content
+// of each holder, and how long until the first of them expires.
+func (s *Semaphore[T]) tryAcquire(ctx context.Context, content T) (*Lock[T], []T, time.Duration, error) {
+	var holders []T
+	var waitTime time.Duration
+	for i := range s.size {
+		key := fmt.Sprintf("%s.slot.%d", s.key, i)
+		lock := s.f.newLock(key, content)
+		for {
+			// Marshal the lock content every time we try to acquire
+			// the slot so the expiry time is updated.
+			v, err := lock.Marshal(content)
+			if err != nil {
+				return nil, nil, 0, stacktrace.Wrap(err)
+			}
+
+			// Attempt to acquire the slot.
+			before := s.f.clock.local()
+			rev, err := s.f.kv.Create(ctx, key, v)
+			after := s.f.clock.local()
+			switch {
+			case errors.Is(err, jetstream.ErrKeyExists):
+				// The slot is held by someone else.
+			case err != nil:
+				// Unexpected error.
+				return nil, nil, 0, stacktrace.Wrap(err)
+			default:
+				// Slot acquired.
+				lock.acquired(ctx, rev, before, after)
+				return lock, nil, 0, nil
+			}
+
+			// Otherwise, get the current slot holder details.
+			kve, err := s.f.kv.Get(ctx, key)
+			switch {
+			case errors.Is(err, jetstream.ErrKeyNotFound):
+				// The slot was released. Try again.
+				continue
+			case err != nil:
+				// Unexpected error.
+				return nil, nil, 0, stacktrace.Wrap(err)
+			}
+
+			var value lockValue[T]
+			if err := json.Unmarshal(kve.Value(), &value); err != nil {
+				// The value is garbage: delete it, ignoring any errors, and try again.
+				lock.opts.logger.Warn("detected garbage lock contents - deleting key", log.ErrAttr(err), slog.Uint64("rev", kve.Revision()))
+				_ = s.f.kv.Delete(ctx, key, jetstream.LastRevision(kve.Revision()))
+				continue
+			}
+
+			// If the slot has expired: delete it, ignoring any errors, and try again.
+			expiresAt := s.f.expiry(kve, value).Add(s.f.opts.clockSkewTolerance)
+			now := s.f.clock.now()
+			if expiresAt.Before(now) {
+				lock.opts.logger.Info("detected expired lock - deleting key", slog.Uint64("rev", kve.Revision()))
+				_ = s.f.kv.Delete(ctx, key, jetstream.LastRevision(kve.Revision()))
+				continue
+			}
+
+			holders = append(holders, value.Content)
+			if d := expiresAt.Sub(now); waitTime == 0 || d < waitTime {
+				waitTime = d
+			}
+			break
+		}
+	}
+	return nil, holders, waitTime, nil
+}
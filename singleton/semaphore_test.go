@@ -0,0 +1,145 @@
+package singleton_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/calm/errgroup"
+	zkrlog "github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/messagebus/testutils"
+	"github.com/zircuit-labs/zkr-go-common/singleton"
+)
+
+func TestSemaphoreTryAcquire(t *testing.T) { //nolint:paralleltest // parallel exposes a data race in the nats server code itself, but does not affect the validity of this test/code.
+	natsServer := testutils.NewEmbeddedServer(t)
+	t.Cleanup(natsServer.Close)
+	nc, _ := natsServer.Conn(t)
+	t.Cleanup(nc.Close)
+
+	lockFactory := createLockFactory[string](t, nc, zkrlog.NewTestLogger(t))
+	key := "provers_" + xid.New().String()
+	ctx := t.Context()
+
+	newSemaphore := func() *singleton.Semaphore[string] {
+		semaphore, err := lockFactory.NewSemaphore(key, 2)
+		require.NoError(t, err)
+		return semaphore
+	}
+
+	// two holders are allowed
+	a, b, c := newSemaphore(), newSemaphore(), newSemaphore()
+	ok, holders, err := a.TryAcquire(ctx, "a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Nil(t, holders)
+	ok, _, err = b.TryAcquire(ctx, "b")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// a third gets the content of the current holders instead
+	ok, holders, err = c.TryAcquire(ctx, "c")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.ElementsMatch(t, []string{"a", "b"}, holders)
+	assert.False(t, c.Held())
+
+	// each semaphore holds at most one slot
+	_, _, err = a.TryAcquire(ctx, "a")
+	require.ErrorIs(t, err, singleton.ErrSlotHeld)
+
+	// once a slot is released it can be acquired
+	require.NoError(t, a.Release())
+	assert.False(t, a.Held())
+	ok, _, err = c.TryAcquire(ctx, "c")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, b.Release())
+	require.NoError(t, c.Release())
+	require.NoError(t, c.Release())
+
+	_, err = lockFactory.NewSemaphore(key, 0)
+	require.ErrorIs(t, err, singleton.ErrInvalidOption)
+}
+
+func TestSemaphoreAcquire(t *testing.T) { //nolint:paralleltest // parallel exposes a data race in the nats server code itself, but does not affect the validity of this test/code.
+	natsServer := testutils.NewEmbeddedServer(t)
+	t.Cleanup(natsServer.Close)
+	nc, _ := natsServer.Conn(t)
+	t.Cleanup(nc.Close)
+
+	lockFactory := createLockFactory[any](t, nc, zkrlog.NewTestLogger(t))
+	key := "provers_" + xid.New().String()
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second*10)
+	defer cancel()
+
+	// many workers contend for 3 slots, and no more than 3 ever work at once
+	const slots = 3
+	var working, maxWorking atomic.Int32
+	eg := errgroup.New()
+	for range 10 {
+		eg.Go(func() error {
+			semaphore, err := lockFactory.NewSemaphore(key, slots)
+			if err != nil {
+				return err
+			}
+			if err := semaphore.Acquire(ctx, nil); err != nil {
+				return err
+			}
+			n := working.Add(1)
+			for {
+				m := maxWorking.Load()
+				if n <= m || maxWorking.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond * 20)
+			working.Add(-1)
+			return semaphore.Release()
+		})
+	}
+	require.NoError(t, eg.Wait())
+	assert.LessOrEqual(t, maxWorking.Load(), int32(slots))
+	assert.Positive(t, maxWorking.Load())
+}
+
+func TestSemaphoreSlotLost(t *testing.T) { //nolint:paralleltest // parallel exposes a data race in the nats server code itself, but does not affect the validity of this test/code.
+	natsServer := testutils.NewEmbeddedServer(t)
+	t.Cleanup(natsServer.Close)
+	nc, js := natsServer.Conn(t)
+	t.Cleanup(nc.Close)
+
+	lockFactory := createLockFactory[any](t, nc, zkrlog.NewTestLogger(t))
+	key := "provers_" + xid.New().String()
+	ctx := t.Context()
+
+	semaphore, err := lockFactory.NewSemaphore(key, 1)
+	require.NoError(t, err)
+	require.NoError(t, semaphore.Run(ctx)) // nothing held yet
+	require.NoError(t, semaphore.Acquire(ctx, nil))
+	require.True(t, semaphore.Held())
+
+	eg := errgroup.New()
+	eg.Go(func() error {
+		return semaphore.Run(ctx)
+	})
+
+	// Outside of the semaphore, delete the slot value causing it to be lost
+	kv, err := js.KeyValue(ctx, singleton.BucketName)
+	require.NoError(t, err)
+	require.NoError(t, kv.Delete(ctx, key+".slot.0"))
+
+	err = eg.Wait()
+	require.ErrorIs(t, err, singleton.ErrLockLost)
+	assert.False(t, semaphore.Held())
+
+	// a lost slot can be acquired again
+	require.NoError(t, semaphore.Acquire(ctx, nil))
+	require.NoError(t, semaphore.Release())
+}
@@ -0,0 +1,281 @@
+// Package cas provides content-addressable storage on top of a blob store.
+// Blobs are keyed by the SHA-256 digest of their content, so identical content is stored only once.
+package cas
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/zircuit-labs/zkr-go-common/stores/s3"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const (
+	defaultPrefix = "cas/"
+	blobsDir      = "blobs/"
+	refsDir       = "refs/"
+)
+
+var (
+	ErrInvalidDigest  = errors.New("invalid digest")
+	ErrDigestMismatch = errors.New("content does not match digest")
+	ErrInvalidRef     = errors.New("ref must be non-empty and must not contain '/'")
+)
+
+// BlobStore is the underlying storage. The s3.BlobStore satisfies this interface.
+// Get and Exists must return an error wrapping s3.ErrNotFound for missing keys.
+type BlobStore interface {
+	Upload(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Exists(ctx context.Context, key string) error
+	Delete(ctx context.Context, key string) error
+	GetList(ctx context.Context, prefix string) ([]string, error)
+}
+
+// Digest is the SHA-256 digest of a blob.
+type Digest [sha256.Size]byte
+
+// DigestOf computes the digest of the data.
+func DigestOf(data []byte) Digest {
+	return sha256.Sum256(data)
+}
+
+// ParseDigest parses the hex representation of a digest.
+func ParseDigest(s string) (Digest, error) {
+	var d Digest
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != len(d) {
+		return d, errclass.WrapAs(stacktrace.Wrap(ErrInvalidDigest), errclass.Persistent)
+	}
+	copy(d[:], b)
+	return d, nil
+}
+
+// String returns the hex representation of the digest.
+func (d Digest) String() string {
+	return hex.EncodeToString(d[:])
+}
+
+type options struct {
+	prefix string
+}
+
+// Option is an option func for New.
+type Option func(options *options)
+
+// WithPrefix sets the key prefix under which all data is stored. Defaults to "cas/".
+func WithPrefix(prefix string) Option {
+	return func(options *options) {
+		options.prefix = prefix
+	}
+}
+
+// Store is a content-addressable store.
+//
+// Blobs are reference counted: each ref is a named marker object (eg the ID of the
+// entity using the blob), which avoids read-modify-write races on a shared counter.
+// Blobs without any refs are removed by GarbageCollect.
+type Store struct {
+	blobs  BlobStore
+	prefix string
+}
+
+// New creates a Store backed by the BlobStore.
+func New(blobs BlobStore, opts ...Option) *Store {
+	options := options{
+		prefix: defaultPrefix,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &Store{blobs: blobs, prefix: options.prefix}
+}
+
+func (s *Store) blobKey(d Digest) string {
+	return s.prefix + blobsDir + d.String()
+}
+
+func (s *Store) refsPrefix(d Digest) string {
+	return s.prefix + refsDir + d.String() + "/"
+}
+
+// Put stores the data, adding the given refs, and returns its digest.
+// If the content is already stored it is not uploaded again.
+// Refs are written before the blob so that a concurrent GarbageCollect cannot remove it.
+func (s *Store) Put(ctx context.Context, data []byte, refs ...string) (d Digest, err error) {
+	d = DigestOf(data)
+	defer func() {
+		err = errcontext.Add(err, slog.String("digest", d.String()))
+	}()
+
+	for _, ref := range refs {
+		if err := s.addRef(ctx, d, ref); err != nil {
+			return d, err
+		}
+	}
+
+	exists, err := s.Has(ctx, d)
+	if err != nil {
+		return d, err
+	}
+	if exists {
+		return d, nil
+	}
+	if err := s.blobs.Upload(ctx, s.blobKey(d), data); err != nil {
+		return d, stacktrace.Wrap(err)
+	}
+	return d, nil
+}
+
+// Get returns the data for the digest, verifying that the content matches.
+func (s *Store) Get(ctx context.Context, d Digest) (data []byte, err error) {
+	defer func() {
+		err = errcontext.Add(err, slog.String("digest", d.String()))
+	}()
+
+	data, err = s.blobs.Get(ctx, s.blobKey(d))
+	if err != nil {
+		return nil, stacktrace.Wrap(err)
+	}
+	if DigestOf(data) != d {
+		return nil, errclass.WrapAs(stacktrace.Wrap(ErrDigestMismatch), errclass.Persistent)
+	}
+	return data, nil
+}
+
+// Has reports whether a blob with the digest is stored.
+func (s *Store) Has(ctx context.Context, d Digest) (bool, error) {
+	err := s.blobs.Exists(ctx, s.blobKey(d))
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, s3.ErrNotFound):
+		return false, nil
+	default:
+		return false, stacktrace.Wrap(err)
+	}
+}
+
+// AddRef adds a ref to an existing blob.
+func (s *Store) AddRef(ctx context.Context, d Digest, ref string) (err error) {
+	defer func() {
+		err = errcontext.Add(err, slog.String("digest", d.String()), slog.String("ref", ref))
+	}()
+
+	if err := s.addRef(ctx, d, ref); err != nil {
+		return err
+	}
+	exists, err := s.Has(ctx, d)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		// don't leave a dangling ref behind
+		_ = s.blobs.Delete(ctx, s.refsPrefix(d)+ref)
+		return errclass.WrapAs(stacktrace.Wrap(s3.ErrNotFound), errclass.Persistent)
+	}
+	return nil
+}
+
+func (s *Store) addRef(ctx context.Context, d Digest, ref string) error {
+	if ref == "" || strings.Contains(ref, "/") {
+		return errclass.WrapAs(stacktrace.Wrap(ErrInvalidRef), errclass.Persistent)
+	}
+	if err := s.blobs.Upload(ctx, s.refsPrefix(d)+ref, nil); err != nil {
+		return stacktrace.Wrap(err)
+	}
+	return nil
+}
+
+// RemoveRef removes a ref from a blob. The blob itself is removed by GarbageCollect once it has no refs.
+func (s *Store) RemoveRef(ctx context.Context, d Digest, ref string) (err error) {
+	defer func() {
+		err = errcontext.Add(err, slog.String("digest", d.String()), slog.String("ref", ref))
+	}()
+
+	if err := s.blobs.Delete(ctx, s.refsPrefix(d)+ref); err != nil && !errors.Is(err, s3.ErrNotFound) {
+		return stacktrace.Wrap(err)
+	}
+	return nil
+}
+
+// Refs returns the refs of a blob.
+func (s *Store) Refs(ctx context.Context, d Digest) ([]string, error) {
+	prefix := s.refsPrefix(d)
+	keys, err := s.blobs.GetList(ctx, prefix)
+	if err != nil {
+		return nil, errcontext.Add(stacktrace.Wrap(err), slog.String("digest", d.String()))
+	}
+	refs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		refs = append(refs, strings.TrimPrefix(key, prefix))
+	}
+	return refs, nil
+}
+
+// RefCount returns the number of refs of a blob.
+func (s *Store) RefCount(ctx context.Context, d Digest) (int, error) {
+	refs, err := s.Refs(ctx, d)
+	return len(refs), err
+}
+
+// Unreferenced returns the digests of all stored blobs without any refs.
+func (s *Store) Unreferenced(ctx context.Context) ([]Digest, error) {
+	blobKeys, err := s.blobs.GetList(ctx, s.prefix+blobsDir)
+	if err != nil {
+		return nil, stacktrace.Wrap(err)
+	}
+	refKeys, err := s.blobs.GetList(ctx, s.prefix+refsDir)
+	if err != nil {
+		return nil, stacktrace.Wrap(err)
+	}
+
+	referenced := make(map[string]struct{}, len(refKeys))
+	for _, key := range refKeys {
+		digest, _, _ := strings.Cut(strings.TrimPrefix(key, s.prefix+refsDir), "/")
+		referenced[digest] = struct{}{}
+	}
+
+	var unreferenced []Digest
+	for _, key := range blobKeys {
+		name := strings.TrimPrefix(key, s.prefix+blobsDir)
+		if _, ok := referenced[name]; ok {
+			continue
+		}
+		d, err := ParseDigest(name)
+		if err != nil {
+			// not a blob written by this package
+			continue
+		}
+		unreferenced = append(unreferenced, d)
+	}
+	return unreferenced, nil
+}
+
+// GarbageCollect deletes all blobs without any refs, returning the digests of deleted blobs.
+// NOTE: Blobs stored by Put without refs are also deleted, so always Put with at least one ref
+// when GarbageCollect may run concurrently. A blob which had no refs may still be deleted
+// concurrently with a Put re-adding one, so GarbageCollect is best run while writers are idle.
+func (s *Store) GarbageCollect(ctx context.Context) ([]Digest, error) {
+	unreferenced, err := s.Unreferenced(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make([]Digest, 0, len(unreferenced))
+	var errs []error
+	for _, d := range unreferenced {
+		if err := s.blobs.Delete(ctx, s.blobKey(d)); err != nil {
+			errs = append(errs, errcontext.Add(stacktrace.Wrap(err), slog.String("digest", d.String())))
+			continue
+		}
+		deleted = append(deleted, d)
+	}
+	return deleted, errors.Join(errs...)
+}
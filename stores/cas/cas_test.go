@@ -0,0 +1,167 @@
+package cas_test
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/stores/cas"
+	"github.com/zircuit-labs/zkr-go-common/stores/s3"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+type memoryBlobStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	uploads int
+}
+
+func newMemoryBlobStore() *memoryBlobStore {
+	return &memoryBlobStore{objects: map[string][]byte{}}
+}
+
+func (m *memoryBlobStore) Upload(_ context.Context, key string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.uploads++
+	m.objects[key] = slices.Clone(data)
+	return nil
+}
+
+func (m *memoryBlobStore) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.objects[key]
+	if !ok {
+		return nil, stacktrace.Wrap(s3.ErrNotFound)
+	}
+	return data, nil
+}
+
+func (m *memoryBlobStore) Exists(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.objects[key]; !ok {
+		return stacktrace.Wrap(s3.ErrNotFound)
+	}
+	return nil
+}
+
+func (m *memoryBlobStore) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *memoryBlobStore) GetList(_ context.Context, prefix string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var keys []string
+	for key := range maps.Keys(m.objects) {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func TestDigest(t *testing.T) {
+	t.Parallel()
+
+	d := cas.DigestOf([]byte("hello"))
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", d.String())
+
+	parsed, err := cas.ParseDigest(d.String())
+	require.NoError(t, err)
+	assert.Equal(t, d, parsed)
+
+	for _, invalid := range []string{"", "abc", "zz" + d.String()[2:], d.String() + "00"} {
+		_, err := cas.ParseDigest(invalid)
+		assert.ErrorIs(t, err, cas.ErrInvalidDigest, invalid)
+	}
+}
+
+func TestPutGet(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+	blobs := newMemoryBlobStore()
+	store := cas.New(blobs)
+
+	d, err := store.Put(ctx, []byte("proof input"))
+	require.NoError(t, err)
+	assert.Equal(t, cas.DigestOf([]byte("proof input")), d)
+
+	// identical content is only uploaded once
+	d2, err := store.Put(ctx, []byte("proof input"))
+	require.NoError(t, err)
+	assert.Equal(t, d, d2)
+	assert.Equal(t, 1, blobs.uploads)
+
+	data, err := store.Get(ctx, d)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("proof input"), data)
+
+	// missing content
+	_, err = store.Get(ctx, cas.DigestOf([]byte("missing")))
+	assert.ErrorIs(t, err, s3.ErrNotFound)
+
+	// corrupted content is detected
+	blobs.objects["cas/blobs/"+d.String()] = []byte("corrupted")
+	_, err = store.Get(ctx, d)
+	assert.ErrorIs(t, err, cas.ErrDigestMismatch)
+	assert.Equal(t, errclass.Persistent, errclass.GetClass(err))
+}
+
+func TestRefsAndGarbageCollection(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+	store := cas.New(newMemoryBlobStore(), cas.WithPrefix("test/"))
+
+	shared, err := store.Put(ctx, []byte("shared"), "job-1", "job-2")
+	require.NoError(t, err)
+	single, err := store.Put(ctx, []byte("single"), "job-1")
+	require.NoError(t, err)
+	orphan, err := store.Put(ctx, []byte("orphan"))
+	require.NoError(t, err)
+
+	refs, err := store.Refs(ctx, shared)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"job-1", "job-2"}, refs)
+
+	// refs can only be added to stored blobs
+	require.NoError(t, store.AddRef(ctx, single, "job-3"))
+	assert.ErrorIs(t, store.AddRef(ctx, cas.DigestOf([]byte("missing")), "job-3"), s3.ErrNotFound)
+	assert.ErrorIs(t, store.AddRef(ctx, single, "a/b"), cas.ErrInvalidRef)
+	count, err := store.RefCount(ctx, single)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// job-1 completes
+	require.NoError(t, store.RemoveRef(ctx, shared, "job-1"))
+	require.NoError(t, store.RemoveRef(ctx, single, "job-1"))
+	require.NoError(t, store.RemoveRef(ctx, single, "job-1")) // removing again is fine
+
+	deleted, err := store.GarbageCollect(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []cas.Digest{orphan}, deleted)
+
+	// job-3 completes
+	require.NoError(t, store.RemoveRef(ctx, single, "job-3"))
+	deleted, err = store.GarbageCollect(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []cas.Digest{single}, deleted)
+
+	for d, expected := range map[cas.Digest]bool{shared: true, single: false, orphan: false} {
+		has, err := store.Has(ctx, d)
+		require.NoError(t, err)
+		assert.Equal(t, expected, has)
+	}
+}
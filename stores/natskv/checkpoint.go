@@ -0,0 +1,40 @@
+package natskv
+
+import (
+	"context"
+	"errors"
+
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+)
+
+// Checkpoint records the progress of a messagebus.Migration in a KV bucket,
+// so that an interrupted migration can be resumed.
+type Checkpoint struct {
+	store *TypedKV[uint64]
+	key   string
+}
+
+var _ messagebus.Checkpointer = (*Checkpoint)(nil)
+
+// NewCheckpoint creates a Checkpoint for the migration named by key (eg "orders-v2").
+func NewCheckpoint(store *TypedKV[uint64], key string) *Checkpoint {
+	return &Checkpoint{store: store, key: key}
+}
+
+// LoadCheckpoint implements messagebus.Checkpointer.
+func (c *Checkpoint) LoadCheckpoint(ctx context.Context) (uint64, error) {
+	entry, err := c.store.Get(ctx, c.key)
+	if errors.Is(err, ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return entry.Value, nil
+}
+
+// SaveCheckpoint implements messagebus.Checkpointer.
+func (c *Checkpoint) SaveCheckpoint(ctx context.Context, sequence uint64) error {
+	_, err := c.store.Put(ctx, c.key, sequence)
+	return err
+}
@@ -0,0 +1,34 @@
+package natskv_test
+
+import (
+	"testing"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/messagebus/testutils"
+	"github.com/zircuit-labs/zkr-go-common/stores/natskv"
+)
+
+func TestCheckpoint(t *testing.T) { //nolint:paralleltest // parallel exposes a data race in the nats server code itself, but does not affect the validity of this test/code.
+	natsServer := testutils.NewEmbeddedServer(t)
+	t.Cleanup(natsServer.Close)
+	nc, js := natsServer.Conn(t)
+	t.Cleanup(nc.Close)
+	ctx := t.Context()
+
+	store, err := natskv.NewTypedKVFromConfig[uint64](ctx, js, natskv.Config{Bucket: "migrations_" + xid.New().String()})
+	require.NoError(t, err)
+	checkpoint := natskv.NewCheckpoint(store, "orders-v2")
+
+	// nothing has been migrated yet
+	sequence, err := checkpoint.LoadCheckpoint(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, sequence)
+
+	require.NoError(t, checkpoint.SaveCheckpoint(ctx, 42))
+	sequence, err = checkpoint.LoadCheckpoint(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), sequence)
+}
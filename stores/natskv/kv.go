@@ -0,0 +1,308 @@
+// Package natskv provides a typed key-value store backed by a NATS JetStream KV bucket.
+package natskv
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+var (
+	ErrNoBucket         = errors.New("no bucket supplied")
+	ErrNotFound         = errors.New("key not found")
+	ErrExists           = errors.New("key already exists")
+	ErrRevisionMismatch = errors.New("key has been modified since the given revision")
+)
+
+// Config configures the KV bucket, which is created or updated to match.
+type Config struct {
+	Bucket      string
+	Description string
+	// TTL optionally expires keys which have not been updated for this long.
+	TTL time.Duration
+	// History is the number of revisions kept per key. Defaults to 1.
+	History uint8
+	// MaxBytes optionally limits the size of the bucket.
+	MaxBytes int64 `koanf:"maxbytes"`
+	// Replicas is the number of replicas in a clustered deployment. Defaults to 1.
+	Replicas    int
+	Compression bool
+}
+
+type options struct {
+	codec  messagebus.Codec
+	logger *slog.Logger
+}
+
+// Option is an option func for NewTypedKV.
+type Option func(options *options)
+
+// WithCodec sets the codec used to serialize values. Defaults to messagebus.JSONCodec.
+func WithCodec(codec messagebus.Codec) Option {
+	return func(options *options) {
+		options.codec = codec
+	}
+}
+
+// WithLogger sets the logger to be used.
+func WithLogger(logger *slog.Logger) Option {
+	return func(options *options) {
+		options.logger = logger
+	}
+}
+
+// Entry is a value along with its key and revision.
+type Entry[T any] struct {
+	Key   string
+	Value T
+	// Revision is used for optimistic concurrency with Update and DeleteRevision.
+	Revision uint64
+	Created  time.Time
+	// Deleted is only set for entries received from Watch, in which case Value is the zero value.
+	Deleted bool
+}
+
+// TypedKV stores values of type T in a NATS KV bucket.
+type TypedKV[T any] struct {
+	kv   jetstream.KeyValue
+	opts options
+}
+
+// NewTypedKVFromConfig creates the KV bucket, or updates it to match the config, and returns a store using it.
+func NewTypedKVFromConfig[T any](ctx context.Context, js jetstream.JetStream, config Config, opts ...Option) (*TypedKV[T], error) {
+	options := options{
+		codec:  messagebus.JSONCodec,
+		logger: log.NewNilLogger(),
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if config.Bucket == "" {
+		return nil, stacktrace.Wrap(ErrNoBucket)
+	}
+
+	kv, err := js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket:      config.Bucket,
+		Description: config.Description,
+		TTL:         config.TTL,
+		History:     config.History,
+		MaxBytes:    config.MaxBytes,
+		Replicas:    config.Replicas,
+		Compression: config.Compression,
+	})
+	if err != nil {
+		return nil, errcontext.Add(stacktrace.Wrap(err), slog.String("bucket", config.Bucket))
+	}
+
+	return &TypedKV[T]{kv: kv, opts: options}, nil
+}
+
+// NewTypedKV is the same as NewTypedKVFromConfig, using the config at cfgPath.
+func NewTypedKV[T any](ctx context.Context, js jetstream.JetStream, cfg *config.Configuration, cfgPath string, opts ...Option) (*TypedKV[T], error) {
+	config := Config{}
+	if err := cfg.Unmarshal(cfgPath, &config); err != nil {
+		return nil, stacktrace.Wrap(err)
+	}
+
+	return NewTypedKVFromConfig[T](ctx, js, config, opts...)
+}
+
+// Bucket returns the name of the KV bucket.
+func (s *TypedKV[T]) Bucket() string {
+	return s.kv.Bucket()
+}
+
+// Get returns the latest revision of the key, or ErrNotFound.
+func (s *TypedKV[T]) Get(ctx context.Context, key string) (_ Entry[T], err error) {
+	defer func() {
+		err = errcontext.Add(err, slog.String("key", key))
+	}()
+
+	kve, err := s.kv.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyNotFound) {
+			return Entry[T]{}, stacktrace.Wrap(ErrNotFound)
+		}
+		return Entry[T]{}, stacktrace.Wrap(err)
+	}
+	return s.entry(kve)
+}
+
+// Put sets the value of the key regardless of its current revision, returning the new revision.
+func (s *TypedKV[T]) Put(ctx context.Context, key string, value T) (_ uint64, err error) {
+	defer func() {
+		err = errcontext.Add(err, slog.String("key", key))
+	}()
+
+	b, err := s.marshal(value)
+	if err != nil {
+		return 0, err
+	}
+	rev, err := s.kv.Put(ctx, key, b)
+	if err != nil {
+		return 0, stacktrace.Wrap(err)
+	}
+	return rev, nil
+}
+
+// Create sets the value of the key only if it does not exist (or was deleted), returning its revision.
+// Otherwise it returns ErrExists.
+func (s *TypedKV[T]) Create(ctx context.Context, key string, value T) (_ uint64, err error) {
+	defer func() {
+		err = errcontext.Add(err, slog.String("key", key))
+	}()
+
+	b, err := s.marshal(value)
+	if err != nil {
+		return 0, err
+	}
+	rev, err := s.kv.Create(ctx, key, b)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyExists) {
+			return 0, stacktrace.Wrap(ErrExists)
+		}
+		return 0, stacktrace.Wrap(err)
+	}
+	return rev, nil
+}
+
+// Update sets the value of the key only if its latest revision is the given one, returning the new revision.
+// Otherwise it returns ErrRevisionMismatch, in which case the caller should Get the key and try again.
+func (s *TypedKV[T]) Update(ctx context.Context, key string, value T, revision uint64) (_ uint64, err error) {
+	defer func() {
+		err = errcontext.Add(err, slog.String("key", key), slog.Uint64("revision", revision))
+	}()
+
+	b, err := s.marshal(value)
+	if err != nil {
+		return 0, err
+	}
+	rev, err := s.kv.Update(ctx, key, b, revision)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyExists) {
+			return 0, stacktrace.Wrap(ErrRevisionMismatch)
+		}
+		return 0, stacktrace.Wrap(err)
+	}
+	return rev, nil
+}
+
+// Delete deletes the key. Deleting a key which does not exist is not an error.
+func (s *TypedKV[T]) Delete(ctx context.Context, key string) (err error) {
+	defer func() {
+		err = errcontext.Add(err, slog.String("key", key))
+	}()
+
+	if err := s.kv.Delete(ctx, key); err != nil {
+		return stacktrace.Wrap(err)
+	}
+	return nil
+}
+
+// DeleteRevision deletes the key only if its latest revision is the given one.
+// Otherwise it returns ErrRevisionMismatch.
+func (s *TypedKV[T]) DeleteRevision(ctx context.Context, key string, revision uint64) (err error) {
+	defer func() {
+		err = errcontext.Add(err, slog.String("key", key), slog.Uint64("revision", revision))
+	}()
+
+	if err := s.kv.Delete(ctx, key, jetstream.LastRevision(revision)); err != nil {
+		if errors.Is(err, jetstream.ErrKeyExists) {
+			return stacktrace.Wrap(ErrRevisionMismatch)
+		}
+		return stacktrace.Wrap(err)
+	}
+	return nil
+}
+
+// Keys returns all keys in the bucket, which is empty if there are none.
+func (s *TypedKV[T]) Keys(ctx context.Context) ([]string, error) {
+	keys, err := s.kv.Keys(ctx, jetstream.MetaOnly())
+	if err != nil {
+		if errors.Is(err, jetstream.ErrNoKeysFound) {
+			return []string{}, nil
+		}
+		return nil, stacktrace.Wrap(err)
+	}
+	return keys, nil
+}
+
+// Watch sends the latest revision of each key matching the pattern (which may contain the
+// wildcards * and >), and then every update and deletion, until the context is done and the
+// channel is closed. Values which cannot be unmarshaled are logged and skipped.
+func (s *TypedKV[T]) Watch(ctx context.Context, pattern string) (<-chan Entry[T], error) {
+	watcher, err := s.kv.Watch(ctx, pattern)
+	if err != nil {
+		return nil, errcontext.Add(stacktrace.Wrap(err), slog.String("pattern", pattern))
+	}
+
+	entries := make(chan Entry[T])
+	go func() {
+		defer close(entries)
+		defer func() {
+			_ = watcher.Stop()
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case kve, ok := <-watcher.Updates():
+				if !ok {
+					return
+				}
+				// A nil entry marks the end of the initial values
+				if kve == nil {
+					continue
+				}
+				entry, err := s.entry(kve)
+				if err != nil {
+					s.opts.logger.Error("failed to unmarshal value - skipping", log.ErrAttr(err),
+						slog.String("bucket", s.kv.Bucket()))
+					continue
+				}
+				select {
+				case entries <- entry:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return entries, nil
+}
+
+func (s *TypedKV[T]) marshal(value T) ([]byte, error) {
+	b, err := s.opts.codec.Marshal(value)
+	if err != nil {
+		return nil, errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+	}
+	return b, nil
+}
+
+func (s *TypedKV[T]) entry(kve jetstream.KeyValueEntry) (Entry[T], error) {
+	entry := Entry[T]{
+		Key:      kve.Key(),
+		Revision: kve.Revision(),
+		Created:  kve.Created(),
+		Deleted:  kve.Operation() != jetstream.KeyValuePut,
+	}
+	if entry.Deleted {
+		return entry, nil
+	}
+	if err := s.opts.codec.Unmarshal(kve.Value(), &entry.Value); err != nil {
+		return Entry[T]{}, errcontext.Add(errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent),
+			slog.String("key", kve.Key()), slog.Uint64("revision", kve.Revision()))
+	}
+	return entry, nil
+}
@@ -0,0 +1,181 @@
+package natskv_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/messagebus"
+	"github.com/zircuit-labs/zkr-go-common/messagebus/testutils"
+	"github.com/zircuit-labs/zkr-go-common/stores/natskv"
+)
+
+type account struct {
+	Owner   string
+	Balance int
+}
+
+func newStore(t *testing.T, bucket string, opts ...natskv.Option) *natskv.TypedKV[account] {
+	t.Helper()
+	natsServer := testutils.NewEmbeddedServer(t)
+	t.Cleanup(natsServer.Close)
+	nc, js := natsServer.Conn(t)
+	t.Cleanup(nc.Close)
+
+	// the embedded server persists buckets between runs, so each needs a unique name
+	cfg, err := config.NewConfigurationFromMap(map[string]any{
+		"accounts": map[string]any{
+			"bucket":  bucket + "_" + xid.New().String(),
+			"history": 5,
+		},
+	})
+	require.NoError(t, err)
+	store, err := natskv.NewTypedKV[account](t.Context(), js, cfg, "accounts", opts...)
+	require.NoError(t, err)
+	return store
+}
+
+func TestTypedKV(t *testing.T) { //nolint:paralleltest // parallel exposes a data race in the nats server code itself, but does not affect the validity of this test/code.
+	store := newStore(t, "accounts_crud")
+	ctx := t.Context()
+	assert.Contains(t, store.Bucket(), "accounts_crud")
+
+	_, err := store.Get(ctx, "alice")
+	require.ErrorIs(t, err, natskv.ErrNotFound)
+	keys, err := store.Keys(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	rev, err := store.Put(ctx, "alice", account{Owner: "alice", Balance: 10})
+	require.NoError(t, err)
+	entry, err := store.Get(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", entry.Key)
+	assert.Equal(t, account{Owner: "alice", Balance: 10}, entry.Value)
+	assert.Equal(t, rev, entry.Revision)
+	assert.False(t, entry.Created.IsZero())
+
+	// create only succeeds for new keys
+	_, err = store.Create(ctx, "alice", account{Owner: "mallory"})
+	require.ErrorIs(t, err, natskv.ErrExists)
+	_, err = store.Create(ctx, "bob", account{Owner: "bob"})
+	require.NoError(t, err)
+
+	keys, err = store.Keys(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"alice", "bob"}, keys)
+
+	require.NoError(t, store.Delete(ctx, "bob"))
+	_, err = store.Get(ctx, "bob")
+	require.ErrorIs(t, err, natskv.ErrNotFound)
+	require.NoError(t, store.Delete(ctx, "nobody"))
+}
+
+func TestTypedKVOptimisticConcurrency(t *testing.T) { //nolint:paralleltest // parallel exposes a data race in the nats server code itself, but does not affect the validity of this test/code.
+	store := newStore(t, "accounts_cas")
+	ctx := t.Context()
+
+	rev, err := store.Create(ctx, "alice", account{Owner: "alice", Balance: 10})
+	require.NoError(t, err)
+
+	// two writers read the same revision, and only the first update succeeds
+	newRev, err := store.Update(ctx, "alice", account{Owner: "alice", Balance: 20}, rev)
+	require.NoError(t, err)
+	assert.Greater(t, newRev, rev)
+	_, err = store.Update(ctx, "alice", account{Owner: "alice", Balance: 30}, rev)
+	require.ErrorIs(t, err, natskv.ErrRevisionMismatch)
+
+	entry, err := store.Get(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 20, entry.Value.Balance)
+
+	// likewise for deletes
+	require.ErrorIs(t, store.DeleteRevision(ctx, "alice", rev), natskv.ErrRevisionMismatch)
+	require.NoError(t, store.DeleteRevision(ctx, "alice", newRev))
+	_, err = store.Get(ctx, "alice")
+	require.ErrorIs(t, err, natskv.ErrNotFound)
+}
+
+func TestTypedKVWatch(t *testing.T) { //nolint:paralleltest // parallel exposes a data race in the nats server code itself, but does not affect the validity of this test/code.
+	store := newStore(t, "accounts_watch")
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second*10)
+	defer cancel()
+
+	_, err := store.Put(ctx, "eu.alice", account{Owner: "alice"})
+	require.NoError(t, err)
+	_, err = store.Put(ctx, "us.bob", account{Owner: "bob"})
+	require.NoError(t, err)
+
+	entries, err := store.Watch(ctx, "eu.*")
+	require.NoError(t, err)
+
+	next := func() natskv.Entry[account] {
+		t.Helper()
+		select {
+		case entry := <-entries:
+			return entry
+		case <-ctx.Done():
+			require.FailNow(t, "no entry received")
+			return natskv.Entry[account]{}
+		}
+	}
+
+	// existing values are sent first, then updates and deletions of matching keys
+	assert.Equal(t, "alice", next().Value.Owner)
+	_, err = store.Put(ctx, "us.bob", account{Owner: "bob", Balance: 1})
+	require.NoError(t, err)
+	_, err = store.Put(ctx, "eu.carol", account{Owner: "carol"})
+	require.NoError(t, err)
+	assert.Equal(t, "carol", next().Value.Owner)
+	require.NoError(t, store.Delete(ctx, "eu.alice"))
+	deleted := next()
+	assert.Equal(t, "eu.alice", deleted.Key)
+	assert.True(t, deleted.Deleted)
+
+	// the channel is closed once the context is done
+	cancel()
+	require.Eventually(t, func() bool {
+		select {
+		case _, ok := <-entries:
+			return !ok
+		default:
+			return false
+		}
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func TestTypedKVCodec(t *testing.T) { //nolint:paralleltest // parallel exposes a data race in the nats server code itself, but does not affect the validity of this test/code.
+	store := newStore(t, "accounts_msgpack", natskv.WithCodec(messagebus.MsgpackCodec))
+	ctx := t.Context()
+
+	_, err := store.Put(ctx, "alice", account{Owner: "alice", Balance: 10})
+	require.NoError(t, err)
+	entry, err := store.Get(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, account{Owner: "alice", Balance: 10}, entry.Value)
+}
+
+func TestNewTypedKVErrors(t *testing.T) { //nolint:paralleltest // parallel exposes a data race in the nats server code itself, but does not affect the validity of this test/code.
+	natsServer := testutils.NewEmbeddedServer(t)
+	t.Cleanup(natsServer.Close)
+	nc, js := natsServer.Conn(t)
+	t.Cleanup(nc.Close)
+
+	_, err := natskv.NewTypedKVFromConfig[account](t.Context(), js, natskv.Config{})
+	require.ErrorIs(t, err, natskv.ErrNoBucket)
+
+	// the bucket is created to match the config
+	bucket := "accounts_config_" + xid.New().String()
+	_, err = natskv.NewTypedKVFromConfig[account](t.Context(), js, natskv.Config{Bucket: bucket, History: 3})
+	require.NoError(t, err)
+	kv, err := js.KeyValue(t.Context(), bucket)
+	require.NoError(t, err)
+	status, err := kv.Status(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), status.History())
+}
@@ -0,0 +1,42 @@
+package natskv
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/zircuit-labs/zkr-go-common/retry"
+)
+
+// FailureMarker records when attempts on a dependency last failed in a shared KV bucket,
+// so that instances retrying it can coordinate via retry.WithCoordinator.
+// Setting a TTL on the bucket lets stale markers expire.
+type FailureMarker struct {
+	store *TypedKV[time.Time]
+	key   string
+}
+
+var _ retry.Coordinator = (*FailureMarker)(nil)
+
+// NewFailureMarker creates a FailureMarker for the dependency named by key (eg "payments-api").
+func NewFailureMarker(store *TypedKV[time.Time], key string) *FailureMarker {
+	return &FailureMarker{store: store, key: key}
+}
+
+// MarkFailure implements retry.Coordinator.
+func (m *FailureMarker) MarkFailure(ctx context.Context, at time.Time) error {
+	_, err := m.store.Put(ctx, m.key, at)
+	return err
+}
+
+// LastFailure implements retry.Coordinator.
+func (m *FailureMarker) LastFailure(ctx context.Context) (time.Time, error) {
+	entry, err := m.store.Get(ctx, m.key)
+	if errors.Is(err, ErrNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return entry.Value, nil
+}
@@ -0,0 +1,42 @@
+package natskv_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/messagebus/testutils"
+	"github.com/zircuit-labs/zkr-go-common/stores/natskv"
+)
+
+func TestFailureMarker(t *testing.T) { //nolint:paralleltest // parallel exposes a data race in the nats server code itself, but does not affect the validity of this test/code.
+	natsServer := testutils.NewEmbeddedServer(t)
+	t.Cleanup(natsServer.Close)
+	nc, js := natsServer.Conn(t)
+	t.Cleanup(nc.Close)
+	ctx := t.Context()
+
+	store, err := natskv.NewTypedKVFromConfig[time.Time](ctx, js, natskv.Config{Bucket: "failures_" + xid.New().String()})
+	require.NoError(t, err)
+	marker := natskv.NewFailureMarker(store, "payments-api")
+	other := natskv.NewFailureMarker(store, "ledger")
+
+	// nothing has failed yet
+	last, err := marker.LastFailure(ctx)
+	require.NoError(t, err)
+	assert.True(t, last.IsZero())
+
+	failedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, marker.MarkFailure(ctx, failedAt))
+	last, err = marker.LastFailure(ctx)
+	require.NoError(t, err)
+	assert.True(t, failedAt.Equal(last))
+
+	// dependencies are marked separately
+	last, err = other.LastFailure(ctx)
+	require.NoError(t, err)
+	assert.True(t, last.IsZero())
+}
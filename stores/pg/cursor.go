@@ -0,0 +1,102 @@
+package pg
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const minCursorKeyLength = 16
+
+var (
+	ErrCursorKey     = errors.New("cursor signing keys must be at least 16 bytes")
+	ErrInvalidCursor = errors.New("cursor is malformed or has been tampered with")
+)
+
+// CursorCodec encodes cursors as opaque signed tokens, so that clients cannot forge positions,
+// and do not come to depend on the values (and so the schema) behind them.
+// NOTE: The values are encoded rather than encrypted, so they must not be secret.
+// A nil CursorCodec leaves cursors as the comma-joined values. Create it with NewCursorCodec;
+// it is safe for concurrent use.
+type CursorCodec struct {
+	keys [][]byte
+}
+
+// NewCursorCodec creates a CursorCodec signing with the key. Tokens signed with any of the
+// previous keys are still accepted, so that keys can be rotated without breaking existing cursors.
+func NewCursorCodec(key []byte, previousKeys ...[]byte) (*CursorCodec, error) {
+	keys := append([][]byte{key}, previousKeys...)
+	for _, k := range keys {
+		if len(k) < minCursorKeyLength {
+			return nil, errclass.WrapAs(stacktrace.Wrap(ErrCursorKey), errclass.Persistent)
+		}
+	}
+	return &CursorCodec{keys: keys}, nil
+}
+
+type paginateOptions struct {
+	cursorCodec *CursorCodec
+}
+
+// PaginateOption is an option func for Paginate.
+type PaginateOption func(options *paginateOptions)
+
+// WithCursorCodec sets the codec used by Paginate to encode and verify cursors.
+func WithCursorCodec(codec *CursorCodec) PaginateOption {
+	return func(options *paginateOptions) {
+		options.cursorCodec = codec
+	}
+}
+
+// Encode returns the token for the cursor values.
+func (c *CursorCodec) Encode(values []string) string {
+	if c == nil {
+		return strings.Join(values, ",")
+	}
+	payload, _ := json.Marshal(values) // cannot fail for strings
+	token := append(payload, c.sign(c.keys[0], payload)...)
+	return base64.RawURLEncoding.EncodeToString(token)
+}
+
+// Decode verifies the token and returns the cursor values, or ErrInvalidCursor classed as Persistent.
+func (c *CursorCodec) Decode(token string) ([]string, error) {
+	if c == nil {
+		return strings.Split(token, ","), nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(b) < sha256.Size {
+		return nil, errclass.WrapAs(stacktrace.Wrap(ErrInvalidCursor), errclass.Persistent)
+	}
+	payload, signature := b[:len(b)-sha256.Size], b[len(b)-sha256.Size:]
+	if !c.verify(payload, signature) {
+		return nil, errclass.WrapAs(stacktrace.Wrap(ErrInvalidCursor), errclass.Persistent)
+	}
+
+	var values []string
+	if err := json.Unmarshal(payload, &values); err != nil {
+		return nil, errclass.WrapAs(stacktrace.Wrap(ErrInvalidCursor), errclass.Persistent)
+	}
+	return values, nil
+}
+
+func (c *CursorCodec) sign(key, payload []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func (c *CursorCodec) verify(payload, signature []byte) bool {
+	for _, key := range c.keys {
+		if hmac.Equal(signature, c.sign(key, payload)) {
+			return true
+		}
+	}
+	return false
+}
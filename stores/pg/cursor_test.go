@@ -0,0 +1,115 @@
+package pg_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+
+	"github.com/zircuit-labs/zkr-go-common/stores/pg"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+var (
+	cursorKey    = []byte("0123456789abcdef0123456789abcdef")
+	oldCursorKey = []byte("fedcba9876543210fedcba9876543210")
+)
+
+func TestCursorCodec(t *testing.T) {
+	t.Parallel()
+
+	_, err := pg.NewCursorCodec([]byte("short"))
+	require.ErrorIs(t, err, pg.ErrCursorKey)
+	_, err = pg.NewCursorCodec(cursorKey, []byte("short"))
+	require.ErrorIs(t, err, pg.ErrCursorKey)
+
+	codec, err := pg.NewCursorCodec(cursorKey)
+	require.NoError(t, err)
+
+	// values are opaque, and may contain commas
+	values := []string{"2024-01-02T03:04:05Z", "smith, john", "42"}
+	token := codec.Encode(values)
+	assert.NotContains(t, token, "smith")
+	decoded, err := codec.Decode(token)
+	require.NoError(t, err)
+	assert.Equal(t, values, decoded)
+
+	// any change is rejected
+	tampered := []string{
+		"",
+		"not base64!",
+		token[:len(token)-1],
+		strings.ToUpper(token[:1]) + strings.ToLower(token[1:]),
+	}
+	forged, err := pg.NewCursorCodec(oldCursorKey)
+	require.NoError(t, err)
+	tampered = append(tampered, forged.Encode(values))
+	for _, token := range tampered {
+		_, err := codec.Decode(token)
+		require.ErrorIs(t, err, pg.ErrInvalidCursor, token)
+		assert.Equal(t, errclass.Persistent, errclass.GetClass(err))
+	}
+
+	// previous keys are still accepted after rotation
+	rotated, err := pg.NewCursorCodec(cursorKey, oldCursorKey)
+	require.NoError(t, err)
+	decoded, err = rotated.Decode(forged.Encode(values))
+	require.NoError(t, err)
+	assert.Equal(t, values, decoded)
+	assert.Equal(t, token, rotated.Encode(values))
+
+	// a nil codec uses plain comma-joined values
+	var plain *pg.CursorCodec
+	assert.Equal(t, "a,b", plain.Encode([]string{"a", "b"}))
+	decoded, err = plain.Decode("a,b")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, decoded)
+}
+
+type cursorOpts struct {
+	limit  int
+	cursor pg.Cursor
+}
+
+func (o cursorOpts) GetLimit() int        { return o.limit }
+func (o cursorOpts) GetCursor() pg.Cursor { return o.cursor }
+
+func TestPaginateCursorCodec(t *testing.T) {
+	t.Parallel()
+	sqldb, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	t.Cleanup(func() { sqldb.Close() })
+	db := bun.NewDB(sqldb, pgdialect.New())
+
+	codec, err := pg.NewCursorCodec(cursorKey)
+	require.NoError(t, err)
+
+	// the first page returns a signed cursor
+	mock.ExpectQuery(`SELECT "user"."id", "user"."name" FROM "users" AS "user" ORDER BY "name" ASC, "id" ASC LIMIT 2`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "aaron").AddRow(2, "abby"))
+	results, cursor, err := pg.Paginate[user, userPage](t.Context(), db.NewSelect().Model((*user)(nil)), cursorOpts{limit: 1}, pg.WithCursorCodec(codec))
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.NotContains(t, cursor.Next, "aaron")
+	assert.Empty(t, cursor.Previous)
+
+	// which is used to fetch the next page
+	mock.ExpectQuery(`SELECT "user"."id", "user"."name" FROM "users" AS "user" WHERE ((name > 'aaron') OR (name = 'aaron' AND id > 1)) ORDER BY "name" ASC, "id" ASC LIMIT 2`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "abby"))
+	results, _, err = pg.Paginate[user, userPage](t.Context(), db.NewSelect().Model((*user)(nil)), cursorOpts{limit: 1, cursor: cursor}, pg.WithCursorCodec(codec))
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "abby", results[0].Name)
+
+	// raw or tampered cursors are rejected without querying
+	for _, tampered := range []pg.Cursor{{Next: "aaron,1"}, {Previous: cursor.Next + "x"}} {
+		_, _, err = pg.Paginate[user, userPage](t.Context(), db.NewSelect().Model((*user)(nil)), cursorOpts{limit: 1, cursor: tampered}, pg.WithCursorCodec(codec))
+		require.ErrorIs(t, err, pg.ErrInvalidCursor)
+		assert.Equal(t, errclass.Persistent, errclass.GetClass(err))
+	}
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,276 @@
+package pg
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	ErrEncryptionKey    = errors.New("encryption keys must be 16, 24 or 32 bytes with a non-empty ID of at most 255 bytes")
+	ErrNoKeyring        = errors.New("no keyring set for encrypted columns")
+	ErrUnknownKey       = errors.New("encrypted with an unknown key")
+	ErrDecryptionFailed = errors.New("encrypted value is malformed or has been tampered with")
+)
+
+// Keyring holds the AES keys used to encrypt columns, identified by ID.
+// Values are encrypted with the primary key, and record its ID, such that values encrypted with
+// previous keys can still be decrypted while keys are rotated. Create it with NewKeyring;
+// it is safe for concurrent use.
+type Keyring struct {
+	primary string
+	aeads   map[string]cipher.AEAD
+}
+
+// KeyringConfig configures a Keyring.
+type KeyringConfig struct {
+	// Primary is the ID of the key used to encrypt.
+	Primary string
+	// Keys are the base64 encoded keys by ID, including previous keys which may still be in use.
+	Keys map[string]string
+}
+
+// NewKeyring creates a Keyring encrypting with the key with the primary ID. Keys must be 16, 24 or 32 bytes,
+// to use AES-128, AES-192 or AES-256. Keys may come from anywhere, eg data keys decrypted by a KMS.
+func NewKeyring(primary string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[primary]; !ok || len(primary) > 255 {
+		return nil, encryptionKeyError(primary)
+	}
+	k := &Keyring{primary: primary, aeads: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		if id == "" || len(id) > 255 {
+			return nil, encryptionKeyError(id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, encryptionKeyError(id)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+		}
+		k.aeads[id] = aead
+	}
+	return k, nil
+}
+
+// NewKeyringFromConfig creates a Keyring from the KeyringConfig at cfgPath.
+func NewKeyringFromConfig(cfg *config.Configuration, cfgPath string) (*Keyring, error) {
+	var keyringConfig KeyringConfig
+	if err := cfg.Unmarshal(cfgPath, &keyringConfig); err != nil {
+		return nil, stacktrace.Wrap(err)
+	}
+	keys := make(map[string][]byte, len(keyringConfig.Keys))
+	for id, encoded := range keyringConfig.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, encryptionKeyError(id)
+		}
+		keys[id] = key
+	}
+	return NewKeyring(keyringConfig.Primary, keys)
+}
+
+func encryptionKeyError(id string) error {
+	err := errcontext.Add(stacktrace.Wrap(ErrEncryptionKey), slog.String("key_id", id))
+	return errclass.WrapAs(err, errclass.Persistent)
+}
+
+// Primary returns the ID of the key used to encrypt.
+func (k *Keyring) Primary() string {
+	return k.primary
+}
+
+// Encrypt encrypts the plaintext with the primary key using AES-GCM.
+// The result records the key ID: [len(id)] id nonce ciphertext.
+func (k *Keyring) Encrypt(plaintext []byte) ([]byte, error) {
+	aead := k.aeads[k.primary]
+	out := make([]byte, 0, 1+len(k.primary)+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	out = append(out, byte(len(k.primary)))
+	out = append(out, k.primary...)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, stacktrace.Wrap(err)
+	}
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, plaintext, nil), nil
+}
+
+// Decrypt decrypts a value returned by Encrypt, returning the plaintext and the ID of the key it was encrypted with.
+func (k *Keyring) Decrypt(ciphertext []byte) ([]byte, string, error) {
+	if len(ciphertext) < 1 || len(ciphertext) < 1+int(ciphertext[0]) {
+		return nil, "", errclass.WrapAs(stacktrace.Wrap(ErrDecryptionFailed), errclass.Persistent)
+	}
+	id := string(ciphertext[1 : 1+ciphertext[0]])
+	ciphertext = ciphertext[1+len(id):]
+
+	aead, ok := k.aeads[id]
+	if !ok {
+		err := errcontext.Add(stacktrace.Wrap(ErrUnknownKey), slog.String("key_id", id))
+		return nil, id, errclass.WrapAs(err, errclass.Persistent)
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, id, errclass.WrapAs(stacktrace.Wrap(ErrDecryptionFailed), errclass.Persistent)
+	}
+	plaintext, err := aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, id, errclass.WrapAs(stacktrace.Wrap(ErrDecryptionFailed), errclass.Persistent)
+	}
+	return plaintext, id, nil
+}
+
+// keyring is used by the encrypted column types, which cannot otherwise be given one when scanned.
+var keyring atomic.Pointer[Keyring]
+
+// SetKeyring sets the keyring used by EncryptedString and EncryptedJSON. It should be called once at startup,
+// before any encrypted columns are read or written.
+func SetKeyring(k *Keyring) {
+	keyring.Store(k)
+}
+
+func currentKeyring() (*Keyring, error) {
+	k := keyring.Load()
+	if k == nil {
+		return nil, errclass.WrapAs(stacktrace.Wrap(ErrNoKeyring), errclass.Persistent)
+	}
+	return k, nil
+}
+
+// encryptedValue holds the key ID of a scanned value, to support key rotation.
+type encryptedValue struct {
+	keyID string
+}
+
+// KeyID returns the ID of the key the value was encrypted with when it was scanned, or "" if it was not scanned.
+func (e encryptedValue) KeyID() string {
+	return e.keyID
+}
+
+// NeedsRotation reports whether the value was scanned having been encrypted with a key other than the primary key.
+// Saving the value again encrypts it with the primary key.
+func (e encryptedValue) NeedsRotation() bool {
+	k := keyring.Load()
+	return e.keyID != "" && k != nil && e.keyID != k.primary
+}
+
+func (e *encryptedValue) decrypt(src any) ([]byte, bool, error) {
+	var ciphertext []byte
+	switch src := src.(type) {
+	case nil:
+		e.keyID = ""
+		return nil, false, nil
+	case []byte:
+		ciphertext = src
+	case string:
+		ciphertext = []byte(src)
+	default:
+		return nil, false, errclass.WrapAs(stacktrace.Wrap(ErrDecryptionFailed), errclass.Persistent)
+	}
+	k, err := currentKeyring()
+	if err != nil {
+		return nil, false, err
+	}
+	plaintext, id, err := k.Decrypt(ciphertext)
+	if err != nil {
+		return nil, false, err
+	}
+	e.keyID = id
+	return plaintext, true, nil
+}
+
+func encrypt(plaintext []byte) (driver.Value, error) {
+	k, err := currentKeyring()
+	if err != nil {
+		return nil, err
+	}
+	return k.Encrypt(plaintext)
+}
+
+// EncryptedString is a string stored encrypted with AES-GCM (see SetKeyring), in a bytea column.
+// NULL is scanned as an empty string, so use a pointer for nullable columns.
+// It is redacted when formatted or logged, so as not to leak the plaintext by accident.
+type EncryptedString struct {
+	encryptedValue
+	Plaintext string
+}
+
+// Value implements driver.Valuer.
+func (s EncryptedString) Value() (driver.Value, error) {
+	return encrypt([]byte(s.Plaintext))
+}
+
+// Scan implements sql.Scanner.
+func (s *EncryptedString) Scan(src any) error {
+	plaintext, _, err := s.decrypt(src)
+	if err != nil {
+		return err
+	}
+	s.Plaintext = string(plaintext)
+	return nil
+}
+
+// String implements fmt.Stringer, redacting the plaintext.
+func (s EncryptedString) String() string {
+	return redacted
+}
+
+// LogValue implements slog.LogValuer, redacting the plaintext.
+func (s EncryptedString) LogValue() slog.Value {
+	return slog.StringValue(redacted)
+}
+
+// EncryptedJSON is a value stored as JSON encrypted with AES-GCM (see SetKeyring), in a bytea column.
+// NULL is scanned as the zero value, so use a pointer for nullable columns.
+// It is redacted when formatted or logged, so as not to leak the plaintext by accident.
+type EncryptedJSON[T any] struct {
+	encryptedValue
+	Data T
+}
+
+// Value implements driver.Valuer.
+func (j EncryptedJSON[T]) Value() (driver.Value, error) {
+	plaintext, err := json.Marshal(j.Data)
+	if err != nil {
+		return nil, errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+	}
+	return encrypt(plaintext)
+}
+
+// Scan implements sql.Scanner.
+func (j *EncryptedJSON[T]) Scan(src any) error {
+	plaintext, ok, err := j.decrypt(src)
+	if err != nil {
+		return err
+	}
+	var data T
+	if ok {
+		if err := json.Unmarshal(plaintext, &data); err != nil {
+			return errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+		}
+	}
+	j.Data = data
+	return nil
+}
+
+// String implements fmt.Stringer, redacting the plaintext.
+func (j EncryptedJSON[T]) String() string {
+	return redacted
+}
+
+// LogValue implements slog.LogValuer, redacting the plaintext.
+func (j EncryptedJSON[T]) LogValue() slog.Value {
+	return slog.StringValue(redacted)
+}
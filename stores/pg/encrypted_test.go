@@ -0,0 +1,143 @@
+package pg_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/stores/pg"
+)
+
+var (
+	encryptionKey    = []byte("0123456789abcdef0123456789abcdef")
+	oldEncryptionKey = []byte("fedcba9876543210")
+)
+
+type address struct {
+	Street string `json:"street"`
+	City   string `json:"city"`
+}
+
+type customer struct {
+	ID      int64                      `bun:"id,pk"`
+	SSN     pg.EncryptedString         `bun:"ssn,type:bytea"`
+	Address *pg.EncryptedJSON[address] `bun:"address,type:bytea"`
+}
+
+func TestKeyring(t *testing.T) {
+	t.Parallel()
+
+	_, err := pg.NewKeyring("missing", map[string][]byte{"v1": encryptionKey})
+	require.ErrorIs(t, err, pg.ErrEncryptionKey)
+	_, err = pg.NewKeyring("v1", map[string][]byte{"v1": []byte("short")})
+	require.ErrorIs(t, err, pg.ErrEncryptionKey)
+
+	old, err := pg.NewKeyring("v1", map[string][]byte{"v1": oldEncryptionKey})
+	require.NoError(t, err)
+	rotated, err := pg.NewKeyring("v2", map[string][]byte{"v1": oldEncryptionKey, "v2": encryptionKey})
+	require.NoError(t, err)
+
+	ciphertext, err := old.Encrypt([]byte("secret"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(ciphertext), "secret")
+
+	// previous keys can still decrypt
+	plaintext, id, err := rotated.Decrypt(ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(plaintext))
+	assert.Equal(t, "v1", id)
+
+	// but unknown keys cannot
+	ciphertext, err = rotated.Encrypt([]byte("secret"))
+	require.NoError(t, err)
+	_, id, err = old.Decrypt(ciphertext)
+	require.ErrorIs(t, err, pg.ErrUnknownKey)
+	assert.Equal(t, "v2", id)
+
+	// tampering is detected
+	ciphertext[len(ciphertext)-1] ^= 1
+	_, _, err = rotated.Decrypt(ciphertext)
+	require.ErrorIs(t, err, pg.ErrDecryptionFailed)
+	_, _, err = rotated.Decrypt([]byte{10, 'v'})
+	require.ErrorIs(t, err, pg.ErrDecryptionFailed)
+}
+
+func TestKeyringFromConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := config.NewConfigurationFromMap(map[string]any{
+		"encryption": map[string]any{
+			"primary": "v2",
+			"keys": map[string]any{
+				"v1": base64.StdEncoding.EncodeToString(oldEncryptionKey),
+				"v2": base64.StdEncoding.EncodeToString(encryptionKey),
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	keyring, err := pg.NewKeyringFromConfig(cfg, "encryption")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", keyring.Primary())
+}
+
+func TestEncryptedColumns(t *testing.T) { //nolint:paralleltest // This test cannot be parallel since it sets the global keyring
+	t.Cleanup(func() { pg.SetKeyring(nil) })
+
+	// without a keyring, values cannot be encrypted
+	_, err := pg.EncryptedString{Plaintext: "123-45-6789"}.Value()
+	require.ErrorIs(t, err, pg.ErrNoKeyring)
+
+	old, err := pg.NewKeyring("v1", map[string][]byte{"v1": oldEncryptionKey})
+	require.NoError(t, err)
+	pg.SetKeyring(old)
+
+	ssn, err := pg.EncryptedString{Plaintext: "123-45-6789"}.Value()
+	require.NoError(t, err)
+	addr, err := pg.EncryptedJSON[address]{Data: address{Street: "1 Main St", City: "Springfield"}}.Value()
+	require.NoError(t, err)
+
+	// rotate the key, then read values encrypted with the previous key
+	rotated, err := pg.NewKeyring("v2", map[string][]byte{"v1": oldEncryptionKey, "v2": encryptionKey})
+	require.NoError(t, err)
+	pg.SetKeyring(rotated)
+
+	sqldb, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqldb.Close() })
+	db := bun.NewDB(sqldb, pgdialect.New())
+
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id", "ssn", "address"}).
+		AddRow(1, ssn, addr).
+		AddRow(2, ssn, nil))
+	var customers []customer
+	require.NoError(t, db.NewSelect().Model(&customers).Scan(t.Context()))
+	require.Len(t, customers, 2)
+
+	c := customers[0]
+	assert.Equal(t, "123-45-6789", c.SSN.Plaintext)
+	assert.Equal(t, "v1", c.SSN.KeyID())
+	assert.True(t, c.SSN.NeedsRotation())
+	require.NotNil(t, c.Address)
+	assert.Equal(t, address{Street: "1 Main St", City: "Springfield"}, c.Address.Data)
+	assert.Nil(t, customers[1].Address)
+
+	// plaintext is not leaked by formatting
+	assert.NotContains(t, fmt.Sprintf("%v %s", c.SSN, c.Address), "123")
+
+	// saving again encrypts with the primary key
+	value, err := c.SSN.Value()
+	require.NoError(t, err)
+	var rescanned pg.EncryptedString
+	require.NoError(t, rescanned.Scan(value))
+	assert.Equal(t, "123-45-6789", rescanned.Plaintext)
+	assert.Equal(t, "v2", rescanned.KeyID())
+	assert.False(t, rescanned.NeedsRotation())
+}
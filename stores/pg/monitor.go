@@ -0,0 +1,269 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/uptrace/bun"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/metrics"
+	"github.com/zircuit-labs/zkr-go-common/task/polling"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const defaultMonitorInterval = time.Minute
+
+// MonitorConfig configures the checks performed by a Monitor.
+type MonitorConfig struct {
+	// Interval between checks. Defaults to one minute.
+	Interval  time.Duration
+	Freshness []FreshnessCheck
+	Counts    []CountCheck
+}
+
+// FreshnessCheck warns when the most recent data is older than MaxAge.
+type FreshnessCheck struct {
+	Name string
+	// Query must return a single timestamp, eg SELECT max(created_at) FROM orders
+	// A NULL result (eg an empty table) is considered stale.
+	Query  string
+	MaxAge time.Duration
+}
+
+// CountCheck warns when a row count falls outside of [Min, Max].
+type CountCheck struct {
+	Name string
+	// Query must return a single integer, eg SELECT count(*) FROM orders WHERE status = 'pending'
+	Query string
+	Min   int64
+	// Max is ignored if zero.
+	Max int64
+}
+
+type options struct {
+	logger     *slog.Logger
+	registerer prometheus.Registerer
+	clock      clockwork.Clock
+}
+
+// Option is an option func for NewMonitor.
+type Option func(options *options)
+
+// WithLogger sets the logger to be used.
+func WithLogger(logger *slog.Logger) Option {
+	return func(options *options) {
+		options.logger = logger
+	}
+}
+
+// WithMetrics records the results of each check with the given registerer.
+func WithMetrics(registerer prometheus.Registerer) Option {
+	return func(options *options) {
+		options.registerer = registerer
+	}
+}
+
+// WithClock allows users to mock the clock used to determine data age for testing purposes.
+func WithClock(clock clockwork.Clock) Option {
+	return func(options *options) {
+		options.clock = clock
+	}
+}
+
+// Monitor periodically runs freshness and row count queries, logging a warning
+// (and updating metrics, if enabled) whenever data is stale or counts are out of range.
+// It implements polling.Action.
+type Monitor struct {
+	db      bun.IDB
+	cfg     MonitorConfig
+	opts    options
+	metrics *monitorMetrics
+}
+
+type monitorMetrics struct {
+	age     *prometheus.GaugeVec
+	count   *prometheus.GaugeVec
+	healthy *prometheus.GaugeVec
+}
+
+// NewMonitor creates a Monitor from the config at cfgPath.
+func NewMonitor(cfg *config.Configuration, cfgPath string, db bun.IDB, opts ...Option) (*Monitor, error) {
+	monitorConfig := MonitorConfig{
+		Interval: defaultMonitorInterval,
+	}
+	if err := cfg.Unmarshal(cfgPath, &monitorConfig); err != nil {
+		return nil, stacktrace.Wrap(err)
+	}
+
+	options := options{
+		logger: log.NewNilLogger(),
+		clock:  clockwork.NewRealClock(),
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	m := &Monitor{
+		db:   db,
+		cfg:  monitorConfig,
+		opts: options,
+	}
+
+	if options.registerer != nil {
+		metrics, err := registerMonitorMetrics(options.registerer)
+		if err != nil {
+			return nil, err
+		}
+		m.metrics = metrics
+	}
+
+	return m, nil
+}
+
+// Task returns a polling task which runs the monitor at the configured interval, starting immediately.
+func (m *Monitor) Task(name string, opts ...polling.Option) *polling.Task {
+	opts = append([]polling.Option{
+		polling.WithInterval(m.cfg.Interval),
+		polling.WithRunAtStart(),
+		polling.WithLogger(m.opts.logger),
+	}, opts...)
+	return polling.NewTask(name, m, opts...)
+}
+
+// Run implements polling.Action by performing all checks once.
+// Stale data and out of range counts are only logged, while failed queries are returned as errors.
+func (m *Monitor) Run(ctx context.Context) error {
+	var errs []error
+	for _, check := range m.cfg.Freshness {
+		if err := m.checkFreshness(ctx, check); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, check := range m.cfg.Counts {
+		if err := m.checkCount(ctx, check); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Cleanup implements polling.Action.
+func (m *Monitor) Cleanup() {}
+
+func (m *Monitor) checkFreshness(ctx context.Context, check FreshnessCheck) (err error) {
+	defer func() {
+		if err != nil {
+			err = errcontext.Add(err, slog.String("check", check.Name))
+		}
+	}()
+
+	var latest sql.NullTime
+	if err := m.db.NewRaw(check.Query).Scan(ctx, &latest); err != nil {
+		return stacktrace.Wrap(err)
+	}
+
+	logger := m.opts.logger.With(slog.String("check", check.Name))
+	if !latest.Valid {
+		logger.Warn("data is stale: no data found")
+		m.metrics.setAge(check.Name, -1)
+		m.metrics.setHealthy(check.Name, false)
+		return nil
+	}
+
+	age := m.opts.clock.Since(latest.Time)
+	m.metrics.setAge(check.Name, age.Seconds())
+	healthy := check.MaxAge <= 0 || age <= check.MaxAge
+	if !healthy {
+		logger.Warn("data is stale",
+			slog.Time("latest", latest.Time),
+			slog.Duration("age", age),
+			slog.Duration("max_age", check.MaxAge),
+		)
+	}
+	m.metrics.setHealthy(check.Name, healthy)
+	return nil
+}
+
+func (m *Monitor) checkCount(ctx context.Context, check CountCheck) (err error) {
+	defer func() {
+		if err != nil {
+			err = errcontext.Add(err, slog.String("check", check.Name))
+		}
+	}()
+
+	var count int64
+	if err := m.db.NewRaw(check.Query).Scan(ctx, &count); err != nil {
+		return stacktrace.Wrap(err)
+	}
+
+	m.metrics.setCount(check.Name, count)
+	healthy := count >= check.Min && (check.Max == 0 || count <= check.Max)
+	if !healthy {
+		m.opts.logger.Warn("row count out of range",
+			slog.String("check", check.Name),
+			slog.Int64("count", count),
+			slog.Int64("min", check.Min),
+			slog.Int64("max", check.Max),
+		)
+	}
+	m.metrics.setHealthy(check.Name, healthy)
+	return nil
+}
+
+// The following are no-ops when metrics are not enabled.
+
+func (mm *monitorMetrics) setAge(name string, seconds float64) {
+	if mm != nil {
+		mm.age.WithLabelValues(name).Set(seconds)
+	}
+}
+
+func (mm *monitorMetrics) setCount(name string, count int64) {
+	if mm != nil {
+		mm.count.WithLabelValues(name).Set(float64(count))
+	}
+}
+
+func (mm *monitorMetrics) setHealthy(name string, healthy bool) {
+	if mm == nil {
+		return
+	}
+	value := 0.0
+	if healthy {
+		value = 1
+	}
+	mm.healthy.WithLabelValues(name).Set(value)
+}
+
+func registerMonitorMetrics(registerer prometheus.Registerer) (*monitorMetrics, error) {
+	age, err := metrics.Register(registerer, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pg_monitor_data_age_seconds",
+		Help: "Age of the most recent data for each freshness check, or -1 if there is none.",
+	}, []string{"check"}))
+	if err != nil {
+		return nil, err
+	}
+	count, err := metrics.Register(registerer, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pg_monitor_row_count",
+		Help: "Row count for each count check.",
+	}, []string{"check"}))
+	if err != nil {
+		return nil, err
+	}
+	healthy, err := metrics.Register(registerer, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pg_monitor_check_healthy",
+		Help: "Whether each check last passed (1) or failed (0).",
+	}, []string{"check"}))
+	if err != nil {
+		return nil, err
+	}
+	return &monitorMetrics{age: age, count: count, healthy: healthy}, nil
+}
@@ -0,0 +1,128 @@
+package pg_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jonboulle/clockwork"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/stores/pg"
+)
+
+const (
+	ordersFreshness = "SELECT max(created_at) FROM orders"
+	eventsFreshness = "SELECT max(created_at) FROM events"
+	pendingCount    = "SELECT count(*) FROM orders WHERE status = 'pending'"
+)
+
+var errQuery = errors.New("query failed")
+
+func newMonitorConfig(t *testing.T) *config.Configuration {
+	t.Helper()
+	cfg, err := config.NewConfigurationFromMap(map[string]any{
+		"monitor": map[string]any{
+			"interval": "30s",
+			"freshness": []map[string]any{
+				{"name": "orders", "query": ordersFreshness, "maxage": "10m"},
+				{"name": "events", "query": eventsFreshness, "maxage": "1h"},
+			},
+			"counts": []map[string]any{
+				{"name": "pending", "query": pendingCount, "min": 1, "max": 100},
+			},
+		},
+	})
+	require.NoError(t, err)
+	return cfg
+}
+
+func TestMonitor(t *testing.T) {
+	t.Parallel()
+
+	sqldb, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	t.Cleanup(func() { sqldb.Close() })
+	db := bun.NewDB(sqldb, pgdialect.New())
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := clockwork.NewFakeClockAt(now)
+	registry := prometheus.NewRegistry()
+	buf := &bytes.Buffer{}
+	logger, err := log.NewLogger(log.WithWriter(buf))
+	require.NoError(t, err)
+
+	monitor, err := pg.NewMonitor(newMonitorConfig(t), "monitor", db,
+		pg.WithClock(clock),
+		pg.WithMetrics(registry),
+		pg.WithLogger(logger),
+	)
+	require.NoError(t, err)
+
+	// first run: orders are stale, events are fresh, and the pending count is too high
+	mock.ExpectQuery(ordersFreshness).WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(now.Add(-time.Hour)))
+	mock.ExpectQuery(eventsFreshness).WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(now.Add(-time.Minute)))
+	mock.ExpectQuery(pendingCount).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(150))
+	require.NoError(t, monitor.Run(t.Context()))
+
+	logs := buf.String()
+	assert.Contains(t, logs, `"msg":"data is stale"`)
+	assert.Contains(t, logs, `"check":"orders"`)
+	assert.NotContains(t, logs, `"check":"events"`)
+	assert.Contains(t, logs, `"msg":"row count out of range"`)
+
+	healthy := func(check string) float64 {
+		return gaugeValue(t, registry, "pg_monitor_check_healthy", check)
+	}
+	assert.InDelta(t, 0.0, healthy("orders"), 0)
+	assert.InDelta(t, 1.0, healthy("events"), 0)
+	assert.InDelta(t, 0.0, healthy("pending"), 0)
+	assert.InDelta(t, time.Hour.Seconds(), gaugeValue(t, registry, "pg_monitor_data_age_seconds", "orders"), 0)
+	assert.InDelta(t, 150.0, gaugeValue(t, registry, "pg_monitor_row_count", "pending"), 0)
+
+	// second run: an empty table is stale, and a failed query does not prevent other checks
+	mock.ExpectQuery(ordersFreshness).WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+	mock.ExpectQuery(eventsFreshness).WillReturnError(errQuery)
+	mock.ExpectQuery(pendingCount).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+	err = monitor.Run(t.Context())
+	require.ErrorIs(t, err, errQuery)
+
+	assert.InDelta(t, -1.0, gaugeValue(t, registry, "pg_monitor_data_age_seconds", "orders"), 0)
+	assert.InDelta(t, 1.0, healthy("pending"), 0)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// metrics may be shared between monitors
+	_, err = pg.NewMonitor(newMonitorConfig(t), "monitor", db, pg.WithMetrics(registry))
+	require.NoError(t, err)
+
+	assert.Equal(t, "pg monitor", monitor.Task("pg monitor").Name())
+}
+
+// gaugeValue returns the value of the gauge with the given check label.
+func gaugeValue(t *testing.T, registry *prometheus.Registry, name, check string) float64 {
+	t.Helper()
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "check" && label.GetValue() == check {
+					return metric.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	require.Failf(t, "metric not found", "%s{check=%q}", name, check)
+	return 0
+}
@@ -0,0 +1,74 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/uptrace/bun"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// PageOpts extends QueryOpts with the page to fetch for offset pagination.
+// The cursor is not used.
+type PageOpts interface {
+	QueryOpts
+	GetPage() int // 1-based; values less than 1 are treated as the first page
+}
+
+// PageInfo describes the page of results returned by OffsetPaginate.
+type PageInfo struct {
+	Page       int `json:"page"`
+	PerPage    int `json:"per_page"`
+	Total      int `json:"total"`
+	TotalPages int `json:"total_pages"`
+}
+
+// OffsetPaginate returns the requested page of results, with GetLimit results per page, along with the total
+// number of results and pages. If no limit is set, all results are returned as a single page.
+// Results are ordered as per Paginate, and a page beyond the last returns no results.
+// NOTE: Unlike Paginate, the database must count every matching row and skip those before the page,
+// so this is best suited to admin or reporting endpoints where jumping to arbitrary pages matters.
+// Rows inserted or deleted between requests may also shift results between pages.
+func OffsetPaginate[V any, T Pageable[V]](ctx context.Context, filterQuery *bun.SelectQuery, opts PageOpts) (results []*V, info PageInfo, err error) {
+	var data []T
+
+	if err := validateOrdering[V, T](); err != nil {
+		return nil, info, err
+	}
+
+	info.Page = max(opts.GetPage(), 1)
+	info.PerPage = opts.GetLimit()
+
+	// The count ignores any ordering, limit and offset
+	info.Total, err = filterQuery.Count(ctx)
+	if err != nil {
+		return nil, info, stacktrace.Wrap(err)
+	}
+	if info.PerPage <= 0 {
+		info.PerPage = info.Total
+	}
+	if info.Total == 0 {
+		return nil, info, nil
+	}
+	info.TotalPages = (info.Total + info.PerPage - 1) / info.PerPage
+	if info.Page > info.TotalPages {
+		return nil, info, nil
+	}
+
+	filterQuery = paginationSort[V, T](filterQuery).
+		Limit(info.PerPage).
+		Offset((info.Page - 1) * info.PerPage)
+
+	// Execute the query
+	err = filterQuery.Scan(ctx, &data)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, info, stacktrace.Wrap(err)
+	}
+	if len(data) == 0 {
+		return nil, info, nil
+	}
+
+	return parseOrderedWrapper(data), info, nil
+}
@@ -0,0 +1,173 @@
+package pg_test
+
+import (
+	"database/sql/driver"
+	"strconv"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+
+	"github.com/zircuit-labs/zkr-go-common/stores/pg"
+)
+
+type user struct {
+	bun.BaseModel `bun:"table:users"`
+	ID            int64  `bun:"id"`
+	Name          string `bun:"name"`
+}
+
+// userPage orders users by name, using the id as a tiebreaker.
+type userPage struct {
+	user
+}
+
+func (u userPage) KeySort() []pg.KeySort {
+	return []pg.KeySort{{Key: "name", Sort: pg.SortOrderAscending}}
+}
+
+func (u userPage) CursorValues() []string {
+	return []string{u.Name}
+}
+
+func (u userPage) DeserizalizeCursorValues(values []string) ([]any, error) {
+	return []any{values[0]}, nil
+}
+
+func (u userPage) UnWrap() user {
+	return u.user
+}
+
+func (u userPage) TiebreakerKey() string {
+	return "id"
+}
+
+func (u userPage) TiebreakerValue() string {
+	return strconv.FormatInt(u.ID, 10)
+}
+
+func (u userPage) DeserializeTiebreakerValue(value string) (any, error) {
+	return strconv.ParseInt(value, 10, 64)
+}
+
+type pageOpts struct {
+	page, perPage int
+}
+
+func (o pageOpts) GetLimit() int        { return o.perPage }
+func (o pageOpts) GetCursor() pg.Cursor { return pg.Cursor{} }
+func (o pageOpts) GetPage() int         { return o.page }
+
+func TestOffsetPaginate(t *testing.T) {
+	t.Parallel()
+
+	const countQuery = `SELECT count(*) FROM "users" AS "user" WHERE (name LIKE 'a%')`
+
+	testCases := []struct {
+		name     string
+		opts     pageOpts
+		total    int
+		query    string
+		rows     [][]driver.Value
+		expected pg.PageInfo
+		results  []user
+	}{
+		{
+			name:     "first page",
+			opts:     pageOpts{page: 1, perPage: 2},
+			total:    5,
+			query:    `SELECT "user"."id", "user"."name" FROM "users" AS "user" WHERE (name LIKE 'a%') ORDER BY "name" ASC, "id" ASC LIMIT 2`,
+			rows:     [][]driver.Value{{1, "aaron"}, {2, "abby"}},
+			expected: pg.PageInfo{Page: 1, PerPage: 2, Total: 5, TotalPages: 3},
+			results:  []user{{ID: 1, Name: "aaron"}, {ID: 2, Name: "abby"}},
+		},
+		{
+			name:     "last partial page",
+			opts:     pageOpts{page: 3, perPage: 2},
+			total:    5,
+			query:    `SELECT "user"."id", "user"."name" FROM "users" AS "user" WHERE (name LIKE 'a%') ORDER BY "name" ASC, "id" ASC LIMIT 2 OFFSET 4`,
+			rows:     [][]driver.Value{{5, "amy"}},
+			expected: pg.PageInfo{Page: 3, PerPage: 2, Total: 5, TotalPages: 3},
+			results:  []user{{ID: 5, Name: "amy"}},
+		},
+		{
+			name:     "page beyond the last",
+			opts:     pageOpts{page: 4, perPage: 2},
+			total:    5,
+			expected: pg.PageInfo{Page: 4, PerPage: 2, Total: 5, TotalPages: 3},
+		},
+		{
+			name:     "page less than 1 is the first page",
+			opts:     pageOpts{page: 0, perPage: 10},
+			total:    1,
+			query:    `SELECT "user"."id", "user"."name" FROM "users" AS "user" WHERE (name LIKE 'a%') ORDER BY "name" ASC, "id" ASC LIMIT 10`,
+			rows:     [][]driver.Value{{1, "aaron"}},
+			expected: pg.PageInfo{Page: 1, PerPage: 10, Total: 1, TotalPages: 1},
+			results:  []user{{ID: 1, Name: "aaron"}},
+		},
+		{
+			name:     "no limit returns a single page",
+			opts:     pageOpts{page: 1},
+			total:    2,
+			query:    `SELECT "user"."id", "user"."name" FROM "users" AS "user" WHERE (name LIKE 'a%') ORDER BY "name" ASC, "id" ASC LIMIT 2`,
+			rows:     [][]driver.Value{{1, "aaron"}, {2, "abby"}},
+			expected: pg.PageInfo{Page: 1, PerPage: 2, Total: 2, TotalPages: 1},
+			results:  []user{{ID: 1, Name: "aaron"}, {ID: 2, Name: "abby"}},
+		},
+		{
+			name:     "no results",
+			opts:     pageOpts{page: 1, perPage: 2},
+			total:    0,
+			expected: pg.PageInfo{Page: 1, PerPage: 2},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			sqldb, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			require.NoError(t, err)
+			t.Cleanup(func() { sqldb.Close() })
+			db := bun.NewDB(sqldb, pgdialect.New())
+
+			mock.ExpectQuery(countQuery).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tc.total))
+			if tc.query != "" {
+				rows := sqlmock.NewRows([]string{"id", "name"})
+				for _, row := range tc.rows {
+					rows.AddRow(row...)
+				}
+				mock.ExpectQuery(tc.query).WillReturnRows(rows)
+			}
+
+			query := db.NewSelect().Model((*user)(nil)).Where("name LIKE 'a%'")
+			results, info, err := pg.OffsetPaginate[user, userPage](t.Context(), query, tc.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, info)
+			require.Len(t, results, len(tc.results))
+			for i, result := range results {
+				assert.Equal(t, tc.results[i], *result)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestOffsetPaginateError(t *testing.T) {
+	t.Parallel()
+	sqldb, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqldb.Close() })
+	db := bun.NewDB(sqldb, pgdialect.New())
+
+	mock.ExpectQuery("SELECT count").WillReturnError(errQuery)
+	_, _, err = pg.OffsetPaginate[user, userPage](t.Context(), db.NewSelect().Model((*user)(nil)), pageOpts{page: 1, perPage: 2})
+	require.ErrorIs(t, err, errQuery)
+
+	mock.ExpectQuery("SELECT count").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery("SELECT").WillReturnError(errQuery)
+	_, _, err = pg.OffsetPaginate[user, userPage](t.Context(), db.NewSelect().Model((*user)(nil)), pageOpts{page: 1, perPage: 2})
+	require.ErrorIs(t, err, errQuery)
+}
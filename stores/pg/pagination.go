@@ -10,10 +10,14 @@ import (
 
 	"github.com/uptrace/bun"
 
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
 	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
 )
 
-var ErrCursorValues = errors.New("unable to deserialize expected cursor values")
+var (
+	ErrCursorValues      = errors.New("unable to deserialize expected cursor values")
+	ErrNonUniqueOrdering = errors.New("pagination ordering is not unique: the final KeySort must be Unique, or a Tiebreaker must be implemented")
+)
 
 type SortOrder string
 
@@ -65,6 +69,10 @@ type KeySort struct {
 	Key     string
 	Sort    SortOrder
 	Complex bool
+	// Unique declares that no two rows share a value for this key (eg a primary key).
+	// Pagination requires the final key to be unique, otherwise rows with equal
+	// sort values may be duplicated or skipped across pages.
+	Unique bool
 }
 
 func (k KeySort) String() string {
@@ -88,8 +96,29 @@ type Pageable[V any] interface {
 	UnWrap() V                                               // return the underlying struct
 }
 
-func Paginate[V any, T Pageable[V]](ctx context.Context, filterQuery *bun.SelectQuery, opts QueryOpts) (results []*V, cursor Cursor, err error) {
+// Tiebreaker may be implemented by a Pageable whose KeySort does not end with a Unique key.
+// The tiebreaker key is appended to the KeySort, using the sort order of the final key,
+// and its value is appended to the cursor values.
+type Tiebreaker interface {
+	TiebreakerKey() string                                // a unique column, typically the primary key eg "id"
+	TiebreakerValue() string                              // the value of the tiebreaker column as a string
+	DeserializeTiebreakerValue(value string) (any, error) // convert the tiebreaker value to its respective type
+}
+
+// Paginate returns the page of results after (or before) the cursor of opts, along with the cursors
+// of the neighbouring pages. Use WithCursorCodec to encode cursors as opaque signed tokens, in which case
+// a cursor which has been tampered with returns ErrInvalidCursor classed as Persistent.
+func Paginate[V any, T Pageable[V]](ctx context.Context, filterQuery *bun.SelectQuery, opts QueryOpts, paginationOpts ...PaginateOption) (results []*V, cursor Cursor, err error) {
 	var data []T
+	var options paginateOptions
+	for _, opt := range paginationOpts {
+		opt(&options)
+	}
+	codec := options.cursorCodec
+
+	if err := validateOrdering[V, T](); err != nil {
+		return nil, cursor, err
+	}
 
 	// If no cursor is present, start from the beginning
 	if !opts.GetCursor().Exists() {
@@ -120,7 +149,7 @@ func Paginate[V any, T Pageable[V]](ctx context.Context, filterQuery *bun.Select
 
 		// Return cursor values for later use.
 		if moreData {
-			cursor.Next = strings.Join(data[len(data)-1].CursorValues(), ",")
+			cursor.Next = codec.Encode(cursorValues[V](data[len(data)-1]))
 		}
 		// cursor.Previous should remain empty as this is the first page of results.
 
@@ -128,7 +157,7 @@ func Paginate[V any, T Pageable[V]](ctx context.Context, filterQuery *bun.Select
 	}
 
 	// Otherwise, apply cursor style pagination
-	filterQuery, err = paginationWhere[V, T](filterQuery, opts.GetCursor())
+	filterQuery, err = paginationWhere[V, T](filterQuery, opts.GetCursor(), codec)
 	if err != nil {
 		return nil, cursor, stacktrace.Wrap(err)
 	}
@@ -161,23 +190,91 @@ func Paginate[V any, T Pageable[V]](ctx context.Context, filterQuery *bun.Select
 		}
 		slices.Reverse(data)
 		if moreData {
-			cursor.Previous = strings.Join(data[0].CursorValues(), ",")
+			cursor.Previous = codec.Encode(cursorValues[V](data[0]))
 		}
-		cursor.Next = strings.Join(data[len(data)-1].CursorValues(), ",")
+		cursor.Next = codec.Encode(cursorValues[V](data[len(data)-1]))
 	} else {
 		if moreData {
 			data = data[:len(data)-1]
-			cursor.Next = strings.Join(data[len(data)-1].CursorValues(), ",")
+			cursor.Next = codec.Encode(cursorValues[V](data[len(data)-1]))
 		}
-		cursor.Previous = strings.Join(data[0].CursorValues(), ",")
+		cursor.Previous = codec.Encode(cursorValues[V](data[0]))
 	}
 
 	return parseOrderedWrapper(data), cursor, nil
 }
 
-func paginationSort[V any, T Pageable[V]](q *bun.SelectQuery) *bun.SelectQuery {
+// tiebreaker returns the Tiebreaker of T if it is needed to make the ordering unique.
+func tiebreaker[V any, T Pageable[V]]() (Tiebreaker, bool) {
+	var data T
+	tb, ok := any(data).(Tiebreaker)
+	if !ok {
+		return nil, false
+	}
+	keys := data.KeySort()
+	if len(keys) > 0 && keys[len(keys)-1].Unique {
+		return nil, false
+	}
+	return tb, true
+}
+
+// keySorts returns the KeySort of T including any tiebreaker.
+func keySorts[V any, T Pageable[V]]() []KeySort {
+	var data T
+	keys := data.KeySort()
+	tb, ok := tiebreaker[V, T]()
+	if !ok {
+		return keys
+	}
+	sort := SortOrderAscending
+	if len(keys) > 0 {
+		sort = keys[len(keys)-1].Sort
+	}
+	return append(slices.Clone(keys), KeySort{Key: tb.TiebreakerKey(), Sort: sort, Unique: true})
+}
+
+// validateOrdering ensures that the ordering of T is unique.
+func validateOrdering[V any, T Pageable[V]]() error {
+	keys := keySorts[V, T]()
+	if len(keys) == 0 || !keys[len(keys)-1].Unique {
+		return errclass.WrapAs(stacktrace.Wrap(ErrNonUniqueOrdering), errclass.Persistent)
+	}
+	return nil
+}
+
+// cursorValues returns the cursor values of t including any tiebreaker value.
+func cursorValues[V any, T Pageable[V]](t T) []string {
+	values := t.CursorValues()
+	if _, ok := tiebreaker[V, T](); ok {
+		values = append(slices.Clone(values), any(t).(Tiebreaker).TiebreakerValue()) //nolint:forcetypeassert // checked by tiebreaker
+	}
+	return values
+}
+
+// deserializeCursorValues converts cursor values including any tiebreaker value to their respective types.
+func deserializeCursorValues[V any, T Pageable[V]](values []string) ([]any, error) {
 	var data T
-	for _, keySort := range data.KeySort() {
+	tb, ok := tiebreaker[V, T]()
+	if !ok {
+		return data.DeserizalizeCursorValues(values)
+	}
+	if len(values) == 0 {
+		return nil, stacktrace.Wrap(ErrCursorValues)
+	}
+	last := len(values) - 1
+	deserialized, err := data.DeserizalizeCursorValues(values[:last])
+	if err != nil {
+		return nil, err
+	}
+	tbValue, err := tb.DeserializeTiebreakerValue(values[last])
+	if err != nil {
+		return nil, err
+	}
+	return append(deserialized, tbValue), nil
+}
+
+func paginationSort[V any, T Pageable[V]](q *bun.SelectQuery) *bun.SelectQuery {
+	for _, keySort := range keySorts[V, T]() {
 		if keySort.Complex {
 			q.OrderExpr(keySort.String())
 			continue
@@ -189,8 +286,7 @@ func paginationSort[V any, T Pageable[V]](q *bun.SelectQuery) *bun.SelectQuery {
 }
 
 func paginationReverseSort[V any, T Pageable[V]](q *bun.SelectQuery) *bun.SelectQuery {
-	var data T
-	for _, keySort := range data.KeySort() {
+	for _, keySort := range keySorts[V, T]() {
 		if keySort.Complex {
 			q.OrderExpr(keySort.Opposite())
 			continue
@@ -215,27 +311,46 @@ func (cl clause) EqualityString() string {
 	return fmt.Sprintf("%s %s ?", cl.key, ComparisionOperatorEqual)
 }
 
-func paginationWhere[V any, T Pageable[V]](q *bun.SelectQuery, cur Cursor) (*bun.SelectQuery, error) {
-	var data T
-
+func paginationWhere[V any, T Pageable[V]](q *bun.SelectQuery, cur Cursor, codec *CursorCodec) (*bun.SelectQuery, error) {
 	// Deserialize the cursor values
 	cursorValue := cur.Next
 	if cur.Previous != "" {
 		cursorValue = cur.Previous
 	}
-	cursorValues := strings.Split(cursorValue, ",")
-	actualCursorValues, err := data.DeserizalizeCursorValues(cursorValues)
+	values, err := codec.Decode(cursorValue)
+	if err != nil {
+		return nil, err
+	}
+	filterQuery, err := cursorWhere[V, T](q, values, cur.Previous != "")
+	if err != nil {
+		return nil, err
+	}
+
+	// Include the appropriate sort order
+	if cur.Next != "" {
+		filterQuery = paginationSort[V, T](filterQuery)
+	} else {
+		filterQuery = paginationReverseSort[V, T](filterQuery)
+	}
+	return filterQuery, nil
+}
+
+// cursorWhere restricts the query to the rows after the cursor values, or before them if reverse.
+func cursorWhere[V any, T Pageable[V]](q *bun.SelectQuery, values []string, reverse bool) (*bun.SelectQuery, error) {
+	keys := keySorts[V, T]()
+
+	actualCursorValues, err := deserializeCursorValues[V, T](values)
 	if err != nil {
 		return nil, stacktrace.Wrap(err)
 	}
 
-	if len(actualCursorValues) != len(data.KeySort()) {
+	if len(actualCursorValues) != len(keys) {
 		return nil, stacktrace.Wrap(ErrCursorValues)
 	}
 
 	// Build the where clause(s)
-	clauses := make([]clause, 0, len(data.KeySort()))
-	for i, keySort := range data.KeySort() {
+	clauses := make([]clause, 0, len(keys))
+	for i, keySort := range keys {
 		cl := clause{
 			key:   keySort.Key,
 			sort:  keySort.Sort,
@@ -250,7 +365,7 @@ func paginationWhere[V any, T Pageable[V]](q *bun.SelectQuery, cur Cursor) (*bun
 
 		// For previous page, we must reversing the sort order and the comparator
 		// NOTE: The query results will need to be reversed before being returned.
-		if cur.Previous != "" {
+		if reverse {
 			cl.comparator = cl.comparator.Opposite()
 			cl.sort = cl.sort.Opposite()
 		}
@@ -277,15 +392,7 @@ func paginationWhere[V any, T Pageable[V]](q *bun.SelectQuery, cur Cursor) (*bun
 	compoundWhereClause := strings.Join(fullClauses, " OR ")
 
 	// Apply the where clause
-	filterQuery := q.Where(compoundWhereClause, valueSet...)
-
-	// Include the appropriate sort order
-	if cur.Next != "" {
-		filterQuery = paginationSort[V, T](filterQuery)
-	} else {
-		filterQuery = paginationReverseSort[V, T](filterQuery)
-	}
-	return filterQuery, nil
+	return q.Where(compoundWhereClause, valueSet...), nil
 }
 
 func parseOrderedWrapper[V any, T Pageable[V]](ordered []T) []*V {
@@ -1,12 +1,16 @@
 package pg
 
 import (
+	"strconv"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/dialect/pgdialect"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
 )
 
 func TestKeySortString(t *testing.T) {
@@ -113,6 +117,47 @@ func TestPaginationReverseSort(t *testing.T) {
 	}
 }
 
+func TestValidateOrdering(t *testing.T) {
+	t.Parallel()
+
+	err := validateOrdering[MockData, MockDataOrdered]()
+	require.ErrorIs(t, err, ErrNonUniqueOrdering)
+	assert.Equal(t, errclass.Persistent, errclass.GetClass(err))
+
+	assert.NoError(t, validateOrdering[MockData, MockDataUnique]())
+	assert.NoError(t, validateOrdering[MockData, MockDataTiebreaker]())
+}
+
+func TestPaginationTiebreaker(t *testing.T) {
+	t.Parallel()
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	mockBun := bun.NewDB(db, pgdialect.New())
+
+	finalQuery := paginationSort[MockData, MockDataTiebreaker](mockBun.NewSelect())
+	assert.Equal(t, `SELECT * ORDER BY "name" ASC, "name2" DESC, "id" DESC`, finalQuery.String())
+
+	finalQuery = paginationReverseSort[MockData, MockDataTiebreaker](mockBun.NewSelect())
+	assert.Equal(t, `SELECT * ORDER BY "name" DESC, "name2" ASC, "id" ASC`, finalQuery.String())
+
+	assert.Equal(t, []string{"a", "b", "7"}, cursorValues[MockData](MockDataTiebreaker{}))
+
+	values, err := deserializeCursorValues[MockData, MockDataTiebreaker]([]string{"a", "b", "7"})
+	require.NoError(t, err)
+	assert.Equal(t, []any{"a", "b", int64(7)}, values)
+
+	finalQuery, err = paginationWhere[MockData, MockDataTiebreaker](mockBun.NewSelect(), Cursor{Next: "a,b,7"}, nil)
+	require.NoError(t, err)
+	assert.Contains(t, finalQuery.String(), "name = 'a' AND name2 = 'b' AND id < 7")
+
+	// a unique final key means the tiebreaker is not needed
+	finalQuery = paginationSort[MockData, MockDataUnique](mockBun.NewSelect())
+	assert.Equal(t, `SELECT * ORDER BY "name" ASC, "id" ASC`, finalQuery.String())
+	assert.Equal(t, []string{"a", "7"}, cursorValues[MockData](MockDataUnique{}))
+}
+
 type (
 	MockData struct {
 		bun.BaseModel `bun:"table:mock_data"`
@@ -139,3 +184,57 @@ func (c MockDataOrdered) DeserizalizeCursorValues(values []string) ([]any, error
 func (c MockDataOrdered) UnWrap() MockData {
 	return MockData{}
 }
+
+// MockDataTiebreaker has a non-unique ordering made unique by a tiebreaker.
+type MockDataTiebreaker struct{}
+
+func (c MockDataTiebreaker) KeySort() []KeySort {
+	return []KeySort{
+		{Key: "name", Sort: SortOrderAscending},
+		{Key: "name2", Sort: SortOrderDescending},
+	}
+}
+
+func (c MockDataTiebreaker) CursorValues() []string {
+	return []string{"a", "b"}
+}
+
+func (c MockDataTiebreaker) DeserizalizeCursorValues(values []string) ([]any, error) {
+	result := make([]any, 0, len(values))
+	for _, v := range values {
+		result = append(result, v)
+	}
+	return result, nil
+}
+
+func (c MockDataTiebreaker) UnWrap() MockData {
+	return MockData{}
+}
+
+func (c MockDataTiebreaker) TiebreakerKey() string {
+	return "id"
+}
+
+func (c MockDataTiebreaker) TiebreakerValue() string {
+	return "7"
+}
+
+func (c MockDataTiebreaker) DeserializeTiebreakerValue(value string) (any, error) {
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// MockDataUnique has an ordering that is already unique.
+type MockDataUnique struct {
+	MockDataTiebreaker
+}
+
+func (c MockDataUnique) KeySort() []KeySort {
+	return []KeySort{
+		{Key: "name", Sort: SortOrderAscending},
+		{Key: "id", Sort: SortOrderAscending, Unique: true},
+	}
+}
+
+func (c MockDataUnique) CursorValues() []string {
+	return []string{"a", "7"}
+}
@@ -0,0 +1,163 @@
+package pg
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/uptrace/bun"
+
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const defaultFailoverCooldown = 10 * time.Second
+
+var ErrNoNodes = errors.New("no nodes supplied")
+
+type readRouterOptions struct {
+	logger           *slog.Logger
+	clock            clockwork.Clock
+	failoverCooldown time.Duration
+}
+
+// ReadRouterOption is an option func for NewReadRouter.
+type ReadRouterOption func(options *readRouterOptions)
+
+// WithReadRouterLogger sets the logger used to report failovers.
+func WithReadRouterLogger(logger *slog.Logger) ReadRouterOption {
+	return func(options *readRouterOptions) {
+		options.logger = logger
+	}
+}
+
+// WithReadRouterClock allows users to mock the clock used to determine node health for testing purposes.
+func WithReadRouterClock(clock clockwork.Clock) ReadRouterOption {
+	return func(options *readRouterOptions) {
+		options.clock = clock
+	}
+}
+
+// WithFailoverCooldown sets how long a ReadRouter avoids a node after a failover error,
+// unless no other node is available. Defaults to 10 seconds.
+func WithFailoverCooldown(d time.Duration) ReadRouterOption {
+	return func(options *readRouterOptions) {
+		options.failoverCooldown = d
+	}
+}
+
+// ReadRouter spreads read-only queries over a set of nodes (eg the read replicas, optionally
+// followed by the primary), retrying a query on the next healthy node when its node fails over
+// or restarts, rather than returning the error to the user.
+type ReadRouter struct {
+	nodes []bun.IDB
+	// unhealthyUntil holds, per node, the unix nano time until which it is avoided.
+	unhealthyUntil []atomic.Int64
+	next           atomic.Uint64
+	opts           readRouterOptions
+}
+
+// NewReadRouter creates a ReadRouter over the given nodes.
+func NewReadRouter(nodes []bun.IDB, opts ...ReadRouterOption) (*ReadRouter, error) {
+	if len(nodes) == 0 {
+		return nil, stacktrace.Wrap(ErrNoNodes)
+	}
+
+	options := readRouterOptions{
+		logger:           log.NewNilLogger(),
+		clock:            clockwork.NewRealClock(),
+		failoverCooldown: defaultFailoverCooldown,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &ReadRouter{
+		nodes:          nodes,
+		unhealthyUntil: make([]atomic.Int64, len(nodes)),
+		opts:           options,
+	}, nil
+}
+
+// Read calls fn with the next healthy node in turn. If fn fails with a failover error (see IsFailover),
+// the node is avoided for the cooldown and fn is called again with the next node, until every node
+// has been tried once or ctx is done. Failover errors are returned classed as Transient, and other
+// errors are returned as is.
+//
+// NOTE: fn may be called more than once, so it must only run read-only statements.
+func (r *ReadRouter) Read(ctx context.Context, fn func(ctx context.Context, db bun.IDB) error) error {
+	var err error
+	for _, i := range r.order() {
+		err = fn(ctx, r.nodes[i])
+		if err == nil {
+			r.unhealthyUntil[i].Store(0)
+			return nil
+		}
+		if !IsFailover(err) {
+			return err
+		}
+
+		r.unhealthyUntil[i].Store(r.opts.clock.Now().Add(r.opts.failoverCooldown).UnixNano())
+		r.opts.logger.Warn("read failed due to node failover", log.ErrAttr(err), slog.Int("node", i))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return errclass.WrapAs(stacktrace.Wrap(err), errclass.Transient)
+}
+
+// order returns the indices of all nodes, starting from the next in turn,
+// with any currently unhealthy nodes moved to the end.
+func (r *ReadRouter) order() []int {
+	start := int((r.next.Add(1) - 1) % uint64(len(r.nodes))) //nolint:gosec // less than len(r.nodes)
+	now := r.opts.clock.Now().UnixNano()
+	healthy := make([]int, 0, len(r.nodes))
+	var unhealthy []int
+	for n := range len(r.nodes) {
+		i := (start + n) % len(r.nodes)
+		if r.unhealthyUntil[i].Load() > now {
+			unhealthy = append(unhealthy, i)
+			continue
+		}
+		healthy = append(healthy, i)
+	}
+	return append(healthy, unhealthy...)
+}
+
+// sqlStater is implemented by the errors of common postgres drivers (eg pgx and lib/pq).
+type sqlStater interface {
+	SQLState() string
+}
+
+// IsFailover returns true if the error indicates that the connection to the node was lost or refused,
+// as happens when a node restarts or fails over, such that the query may succeed on another node.
+func IsFailover(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var stater sqlStater
+	if errors.As(err, &stater) {
+		state := stater.SQLState()
+		// Class 08 is connection exceptions, and 57P01-3 are admin shutdown, crash shutdown and cannot connect now
+		return strings.HasPrefix(state, "08") || state == "57P01" || state == "57P02" || state == "57P03"
+	}
+
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		(errors.As(err, &netErr) && !netErr.Timeout())
+}
@@ -0,0 +1,152 @@
+package pg_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+
+	"github.com/zircuit-labs/zkr-go-common/stores/pg"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+const countUsers = "SELECT count(*) FROM users"
+
+// sqlStateError mimics the errors of postgres drivers.
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "sqlstate " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func newMockNode(t *testing.T) (*bun.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	sqldb, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	t.Cleanup(func() { sqldb.Close() })
+	return bun.NewDB(sqldb, pgdialect.New()), mock
+}
+
+func countRead(count *int64) func(ctx context.Context, db bun.IDB) error {
+	return func(ctx context.Context, db bun.IDB) error {
+		return db.NewRaw(countUsers).Scan(ctx, count)
+	}
+}
+
+func TestReadRouterFailover(t *testing.T) {
+	t.Parallel()
+
+	replica1, mock1 := newMockNode(t)
+	replica2, mock2 := newMockNode(t)
+	clock := clockwork.NewFakeClock()
+	router, err := pg.NewReadRouter([]bun.IDB{replica1, replica2},
+		pg.WithReadRouterClock(clock),
+		pg.WithFailoverCooldown(time.Minute),
+	)
+	require.NoError(t, err)
+
+	// the first replica restarts, so the read is retried on the second
+	mock1.ExpectQuery(countUsers).WillReturnError(sqlStateError("57P01"))
+	mock2.ExpectQuery(countUsers).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	var count int64
+	require.NoError(t, router.Read(t.Context(), countRead(&count)))
+	assert.Equal(t, int64(3), count)
+
+	// the first replica is avoided until the cooldown has passed
+	mock2.ExpectQuery(countUsers).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+	mock2.ExpectQuery(countUsers).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+	require.NoError(t, router.Read(t.Context(), countRead(&count)))
+	require.NoError(t, router.Read(t.Context(), countRead(&count)))
+	assert.Equal(t, int64(5), count)
+
+	clock.Advance(time.Minute)
+	mock1.ExpectQuery(countUsers).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(6))
+	mock2.ExpectQuery(countUsers).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(6))
+	require.NoError(t, router.Read(t.Context(), countRead(&count)))
+	require.NoError(t, router.Read(t.Context(), countRead(&count)))
+
+	require.NoError(t, mock1.ExpectationsWereMet())
+	require.NoError(t, mock2.ExpectationsWereMet())
+}
+
+func TestReadRouterErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := pg.NewReadRouter(nil)
+	require.ErrorIs(t, err, pg.ErrNoNodes)
+
+	replica1, mock1 := newMockNode(t)
+	replica2, mock2 := newMockNode(t)
+	router, err := pg.NewReadRouter([]bun.IDB{replica1, replica2})
+	require.NoError(t, err)
+
+	// other errors are not retried
+	mock1.ExpectQuery(countUsers).WillReturnError(sql.ErrNoRows)
+	var count int64
+	err = router.Read(t.Context(), countRead(&count))
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// when every node fails over, the last error is returned as transient
+	mock2.ExpectQuery(countUsers).WillReturnError(io.ErrUnexpectedEOF)
+	mock1.ExpectQuery(countUsers).WillReturnError(syscall.ECONNREFUSED)
+	err = router.Read(t.Context(), countRead(&count))
+	require.ErrorIs(t, err, syscall.ECONNREFUSED)
+	assert.Equal(t, errclass.Transient, errclass.GetClass(err))
+
+	require.NoError(t, mock1.ExpectationsWereMet())
+	require.NoError(t, mock2.ExpectationsWereMet())
+}
+
+func TestReadRouterContextDone(t *testing.T) {
+	t.Parallel()
+
+	replica1, _ := newMockNode(t)
+	replica2, _ := newMockNode(t)
+	router, err := pg.NewReadRouter([]bun.IDB{replica1, replica2})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	calls := 0
+	err = router.Read(ctx, func(context.Context, bun.IDB) error {
+		calls++
+		cancel()
+		return syscall.ECONNRESET
+	})
+	require.ErrorIs(t, err, syscall.ECONNRESET)
+	assert.Equal(t, 1, calls)
+}
+
+func TestIsFailover(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{err: nil, expected: false},
+		{err: sql.ErrNoRows, expected: false},
+		{err: context.DeadlineExceeded, expected: false},
+		{err: sqlStateError("23505"), expected: false},
+		{err: sqlStateError("08006"), expected: true},
+		{err: sqlStateError("57P03"), expected: true},
+		{err: fmt.Errorf("query: %w", io.EOF), expected: true},
+		{err: syscall.ECONNRESET, expected: true},
+		{err: errors.Join(errors.New("read"), syscall.EPIPE), expected: true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.err), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, pg.IsFailover(tt.err))
+		})
+	}
+}
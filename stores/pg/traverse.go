@@ -0,0 +1,88 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"iter"
+
+	"github.com/uptrace/bun"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const defaultTraversalPageSize = 1000
+
+type paginateAllOptions struct {
+	reverse bool
+}
+
+// PaginateAllOption is an option func for PaginateAll.
+type PaginateAllOption func(options *paginateAllOptions)
+
+// WithReverse makes PaginateAll traverse the results from last to first.
+func WithReverse() PaginateAllOption {
+	return func(options *paginateAllOptions) {
+		options.reverse = true
+	}
+}
+
+// PaginateAll iterates over every result of the query, in the order of Paginate (or its reverse using WithReverse),
+// fetching pageSize rows at a time (1000 if not positive) so that entire tables can be streamed with bounded memory.
+// Each page is fetched with a clone of the query, after the last row of the previous page. The iteration stops at the
+// first error, which is yielded with a nil value.
+// NOTE: Rows inserted or updated behind the current position during the traversal are not seen.
+func PaginateAll[V any, T Pageable[V]](ctx context.Context, filterQuery *bun.SelectQuery, pageSize int, paginationOpts ...PaginateAllOption) iter.Seq2[*V, error] {
+	var options paginateAllOptions
+	for _, opt := range paginationOpts {
+		opt(&options)
+	}
+	if pageSize <= 0 {
+		pageSize = defaultTraversalPageSize
+	}
+
+	return func(yield func(*V, error) bool) {
+		if err := validateOrdering[V, T](); err != nil {
+			yield(nil, err)
+			return
+		}
+
+		var after []string
+		for {
+			query := filterQuery.Clone()
+			if after != nil {
+				var err error
+				query, err = cursorWhere[V, T](query, after, options.reverse)
+				if err != nil {
+					yield(nil, stacktrace.Wrap(err))
+					return
+				}
+			}
+			if options.reverse {
+				query = paginationReverseSort[V, T](query)
+			} else {
+				query = paginationSort[V, T](query)
+			}
+
+			var data []T
+			err := query.Limit(pageSize).Scan(ctx, &data)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				yield(nil, stacktrace.Wrap(err))
+				return
+			}
+
+			for _, t := range data {
+				value := t.UnWrap()
+				if !yield(&value, nil) {
+					return
+				}
+			}
+
+			// a short page is the last
+			if len(data) < pageSize {
+				return
+			}
+			after = cursorValues[V](data[len(data)-1])
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package pg_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+
+	"github.com/zircuit-labs/zkr-go-common/stores/pg"
+)
+
+func TestPaginateAll(t *testing.T) {
+	t.Parallel()
+
+	const selectUsers = `SELECT "user"."id", "user"."name" FROM "users" AS "user" `
+	testCases := []struct {
+		name    string
+		opts    []pg.PaginateAllOption
+		queries []string
+		pages   [][]user
+	}{
+		{
+			name: "forward",
+			queries: []string{
+				selectUsers + `ORDER BY "name" ASC, "id" ASC LIMIT 2`,
+				selectUsers + `WHERE ((name > 'abby') OR (name = 'abby' AND id > 2)) ORDER BY "name" ASC, "id" ASC LIMIT 2`,
+				selectUsers + `WHERE ((name > 'carl') OR (name = 'carl' AND id > 3)) ORDER BY "name" ASC, "id" ASC LIMIT 2`,
+			},
+			pages: [][]user{
+				{{ID: 1, Name: "aaron"}, {ID: 2, Name: "abby"}},
+				{{ID: 4, Name: "bob"}, {ID: 3, Name: "carl"}},
+				{},
+			},
+		},
+		{
+			name: "reverse",
+			opts: []pg.PaginateAllOption{pg.WithReverse()},
+			queries: []string{
+				selectUsers + `ORDER BY "name" DESC, "id" DESC LIMIT 2`,
+				selectUsers + `WHERE ((name < 'bob') OR (name = 'bob' AND id < 4)) ORDER BY "name" DESC, "id" DESC LIMIT 2`,
+			},
+			pages: [][]user{
+				{{ID: 3, Name: "carl"}, {ID: 4, Name: "bob"}},
+				{{ID: 2, Name: "abby"}},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			sqldb, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			require.NoError(t, err)
+			t.Cleanup(func() { sqldb.Close() })
+			db := bun.NewDB(sqldb, pgdialect.New())
+
+			var expected []string
+			for i, query := range tc.queries {
+				rows := sqlmock.NewRows([]string{"id", "name"})
+				for _, u := range tc.pages[i] {
+					rows.AddRow(u.ID, u.Name)
+					expected = append(expected, u.Name)
+				}
+				mock.ExpectQuery(query).WillReturnRows(rows)
+			}
+
+			var names []string
+			for u, err := range pg.PaginateAll[user, userPage](t.Context(), db.NewSelect().Model((*user)(nil)), 2, tc.opts...) {
+				require.NoError(t, err)
+				names = append(names, u.Name)
+			}
+			assert.Equal(t, expected, names)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPaginateAllStop(t *testing.T) {
+	t.Parallel()
+	sqldb, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqldb.Close() })
+	db := bun.NewDB(sqldb, pgdialect.New())
+
+	// breaking out of the loop fetches no further pages
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "aaron").AddRow(2, "abby"))
+	for u, err := range pg.PaginateAll[user, userPage](t.Context(), db.NewSelect().Model((*user)(nil)), 2) {
+		require.NoError(t, err)
+		assert.Equal(t, "aaron", u.Name)
+		break
+	}
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// errors end the iteration
+	errQuery := errors.New("connection reset")
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "aaron").AddRow(2, "abby"))
+	mock.ExpectQuery("SELECT").WillReturnError(errQuery)
+	var errs []error
+	count := 0
+	for u, err := range pg.PaginateAll[user, userPage](t.Context(), db.NewSelect().Model((*user)(nil)), 2) {
+		if err != nil {
+			assert.Nil(t, u)
+			errs = append(errs, err)
+			continue
+		}
+		count++
+	}
+	assert.Equal(t, 2, count)
+	require.Len(t, errs, 1)
+	require.ErrorIs(t, errs[0], errQuery)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
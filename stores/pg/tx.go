@@ -0,0 +1,193 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/uptrace/bun"
+
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/retry/strategy"
+	"github.com/zircuit-labs/zkr-go-common/stores/txhook"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const (
+	defaultTxMaxAttempts = 5
+	txRetryInitialDelay  = 10 * time.Millisecond
+	txRetryMaxDelay      = time.Second
+)
+
+type txManagerOptions struct {
+	logger        *slog.Logger
+	clock         clockwork.Clock
+	txMaxAttempts int
+	txOptions     *sql.TxOptions
+}
+
+// TxManagerOption is an option func for NewTxManager.
+type TxManagerOption func(options *txManagerOptions)
+
+// WithTxLogger sets the logger used to report retries and failed after commit callbacks.
+func WithTxLogger(logger *slog.Logger) TxManagerOption {
+	return func(options *txManagerOptions) {
+		options.logger = logger
+	}
+}
+
+// WithTxClock allows users to mock the clock used to wait between attempts for testing purposes.
+func WithTxClock(clock clockwork.Clock) TxManagerOption {
+	return func(options *txManagerOptions) {
+		options.clock = clock
+	}
+}
+
+// WithTxMaxAttempts sets how many times a TxManager runs a transaction which fails due to
+// a serialization failure or deadlock. Defaults to 5.
+func WithTxMaxAttempts(n int) TxManagerOption {
+	return func(options *txManagerOptions) {
+		options.txMaxAttempts = n
+	}
+}
+
+// WithTxOptions sets the options (eg the isolation level) of transactions begun by a TxManager.
+func WithTxOptions(txOptions *sql.TxOptions) TxManagerOption {
+	return func(options *txManagerOptions) {
+		options.txOptions = txOptions
+	}
+}
+
+type txKey struct{}
+
+// TxFromContext returns the ambient transaction carried by ctx, if any.
+func TxFromContext(ctx context.Context) (bun.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(bun.Tx)
+	return tx, ok
+}
+
+// DBFromContext returns the ambient transaction carried by ctx if any, or otherwise db.
+// Repositories should use this for every query, such that they participate in any transaction
+// begun by TxManager.RunInTx.
+func DBFromContext(ctx context.Context, db bun.IDB) bun.IDB {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return db
+}
+
+// AfterCommit registers f to be called once the transaction begun by TxManager.RunInTx which ctx
+// carries has committed, eg to publish a message announcing the change. Callbacks registered during
+// an attempt which is rolled back or retried, or within a nested transaction which is rolled back,
+// are discarded. It returns false, without registering f, if ctx carries no such transaction.
+// See txhook.AfterRollback for the reverse.
+func AfterCommit(ctx context.Context, f func(ctx context.Context) error) bool {
+	return txhook.AfterCommit(ctx, f)
+}
+
+// TxManager runs units of work in transactions, passing the transaction to repositories
+// through the context.
+type TxManager struct {
+	db    *bun.DB
+	opts  txManagerOptions
+	delay strategy.Factory
+}
+
+// NewTxManager creates a TxManager for the database.
+func NewTxManager(db *bun.DB, opts ...TxManagerOption) (*TxManager, error) {
+	options := txManagerOptions{
+		logger:        log.NewNilLogger(),
+		clock:         clockwork.NewRealClock(),
+		txMaxAttempts: defaultTxMaxAttempts,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	delay, err := strategy.NewExponential(txRetryInitialDelay, txRetryMaxDelay)
+	if err != nil {
+		return nil, stacktrace.Wrap(err)
+	}
+
+	return &TxManager{db: db, opts: options, delay: delay}, nil
+}
+
+// DB returns the ambient transaction carried by ctx if any, or otherwise the database.
+func (m *TxManager) DB(ctx context.Context) bun.IDB {
+	return DBFromContext(ctx, m.db)
+}
+
+// RunInTx calls fn with a context carrying a transaction, which is committed if fn returns nil
+// and rolled back otherwise.
+//
+// If ctx already carries a transaction, a nested transaction is created using a savepoint instead,
+// such that only the work of fn is rolled back if it fails. Otherwise, if the transaction fails
+// due to a serialization failure or deadlock (see IsSerializationFailure), it is retried from the
+// start, so fn must not have side effects outside of the transaction (see AfterCommit). Such failures
+// are returned classed as Transient once the attempts are exhausted.
+//
+// Once the transaction has committed, the callbacks registered with AfterCommit are called in order
+// with ctx. Their errors are logged rather than returned, as the work of fn has already been committed.
+// Callbacks registered with txhook.AfterRollback are instead called whenever an attempt or nested
+// transaction is rolled back.
+func (m *TxManager) RunInTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if tx, ok := TxFromContext(ctx); ok {
+		hooks := &txhook.Hooks{}
+		err := tx.RunInTx(ctx, nil, func(ctx context.Context, sp bun.Tx) error {
+			return fn(txhook.NewContext(context.WithValue(ctx, txKey{}, sp), hooks))
+		})
+		if err != nil {
+			hooks.Rollback()
+			return err
+		}
+		if parent, ok := txhook.FromContext(ctx); ok {
+			hooks.Release(parent)
+		}
+		return nil
+	}
+
+	backoff := m.delay()
+	for attempt := 1; ; attempt++ {
+		hooks := &txhook.Hooks{}
+		err := m.db.RunInTx(ctx, m.opts.txOptions, func(ctx context.Context, tx bun.Tx) error {
+			return fn(txhook.NewContext(context.WithValue(ctx, txKey{}, tx), hooks))
+		})
+		if err == nil {
+			if err := hooks.Commit(ctx); err != nil {
+				m.opts.logger.Error("after commit callback failed", log.ErrAttr(err))
+			}
+			return nil
+		}
+		hooks.Rollback()
+		if !IsSerializationFailure(err) {
+			return err
+		}
+
+		err = errclass.WrapAs(stacktrace.Wrap(err), errclass.Transient)
+		if attempt >= m.opts.txMaxAttempts {
+			return err
+		}
+		m.opts.logger.Debug("retrying transaction after serialization failure", log.ErrAttr(err), slog.Int("attempt", attempt))
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-m.opts.clock.After(backoff.NextDelay()):
+		}
+	}
+}
+
+// IsSerializationFailure returns true if the error indicates that the transaction was aborted due to
+// a serialization failure (SQLSTATE 40001) or deadlock (SQLSTATE 40P01), such that it may succeed if retried.
+func IsSerializationFailure(err error) bool {
+	var stater sqlStater
+	if !errors.As(err, &stater) {
+		return false
+	}
+	state := stater.SQLState()
+	return state == "40001" || state == "40P01"
+}
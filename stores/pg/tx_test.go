@@ -0,0 +1,253 @@
+package pg_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+
+	"github.com/zircuit-labs/zkr-go-common/stores/pg"
+	"github.com/zircuit-labs/zkr-go-common/stores/txhook"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+// userRepo participates in any ambient transaction.
+type userRepo struct {
+	db bun.IDB
+}
+
+func (r userRepo) create(ctx context.Context, name string) error {
+	_, err := pg.DBFromContext(ctx, r.db).NewRaw("INSERT INTO users (name) VALUES (?)", name).Exec(ctx)
+	return err
+}
+
+func newTxManager(t *testing.T, opts ...pg.TxManagerOption) (*pg.TxManager, userRepo, sqlmock.Sqlmock) {
+	t.Helper()
+	sqldb, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqldb.Close() })
+	db := bun.NewDB(sqldb, pgdialect.New())
+	tm, err := pg.NewTxManager(db, opts...)
+	require.NoError(t, err)
+	return tm, userRepo{db: db}, mock
+}
+
+func TestTxManager(t *testing.T) {
+	t.Parallel()
+
+	tm, repo, mock := newTxManager(t)
+	ctx := t.Context()
+
+	// outside of a transaction, the database is used directly
+	_, ok := pg.TxFromContext(ctx)
+	assert.False(t, ok)
+	mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(1, 1))
+	require.NoError(t, repo.create(ctx, "alice"))
+
+	// work is committed on success
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectCommit()
+	err := tm.RunInTx(ctx, func(ctx context.Context) error {
+		_, ok := pg.TxFromContext(ctx)
+		assert.True(t, ok)
+		assert.IsType(t, bun.Tx{}, tm.DB(ctx))
+		if err := repo.create(ctx, "bob"); err != nil {
+			return err
+		}
+		return repo.create(ctx, "carol")
+	})
+	require.NoError(t, err)
+
+	// and rolled back on failure
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(4, 1))
+	mock.ExpectRollback()
+	err = tm.RunInTx(ctx, func(ctx context.Context) error {
+		if err := repo.create(ctx, "dave"); err != nil {
+			return err
+		}
+		return errQuery
+	})
+	require.ErrorIs(t, err, errQuery)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTxManagerRetry(t *testing.T) {
+	t.Parallel()
+
+	tm, repo, mock := newTxManager(t, pg.WithTxMaxAttempts(2))
+	ctx := t.Context()
+	create := func(ctx context.Context) error {
+		return repo.create(ctx, "alice")
+	}
+
+	// a serialization failure is retried from the start
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO users").WillReturnError(sqlStateError("40001"))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	require.NoError(t, tm.RunInTx(ctx, create))
+
+	// including at commit, and until the attempts are exhausted
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit().WillReturnError(sqlStateError("40001"))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO users").WillReturnError(sqlStateError("40P01"))
+	mock.ExpectRollback()
+	err := tm.RunInTx(ctx, create)
+	require.Error(t, err)
+	assert.True(t, pg.IsSerializationFailure(err))
+	assert.Equal(t, errclass.Transient, errclass.GetClass(err))
+
+	// other errors are not retried
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO users").WillReturnError(sqlStateError("23505"))
+	mock.ExpectRollback()
+	err = tm.RunInTx(ctx, create)
+	require.Error(t, err)
+	assert.False(t, pg.IsSerializationFailure(err))
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTxManagerNested(t *testing.T) {
+	t.Parallel()
+
+	tm, repo, mock := newTxManager(t)
+	ctx := t.Context()
+
+	// nested transactions use savepoints, so that only their own work is rolled back
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("SAVEPOINT SP_").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("RELEASE SAVEPOINT SP_").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT SP_").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT SP_").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	errNested := errors.New("nested failure")
+	err := tm.RunInTx(ctx, func(ctx context.Context) error {
+		if err := repo.create(ctx, "alice"); err != nil {
+			return err
+		}
+		outer, _ := pg.TxFromContext(ctx)
+		err := tm.RunInTx(ctx, func(ctx context.Context) error {
+			inner, _ := pg.TxFromContext(ctx)
+			assert.NotEqual(t, outer, inner)
+			return repo.create(ctx, "bob")
+		})
+		if err != nil {
+			return err
+		}
+		err = tm.RunInTx(ctx, func(context.Context) error {
+			return errNested
+		})
+		assert.ErrorIs(t, err, errNested)
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTxManagerAfterCommit(t *testing.T) {
+	t.Parallel()
+
+	tm, repo, mock := newTxManager(t, pg.WithTxMaxAttempts(2))
+	ctx := t.Context()
+
+	var called []string
+	after := func(name string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			_, ok := pg.TxFromContext(ctx)
+			assert.False(t, ok)
+			called = append(called, name)
+			return errQuery // logged, and does not fail the committed transaction
+		}
+	}
+
+	// outside of a transaction, nothing is registered
+	assert.False(t, pg.AfterCommit(ctx, after("none")))
+
+	// callbacks of attempts which are retried are discarded
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO users").WillReturnError(sqlStateError("40001"))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("SAVEPOINT SP_").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("RELEASE SAVEPOINT SP_").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT SP_").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT SP_").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	attempt := 0
+	var rolledBack []string
+	rollback := func(name string) func() {
+		return func() {
+			rolledBack = append(rolledBack, name)
+		}
+	}
+	err := tm.RunInTx(ctx, func(ctx context.Context) error {
+		attempt++
+		assert.True(t, pg.AfterCommit(ctx, after(fmt.Sprintf("attempt %d", attempt))))
+		assert.True(t, txhook.AfterRollback(ctx, rollback(fmt.Sprintf("attempt %d", attempt))))
+		if err := repo.create(ctx, "alice"); err != nil {
+			return err
+		}
+		// as are those of nested transactions which are rolled back
+		require.NoError(t, tm.RunInTx(ctx, func(ctx context.Context) error {
+			assert.True(t, pg.AfterCommit(ctx, after("released")))
+			return nil
+		}))
+		_ = tm.RunInTx(ctx, func(ctx context.Context) error {
+			assert.True(t, pg.AfterCommit(ctx, after("rolled back")))
+			assert.True(t, txhook.AfterRollback(ctx, rollback("rolled back")))
+			return errQuery
+		})
+		assert.Empty(t, called)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"attempt 2", "released"}, called)
+	// while their rollback callbacks are called instead
+	assert.Equal(t, []string{"attempt 1", "rolled back"}, rolledBack)
+
+	// and callbacks are not called if the transaction is rolled back
+	called = nil
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	err = tm.RunInTx(ctx, func(ctx context.Context) error {
+		pg.AfterCommit(ctx, after("failed"))
+		return errQuery
+	})
+	require.ErrorIs(t, err, errQuery)
+	assert.Empty(t, called)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTxManagerSharedDB(t *testing.T) {
+	t.Parallel()
+
+	sqldb, _, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqldb.Close() })
+	db := bun.NewDB(sqldb, pgdialect.New())
+
+	tm, err := pg.NewTxManager(db)
+	require.NoError(t, err)
+	assert.Equal(t, bun.IDB(db), tm.DB(t.Context()))
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
@@ -29,11 +30,14 @@ type S3Client interface {
 	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
 	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
 	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
 }
 
 type BlobStore struct {
-	bucket string
-	s3     S3Client
+	bucket      string
+	s3          S3Client
+	metrics     *blobStoreMetrics
+	compression Compression
 }
 
 type BlobStoreConfig struct {
@@ -49,7 +53,7 @@ type BlobStoreConfig struct {
 	DisableSSL bool `koanf:"disablessl"`
 }
 
-func NewBlobStoreFromConfig(ctx context.Context, config BlobStoreConfig) (*BlobStore, error) {
+func NewBlobStoreFromConfig(ctx context.Context, config BlobStoreConfig, opts ...Option) (*BlobStore, error) {
 	if config.Region == "" {
 		return nil, stacktrace.Wrap(ErrNoRegion)
 	}
@@ -92,19 +96,41 @@ func NewBlobStoreFromConfig(ctx context.Context, config BlobStoreConfig) (*BlobS
 	}
 
 	s3Client := s3.NewFromConfig(awsConfig, clientOptions...)
-	return &BlobStore{
-		bucket: config.Bucket,
-		s3:     s3Client,
-	}, nil
+	return NewBlobStoreWithClient(s3Client, config.Bucket, opts...)
 }
 
-func NewBlobStore(ctx context.Context, cfg *config.Configuration, cfgPath string) (*BlobStore, error) {
+// NewBlobStoreWithClient creates a BlobStore using the given client, eg a FakeS3Client in tests.
+func NewBlobStoreWithClient(client S3Client, bucket string, opts ...Option) (*BlobStore, error) {
+	options := parseOptions(opts)
+
+	if bucket == "" {
+		return nil, stacktrace.Wrap(ErrNoBucket)
+	}
+
+	b := &BlobStore{
+		bucket:      bucket,
+		s3:          client,
+		compression: options.compression,
+	}
+
+	if options.registerer != nil {
+		metrics, err := registerBlobStoreMetrics(options.registerer)
+		if err != nil {
+			return nil, err
+		}
+		b.metrics = metrics
+	}
+
+	return b, nil
+}
+
+func NewBlobStore(ctx context.Context, cfg *config.Configuration, cfgPath string, opts ...Option) (*BlobStore, error) {
 	config := BlobStoreConfig{}
 	if err := cfg.Unmarshal(cfgPath, &config); err != nil {
 		return nil, stacktrace.Wrap(err)
 	}
 
-	return NewBlobStoreFromConfig(ctx, config)
+	return NewBlobStoreFromConfig(ctx, config, opts...)
 }
 
 func (b *BlobStore) SetBucket(bucket string) {
@@ -116,19 +142,37 @@ func (b *BlobStore) GetBucket() string {
 }
 
 func (b *BlobStore) Upload(ctx context.Context, key string, data []byte) error {
-	_, err := b.s3.PutObject(ctx, &s3.PutObjectInput{
-		Bucket: aws.String(b.bucket),
-		Key:    aws.String(key),
-		Body:   bytes.NewReader(data),
-	})
+	return b.upload(ctx, key, data, nil)
+}
+
+// upload compresses and uploads the data, with the given user-defined metadata.
+func (b *BlobStore) upload(ctx context.Context, key string, data []byte, metadata map[string]string) (err error) {
+	defer b.metrics.observe(b.bucket, opUpload, time.Now(), &err)
+
+	body, err := compress(data, b.compression)
+	if err != nil {
+		return err
+	}
+	input := &s3.PutObjectInput{
+		Bucket:   aws.String(b.bucket),
+		Key:      aws.String(key),
+		Body:     bytes.NewReader(body),
+		Metadata: metadata,
+	}
+	if b.compression != CompressionNone {
+		input.ContentEncoding = aws.String(string(b.compression))
+	}
+	_, err = b.s3.PutObject(ctx, input)
 	if err != nil {
 		return stacktrace.Wrap(err)
 	}
+	b.metrics.addBytes(b.bucket, opUpload, len(body))
 
 	return nil
 }
 
 func (b *BlobStore) Get(ctx context.Context, key string) (res []byte, err error) {
+	defer b.metrics.observe(b.bucket, opGet, time.Now(), &err)
 	defer func() {
 		err = errcontext.Add(err, slog.String("key", key))
 	}()
@@ -151,11 +195,13 @@ func (b *BlobStore) Get(ctx context.Context, key string) (res []byte, err error)
 	if err != nil {
 		return nil, stacktrace.Wrap(err)
 	}
+	b.metrics.addBytes(b.bucket, opGet, buf.Len())
 
-	return buf.Bytes(), nil
+	return decompress(buf.Bytes(), aws.ToString(data.ContentEncoding))
 }
 
 func (b *BlobStore) Exists(ctx context.Context, key string) (err error) {
+	defer b.metrics.observe(b.bucket, opExists, time.Now(), &err)
 	defer func() {
 		err = errcontext.Add(err, slog.String("key", key))
 	}()
@@ -179,7 +225,32 @@ func (b *BlobStore) Exists(ctx context.Context, key string) (err error) {
 }
 
 func (b *BlobStore) GetAllList(ctx context.Context) ([]string, error) {
-	var keys []string
+	return b.GetList(ctx, "")
+}
+
+// List returns all keys beginning with the prefix, as GetList.
+func (b *BlobStore) List(ctx context.Context, prefix string) ([]string, error) {
+	return b.GetList(ctx, prefix)
+}
+
+// GetList returns all keys beginning with the prefix.
+func (b *BlobStore) GetList(ctx context.Context, prefix string) ([]string, error) {
+	objects, err := b.listObjects(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		keys = append(keys, aws.ToString(obj.Key))
+	}
+	return keys, nil
+}
+
+// listObjects returns all objects with keys beginning with the prefix.
+func (b *BlobStore) listObjects(ctx context.Context, prefix string) (_ []types.Object, err error) {
+	defer b.metrics.observe(b.bucket, opList, time.Now(), &err)
+
+	var objects []types.Object
 	var continuationToken *string
 
 	for {
@@ -190,17 +261,21 @@ func (b *BlobStore) GetAllList(ctx context.Context) ([]string, error) {
 		default:
 		}
 
-		output, err := b.s3.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		input := &s3.ListObjectsV2Input{
 			Bucket:            aws.String(b.bucket),
 			ContinuationToken: continuationToken,
-		})
+		}
+		if prefix != "" {
+			input.Prefix = aws.String(prefix)
+		}
+		output, err := b.s3.ListObjectsV2(ctx, input)
 		if err != nil {
 			return nil, stacktrace.Wrap(err)
 		}
 
 		for _, obj := range output.Contents {
 			if obj.Key != nil {
-				keys = append(keys, *obj.Key)
+				objects = append(objects, obj)
 			}
 		}
 
@@ -210,10 +285,11 @@ func (b *BlobStore) GetAllList(ctx context.Context) ([]string, error) {
 		continuationToken = output.NextContinuationToken
 	}
 
-	return keys, nil
+	return objects, nil
 }
 
 func (b *BlobStore) Delete(ctx context.Context, key string) (err error) {
+	defer b.metrics.observe(b.bucket, opDelete, time.Now(), &err)
 	defer func() {
 		err = errcontext.Add(err, slog.String("key", key))
 	}()
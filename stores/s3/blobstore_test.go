@@ -240,3 +240,23 @@ func TestDelete(t *testing.T) {
 	err = bs.Delete(ctx, key2)
 	assert.Error(t, err)
 }
+
+func TestGetList(t *testing.T) {
+	t.Parallel()
+	bs, config, mockS3 := testSetup(t)
+	ctx := t.Context()
+
+	mockS3.EXPECT().ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(config.Bucket),
+		Prefix: aws.String("dir/"),
+	}).Return(&s3.ListObjectsV2Output{
+		Contents: []types.Object{
+			{Key: aws.String("dir/file1.txt")},
+		},
+		IsTruncated: aws.Bool(false),
+	}, nil)
+
+	keyList, err := bs.GetList(ctx, "dir/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dir/file1.txt"}, keyList)
+}
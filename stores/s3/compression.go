@@ -0,0 +1,101 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// Compression is a Content-Encoding used to compress objects.
+type Compression string
+
+const (
+	CompressionNone Compression = ""
+	CompressionGzip Compression = "gzip"
+	CompressionZstd Compression = "zstd"
+)
+
+var ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+
+var (
+	// zstd encoders and decoders are safe for concurrent use with EncodeAll and DecodeAll
+	zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+		return zstd.NewWriter(nil)
+	})
+	zstdDecoder = sync.OnceValues(func() (*zstd.Decoder, error) {
+		return zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+	})
+)
+
+// WithCompression makes BlobStore.Upload compress objects, setting their Content-Encoding accordingly.
+// Get always decompresses objects according to their Content-Encoding, whether or not this option is used,
+// so readers must be upgraded before writers enable compression.
+func WithCompression(compression Compression) Option {
+	return func(options *options) {
+		options.compression = compression
+	}
+}
+
+// compress returns the data compressed as given, or ErrUnsupportedEncoding.
+func compress(data []byte, compression Compression) ([]byte, error) {
+	switch compression {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		buf := bytes.NewBuffer(make([]byte, 0, len(data)/2))
+		w := gzip.NewWriter(buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, stacktrace.Wrap(err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, stacktrace.Wrap(err)
+		}
+		return buf.Bytes(), nil
+	case CompressionZstd:
+		encoder, err := zstdEncoder()
+		if err != nil {
+			return nil, stacktrace.Wrap(err)
+		}
+		return encoder.EncodeAll(data, make([]byte, 0, len(data)/2)), nil
+	default:
+		return nil, errclass.WrapAs(stacktrace.Wrap(ErrUnsupportedEncoding), errclass.Persistent)
+	}
+}
+
+// decompress returns the original data according to the Content-Encoding of the object.
+func decompress(data []byte, contentEncoding string) ([]byte, error) {
+	switch Compression(contentEncoding) {
+	case CompressionNone, "identity":
+		return data, nil
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+		}
+		defer r.Close()
+		decoded, err := io.ReadAll(r)
+		if err != nil {
+			return nil, errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+		}
+		return decoded, nil
+	case CompressionZstd:
+		decoder, err := zstdDecoder()
+		if err != nil {
+			return nil, stacktrace.Wrap(err)
+		}
+		decoded, err := decoder.DecodeAll(data, nil)
+		if err != nil {
+			return nil, errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+		}
+		return decoded, nil
+	default:
+		return nil, errclass.WrapAs(stacktrace.Wrap(ErrUnsupportedEncoding), errclass.Persistent)
+	}
+}
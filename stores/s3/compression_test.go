@@ -0,0 +1,92 @@
+package s3
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+func TestCompression(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(strings.Repeat(`{"block":12345,"hash":"0xabcdef"},`, 1000))
+	for _, compression := range []Compression{CompressionGzip, CompressionZstd} {
+		t.Run(string(compression), func(t *testing.T) {
+			t.Parallel()
+			ctx := t.Context()
+			fake := NewFakeS3Client()
+			bs, err := NewBlobStoreWithClient(fake, "blobs", WithCompression(compression))
+			require.NoError(t, err)
+
+			require.NoError(t, bs.Upload(ctx, "key", data))
+			got, err := bs.Get(ctx, "key")
+			require.NoError(t, err)
+			assert.Equal(t, data, got)
+
+			// the object is stored compressed, with its encoding
+			raw, err := fake.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String("blobs"), Key: aws.String("key")})
+			require.NoError(t, err)
+			assert.Equal(t, string(compression), aws.ToString(raw.ContentEncoding))
+			assert.Less(t, aws.ToInt64(raw.ContentLength), int64(len(data)/10))
+
+			// objects are decompressed regardless of the store's own compression
+			plain, err := NewBlobStoreWithClient(fake, "blobs")
+			require.NoError(t, err)
+			got, err = plain.Get(ctx, "key")
+			require.NoError(t, err)
+			assert.Equal(t, data, got)
+			public, err := NewPublicBlobStoreWithClient(fake, "blobs")
+			require.NoError(t, err)
+			got, err = public.Get(ctx, "key")
+			require.NoError(t, err)
+			assert.Equal(t, data, got)
+
+			// and uncompressed objects are still read as is
+			require.NoError(t, plain.Upload(ctx, "plain", data))
+			got, err = bs.Get(ctx, "plain")
+			require.NoError(t, err)
+			assert.Equal(t, data, got)
+		})
+	}
+}
+
+func TestCompressionErrors(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+	fake := NewFakeS3Client()
+
+	bs, err := NewBlobStoreWithClient(fake, "blobs", WithCompression("br"))
+	require.NoError(t, err)
+	err = bs.Upload(ctx, "key", []byte("data"))
+	require.ErrorIs(t, err, ErrUnsupportedEncoding)
+	assert.Empty(t, fake.Keys("blobs"))
+
+	put := func(key, encoding string, body []byte) {
+		t.Helper()
+		_, err := fake.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:          aws.String("blobs"),
+			Key:             aws.String(key),
+			Body:            io.NopCloser(bytes.NewReader(body)),
+			ContentEncoding: aws.String(encoding),
+		})
+		require.NoError(t, err)
+	}
+	put("brotli", "br", []byte("data"))
+	put("corrupt", string(CompressionGzip), []byte("not gzip"))
+
+	bs, err = NewBlobStoreWithClient(fake, "blobs")
+	require.NoError(t, err)
+	_, err = bs.Get(ctx, "brotli")
+	require.ErrorIs(t, err, ErrUnsupportedEncoding)
+	_, err = bs.Get(ctx, "corrupt")
+	require.Error(t, err)
+	assert.Equal(t, errclass.Persistent, errclass.GetClass(err))
+}
@@ -0,0 +1,91 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+type copyOptions struct {
+	bucket string
+}
+
+// CopyOption is an option func for Copy and Move.
+type CopyOption func(options *copyOptions)
+
+// WithDestinationBucket copies (or moves) the object to another bucket, rather than within the store's own bucket.
+func WithDestinationBucket(bucket string) CopyOption {
+	return func(options *copyOptions) {
+		options.bucket = bucket
+	}
+}
+
+// Copy copies the object at srcKey to dstKey server-side, without downloading it. The object keeps its
+// Content-Encoding and metadata. Returns an error wrapping ErrNotFound if there is no object at srcKey.
+// NOTE: S3 copies objects of up to 5GB in a single request.
+func (b *BlobStore) Copy(ctx context.Context, srcKey, dstKey string, opts ...CopyOption) (err error) {
+	defer b.metrics.observe(b.bucket, opCopy, time.Now(), &err)
+
+	options := copyOptions{bucket: b.bucket}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	defer func() {
+		err = errcontext.Add(err,
+			slog.String("src_key", srcKey),
+			slog.String("dst_key", dstKey),
+			slog.String("dst_bucket", options.bucket),
+		)
+	}()
+
+	_, err = b.s3.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(options.bucket),
+		Key:        aws.String(dstKey),
+		CopySource: aws.String(url.PathEscape(b.bucket + "/" + srcKey)),
+	})
+	if err != nil {
+		if isNoSuchKey(err) {
+			return stacktrace.Wrap(ErrNotFound)
+		}
+		return stacktrace.Wrap(err)
+	}
+	return nil
+}
+
+// Move copies the object at srcKey to dstKey server-side, then deletes it from srcKey. If the delete fails,
+// the object exists in both locations, so a failed Move can safely be retried.
+func (b *BlobStore) Move(ctx context.Context, srcKey, dstKey string, opts ...CopyOption) error {
+	options := copyOptions{bucket: b.bucket}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.bucket == b.bucket && srcKey == dstKey {
+		return nil
+	}
+
+	if err := b.Copy(ctx, srcKey, dstKey, opts...); err != nil {
+		return err
+	}
+	return b.Delete(ctx, srcKey)
+}
+
+// isNoSuchKey reports whether the error is due to a missing key. CopyObject does not return
+// *types.NoSuchKey, but an API error with the same code.
+func isNoSuchKey(err error) bool {
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return true
+	}
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchKey"
+}
@@ -0,0 +1,49 @@
+package s3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyAndMove(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+	fake := NewFakeS3Client()
+
+	bs, err := NewBlobStoreWithClient(fake, "artifacts", WithCompression(CompressionGzip))
+	require.NoError(t, err)
+	require.NoError(t, bs.Upload(ctx, "staging/proof 1+1.bin", []byte("proof")))
+
+	// keys are escaped in the copy source
+	require.NoError(t, bs.Copy(ctx, "staging/proof 1+1.bin", "copies/proof.bin"))
+	data, err := bs.Get(ctx, "copies/proof.bin")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("proof"), data)
+
+	require.NoError(t, bs.Move(ctx, "staging/proof 1+1.bin", "final/proof.bin"))
+	assert.Equal(t, []string{"copies/proof.bin", "final/proof.bin"}, fake.Keys("artifacts"))
+	data, err = bs.Get(ctx, "final/proof.bin")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("proof"), data)
+
+	// moving an object onto itself does nothing
+	require.NoError(t, bs.Move(ctx, "final/proof.bin", "final/proof.bin"))
+	require.NoError(t, bs.Exists(ctx, "final/proof.bin"))
+
+	// across buckets
+	require.NoError(t, bs.Move(ctx, "final/proof.bin", "proof.bin", WithDestinationBucket("archive")))
+	assert.Equal(t, []string{"copies/proof.bin"}, fake.Keys("artifacts"))
+	assert.Equal(t, []string{"proof.bin"}, fake.Keys("archive"))
+	archive, err := NewBlobStoreWithClient(fake, "archive")
+	require.NoError(t, err)
+	data, err = archive.Get(ctx, "proof.bin")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("proof"), data)
+
+	// missing sources
+	require.ErrorIs(t, bs.Copy(ctx, "missing", "other"), ErrNotFound)
+	require.ErrorIs(t, bs.Move(ctx, "missing", "other"), ErrNotFound)
+	assert.Equal(t, []string{"copies/proof.bin"}, fake.Keys("artifacts"))
+}
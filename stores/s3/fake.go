@@ -0,0 +1,253 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" //nolint:gosec // S3 ETags are the MD5 of the content
+	"encoding/hex"
+	"io"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/jonboulle/clockwork"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// defaultMaxKeys is the maximum number of keys S3 returns per page.
+const defaultMaxKeys = 1000
+
+type fakeObject struct {
+	data            []byte
+	contentType     *string
+	contentEncoding *string
+	metadata        map[string]string
+	etag            string
+	lastModified    time.Time
+}
+
+// FakeS3Client is an in-memory S3Client for tests, so that they need not set expectations
+// for every call (eg each page of a listing). Buckets exist implicitly and start empty.
+// Missing keys are reported as by S3: GetObject returns *types.NoSuchKey, and HeadObject *types.NotFound.
+// Listings are sorted by key, and support prefixes, delimiters, start after, and pagination.
+// It is safe for concurrent use.
+type FakeS3Client struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string]fakeObject
+	clock   clockwork.Clock
+}
+
+var _ S3Client = (*FakeS3Client)(nil)
+
+// NewFakeS3Client creates an empty FakeS3Client.
+func NewFakeS3Client() *FakeS3Client {
+	return NewFakeS3ClientWithClock(clockwork.NewRealClock())
+}
+
+// NewFakeS3ClientWithClock creates an empty FakeS3Client using the clock for the LastModified time of objects.
+func NewFakeS3ClientWithClock(clock clockwork.Clock) *FakeS3Client {
+	return &FakeS3Client{
+		buckets: make(map[string]map[string]fakeObject),
+		clock:   clock,
+	}
+}
+
+// Keys returns the keys of all objects in the bucket, sorted.
+func (f *FakeS3Client) Keys(bucket string) []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return slices.Sorted(maps.Keys(f.buckets[bucket]))
+}
+
+// PutObject implements S3Client.
+func (f *FakeS3Client) PutObject(_ context.Context, params *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	var data []byte
+	if params.Body != nil {
+		var err error
+		data, err = io.ReadAll(params.Body)
+		if err != nil {
+			return nil, stacktrace.Wrap(err)
+		}
+	}
+	sum := md5.Sum(data) //nolint:gosec // S3 ETags are the MD5 of the content
+	obj := fakeObject{
+		data:            data,
+		contentType:     params.ContentType,
+		contentEncoding: params.ContentEncoding,
+		metadata:        maps.Clone(params.Metadata),
+		etag:            `"` + hex.EncodeToString(sum[:]) + `"`,
+		lastModified:    f.clock.Now().UTC(),
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	bucket := aws.ToString(params.Bucket)
+	if f.buckets[bucket] == nil {
+		f.buckets[bucket] = make(map[string]fakeObject)
+	}
+	f.buckets[bucket][aws.ToString(params.Key)] = obj
+	return &s3.PutObjectOutput{ETag: aws.String(obj.etag)}, nil
+}
+
+// GetObject implements S3Client.
+func (f *FakeS3Client) GetObject(_ context.Context, params *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	obj, ok := f.object(params.Bucket, params.Key)
+	if !ok {
+		return nil, &types.NoSuchKey{Message: aws.String("The specified key does not exist.")}
+	}
+	return &s3.GetObjectOutput{
+		Body:            io.NopCloser(bytes.NewReader(obj.data)),
+		ContentLength:   aws.Int64(int64(len(obj.data))),
+		ContentType:     obj.contentType,
+		ContentEncoding: obj.contentEncoding,
+		ETag:            aws.String(obj.etag),
+		LastModified:    aws.Time(obj.lastModified),
+		Metadata:        maps.Clone(obj.metadata),
+	}, nil
+}
+
+// HeadObject implements S3Client.
+func (f *FakeS3Client) HeadObject(_ context.Context, params *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	obj, ok := f.object(params.Bucket, params.Key)
+	if !ok {
+		return nil, &types.NotFound{Message: aws.String("Not Found")}
+	}
+	return &s3.HeadObjectOutput{
+		ContentLength:   aws.Int64(int64(len(obj.data))),
+		ContentType:     obj.contentType,
+		ContentEncoding: obj.contentEncoding,
+		ETag:            aws.String(obj.etag),
+		LastModified:    aws.Time(obj.lastModified),
+		Metadata:        maps.Clone(obj.metadata),
+	}, nil
+}
+
+// ListObjectsV2 implements S3Client. Continuation tokens are the last key of the previous page.
+func (f *FakeS3Client) ListObjectsV2(_ context.Context, params *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	prefix := aws.ToString(params.Prefix)
+	delimiter := aws.ToString(params.Delimiter)
+	maxKeys := int(aws.ToInt32(params.MaxKeys))
+	if params.MaxKeys == nil || maxKeys > defaultMaxKeys {
+		maxKeys = defaultMaxKeys
+	}
+	after := aws.ToString(params.StartAfter)
+	if params.ContinuationToken != nil {
+		after = *params.ContinuationToken
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	objects := f.buckets[aws.ToString(params.Bucket)]
+
+	output := &s3.ListObjectsV2Output{
+		Name:              params.Bucket,
+		Prefix:            params.Prefix,
+		Delimiter:         params.Delimiter,
+		MaxKeys:           aws.Int32(int32(maxKeys)), //nolint:gosec // at most defaultMaxKeys
+		ContinuationToken: params.ContinuationToken,
+		StartAfter:        params.StartAfter,
+		IsTruncated:       aws.Bool(false),
+	}
+	count := 0
+	last := ""
+	for _, key := range slices.Sorted(maps.Keys(objects)) {
+		if !strings.HasPrefix(key, prefix) || key <= after {
+			continue
+		}
+		// keys sharing a common prefix up to the delimiter are rolled up into that prefix
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				common := key[:len(prefix)+i+len(delimiter)]
+				if common <= after || common+"\xff" == last {
+					continue
+				}
+				if count == maxKeys {
+					output.IsTruncated = aws.Bool(true)
+					break
+				}
+				output.CommonPrefixes = append(output.CommonPrefixes, types.CommonPrefix{Prefix: aws.String(common)})
+				count++
+				// continue after every key with this common prefix (0xff never occurs in UTF-8)
+				last = common + "\xff"
+				continue
+			}
+		}
+		if count == maxKeys {
+			output.IsTruncated = aws.Bool(true)
+			break
+		}
+		obj := objects[key]
+		output.Contents = append(output.Contents, types.Object{
+			Key:          aws.String(key),
+			Size:         aws.Int64(int64(len(obj.data))),
+			ETag:         aws.String(obj.etag),
+			LastModified: aws.Time(obj.lastModified),
+		})
+		count++
+		last = key
+	}
+	output.KeyCount = aws.Int32(int32(count)) //nolint:gosec // at most defaultMaxKeys
+	if aws.ToBool(output.IsTruncated) {
+		output.NextContinuationToken = aws.String(last)
+	}
+	return output, nil
+}
+
+// DeleteObject implements S3Client. As per S3, deleting a missing key is not an error.
+func (f *FakeS3Client) DeleteObject(_ context.Context, params *s3.DeleteObjectInput, _ ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.buckets[aws.ToString(params.Bucket)], aws.ToString(params.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+// CopyObject implements S3Client. Missing sources are reported as by S3, with an API error coded "NoSuchKey".
+// Metadata is copied, unless the directive is REPLACE.
+func (f *FakeS3Client) CopyObject(_ context.Context, params *s3.CopyObjectInput, _ ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	source, err := url.PathUnescape(aws.ToString(params.CopySource))
+	if err != nil {
+		return nil, &smithy.GenericAPIError{Code: "InvalidArgument", Message: "Invalid copy source encoding."}
+	}
+	srcBucket, srcKey, _ := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+	dstBucket, dstKey := aws.ToString(params.Bucket), aws.ToString(params.Key)
+	replace := params.MetadataDirective == types.MetadataDirectiveReplace
+	if srcBucket == dstBucket && srcKey == dstKey && !replace {
+		return nil, &smithy.GenericAPIError{Code: "InvalidRequest", Message: "This copy request is illegal because it is trying to copy an object to itself."}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, ok := f.buckets[srcBucket][srcKey]
+	if !ok {
+		return nil, &smithy.GenericAPIError{Code: "NoSuchKey", Message: "The specified key does not exist."}
+	}
+	if replace {
+		obj.contentType = params.ContentType
+		obj.contentEncoding = params.ContentEncoding
+		obj.metadata = params.Metadata
+	}
+	obj.metadata = maps.Clone(obj.metadata)
+	obj.lastModified = f.clock.Now().UTC()
+	if f.buckets[dstBucket] == nil {
+		f.buckets[dstBucket] = make(map[string]fakeObject)
+	}
+	f.buckets[dstBucket][dstKey] = obj
+	return &s3.CopyObjectOutput{
+		CopyObjectResult: &types.CopyObjectResult{ETag: aws.String(obj.etag), LastModified: aws.Time(obj.lastModified)},
+	}, nil
+}
+
+func (f *FakeS3Client) object(bucket, key *string) (fakeObject, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	obj, ok := f.buckets[aws.ToString(bucket)][aws.ToString(key)]
+	return obj, ok
+}
@@ -0,0 +1,188 @@
+package s3
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeBlobStore(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+	fake := NewFakeS3Client()
+
+	_, err := NewBlobStoreWithClient(fake, "")
+	require.ErrorIs(t, err, ErrNoBucket)
+	bs, err := NewBlobStoreWithClient(fake, "snapshots")
+	require.NoError(t, err)
+
+	_, err = bs.Get(ctx, "missing")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.ErrorIs(t, bs.Exists(ctx, "missing"), ErrNotFound)
+	require.NoError(t, bs.Delete(ctx, "missing"))
+
+	require.NoError(t, bs.Upload(ctx, "hello", []byte("world")))
+	require.NoError(t, bs.Exists(ctx, "hello"))
+	data, err := bs.Get(ctx, "hello")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("world"), data)
+
+	// buckets are separate
+	bs.SetBucket("diffs")
+	require.ErrorIs(t, bs.Exists(ctx, "hello"), ErrNotFound)
+	bs.SetBucket("snapshots")
+
+	require.NoError(t, bs.Delete(ctx, "hello"))
+	require.ErrorIs(t, bs.Exists(ctx, "hello"), ErrNotFound)
+	assert.Empty(t, fake.Keys("snapshots"))
+}
+
+func TestFakeBlobStoreGetList(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+	fake := NewFakeS3Client()
+	bs, err := NewBlobStoreWithClient(fake, "snapshots")
+	require.NoError(t, err)
+
+	// more keys than fit in a single page
+	var expected []string
+	for i := range 2500 {
+		key := fmt.Sprintf("dir/%05d", i)
+		expected = append(expected, key)
+		require.NoError(t, bs.Upload(ctx, key, []byte{byte(i)}))
+	}
+	require.NoError(t, bs.Upload(ctx, "other/file", nil))
+
+	keys, err := bs.GetList(ctx, "dir/")
+	require.NoError(t, err)
+	assert.Equal(t, expected, keys)
+
+	keys, err = bs.GetAllList(ctx)
+	require.NoError(t, err)
+	assert.Len(t, keys, 2501)
+}
+
+func TestFakePublicBlobStoreList(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+	fake := NewFakeS3Client()
+	for _, key := range []string{"a/1", "a/2", "a/3", "a/4", "a/5", "b/1"} {
+		_, err := fake.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: aws.String("public"),
+			Key:    aws.String(key),
+			Body:   strings.NewReader(key),
+		})
+		require.NoError(t, err)
+	}
+	p, err := NewPublicBlobStoreWithClient(fake, "public")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		opts     ListOptions
+		expected []string
+	}{
+		{"all", ListOptions{}, []string{"a/1", "a/2", "a/3", "a/4", "a/5", "b/1"}},
+		{"prefix", ListOptions{Prefix: "a/"}, []string{"a/1", "a/2", "a/3", "a/4", "a/5"}},
+		{"max keys", ListOptions{MaxKeys: 2}, []string{"a/1", "a/2", "a/3", "a/4", "a/5", "b/1"}},
+		{"max pages", ListOptions{MaxKeys: 2, MaxPages: 2}, []string{"a/1", "a/2", "a/3", "a/4"}},
+		{"all options", ListOptions{Prefix: "a/", MaxKeys: 3, MaxPages: 1}, []string{"a/1", "a/2", "a/3"}},
+		{"no match", ListOptions{Prefix: "c/"}, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			keys, err := p.List(t.Context(), tc.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, keys)
+		})
+	}
+}
+
+func TestFakeS3Client(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+	clock := clockwork.NewFakeClockAt(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	fake := NewFakeS3ClientWithClock(clock)
+	for _, key := range []string{"a.txt", "dir/a/1", "dir/a/2", "dir/b/1", "dir/c", "z.txt"} {
+		_, err := fake.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String("bucket"),
+			Key:         aws.String(key),
+			Body:        strings.NewReader("hello"),
+			ContentType: aws.String("text/plain"),
+		})
+		require.NoError(t, err)
+	}
+
+	_, err := fake.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String("bucket"), Key: aws.String("missing")})
+	var noSuchKey *types.NoSuchKey
+	require.ErrorAs(t, err, &noSuchKey)
+	_, err = fake.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String("missing")})
+	var notFound *types.NotFound
+	require.ErrorAs(t, err, &notFound)
+
+	head, err := fake.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String("a.txt")})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), aws.ToInt64(head.ContentLength))
+	assert.Equal(t, "text/plain", aws.ToString(head.ContentType))
+	assert.Equal(t, `"5d41402abc4b2a76b9719d911017c592"`, aws.ToString(head.ETag))
+	assert.Equal(t, clock.Now(), aws.ToTime(head.LastModified))
+
+	list := func(input *s3.ListObjectsV2Input) (keys, prefixes []string, next *string) {
+		t.Helper()
+		input.Bucket = aws.String("bucket")
+		output, err := fake.ListObjectsV2(ctx, input)
+		require.NoError(t, err)
+		for _, obj := range output.Contents {
+			keys = append(keys, aws.ToString(obj.Key))
+		}
+		for _, prefix := range output.CommonPrefixes {
+			prefixes = append(prefixes, aws.ToString(prefix.Prefix))
+		}
+		assert.Equal(t, len(keys)+len(prefixes), int(aws.ToInt32(output.KeyCount)))
+		assert.Equal(t, output.NextContinuationToken != nil, aws.ToBool(output.IsTruncated))
+		return keys, prefixes, output.NextContinuationToken
+	}
+
+	// delimiters roll up keys into common prefixes
+	keys, prefixes, next := list(&s3.ListObjectsV2Input{Delimiter: aws.String("/")})
+	assert.Equal(t, []string{"a.txt", "z.txt"}, keys)
+	assert.Equal(t, []string{"dir/"}, prefixes)
+	assert.Nil(t, next)
+
+	keys, prefixes, _ = list(&s3.ListObjectsV2Input{Prefix: aws.String("dir/"), Delimiter: aws.String("/")})
+	assert.Equal(t, []string{"dir/c"}, keys)
+	assert.Equal(t, []string{"dir/a/", "dir/b/"}, prefixes)
+
+	// common prefixes count towards max keys, and are not repeated on the next page
+	keys, prefixes, next = list(&s3.ListObjectsV2Input{Prefix: aws.String("dir/"), Delimiter: aws.String("/"), MaxKeys: aws.Int32(1)})
+	assert.Empty(t, keys)
+	assert.Equal(t, []string{"dir/a/"}, prefixes)
+	require.NotNil(t, next)
+	keys, prefixes, next = list(&s3.ListObjectsV2Input{Prefix: aws.String("dir/"), Delimiter: aws.String("/"), MaxKeys: aws.Int32(1), ContinuationToken: next})
+	assert.Empty(t, keys)
+	assert.Equal(t, []string{"dir/b/"}, prefixes)
+	require.NotNil(t, next)
+	keys, prefixes, next = list(&s3.ListObjectsV2Input{Prefix: aws.String("dir/"), Delimiter: aws.String("/"), MaxKeys: aws.Int32(1), ContinuationToken: next})
+	assert.Equal(t, []string{"dir/c"}, keys)
+	assert.Empty(t, prefixes)
+	assert.Nil(t, next)
+
+	// start after skips keys up to and including it
+	keys, _, _ = list(&s3.ListObjectsV2Input{StartAfter: aws.String("dir/b/1")})
+	assert.Equal(t, []string{"dir/c", "z.txt"}, keys)
+
+	// an empty bucket has no keys
+	keys, prefixes, next = list(&s3.ListObjectsV2Input{Prefix: aws.String("none/")})
+	assert.Empty(t, keys)
+	assert.Empty(t, prefixes)
+	assert.Nil(t, next)
+}
@@ -0,0 +1,97 @@
+package s3
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/zircuit-labs/zkr-go-common/metrics"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+// Operation names used as the "operation" label.
+const (
+	opUpload  = "upload"
+	opGet     = "get"
+	opExists  = "exists"
+	opList    = "list"
+	opDelete  = "delete"
+	opCopy    = "copy"
+)
+
+type options struct {
+	registerer  prometheus.Registerer
+	compression Compression
+}
+
+// Option is an option func for creating a BlobStore or PublicBlobStore.
+type Option func(options *options)
+
+// WithMetrics records the latency, bytes transferred and errors of each operation with the given registerer.
+// Metrics are labeled by bucket and operation, so several stores may share the same registerer.
+func WithMetrics(registerer prometheus.Registerer) Option {
+	return func(options *options) {
+		options.registerer = registerer
+	}
+}
+
+func parseOptions(opts []Option) options {
+	options := options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+type blobStoreMetrics struct {
+	duration *prometheus.HistogramVec
+	bytes    *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+}
+
+func registerBlobStoreMetrics(registerer prometheus.Registerer) (*blobStoreMetrics, error) {
+	duration, err := metrics.Register(registerer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "s3_blobstore_operation_duration_seconds",
+		Help:    "Latency of blob store operations, including those which failed.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"bucket", "operation"}))
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := metrics.Register(registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "s3_blobstore_bytes_total",
+		Help: "Number of bytes uploaded or downloaded by blob store operations.",
+	}, []string{"bucket", "operation"}))
+	if err != nil {
+		return nil, err
+	}
+	errs, err := metrics.Register(registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "s3_blobstore_errors_total",
+		Help: "Number of failed blob store operations by error class. Objects which are not found are not counted.",
+	}, []string{"bucket", "operation", "class"}))
+	if err != nil {
+		return nil, err
+	}
+	return &blobStoreMetrics{duration: duration, bytes: bytes, errors: errs}, nil
+}
+
+// The following are no-ops when metrics are not enabled.
+
+// observe records the outcome of an operation which started at start.
+// It is intended to be deferred with a pointer to the named error result.
+func (m *blobStoreMetrics) observe(bucket, operation string, start time.Time, err *error) {
+	if m == nil {
+		return
+	}
+	m.duration.WithLabelValues(bucket, operation).Observe(time.Since(start).Seconds())
+	if *err != nil && !errors.Is(*err, ErrNotFound) {
+		m.errors.WithLabelValues(bucket, operation, errclass.GetClass(*err).String()).Inc()
+	}
+}
+
+func (m *blobStoreMetrics) addBytes(bucket, operation string, n int) {
+	if m != nil {
+		m.bytes.WithLabelValues(bucket, operation).Add(float64(n))
+	}
+}
@@ -0,0 +1,67 @@
+package s3
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gomock "go.uber.org/mock/gomock"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+	bs, config, mockS3 := testSetup(t)
+	ctx := t.Context()
+
+	registry := prometheus.NewRegistry()
+	metrics, err := registerBlobStoreMetrics(registry)
+	require.NoError(t, err)
+	bs.metrics = metrics
+
+	data := []byte("hello world")
+	mockS3.EXPECT().PutObject(ctx, gomock.Any()).Return(&s3.PutObjectOutput{}, nil)
+	mockS3.EXPECT().GetObject(ctx, gomock.Any()).Return(&s3.GetObjectOutput{
+		Body: io.NopCloser(bytes.NewReader(data)),
+	}, nil)
+	mockS3.EXPECT().HeadObject(ctx, gomock.Any()).Return(nil, &types.NotFound{})
+	mockS3.EXPECT().DeleteObject(ctx, gomock.Any()).Return(nil, errclass.WrapAs(assert.AnError, errclass.Transient))
+
+	require.NoError(t, bs.Upload(ctx, "key", data))
+	_, err = bs.Get(ctx, "key")
+	require.NoError(t, err)
+	require.ErrorIs(t, bs.Exists(ctx, "key"), ErrNotFound)
+	require.Error(t, bs.Delete(ctx, "key"))
+
+	// every operation is timed
+	assert.Equal(t, 4, testutil.CollectAndCount(metrics.duration))
+
+	// bytes in both directions are counted
+	assert.InDelta(t, float64(len(data)), testutil.ToFloat64(metrics.bytes.WithLabelValues(config.Bucket, opUpload)), 0)
+	assert.InDelta(t, float64(len(data)), testutil.ToFloat64(metrics.bytes.WithLabelValues(config.Bucket, opGet)), 0)
+
+	// errors are counted by class, except for objects which are not found
+	assert.Equal(t, 1, testutil.CollectAndCount(metrics.errors))
+	assert.InDelta(t, 1, testutil.ToFloat64(metrics.errors.WithLabelValues(config.Bucket, opDelete, errclass.Transient.String())), 0)
+
+	// metrics may be shared between stores
+	other, err := NewBlobStoreFromConfig(ctx, config, WithMetrics(registry))
+	require.NoError(t, err)
+	assert.Same(t, metrics.duration, other.metrics.duration)
+}
+
+func TestNoMetrics(t *testing.T) {
+	t.Parallel()
+	bs, _, mockS3 := testSetup(t)
+	ctx := t.Context()
+
+	mockS3.EXPECT().DeleteObject(ctx, gomock.Any()).Return(nil, assert.AnError)
+	require.Error(t, bs.Delete(ctx, "key"))
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
@@ -18,8 +19,9 @@ import (
 
 // PublicBlobStore is a read-only S3 client for accessing public buckets without credentials
 type PublicBlobStore struct {
-	bucket string
-	s3     S3Client
+	bucket  string
+	s3      S3Client
+	metrics *blobStoreMetrics
 }
 
 type PublicBlobStoreConfig struct {
@@ -35,7 +37,7 @@ type PublicBlobStoreConfig struct {
 
 // NewPublicBlobStoreFromConfig creates a new PublicBlobStore from the provided config
 // This client uses anonymous credentials and is intended for read-only access to public buckets
-func NewPublicBlobStoreFromConfig(ctx context.Context, config PublicBlobStoreConfig) (*PublicBlobStore, error) {
+func NewPublicBlobStoreFromConfig(ctx context.Context, config PublicBlobStoreConfig, opts ...Option) (*PublicBlobStore, error) {
 	if config.Region == "" {
 		return nil, stacktrace.Wrap(ErrNoRegion)
 	}
@@ -66,24 +68,46 @@ func NewPublicBlobStoreFromConfig(ctx context.Context, config PublicBlobStoreCon
 	}
 
 	s3Client := s3.NewFromConfig(awsConfig, clientOptions...)
-	return &PublicBlobStore{
-		bucket: config.Bucket,
-		s3:     s3Client,
-	}, nil
+	return NewPublicBlobStoreWithClient(s3Client, config.Bucket, opts...)
+}
+
+// NewPublicBlobStoreWithClient creates a PublicBlobStore using the given client, eg a FakeS3Client in tests.
+func NewPublicBlobStoreWithClient(client S3Client, bucket string, opts ...Option) (*PublicBlobStore, error) {
+	options := parseOptions(opts)
+
+	if bucket == "" {
+		return nil, stacktrace.Wrap(ErrNoBucket)
+	}
+
+	p := &PublicBlobStore{
+		bucket: bucket,
+		s3:     client,
+	}
+
+	if options.registerer != nil {
+		metrics, err := registerBlobStoreMetrics(options.registerer)
+		if err != nil {
+			return nil, err
+		}
+		p.metrics = metrics
+	}
+
+	return p, nil
 }
 
 // NewPublicBlobStore creates a new PublicBlobStore from the application configuration
-func NewPublicBlobStore(ctx context.Context, cfg *config.Configuration, cfgPath string) (*PublicBlobStore, error) {
+func NewPublicBlobStore(ctx context.Context, cfg *config.Configuration, cfgPath string, opts ...Option) (*PublicBlobStore, error) {
 	config := PublicBlobStoreConfig{}
 	if err := cfg.Unmarshal(cfgPath, &config); err != nil {
 		return nil, stacktrace.Wrap(err)
 	}
 
-	return NewPublicBlobStoreFromConfig(ctx, config)
+	return NewPublicBlobStoreFromConfig(ctx, config, opts...)
 }
 
 // Get retrieves an object from the public S3 bucket
 func (p *PublicBlobStore) Get(ctx context.Context, key string) (res []byte, err error) {
+	defer p.metrics.observe(p.bucket, opGet, time.Now(), &err)
 	defer func() {
 		err = errcontext.Add(err, slog.String("key", key))
 	}()
@@ -106,12 +130,14 @@ func (p *PublicBlobStore) Get(ctx context.Context, key string) (res []byte, err
 	if err != nil {
 		return nil, stacktrace.Wrap(err)
 	}
+	p.metrics.addBytes(p.bucket, opGet, buf.Len())
 
-	return buf.Bytes(), nil
+	return decompress(buf.Bytes(), aws.ToString(data.ContentEncoding))
 }
 
 // Exists checks if an object exists in the public S3 bucket
 func (p *PublicBlobStore) Exists(ctx context.Context, key string) (err error) {
+	defer p.metrics.observe(p.bucket, opExists, time.Now(), &err)
 	defer func() {
 		err = errcontext.Add(err, slog.String("key", key))
 	}()
@@ -163,7 +189,9 @@ type ListOptions struct {
 //   - Use MaxPages to limit total number of API calls (prevents timeout on large buckets)
 //
 // Returns a slice of object keys matching the criteria.
-func (p *PublicBlobStore) List(ctx context.Context, opts ListOptions) ([]string, error) {
+func (p *PublicBlobStore) List(ctx context.Context, opts ListOptions) (_ []string, err error) {
+	defer p.metrics.observe(p.bucket, opList, time.Now(), &err)
+
 	var keys []string
 	var continuationToken *string
 	pagesRetrieved := 0
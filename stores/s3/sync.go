@@ -0,0 +1,366 @@
+package s3
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec // S3 ETags are the MD5 of the content
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/zircuit-labs/zkr-go-common/calm/errgroup"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const (
+	defaultSyncConcurrency = 8
+
+	// checksumMetadataKey is the user-defined metadata holding the MD5 of the uncompressed content,
+	// since the ETag of a compressed object is the MD5 of the compressed content.
+	checksumMetadataKey = "content-md5"
+)
+
+var ErrUnsafeKey = errors.New("key is not a local path within the directory")
+
+type syncOptions struct {
+	concurrency int
+	include     []string
+	exclude     []string
+	progress    func(SyncProgress)
+}
+
+// SyncOption is an option func for SyncUp and SyncDown.
+type SyncOption func(options *syncOptions)
+
+// WithSyncConcurrency sets the number of files transferred at once. Defaults to 8.
+func WithSyncConcurrency(n int) SyncOption {
+	return func(options *syncOptions) {
+		options.concurrency = max(n, 1)
+	}
+}
+
+// WithInclude only syncs files whose path relative to the directory (using "/" as separator)
+// matches at least one of the patterns, as per path.Match. As for .gitignore, patterns without
+// a "/" match the file name in any directory, eg "*.json".
+func WithInclude(patterns ...string) SyncOption {
+	return func(options *syncOptions) {
+		options.include = append(options.include, patterns...)
+	}
+}
+
+// WithExclude does not sync files whose path relative to the directory (using "/" as separator)
+// matches any of the patterns, as per WithInclude. Exclusions take precedence over inclusions.
+func WithExclude(patterns ...string) SyncOption {
+	return func(options *syncOptions) {
+		options.exclude = append(options.exclude, patterns...)
+	}
+}
+
+// WithProgress calls the function after each file is transferred or skipped.
+// Calls are not concurrent, so the function need not be safe for concurrent use, but should not block.
+func WithProgress(progress func(SyncProgress)) SyncOption {
+	return func(options *syncOptions) {
+		options.progress = progress
+	}
+}
+
+// SyncProgress describes a file which has just been synced.
+type SyncProgress struct {
+	Path    string // local path of the file
+	Key     string // key of the object
+	Size    int64  // uncompressed size in bytes
+	Skipped bool   // true if the file was unchanged, and so not transferred
+	Done    int    // number of files synced so far, including this one
+	Total   int    // number of files to be synced
+}
+
+// SyncStats summarizes the result of SyncUp or SyncDown.
+type SyncStats struct {
+	Transferred int
+	Skipped     int
+	Bytes       int64 // uncompressed bytes transferred
+}
+
+// LogValue implements slog.LogValuer.
+func (s SyncStats) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("transferred", s.Transferred),
+		slog.Int("skipped", s.Skipped),
+		slog.Int64("bytes", s.Bytes),
+	)
+}
+
+// syncFile is a file to be synced with an object.
+type syncFile struct {
+	local  string
+	key    string
+	remote *types.Object // nil if there is no such object
+}
+
+// SyncUp uploads every file in localDir (recursively) to the key of its relative path under the prefix,
+// skipping files whose content is unchanged. Files are uploaded using the store's compression, and
+// are read into memory in full, so the concurrency should be set with file sizes in mind.
+// Objects under the prefix without a corresponding file are left in place.
+func (b *BlobStore) SyncUp(ctx context.Context, localDir, prefix string, opts ...SyncOption) (SyncStats, error) {
+	options := parseSyncOptions(opts)
+
+	existing, err := b.listObjects(ctx, dirPrefix(prefix))
+	if err != nil {
+		return SyncStats{}, err
+	}
+	objects := make(map[string]*types.Object, len(existing))
+	for i := range existing {
+		objects[aws.ToString(existing[i].Key)] = &existing[i]
+	}
+
+	var files []syncFile
+	err = filepath.WalkDir(localDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(localDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !options.matches(rel) {
+			return nil
+		}
+		key := path.Join(prefix, rel)
+		files = append(files, syncFile{local: p, key: key, remote: objects[key]})
+		return nil
+	})
+	if err != nil {
+		return SyncStats{}, errcontext.Add(stacktrace.Wrap(err), slog.String("dir", localDir))
+	}
+
+	return b.sync(ctx, files, options, b.syncFileUp)
+}
+
+// SyncDown downloads every object under the prefix to the file of its relative key in localDir,
+// creating directories as needed and skipping files whose content is unchanged. Objects are decompressed
+// according to their Content-Encoding. Files without a corresponding object are left in place.
+// Fails with ErrUnsafeKey if a key would be written outside of localDir (eg containing "..").
+func (b *BlobStore) SyncDown(ctx context.Context, prefix, localDir string, opts ...SyncOption) (SyncStats, error) {
+	options := parseSyncOptions(opts)
+
+	dir := dirPrefix(prefix)
+	objects, err := b.listObjects(ctx, dir)
+	if err != nil {
+		return SyncStats{}, err
+	}
+
+	var files []syncFile
+	for i, obj := range objects {
+		key := aws.ToString(obj.Key)
+		rel := strings.TrimPrefix(key, dir)
+		if rel == "" || strings.HasSuffix(rel, "/") {
+			// folder placeholder
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(rel)) {
+			return SyncStats{}, errcontext.Add(
+				errclass.WrapAs(stacktrace.Wrap(ErrUnsafeKey), errclass.Persistent),
+				slog.String("key", key),
+			)
+		}
+		if !options.matches(rel) {
+			continue
+		}
+		files = append(files, syncFile{
+			local:  filepath.Join(localDir, filepath.FromSlash(rel)),
+			key:    key,
+			remote: &objects[i],
+		})
+	}
+
+	return b.sync(ctx, files, options, b.syncFileDown)
+}
+
+// sync concurrently applies the transfer func to each file, collating stats and progress.
+// transfer returns the size of the file, and whether it was skipped.
+func (b *BlobStore) sync(
+	ctx context.Context,
+	files []syncFile,
+	options syncOptions,
+	transfer func(context.Context, syncFile) (int64, bool, error),
+) (SyncStats, error) {
+	var (
+		mu    sync.Mutex
+		stats SyncStats
+	)
+	group, ctx := errgroup.WithContext(ctx, errgroup.WithLimit(options.concurrency))
+	for _, file := range files {
+		group.Go(func() error {
+			size, skipped, err := transfer(ctx, file)
+			if err != nil {
+				return errcontext.Add(err, slog.String("path", file.local), slog.String("key", file.key))
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if skipped {
+				stats.Skipped++
+			} else {
+				stats.Transferred++
+				stats.Bytes += size
+			}
+			if options.progress != nil {
+				options.progress(SyncProgress{
+					Path:    file.local,
+					Key:     file.key,
+					Size:    size,
+					Skipped: skipped,
+					Done:    stats.Skipped + stats.Transferred,
+					Total:   len(files),
+				})
+			}
+			return nil
+		})
+	}
+	err := group.Wait()
+	return stats, err
+}
+
+func (b *BlobStore) syncFileUp(ctx context.Context, file syncFile) (int64, bool, error) {
+	data, err := os.ReadFile(file.local)
+	if err != nil {
+		return 0, false, stacktrace.Wrap(err)
+	}
+	sum := md5.Sum(data) //nolint:gosec // S3 ETags are the MD5 of the content
+	checksum := hex.EncodeToString(sum[:])
+
+	if file.remote != nil {
+		unchanged, err := b.unchanged(ctx, *file.remote, checksum)
+		if err != nil {
+			return 0, false, err
+		}
+		if unchanged {
+			return int64(len(data)), true, nil
+		}
+	}
+
+	err = b.upload(ctx, file.key, data, map[string]string{checksumMetadataKey: checksum})
+	return int64(len(data)), false, err
+}
+
+func (b *BlobStore) syncFileDown(ctx context.Context, file syncFile) (int64, bool, error) {
+	local, err := os.ReadFile(file.local)
+	switch {
+	case err == nil:
+		sum := md5.Sum(local) //nolint:gosec // S3 ETags are the MD5 of the content
+		unchanged, err := b.unchanged(ctx, *file.remote, hex.EncodeToString(sum[:]))
+		if err != nil {
+			return 0, false, err
+		}
+		if unchanged {
+			return int64(len(local)), true, nil
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return 0, false, stacktrace.Wrap(err)
+	}
+
+	data, err := b.Get(ctx, file.key)
+	if err != nil {
+		return 0, false, err
+	}
+	if err := writeFileAtomic(file.local, data); err != nil {
+		return 0, false, err
+	}
+	return int64(len(data)), false, nil
+}
+
+// unchanged reports whether the object has the content with the given (hex) MD5 checksum.
+// This is the case if its ETag is the checksum (as for uncompressed objects uploaded in a single part),
+// or if it was uploaded by SyncUp with that checksum.
+func (b *BlobStore) unchanged(ctx context.Context, obj types.Object, checksum string) (_ bool, err error) {
+	if strings.Trim(aws.ToString(obj.ETag), `"`) == checksum {
+		return true, nil
+	}
+
+	defer b.metrics.observe(b.bucket, opExists, time.Now(), &err)
+	head, err := b.s3.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    obj.Key,
+	})
+	if err != nil {
+		return false, stacktrace.Wrap(err)
+	}
+	return head.Metadata[checksumMetadataKey] == checksum, nil
+}
+
+// writeFileAtomic writes the data to a temporary file which then replaces the file at name,
+// so that readers never see a partially written file.
+func writeFileAtomic(name string, data []byte) error {
+	dir := filepath.Dir(name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return stacktrace.Wrap(err)
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return stacktrace.Wrap(err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return stacktrace.Wrap(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return stacktrace.Wrap(err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return stacktrace.Wrap(err)
+	}
+	return stacktrace.Wrap(os.Rename(tmp.Name(), name))
+}
+
+func parseSyncOptions(opts []SyncOption) syncOptions {
+	options := syncOptions{concurrency: defaultSyncConcurrency}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// matches reports whether the relative path is included and not excluded.
+func (o syncOptions) matches(rel string) bool {
+	if slices.ContainsFunc(o.exclude, matchesPattern(rel)) {
+		return false
+	}
+	return len(o.include) == 0 || slices.ContainsFunc(o.include, matchesPattern(rel))
+}
+
+// matchesPattern returns a func reporting whether a pattern matches the relative path,
+// or if the pattern has no "/", its file name.
+func matchesPattern(rel string) func(string) bool {
+	return func(pattern string) bool {
+		name := rel
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(rel)
+		}
+		ok, _ := path.Match(pattern, name)
+		return ok
+	}
+}
+
+// dirPrefix returns the prefix as a directory, so that eg "a" does not also match "ab/c".
+func dirPrefix(prefix string) string {
+	if prefix == "" || strings.HasSuffix(prefix, "/") {
+		return prefix
+	}
+	return prefix + "/"
+}
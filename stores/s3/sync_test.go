@@ -0,0 +1,123 @@
+package s3
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o600))
+	}
+}
+
+func TestSyncUpAndDown(t *testing.T) {
+	t.Parallel()
+
+	for _, compression := range []Compression{CompressionNone, CompressionZstd} {
+		t.Run(string(compression), func(t *testing.T) {
+			t.Parallel()
+			ctx := t.Context()
+			fake := NewFakeS3Client()
+			bs, err := NewBlobStoreWithClient(fake, "artifacts", WithCompression(compression))
+			require.NoError(t, err)
+
+			src := t.TempDir()
+			writeFiles(t, src, map[string]string{
+				"proof.bin":         "proof",
+				"circuits/a.json":   "a",
+				"circuits/b.json":   "b",
+				"circuits/tmp.swp":  "swap",
+				"logs/run.log":      "log",
+				"circuits/c/d.json": "d",
+			})
+
+			var progress []SyncProgress
+			stats, err := bs.SyncUp(ctx, src, "batch/1",
+				WithExclude("*.swp", "logs/*"),
+				WithSyncConcurrency(2),
+				WithProgress(func(p SyncProgress) { progress = append(progress, p) }),
+			)
+			require.NoError(t, err)
+			assert.Equal(t, SyncStats{Transferred: 4, Bytes: 8}, stats)
+			assert.Equal(t, []string{
+				"batch/1/circuits/a.json",
+				"batch/1/circuits/b.json",
+				"batch/1/circuits/c/d.json",
+				"batch/1/proof.bin",
+			}, fake.Keys("artifacts"))
+			require.Len(t, progress, 4)
+			assert.Equal(t, 4, progress[3].Done)
+			assert.Equal(t, 4, progress[3].Total)
+
+			// only changed files are uploaded again
+			writeFiles(t, src, map[string]string{"circuits/a.json": "A"})
+			stats, err = bs.SyncUp(ctx, src, "batch/1/", WithExclude("*.swp", "logs/*"))
+			require.NoError(t, err)
+			assert.Equal(t, SyncStats{Transferred: 1, Skipped: 3, Bytes: 1}, stats)
+
+			// download, with includes
+			dst := t.TempDir()
+			stats, err = bs.SyncDown(ctx, "batch/1", dst, WithInclude("circuits/*"))
+			require.NoError(t, err)
+			assert.Equal(t, SyncStats{Transferred: 2, Bytes: 2}, stats)
+			data, err := os.ReadFile(filepath.Join(dst, "circuits", "a.json"))
+			require.NoError(t, err)
+			assert.Equal(t, "A", string(data))
+			_, err = os.Stat(filepath.Join(dst, "proof.bin"))
+			require.ErrorIs(t, err, os.ErrNotExist)
+
+			// only changed or missing files are downloaded again
+			writeFiles(t, dst, map[string]string{"circuits/b.json": "local change"})
+			stats, err = bs.SyncDown(ctx, "batch/1", dst)
+			require.NoError(t, err)
+			assert.Equal(t, SyncStats{Transferred: 3, Skipped: 1, Bytes: 7}, stats)
+			data, err = os.ReadFile(filepath.Join(dst, "circuits", "b.json"))
+			require.NoError(t, err)
+			assert.Equal(t, "b", string(data))
+			data, err = os.ReadFile(filepath.Join(dst, "circuits", "c", "d.json"))
+			require.NoError(t, err)
+			assert.Equal(t, "d", string(data))
+		})
+	}
+}
+
+func TestSyncDownPrefixIsDirectory(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+	fake := NewFakeS3Client()
+	bs, err := NewBlobStoreWithClient(fake, "artifacts")
+	require.NoError(t, err)
+
+	require.NoError(t, bs.Upload(ctx, "batch/1/proof.bin", []byte("proof")))
+	require.NoError(t, bs.Upload(ctx, "batch/10/proof.bin", []byte("other")))
+	require.NoError(t, bs.Upload(ctx, "batch/1/", nil)) // folder placeholder
+
+	dst := t.TempDir()
+	stats, err := bs.SyncDown(ctx, "batch/1", dst)
+	require.NoError(t, err)
+	assert.Equal(t, SyncStats{Transferred: 1, Bytes: 5}, stats)
+	entries, err := os.ReadDir(dst)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "proof.bin", entries[0].Name())
+}
+
+func TestSyncDownUnsafeKey(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+	fake := NewFakeS3Client()
+	bs, err := NewBlobStoreWithClient(fake, "artifacts")
+	require.NoError(t, err)
+
+	require.NoError(t, bs.Upload(ctx, "batch/../../escape", []byte("nope")))
+	_, err = bs.SyncDown(ctx, "batch", t.TempDir())
+	require.ErrorIs(t, err, ErrUnsafeKey)
+}
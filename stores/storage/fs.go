@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const (
+	dirPerm  = 0o750
+	filePerm = 0o640
+)
+
+// FSBlobStore is a BlobStore holding each blob as a file below a root directory, intended for
+// local development and tests. Keys are slash separated paths, such as "blocks/123", and may not
+// escape the root. Writes are not atomic, so readers may see partially written blobs.
+type FSBlobStore struct {
+	dir string
+}
+
+// NewFSBlobStore creates an FSBlobStore rooted at dir, creating the directory if needed.
+func NewFSBlobStore(dir string) (*FSBlobStore, error) {
+	if dir == "" {
+		return nil, errclass.WrapAs(stacktrace.Wrap(ErrNoPath), errclass.Persistent)
+	}
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		return nil, stacktrace.Wrap(err)
+	}
+	return &FSBlobStore{dir: dir}, nil
+}
+
+// root validates the key, then opens the root directory.
+func (f *FSBlobStore) root(key string) (*os.Root, error) {
+	if !fs.ValidPath(key) || key == "." {
+		return nil, errclass.WrapAs(stacktrace.Wrap(ErrInvalidKey), errclass.Persistent)
+	}
+	return f.openRoot()
+}
+
+// openRoot opens the root directory. Operations go through os.Root so that keys cannot escape it (eg via symlinks).
+func (f *FSBlobStore) openRoot() (*os.Root, error) {
+	root, err := os.OpenRoot(f.dir)
+	if err != nil {
+		return nil, stacktrace.Wrap(err)
+	}
+	return root, nil
+}
+
+// Get implements BlobStore.
+func (f *FSBlobStore) Get(_ context.Context, key string) (_ []byte, err error) {
+	defer func() {
+		err = errcontext.Add(err, slog.String("key", key))
+	}()
+
+	root, err := f.root(key)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	data, err := root.ReadFile(key)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return data, nil
+}
+
+// Upload implements BlobStore.
+func (f *FSBlobStore) Upload(_ context.Context, key string, data []byte) (err error) {
+	defer func() {
+		err = errcontext.Add(err, slog.String("key", key))
+	}()
+
+	root, err := f.root(key)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	if err := root.MkdirAll(path.Dir(key), dirPerm); err != nil {
+		return stacktrace.Wrap(err)
+	}
+	if err := root.WriteFile(key, data, filePerm); err != nil {
+		return stacktrace.Wrap(err)
+	}
+	return nil
+}
+
+// Exists implements BlobStore.
+func (f *FSBlobStore) Exists(_ context.Context, key string) (err error) {
+	defer func() {
+		err = errcontext.Add(err, slog.String("key", key))
+	}()
+
+	root, err := f.root(key)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	info, err := root.Stat(key)
+	if err != nil {
+		return notFound(err)
+	}
+	if info.IsDir() {
+		return stacktrace.Wrap(ErrNotFound)
+	}
+	return nil
+}
+
+// List implements BlobStore.
+func (f *FSBlobStore) List(ctx context.Context, prefix string) ([]string, error) {
+	root, err := f.openRoot()
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	// only walk the directory which may contain the prefix
+	start := "."
+	if i := strings.LastIndex(prefix, "/"); i > 0 && fs.ValidPath(prefix[:i]) {
+		start = prefix[:i]
+	}
+
+	var keys []string
+	err = fs.WalkDir(root.FS(), start, func(key string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, stacktrace.Wrap(err)
+	}
+
+	// the walk is ordered by path element, which differs from the order of the keys
+	slices.Sort(keys)
+	return keys, nil
+}
+
+// Delete implements BlobStore.
+func (f *FSBlobStore) Delete(_ context.Context, key string) (err error) {
+	defer func() {
+		err = errcontext.Add(err, slog.String("key", key))
+	}()
+
+	root, err := f.root(key)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	if err := root.Remove(key); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return stacktrace.Wrap(err)
+	}
+	return nil
+}
+
+// notFound wraps ErrNotFound for missing files, or else the error itself.
+func notFound(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return stacktrace.Wrap(ErrNotFound)
+	}
+	return stacktrace.Wrap(err)
+}
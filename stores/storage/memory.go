@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// MemoryBlobStore is a BlobStore holding blobs in memory. It is safe for concurrent use.
+type MemoryBlobStore struct {
+	mu    sync.RWMutex
+	blobs map[string][]byte
+}
+
+// NewMemoryBlobStore creates an empty MemoryBlobStore.
+func NewMemoryBlobStore() *MemoryBlobStore {
+	return &MemoryBlobStore{blobs: make(map[string][]byte)}
+}
+
+// Get implements BlobStore.
+func (m *MemoryBlobStore) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	data, ok := m.blobs[key]
+	if !ok {
+		return nil, errcontext.Add(stacktrace.Wrap(ErrNotFound), slog.String("key", key))
+	}
+	return bytes.Clone(data), nil
+}
+
+// Upload implements BlobStore.
+func (m *MemoryBlobStore) Upload(_ context.Context, key string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.blobs[key] = bytes.Clone(data)
+	return nil
+}
+
+// Exists implements BlobStore.
+func (m *MemoryBlobStore) Exists(_ context.Context, key string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.blobs[key]; !ok {
+		return errcontext.Add(stacktrace.Wrap(ErrNotFound), slog.String("key", key))
+	}
+	return nil
+}
+
+// List implements BlobStore.
+func (m *MemoryBlobStore) List(_ context.Context, prefix string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var keys []string
+	for _, key := range slices.Sorted(maps.Keys(m.blobs)) {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Delete implements BlobStore.
+func (m *MemoryBlobStore) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.blobs, key)
+	return nil
+}
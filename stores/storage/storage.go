@@ -0,0 +1,93 @@
+// Package storage defines a BlobStore interface, implemented by the S3 BlobStore as well as
+// filesystem and in-memory backends, so that services can swap backends by configuration
+// (eg a local directory for development, or memory for tests).
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/stores/s3"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const (
+	TypeS3     = "s3"
+	TypeFS     = "fs"
+	TypeMemory = "memory"
+)
+
+var (
+	// ErrNotFound is returned by every backend for missing keys. It is the same error as s3.ErrNotFound.
+	ErrNotFound = s3.ErrNotFound
+
+	ErrUnknownType = errors.New("unknown blob store type")
+	ErrNoPath      = errors.New("no path supplied")
+	ErrInvalidKey  = errors.New("invalid key")
+)
+
+// BlobStore stores blobs by key. Get and Exists return an error wrapping ErrNotFound for missing keys,
+// while deleting a missing key is not an error. List returns the keys beginning with the prefix, sorted.
+type BlobStore interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Upload(ctx context.Context, key string, data []byte) error
+	Exists(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]string, error)
+	Delete(ctx context.Context, key string) error
+}
+
+var (
+	_ BlobStore = (*s3.BlobStore)(nil)
+	_ BlobStore = (*FSBlobStore)(nil)
+	_ BlobStore = (*MemoryBlobStore)(nil)
+)
+
+// Config selects the backend. The remaining configuration of the S3 backend is that of s3.BlobStoreConfig,
+// at the same path.
+type Config struct {
+	// Type is one of "s3" (the default), "fs" or "memory".
+	Type string `koanf:"type"`
+	// Path is the root directory of the fs backend.
+	Path string `koanf:"path"`
+}
+
+type options struct {
+	s3Options []s3.Option
+}
+
+// Option is an option func for NewBlobStore.
+type Option func(options *options)
+
+// WithS3Options sets the options used when creating the S3 backend.
+func WithS3Options(opts ...s3.Option) Option {
+	return func(options *options) {
+		options.s3Options = append(options.s3Options, opts...)
+	}
+}
+
+// NewBlobStore creates the BlobStore of the type configured at cfgPath.
+func NewBlobStore(ctx context.Context, cfg *config.Configuration, cfgPath string, opts ...Option) (BlobStore, error) {
+	options := options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	storeConfig := Config{}
+	if err := cfg.Unmarshal(cfgPath, &storeConfig); err != nil {
+		return nil, stacktrace.Wrap(err)
+	}
+
+	switch strings.ToLower(storeConfig.Type) {
+	case "", TypeS3:
+		return s3.NewBlobStore(ctx, cfg, cfgPath, options.s3Options...)
+	case TypeFS:
+		return NewFSBlobStore(storeConfig.Path)
+	case TypeMemory:
+		return NewMemoryBlobStore(), nil
+	default:
+		return nil, errclass.WrapAs(stacktrace.Wrap(ErrUnknownType), errclass.Persistent)
+	}
+}
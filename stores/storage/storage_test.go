@@ -0,0 +1,129 @@
+package storage_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/config"
+	"github.com/zircuit-labs/zkr-go-common/stores/s3"
+	"github.com/zircuit-labs/zkr-go-common/stores/storage"
+)
+
+func TestBlobStores(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		store func(t *testing.T) storage.BlobStore
+	}{
+		{
+			name: "memory",
+			store: func(*testing.T) storage.BlobStore {
+				return storage.NewMemoryBlobStore()
+			},
+		},
+		{
+			name: "fs",
+			store: func(t *testing.T) storage.BlobStore {
+				t.Helper()
+				store, err := storage.NewFSBlobStore(filepath.Join(t.TempDir(), "blobs"))
+				require.NoError(t, err)
+				return store
+			},
+		},
+		{
+			name: "s3",
+			store: func(t *testing.T) storage.BlobStore {
+				t.Helper()
+				store, err := s3.NewBlobStoreWithClient(s3.NewFakeS3Client(), "blobs")
+				require.NoError(t, err)
+				return store
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := t.Context()
+			store := tc.store(t)
+
+			_, err := store.Get(ctx, "a/b")
+			require.ErrorIs(t, err, storage.ErrNotFound)
+			require.ErrorIs(t, store.Exists(ctx, "a/b"), storage.ErrNotFound)
+
+			for _, key := range []string{"a/b", "a.b", "a/c/d", "b"} {
+				require.NoError(t, store.Upload(ctx, key, []byte(key)))
+			}
+			require.NoError(t, store.Upload(ctx, "a/b", []byte("updated")))
+
+			data, err := store.Get(ctx, "a/b")
+			require.NoError(t, err)
+			assert.Equal(t, []byte("updated"), data)
+			require.NoError(t, store.Exists(ctx, "a/c/d"))
+
+			keys, err := store.List(ctx, "")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"a.b", "a/b", "a/c/d", "b"}, keys)
+			keys, err = store.List(ctx, "a/")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"a/b", "a/c/d"}, keys)
+			keys, err = store.List(ctx, "a/c/")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"a/c/d"}, keys)
+			keys, err = store.List(ctx, "c/")
+			require.NoError(t, err)
+			assert.Empty(t, keys)
+
+			require.NoError(t, store.Delete(ctx, "a/b"))
+			require.NoError(t, store.Delete(ctx, "a/b"))
+			require.ErrorIs(t, store.Exists(ctx, "a/b"), storage.ErrNotFound)
+		})
+	}
+}
+
+func TestFSBlobStoreInvalidKey(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+
+	_, err := storage.NewFSBlobStore("")
+	require.ErrorIs(t, err, storage.ErrNoPath)
+
+	store, err := storage.NewFSBlobStore(t.TempDir())
+	require.NoError(t, err)
+	for _, key := range []string{"../escape", "/abs", "a//b", ""} {
+		require.ErrorIs(t, store.Upload(ctx, key, []byte("data")), storage.ErrInvalidKey, key)
+	}
+}
+
+func TestNewBlobStore(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+
+	dir := t.TempDir()
+	cfg, err := config.NewConfigurationFromMap(map[string]any{
+		"memory":  map[string]any{"type": "memory"},
+		"fs":      map[string]any{"type": "fs", "path": dir},
+		"s3":      map[string]any{"type": "s3", "bucket": "blobs"},
+		"unknown": map[string]any{"type": "tape"},
+	})
+	require.NoError(t, err)
+
+	store, err := storage.NewBlobStore(ctx, cfg, "memory")
+	require.NoError(t, err)
+	assert.IsType(t, &storage.MemoryBlobStore{}, store)
+
+	store, err = storage.NewBlobStore(ctx, cfg, "fs")
+	require.NoError(t, err)
+	require.NoError(t, store.Upload(ctx, "key", []byte("data")))
+	assert.FileExists(t, filepath.Join(dir, "key"))
+
+	// the s3 configuration is validated as usual
+	_, err = storage.NewBlobStore(ctx, cfg, "s3")
+	require.ErrorIs(t, err, s3.ErrNoRegion)
+
+	_, err = storage.NewBlobStore(ctx, cfg, "unknown")
+	require.ErrorIs(t, err, storage.ErrUnknownType)
+}
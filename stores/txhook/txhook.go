@@ -0,0 +1,95 @@
+// Package txhook carries the callbacks to be called when a database transaction ends through the
+// context, so that code can react to the transaction (eg by publishing a message once it commits)
+// without depending on the package which began it.
+package txhook
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+type hooksKey struct{}
+
+// Hooks holds the callbacks registered during a transaction.
+// Transaction managers create one per transaction (see NewContext), and call Commit or Rollback once it ends.
+type Hooks struct {
+	mu         sync.Mutex
+	onCommit   []func(ctx context.Context) error
+	onRollback []func()
+}
+
+// NewContext returns a context carrying h, such that AfterCommit and AfterRollback register with it.
+func NewContext(ctx context.Context, h *Hooks) context.Context {
+	return context.WithValue(ctx, hooksKey{}, h)
+}
+
+// FromContext returns the Hooks carried by ctx, if any.
+func FromContext(ctx context.Context) (*Hooks, bool) {
+	h, ok := ctx.Value(hooksKey{}).(*Hooks)
+	return h, ok
+}
+
+// AfterCommit registers f to be called once the transaction carried by ctx has committed.
+// It returns false, without registering f, if ctx carries no transaction.
+func AfterCommit(ctx context.Context, f func(ctx context.Context) error) bool {
+	h, ok := FromContext(ctx)
+	if !ok {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onCommit = append(h.onCommit, f)
+	return true
+}
+
+// AfterRollback registers f to be called once the transaction carried by ctx has been rolled back.
+// It returns false, without registering f, if ctx carries no transaction.
+func AfterRollback(ctx context.Context, f func()) bool {
+	h, ok := FromContext(ctx)
+	if !ok {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onRollback = append(h.onRollback, f)
+	return true
+}
+
+// Release moves the callbacks of h into parent, eg once a nested transaction is released into it.
+func (h *Hooks) Release(parent *Hooks) {
+	onCommit, onRollback := h.take()
+	parent.mu.Lock()
+	defer parent.mu.Unlock()
+	parent.onCommit = append(parent.onCommit, onCommit...)
+	parent.onRollback = append(parent.onRollback, onRollback...)
+}
+
+// Commit calls the callbacks registered with AfterCommit in order, discarding the others.
+// All callbacks are called, and any errors are joined.
+func (h *Hooks) Commit(ctx context.Context) error {
+	onCommit, _ := h.take()
+	var errs []error
+	for _, f := range onCommit {
+		if err := f(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Rollback calls the callbacks registered with AfterRollback in order, discarding the others.
+func (h *Hooks) Rollback() {
+	_, onRollback := h.take()
+	for _, f := range onRollback {
+		f()
+	}
+}
+
+func (h *Hooks) take() ([]func(ctx context.Context) error, []func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	onCommit, onRollback := h.onCommit, h.onRollback
+	h.onCommit, h.onRollback = nil, nil
+	return onCommit, onRollback
+}
@@ -0,0 +1,68 @@
+package txhook_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/stores/txhook"
+)
+
+var errCallback = errors.New("callback failed")
+
+func TestHooks(t *testing.T) {
+	t.Parallel()
+
+	var called []string
+	onCommit := func(name string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			called = append(called, name)
+			return errCallback
+		}
+	}
+	onRollback := func(name string) func() {
+		return func() {
+			called = append(called, name)
+		}
+	}
+
+	// without hooks, nothing is registered
+	assert.False(t, txhook.AfterCommit(t.Context(), onCommit("none")))
+	assert.False(t, txhook.AfterRollback(t.Context(), onRollback("none")))
+
+	parent := &txhook.Hooks{}
+	ctx := txhook.NewContext(t.Context(), parent)
+	assert.True(t, txhook.AfterCommit(ctx, onCommit("commit parent")))
+	assert.True(t, txhook.AfterRollback(ctx, onRollback("rollback parent")))
+
+	// nested hooks which are released are merged into their parent
+	child := &txhook.Hooks{}
+	childCtx := txhook.NewContext(ctx, child)
+	assert.True(t, txhook.AfterCommit(childCtx, onCommit("commit child")))
+	assert.True(t, txhook.AfterRollback(childCtx, onRollback("rollback child")))
+	child.Release(parent)
+	require.NoError(t, child.Commit(t.Context()))
+	assert.Empty(t, called)
+
+	// commit calls all commit callbacks in order, joining their errors
+	err := parent.Commit(t.Context())
+	require.ErrorIs(t, err, errCallback)
+	assert.Equal(t, []string{"commit parent", "commit child"}, called)
+
+	// and the callbacks are only called once
+	called = nil
+	require.NoError(t, parent.Commit(t.Context()))
+	parent.Rollback()
+	assert.Empty(t, called)
+
+	// rollback calls only the rollback callbacks
+	rolledBack := &txhook.Hooks{}
+	ctx = txhook.NewContext(t.Context(), rolledBack)
+	txhook.AfterCommit(ctx, onCommit("commit"))
+	txhook.AfterRollback(ctx, onRollback("rollback"))
+	rolledBack.Rollback()
+	assert.Equal(t, []string{"rollback"}, called)
+}
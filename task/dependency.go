@@ -0,0 +1,167 @@
+package task
+
+import (
+	"errors"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// ErrIncomparableDependency is returned when a dependency is of a type which cannot be compared (use a pointer instead).
+var ErrIncomparableDependency = errors.New("dependency is not comparable")
+
+// configured is a task with settings for the Manager (see DependsOn, StopInPhase and StopWithin).
+type configured struct {
+	Task
+	dependencies    []Task
+	phase           int
+	shutdownTimeout time.Duration
+}
+
+// configure returns the task with its settings changed by f, without changing those of the given task.
+func configure(t Task, f func(c *configured)) Task {
+	c := &configured{Task: t}
+	if existing, ok := t.(*configured); ok {
+		*c = *existing
+		c.dependencies = slices.Clone(existing.dependencies)
+	}
+	f(c)
+	return c
+}
+
+// settings returns the task without its settings, along with them.
+func settings(t Task) (Task, configured) {
+	if c, ok := t.(*configured); ok {
+		return c.Task, *c
+	}
+	return t, configured{Task: t}
+}
+
+// DependsOn declares that the task depends on the given tasks, such that when run by a Manager
+// it starts only once they are all ready, and is stopped before any of them are.
+// A dependency is ready once it is Running (see Starter) or Degraded, unless it was run with RunTerminable
+// (eg a migration), in which case it is ready once it has stopped without error. If a dependency fails,
+// the task is not started.
+// The dependencies must also be run by the same Manager, before or after the task itself.
+func DependsOn(t Task, dependencies ...Task) Task {
+	return configure(t, func(c *configured) {
+		c.dependencies = append(c.dependencies, dependencies...)
+	})
+}
+
+// Dependencies returns the task without any settings (eg dependencies declared with DependsOn),
+// along with its dependencies.
+func Dependencies(t Task) (Task, []Task) {
+	t, c := settings(t)
+	return t, slices.Clone(c.dependencies)
+}
+
+// Rewrap returns the task with wrap applied to it and to each of its dependencies, keeping any settings
+// (see DependsOn, StopInPhase and StopWithin). It is intended for use by wrappers of tasks, such that
+// wrapped tasks keep their settings. So that dependencies keep their identity, wrap must return the same
+// Task each time it is given the same task.
+func Rewrap(t Task, wrap func(t Task) Task) Task {
+	inner, c := settings(t)
+	if inner == t {
+		return wrap(t)
+	}
+	return configure(wrap(inner), func(wrapped *configured) {
+		wrapped.phase = c.phase
+		wrapped.shutdownTimeout = c.shutdownTimeout
+		for _, dependency := range c.dependencies {
+			dependency, _ = Dependencies(dependency)
+			wrapped.dependencies = append(wrapped.dependencies, wrap(dependency))
+		}
+	})
+}
+
+// node tracks a task within the dependency graph of a Manager.
+type node struct {
+	// terminable tasks (see RunTerminable) are ready only once they have stopped
+	terminable      bool
+	phase           int
+	shutdownTimeout time.Duration
+
+	ready     chan struct{}
+	readyOnce sync.Once
+	done      chan struct{}
+	doneOnce  sync.Once
+
+	// guarded by Manager.statusMu
+	dependencies []*node
+	dependents   []*node
+}
+
+func newNode() *node {
+	return &node{ready: make(chan struct{}), done: make(chan struct{})}
+}
+
+func (n *node) markReady() {
+	n.readyOnce.Do(func() { close(n.ready) })
+}
+
+func (n *node) markDone() {
+	n.doneOnce.Do(func() { close(n.done) })
+}
+
+// node returns the node of the task, creating it if needed. It must be called with statusMu held.
+func (tm *Manager) node(t Task) *node {
+	if !reflect.TypeOf(t).Comparable() {
+		// Such a task cannot be a dependency, so needs no entry
+		return newNode()
+	}
+	n, ok := tm.nodes[t]
+	if !ok {
+		n = newNode()
+		tm.nodes[t] = n
+	}
+	return n
+}
+
+// addDependencies records that the task depends on the given tasks, returning an error if this would create a cycle.
+func (tm *Manager) addDependencies(t Task, n *node, dependencies []Task) error {
+	tm.statusMu.Lock()
+	defer tm.statusMu.Unlock()
+
+	for _, dependency := range dependencies {
+		dependency, _ = Dependencies(dependency)
+		if !reflect.TypeOf(dependency).Comparable() {
+			return errcontext.Add(stacktrace.Wrap(ErrIncomparableDependency), slog.String("dependency", dependency.Name()))
+		}
+		if err := tm.graph.AddEdge(dependency, t); err != nil {
+			return err
+		}
+		dn := tm.node(dependency)
+		n.dependencies = append(n.dependencies, dn)
+		dn.dependents = append(dn.dependents, n)
+	}
+	return nil
+}
+
+// awaitDependencies blocks until all dependencies of the task are ready, returning false if
+// the task should not be started because a dependency failed or the manager is stopping.
+func (tm *Manager) awaitDependencies(t Task, n *node) bool {
+	tm.statusMu.Lock()
+	dependencies := append([]*node(nil), n.dependencies...)
+	tm.statusMu.Unlock()
+
+	if len(dependencies) > 0 {
+		tm.logger.Info("task waiting for dependencies", slog.String("task", t.Name()))
+	}
+	for _, dn := range dependencies {
+		select {
+		case <-dn.ready:
+		case <-dn.done:
+			// Done without becoming ready, so it failed
+			return false
+		case <-tm.ctx.Done():
+			return false
+		}
+	}
+	return true
+}
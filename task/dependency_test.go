@@ -0,0 +1,151 @@
+package task_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"testing/synctest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/collections"
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/task"
+)
+
+// stopOrder records the order in which tasks return.
+type stopOrder struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (o *stopOrder) get() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.names
+}
+
+// orderedTask runs until its context is cancelled, then records that it stopped.
+type orderedTask struct {
+	name  string
+	order *stopOrder
+}
+
+func (t *orderedTask) Run(ctx context.Context) error {
+	<-ctx.Done()
+	t.order.mu.Lock()
+	defer t.order.mu.Unlock()
+	t.order.names = append(t.order.names, t.name)
+	return nil
+}
+
+func (t *orderedTask) Name() string {
+	return t.name
+}
+
+func TestDependsOn(t *testing.T) {
+	t.Parallel()
+	synctest.Test(t, func(t *testing.T) {
+		tm := task.NewManager(task.WithLogger(log.NewTestLogger(t)))
+
+		order := &stopOrder{}
+		db := &reportingTask{name: "db", reports: make(chan task.TaskStatus), done: make(chan struct{})}
+		bus := &orderedTask{name: "bus", order: order}
+		api := &orderedTask{name: "api", order: order}
+		worker := &orderedTask{name: "worker", order: order}
+
+		// dependents may be run before their dependencies
+		tm.Run(task.DependsOn(api, db, bus), task.DependsOn(worker, api))
+		tm.Run(bus, db)
+		synctest.Wait()
+
+		// api waits for db to report itself Running
+		assert.Equal(t, task.StatusPending, status(t, tm, "api").Status)
+		assert.Equal(t, task.StatusPending, status(t, tm, "worker").Status)
+		assert.Equal(t, task.StatusRunning, status(t, tm, "bus").Status)
+
+		db.report(task.StatusRunning)
+		synctest.Wait()
+		assert.Equal(t, task.StatusRunning, status(t, tm, "api").Status)
+		assert.Equal(t, task.StatusRunning, status(t, tm, "worker").Status)
+
+		// tasks are stopped in reverse order
+		require.NoError(t, tm.Stop())
+		assert.Equal(t, []string{"worker", "api", "bus"}, order.get())
+		assert.Equal(t, task.StatusStopped, status(t, tm, "db").Status)
+	})
+}
+
+func TestDependsOnTerminable(t *testing.T) {
+	t.Parallel()
+	synctest.Test(t, func(t *testing.T) {
+		tm := task.NewManager(task.WithLogger(log.NewTestLogger(t)))
+
+		migrate := NewTestTask("migrate", nil)
+		api := &orderedTask{name: "api", order: &stopOrder{}}
+		tm.Run(task.DependsOn(api, migrate))
+		tm.RunTerminable(migrate)
+		synctest.Wait()
+		assert.Equal(t, task.StatusPending, status(t, tm, "api").Status)
+
+		// a dependency which stops without error is ready
+		migrate.Error(nil)
+		synctest.Wait()
+		assert.Equal(t, task.StatusRunning, status(t, tm, "api").Status)
+		require.NoError(t, tm.Stop())
+	})
+}
+
+func TestDependsOnFailed(t *testing.T) {
+	t.Parallel()
+	synctest.Test(t, func(t *testing.T) {
+		tm := task.NewManager(task.WithLogger(log.NewTestLogger(t)))
+
+		migrate := NewTestTask("migrate", nil)
+		api := &orderedTask{name: "api", order: &stopOrder{}}
+		tm.Run(task.DependsOn(api, migrate))
+		tm.RunTerminable(migrate)
+		synctest.Wait()
+
+		// a task is not started if a dependency fails
+		migrate.Error(errTest)
+		require.ErrorIs(t, tm.Wait(), errTest)
+		assert.Equal(t, task.StatusFailed, status(t, tm, "migrate").Status)
+		assert.Equal(t, task.StatusStopped, status(t, tm, "api").Status)
+		assert.Empty(t, api.order.get())
+	})
+}
+
+func TestDependsOnCycle(t *testing.T) {
+	t.Parallel()
+	synctest.Test(t, func(t *testing.T) {
+		tm := task.NewManager(task.WithLogger(log.NewTestLogger(t)))
+
+		a := &orderedTask{name: "a", order: &stopOrder{}}
+		b := &orderedTask{name: "b", order: &stopOrder{}}
+		tm.Run(task.DependsOn(a, b), task.DependsOn(b, a))
+		require.ErrorIs(t, tm.Wait(), collections.ErrCycle)
+	})
+}
+
+func TestDependsOnTransitive(t *testing.T) {
+	t.Parallel()
+	synctest.Test(t, func(t *testing.T) {
+		tm := task.NewManager(task.WithLogger(log.NewTestLogger(t)))
+
+		order := &stopOrder{}
+		migrate := NewTestTask("migrate", nil)
+		api := &orderedTask{name: "api", order: order}
+		worker := &orderedTask{name: "worker", order: order}
+		tm.Run(task.DependsOn(api, migrate), task.DependsOn(worker, api))
+		tm.RunTerminable(migrate)
+		synctest.Wait()
+
+		// tasks not started are not ready, so neither are started
+		migrate.Error(errTest)
+		require.ErrorIs(t, tm.Wait(), errTest)
+		assert.Equal(t, task.StatusStopped, status(t, tm, "worker").Status)
+		assert.Empty(t, order.get())
+	})
+}
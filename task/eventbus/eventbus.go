@@ -0,0 +1,238 @@
+// Package eventbus provides an in-process publish/subscribe bus of typed events, for communication between tasks.
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const defaultBufferSize = 16
+
+// ErrBusClosed is returned by Publish once the bus has been closed.
+var ErrBusClosed = errors.New("event bus is closed")
+
+// Overflow determines what happens when an event is published to a subscription whose buffer is full.
+type Overflow int
+
+const (
+	// Block waits for the subscriber to receive the event, until the context given to Publish is done.
+	Block Overflow = iota
+	// DropNewest discards the event being published.
+	DropNewest
+	// DropOldest discards the oldest event in the buffer to make room for the event being published.
+	DropOldest
+)
+
+// subscriber is the type-independent part of a Subscription, so that the bus can close it.
+type subscriber interface {
+	Close()
+}
+
+// Bus delivers each published event to every current subscriber of its type.
+// The zero value is not usable; use NewBus.
+type Bus struct {
+	mu          sync.RWMutex
+	closed      bool
+	subscribers map[reflect.Type][]subscriber
+}
+
+// NewBus creates a Bus.
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: make(map[reflect.Type][]subscriber),
+	}
+}
+
+// Close closes every subscription, after which Publish returns ErrBusClosed and new subscriptions are closed immediately.
+// It may be registered with task.Manager.Cleanup.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	b.closed = true
+	subscribers := b.subscribers
+	b.subscribers = make(map[reflect.Type][]subscriber)
+	b.mu.Unlock()
+
+	for _, subs := range subscribers {
+		for _, sub := range subs {
+			sub.Close()
+		}
+	}
+}
+
+func (b *Bus) remove(t reflect.Type, sub subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[t] = slices.DeleteFunc(b.subscribers[t], func(s subscriber) bool { return s == sub })
+}
+
+type options struct {
+	bufferSize int
+	overflow   Overflow
+}
+
+// Option is an option func for Subscribe.
+type Option func(options *options)
+
+// WithBufferSize sets the number of events which may wait to be received by the subscriber. Defaults to 16.
+// A size of 0 means each event is handed directly to the subscriber.
+func WithBufferSize(n int) Option {
+	return func(options *options) {
+		options.bufferSize = max(n, 0)
+	}
+}
+
+// WithOverflow sets what happens when the buffer is full. Defaults to Block.
+// Subscribers which may fall behind without harm (eg metrics) should drop events rather than slow the publisher.
+func WithOverflow(overflow Overflow) Option {
+	return func(options *options) {
+		options.overflow = overflow
+	}
+}
+
+// Subscription receives the events of type T published to a Bus.
+type Subscription[T any] struct {
+	bus      *Bus
+	overflow Overflow
+	events   chan T
+	done     chan struct{}
+	dropped  atomic.Uint64
+
+	mu        sync.RWMutex // held for writing only to close events, so that it is never sent to once closed
+	closed    bool
+	closeOnce sync.Once
+	stop      func() bool
+}
+
+// Subscribe subscribes to events of exactly the type T (so subscribing to an interface type receives only events
+// published as that interface type). The subscription is closed when the context is done, or with Close.
+func Subscribe[T any](ctx context.Context, bus *Bus, opts ...Option) *Subscription[T] {
+	// Set up default options
+	options := options{
+		bufferSize: defaultBufferSize,
+		overflow:   Block,
+	}
+
+	// Apply provided options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	sub := &Subscription[T]{
+		bus:      bus,
+		overflow: options.overflow,
+		events:   make(chan T, options.bufferSize),
+		done:     make(chan struct{}),
+	}
+
+	bus.mu.Lock()
+	closed := bus.closed
+	if !closed {
+		t := reflect.TypeFor[T]()
+		bus.subscribers[t] = append(bus.subscribers[t], sub)
+	}
+	bus.mu.Unlock()
+
+	if closed {
+		sub.Close()
+		return sub
+	}
+	sub.stop = context.AfterFunc(ctx, sub.Close)
+	return sub
+}
+
+// Events returns the channel of events, which is closed when the subscription is closed.
+func (s *Subscription[T]) Events() <-chan T {
+	return s.events
+}
+
+// Dropped returns the number of events dropped because the buffer was full.
+func (s *Subscription[T]) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close unsubscribes and closes the channel of events. Events still buffered may be received before it is closed.
+func (s *Subscription[T]) Close() {
+	s.closeOnce.Do(func() {
+		if s.stop != nil {
+			s.stop()
+		}
+		s.bus.remove(reflect.TypeFor[T](), s)
+
+		// Release any publishers blocked on this subscription before closing the channel
+		close(s.done)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.closed = true
+		close(s.events)
+	})
+}
+
+// deliver sends the event according to the overflow policy.
+func (s *Subscription[T]) deliver(ctx context.Context, event T) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return nil
+	}
+
+	switch s.overflow {
+	case DropNewest:
+		select {
+		case s.events <- event:
+		default:
+			s.dropped.Add(1)
+		}
+	case DropOldest:
+		for {
+			select {
+			case s.events <- event:
+				return nil
+			default:
+			}
+			select {
+			case <-s.events:
+				s.dropped.Add(1)
+			default:
+				if cap(s.events) == 0 {
+					// Nothing is buffered to be dropped, so drop this event instead
+					s.dropped.Add(1)
+					return nil
+				}
+			}
+		}
+	default:
+		select {
+		case s.events <- event:
+		case <-s.done:
+		case <-ctx.Done():
+			return stacktrace.Wrap(ctx.Err())
+		}
+	}
+	return nil
+}
+
+// Publish delivers the event to every subscriber of its type, in the order they subscribed.
+// Depending on the overflow policy of each subscriber, it may block until the event is received,
+// in which case it returns the error of the context if it is done first.
+func Publish[T any](ctx context.Context, bus *Bus, event T) error {
+	bus.mu.RLock()
+	if bus.closed {
+		bus.mu.RUnlock()
+		return stacktrace.Wrap(ErrBusClosed)
+	}
+	subscribers := slices.Clone(bus.subscribers[reflect.TypeFor[T]()])
+	bus.mu.RUnlock()
+
+	for _, sub := range subscribers {
+		if err := sub.(*Subscription[T]).deliver(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package eventbus_test
+
+import (
+	"context"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/task/eventbus"
+)
+
+type consumed struct {
+	Stream string
+	Count  int
+}
+
+type lagging struct {
+	Stream string
+}
+
+func receive[T any](t *testing.T, sub *eventbus.Subscription[T]) []T {
+	t.Helper()
+	var events []T
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				return events
+			}
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	bus := eventbus.NewBus()
+
+	metrics := eventbus.Subscribe[consumed](ctx, bus)
+	health := eventbus.Subscribe[consumed](ctx, bus)
+	lags := eventbus.Subscribe[lagging](ctx, bus)
+
+	require.NoError(t, eventbus.Publish(ctx, bus, consumed{Stream: "blocks", Count: 1}))
+	require.NoError(t, eventbus.Publish(ctx, bus, consumed{Stream: "blocks", Count: 2}))
+	require.NoError(t, eventbus.Publish(ctx, bus, lagging{Stream: "txs"}))
+
+	// every subscriber of the type receives every event, in order
+	want := []consumed{{Stream: "blocks", Count: 1}, {Stream: "blocks", Count: 2}}
+	assert.Equal(t, want, receive(t, metrics))
+	assert.Equal(t, want, receive(t, health))
+	assert.Equal(t, []lagging{{Stream: "txs"}}, receive(t, lags))
+
+	// once closed, a subscription receives nothing more
+	metrics.Close()
+	require.NoError(t, eventbus.Publish(ctx, bus, consumed{Stream: "blocks", Count: 3}))
+	_, ok := <-metrics.Events()
+	assert.False(t, ok)
+	assert.Equal(t, []consumed{{Stream: "blocks", Count: 3}}, receive(t, health))
+
+	// nor does any once the bus is closed
+	bus.Close()
+	require.ErrorIs(t, eventbus.Publish(ctx, bus, consumed{}), eventbus.ErrBusClosed)
+	_, ok = <-health.Events()
+	assert.False(t, ok)
+	_, ok = <-eventbus.Subscribe[consumed](ctx, bus).Events()
+	assert.False(t, ok)
+}
+
+func TestSubscribeContext(t *testing.T) {
+	t.Parallel()
+
+	bus := eventbus.NewBus()
+	ctx, cancel := context.WithCancel(t.Context())
+	sub := eventbus.Subscribe[consumed](ctx, bus)
+
+	cancel()
+	require.Eventually(t, func() bool {
+		select {
+		case _, ok := <-sub.Events():
+			return !ok
+		default:
+			return false
+		}
+	}, time.Second, time.Millisecond)
+	require.NoError(t, eventbus.Publish(t.Context(), bus, consumed{}))
+}
+
+func TestOverflowBlock(t *testing.T) {
+	t.Parallel()
+	synctest.Test(t, func(t *testing.T) {
+		bus := eventbus.NewBus()
+		sub := eventbus.Subscribe[int](t.Context(), bus, eventbus.WithBufferSize(1))
+
+		require.NoError(t, eventbus.Publish(t.Context(), bus, 1))
+
+		// the publisher waits for the subscriber, until its context is done
+		ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+		defer cancel()
+		require.ErrorIs(t, eventbus.Publish(ctx, bus, 2), context.DeadlineExceeded)
+
+		published := make(chan error)
+		go func() {
+			published <- eventbus.Publish(t.Context(), bus, 3)
+		}()
+		synctest.Wait()
+		assert.Equal(t, 1, <-sub.Events())
+		require.NoError(t, <-published)
+		assert.Equal(t, 3, <-sub.Events())
+
+		// closing the subscription releases a blocked publisher
+		require.NoError(t, eventbus.Publish(t.Context(), bus, 4))
+		go func() {
+			published <- eventbus.Publish(t.Context(), bus, 5)
+		}()
+		synctest.Wait()
+		sub.Close()
+		require.NoError(t, <-published)
+		assert.Zero(t, sub.Dropped())
+	})
+}
+
+func TestOverflowDrop(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	bus := eventbus.NewBus()
+	newest := eventbus.Subscribe[int](ctx, bus, eventbus.WithBufferSize(2), eventbus.WithOverflow(eventbus.DropNewest))
+	oldest := eventbus.Subscribe[int](ctx, bus, eventbus.WithBufferSize(2), eventbus.WithOverflow(eventbus.DropOldest))
+	unbuffered := eventbus.Subscribe[int](ctx, bus, eventbus.WithBufferSize(0), eventbus.WithOverflow(eventbus.DropOldest))
+
+	for i := range 5 {
+		require.NoError(t, eventbus.Publish(ctx, bus, i))
+	}
+
+	assert.Equal(t, []int{0, 1}, receive(t, newest))
+	assert.Equal(t, uint64(3), newest.Dropped())
+	assert.Equal(t, []int{3, 4}, receive(t, oldest))
+	assert.Equal(t, uint64(3), oldest.Dropped())
+	assert.Empty(t, receive(t, unbuffered))
+	assert.Equal(t, uint64(5), unbuffered.Dropped())
+}
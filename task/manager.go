@@ -1,11 +1,15 @@
 package task
 
 import (
+	"cmp"
 	"context"
 	"log/slog"
 	"slices"
+	"sync"
+	"time"
 
 	"github.com/zircuit-labs/zkr-go-common/calm/errgroup"
+	"github.com/zircuit-labs/zkr-go-common/collections"
 	"github.com/zircuit-labs/zkr-go-common/log"
 )
 
@@ -17,10 +21,22 @@ type Manager struct {
 	group   *errgroup.Group
 	logger  *slog.Logger
 	cleanup []func()
+
+	taskContext     bool
+	shutdownTimeout time.Duration
+
+	statusMu    sync.Mutex
+	statuses    []*taskStatus
+	statusHooks []StatusHook
+	nodes       map[Task]*node
+	graph       *collections.DAG[Task]
 }
 
 type options struct {
-	logger *slog.Logger
+	logger          *slog.Logger
+	taskContext     bool
+	shutdownTimeout time.Duration
+	statusHooks     []StatusHook
 }
 
 // Option is an option func for NewManager.
@@ -33,6 +49,15 @@ func WithLogger(logger *slog.Logger) Option {
 	}
 }
 
+// WithTaskContext gives each task a context carrying its name and a new run ID,
+// such that logs written with that context (eg logger.InfoContext) include them.
+// See log.ContextWithTask.
+func WithTaskContext() Option {
+	return func(options *options) {
+		options.taskContext = true
+	}
+}
+
 // NewManager creates a Manager.
 func NewManager(opts ...Option) *Manager {
 	// Set up default options
@@ -46,15 +71,23 @@ func NewManager(opts ...Option) *Manager {
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
-	return &Manager{
+	tm := &Manager{
 		ctx:    ctx,
 		cancel: cancel,
 		group:  errgroup.New(),
 		logger: options.logger,
+
+		taskContext:     options.taskContext,
+		shutdownTimeout: options.shutdownTimeout,
+		statusHooks:     options.statusHooks,
+		nodes:           make(map[Task]*node),
+		graph:           collections.NewDAG[Task](),
 	}
+	context.AfterFunc(ctx, tm.stopping)
+	return tm
 }
 
-// Run immediately starts all of the given tasks.
+// Run immediately starts all of the given tasks, except those waiting for dependencies (see DependsOn).
 func (tm *Manager) Run(tasks ...Task) {
 	for _, task := range tasks {
 		t := task // local for closure
@@ -65,8 +98,8 @@ func (tm *Manager) Run(tasks ...Task) {
 	}
 }
 
-// Run immediately starts all of the given tasks. These tasks are expected to
-// to terminate without error, while others continue running.
+// Run immediately starts all of the given tasks, except those waiting for dependencies (see DependsOn).
+// These tasks are expected to terminate without error, while others continue running.
 func (tm *Manager) RunTerminable(tasks ...Task) {
 	for _, task := range tasks {
 		t := task // local for closure
@@ -102,13 +135,56 @@ func (tm *Manager) Stop() error {
 }
 
 func (tm *Manager) runTask(t Task, terminateAll bool) func() error {
+	t, c := settings(t)
+	ts := tm.track(t)
+	ts.node.terminable = !terminateAll
+	ts.node.phase = c.phase
+	ts.node.shutdownTimeout = cmp.Or(c.shutdownTimeout, tm.shutdownTimeout)
+	err := tm.addDependencies(t, ts.node, c.dependencies)
 	return func() error {
+		defer ts.node.markDone()
+		if err != nil {
+			tm.logger.Error("task failed", slog.String("task", t.Name()), log.ErrAttr(err))
+			tm.fail(ts, err)
+			tm.cancel()
+			return err
+		}
+
+		// Each task is stopped in its turn (see StopInPhase and DependsOn)
+		ctx, cancel := context.WithCancel(context.WithoutCancel(tm.ctx))
+		defer cancel()
+		tm.stopInOrder(ts, cancel)
+
+		if !tm.awaitDependencies(t, ts.node) {
+			tm.logger.Info("task not started", slog.String("task", t.Name()))
+			tm.setStatus(ts, StatusStopped, "", func(TaskStatus) bool { return true })
+			return nil
+		}
+
+		ctx = context.WithValue(ctx, taskStatusKey{}, ts)
+		if tm.taskContext {
+			ctx = log.ContextWithTask(ctx, t.Name())
+		}
 		tm.logger.Info("task starting", slog.String("task", t.Name()))
-		if err := t.Run(tm.ctx); err != nil {
+		tm.setStatus(ts, StatusStarting, "", func(current TaskStatus) bool {
+			return current == StatusPending
+		})
+		if _, ok := t.(Starter); !ok {
+			tm.setStatus(ts, StatusRunning, "", func(current TaskStatus) bool {
+				return current == StatusStarting
+			})
+		}
+
+		// Recover a panic here (rather than leaving it to calm/errgroup) so that the task is marked Failed
+		err := runWithin(ctx, t, ts.node.shutdownTimeout)
+		if err != nil {
 			tm.logger.Error("task failed", slog.String("task", t.Name()), log.ErrAttr(err))
+			tm.fail(ts, err)
 			tm.cancel()
 			return err
 		}
+		tm.setStatus(ts, StatusStopped, "", func(TaskStatus) bool { return true })
+		ts.node.markReady()
 
 		if terminateAll {
 			// when the task completes, regardless of why, cancel the context
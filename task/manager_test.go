@@ -185,3 +185,36 @@ func TestTaskManagerRunTerminable(t *testing.T) {
 		assert.Equal(t, []int{2, 1}, cleanupCheck)
 	})
 }
+
+type contextTask struct {
+	name  string
+	tasks chan log.TaskInfo
+}
+
+func (t contextTask) Run(ctx context.Context) error {
+	info, _ := log.TaskFromContext(ctx)
+	t.tasks <- info
+	return nil
+}
+
+func (t contextTask) Name() string {
+	return t.name
+}
+
+func TestTaskManagerTaskContext(t *testing.T) {
+	t.Parallel()
+
+	tasks := make(chan log.TaskInfo, 2)
+	tm := task.NewManager(task.WithLogger(log.NewTestLogger(t)), task.WithTaskContext())
+	tm.RunTerminable(contextTask{name: "a", tasks: tasks}, contextTask{name: "b", tasks: tasks})
+	assert.NoError(t, tm.Stop())
+	close(tasks)
+
+	names := map[string]uint64{}
+	for info := range tasks {
+		names[info.Name] = info.Run
+	}
+	assert.Len(t, names, 2)
+	assert.NotZero(t, names["a"])
+	assert.NotEqual(t, names["a"], names["b"])
+}
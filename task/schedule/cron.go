@@ -0,0 +1,239 @@
+package schedule
+
+import (
+	"errors"
+	"log/slog"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// ErrInvalidCron is returned when a cron expression cannot be parsed.
+var ErrInvalidCron = errors.New("invalid cron expression")
+
+// maxSearchYears bounds the search for the next matching time of expressions which rarely (or never) match,
+// such as "0 0 30 2 *".
+const maxSearchYears = 5
+
+// Schedule determines when a Task runs.
+type Schedule interface {
+	// Next returns the first time after t at which to run, or the zero time if there is none.
+	Next(t time.Time) time.Time
+}
+
+// Cron is a Schedule given by a standard 5 field cron expression: minute, hour, day of month, month and day of week.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// If both day fields are restricted, a day matching either is matched (as per standard cron)
+	domRestricted, dowRestricted bool
+}
+
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is also accepted as Sunday
+	dowField = field{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// descriptors are shorthands for common expressions.
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a standard 5 field cron expression (eg "*/15 9-17 * * MON-FRI"), or one of the descriptors
+// @yearly, @monthly, @weekly, @daily and @hourly. Fields may be lists of values, ranges and steps,
+// and months and days of the week may be given by their first three letters.
+func ParseCron(expr string) (*Cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) == 1 {
+		if d, ok := descriptors[strings.ToLower(fields[0])]; ok {
+			fields = strings.Fields(d)
+		}
+	}
+	if len(fields) != 5 {
+		return nil, invalidCron(expr, "expected 5 fields")
+	}
+
+	var c Cron
+	var err error
+	parsers := []struct {
+		bits  *uint64
+		field field
+	}{
+		{&c.minute, minuteField},
+		{&c.hour, hourField},
+		{&c.dom, domField},
+		{&c.month, monthField},
+		{&c.dow, dowField},
+	}
+	for i, p := range parsers {
+		if *p.bits, err = p.field.parse(fields[i]); err != nil {
+			return nil, invalidCron(expr, err.Error())
+		}
+	}
+	// Sunday may be given as 0 or 7
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domRestricted = fields[2] != "*" && fields[2] != "?"
+	c.dowRestricted = fields[4] != "*" && fields[4] != "?"
+	return &c, nil
+}
+
+func invalidCron(expr, reason string) error {
+	err := errcontext.Add(stacktrace.Wrap(ErrInvalidCron), slog.String("expression", expr), slog.String("reason", reason))
+	return errclass.WrapAs(err, errclass.Persistent)
+}
+
+// parse returns the values of the field as a bit set.
+func (f field) parse(s string) (uint64, error) {
+	var set uint64
+	for part := range strings.SplitSeq(s, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n < 1 {
+				return 0, errors.New(f.name + ": invalid step " + strconv.Quote(stepPart))
+			}
+			step = n
+		}
+
+		low, high := f.min, f.max
+		switch {
+		case rangePart == "*" || rangePart == "?":
+		case strings.Contains(rangePart, "-"):
+			lowPart, highPart, _ := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = f.value(lowPart); err != nil {
+				return 0, err
+			}
+			if high, err = f.value(highPart); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, errors.New(f.name + ": invalid range " + strconv.Quote(rangePart))
+			}
+		default:
+			var err error
+			if low, err = f.value(rangePart); err != nil {
+				return 0, err
+			}
+			// "n/step" means from n to the maximum
+			if !hasStep {
+				high = low
+			}
+		}
+
+		for v := low; v <= high; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// value parses a single value of the field.
+func (f field) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, errors.New(f.name + ": invalid value " + strconv.Quote(s))
+	}
+	return v, nil
+}
+
+// Next implements Schedule. Times are matched in the location of t.
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		if !has(c.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !has(c.hour, t.Hour()) {
+			next := time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			if !next.After(t) {
+				// Repeated hour when daylight saving time ends
+				next = t.Add(time.Hour - time.Duration(t.Minute())*time.Minute)
+			}
+			t = next
+			continue
+		}
+		if !has(c.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c *Cron) matchesDay(t time.Time) bool {
+	dom := has(c.dom, t.Day())
+	dow := has(c.dow, int(t.Weekday()))
+	if c.domRestricted && c.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+func has(set uint64, v int) bool {
+	return set&(1<<v) != 0
+}
+
+// String returns the expression matching the same times, in a canonical form.
+func (c *Cron) String() string {
+	return strings.Join([]string{
+		format(c.minute, minuteField),
+		format(c.hour, hourField),
+		format(c.dom, domField),
+		format(c.month, monthField),
+		format(c.dow&^(1<<7), field{min: 0, max: 6}),
+	}, " ")
+}
+
+func format(set uint64, f field) string {
+	if bits.OnesCount64(set) == f.max-f.min+1 {
+		return "*"
+	}
+	var values []string
+	for v := f.min; v <= f.max; v++ {
+		if has(set, v) {
+			values = append(values, strconv.Itoa(v))
+		}
+	}
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,127 @@
+package schedule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/task/schedule"
+)
+
+func TestParseCron(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		expr     string
+		expected string
+	}{
+		{"* * * * *", "* * * * *"},
+		{"*/15 9-17 * * MON-FRI", "0,15,30,45 9,10,11,12,13,14,15,16,17 * * 1,2,3,4,5"},
+		{"5,10 0 1 jan,jul 7", "5,10 0 1 1,7 0"},
+		{"0 12 * * 1-5/2", "0 12 * * 1,3,5"},
+		{"30 2/6 * * *", "30 2,8,14,20 * * *"},
+		{"@daily", "0 0 * * *"},
+		{"@hourly", "0 * * * *"},
+		{"0 0 ? * SUN", "0 0 * * 0"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			t.Parallel()
+			cron, err := schedule.ParseCron(tc.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, cron.String())
+		})
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *",
+		"* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@never"} {
+		t.Run("invalid "+expr, func(t *testing.T) {
+			t.Parallel()
+			_, err := schedule.ParseCron(expr)
+			require.ErrorIs(t, err, schedule.ErrInvalidCron)
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	t.Parallel()
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	date := func(loc *time.Location, year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, loc)
+	}
+
+	testCases := []struct {
+		name     string
+		expr     string
+		from     time.Time
+		expected time.Time
+	}{
+		{
+			name:     "next minute",
+			expr:     "* * * * *",
+			from:     date(time.UTC, 2025, 1, 1, 12, 0).Add(30 * time.Second),
+			expected: date(time.UTC, 2025, 1, 1, 12, 1),
+		},
+		{
+			name:     "strictly after",
+			expr:     "0 12 * * *",
+			from:     date(time.UTC, 2025, 1, 1, 12, 0),
+			expected: date(time.UTC, 2025, 1, 2, 12, 0),
+		},
+		{
+			name:     "weekday",
+			expr:     "0 9 * * MON-FRI",
+			from:     date(time.UTC, 2025, 1, 3, 10, 0), // Friday
+			expected: date(time.UTC, 2025, 1, 6, 9, 0),
+		},
+		{
+			name:     "day of month or week",
+			expr:     "0 0 15 * MON",
+			from:     date(time.UTC, 2025, 1, 7, 0, 0), // Tuesday
+			expected: date(time.UTC, 2025, 1, 13, 0, 0),
+		},
+		{
+			name:     "leap day",
+			expr:     "0 0 29 2 *",
+			from:     date(time.UTC, 2025, 1, 1, 0, 0),
+			expected: date(time.UTC, 2028, 2, 29, 0, 0),
+		},
+		{
+			name:     "time zone",
+			expr:     "0 9 * * *",
+			from:     date(newYork, 2025, 1, 1, 10, 0),
+			expected: date(newYork, 2025, 1, 2, 9, 0),
+		},
+		{
+			name:     "daylight saving time starts",
+			expr:     "30 2 * * *", // 2:30 does not exist on 9 March
+			from:     date(newYork, 2025, 3, 9, 0, 0),
+			expected: date(newYork, 2025, 3, 10, 2, 30),
+		},
+		{
+			name:     "daylight saving time ends",
+			expr:     "0 3 * * *",
+			from:     date(newYork, 2025, 11, 2, 1, 30),
+			expected: date(newYork, 2025, 11, 2, 3, 0),
+		},
+		{
+			name:     "never",
+			expr:     "0 0 30 2 *",
+			from:     date(time.UTC, 2025, 1, 1, 0, 0),
+			expected: time.Time{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			cron, err := schedule.ParseCron(tc.expr)
+			require.NoError(t, err)
+			next := cron.Next(tc.from)
+			assert.True(t, tc.expected.Equal(next), "expected %s, got %s", tc.expected, next)
+		})
+	}
+}
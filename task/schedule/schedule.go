@@ -0,0 +1,178 @@
+// Package schedule provides a Task that executes a function at times given by a cron expression.
+package schedule
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/task/polling"
+)
+
+// MissedRunPolicy determines what happens when a scheduled time passes while the action is still running
+// (or the process is suspended).
+type MissedRunPolicy int
+
+const (
+	// MissedRunSkip skips missed runs, waiting for the next scheduled time (default).
+	MissedRunSkip MissedRunPolicy = iota
+	// MissedRunImmediately runs once immediately, however many runs were missed.
+	MissedRunImmediately
+)
+
+// Task runs the action at the times given by its Schedule.
+type Task struct {
+	name     string
+	schedule Schedule
+	action   polling.Action
+	opts     options
+}
+
+type options struct {
+	location         *time.Location
+	jitter           time.Duration
+	missedRunPolicy  MissedRunPolicy
+	terminateOnError bool
+	logger           *slog.Logger
+}
+
+// Option is an option func for NewTask.
+type Option func(options *options)
+
+// WithLogger sets the logger to be used.
+func WithLogger(logger *slog.Logger) Option {
+	return func(options *options) {
+		options.logger = logger
+	}
+}
+
+// WithLocation sets the time zone in which the schedule is evaluated. Defaults to UTC.
+func WithLocation(loc *time.Location) Option {
+	return func(options *options) {
+		options.location = loc
+	}
+}
+
+// WithJitter delays each run by a random duration in [0, d), such that
+// instances sharing a schedule do not all run at exactly the same time.
+func WithJitter(d time.Duration) Option {
+	return func(options *options) {
+		options.jitter = d
+	}
+}
+
+// WithMissedRunPolicy sets what happens when scheduled times are missed. Defaults to MissedRunSkip.
+func WithMissedRunPolicy(policy MissedRunPolicy) Option {
+	return func(options *options) {
+		options.missedRunPolicy = policy
+	}
+}
+
+// WithTerminateOnError causes the task to exit with an error if the
+// action returns an error (by default it just logs an error).
+func WithTerminateOnError() Option {
+	return func(options *options) {
+		options.terminateOnError = true
+	}
+}
+
+// NewTask creates a new Task running the action according to the schedule (see ParseCron).
+// The action is the same as that of a polling task.
+func NewTask(name string, schedule Schedule, action polling.Action, opts ...Option) *Task {
+	// Set up default options
+	options := options{
+		location: time.UTC,
+		logger:   log.NewNilLogger(),
+	}
+
+	// Apply provided options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &Task{
+		name:     name,
+		schedule: schedule,
+		action:   action,
+		opts:     options,
+	}
+}
+
+// NewCronTask creates a new Task running the action at the times given by the cron expression.
+func NewCronTask(name string, expr string, action polling.Action, opts ...Option) (*Task, error) {
+	cron, err := ParseCron(expr)
+	if err != nil {
+		return nil, err
+	}
+	return NewTask(name, cron, action, opts...), nil
+}
+
+// Name returns the name of this task.
+func (t *Task) Name() string {
+	return t.name
+}
+
+// Run executes the task. It returns nil once the context is cancelled, or if the schedule has no more times.
+func (t *Task) Run(ctx context.Context) error {
+	defer t.action.Cleanup()
+
+	next := t.schedule.Next(t.now())
+	for !next.IsZero() {
+		timer := time.NewTimer(time.Until(next) + t.jitter())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+		// Runs which are due immediately may race with cancellation
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if err := t.executeAction(ctx); err != nil {
+			return err
+		}
+
+		now := t.now()
+		following := t.schedule.Next(next)
+		if !following.IsZero() && !following.After(now) {
+			t.opts.logger.Warn("scheduled run missed", slog.String("task", t.Name()), slog.Time("scheduled", following))
+			if t.opts.missedRunPolicy == MissedRunImmediately {
+				following = now
+			} else {
+				following = t.schedule.Next(now)
+			}
+		}
+		next = following
+	}
+	return nil
+}
+
+func (t *Task) now() time.Time {
+	return time.Now().In(t.opts.location)
+}
+
+func (t *Task) jitter() time.Duration {
+	if t.opts.jitter <= 0 {
+		return 0
+	}
+	return rand.N(t.opts.jitter)
+}
+
+func (t *Task) executeAction(ctx context.Context) error {
+	if err := t.action.Run(ctx); err != nil {
+		if errors.Is(err, context.Canceled) {
+			return nil
+		} else if t.opts.terminateOnError {
+			return err
+		}
+		// Don't return the error so that the task will not terminate,
+		// however still log this as an error for appropriate visibility.
+		t.opts.logger.Error("scheduled action failed", log.ErrAttr(err), slog.String("task", t.Name()))
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package schedule_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/task/schedule"
+)
+
+var errTest = errors.New("example error")
+
+type testAction struct {
+	Err           error
+	Duration      time.Duration
+	Runs          []time.Time
+	CleanupCalled bool
+}
+
+func (a *testAction) Run(_ context.Context) error {
+	a.Runs = append(a.Runs, time.Now())
+	time.Sleep(a.Duration)
+	return a.Err
+}
+
+func (a *testAction) Cleanup() {
+	a.CleanupCalled = true
+}
+
+// runFor runs the task for the duration, returning the error from Run.
+func runFor(t *testing.T, task *schedule.Task, d time.Duration) error {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(t.Context(), d)
+	defer cancel()
+	return task.Run(ctx)
+}
+
+// offsets returns the times relative to start.
+func offsets(start time.Time, times []time.Time) []time.Duration {
+	result := make([]time.Duration, len(times))
+	for i, t := range times {
+		result[i] = t.Sub(start)
+	}
+	return result
+}
+
+func TestTask(t *testing.T) {
+	t.Parallel()
+
+	synctest.Test(t, func(t *testing.T) {
+		// synctest starts at midnight UTC
+		start := time.Now()
+		action := &testAction{Err: errTest}
+		task, err := schedule.NewCronTask("every 15 minutes", "*/15 * * * *", action)
+		require.NoError(t, err)
+		assert.Equal(t, "every 15 minutes", task.Name())
+
+		// errors are ignored by default
+		require.NoError(t, runFor(t, task, 59*time.Minute))
+		assert.Equal(t, []time.Duration{15 * time.Minute, 30 * time.Minute, 45 * time.Minute}, offsets(start, action.Runs))
+		assert.True(t, action.CleanupCalled)
+	})
+}
+
+func TestTaskLocation(t *testing.T) {
+	t.Parallel()
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+
+	synctest.Test(t, func(t *testing.T) {
+		// 09:00 in Tokyo is 00:00 UTC
+		start := time.Now()
+		action := &testAction{}
+		task, err := schedule.NewCronTask("daily", "0 10 * * *", action, schedule.WithLocation(tokyo))
+		require.NoError(t, err)
+
+		require.NoError(t, runFor(t, task, 2*time.Hour))
+		assert.Equal(t, []time.Duration{time.Hour}, offsets(start, action.Runs))
+	})
+}
+
+func TestTaskMissedRuns(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		policy   schedule.MissedRunPolicy
+		expected []time.Duration
+	}{
+		{
+			name:     "skip",
+			policy:   schedule.MissedRunSkip,
+			expected: []time.Duration{10 * time.Minute, 30 * time.Minute},
+		},
+		{
+			name:     "immediately",
+			policy:   schedule.MissedRunImmediately,
+			expected: []time.Duration{10 * time.Minute, 25 * time.Minute, 40 * time.Minute},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			synctest.Test(t, func(t *testing.T) {
+				start := time.Now()
+				// each run takes 15 minutes, missing the next run 5 minutes later
+				action := &testAction{Duration: 15 * time.Minute}
+				task, err := schedule.NewCronTask("slow", "*/10 * * * *", action, schedule.WithMissedRunPolicy(tc.policy))
+				require.NoError(t, err)
+
+				require.NoError(t, runFor(t, task, 45*time.Minute))
+				assert.Equal(t, tc.expected, offsets(start, action.Runs))
+			})
+		})
+	}
+}
+
+func TestTaskJitter(t *testing.T) {
+	t.Parallel()
+
+	synctest.Test(t, func(t *testing.T) {
+		start := time.Now()
+		action := &testAction{}
+		task, err := schedule.NewCronTask("hourly", "@hourly", action, schedule.WithJitter(time.Minute))
+		require.NoError(t, err)
+
+		require.NoError(t, runFor(t, task, 5*time.Hour-time.Second))
+		require.Len(t, action.Runs, 4)
+		for i, offset := range offsets(start, action.Runs) {
+			scheduled := time.Duration(i+1) * time.Hour
+			assert.GreaterOrEqual(t, offset, scheduled)
+			assert.Less(t, offset, scheduled+time.Minute)
+		}
+	})
+}
+
+func TestTaskTerminateOnError(t *testing.T) {
+	t.Parallel()
+
+	synctest.Test(t, func(t *testing.T) {
+		action := &testAction{Err: errTest}
+		task, err := schedule.NewCronTask("failing", "* * * * *", action, schedule.WithTerminateOnError())
+		require.NoError(t, err)
+
+		require.ErrorIs(t, runFor(t, task, time.Hour), errTest)
+		assert.Len(t, action.Runs, 1)
+		assert.True(t, action.CleanupCalled)
+	})
+}
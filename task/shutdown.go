@@ -0,0 +1,108 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/zircuit-labs/zkr-go-common/calm"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// ErrShutdownTimeout is returned when a task does not return within its shutdown timeout (see StopWithin).
+var ErrShutdownTimeout = errors.New("task exceeded shutdown timeout")
+
+// StopInPhase sets the phase in which the task is stopped, such that when the Manager stops,
+// tasks in lower phases (eg HTTP listeners) have returned before those in higher phases (eg consumers, then producers)
+// are stopped. Tasks are in phase 0 unless set otherwise.
+// The dependencies of a task (see DependsOn) are always stopped after it, so are in effect in the same or a later phase.
+func StopInPhase(t Task, phase int) Task {
+	return configure(t, func(c *configured) {
+		c.phase = phase
+	})
+}
+
+// StopWithin limits the time the task may take to return once it is stopped, overriding WithShutdownTimeout.
+// A task exceeding it is abandoned (its goroutine cannot be forcibly stopped), and fails with ErrShutdownTimeout.
+func StopWithin(t Task, timeout time.Duration) Task {
+	return configure(t, func(c *configured) {
+		c.shutdownTimeout = timeout
+	})
+}
+
+// WithShutdownTimeout limits the time each task may take to return once it is stopped, unless set otherwise
+// with StopWithin. By default there is no limit.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(options *options) {
+		options.shutdownTimeout = timeout
+	}
+}
+
+// effectivePhase returns the phase in which the task is stopped, being no earlier than that of any of its dependents.
+// It must be called with statusMu held.
+func effectivePhase(n *node) int {
+	phase := n.phase
+	for _, dn := range n.dependents {
+		phase = max(phase, effectivePhase(dn))
+	}
+	return phase
+}
+
+// stopInOrder cancels the task context once the manager is stopping, all of the dependents of the task have returned,
+// and all of the tasks in earlier phases have returned. As such, tasks are stopped in phase order, and within a phase
+// in the reverse order to which their dependencies allowed them to start.
+func (tm *Manager) stopInOrder(ts *taskStatus, cancel context.CancelFunc) {
+	context.AfterFunc(tm.ctx, func() {
+		tm.statusMu.Lock()
+		awaited := append([]*node(nil), ts.node.dependents...)
+		phase := effectivePhase(ts.node)
+		for _, other := range tm.statuses {
+			if effectivePhase(other.node) < phase {
+				awaited = append(awaited, other.node)
+			}
+		}
+		tm.statusMu.Unlock()
+
+		for _, n := range awaited {
+			<-n.done
+		}
+		// Ensure the task is seen to be Stopping before it can return
+		tm.setStatus(ts, StatusStopping, "", func(current TaskStatus) bool {
+			return !current.done()
+		})
+		cancel()
+	})
+}
+
+// runWithin runs the task, returning ErrShutdownTimeout if it does not return within the timeout once ctx is done.
+func runWithin(ctx context.Context, t Task, timeout time.Duration) error {
+	if timeout <= 0 {
+		return calm.Unpanic(func() error {
+			return t.Run(ctx)
+		})
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- calm.Unpanic(func() error {
+			return t.Run(ctx)
+		})
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-result:
+		return err
+	case <-timer.C:
+		return errcontext.Add(stacktrace.Wrap(ErrShutdownTimeout), slog.String("task", t.Name()), slog.Duration("timeout", timeout))
+	}
+}
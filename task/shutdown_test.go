@@ -0,0 +1,113 @@
+package task_test
+
+import (
+	"context"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/task"
+)
+
+func TestStopInPhase(t *testing.T) {
+	t.Parallel()
+	synctest.Test(t, func(t *testing.T) {
+		tm := task.NewManager(task.WithLogger(log.NewTestLogger(t)))
+
+		order := &stopOrder{}
+		listener := &orderedTask{name: "listener", order: order}
+		consumer := &orderedTask{name: "consumer", order: order}
+		producer := &orderedTask{name: "producer", order: order}
+		metrics := &orderedTask{name: "metrics", order: order}
+
+		tm.Run(
+			task.StopInPhase(producer, 2),
+			task.StopInPhase(consumer, 1),
+			task.DependsOn(&orderedTask{name: "cache", order: order}, producer),
+			// the listener is stopped after its dependent, despite its phase
+			listener,
+			task.StopInPhase(task.DependsOn(metrics, listener), 3),
+		)
+		synctest.Wait()
+
+		require.NoError(t, tm.Stop())
+		assert.Equal(t, []string{"cache", "consumer", "producer", "metrics", "listener"}, order.get())
+	})
+}
+
+// stubbornTask ignores the cancellation of its context until released.
+type stubbornTask struct {
+	release chan struct{}
+}
+
+func (t *stubbornTask) Run(context.Context) error {
+	<-t.release
+	return nil
+}
+
+func (t *stubbornTask) Name() string {
+	return "stubborn"
+}
+
+func TestStopWithin(t *testing.T) {
+	t.Parallel()
+	synctest.Test(t, func(t *testing.T) {
+		tm := task.NewManager(task.WithLogger(log.NewTestLogger(t)), task.WithShutdownTimeout(time.Minute))
+
+		stubborn := &stubbornTask{release: make(chan struct{})}
+		defer close(stubborn.release)
+		tm.Run(task.StopWithin(stubborn, 5*time.Second), NewTestTask("prompt", nil))
+
+		// the task is abandoned once it exceeds its own timeout
+		start := time.Now()
+		err := tm.Stop()
+		require.ErrorIs(t, err, task.ErrShutdownTimeout)
+		assert.Equal(t, 5*time.Second, time.Since(start))
+		assert.Equal(t, task.StatusFailed, status(t, tm, "stubborn").Status)
+		assert.Equal(t, task.StatusStopped, status(t, tm, "prompt").Status)
+	})
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	t.Parallel()
+	synctest.Test(t, func(t *testing.T) {
+		tm := task.NewManager(task.WithLogger(log.NewTestLogger(t)), task.WithShutdownTimeout(time.Minute))
+
+		stubborn := &stubbornTask{release: make(chan struct{})}
+		defer close(stubborn.release)
+		tm.Run(stubborn)
+
+		start := time.Now()
+		require.ErrorIs(t, tm.Stop(), task.ErrShutdownTimeout)
+		assert.Equal(t, time.Minute, time.Since(start))
+	})
+}
+
+func TestRewrap(t *testing.T) {
+	t.Parallel()
+
+	a := NewTestTask("a", nil)
+	b := NewTestTask("b", nil)
+	wrappers := map[task.Task]task.Task{}
+	wrap := func(t task.Task) task.Task {
+		if _, ok := wrappers[t]; !ok {
+			wrappers[t] = &orderedTask{name: t.Name() + " (wrapped)"}
+		}
+		return wrappers[t]
+	}
+
+	// plain tasks are simply wrapped
+	rewrapped := task.Rewrap(b, wrap)
+	assert.Equal(t, wrappers[b], rewrapped)
+
+	// as are dependencies, keeping the settings of the task
+	rewrapped = task.Rewrap(task.StopInPhase(task.DependsOn(a, b), 1), wrap)
+	inner, dependencies := task.Dependencies(rewrapped)
+	assert.Equal(t, wrappers[a], inner)
+	assert.Equal(t, []task.Task{wrappers[b]}, dependencies)
+	assert.Equal(t, "a (wrapped)", rewrapped.Name())
+}
@@ -0,0 +1,215 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+var ErrUnknownStatus = errors.New("unknown task status")
+
+// TaskStatus is the state of a task run by a Manager.
+type TaskStatus int
+
+// A task is Pending until the manager runs it, then Starting and Running (see Starter). While running,
+// a task may report itself Degraded and Running again (see SetStatus). Once the manager begins to stop,
+// tasks are Stopping, and are finally Stopped, or Failed if they returned an error.
+const (
+	StatusPending TaskStatus = iota
+	StatusStarting
+	StatusRunning
+	StatusDegraded
+	StatusStopping
+	StatusStopped
+	StatusFailed
+)
+
+// String implements fmt.Stringer.
+func (s TaskStatus) String() string {
+	switch s {
+	case StatusPending:
+		return "pending"
+	case StatusStarting:
+		return "starting"
+	case StatusRunning:
+		return "running"
+	case StatusDegraded:
+		return "degraded"
+	case StatusStopping:
+		return "stopping"
+	case StatusStopped:
+		return "stopped"
+	case StatusFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler, such that statuses are encoded by name.
+func (s TaskStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, such that statuses are decoded by name.
+func (s *TaskStatus) UnmarshalText(text []byte) error {
+	for status := StatusPending; status <= StatusFailed; status++ {
+		if status.String() == string(text) {
+			*s = status
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownStatus, text)
+}
+
+// done returns true if the task has returned.
+func (s TaskStatus) done() bool {
+	return s == StatusStopped || s == StatusFailed
+}
+
+// Starter may be implemented by tasks which take time to start. The manager considers such tasks
+// Starting until they report themselves Running with SetStatus, rather than as soon as they are run.
+type Starter interface {
+	Task
+	ReportsRunning()
+}
+
+// TaskState is a snapshot of the status of a task.
+type TaskState struct {
+	Name   string     `json:"name"`
+	Status TaskStatus `json:"status"`
+	// Reason is the reason given for being Degraded, or the error of a Failed task.
+	Reason string `json:"reason,omitempty"`
+	// Class is the class of the error of a Failed task (see errclass).
+	Class string    `json:"class,omitempty"`
+	Since time.Time `json:"since"`
+}
+
+// StatusHook is called whenever the status of a task changes, along with its previous status.
+// Hooks may be called concurrently for different tasks.
+type StatusHook func(state TaskState, previous TaskStatus)
+
+// WithStatusHook adds a hook called whenever the status of a task changes.
+func WithStatusHook(hook StatusHook) Option {
+	return func(options *options) {
+		options.statusHooks = append(options.statusHooks, hook)
+	}
+}
+
+// taskStatus tracks the status of a single task.
+type taskStatus struct {
+	tm    *Manager
+	node  *node
+	state TaskState // guarded by tm.statusMu
+}
+
+type taskStatusKey struct{}
+
+// SetStatus reports the status of the task given ctx by its Manager: StatusRunning once a Starter
+// has started or a Degraded task has recovered, or StatusDegraded with a reason when the task is
+// impaired but still running. Other statuses, and calls once the manager has begun to stop, are ignored.
+func SetStatus(ctx context.Context, status TaskStatus, reason string) {
+	ts, ok := ctx.Value(taskStatusKey{}).(*taskStatus)
+	if !ok || (status != StatusRunning && status != StatusDegraded) {
+		return
+	}
+	if status == StatusRunning {
+		reason = ""
+	}
+	ts.tm.setStatus(ts, status, reason, func(current TaskStatus) bool {
+		return current == StatusStarting || current == StatusRunning || current == StatusDegraded
+	})
+}
+
+// Statuses returns a snapshot of the status of every task run by the manager, in the order they were run.
+func (tm *Manager) Statuses() []TaskState {
+	tm.statusMu.Lock()
+	defer tm.statusMu.Unlock()
+
+	states := make([]TaskState, 0, len(tm.statuses))
+	for _, ts := range tm.statuses {
+		states = append(states, ts.state)
+	}
+	return states
+}
+
+// track registers a new Pending task.
+func (tm *Manager) track(t Task) *taskStatus {
+	tm.statusMu.Lock()
+	defer tm.statusMu.Unlock()
+
+	ts := &taskStatus{
+		tm:    tm,
+		node:  tm.node(t),
+		state: TaskState{Name: t.Name(), Status: StatusPending, Since: time.Now()},
+	}
+	tm.statuses = append(tm.statuses, ts)
+	return ts
+}
+
+// stopping marks all tasks which have not yet returned as Stopping.
+func (tm *Manager) stopping() {
+	tm.statusMu.Lock()
+	statuses := append([]*taskStatus(nil), tm.statuses...)
+	tm.statusMu.Unlock()
+
+	for _, ts := range statuses {
+		tm.setStatus(ts, StatusStopping, "", func(current TaskStatus) bool {
+			return !current.done()
+		})
+	}
+}
+
+// fail marks the task as Failed with the error as its reason.
+func (tm *Manager) fail(ts *taskStatus, err error) {
+	tm.statusMu.Lock()
+	ts.state.Class = errclass.GetClass(err).String()
+	tm.statusMu.Unlock()
+	tm.setStatus(ts, StatusFailed, err.Error(), func(TaskStatus) bool { return true })
+}
+
+// setStatus changes the status of the task if allowed, then logs the change and calls the hooks.
+func (tm *Manager) setStatus(ts *taskStatus, status TaskStatus, reason string, allowed func(current TaskStatus) bool) {
+	tm.statusMu.Lock()
+	previous := ts.state.Status
+	if !allowed(previous) || (previous == status && ts.state.Reason == reason) {
+		tm.statusMu.Unlock()
+		return
+	}
+	ts.state.Status = status
+	ts.state.Reason = reason
+	ts.state.Since = time.Now()
+	state := ts.state
+	tm.statusMu.Unlock()
+
+	// Release any tasks waiting on this one (see DependsOn)
+	if (status == StatusRunning || status == StatusDegraded) && !ts.node.terminable {
+		ts.node.markReady()
+	}
+
+	// Routine changes are already logged by runTask
+	level := slog.LevelDebug
+	switch {
+	case status == StatusDegraded || status == StatusFailed:
+		level = slog.LevelWarn
+	case previous == StatusDegraded:
+		level = slog.LevelInfo
+	}
+	attrs := []slog.Attr{
+		slog.String("task", state.Name),
+		slog.String("status", status.String()),
+		slog.String("previous", previous.String()),
+	}
+	if reason != "" {
+		attrs = append(attrs, slog.String("reason", reason))
+	}
+	tm.logger.LogAttrs(context.Background(), level, "task status changed", attrs...)
+
+	for _, hook := range tm.statusHooks {
+		hook(state, previous)
+	}
+}
@@ -0,0 +1,164 @@
+package task_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/task"
+)
+
+// reportingTask is a Starter which reports its status as instructed.
+type reportingTask struct {
+	name    string
+	reports chan task.TaskStatus
+	done    chan struct{}
+}
+
+func (t *reportingTask) Run(ctx context.Context) error {
+	defer close(t.done)
+	for {
+		select {
+		case status := <-t.reports:
+			task.SetStatus(ctx, status, "rpc unavailable")
+			t.done <- struct{}{}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (t *reportingTask) Name() string {
+	return t.name
+}
+
+func (t *reportingTask) ReportsRunning() {}
+
+func (t *reportingTask) report(status task.TaskStatus) {
+	t.reports <- status
+	<-t.done
+}
+
+type statusRecorder struct {
+	mu      sync.Mutex
+	changes map[string][]task.TaskStatus
+}
+
+func (r *statusRecorder) hook(state task.TaskState, _ task.TaskStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes[state.Name] = append(r.changes[state.Name], state.Status)
+}
+
+func (r *statusRecorder) get(name string) []task.TaskStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.changes[name]
+}
+
+func status(t *testing.T, tm *task.Manager, name string) task.TaskState {
+	t.Helper()
+	for _, state := range tm.Statuses() {
+		if state.Name == name {
+			return state
+		}
+	}
+	require.FailNow(t, "task not found", name)
+	return task.TaskState{}
+}
+
+func TestTaskStatus(t *testing.T) {
+	t.Parallel()
+
+	recorder := &statusRecorder{changes: map[string][]task.TaskStatus{}}
+	tm := task.NewManager(task.WithLogger(log.NewTestLogger(t)), task.WithStatusHook(recorder.hook))
+
+	starter := &reportingTask{name: "starter", reports: make(chan task.TaskStatus), done: make(chan struct{})}
+	plain := NewTestTask("plain", nil)
+	failing := NewTestTask("failing", errTest)
+	tm.Run(starter, plain)
+	tm.RunTerminable(failing)
+
+	// tasks are listed in the order they were run
+	names := []string{}
+	for _, state := range tm.Statuses() {
+		names = append(names, state.Name)
+	}
+	assert.Equal(t, []string{"starter", "plain", "failing"}, names)
+
+	// a Starter remains Starting until it reports itself Running, and may then become Degraded
+	assert.Eventually(t, func() bool {
+		return status(t, tm, "plain").Status == task.StatusRunning
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, task.StatusStarting, status(t, tm, "starter").Status)
+	starter.report(task.StatusRunning)
+	assert.Equal(t, task.StatusRunning, status(t, tm, "starter").Status)
+	starter.report(task.StatusDegraded)
+	degraded := status(t, tm, "starter")
+	assert.Equal(t, task.StatusDegraded, degraded.Status)
+	assert.Equal(t, "rpc unavailable", degraded.Reason)
+
+	// tasks may not report other statuses
+	starter.report(task.StatusStopped)
+	assert.Equal(t, task.StatusDegraded, status(t, tm, "starter").Status)
+
+	// a task returning an error fails, stopping the others
+	failing.Error(errTest)
+	require.ErrorIs(t, tm.Wait(), errTest)
+
+	failed := status(t, tm, "failing")
+	assert.Equal(t, task.StatusFailed, failed.Status)
+	assert.Contains(t, failed.Reason, errTest.Error())
+	assert.Equal(t, task.StatusStopped, status(t, tm, "starter").Status)
+	assert.Equal(t, task.StatusStopped, status(t, tm, "plain").Status)
+
+	assert.Equal(t, []task.TaskStatus{
+		task.StatusStarting, task.StatusRunning, task.StatusDegraded, task.StatusStopping, task.StatusStopped,
+	}, recorder.get("starter"))
+	assert.Equal(t, []task.TaskStatus{
+		task.StatusStarting, task.StatusRunning, task.StatusStopping, task.StatusStopped,
+	}, recorder.get("plain"))
+}
+
+func TestTaskStatusPanic(t *testing.T) {
+	t.Parallel()
+
+	tm := task.NewManager(task.WithLogger(log.NewTestLogger(t)))
+	tm.Run(panicTask{})
+	require.Error(t, tm.Wait())
+	assert.Equal(t, task.StatusFailed, status(t, tm, "panic").Status)
+}
+
+type panicTask struct{}
+
+func (panicTask) Run(context.Context) error {
+	panic("boom")
+}
+
+func (panicTask) Name() string {
+	return "panic"
+}
+
+func TestTaskStatusJSON(t *testing.T) {
+	t.Parallel()
+
+	b, err := json.Marshal(task.TaskState{Name: "consumer", Status: task.StatusDegraded, Reason: "lagging"})
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"status":"degraded"`)
+
+	var decoded task.TaskState
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, task.StatusDegraded, decoded.Status)
+
+	for status := task.StatusPending; status <= task.StatusFailed; status++ {
+		assert.NotEqual(t, "unknown", status.String())
+	}
+	assert.Equal(t, "unknown", task.TaskStatus(-1).String())
+	require.ErrorIs(t, json.Unmarshal([]byte(`{"status":"unknown"}`), &decoded), task.ErrUnknownStatus)
+}
@@ -0,0 +1,53 @@
+package watchdog
+
+import (
+	"log/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/zircuit-labs/zkr-go-common/metrics"
+)
+
+type watchdogMetrics struct {
+	usage    *prometheus.GaugeVec
+	exceeded *prometheus.CounterVec
+}
+
+func registerWatchdogMetrics(registerer prometheus.Registerer) (*watchdogMetrics, error) {
+	usage, err := metrics.Register(registerer, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "watchdog_resource_usage",
+		Help: "Most recent sample of each resource used by the process.",
+	}, []string{"resource"}))
+	if err != nil {
+		return nil, err
+	}
+	exceeded, err := metrics.Register(registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "watchdog_limit_exceeded_total",
+		Help: "Number of samples exceeding the warn or hard limit of each resource.",
+	}, []string{"resource", "level"}))
+	if err != nil {
+		return nil, err
+	}
+	return &watchdogMetrics{usage: usage, exceeded: exceeded}, nil
+}
+
+// The following are no-ops when metrics are not enabled.
+
+func (m *watchdogMetrics) record(s Sample) {
+	if m == nil {
+		return
+	}
+	m.usage.WithLabelValues(ResourceRSS).Set(float64(s.RSS))
+	m.usage.WithLabelValues(ResourceGoroutines).Set(float64(s.Goroutines))
+	m.usage.WithLabelValues(ResourceGCPause).Set(s.GCPause.Seconds())
+	m.usage.WithLabelValues(ResourceOpenFDs).Set(float64(s.OpenFDs))
+}
+
+func (m *watchdogMetrics) recordExceeded(attrs []slog.Attr, level string) {
+	if m == nil {
+		return
+	}
+	for _, attr := range attrs {
+		m.exceeded.WithLabelValues(attr.Key, level).Inc()
+	}
+}
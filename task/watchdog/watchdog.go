@@ -0,0 +1,265 @@
+// Package watchdog provides a Task that monitors the resource usage of the process,
+// protecting long-running services from slow leaks.
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const defaultInterval = time.Second * 30
+
+// ErrLimitExceeded is returned by Run when a hard limit is exceeded.
+var ErrLimitExceeded = errors.New("resource hard limit exceeded")
+
+// Resource names, used in logs and as the "resource" metric label.
+const (
+	ResourceRSS        = "rss_bytes"
+	ResourceGoroutines = "goroutines"
+	ResourceGCPause    = "gc_pause_seconds"
+	ResourceOpenFDs    = "open_fds"
+)
+
+// Sample is a measurement of the resource usage of the process.
+// Resources which cannot be measured on the platform (eg RSS and open file descriptors outside of Linux) are zero.
+type Sample struct {
+	RSS        uint64
+	Goroutines int
+	// GCPause is the longest garbage collection pause since the previous sample.
+	GCPause time.Duration
+	OpenFDs int
+}
+
+// Limits are thresholds on resource usage. Zero values are not checked.
+type Limits struct {
+	RSS        uint64
+	Goroutines int
+	GCPause    time.Duration
+	OpenFDs    int
+}
+
+// exceeded returns the resources of the sample which exceed the limits.
+func (l Limits) exceeded(s Sample) []slog.Attr {
+	var attrs []slog.Attr
+	if l.RSS > 0 && s.RSS > l.RSS {
+		attrs = append(attrs, slog.Uint64(ResourceRSS, s.RSS))
+	}
+	if l.Goroutines > 0 && s.Goroutines > l.Goroutines {
+		attrs = append(attrs, slog.Int(ResourceGoroutines, s.Goroutines))
+	}
+	if l.GCPause > 0 && s.GCPause > l.GCPause {
+		attrs = append(attrs, slog.Float64(ResourceGCPause, s.GCPause.Seconds()))
+	}
+	if l.OpenFDs > 0 && s.OpenFDs > l.OpenFDs {
+		attrs = append(attrs, slog.Int(ResourceOpenFDs, s.OpenFDs))
+	}
+	return attrs
+}
+
+// Sampler measures the resource usage of the process.
+type Sampler interface {
+	Sample() Sample
+}
+
+type options struct {
+	interval   time.Duration
+	warn       Limits
+	hard       Limits
+	registerer prometheus.Registerer
+	sampler    Sampler
+	logger     *slog.Logger
+}
+
+// Option is an option func for NewTask.
+type Option func(options *options)
+
+// WithLogger sets the logger to be used.
+func WithLogger(logger *slog.Logger) Option {
+	return func(options *options) {
+		options.logger = logger
+	}
+}
+
+// WithInterval sets how often resource usage is sampled. Defaults to 30 seconds.
+// If the duration is less than or equal to zero, the option will be ignored.
+func WithInterval(d time.Duration) Option {
+	return func(options *options) {
+		if d <= 0 {
+			return
+		}
+		options.interval = d
+	}
+}
+
+// WithWarnLimits logs a warning for each sample exceeding the limits.
+func WithWarnLimits(limits Limits) Option {
+	return func(options *options) {
+		options.warn = limits
+	}
+}
+
+// WithHardLimits stops the task with ErrLimitExceeded when a sample exceeds the limits,
+// which stops the other tasks of the task manager, so that the service restarts gracefully.
+func WithHardLimits(limits Limits) Option {
+	return func(options *options) {
+		options.hard = limits
+	}
+}
+
+// WithMetrics exports each sample as the gauge "watchdog_resource_usage" labeled by resource,
+// and counts samples exceeding limits as "watchdog_limit_exceeded_total" labeled by resource and level.
+func WithMetrics(registerer prometheus.Registerer) Option {
+	return func(options *options) {
+		options.registerer = registerer
+	}
+}
+
+// WithSampler overrides how resource usage is measured, eg for testing.
+func WithSampler(sampler Sampler) Option {
+	return func(options *options) {
+		options.sampler = sampler
+	}
+}
+
+// Task periodically samples resource usage, checking it against the limits.
+type Task struct {
+	opts    options
+	metrics *watchdogMetrics
+}
+
+// NewTask creates a new watchdog Task.
+func NewTask(opts ...Option) (*Task, error) {
+	// Set up default options
+	options := options{
+		interval: defaultInterval,
+		sampler:  NewRuntimeSampler(),
+		logger:   log.NewNilLogger(),
+	}
+
+	// Apply provided options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	t := &Task{opts: options}
+	if options.registerer != nil {
+		metrics, err := registerWatchdogMetrics(options.registerer)
+		if err != nil {
+			return nil, err
+		}
+		t.metrics = metrics
+	}
+	return t, nil
+}
+
+// Name returns the name of this task.
+func (t *Task) Name() string {
+	return "watchdog task"
+}
+
+// Run executes the task.
+func (t *Task) Run(ctx context.Context) error {
+	ticker := time.NewTicker(t.opts.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := t.check(t.opts.sampler.Sample()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// check records the sample, and returns ErrLimitExceeded if it exceeds the hard limits.
+func (t *Task) check(sample Sample) error {
+	t.metrics.record(sample)
+
+	if exceeded := t.opts.hard.exceeded(sample); len(exceeded) > 0 {
+		t.metrics.recordExceeded(exceeded, "hard")
+		t.opts.logger.Error("resource hard limit exceeded, stopping", slog.Any("exceeded", slog.GroupValue(exceeded...)))
+		return errcontext.Add(
+			errclass.WrapAs(stacktrace.Wrap(ErrLimitExceeded), errclass.Persistent),
+			slog.Any("exceeded", slog.GroupValue(exceeded...)),
+		)
+	}
+	if exceeded := t.opts.warn.exceeded(sample); len(exceeded) > 0 {
+		t.metrics.recordExceeded(exceeded, "warn")
+		t.opts.logger.Warn("resource warning limit exceeded", slog.Any("exceeded", slog.GroupValue(exceeded...)))
+	}
+	return nil
+}
+
+// RuntimeSampler measures the resource usage of the current process.
+// It is not safe for concurrent use, as it tracks garbage collections between samples.
+type RuntimeSampler struct {
+	lastNumGC uint32
+}
+
+// NewRuntimeSampler creates a RuntimeSampler.
+func NewRuntimeSampler() *RuntimeSampler {
+	return &RuntimeSampler{}
+}
+
+// Sample implements Sampler.
+func (s *RuntimeSampler) Sample() Sample {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	// PauseNs is a circular buffer of the most recent pauses
+	var pause uint64
+	for n := max(s.lastNumGC, stats.NumGC-min(stats.NumGC, uint32(len(stats.PauseNs)))); n < stats.NumGC; n++ {
+		pause = max(pause, stats.PauseNs[n%uint32(len(stats.PauseNs))])
+	}
+	s.lastNumGC = stats.NumGC
+
+	return Sample{
+		RSS:        readRSS(),
+		Goroutines: runtime.NumGoroutine(),
+		GCPause:    time.Duration(pause), //nolint:gosec // pauses are far below the maximum duration
+		OpenFDs:    countOpenFDs(),
+	}
+}
+
+// readRSS returns the resident set size from procfs, or 0 if unavailable.
+func readRSS() uint64 {
+	b, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) < 2 {
+		return 0
+	}
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return pages * uint64(os.Getpagesize()) //nolint:gosec // page size is positive
+}
+
+// countOpenFDs returns the number of open file descriptors from procfs, or 0 if unavailable.
+func countOpenFDs() int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return 0
+	}
+	// reading the directory itself uses a descriptor
+	return max(len(entries)-1, 0)
+}
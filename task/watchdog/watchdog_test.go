@@ -0,0 +1,119 @@
+package watchdog_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/task/watchdog"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+// fakeSampler returns each of its samples in turn, then repeats the last.
+type fakeSampler struct {
+	mu      sync.Mutex
+	samples []watchdog.Sample
+	calls   int
+}
+
+func (s *fakeSampler) Sample() watchdog.Sample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sample := s.samples[min(s.calls, len(s.samples)-1)]
+	s.calls++
+	return sample
+}
+
+func TestWatchdog(t *testing.T) {
+	t.Parallel()
+
+	synctest.Test(t, func(t *testing.T) {
+		healthy := watchdog.Sample{RSS: 100, Goroutines: 10, GCPause: time.Millisecond, OpenFDs: 5}
+		leaking := watchdog.Sample{RSS: 100, Goroutines: 60, GCPause: time.Millisecond, OpenFDs: 5}
+		sampler := &fakeSampler{samples: []watchdog.Sample{healthy, leaking, leaking, healthy}}
+
+		buf := &bytes.Buffer{}
+		registry := prometheus.NewRegistry()
+		task, err := watchdog.NewTask(
+			watchdog.WithInterval(time.Second),
+			watchdog.WithSampler(sampler),
+			watchdog.WithWarnLimits(watchdog.Limits{Goroutines: 50, OpenFDs: 50}),
+			watchdog.WithHardLimits(watchdog.Limits{Goroutines: 1000}),
+			watchdog.WithMetrics(registry),
+			watchdog.WithLogger(slog.New(slog.NewJSONHandler(buf, nil))),
+		)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(t.Context())
+		done := make(chan error)
+		go func() {
+			done <- task.Run(ctx)
+		}()
+
+		time.Sleep(time.Second*4 + time.Millisecond)
+		synctest.Wait()
+		cancel()
+		require.NoError(t, <-done)
+		assert.Equal(t, 4, sampler.calls)
+
+		// exceeding warn limits is logged and counted, but does not stop the task
+		assert.Equal(t, 2, strings.Count(buf.String(), `"msg":"resource warning limit exceeded","exceeded":{"goroutines":60}`))
+		expected := `
+# HELP watchdog_limit_exceeded_total Number of samples exceeding the warn or hard limit of each resource.
+# TYPE watchdog_limit_exceeded_total counter
+watchdog_limit_exceeded_total{level="warn",resource="goroutines"} 2
+# HELP watchdog_resource_usage Most recent sample of each resource used by the process.
+# TYPE watchdog_resource_usage gauge
+watchdog_resource_usage{resource="gc_pause_seconds"} 0.001
+watchdog_resource_usage{resource="goroutines"} 10
+watchdog_resource_usage{resource="open_fds"} 5
+watchdog_resource_usage{resource="rss_bytes"} 100
+`
+		require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected)))
+	})
+}
+
+func TestWatchdogHardLimit(t *testing.T) {
+	t.Parallel()
+
+	synctest.Test(t, func(t *testing.T) {
+		sampler := &fakeSampler{samples: []watchdog.Sample{{RSS: 100}, {RSS: 2000}}}
+		task, err := watchdog.NewTask(
+			watchdog.WithInterval(time.Second),
+			watchdog.WithSampler(sampler),
+			watchdog.WithHardLimits(watchdog.Limits{RSS: 1000}),
+		)
+		require.NoError(t, err)
+
+		// the task stops itself on the second sample
+		err = task.Run(t.Context())
+		require.ErrorIs(t, err, watchdog.ErrLimitExceeded)
+		assert.Equal(t, errclass.Persistent, errclass.GetClass(err))
+		assert.Equal(t, 2, sampler.calls)
+	})
+}
+
+func TestRuntimeSampler(t *testing.T) {
+	t.Parallel()
+
+	sampler := watchdog.NewRuntimeSampler()
+	runtime.GC()
+	sample := sampler.Sample()
+	assert.Positive(t, sample.Goroutines)
+	assert.Positive(t, sample.GCPause)
+	if runtime.GOOS == "linux" {
+		assert.Positive(t, sample.RSS)
+		assert.Positive(t, sample.OpenFDs)
+	}
+}
@@ -0,0 +1,263 @@
+// Package workerpool provides a Task that runs jobs across a fixed number of workers.
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/zircuit-labs/zkr-go-common/calm"
+	"github.com/zircuit-labs/zkr-go-common/log"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const defaultDrainTimeout = 30 * time.Second
+
+// ErrPoolClosed is returned by Submit once the pool has stopped accepting jobs.
+var ErrPoolClosed = errors.New("worker pool is closed")
+
+// Handler handles a single job.
+type Handler[T any] func(ctx context.Context, job T) error
+
+// Retrier retries a func according to the class of its error (see retry.Retrier).
+type Retrier interface {
+	Try(ctx context.Context, f func() error) error
+}
+
+type options struct {
+	workers      int
+	queueSize    int
+	jobTimeout   time.Duration
+	drainTimeout time.Duration
+	retrier      Retrier
+	logger       *slog.Logger
+}
+
+// Option is an option func for NewPool and NewPoolFromChannel.
+type Option func(options *options)
+
+// WithLogger sets the logger to be used.
+func WithLogger(logger *slog.Logger) Option {
+	return func(options *options) {
+		options.logger = logger
+	}
+}
+
+// WithWorkers sets the number of jobs run at once. Defaults to GOMAXPROCS.
+// If n is less than 1, the option will be ignored.
+func WithWorkers(n int) Option {
+	return func(options *options) {
+		if n < 1 {
+			return
+		}
+		options.workers = n
+	}
+}
+
+// WithQueueSize sets the number of submitted jobs which may wait for a worker
+// before Submit blocks. Defaults to the number of workers.
+func WithQueueSize(n int) Option {
+	return func(options *options) {
+		options.queueSize = max(n, 0)
+	}
+}
+
+// WithJobTimeout limits the time each attempt of a job may take. By default there is no limit.
+func WithJobTimeout(d time.Duration) Option {
+	return func(options *options) {
+		options.jobTimeout = d
+	}
+}
+
+// WithDrainTimeout limits the time spent finishing queued jobs after the pool is stopped. Defaults to 30 seconds.
+// Jobs still running when it expires have their context cancelled, and jobs not yet started are dropped.
+func WithDrainTimeout(d time.Duration) Option {
+	return func(options *options) {
+		options.drainTimeout = d
+	}
+}
+
+// WithRetrier retries failed jobs (see retry.Retrier), such that errors classed as transient are retried
+// and those classed as persistent are not. By default, each job is attempted once.
+func WithRetrier(retrier Retrier) Option {
+	return func(options *options) {
+		options.retrier = retrier
+	}
+}
+
+// Pool is a Task which runs jobs across a fixed number of workers.
+// Jobs are given to the pool via Submit, or read from a channel (see NewPoolFromChannel).
+// Panics in the handler are recovered as errors, and jobs which fail are logged.
+// When the context given to Run is cancelled, the pool stops accepting jobs,
+// and finishes those already queued (see WithDrainTimeout) before returning.
+type Pool[T any] struct {
+	name    string
+	handler Handler[T]
+	source  <-chan T
+	queue   chan T
+	opts    options
+
+	mu       sync.RWMutex
+	closed   bool
+	stopping chan struct{}
+}
+
+// NewPool creates a new Pool running the handler for each submitted job.
+func NewPool[T any](name string, handler Handler[T], opts ...Option) *Pool[T] {
+	return NewPoolFromChannel(name, nil, handler, opts...)
+}
+
+// NewPoolFromChannel creates a new Pool running the handler for each job received from the channel,
+// as well as each submitted job. The pool continues to run after the channel is closed.
+func NewPoolFromChannel[T any](name string, jobs <-chan T, handler Handler[T], opts ...Option) *Pool[T] {
+	// Set up default options
+	options := options{
+		workers:      runtime.GOMAXPROCS(0),
+		queueSize:    -1,
+		drainTimeout: defaultDrainTimeout,
+		logger:       log.NewNilLogger(),
+	}
+
+	// Apply provided options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.queueSize < 0 {
+		options.queueSize = options.workers
+	}
+
+	return &Pool[T]{
+		name:     name,
+		handler:  handler,
+		source:   jobs,
+		queue:    make(chan T, options.queueSize),
+		opts:     options,
+		stopping: make(chan struct{}),
+	}
+}
+
+// Name returns the name of this task.
+func (p *Pool[T]) Name() string {
+	return p.name
+}
+
+// Submit queues the job, blocking until there is room in the queue.
+// It returns ErrPoolClosed if the pool has stopped, or the error of the context if it is done first.
+func (p *Pool[T]) Submit(ctx context.Context, job T) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return errclass.WrapAs(stacktrace.Wrap(ErrPoolClosed), errclass.Persistent)
+	}
+	select {
+	case p.queue <- job:
+		return nil
+	case <-p.stopping:
+		return errclass.WrapAs(stacktrace.Wrap(ErrPoolClosed), errclass.Persistent)
+	case <-ctx.Done():
+		return stacktrace.Wrap(ctx.Err())
+	}
+}
+
+// Run executes the task. It must only be called once.
+func (p *Pool[T]) Run(ctx context.Context) error {
+	// Jobs are not cancelled as soon as the pool is stopped, but only once draining times out
+	jobCtx, cancelJobs := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelJobs()
+
+	var wg sync.WaitGroup
+	drain := make(chan struct{})
+	for range p.opts.workers {
+		wg.Go(func() {
+			p.work(ctx, jobCtx, drain)
+		})
+	}
+
+	<-ctx.Done()
+
+	// Stop accepting jobs, waiting for any Submit in progress to finish
+	close(p.stopping)
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	close(drain)
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(p.opts.drainTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		p.opts.logger.Warn("timed out draining worker pool - cancelling jobs",
+			slog.String("task", p.Name()), slog.Int("dropped", len(p.queue)))
+		cancelJobs()
+		<-done
+	}
+	return nil
+}
+
+// work runs jobs until the pool is stopped, then runs any remaining queued jobs until draining times out.
+func (p *Pool[T]) work(ctx, jobCtx context.Context, drain <-chan struct{}) {
+	source := p.source
+	for {
+		select {
+		case job, ok := <-source:
+			if !ok {
+				source = nil
+				continue
+			}
+			p.run(jobCtx, job)
+		case job := <-p.queue:
+			p.run(jobCtx, job)
+		case <-drain:
+			for {
+				select {
+				case <-jobCtx.Done():
+					return
+				case job := <-p.queue:
+					p.run(jobCtx, job)
+				default:
+					return
+				}
+			}
+		case <-ctx.Done():
+			// Wait for the signal to drain, so that no submitted job is missed
+			<-drain
+		}
+	}
+}
+
+// run handles a single job, logging any failure.
+func (p *Pool[T]) run(ctx context.Context, job T) {
+	attempt := func() error {
+		return calm.Unpanic(func() error {
+			attemptCtx := ctx
+			if p.opts.jobTimeout > 0 {
+				var cancel context.CancelFunc
+				attemptCtx, cancel = context.WithTimeout(ctx, p.opts.jobTimeout)
+				defer cancel()
+			}
+			return p.handler(attemptCtx, job)
+		})
+	}
+
+	var err error
+	if p.opts.retrier != nil {
+		err = p.opts.retrier.Try(ctx, attempt)
+	} else {
+		err = attempt()
+	}
+	if err != nil {
+		p.opts.logger.Error("job failed", log.ErrAttr(err), slog.String("task", p.Name()))
+	}
+}
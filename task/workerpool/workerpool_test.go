@@ -0,0 +1,193 @@
+package workerpool_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/retry"
+	"github.com/zircuit-labs/zkr-go-common/task/workerpool"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+)
+
+var errTest = errors.New("example error")
+
+// runPool runs the pool in the background, returning a func which stops it and returns the error from Run.
+func runPool[T any](t *testing.T, pool *workerpool.Pool[T]) func() error {
+	t.Helper()
+	ctx, cancel := context.WithCancel(t.Context())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- pool.Run(ctx)
+	}()
+	return func() error {
+		cancel()
+		return <-errCh
+	}
+}
+
+func TestPool(t *testing.T) {
+	t.Parallel()
+
+	synctest.Test(t, func(t *testing.T) {
+		var active, maxActive atomic.Int32
+		var mu sync.Mutex
+		handled := map[int]bool{}
+		handler := func(_ context.Context, job int) error {
+			n := active.Add(1)
+			defer active.Add(-1)
+			for {
+				m := maxActive.Load()
+				if n <= m || maxActive.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Second)
+			if job%5 == 0 {
+				panic("bad job")
+			}
+			mu.Lock()
+			handled[job] = true
+			mu.Unlock()
+			return nil
+		}
+
+		jobs := make(chan int)
+		pool := workerpool.NewPoolFromChannel("pool", jobs, handler, workerpool.WithWorkers(3))
+		assert.Equal(t, "pool", pool.Name())
+		stop := runPool(t, pool)
+
+		for i := 1; i <= 10; i++ {
+			if i%2 == 0 {
+				jobs <- i
+			} else {
+				require.NoError(t, pool.Submit(t.Context(), i))
+			}
+		}
+		close(jobs)
+		synctest.Wait()
+		time.Sleep(time.Minute)
+
+		require.NoError(t, stop())
+		assert.Equal(t, int32(3), maxActive.Load())
+		// panicking jobs do not stop the pool
+		assert.Equal(t, map[int]bool{1: true, 2: true, 3: true, 4: true, 6: true, 7: true, 8: true, 9: true}, handled)
+
+		err := pool.Submit(t.Context(), 11)
+		require.ErrorIs(t, err, workerpool.ErrPoolClosed)
+	})
+}
+
+func TestPoolRetries(t *testing.T) {
+	t.Parallel()
+
+	synctest.Test(t, func(t *testing.T) {
+		retrier, err := retry.NewRetrier(retry.WithMaxAttempts(5))
+		require.NoError(t, err)
+
+		var attempts sync.Map
+		handler := func(_ context.Context, job string) error {
+			n, _ := attempts.LoadOrStore(job, new(atomic.Int32))
+			count := n.(*atomic.Int32).Add(1)
+			switch job {
+			case "transient":
+				if count < 3 {
+					return errclass.WrapAs(errTest, errclass.Transient)
+				}
+				return nil
+			default:
+				return errclass.WrapAs(errTest, errclass.Persistent)
+			}
+		}
+
+		pool := workerpool.NewPool("pool", handler, workerpool.WithRetrier(retrier))
+		stop := runPool(t, pool)
+		require.NoError(t, pool.Submit(t.Context(), "transient"))
+		require.NoError(t, pool.Submit(t.Context(), "persistent"))
+		time.Sleep(time.Hour)
+		require.NoError(t, stop())
+
+		count := func(job string) int32 {
+			n, ok := attempts.Load(job)
+			require.True(t, ok)
+			return n.(*atomic.Int32).Load()
+		}
+		assert.Equal(t, int32(3), count("transient"))
+		assert.Equal(t, int32(1), count("persistent"))
+	})
+}
+
+func TestPoolJobTimeout(t *testing.T) {
+	t.Parallel()
+
+	synctest.Test(t, func(t *testing.T) {
+		var took atomic.Int64
+		handler := func(ctx context.Context, _ int) error {
+			start := time.Now()
+			<-ctx.Done()
+			took.Store(int64(time.Since(start)))
+			return ctx.Err()
+		}
+
+		pool := workerpool.NewPool("pool", handler, workerpool.WithJobTimeout(time.Second))
+		stop := runPool(t, pool)
+		require.NoError(t, pool.Submit(t.Context(), 1))
+		time.Sleep(time.Minute)
+		require.NoError(t, stop())
+
+		assert.Equal(t, time.Second, time.Duration(took.Load()))
+	})
+}
+
+func TestPoolDrain(t *testing.T) {
+	t.Parallel()
+
+	synctest.Test(t, func(t *testing.T) {
+		var handled atomic.Int32
+		handler := func(ctx context.Context, _ int) error {
+			select {
+			case <-time.After(time.Second):
+				handled.Add(1)
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		// 1 running and 4 queued, of which 3 finish within the drain timeout
+		pool := workerpool.NewPool("pool", handler,
+			workerpool.WithWorkers(1),
+			workerpool.WithQueueSize(4),
+			workerpool.WithDrainTimeout(3500*time.Millisecond),
+		)
+		stop := runPool(t, pool)
+		for i := range 5 {
+			require.NoError(t, pool.Submit(t.Context(), i))
+		}
+		synctest.Wait()
+
+		start := time.Now()
+		require.NoError(t, stop())
+		assert.Equal(t, 3500*time.Millisecond, time.Since(start))
+		assert.Equal(t, int32(3), handled.Load())
+	})
+}
+
+func TestSubmitContext(t *testing.T) {
+	t.Parallel()
+
+	// without Run, the queue fills up
+	pool := workerpool.NewPool("pool", func(context.Context, int) error { return nil }, workerpool.WithQueueSize(1))
+	require.NoError(t, pool.Submit(t.Context(), 1))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, pool.Submit(ctx, 2), context.DeadlineExceeded)
+}
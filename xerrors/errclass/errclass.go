@@ -49,6 +49,9 @@ func (c Class) LogValue() slog.Value {
 	)
 }
 
+// ExternallySafe marks Class as safe to expose to external clients (see xerrors.Sanitize).
+func (c Class) ExternallySafe() {}
+
 // WrapAs extends an error with the given class data.
 func WrapAs(err error, class Class) error {
 	if err == nil {
@@ -0,0 +1,226 @@
+package errcode
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcontext"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const defaultLocale = "en"
+
+var (
+	ErrInvalidCatalog = errors.New("invalid message catalog")
+	ErrUnknownCode    = errors.New("no message for error code")
+)
+
+type options struct {
+	defaultLocale string
+}
+
+// Option is an option func for NewCatalog.
+type Option func(options *options)
+
+// WithDefaultLocale sets the locale used when no requested locale is supported. Defaults to "en".
+func WithDefaultLocale(locale string) Option {
+	return func(options *options) {
+		options.defaultLocale = locale
+	}
+}
+
+// Catalog holds the message templates of each error code for each locale.
+// It is safe for concurrent use.
+type Catalog struct {
+	// locales maps the lower case locale to its messages
+	locales       map[string]map[Code]*template.Template
+	names         []string
+	defaultLocale string
+}
+
+// NewCatalog loads a catalog from the JSON files in the root of fsys (typically an embed.FS), one per locale
+// and named for it (eg "en.json", "pt-BR.json"), each mapping codes to text/template messages:
+//
+//	{"account.insufficient_funds": "Your balance of {{.balance}} is too low."}
+//
+// The default locale must be present, and should include every code.
+func NewCatalog(fsys fs.FS, opts ...Option) (*Catalog, error) {
+	options := options{
+		defaultLocale: defaultLocale,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	files, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+	}
+
+	c := &Catalog{
+		locales:       make(map[string]map[Code]*template.Template, len(files)),
+		defaultLocale: strings.ToLower(options.defaultLocale),
+	}
+	for _, file := range files {
+		locale := strings.TrimSuffix(path.Base(file), ".json")
+		messages, err := loadMessages(fsys, file)
+		if err != nil {
+			return nil, errcontext.Add(err, slog.String("file", file))
+		}
+		c.locales[strings.ToLower(locale)] = messages
+		c.names = append(c.names, locale)
+	}
+
+	if _, ok := c.locales[c.defaultLocale]; !ok {
+		return nil, errcontext.Add(
+			errclass.WrapAs(stacktrace.Wrap(ErrInvalidCatalog), errclass.Persistent),
+			slog.String("default_locale", options.defaultLocale),
+		)
+	}
+	return c, nil
+}
+
+func loadMessages(fsys fs.FS, file string) (map[Code]*template.Template, error) {
+	b, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return nil, errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+	}
+	var raw map[Code]string
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, errclass.WrapAs(stacktrace.Wrap(errors.Join(ErrInvalidCatalog, err)), errclass.Persistent)
+	}
+
+	messages := make(map[Code]*template.Template, len(raw))
+	for code, text := range raw {
+		tmpl, err := template.New(string(code)).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, errcontext.Add(
+				errclass.WrapAs(stacktrace.Wrap(errors.Join(ErrInvalidCatalog, err)), errclass.Persistent),
+				slog.String("code", string(code)),
+			)
+		}
+		messages[code] = tmpl
+	}
+	return messages, nil
+}
+
+// Locales returns the locales of the catalog, as named by their files.
+func (c *Catalog) Locales() []string {
+	return slices.Clone(c.names)
+}
+
+// Match returns the supported locale best matching the Accept-Language header value
+// (eg "fr-CH, fr;q=0.9, en;q=0.8"), or the default locale if none match.
+// A region-specific locale matches its base language (eg "fr-CH" matches "fr"), and vice versa.
+func (c *Catalog) Match(acceptLanguage string) string {
+	type tag struct {
+		locale string
+		q      float64
+	}
+	var tags []tag
+	for part := range strings.SplitSeq(acceptLanguage, ",") {
+		locale, params, _ := strings.Cut(part, ";")
+		t := tag{locale: strings.ToLower(strings.TrimSpace(locale)), q: 1}
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			t.q = q
+		}
+		if t.locale != "" && t.q > 0 {
+			tags = append(tags, t)
+		}
+	}
+	slices.SortStableFunc(tags, func(a, b tag) int {
+		return cmp.Compare(b.q, a.q)
+	})
+
+	for _, t := range tags {
+		if t.locale == "*" {
+			break
+		}
+		if locale, ok := c.resolve(t.locale); ok {
+			return c.name(locale)
+		}
+	}
+	return c.name(c.defaultLocale)
+}
+
+// resolve returns the lower case supported locale for the requested one, if any.
+func (c *Catalog) resolve(locale string) (string, bool) {
+	locale = strings.ToLower(locale)
+	if _, ok := c.locales[locale]; ok {
+		return locale, true
+	}
+	base, _, _ := strings.Cut(locale, "-")
+	if _, ok := c.locales[base]; ok {
+		return base, true
+	}
+	for _, name := range c.names {
+		supported := strings.ToLower(name)
+		if supportedBase, _, _ := strings.Cut(supported, "-"); supportedBase == base {
+			return supported, true
+		}
+	}
+	return "", false
+}
+
+// name returns the locale as named by its file.
+func (c *Catalog) name(locale string) string {
+	for _, name := range c.names {
+		if strings.EqualFold(name, locale) {
+			return name
+		}
+	}
+	return locale
+}
+
+// Render returns the message for the code in the locale, executing its template with the params.
+// If the locale (or its base language) is not supported or has no message for the code,
+// that of the default locale is used. Otherwise ErrUnknownCode is returned.
+func (c *Catalog) Render(code Code, locale string, params map[string]any) (_ string, err error) {
+	defer func() {
+		err = errcontext.Add(err, slog.String("code", string(code)), slog.String("locale", locale))
+	}()
+
+	tmpl, ok := c.template(code, locale)
+	if !ok {
+		return "", errclass.WrapAs(stacktrace.Wrap(ErrUnknownCode), errclass.Persistent)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, params); err != nil {
+		return "", errclass.WrapAs(stacktrace.Wrap(err), errclass.Persistent)
+	}
+	return buf.String(), nil
+}
+
+// RenderError is the same as Render, using the code and params of the error.
+// It returns ErrUnknownCode if the error has no code.
+func (c *Catalog) RenderError(err error, locale string) (string, error) {
+	detail, ok := Get(err)
+	if !ok {
+		return "", errclass.WrapAs(stacktrace.Wrap(ErrUnknownCode), errclass.Persistent)
+	}
+	return c.Render(detail.Code, locale, detail.Params)
+}
+
+func (c *Catalog) template(code Code, locale string) (*template.Template, bool) {
+	if resolved, ok := c.resolve(locale); ok {
+		if tmpl, ok := c.locales[resolved][code]; ok {
+			return tmpl, true
+		}
+	}
+	tmpl, ok := c.locales[c.defaultLocale][code]
+	return tmpl, ok
+}
@@ -0,0 +1,50 @@
+// Package errcode marks errors with stable codes that are safe to return to external clients,
+// and renders localized messages for them from a catalog of templates.
+package errcode
+
+import (
+	"log/slog"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors"
+)
+
+// Code identifies a kind of error to external clients, eg "account.insufficient_funds".
+// Unlike error messages, codes are stable and do not depend on the locale.
+type Code string
+
+// String implements stringer interface.
+func (c Code) String() string {
+	return string(c)
+}
+
+// Detail is the code of an error along with the parameters for its message.
+type Detail struct {
+	Code Code
+	// Params are passed to the message template of the code, so they are returned to
+	// external clients and must never include internal details.
+	Params map[string]any
+}
+
+// LogValue implements slog.LogValuer for Detail.
+func (d Detail) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("code", d.Code.String()),
+	)
+}
+
+// ExternallySafe marks Detail as safe to expose to external clients (see xerrors.Sanitize).
+func (d Detail) ExternallySafe() {}
+
+// WrapAs extends an error with the given code and message parameters.
+func WrapAs(err error, code Code, params map[string]any) error {
+	if err == nil {
+		return nil
+	}
+	return xerrors.Extend(Detail{Code: code, Params: params}, err)
+}
+
+// Get returns the code and message parameters of the error, if it has been marked.
+// NOTE: If an error is marked more than once, the outermost code is returned.
+func Get(err error) (Detail, bool) {
+	return xerrors.Extract[Detail](err)
+}
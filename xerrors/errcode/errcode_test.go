@@ -0,0 +1,139 @@
+package errcode_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcode"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+const codeInsufficientFunds errcode.Code = "account.insufficient_funds"
+
+var errTest = errors.New("balance 5 below amount 10")
+
+func TestErrCode(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, errcode.WrapAs(nil, codeInsufficientFunds, nil))
+	_, ok := errcode.Get(errTest)
+	assert.False(t, ok)
+
+	params := map[string]any{"balance": 5}
+	err := fmt.Errorf("withdraw: %w", stacktrace.Wrap(errcode.WrapAs(errTest, codeInsufficientFunds, params)))
+	detail, ok := errcode.Get(err)
+	require.True(t, ok)
+	assert.Equal(t, errcode.Detail{Code: codeInsufficientFunds, Params: params}, detail)
+	require.ErrorIs(t, err, errTest)
+
+	// the code survives sanitizing, unlike the stack trace
+	sanitized := xerrors.Sanitize(err)
+	detail, ok = errcode.Get(sanitized)
+	require.True(t, ok)
+	assert.Equal(t, codeInsufficientFunds, detail.Code)
+	_, ok = xerrors.Extract[stacktrace.StackTrace](sanitized)
+	assert.False(t, ok)
+}
+
+var catalogFS = fstest.MapFS{
+	"en.json": {Data: []byte(`{
+		"account.insufficient_funds": "Your balance of {{.balance}} is too low.",
+		"account.locked": "Your account is locked."
+	}`)},
+	"fr.json": {Data: []byte(`{
+		"account.insufficient_funds": "Votre solde de {{.balance}} est insuffisant."
+	}`)},
+	"pt-BR.json": {Data: []byte(`{
+		"account.insufficient_funds": "Seu saldo de {{.balance}} é insuficiente."
+	}`)},
+	"README.md": {Data: []byte(`not a locale`)},
+}
+
+func TestCatalogRender(t *testing.T) {
+	t.Parallel()
+
+	catalog, err := errcode.NewCatalog(catalogFS)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"en", "fr", "pt-BR"}, catalog.Locales())
+
+	params := map[string]any{"balance": 5}
+	testCases := []struct {
+		name     string
+		code     errcode.Code
+		locale   string
+		expected string
+	}{
+		{"exact locale", codeInsufficientFunds, "fr", "Votre solde de 5 est insuffisant."},
+		{"regional locale falls back to base language", codeInsufficientFunds, "fr-CA", "Votre solde de 5 est insuffisant."},
+		{"base language matches regional locale", codeInsufficientFunds, "pt", "Seu saldo de 5 é insuficiente."},
+		{"locale is case insensitive", codeInsufficientFunds, "PT-br", "Seu saldo de 5 é insuficiente."},
+		{"unsupported locale uses default", codeInsufficientFunds, "de", "Your balance of 5 is too low."},
+		{"missing message uses default", "account.locked", "fr", "Your account is locked."},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			message, err := catalog.Render(tc.code, tc.locale, params)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, message)
+		})
+	}
+
+	_, err = catalog.Render("account.unknown", "en", nil)
+	require.ErrorIs(t, err, errcode.ErrUnknownCode)
+
+	// missing params are an error rather than rendering a placeholder
+	_, err = catalog.Render(codeInsufficientFunds, "en", nil)
+	require.Error(t, err)
+
+	message, err := catalog.RenderError(errcode.WrapAs(errTest, codeInsufficientFunds, params), "fr")
+	require.NoError(t, err)
+	assert.Equal(t, "Votre solde de 5 est insuffisant.", message)
+	_, err = catalog.RenderError(errTest, "fr")
+	require.ErrorIs(t, err, errcode.ErrUnknownCode)
+}
+
+func TestCatalogMatch(t *testing.T) {
+	t.Parallel()
+
+	catalog, err := errcode.NewCatalog(catalogFS)
+	require.NoError(t, err)
+
+	testCases := map[string]string{
+		"":                           "en",
+		"fr":                         "fr",
+		"fr-CH, fr;q=0.9, en;q=0.8":  "fr",
+		"de, en;q=0.5, fr;q=0.8":     "fr",
+		"de, *;q=0.5":                "en",
+		"pt-PT":                      "pt-BR",
+		"fr;q=0, en;q=0.1":           "en",
+		"fr;q=bad, pt-br;q=0.5, de":  "pt-BR",
+		"  FR-ca ; q=0.7 , en;q=0.6": "fr",
+	}
+	for header, expected := range testCases {
+		assert.Equal(t, expected, catalog.Match(header), header)
+	}
+}
+
+func TestNewCatalogErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := errcode.NewCatalog(catalogFS, errcode.WithDefaultLocale("de"))
+	require.ErrorIs(t, err, errcode.ErrInvalidCatalog)
+
+	_, err = errcode.NewCatalog(fstest.MapFS{"en.json": {Data: []byte(`{"a": "{{.unclosed"}`)}})
+	require.ErrorIs(t, err, errcode.ErrInvalidCatalog)
+
+	_, err = errcode.NewCatalog(fstest.MapFS{"en.json": {Data: []byte(`not json`)}})
+	require.ErrorIs(t, err, errcode.ErrInvalidCatalog)
+
+	catalog, err := errcode.NewCatalog(catalogFS, errcode.WithDefaultLocale("FR"))
+	require.NoError(t, err)
+	assert.Equal(t, "fr", catalog.Match("de"))
+}
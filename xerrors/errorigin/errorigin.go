@@ -0,0 +1,102 @@
+// Package errorigin links errors from worker goroutines to the operation which submitted the work.
+//
+// Stack traces captured in a goroutine end at the function passed to `go`, with no record of
+// which caller enqueued the work. Capturing an Origin at submission and attaching it to any
+// resulting error restores that link.
+package errorigin
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+// depth of stack to ignore so that the stack starts at the caller of Capture or Wrap.
+const captureStackDepth = 4
+
+// Origin records where asynchronous work was submitted.
+type Origin struct {
+	// Operation names the submitting operation.
+	Operation string
+	// Stack is the stack of the submitting goroutine at submission.
+	Stack stacktrace.StackTrace
+	// Parent is the origin of the submitting goroutine, if it was itself running submitted work.
+	Parent *Origin
+}
+
+// LogValue implements slog.LogValuer for Origin.
+func (o Origin) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, 3)
+	if o.Operation != "" {
+		attrs = append(attrs, slog.String("operation", o.Operation))
+	}
+	attrs = append(attrs, slog.Any("stacktrace", o.Stack))
+	if o.Parent != nil {
+		attrs = append(attrs, slog.Any("parent", *o.Parent))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying the origin, such that work submitted using it
+// records the origin as its parent.
+func NewContext(ctx context.Context, o Origin) context.Context {
+	return context.WithValue(ctx, contextKey{}, o)
+}
+
+// FromContext returns the origin carried by ctx, if any.
+func FromContext(ctx context.Context) (Origin, bool) {
+	o, ok := ctx.Value(contextKey{}).(Origin)
+	return o, ok
+}
+
+// Capture records the stack of its caller as the origin of the named operation.
+// Call it on the submitting goroutine, before starting the work.
+func Capture(ctx context.Context, operation string) Origin {
+	return capture(ctx, operation)
+}
+
+func capture(ctx context.Context, operation string) Origin {
+	o := Origin{
+		Operation: operation,
+		Stack:     stacktrace.GetStack(captureStackDepth, true),
+	}
+	if parent, ok := FromContext(ctx); ok {
+		o.Parent = &parent
+	}
+	return o
+}
+
+// Attach extends the error with the origin.
+// For joined errors, the origin is attached to each individual error.
+func (o Origin) Attach(err error) error {
+	if err == nil {
+		return nil
+	}
+	if joinedErrors := xerrors.Unjoin(err); len(joinedErrors) > 1 {
+		attached := make([]error, len(joinedErrors))
+		for i, e := range joinedErrors {
+			attached[i] = o.Attach(e)
+		}
+		return errors.Join(attached...)
+	}
+	return xerrors.Extend(o, err)
+}
+
+// Wrap captures the origin of f, returning a function suitable for errgroup.Go (or similar)
+// which runs f with a context carrying the origin and attaches it to any error returned.
+func Wrap(ctx context.Context, operation string, f func(ctx context.Context) error) func() error {
+	o := capture(ctx, operation)
+	return func() error {
+		return o.Attach(f(NewContext(ctx, o)))
+	}
+}
+
+// Get returns the outermost Origin attached to the error, if any.
+func Get(err error) (Origin, bool) {
+	return xerrors.Extract[Origin](err)
+}
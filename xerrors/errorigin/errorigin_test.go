@@ -0,0 +1,122 @@
+package errorigin_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/calm/errgroup"
+	"github.com/zircuit-labs/zkr-go-common/xerrors"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errorigin"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+var errWorker = errors.New("worker failed")
+
+// submit enqueues work which fails, as a caller of errgroup would.
+func submit(ctx context.Context, g *errgroup.Group) {
+	g.Go(errorigin.Wrap(ctx, "sync blocks", func(ctx context.Context) error {
+		return stacktrace.Wrap(errWorker)
+	}))
+}
+
+func hasFunction(st stacktrace.StackTrace, suffix string) bool {
+	for _, frame := range st {
+		if strings.HasSuffix(frame.Function, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestWrap(t *testing.T) {
+	t.Parallel()
+
+	g := errgroup.New()
+	submit(t.Context(), g)
+	err := g.Wait()
+	require.ErrorIs(t, err, errWorker)
+
+	origin, ok := errorigin.Get(err)
+	require.True(t, ok)
+	assert.Equal(t, "sync blocks", origin.Operation)
+	assert.Nil(t, origin.Parent)
+	// the origin leads back to the submitter, which the worker's own stack does not
+	assert.True(t, hasFunction(origin.Stack, "errorigin_test.submit"))
+	assert.True(t, hasFunction(origin.Stack, "errorigin_test.TestWrap"))
+	assert.False(t, hasFunction(stacktrace.Extract(err), "errorigin_test.TestWrap"))
+}
+
+func TestWrapSuccess(t *testing.T) {
+	t.Parallel()
+
+	f := errorigin.Wrap(t.Context(), "noop", func(ctx context.Context) error {
+		return nil
+	})
+	require.NoError(t, f())
+}
+
+func TestNestedOrigins(t *testing.T) {
+	t.Parallel()
+
+	outer := errorigin.Wrap(t.Context(), "outer", func(ctx context.Context) error {
+		g := errgroup.New()
+		g.Go(errorigin.Wrap(ctx, "inner", func(ctx context.Context) error {
+			return errWorker
+		}))
+		return g.Wait()
+	})
+
+	// the outermost origin is returned
+	origin, ok := errorigin.Get(outer())
+	require.True(t, ok)
+	assert.Equal(t, "outer", origin.Operation)
+
+	// origins captured while running submitted work record it as their parent
+	var inner errorigin.Origin
+	require.NoError(t, errorigin.Wrap(t.Context(), "outer", func(ctx context.Context) error {
+		inner = errorigin.Capture(ctx, "inner")
+		return nil
+	})())
+	require.NotNil(t, inner.Parent)
+	assert.Equal(t, "outer", inner.Parent.Operation)
+}
+
+func TestAttach(t *testing.T) {
+	t.Parallel()
+
+	origin := errorigin.Capture(t.Context(), "attach")
+	assert.Equal(t, "attach", origin.Operation)
+	assert.True(t, hasFunction(origin.Stack, "errorigin_test.TestAttach"))
+
+	require.NoError(t, origin.Attach(nil))
+
+	// joined errors each get the origin
+	err := origin.Attach(errors.Join(errWorker, errors.New("other")))
+	errs := xerrors.Unjoin(err)
+	require.Len(t, errs, 2)
+	for _, e := range errs {
+		o, ok := errorigin.Get(e)
+		require.True(t, ok)
+		assert.Equal(t, "attach", o.Operation)
+	}
+
+	_, ok := errorigin.Get(errWorker)
+	assert.False(t, ok)
+}
+
+func TestLogValue(t *testing.T) {
+	t.Parallel()
+
+	parent := errorigin.Origin{Operation: "parent"}
+	origin := errorigin.Origin{Operation: "child", Parent: &parent}
+	attrs := origin.LogValue().Group()
+	require.Len(t, attrs, 3)
+	assert.Equal(t, "operation", attrs[0].Key)
+	assert.Equal(t, "child", attrs[0].Value.String())
+	assert.Equal(t, "parent", attrs[2].Key)
+}
@@ -0,0 +1,126 @@
+// Package errseverity maps errors to the severity of the alert they warrant, according to their class
+// (see errclass) and code (see errcode), such that the decision is made in one place rather than
+// re-derived by the alert rules of every service.
+package errseverity
+
+import (
+	"log/slog"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcode"
+)
+
+// Severity is the alert warranted by an error. The higher the value, the more severe.
+type Severity int
+
+const (
+	// None warrants no alert, and need not be logged.
+	None Severity = iota
+	// Log warrants no alert, but should be logged.
+	Log
+	// Ticket warrants a ticket to be looked at during working hours.
+	Ticket
+	// Page warrants paging whoever is on call.
+	Page
+)
+
+// String implements stringer interface.
+func (s Severity) String() string {
+	switch s {
+	case None:
+		return "none"
+	case Log:
+		return "log"
+	case Ticket:
+		return "ticket"
+	case Page:
+		return "page"
+	default:
+		return "unknown"
+	}
+}
+
+// Level returns the level at which to log an error of this severity.
+func (s Severity) Level() slog.Level {
+	switch {
+	case s >= Ticket:
+		return slog.LevelError
+	case s == Log:
+		return slog.LevelWarn
+	default:
+		return slog.LevelDebug
+	}
+}
+
+type options struct {
+	classes map[errclass.Class]Severity
+	codes   map[errcode.Code]Severity
+}
+
+// Option is an option func for NewMapping.
+type Option func(options *options)
+
+// WithClass sets the severity of errors of the given class.
+func WithClass(class errclass.Class, severity Severity) Option {
+	return func(options *options) {
+		options.classes[class] = severity
+	}
+}
+
+// WithCode sets the severity of errors with the given code, regardless of their class.
+func WithCode(code errcode.Code, severity Severity) Option {
+	return func(options *options) {
+		options.codes[code] = severity
+	}
+}
+
+// Mapping determines the severity of errors.
+type Mapping struct {
+	classes map[errclass.Class]Severity
+	codes   map[errcode.Code]Severity
+}
+
+// NewMapping creates a Mapping. By default:
+//   - transient errors are logged, since they are expected to resolve themselves (eg on retry);
+//   - persistent and unclassified errors warrant a ticket; and
+//   - panics warrant a page.
+func NewMapping(opts ...Option) *Mapping {
+	// Set up default options
+	options := options{
+		classes: map[errclass.Class]Severity{
+			errclass.Nil:        None,
+			errclass.Unknown:    Ticket,
+			errclass.Transient:  Log,
+			errclass.Persistent: Ticket,
+			errclass.Panic:      Page,
+		},
+		codes: map[errcode.Code]Severity{},
+	}
+
+	// Apply provided options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &Mapping{
+		classes: options.classes,
+		codes:   options.codes,
+	}
+}
+
+// Of returns the severity of the error. The severity of its code (the outermost, if more than one) takes
+// precedence over that of its class. Classes without a severity are treated as unclassified.
+func (m *Mapping) Of(err error) Severity {
+	if err == nil {
+		return None
+	}
+	if detail, ok := errcode.Get(err); ok {
+		if severity, ok := m.codes[detail.Code]; ok {
+			return severity
+		}
+	}
+	if severity, ok := m.classes[errclass.GetClass(err)]; ok {
+		return severity
+	}
+	return m.classes[errclass.Unknown]
+}
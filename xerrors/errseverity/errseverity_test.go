@@ -0,0 +1,96 @@
+package errseverity_test
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errcode"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errseverity"
+)
+
+func TestMapping(t *testing.T) {
+	t.Parallel()
+
+	errBase := errors.New("test error")
+	mapping := errseverity.NewMapping(
+		errseverity.WithClass(errclass.Transient, errseverity.None),
+		errseverity.WithCode("payments.provider_down", errseverity.Page),
+	)
+	defaults := errseverity.NewMapping()
+
+	tests := map[string]struct {
+		err      error
+		expected errseverity.Severity
+		defaults errseverity.Severity
+	}{
+		"nil": {
+			err:      nil,
+			expected: errseverity.None,
+			defaults: errseverity.None,
+		},
+		"unclassified": {
+			err:      errBase,
+			expected: errseverity.Ticket,
+			defaults: errseverity.Ticket,
+		},
+		"transient": {
+			err:      errclass.WrapAs(errBase, errclass.Transient),
+			expected: errseverity.None,
+			defaults: errseverity.Log,
+		},
+		"persistent": {
+			err:      errclass.WrapAs(errBase, errclass.Persistent),
+			expected: errseverity.Ticket,
+			defaults: errseverity.Ticket,
+		},
+		"panic": {
+			err:      errclass.WrapAs(errBase, errclass.Panic),
+			expected: errseverity.Page,
+			defaults: errseverity.Page,
+		},
+		"unmapped class": {
+			err:      errclass.WrapAs(errBase, errclass.Class(200)),
+			expected: errseverity.Ticket,
+			defaults: errseverity.Ticket,
+		},
+		"code overrides class": {
+			err:      errclass.WrapAs(errcode.WrapAs(errBase, "payments.provider_down", nil), errclass.Transient),
+			expected: errseverity.Page,
+			defaults: errseverity.Log,
+		},
+		"unmapped code": {
+			err:      errcode.WrapAs(errclass.WrapAs(errBase, errclass.Persistent), "account.insufficient_funds", nil),
+			expected: errseverity.Ticket,
+			defaults: errseverity.Ticket,
+		},
+		"joined": {
+			err:      errors.Join(errclass.WrapAs(errBase, errclass.Transient), errclass.WrapAs(errBase, errclass.Panic)),
+			expected: errseverity.Page,
+			defaults: errseverity.Page,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, mapping.Of(tc.err))
+			assert.Equal(t, tc.defaults, defaults.Of(tc.err))
+		})
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "page", errseverity.Page.String())
+	assert.Equal(t, "unknown", errseverity.Severity(-1).String())
+
+	assert.Equal(t, slog.LevelDebug, errseverity.None.Level())
+	assert.Equal(t, slog.LevelWarn, errseverity.Log.Level())
+	assert.Equal(t, slog.LevelError, errseverity.Ticket.Level())
+	assert.Equal(t, slog.LevelError, errseverity.Page.Level())
+}
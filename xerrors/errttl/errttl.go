@@ -0,0 +1,78 @@
+// Package errttl marks errors with a validity window, so that cached errors
+// (eg negative lookups) can be re-validated once stale rather than cached forever.
+package errttl
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors"
+)
+
+// Validity is the time until which an error remains valid.
+type Validity struct {
+	Expires time.Time
+}
+
+// LogValue implements slog.LogValuer for Validity.
+func (v Validity) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Time("expires", v.Expires),
+	)
+}
+
+// WithTTL marks the error as valid for the given duration from now.
+func WithTTL(err error, ttl time.Duration) error {
+	return WithExpiry(err, time.Now().Add(ttl))
+}
+
+// WithExpiry marks the error as valid until the given time.
+func WithExpiry(err error, expires time.Time) error {
+	if err == nil {
+		return nil
+	}
+	return xerrors.Extend(Validity{Expires: expires}, err)
+}
+
+// Expiry returns the time until which the error is valid, if it has been marked.
+// For joined errors, the earliest expiry of any marked child is returned,
+// since the joined error is only valid while all of its parts are.
+func Expiry(err error) (time.Time, bool) {
+	if err == nil {
+		return time.Time{}, false
+	}
+
+	// an explicit marker on the error itself takes precedence
+	if extended, ok := err.(xerrors.ExtendedError[Validity]); ok { //nolint:errorlint // intentionally not using errors.As
+		return extended.Data.Expires, true
+	}
+
+	if children := xerrors.Unjoin(err); len(children) > 1 {
+		var earliest time.Time
+		found := false
+		for _, child := range children {
+			if expires, ok := Expiry(child); ok && (!found || expires.Before(earliest)) {
+				earliest = expires
+				found = true
+			}
+		}
+		return earliest, found
+	}
+
+	if v, ok := xerrors.Extract[Validity](err); ok {
+		return v.Expires, true
+	}
+	return time.Time{}, false
+}
+
+// IsStale reports whether the error has been marked with a validity window which has now passed.
+// Errors without a validity window are never stale.
+func IsStale(err error) bool {
+	return IsStaleAt(err, time.Now())
+}
+
+// IsStaleAt reports whether the error has been marked with a validity window which has passed at the given time.
+func IsStaleAt(err error, now time.Time) bool {
+	expires, ok := Expiry(err)
+	return ok && !now.Before(expires)
+}
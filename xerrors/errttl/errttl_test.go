@@ -0,0 +1,115 @@
+package errttl_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errclass"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/errttl"
+	"github.com/zircuit-labs/zkr-go-common/xerrors/stacktrace"
+)
+
+var errNotFound = errors.New("not found")
+
+func TestExpiry(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	early := now.Add(time.Minute)
+	late := now.Add(time.Hour)
+
+	testCases := []struct {
+		testName string
+		err      error
+		expires  time.Time
+		ok       bool
+	}{
+		{
+			testName: "nil error",
+			err:      nil,
+		},
+		{
+			testName: "unmarked error",
+			err:      errNotFound,
+		},
+		{
+			testName: "marked error",
+			err:      errttl.WithExpiry(errNotFound, late),
+			expires:  late,
+			ok:       true,
+		},
+		{
+			testName: "wrapped marked error",
+			err:      errclass.WrapAs(stacktrace.Wrap(errttl.WithExpiry(errNotFound, late)), errclass.Persistent),
+			expires:  late,
+			ok:       true,
+		},
+		{
+			testName: "fmt wrapped marked error",
+			err:      fmt.Errorf("lookup: %w", errttl.WithExpiry(errNotFound, late)),
+			expires:  late,
+			ok:       true,
+		},
+		{
+			testName: "joined errors use earliest expiry",
+			err:      errors.Join(errttl.WithExpiry(errNotFound, late), errNotFound, errttl.WithExpiry(errNotFound, early)),
+			expires:  early,
+			ok:       true,
+		},
+		{
+			testName: "explicit marker overrides joined children",
+			err:      errttl.WithExpiry(errors.Join(errttl.WithExpiry(errNotFound, early), errNotFound), late),
+			expires:  late,
+			ok:       true,
+		},
+		{
+			testName: "joined unmarked errors",
+			err:      errors.Join(errNotFound, errNotFound),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+			expires, ok := errttl.Expiry(tc.err)
+			require.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expires, expires)
+		})
+	}
+}
+
+func TestIsStale(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	err := errttl.WithExpiry(errNotFound, now.Add(time.Minute))
+
+	assert.False(t, errttl.IsStaleAt(err, now))
+	assert.True(t, errttl.IsStaleAt(err, now.Add(time.Minute)))
+	assert.True(t, errttl.IsStaleAt(err, now.Add(time.Hour)))
+
+	// unmarked errors are never stale
+	assert.False(t, errttl.IsStaleAt(errNotFound, now.Add(time.Hour)))
+	assert.False(t, errttl.IsStale(nil))
+
+	// the marker does not interfere with the error chain
+	assert.ErrorIs(t, err, errNotFound)
+	assert.Equal(t, errNotFound.Error(), err.Error())
+}
+
+func TestWithTTL(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, errttl.WithTTL(nil, time.Minute))
+
+	err := errttl.WithTTL(errNotFound, time.Hour)
+	assert.False(t, errttl.IsStale(err))
+
+	err = errttl.WithTTL(errNotFound, -time.Second)
+	assert.True(t, errttl.IsStale(err))
+}
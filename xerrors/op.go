@@ -0,0 +1,51 @@
+package xerrors
+
+import "errors"
+
+// opError annotates an error with the name of the operation during which it occurred.
+// It deliberately does not implement slog.LogValuer, since the log package logs the whole chain
+// of operations at once (see Ops) rather than each annotation separately.
+type opError struct {
+	op  string
+	err error
+}
+
+// Error meets the error interface by returning the Error() of the underlying.
+func (e opError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying wrapped error.
+func (e opError) Unwrap() error {
+	return e.err
+}
+
+// Op annotates the error with the name of the operation which failed, by convention "pkg.Func" (or "pkg.Type.Method").
+// Annotating at each layer builds a chain of human-readable breadcrumbs, eg "api.Transfer", "ledger.Debit",
+// "pg.Exec", which is easier to read in dashboards than the frames of a stack trace.
+// For joined errors, the operation is added to each individual error.
+func Op(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if joinedErrors := Unjoin(err); len(joinedErrors) > 1 {
+		annotated := make([]error, len(joinedErrors))
+		for i, e := range joinedErrors {
+			annotated[i] = Op(op, e)
+		}
+		return errors.Join(annotated...)
+	}
+	return opError{op: op, err: err}
+}
+
+// Ops returns the chain of operations the error has been annotated with, outermost first.
+// The chain stops at joined errors, whose individual errors have their own chains.
+func Ops(err error) []string {
+	var ops []string
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if oe, ok := e.(opError); ok {
+			ops = append(ops, oe.op)
+		}
+	}
+	return ops
+}
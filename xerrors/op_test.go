@@ -0,0 +1,44 @@
+package xerrors_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors"
+)
+
+func TestOp(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, xerrors.Op("pkg.Func", nil))
+	assert.Nil(t, xerrors.Ops(nil))
+	assert.Nil(t, xerrors.Ops(errTest))
+
+	// operations are listed outermost first, through other wrapping
+	err := xerrors.Op("pg.Exec", errTest)
+	err = xerrors.Op("ledger.Debit", wrap(err))
+	err = xerrors.Extend(42, err)
+	err = xerrors.Op("api.Transfer", err)
+
+	assert.Equal(t, []string{"api.Transfer", "ledger.Debit", "pg.Exec"}, xerrors.Ops(err))
+	assert.ErrorIs(t, err, errTest)
+	assert.Equal(t, "wrapping: "+errTest.Error(), err.Error())
+
+	// operations are not externally safe
+	assert.Nil(t, xerrors.Ops(xerrors.Sanitize(err)))
+}
+
+func TestOpJoined(t *testing.T) {
+	t.Parallel()
+
+	err1 := xerrors.Op("s3.Get", errors.New("first"))
+	err2 := errors.New("second")
+	joined := xerrors.Op("sync.Run", errors.Join(err1, err2))
+
+	errs := xerrors.Flatten(joined)
+	assert.Len(t, errs, 2)
+	assert.Equal(t, []string{"sync.Run", "s3.Get"}, xerrors.Ops(errs[0]))
+	assert.Equal(t, []string{"sync.Run"}, xerrors.Ops(errs[1]))
+}
@@ -0,0 +1,48 @@
+package xerrors
+
+import "errors"
+
+// ExternallySafe is implemented by extended data types that may be exposed
+// to external clients (such as an error classification).
+type ExternallySafe interface {
+	ExternallySafe()
+}
+
+type extended interface {
+	rewrap(err error) error
+	isExternallySafe() bool
+}
+
+// Sanitize returns a copy of err that is safe to return to external clients.
+// The error message is preserved, as is any extended data implementing ExternallySafe.
+// All other extended data (eg stack traces and error context) is removed, and the
+// original chain of wrapped errors is no longer reachable from the result.
+// For joined errors, each individual error is sanitized.
+func Sanitize(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if joinedErrors := Unjoin(err); len(joinedErrors) > 1 {
+		sanitizedErrors := make([]error, len(joinedErrors))
+		for i, e := range joinedErrors {
+			sanitizedErrors[i] = Sanitize(e)
+		}
+		return errors.Join(sanitizedErrors...)
+	}
+
+	// Collect the safe extended data from outermost to innermost
+	var safe []extended
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if ext, ok := e.(extended); ok && ext.isExternallySafe() {
+			safe = append(safe, ext)
+		}
+	}
+
+	// Rebuild from the innermost so that the outermost data remains outermost
+	sanitized := errors.New(err.Error())
+	for i := len(safe) - 1; i >= 0; i-- {
+		sanitized = safe[i].rewrap(sanitized)
+	}
+	return sanitized
+}
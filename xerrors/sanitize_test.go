@@ -0,0 +1,64 @@
+package xerrors_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zircuit-labs/zkr-go-common/xerrors"
+)
+
+type publicData string
+
+func (publicData) ExternallySafe() {}
+
+type privateData string
+
+func TestSanitize(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, xerrors.Sanitize(nil))
+
+	err := xerrors.Extend(privateData("/home/user/secret.go"), errTest)
+	err = xerrors.Extend(publicData("code-123"), err)
+	err = wrap(err)
+
+	sanitized := xerrors.Sanitize(err)
+	assert.Equal(t, err.Error(), sanitized.Error())
+
+	// public data is preserved
+	pub, ok := xerrors.Extract[publicData](sanitized)
+	assert.True(t, ok)
+	assert.Equal(t, publicData("code-123"), pub)
+
+	// private data and the original chain are removed
+	_, ok = xerrors.Extract[privateData](sanitized)
+	assert.False(t, ok)
+	assert.NotErrorIs(t, sanitized, errTest)
+
+	// the original error is unchanged
+	_, ok = xerrors.Extract[privateData](err)
+	assert.True(t, ok)
+}
+
+func TestSanitizeJoined(t *testing.T) {
+	t.Parallel()
+
+	errA := xerrors.Extend(publicData("a"), xerrors.Extend(privateData("a"), errors.New("error A")))
+	errB := xerrors.Extend(privateData("b"), fmt.Errorf("error B"))
+
+	sanitized := xerrors.Sanitize(errors.Join(errA, errB))
+	children := xerrors.Unjoin(sanitized)
+	assert.Len(t, children, 2)
+
+	pub, ok := xerrors.Extract[publicData](children[0])
+	assert.True(t, ok)
+	assert.Equal(t, publicData("a"), pub)
+	for _, child := range children {
+		_, ok := xerrors.Extract[privateData](child)
+		assert.False(t, ok)
+	}
+	assert.Equal(t, "error A\nerror B", sanitized.Error())
+}
@@ -2,6 +2,7 @@ package stacktrace
 
 import (
 	"errors"
+	"log/slog"
 	"sync/atomic"
 
 	"github.com/zircuit-labs/zkr-go-common/xerrors"
@@ -54,3 +55,55 @@ func Extract(err error) StackTrace {
 	}
 	return st
 }
+
+// LabeledStackTrace is a stack trace describing a step in the journey of an error,
+// eg where it was submitted to a worker or retried.
+type LabeledStackTrace struct {
+	Label string
+	Stack StackTrace
+}
+
+// LogValue implements slog.LogValuer for LabeledStackTrace.
+func (l LabeledStackTrace) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("label", l.Label),
+		slog.Any("stacktrace", l.Stack),
+	)
+}
+
+// WrapLabeled extends an error with a stack trace at the point where this was called, labeled to
+// describe it (eg "retried_at attempt 3"). Unlike Wrap, the stack trace is added even if the error
+// already contains one, such that an error crossing goroutine or retry boundaries records each step.
+// For joined errors, the stack trace is added to each individual error.
+func WrapLabeled(err error, label string) error {
+	// no-op if disabled or the error is nil
+	if Disabled.Load() || err == nil {
+		return err
+	}
+
+	if joinedErrors := xerrors.Unjoin(err); len(joinedErrors) > 1 {
+		wrappedErrors := make([]error, len(joinedErrors))
+		for i, e := range joinedErrors {
+			wrappedErrors[i] = WrapLabeled(e, label)
+		}
+		return errors.Join(wrappedErrors...)
+	}
+
+	return wrapLabeledError(err, label)
+}
+
+func wrapLabeledError(err error, label string) error {
+	return xerrors.Extend(LabeledStackTrace{Label: label, Stack: GetStack(wrapStackDepth, true)}, err)
+}
+
+// ExtractLabeled returns all of the labeled stack traces in the error chain, most recent first.
+// Those within joined errors are not included, since each error of the join has its own.
+func ExtractLabeled(err error) []LabeledStackTrace {
+	var traces []LabeledStackTrace
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if extended, ok := e.(xerrors.ExtendedError[LabeledStackTrace]); ok {
+			traces = append(traces, extended.Data)
+		}
+	}
+	return traces
+}
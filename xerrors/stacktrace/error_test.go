@@ -390,3 +390,69 @@ func TestStackTraceTypes(t *testing.T) {
 		}
 	})
 }
+
+func submit(err error) error {
+	return stacktrace.WrapLabeled(err, "submitted_at")
+}
+
+func retry(err error, attempt int) error {
+	return stacktrace.WrapLabeled(err, fmt.Sprintf("retried_at attempt %d", attempt))
+}
+
+// TestWrapLabeled checks that labeled stack traces are added to errors which already have one.
+func TestWrapLabeled(t *testing.T) {
+	t.Parallel()
+
+	if err := stacktrace.WrapLabeled(nil, "nil"); err != nil {
+		t.Errorf("unexpected error: got %v", err)
+	}
+
+	err := retry(retry(submit(a()), 1), 2)
+	if !errors.Is(err, errTest) {
+		t.Errorf("expected wrapped error: got %v", err)
+	}
+	if st := stacktrace.Extract(err); len(st) != 4 {
+		t.Errorf("expected the original stack trace: got %v", st)
+	}
+
+	labeled := stacktrace.ExtractLabeled(err)
+	expected := []struct {
+		label    string
+		function string
+	}{
+		{label: "retried_at attempt 2", function: "stacktrace_test.retry"},
+		{label: "retried_at attempt 1", function: "stacktrace_test.retry"},
+		{label: "submitted_at", function: "stacktrace_test.submit"},
+	}
+	if len(labeled) != len(expected) {
+		t.Fatalf("unexpected labeled stack traces: want %d got %d", len(expected), len(labeled))
+	}
+	for i, l := range labeled {
+		if l.Label != expected[i].label {
+			t.Errorf("unexpected label: want %s got %s", expected[i].label, l.Label)
+		}
+		if len(l.Stack) == 0 || !strings.HasSuffix(l.Stack[0].Function, expected[i].function) {
+			t.Errorf("unexpected first frame for %s: %v", l.Label, l.Stack)
+		}
+	}
+
+	// each error of a join gets its own labeled stack trace
+	joined := stacktrace.WrapLabeled(errors.Join(errTest, errors.New("other")), "submitted_at")
+	if stacktrace.ExtractLabeled(joined) != nil {
+		t.Error("expected no labeled stack traces for the join itself")
+	}
+	for _, e := range joined.(interface{ Unwrap() []error }).Unwrap() {
+		if len(stacktrace.ExtractLabeled(e)) != 1 {
+			t.Errorf("expected a labeled stack trace for %v", e)
+		}
+	}
+}
+
+func TestWrapLabeledDisabled(t *testing.T) { //nolint:paralleltest // test uses package-level variable
+	stacktrace.Disabled.Store(true)
+	t.Cleanup(func() { stacktrace.Disabled.Store(false) })
+
+	if labeled := stacktrace.ExtractLabeled(submit(errTest)); labeled != nil {
+		t.Errorf("expected no labeled stack traces: got %v", labeled)
+	}
+}
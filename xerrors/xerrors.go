@@ -32,6 +32,17 @@ func (e ExtendedError[T]) LogValue() slog.Value {
 	return slog.AnyValue(e.Data)
 }
 
+// rewrap returns a copy of e wrapping a different underlying error.
+func (e ExtendedError[T]) rewrap(err error) error {
+	return ExtendedError[T]{Data: e.Data, err: err}
+}
+
+// isExternallySafe reports whether the extended data may be exposed to external clients.
+func (e ExtendedError[T]) isExternallySafe() bool {
+	_, ok := any(e.Data).(ExternallySafe)
+	return ok
+}
+
 // Extend creates an ExtendedError wrapping an original error with additional data.
 func Extend[T any](data T, err error) error {
 	if err == nil {